@@ -60,3 +60,15 @@ Create the name of the service account to use
 {{- default "default" .Values.serviceAccount.name }}
 {{- end }}
 {{- end }}
+
+{{/*
+Returns true if the rule passed as "rule" is enabled on an admission endpoint, e.g.
+include "cosignwebhook.ruleEnabled" (dict "root" . "rule" "references")
+*/}}
+{{- define "cosignwebhook.ruleEnabled" -}}
+{{- $rules := .root.Values.admission.rules | default list -}}
+{{- range .root.Values.admission.endpoints }}
+{{- $rules = concat $rules (.rules | default list) -}}
+{{- end }}
+{{- if has .rule $rules }}true{{ end -}}
+{{- end }}
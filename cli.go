@@ -0,0 +1,245 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/eumel8/cosignwebhook/webhook"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// serverFlags are the flags of the server settings, shared by the server and config effective
+var serverFlags = []string{webhook.ConfigFlag, webhook.TLSCertFileFlag, webhook.TLSKeyFileFlag, webhook.LogLevelFlag, webhook.SemanticsFlag, webhook.ShadowSemanticsFlag, webhook.CrashReportDirFlag, webhook.TargetInflightFlag, webhook.MaxInflightFlag, webhook.OverflowFlag, webhook.OverflowDeadlineFlag, webhook.UnparseableFlag, webhook.OnErrorFlag, webhook.OnErrorOverridesFlag, webhook.WebhookConfigurationFlag, webhook.ExemptNamespacesFlag, webhook.ProxyFlag, webhook.NoProxyFlag, webhook.ProxyOverridesFlag, webhook.CABundleFlag}
+
+// logLevels are the values of the logLevel flag
+var logLevels = []string{"trace", "debug", "info", "warn", "error", "fatal"}
+
+// newRootCommand returns the command running the webhook server with the other commands as subcommands
+func newRootCommand() *cobra.Command {
+	root := &cobra.Command{
+		Use:   "cosignwebhook",
+		Short: "Kubernetes admission webhook verifying image signatures and enforcing policies",
+		Long: `cosignwebhook is a validating admission webhook. Without subcommand, it serves the admission
+endpoints on port 8080 and health and metrics on port 8081.
+
+Flags can be given with one or two dashes, e.g. -config or --config.
+Server settings are layered: defaults < config file < env < flags, see "cosignwebhook config effective".`,
+		Example: `  cosignwebhook -config /etc/cosignwebhook/config.yaml -logLevel debug
+  cosignwebhook init
+  source <(cosignwebhook completion bash)`,
+		Args:         cobra.NoArgs,
+		Run:          serve,
+		SilenceUsage: true,
+	}
+	root.Flags().AddGoFlagSet(flag.CommandLine)
+	addServerFlagCompletion(root, root.Flags())
+
+	root.AddCommand(newConfigCommand(), newInitCommand(), newDocsCommand(), newExportCommand(), newOpenAPICommand(), newGenManifestsCommand(), newDoctorCommand(), newOnboardCommand())
+	return root
+}
+
+// newConfigCommand returns the command inspecting the configuration
+func newConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration of the webhook",
+	}
+	var format string
+	effective := &cobra.Command{
+		Use:   "effective",
+		Short: "Print the effective server settings and where they came from",
+		Long: `Print the effective server settings and the layer each value came from.
+Later layers win: default < file < env < flag.`,
+		Example: `  COSIGNWEBHOOK_LOG_LEVEL=debug cosignwebhook config effective --config config.yaml
+  cosignwebhook config effective -o json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			_, settings, err := webhook.LoadEffectiveConfig(setFlags(cmd), os.Getenv)
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			return writeOutput(cmd.OutOrStdout(), format, settings, func(w io.Writer) {
+				fmt.Fprintln(w, "NAME\tVALUE\tSOURCE\tENV")
+				for _, s := range settings {
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Name, s.Value, s.Source, s.Env)
+				}
+			})
+		},
+	}
+	addOutputFlag(effective, &format)
+	for _, name := range serverFlags {
+		effective.Flags().AddGoFlag(flag.Lookup(name))
+	}
+	addServerFlagCompletion(effective, effective.Flags())
+	cmd.AddCommand(effective)
+	return cmd
+}
+
+// newDocsCommand returns the command rendering the documentation of the policy
+func newDocsCommand() *cobra.Command {
+	var format string
+	cmd := &cobra.Command{
+		Use:   "docs",
+		Short: "Render the policy as Markdown or HTML documentation",
+		Long: `Render the endpoints and rules of the effective config as documentation with rule descriptions,
+the kinds they apply to, severities, examples and settings, e.g. for a cluster policy handbook.`,
+		Example: `  cosignwebhook docs --config config.yaml > POLICY.md
+  cosignwebhook docs --config config.yaml --format html > policy.html`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, _, err := webhook.LoadEffectiveConfig(setFlags(cmd), os.Getenv)
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			doc, err := webhook.NewPolicyDoc(cfg)
+			if err != nil {
+				return err
+			}
+			data, err := doc.Render(format)
+			if err != nil {
+				return err
+			}
+			_, err = cmd.OutOrStdout().Write(data)
+			return err
+		},
+	}
+	cmd.Flags().AddGoFlag(flag.Lookup(webhook.ConfigFlag))
+	_ = cmd.Flags().SetAnnotation(webhook.ConfigFlag, cobra.BashCompFilenameExt, []string{"yaml", "yml"})
+	cmd.Flags().StringVar(&format, "format", webhook.DocsFormatMarkdown, "Format of the documentation: markdown or html.")
+	_ = cmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(
+		[]string{webhook.DocsFormatMarkdown, webhook.DocsFormatHTML}, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+// newOpenAPICommand returns the command printing the OpenAPI document of the HTTP APIs
+func newOpenAPICommand() *cobra.Command {
+	var format string
+	cmd := &cobra.Command{
+		Use:   "openapi",
+		Short: "Print the OpenAPI v3 document of the HTTP APIs",
+		Long: `Print the OpenAPI v3 document of the evaluate API, the decision stream and the monitor endpoints,
+e.g. to generate clients in other languages. The running webhook serves it on /openapi/v3 of the monitor port.`,
+		Example: `  cosignwebhook openapi -o json > openapi.json`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if format != outputJSON && format != outputYAML {
+				return fmt.Errorf("unknown output format %q, must be %s or %s", format, outputJSON, outputYAML)
+			}
+			return writeOutput(cmd.OutOrStdout(), format, webhook.OpenAPISpec(), nil)
+		},
+	}
+	cmd.Flags().StringVarP(&format, "output", "o", outputYAML, "Output format: json or yaml.")
+	_ = cmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions([]string{outputJSON, outputYAML}, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+// addServerFlagCompletion registers the completion of the server flags
+func addServerFlagCompletion(cmd *cobra.Command, flags *pflag.FlagSet) {
+	_ = flags.SetAnnotation(webhook.ConfigFlag, cobra.BashCompFilenameExt, []string{"yaml", "yml"})
+	_ = flags.SetAnnotation(webhook.TLSCertFileFlag, cobra.BashCompFilenameExt, []string{"crt", "pem"})
+	_ = flags.SetAnnotation(webhook.TLSKeyFileFlag, cobra.BashCompFilenameExt, []string{"key", "pem"})
+	_ = cmd.RegisterFlagCompletionFunc(webhook.LogLevelFlag, cobra.FixedCompletions(logLevels, cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.RegisterFlagCompletionFunc(webhook.OnErrorFlag, cobra.FixedCompletions(
+		[]string{webhook.OnErrorDeny, webhook.OnErrorAllow, webhook.OnErrorIgnoreRule}, cobra.ShellCompDirectiveNoFileComp))
+}
+
+// completeRules completes the rule names of a comma-separated list
+func completeRules(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeList(webhook.RuleNames(), toComplete), cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeNamespaces completes the namespaces of the cluster of the current kubeconfig context,
+// for a comma-separated list if list is true
+func completeNamespaces(list bool) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		cs, err := kubeClient()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		nsl, err := cs.CoreV1().Namespaces().List(cmd.Context(), metav1.ListOptions{})
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		names := make([]string, 0, len(nsl.Items))
+		for _, ns := range nsl.Items {
+			names = append(names, ns.Name)
+		}
+		if !list {
+			return names, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completeList(names, toComplete), cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// kubeClient returns a client for the cluster of the current kubeconfig context
+func kubeClient() (kubernetes.Interface, error) {
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(), &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(config)
+}
+
+// completeList completes the last item of a comma-separated list, skipping the items already given
+func completeList(values []string, toComplete string) []string {
+	given := strings.Split(toComplete, ",")
+	prefix := strings.Join(given[:len(given)-1], ",")
+	if prefix != "" {
+		prefix += ","
+	}
+	var completions []string
+	for _, v := range values {
+		if !slices.Contains(given, v) {
+			completions = append(completions, prefix+v)
+		}
+	}
+	return completions
+}
+
+// normalizeArgs prefixes long flags given with one dash, like -config, with a second dash,
+// so the flags of the Go flag package deployments use keep working
+func normalizeArgs(root *cobra.Command, args []string) []string {
+	long := map[string]bool{}
+	var collect func(cmd *cobra.Command)
+	collect = func(cmd *cobra.Command) {
+		for _, fs := range []*pflag.FlagSet{cmd.Flags(), cmd.PersistentFlags()} {
+			fs.VisitAll(func(f *pflag.Flag) { long[f.Name] = true })
+		}
+		for _, c := range cmd.Commands() {
+			collect(c)
+		}
+	}
+	collect(root)
+
+	normalized := make([]string, 0, len(args))
+	for i, a := range args {
+		if a == "--" {
+			return append(normalized, args[i:]...)
+		}
+		name, _, _ := strings.Cut(strings.TrimPrefix(a, "-"), "=")
+		if strings.HasPrefix(a, "-") && !strings.HasPrefix(a, "--") && len(name) > 1 && long[name] {
+			a = "-" + a
+		}
+		normalized = append(normalized, a)
+	}
+	return normalized
+}
+
+// setFlags returns the flags set explicitly on the command line by name
+func setFlags(cmd *cobra.Command) map[string]string {
+	flags := map[string]string{}
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		flags[f.Name] = f.Value.String()
+	})
+	return flags
+}
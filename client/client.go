@@ -0,0 +1,154 @@
+// Package client is a Go client of the HTTP APIs of the cosignwebhook, as described by its OpenAPI document
+// (cosignwebhook openapi). Clients authenticate with a bearer token of the cluster.
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/eumel8/cosignwebhook/webhook"
+)
+
+// Client calls the APIs of a webhook
+type Client struct {
+	// URL of the webhook port, e.g. https://cosignwebhook.cosignwebhook.svc
+	URL string
+	// Token is sent as bearer token, e.g. of a service account
+	Token string
+	// HTTPClient sends the requests, it needs to trust the certificate of the webhook. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// New returns the client of the webhook at baseURL authenticating with token
+func New(baseURL, token string, httpClient *http.Client) *Client {
+	return &Client{URL: strings.TrimSuffix(baseURL, "/"), Token: token, HTTPClient: httpClient}
+}
+
+// StatusError is returned for responses with an unexpected status
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("status %d: %s", e.StatusCode, e.Message)
+}
+
+// Evaluate evaluates the object, as JSON or YAML, with the rules of the endpoint, e.g. /validate, without admission.
+// An empty endpoint evaluates with the rules of /validate.
+func (c *Client) Evaluate(ctx context.Context, endpoint string, object []byte) (*webhook.Decision, error) {
+	query := url.Values{}
+	if endpoint != "" {
+		query.Set("endpoint", endpoint)
+	}
+	resp, err := c.do(ctx, http.MethodPost, webhook.EvaluatePath, query, bytes.NewReader(object))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	d := &webhook.Decision{}
+	if err := json.NewDecoder(resp.Body).Decode(d); err != nil {
+		return nil, fmt.Errorf("invalid decision: %w", err)
+	}
+	return d, nil
+}
+
+// FlushCache flushes the caches of the replica answering the request. It returns the flushed caches, or a
+// *StatusError if some couldn't be flushed.
+func (c *Client) FlushCache(ctx context.Context) (*webhook.FlushResult, error) {
+	resp, err := c.do(ctx, http.MethodPost, webhook.FlushCachePath, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	res := &webhook.FlushResult{}
+	if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return nil, fmt.Errorf("invalid flush result: %w", err)
+	}
+	return res, nil
+}
+
+// DecisionFilter filters the streamed decisions, empty fields match all decisions
+type DecisionFilter struct {
+	Namespace string
+	Rule      string
+}
+
+// StreamDecisions calls f with the recent and all later decisions matching the filter, until ctx is done,
+// the stream ends or f returns an error
+func (c *Client) StreamDecisions(ctx context.Context, filter DecisionFilter, f func(d *webhook.Decision) error) error {
+	query := url.Values{}
+	if filter.Namespace != "" {
+		query.Set("namespace", filter.Namespace)
+	}
+	if filter.Rule != "" {
+		query.Set("rule", filter.Rule)
+	}
+	resp, err := c.do(ctx, http.MethodGet, webhook.DecisionStreamPath, query, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	event := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		if v, ok := strings.CutPrefix(line, "event: "); ok {
+			event = v
+			continue
+		}
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || event != "decision" {
+			continue
+		}
+		d := &webhook.Decision{}
+		if err := json.Unmarshal([]byte(data), d); err != nil {
+			return fmt.Errorf("invalid decision: %w", err)
+		}
+		if err := f(d); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+// do sends the request and returns the response if its status is 200
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body io.Reader) (*http.Response, error) {
+	u := c.URL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, &StatusError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(msg))}
+	}
+	return resp, nil
+}
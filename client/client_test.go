@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eumel8/cosignwebhook/webhook"
+)
+
+func TestClient(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(webhook.EvaluatePath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "bearer token required", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `{"endpoint":%q,"allowed":false,"rule":"sanity"}`, r.URL.Query().Get("endpoint"))
+	})
+	mux.HandleFunc(webhook.DecisionStreamPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, ": keep-alive\n\nevent: decision\ndata: {\"namespace\":%q,\"name\":\"a\"}\n\n", r.URL.Query().Get("namespace"))
+		fmt.Fprint(w, "event: decision\ndata: {\"name\":\"b\"}\n\n")
+	})
+	mux.HandleFunc(webhook.FlushCachePath, func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `{"flushed":["memo /validate","rule licenses"]}`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := New(srv.URL+"/", "token", srv.Client())
+	d, err := c.Evaluate(context.Background(), "/validate/strict", []byte("apiVersion: v1\nkind: Pod\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Allowed || d.Rule != "sanity" || d.Endpoint != "/validate/strict" {
+		t.Errorf("Evaluate() = %+v, want denied by sanity on /validate/strict", d)
+	}
+
+	var names []string
+	err = c.StreamDecisions(context.Background(), DecisionFilter{Namespace: "team-a"}, func(d *webhook.Decision) error {
+		if d.Name == "a" && d.Namespace != "team-a" {
+			t.Errorf("namespace filter not sent, got %q", d.Namespace)
+		}
+		names = append(names, d.Name)
+		return nil
+	})
+	if err != nil || len(names) != 2 {
+		t.Errorf("StreamDecisions() = %v with %v, want decisions a and b", err, names)
+	}
+
+	res, err := c.FlushCache(context.Background())
+	if err != nil || len(res.Flushed) != 2 {
+		t.Errorf("FlushCache() = %+v, %v, want 2 flushed caches", res, err)
+	}
+
+	_, err = New(srv.URL, "", nil).Evaluate(context.Background(), "", []byte("{}"))
+	var se *StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Evaluate() without token error = %v, want status 401", err)
+	}
+}
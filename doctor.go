@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/eumel8/cosignwebhook/webhook"
+
+	"github.com/spf13/cobra"
+)
+
+// doctorOptions are the flags of the doctor command
+type doctorOptions struct {
+	webhook.DoctorOptions
+	format string
+}
+
+// doctorResult is the output of the doctor command
+type doctorResult struct {
+	Checks []webhook.DoctorCheck `json:"checks"`
+}
+
+// newDoctorCommand returns the command diagnosing an installation of the webhook
+func newDoctorCommand() *cobra.Command {
+	o := &doctorOptions{}
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check an installation of the webhook end-to-end",
+		Long: `Check an installation of the webhook from outside the cluster with the current kubeconfig context:
+the ValidatingWebhookConfiguration exists, its caBundle verifies the certificate of the TLS Secret for the Service,
+the Service has ready endpoints and a canary pod created with dry run round-trips through the webhook.
+Prints a fix for each failed check and fails if any check failed.`,
+		Example: `  cosignwebhook doctor
+  cosignwebhook doctor --webhook-configuration cosignwebhook --canary-namespace team-a -o json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := checkOutput(o.format); err != nil {
+				return err
+			}
+			cs, err := kubeClient()
+			if err != nil {
+				return fmt.Errorf("can't connect to cluster: %w", err)
+			}
+			result := doctorResult{Checks: webhook.Diagnose(cmd.Context(), cs, o.DoctorOptions)}
+			err = writeOutput(cmd.OutOrStdout(), o.format, result, func(w io.Writer) {
+				fmt.Fprintln(w, "CHECK\tSTATUS\tMESSAGE")
+				for _, c := range result.Checks {
+					status := "ok"
+					if !c.OK {
+						status = "failed"
+					}
+					fmt.Fprintf(w, "%s\t%s\t%s\n", c.Name, status, c.Message)
+					if c.Fix != "" {
+						fmt.Fprintf(w, "\tfix\t%s\n", c.Fix)
+					}
+				}
+			})
+			if err != nil {
+				return err
+			}
+			for _, c := range result.Checks {
+				if !c.OK {
+					return fmt.Errorf("check %s failed", c.Name)
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&o.WebhookConfiguration, "webhook-configuration", "cosignwebhook", "Name of the ValidatingWebhookConfiguration of the webhook.")
+	cmd.Flags().StringVar(&o.Secret, "secret", "", "Name of the TLS Secret of the webhook. Empty uses the name of the Service.")
+	cmd.Flags().StringVar(&o.CanaryNamespace, "canary-namespace", "default", "Namespace validated by the webhook, the canary pod is created in with dry run.")
+	cmd.Flags().StringVar(&o.CanaryImage, "canary-image", "busybox:stable", "Image of the canary pod.")
+	addOutputFlag(cmd, &o.format)
+
+	_ = cmd.RegisterFlagCompletionFunc("canary-namespace", completeNamespaces(false))
+	return cmd
+}
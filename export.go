@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/eumel8/cosignwebhook/webhook"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// exportOptions are the flags of the export state command
+type exportOptions struct {
+	namespace  string
+	configMap  string
+	key        string
+	deployment string
+	format     string
+}
+
+// exportedState is the effective policy of the webhook
+type exportedState struct {
+	cfg *webhook.Config
+	// namingConventions are the conventions of the naming ConfigMap, nil if not read
+	namingConventions []webhook.NamingConvention
+}
+
+// newExportCommand returns the command exporting state for GitOps tooling
+func newExportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export state for GitOps tooling",
+	}
+	o := &exportOptions{}
+	state := &cobra.Command{
+		Use:   "state",
+		Short: "Export the effective policy in a canonical, diffable form",
+		Long: `Export the effective policy of the ConfigMap in the cluster, or of the config file given with --config,
+in a canonical form: defaults applied, empty settings removed and keys sorted. Exporting the config in the
+cluster and the one in git the same way, the diff shows drift only, not formatting.
+
+The server settings are layered like the webhook does: in the cluster with the args and env of the container
+of the Deployment, with --config with the env and server flags of this command. In the cluster, the conventions
+of the naming ConfigMap are exported as naming.configMapConventions.`,
+		Example: `  diff <(cosignwebhook export state --config deploy/config.yaml) <(cosignwebhook export state --namespace cosignwebhook)`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if o.format != outputJSON && o.format != outputYAML {
+				return fmt.Errorf("unknown output format %q, must be %s or %s", o.format, outputJSON, outputYAML)
+			}
+			state, err := exportState(cmd, o)
+			if err != nil {
+				return err
+			}
+			canonical, err := state.canonical()
+			if err != nil {
+				return err
+			}
+			return writeOutput(cmd.OutOrStdout(), o.format, canonical, nil)
+		},
+	}
+	for _, name := range serverFlags {
+		state.Flags().AddGoFlag(flag.Lookup(name))
+	}
+	addServerFlagCompletion(state, state.Flags())
+	state.Flags().StringVar(&o.namespace, "namespace", webhook.DefaultStarterNamespace, "Namespace of the ConfigMap of the webhook.")
+	state.Flags().StringVar(&o.configMap, "configmap", "cosignwebhook", "Name of the ConfigMap of the webhook.")
+	state.Flags().StringVar(&o.key, "key", "config.yaml", "Key of the config in the ConfigMap.")
+	state.Flags().StringVar(&o.deployment, "deployment", "cosignwebhook", "Name of the Deployment of the webhook, whose args and env layer the server settings. Empty skips them.")
+	state.Flags().StringVarP(&o.format, "output", "o", outputYAML, "Output format: json or yaml.")
+	_ = state.RegisterFlagCompletionFunc("namespace", completeNamespaces(false))
+	_ = state.RegisterFlagCompletionFunc("output", cobra.FixedCompletions([]string{outputJSON, outputYAML}, cobra.ShellCompDirectiveNoFileComp))
+	cmd.AddCommand(state)
+	return cmd
+}
+
+// exportState loads the config file if given, or the state of the webhook in the cluster
+func exportState(cmd *cobra.Command, o *exportOptions) (*exportedState, error) {
+	if cmd.Flags().Changed(webhook.ConfigFlag) {
+		cfg, _, err := webhook.LoadEffectiveConfig(setFlags(cmd), os.Getenv)
+		if err != nil {
+			return nil, err
+		}
+		return &exportedState{cfg: cfg}, nil
+	}
+	cs, err := kubeClient()
+	if err != nil {
+		return nil, fmt.Errorf("can't connect to cluster: %w", err)
+	}
+	cm, err := cs.CoreV1().ConfigMaps(o.namespace).Get(cmd.Context(), o.configMap, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("can't get ConfigMap %s/%s: %w", o.namespace, o.configMap, err)
+	}
+	data, ok := cm.Data[o.key]
+	if !ok {
+		return nil, fmt.Errorf("ConfigMap %s/%s has no key %q", o.namespace, o.configMap, o.key)
+	}
+	cfg, err := webhook.ParseConfig([]byte(data))
+	if err != nil {
+		return nil, fmt.Errorf("ConfigMap %s/%s: %w", o.namespace, o.configMap, err)
+	}
+	state := &exportedState{cfg: cfg}
+	if o.deployment != "" {
+		if err := layerDeploymentSettings(cmd.Context(), cs, o, cfg); err != nil {
+			return nil, err
+		}
+	}
+	if ref := cfg.Naming.ConfigMap; ref != "" {
+		ns, name, _ := strings.Cut(ref, "/")
+		cm, err := cs.CoreV1().ConfigMaps(ns).Get(cmd.Context(), name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("can't get naming ConfigMap %s: %w", ref, err)
+		}
+		if state.namingConventions, err = webhook.ParseNamingConfigMap(cm); err != nil {
+			return nil, fmt.Errorf("naming ConfigMap %s: %w", ref, err)
+		}
+	}
+	return state, nil
+}
+
+// layerDeploymentSettings layers the server settings of the config with the args and env of the webhook
+// container of the Deployment. Env vars from references are skipped, as their values aren't in the Deployment.
+func layerDeploymentSettings(ctx context.Context, cs kubernetes.Interface, o *exportOptions, cfg *webhook.Config) error {
+	deploy, err := cs.AppsV1().Deployments(o.namespace).Get(ctx, o.deployment, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("can't get Deployment %s/%s: %w", o.namespace, o.deployment, err)
+	}
+	containers := deploy.Spec.Template.Spec.Containers
+	if len(containers) == 0 {
+		return fmt.Errorf("deployment %s/%s has no containers", o.namespace, o.deployment)
+	}
+	c := containers[0]
+	if i := slices.IndexFunc(containers, func(c corev1.Container) bool { return c.Name == o.deployment }); i >= 0 {
+		c = containers[i]
+	}
+	env := map[string]string{}
+	for _, e := range c.Env {
+		if e.ValueFrom == nil {
+			env[e.Name] = e.Value
+		}
+	}
+	webhook.LayerServerSettings(cfg, webhook.ParseServerArgs(c.Args), func(k string) string { return env[k] })
+	return nil
+}
+
+// canonical returns the state in the canonical form of the config
+func (s *exportedState) canonical() (map[string]any, error) {
+	canonical, err := webhook.CanonicalConfig(s.cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.namingConventions) == 0 {
+		return canonical, nil
+	}
+	conventions, err := webhook.CanonicalValue(s.namingConventions)
+	if err != nil {
+		return nil, err
+	}
+	naming, _ := canonical["naming"].(map[string]any)
+	if naming == nil {
+		naming = map[string]any{}
+		canonical["naming"] = naming
+	}
+	naming["configMapConventions"] = conventions
+	return canonical, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/eumel8/cosignwebhook/webhook"
+
+	"github.com/spf13/cobra"
+)
+
+// genManifestsOptions are the flags of the gen-manifests command
+type genManifestsOptions struct {
+	manifestFormat string
+	namespace      string
+	image          string
+	namespaces     string
+	config         string
+	failurePolicy  string
+	dir            string
+	format         string
+}
+
+// newGenManifestsCommand returns the command generating installation manifests for users not using Helm
+func newGenManifestsCommand() *cobra.Command {
+	o := &genManifestsOptions{}
+	cmd := &cobra.Command{
+		Use:   "gen-manifests",
+		Short: "Generate installation manifests without Helm",
+		Long: `Generate the manifests installing the webhook: namespace, RBAC, Deployment, Service, policy
+ConfigMap, certificate Secret and ValidatingWebhookConfiguration. The webhook certificate and its CA bundle are
+placeholders: ${CA_BUNDLE}, ${TLS_CRT} and ${TLS_KEY} for raw, data values for ytt and top-level arguments for jsonnet.`,
+		Example: `  cosignwebhook gen-manifests --format raw --namespace security --config config.yaml
+  cosignwebhook gen-manifests --format ytt --dir deploy
+  cosignwebhook gen-manifests --format jsonnet --image registry.example.com/cosignwebhook:4.3.0 -o json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runGenManifests(cmd, o)
+		},
+	}
+	cmd.Flags().StringVar(&o.manifestFormat, "format", webhook.ManifestFormatRaw, "Format of the manifests: raw, ytt or jsonnet.")
+	cmd.Flags().StringVar(&o.namespace, "namespace", webhook.DefaultStarterNamespace, "Namespace the webhook is deployed to.")
+	cmd.Flags().StringVar(&o.image, "image", webhook.DefaultStarterImage, "Image of the webhook.")
+	cmd.Flags().StringVar(&o.namespaces, "namespaces", "", "Comma-separated namespaces to validate. Empty validates all but the webhook namespace and kube-system.")
+	cmd.Flags().StringVar(&o.config, "config", "", "Path to the policy config file of the webhook. Empty uses the default configuration.")
+	cmd.Flags().StringVar(&o.failurePolicy, "failure-policy", "Fail", "Failure policy of the webhook configuration: Fail or Ignore.")
+	cmd.Flags().StringVar(&o.dir, "dir", ".", "Directory to write the manifests to.")
+	addOutputFlag(cmd, &o.format)
+
+	_ = cmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(webhook.ManifestFormats, cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.RegisterFlagCompletionFunc("failure-policy", cobra.FixedCompletions([]string{"Fail", "Ignore"}, cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.RegisterFlagCompletionFunc("namespaces", completeNamespaces(true))
+	_ = cmd.RegisterFlagCompletionFunc("namespace", completeNamespaces(false))
+	_ = cmd.MarkFlagFilename("config", "yaml", "yml")
+	_ = cmd.MarkFlagDirname("dir")
+	return cmd
+}
+
+// runGenManifests generates the installation manifests and writes them to the directory
+func runGenManifests(cmd *cobra.Command, o *genManifestsOptions) error {
+	if err := checkOutput(o.format); err != nil {
+		return err
+	}
+	var cfg []byte
+	if o.config != "" {
+		var err error
+		if cfg, err = os.ReadFile(o.config); err != nil {
+			return err
+		}
+	}
+	files, err := webhook.GenerateManifests(webhook.ManifestOptions{
+		Format:        o.manifestFormat,
+		Namespace:     o.namespace,
+		Image:         o.image,
+		Namespaces:    splitList(o.namespaces),
+		Config:        cfg,
+		FailurePolicy: o.failurePolicy,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to generate manifests: %w", err)
+	}
+	result := initResult{}
+	for _, f := range files {
+		path := filepath.Join(o.dir, f.Name)
+		// the manifests are filled with the private key of the webhook, often in place
+		if err := os.WriteFile(path, f.Data, 0o600); err != nil {
+			return err
+		}
+		result.Files = append(result.Files, path)
+	}
+	return writeOutput(cmd.OutOrStdout(), o.format, result, func(w io.Writer) {
+		for _, f := range result.Files {
+			fmt.Fprintf(w, "Wrote %s\n", f)
+		}
+	})
+}
@@ -0,0 +1,42 @@
+//go:build failureinjection
+
+package main
+
+import (
+	"flag"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	log "github.com/gookit/slog"
+)
+
+// flags to degrade the webhook on purpose, only available in binaries built with the failureinjection tag
+var (
+	injectLatency   = flag.Duration("inject-latency", 0, "Delay added to each admission request, e.g. 2s. For resilience tests only.")
+	injectErrorRate = flag.Float64("inject-error-rate", 0, "Ratio of admission requests answered with HTTP 500, between 0 and 1. For resilience tests only.")
+)
+
+// injectFailures wraps the handler of an admission endpoint and degrades it according to the inject flags. The
+// decision, evaluate and admin APIs served next to the endpoints are not affected.
+func injectFailures(path string, next http.Handler) http.Handler {
+	if *injectLatency <= 0 && *injectErrorRate <= 0 {
+		return next
+	}
+	log.Warnf("Failure injection enabled on %s: latency=%v, error rate=%v", path, *injectLatency, *injectErrorRate)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if *injectLatency > 0 {
+			select {
+			case <-time.After(*injectLatency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if *injectErrorRate > 0 && rand.Float64() < *injectErrorRate { //nolint:gosec // no crypto use
+			log.Debugf("Injecting error for request %s", r.URL.Path)
+			http.Error(w, "injected failure", http.StatusInternalServerError)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
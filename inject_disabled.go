@@ -0,0 +1,10 @@
+//go:build !failureinjection
+
+package main
+
+import "net/http"
+
+// injectFailures is a no-op unless the binary is built with the failureinjection tag
+func injectFailures(_ string, next http.Handler) http.Handler {
+	return next
+}
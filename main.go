@@ -16,6 +16,7 @@ import (
 	"github.com/eumel8/cosignwebhook/webhook"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spf13/cobra"
 )
 
 const (
@@ -25,17 +26,52 @@ const (
 	timeout     = 10 * time.Second
 )
 
-var tlscert, tlskey string
-
 func main() {
-	// parse arguments
-	flag.StringVar(&tlscert, "tlsCertFile", "/etc/certs/tls.crt", "File containing the x509 Certificate for HTTPS.")
-	flag.StringVar(&tlskey, "tlsKeyFile", "/etc/certs/tls.key", "File containing the x509 private key to --tlsCertFile.")
-	logLevel := flag.String("logLevel", "info", "loglevel of app, e.g info, debug, warn, error, fatal")
-	flag.Parse()
+	// server flags, the values are layered over config file and env by webhook.LoadEffectiveConfig
+	defaults := webhook.DefaultServerConfig()
+	flag.String(webhook.TLSCertFileFlag, defaults.TLSCertFile, "File containing the x509 Certificate for HTTPS.")
+	flag.String(webhook.TLSKeyFileFlag, defaults.TLSKeyFile, "File containing the x509 private key to --tlsCertFile.")
+	flag.String(webhook.ConfigFlag, "", "YAML file configuring the admission endpoints and their rules. Defaults to the embedded defaults on /validate.")
+	flag.String(webhook.LogLevelFlag, defaults.LogLevel, "loglevel of app, e.g info, debug, warn, error, fatal")
+	flag.String(webhook.SemanticsFlag, defaults.Semantics, "Decision semantics version, the previous version keeps the verdicts of the last release.")
+	flag.String(webhook.ShadowSemanticsFlag, "", "Semantics version evaluated in shadow mode, comparing its verdicts without enforcing them.")
+	flag.String(webhook.CrashReportDirFlag, "", "Directory crash reports are written to on fatal errors, e.g. a mounted volume.")
+	flag.String(webhook.MaxInflightFlag, "", "Maximum of admission requests evaluated at the same time, 0 for no limit.")
+	flag.String(webhook.OverflowFlag, defaults.Overflow, "Behavior for admission requests over the maximum: queue, allow or deny.")
+	flag.String(webhook.OverflowDeadlineFlag, defaults.OverflowDeadline, "How long a request waits for a free slot with overflow queue.")
+	flag.String(webhook.UnparseableFlag, defaults.Unparseable, "Behavior for admission requests with objects the webhook can't decode: allow or deny.")
+	flag.String(webhook.OnErrorFlag, defaults.OnError, "Behavior for admission requests if a rule fails with an internal error: deny, allow or ignore-rule.")
+	flag.String(webhook.OnErrorOverridesFlag, defaults.OnErrorOverrides, "Comma separated rule=behavior overriding onError by rule, e.g. licenses=ignore-rule.")
+	flag.String(webhook.WebhookConfigurationFlag, "", "ValidatingWebhookConfiguration the serving certificate is verified against on boot, empty disables it.")
+	flag.String(webhook.ExemptNamespacesFlag, "", "Comma separated namespaces which are never denied, e.g. kube-system,cert-manager.")
+	flag.String(webhook.ProxyFlag, "", "Proxy URL for external calls, empty uses HTTP_PROXY, HTTPS_PROXY and NO_PROXY.")
+	flag.String(webhook.NoProxyFlag, "", "Comma separated hosts, domains and CIDRs reached without --proxy.")
+	flag.String(webhook.ProxyOverridesFlag, "", "Comma separated host=proxy URL or host=direct, overriding the proxy by destination.")
+	flag.String(webhook.CABundleFlag, "", "PEM file of CAs trusted for external calls in addition to the system CAs.")
+	flag.String(webhook.TargetInflightFlag, "", "Admission requests in flight at which the replica reports not ready on /readyz, 0 disables it.")
+
+	root := newRootCommand()
+	root.SetArgs(normalizeArgs(root, os.Args[1:]))
+	if err := root.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// serve runs the webhook server until it gets a shutdown signal
+func serve(cmd *cobra.Command, _ []string) {
+	flags := setFlags(cmd)
+	cfg, _, err := webhook.LoadEffectiveConfig(flags, os.Getenv)
+	if err != nil {
+		// the config file may be broken, the crash report dir is only taken from flag and env
+		dir, ok := flags[webhook.CrashReportDirFlag]
+		if !ok {
+			dir = os.Getenv(webhook.CrashReportDirEnv)
+		}
+		fatalf(dir, nil, "Failed to load config: %v", err)
+	}
 
 	// set log level
-	switch *logLevel {
+	switch cfg.Server.LogLevel {
 	case "fatal":
 		log.SetLogLevel(log.FatalLevel)
 	case "trace":
@@ -54,7 +90,7 @@ func main() {
 
 	log.GetFormatter().(*log.TextFormatter).SetTemplate(logTemplate)
 
-	certs, err := tls.LoadX509KeyPair(tlscert, tlskey)
+	certs, err := webhook.NewCertReloader(cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile)
 	if err != nil {
 		log.Errorf("failed to load key pair: %v", err)
 	}
@@ -62,8 +98,8 @@ func main() {
 	server := &http.Server{
 		Addr: fmt.Sprintf(":%v", port),
 		TLSConfig: &tls.Config{
-			Certificates: []tls.Certificate{certs},
-			MinVersion:   tls.VersionTLS12,
+			GetCertificate: certs.GetCertificate,
+			MinVersion:     tls.VersionTLS12,
 		},
 		ReadHeaderTimeout: timeout,
 	}
@@ -74,13 +110,45 @@ func main() {
 	}
 
 	// define http server and server handler
-	cs := webhook.NewCosignServerHandler()
+	cs := webhook.NewCosignServerHandler(cfg)
+	defer func() {
+		if r := recover(); r != nil {
+			fatalf(cfg.Server.CrashReportDir, cs, "panic: %v", r)
+		}
+	}()
+	endpoints, err := cs.Endpoints()
+	if err != nil {
+		fatalf(cfg.Server.CrashReportDir, cs, "Failed to create endpoints: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cs.Start(ctx)
+	cs.VerifyTrust(ctx, certs.Leaf())
+
 	mux := http.NewServeMux()
-	mux.HandleFunc("/validate", cs.Serve)
+	for _, e := range endpoints {
+		log.Infof("Serving admission endpoint %s", e.Path)
+		mux.Handle(e.Path, injectFailures(e.Path, e))
+	}
+	if cfg.Decisions.Stream {
+		log.Infof("Serving decision stream %s and %s", webhook.DecisionStreamPath, webhook.DecisionPath)
+		mux.HandleFunc(webhook.DecisionStreamPath, cs.DecisionStream)
+		mux.HandleFunc(webhook.DecisionPath, cs.Decision)
+	}
+	if cfg.Evaluate.Enabled {
+		log.Infof("Serving evaluate API %s", webhook.EvaluatePath)
+		mux.HandleFunc(webhook.EvaluatePath, cs.Evaluate)
+	}
+	if cfg.Admin.Enabled {
+		log.Infof("Serving admin API %s", webhook.FlushCachePath)
+		mux.HandleFunc(webhook.FlushCachePath, cs.FlushCache)
+	}
 	server.Handler = mux
 
 	mmux := http.NewServeMux()
 	mmux.HandleFunc("/healthz", cs.Healthz)
+	mmux.HandleFunc("/readyz", cs.Readyz)
+	mmux.HandleFunc(webhook.OpenAPIPath, webhook.ServeOpenAPI)
 	mmux.Handle("/metrics", promhttp.Handler())
 	mserver.Handler = mmux
 
@@ -106,3 +174,16 @@ func main() {
 	_ = server.Shutdown(context.Background())
 	_ = mserver.Shutdown(context.Background())
 }
+
+// fatalf writes a crash report to dir, logs the fatal error and exits. cs may be nil before the handler is created.
+// The error isn't logged with log.Fatal, it exits before the crash report is written.
+func fatalf(dir string, cs *webhook.CosignServerHandler, format string, args ...any) {
+	reason := fmt.Sprintf(format, args...)
+	if path, err := webhook.WriteCrashReport(dir, reason, cs); err != nil {
+		log.Errorf("Can't write crash report: %v", err)
+	} else if path != "" {
+		log.Infof("Wrote crash report %s", path)
+	}
+	log.Error(reason)
+	os.Exit(1)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/eumel8/cosignwebhook/webhook"
+
+	"github.com/spf13/cobra"
+)
+
+// onboardOptions are the flags of the onboard command
+type onboardOptions struct {
+	webhook.OnboardOptions
+	format string
+}
+
+// onboardResult is the output of the onboard command
+type onboardResult struct {
+	Namespaces []webhook.OnboardedNamespace `json:"namespaces"`
+}
+
+// newOnboardCommand returns the command onboarding the namespaces of a tenant
+func newOnboardCommand() *cobra.Command {
+	o := &onboardOptions{}
+	cmd := &cobra.Command{
+		Use:   "onboard",
+		Short: "Onboard the namespaces of a tenant with a policy preset",
+		Long: `Onboard all namespaces matching --namespace-selector in one step with the current kubeconfig context:
+sets the namespace labels of the preset and checks that the ValidatingWebhookConfiguration selects the labeled
+namespaces. Prints a summary and fails if a namespace couldn't be labeled or isn't validated by the webhook.
+
+Presets:
+  baseline    enforces the baseline Pod Security Standard and warns about the restricted one
+  restricted  enforces the restricted Pod Security Standard and requires images referenced by digest`,
+		Example: `  cosignwebhook onboard --namespace-selector team=foo --preset restricted
+  cosignwebhook onboard --namespace-selector team=foo --preset baseline --dry-run -o json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := checkOutput(o.format); err != nil {
+				return err
+			}
+			cs, err := kubeClient()
+			if err != nil {
+				return fmt.Errorf("can't connect to cluster: %w", err)
+			}
+			namespaces, err := webhook.Onboard(cmd.Context(), cs, o.OnboardOptions)
+			if err != nil {
+				return err
+			}
+			result := onboardResult{Namespaces: namespaces}
+			var changed, failed, unvalidated int
+			for _, ns := range namespaces {
+				if len(ns.Changes) > 0 && !ns.Failed {
+					changed++
+				}
+				if ns.Failed {
+					failed++
+				}
+				if !ns.Validated {
+					unvalidated++
+				}
+			}
+			err = writeOutput(cmd.OutOrStdout(), o.format, result, func(w io.Writer) {
+				fmt.Fprintln(w, "NAMESPACE\tLABEL\tCHANGE\tMESSAGE")
+				for _, ns := range namespaces {
+					if len(ns.Changes) == 0 {
+						fmt.Fprintf(w, "%s\t\t\t%s\n", ns.Namespace, ns.Message)
+						continue
+					}
+					for i, c := range ns.Changes {
+						from := c.From
+						if from == "" {
+							from = "<none>"
+						}
+						name, message := "", ""
+						if i == 0 {
+							name, message = ns.Namespace, ns.Message
+						}
+						fmt.Fprintf(w, "%s\t%s\t%s -> %s\t%s\n", name, c.Label, from, c.To, message)
+					}
+				}
+				fmt.Fprintf(w, "\n%d namespaces matched, %d labeled, %d failed, %d not validated by the webhook\n",
+					len(namespaces), changed, failed, unvalidated)
+			})
+			if err != nil {
+				return err
+			}
+			switch {
+			case len(namespaces) == 0:
+				return fmt.Errorf("no namespace matches %s", o.NamespaceSelector)
+			case failed > 0:
+				return fmt.Errorf("%d namespaces couldn't be labeled", failed)
+			case unvalidated > 0:
+				return fmt.Errorf("%d namespaces aren't validated by the webhook", unvalidated)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&o.NamespaceSelector, "namespace-selector", "", "Label selector of the namespaces to onboard, e.g. team=foo.")
+	cmd.Flags().StringVar(&o.Preset, "preset", webhook.PresetRestricted, "Policy preset of the namespaces: baseline or restricted.")
+	cmd.Flags().StringVar(&o.WebhookConfiguration, "webhook-configuration", "cosignwebhook", "Name of the ValidatingWebhookConfiguration of the webhook. Empty skips the check whether the namespaces are validated.")
+	cmd.Flags().BoolVar(&o.DryRun, "dry-run", false, "Print the changes without applying them.")
+	addOutputFlag(cmd, &o.format)
+
+	_ = cmd.MarkFlagRequired("namespace-selector")
+	_ = cmd.RegisterFlagCompletionFunc("preset", cobra.FixedCompletions(webhook.OnboardPresets(), cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
@@ -0,0 +1,129 @@
+// Command fakeserver is a fake external service for the e2e tests, e.g. a registry or a scanner feed. It answers
+// requests with the responses programmed by the test framework on /_fake/responses and records the requests it
+// received for assertions on /_fake/requests.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// response is a programmed response, the JSON contract of framework.FakeResponse
+type response struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers"`
+	Body    []byte            `json:"body"`
+	// Delay delays the response, e.g. to test timeouts
+	Delay time.Duration `json:"delay"`
+}
+
+// request is a received request, the JSON contract of framework.FakeRequest
+type request struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+}
+
+// server answers the programmed responses by "METHOD /path" or "/path" for all methods
+type server struct {
+	mu        sync.Mutex
+	responses map[string]response
+	requests  []request
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/_fake/responses":
+		s.program(w, r)
+		return
+	case "/_fake/requests":
+		s.received(w, r)
+		return
+	case "/_fake/healthz":
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, request{Method: r.Method, Path: r.URL.Path})
+	res, ok := s.responses[r.Method+" "+r.URL.Path]
+	if !ok {
+		res, ok = s.responses[r.URL.Path]
+	}
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if res.Delay > 0 {
+		select {
+		case <-time.After(res.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	for k, v := range res.Headers {
+		w.Header().Set(k, v)
+	}
+	if res.Status == 0 {
+		res.Status = http.StatusOK
+	}
+	w.WriteHeader(res.Status)
+	if r.Method != http.MethodHead {
+		if _, err := w.Write(res.Body); err != nil {
+			log.Printf("can't write response: %v", err)
+		}
+	}
+}
+
+// program replaces the responses with PUT and adds them with POST
+func (s *server) program(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut && r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	responses := map[string]response{}
+	if err := json.NewDecoder(r.Body).Decode(&responses); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Method == http.MethodPut || s.responses == nil {
+		s.responses = map[string]response{}
+	}
+	for k, v := range responses {
+		s.responses[k] = v
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// received returns the received requests, DELETE clears them
+func (s *server) received(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Method == http.MethodDelete {
+		s.requests = nil
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	requests := s.requests
+	if requests == nil {
+		requests = []request{}
+	}
+	if err := json.NewEncoder(w).Encode(requests); err != nil {
+		log.Printf("can't write requests: %v", err)
+	}
+}
+
+func main() {
+	addr := flag.String("addr", ":8080", "listen address")
+	flag.Parse()
+	srv := &http.Server{Addr: *addr, Handler: &server{}, ReadHeaderTimeout: 10 * time.Second}
+	log.Printf("fake server listening on %s", *addr)
+	log.Fatal(srv.ListenAndServe())
+}
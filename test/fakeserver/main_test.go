@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	ts := httptest.NewServer(&server{})
+	defer ts.Close()
+
+	do := func(method, path, body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { res.Body.Close() })
+		return res
+	}
+
+	if res := do(http.MethodGet, "/feed", ""); res.StatusCode != http.StatusNotFound {
+		t.Errorf("unprogrammed path status = %d, want 404", res.StatusCode)
+	}
+	if res := do(http.MethodPut, "/_fake/responses", `{"/feed":{"body":"c2hhMjU2OmFiYw=="},"HEAD /feed":{"status":503}}`); res.StatusCode != http.StatusNoContent {
+		t.Fatalf("programming status = %d", res.StatusCode)
+	}
+	if res := do(http.MethodPost, "/_fake/responses", `{"/v2/":{"headers":{"Docker-Distribution-API-Version":"registry/2.0"}}}`); res.StatusCode != http.StatusNoContent {
+		t.Fatalf("adding responses status = %d", res.StatusCode)
+	}
+
+	res := do(http.MethodGet, "/feed", "")
+	if body, _ := io.ReadAll(res.Body); res.StatusCode != http.StatusOK || string(body) != "sha256:abc" {
+		t.Errorf("GET /feed = %d %q, want 200 sha256:abc", res.StatusCode, body)
+	}
+	if res := do(http.MethodHead, "/feed", ""); res.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("HEAD /feed status = %d, want 503", res.StatusCode)
+	}
+	if res := do(http.MethodGet, "/v2/", ""); res.Header.Get("Docker-Distribution-API-Version") != "registry/2.0" {
+		t.Errorf("GET /v2/ headers = %v", res.Header)
+	}
+
+	var requests []request
+	if err := json.NewDecoder(do(http.MethodGet, "/_fake/requests", "").Body).Decode(&requests); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 4 || requests[0] != (request{Method: http.MethodGet, Path: "/feed"}) {
+		t.Errorf("requests = %v, want 4 starting with GET /feed", requests)
+	}
+	do(http.MethodDelete, "/_fake/requests", "")
+	requests = nil
+	if err := json.NewDecoder(do(http.MethodGet, "/_fake/requests", "").Body).Decode(&requests); err != nil || len(requests) != 0 {
+		t.Errorf("requests after reset = %v, %v", requests, err)
+	}
+}
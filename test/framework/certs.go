@@ -0,0 +1,222 @@
+package framework
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// reloadedLog is logged by the webhook when it serves a rotated certificate
+const reloadedLog = "Reloaded serving certificate"
+
+// AdmissionLoad is the result of admission requests sent during a test
+type AdmissionLoad struct {
+	Total  int
+	Failed int
+	// Errors are the distinct errors of the failed requests
+	Errors []string
+}
+
+// StartAdmissionLoad creates the pod with dry run in a loop, so each request is admitted by the webhook
+// without creating the pod. The returned func stops the load and returns its result.
+func (f *Framework) StartAdmissionLoad(p corev1.Pod) func() AdmissionLoad {
+	ctx, cancel := context.WithCancel(context.Background())
+	var (
+		wg     sync.WaitGroup
+		result AdmissionLoad
+		seen   = map[string]bool{}
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for ctx.Err() == nil {
+			_, err := f.k8s.CoreV1().Pods(p.Namespace).Create(ctx, &p, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}})
+			if ctx.Err() != nil {
+				return
+			}
+			result.Total++
+			if err != nil {
+				result.Failed++
+				if !seen[err.Error()] {
+					seen[err.Error()] = true
+					result.Errors = append(result.Errors, err.Error())
+				}
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}()
+	f.t.Logf("started admission load with pod %s", p.Name)
+	return func() AdmissionLoad {
+		cancel()
+		wg.Wait()
+		f.t.Logf("stopped admission load after %d requests, %d failed", result.Total, result.Failed)
+		return result
+	}
+}
+
+// WaitForAdmissions waits until n dry-run admissions of the pod in a row were allowed, e.g. to keep an admission load
+// running until the API server verified the webhook certificate with the current caBundle
+func (f *Framework) WaitForAdmissions(p corev1.Pod, n int) {
+	allowed := 0
+	f.pollUntil(f.helperTimeout(nil), 500*time.Millisecond, fmt.Sprintf("%d admissions of pod %s in a row", n, p.Name), func(ctx context.Context) (bool, error) {
+		if v := f.admissionVerdict(ctx, &p); v != allowedVerdict {
+			f.t.Logf("admission of pod %s: %s", p.Name, v)
+			allowed = 0
+			return false, nil
+		}
+		allowed++
+		return allowed >= n, nil
+	})
+}
+
+// allowedVerdict is the verdict of admissions which were allowed
+const allowedVerdict = "allowed"
+
+// admissionVerdict creates the pod with dry run and returns the error of its admission, or allowedVerdict
+func (f *Framework) admissionVerdict(ctx context.Context, p *corev1.Pod) string {
+	if _, err := f.k8s.CoreV1().Pods(p.Namespace).Create(ctx, p, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}}); err != nil {
+		return err.Error()
+	}
+	return allowedVerdict
+}
+
+// RotateWebhookCert rotates the serving certificate of the webhook deployed by the chart to a new CA without
+// downtime: the new CA is added to the caBundle, the Secret is updated, and after all webhook pods reloaded the
+// certificate, the old CA is removed from the caBundle.
+func (f *Framework) RotateWebhookCert(namespace, name string) {
+	if f.err != nil {
+		return
+	}
+	ctx := context.Background()
+	ca, cert, key, err := createServingCert(fmt.Sprintf("%s.%s.svc", name, namespace))
+	if err != nil {
+		f.err = err
+		return
+	}
+
+	f.t.Logf("adding the new CA to the caBundle of %s", name)
+	vwcs := f.k8s.AdmissionregistrationV1().ValidatingWebhookConfigurations()
+	vwc, err := vwcs.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		f.err = err
+		return
+	}
+	for i := range vwc.Webhooks {
+		vwc.Webhooks[i].ClientConfig.CABundle = append(bytes.Clone(vwc.Webhooks[i].ClientConfig.CABundle), ca...)
+	}
+	if vwc, err = vwcs.Update(ctx, vwc, metav1.UpdateOptions{}); err != nil {
+		f.err = err
+		return
+	}
+
+	f.t.Logf("updating the serving certificate in secret %s", name)
+	rotated := metav1.Now()
+	secret, err := f.k8s.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		f.err = err
+		return
+	}
+	secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey] = cert, key
+	if _, err = f.k8s.CoreV1().Secrets(namespace).Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		f.err = err
+		return
+	}
+	f.waitForCertReload(namespace, name, rotated)
+	if f.err != nil {
+		return
+	}
+
+	f.t.Logf("removing the old CA from the caBundle of %s", name)
+	for i := range vwc.Webhooks {
+		vwc.Webhooks[i].ClientConfig.CABundle = ca
+	}
+	if _, err = vwcs.Update(ctx, vwc, metav1.UpdateOptions{}); err != nil {
+		f.err = err
+	}
+}
+
+// waitForCertReload waits until all webhook pods logged the reload of the certificate since passed time.
+// The kubelet updates mounted Secrets with a delay of up to a minute.
+func (f *Framework) waitForCertReload(namespace, name string, since metav1.Time) {
+	var reloaded, total int
+	f.pollUntil(3*time.Minute, 2*time.Second, "the webhook to reload the certificate", func(ctx context.Context) (bool, error) {
+		pods, err := f.k8s.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: fmt.Sprintf("app.kubernetes.io/name=%s", name),
+		})
+		if err != nil {
+			return false, err
+		}
+		reloaded, total = 0, len(pods.Items)
+		for _, p := range pods.Items {
+			logs, err := f.k8s.CoreV1().Pods(namespace).GetLogs(p.Name, &corev1.PodLogOptions{SinceTime: &since}).DoRaw(ctx)
+			if err == nil && strings.Contains(string(logs), reloadedLog) {
+				reloaded++
+			}
+		}
+		return total > 0 && reloaded == total, nil
+	})
+	if f.err != nil {
+		f.err = fmt.Errorf("%w, %d of %d pods reloaded", f.err, reloaded, total)
+		return
+	}
+	f.t.Logf("all %d webhook pods reloaded the certificate", reloaded)
+}
+
+// createServingCert creates a CA and a serving certificate for the DNS name signed by it, all PEM encoded
+func createServingCert(dnsName string) (ca, cert, key []byte, err error) {
+	notBefore := time.Now().Add(-time.Minute)
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(notBefore.UnixNano()),
+		Subject:               pkix.Name{CommonName: "cosign-webhook-e2e-ca"},
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	serverKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	serverDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(notBefore.UnixNano() + 1),
+		Subject:      pkix.Name{CommonName: dnsName},
+		DNSNames:     []string{dnsName},
+		NotBefore:    notBefore,
+		NotAfter:     notBefore.Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, caTemplate, &serverKey.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(serverKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: serverDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		nil
+}
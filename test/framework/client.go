@@ -20,32 +20,57 @@ type Framework struct {
 	k8s *kubernetes.Clientset
 	t   *testing.T
 	err error
+	// fakes are the fake services deployed by the test
+	fakes []*FakeService
+	// context is the kubeconfig context the helpers are directed at, empty for the current context
+	context string
+	// clusters are the frameworks by kubeconfig context, shared by all of them
+	clusters map[string]*Framework
+	// timeout of the helpers, DefaultTimeout if zero
+	timeout time.Duration
+	// timeoutScale multiplies all timeouts, see TimeoutScaleEnv
+	timeoutScale float64
 }
 
 // New creates a new Framework
-func New(t *testing.T) (*Framework, error) {
+func New(t *testing.T, opts ...Option) (*Framework, error) {
 	if t == nil {
 		return nil, fmt.Errorf("test object must not be nil")
 	}
 
-	k8s, err := createClientSet()
+	k8s, err := createClientSet("")
+	if err != nil {
+		return nil, err
+	}
+	scale, err := timeoutScale()
 	if err != nil {
 		return nil, err
 	}
 
-	return &Framework{
-		k8s: k8s,
-		t:   t,
-	}, nil
+	f := &Framework{
+		k8s:          k8s,
+		t:            t,
+		timeout:      DefaultTimeout,
+		timeoutScale: scale,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	f.clusters = map[string]*Framework{"": f}
+	return f, nil
 }
 
-func createClientSet() (k8sClient *kubernetes.Clientset, err error) {
+// createClientSet creates a clientset for the kubeconfig context, the current context if empty
+func createClientSet(context string) (k8sClient *kubernetes.Clientset, err error) {
 	kubeconfig := os.Getenv("KUBECONFIG")
 	if kubeconfig == "" {
 		kubeconfig = os.Getenv("HOME") + "/.kube/config"
 	}
 
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfig},
+		&clientcmd.ConfigOverrides{CurrentContext: context},
+	).ClientConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -57,12 +82,18 @@ func createClientSet() (k8sClient *kubernetes.Clientset, err error) {
 	return cs, nil
 }
 
-// Cleanup removes all resources created by the framework
+// Cleanup removes all resources created by the framework in all clusters
 // and cleans up the testing directory.
 func (f *Framework) Cleanup() {
 	f.cleanupKeys()
-	f.cleanupDeployments()
-	f.cleanupSecrets()
+	for _, c := range f.sortedClusters() {
+		c.cleanupFakeServices()
+		c.cleanupDeployments()
+		c.cleanupSecrets()
+		if c != f && c.err != nil && f.err == nil {
+			f.err = fmt.Errorf("cluster %s: %w", c.context, c.err)
+		}
+	}
 	if f.err != nil {
 		f.t.Fatal(f.err)
 	}
@@ -89,25 +120,21 @@ func (f *Framework) cleanupDeployments() {
 		}
 	}
 
-	timeout := time.After(30 * time.Second)
-	for {
-		select {
-		case <-timeout:
-			f.err = fmt.Errorf("timeout reached while waiting for deployments to be deleted")
-		default:
-			pods, err := f.k8s.CoreV1().Pods("test-cases").List(context.Background(), metav1.ListOptions{})
-			if err != nil {
-				f.err = err
-				return
-			}
-
-			if len(pods.Items) == 0 {
-				f.t.Logf("All pods are deleted")
-				return
-			}
-			time.Sleep(500 * time.Millisecond)
+	// wait even if the test failed, so the pods don't leak into the next test
+	ctx, cancel := context.WithTimeout(context.Background(), f.scaled(f.helperTimeout(nil)))
+	defer cancel()
+	err = PollUntil(ctx, 500*time.Millisecond, func(ctx context.Context) (bool, error) {
+		pods, err := f.k8s.CoreV1().Pods("test-cases").List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return false, err
 		}
+		return len(pods.Items) == 0, nil
+	})
+	if err != nil {
+		f.err = fmt.Errorf("failed waiting for deployments to be deleted: %w", err)
+		return
 	}
+	f.t.Logf("All pods are deleted")
 }
 
 // cleanupSecrets removes all secrets from the testing namespace
@@ -182,156 +209,105 @@ func (f *Framework) CreateSecret(s corev1.Secret) {
 	f.t.Logf("secret %s created", s.Name)
 }
 
-// WaitForDeployment waits until the deployment is ready
-func (f *Framework) WaitForDeployment(d appsv1.Deployment) {
+// WaitForDeployment waits until the deployment is ready, within the timeout of the framework unless overridden
+func (f *Framework) WaitForDeployment(d appsv1.Deployment, timeout ...time.Duration) {
 	if f.err != nil {
 		return
 	}
 
 	f.t.Logf("waiting for deployment %s to be ready", d.Name)
-	// wait until the deployment is ready
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-	w, err := f.k8s.AppsV1().Deployments(d.Namespace).Watch(ctx, metav1.ListOptions{
-		FieldSelector: fmt.Sprintf("metadata.name=%s", d.Name),
-	})
-	if err != nil {
-		f.err = err
-		return
-	}
-
-	for {
-		select {
-		case <-ctx.Done():
-			f.err = fmt.Errorf("timeout reached while waiting for deployment to be ready")
-		case event := <-w.ResultChan():
-			deployment, ok := event.Object.(*appsv1.Deployment)
-			if !ok {
-				time.Sleep(500 * time.Millisecond)
-				continue
-			}
-
-			if deployment.Status.ReadyReplicas == 1 {
-				f.t.Logf("deployment %s is ready", d.Name)
-				return
-			}
-			time.Sleep(500 * time.Millisecond)
+	f.pollUntil(f.helperTimeout(timeout), 500*time.Millisecond, fmt.Sprintf("deployment %s to be ready", d.Name), func(ctx context.Context) (bool, error) {
+		deployment, err := f.k8s.AppsV1().Deployments(d.Namespace).Get(ctx, d.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		replicas := int32(1)
+		if deployment.Spec.Replicas != nil {
+			replicas = *deployment.Spec.Replicas
 		}
+		return deployment.Status.ReadyReplicas >= replicas, nil
+	})
+	if f.err == nil {
+		f.t.Logf("deployment %s is ready", d.Name)
 	}
 }
 
 // waitForReplicaSetCreation waits for the replicaset of the given deployment to be created
-func (f *Framework) waitForReplicaSetCreation(d appsv1.Deployment) string {
+func (f *Framework) waitForReplicaSetCreation(d appsv1.Deployment, timeout time.Duration) string {
 	if f.err != nil {
 		return ""
 	}
 
-	rs, err := f.k8s.AppsV1().ReplicaSets(d.Namespace).Watch(context.Background(), metav1.ListOptions{
-		LabelSelector: fmt.Sprintf("app=%s", d.Name),
-	})
-	if err != nil {
-		f.err = err
-		return ""
-	}
-
-	ctx, done := context.WithTimeout(context.Background(), 30*time.Second)
-	defer done()
-
-	for {
-		select {
-		case <-ctx.Done():
-			f.err = fmt.Errorf("timeout reached while waiting for replicaset to be created")
-		case event := <-rs.ResultChan():
-			rs, ok := event.Object.(*appsv1.ReplicaSet)
-			if ok {
-				f.t.Logf("replicaset %s created", rs.Name)
-				return rs.Name
-			}
-			time.Sleep(500 * time.Millisecond)
+	var name string
+	f.pollUntil(timeout, 500*time.Millisecond, fmt.Sprintf("replicaset of deployment %s to be created", d.Name), func(ctx context.Context) (bool, error) {
+		rs, err := f.k8s.AppsV1().ReplicaSets(d.Namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: fmt.Sprintf("app=%s", d.Name),
+		})
+		if err != nil || len(rs.Items) == 0 {
+			return false, err
 		}
+		name = rs.Items[0].Name
+		return true, nil
+	})
+	if name != "" {
+		f.t.Logf("replicaset %s created", name)
 	}
+	return name
 }
 
-// AssertDeploymentFailed asserts that the deployment cannot start
-func (f *Framework) AssertDeploymentFailed(d appsv1.Deployment) {
+// AssertDeploymentFailed asserts that the deployment cannot start, within the timeout of the framework unless
+// overridden
+func (f *Framework) AssertDeploymentFailed(d appsv1.Deployment, timeout ...time.Duration) {
 	if f.err != nil {
 		return
 	}
 
 	f.t.Logf("waiting for deployment %s to fail", d.Name)
 
-	// watch for replicasets of the deployment
-	rsName := f.waitForReplicaSetCreation(d)
+	// wait for the replicaset of the deployment
+	rsName := f.waitForReplicaSetCreation(d, f.helperTimeout(timeout))
 	if rsName == "" {
 		return
 	}
 
 	// get warning events of deployment's namespace and check if the deployment failed
-	w, err := f.k8s.CoreV1().Events(d.Namespace).Watch(context.Background(), metav1.ListOptions{
-		FieldSelector: fmt.Sprintf("involvedObject.name=%s", rsName),
-	})
-	if err != nil {
-		f.err = err
-		return
-	}
-
-	ctx, done := context.WithTimeout(context.Background(), 30*time.Second)
-	defer done()
-
-	for {
-		select {
-		case <-ctx.Done():
-			f.err = fmt.Errorf("timeout reached while waiting for deployment to fail")
-		case event := <-w.ResultChan():
-			e, ok := event.Object.(*corev1.Event)
-			if !ok {
-				time.Sleep(500 * time.Millisecond)
-				continue
-			}
-			if e.Reason == "FailedCreate" {
-				f.t.Logf("deployment %s failed: %s", d.Name, e.Message)
-				return
-			}
-			time.Sleep(500 * time.Millisecond)
-		}
+	f.waitForEvent(d.Namespace, rsName, "FailedCreate", f.helperTimeout(timeout))
+	if f.err == nil {
+		f.t.Logf("deployment %s failed", d.Name)
 	}
 }
 
-// AssertEventForPod asserts that a PodVerified event is created
-func (f *Framework) AssertEventForPod(reason string, p corev1.Pod) {
+// AssertEventForPod asserts that an event with the reason, e.g. PodVerified, is created for the pod within the
+// timeout of the framework unless overridden
+func (f *Framework) AssertEventForPod(reason string, p corev1.Pod, timeout ...time.Duration) {
 	if f.err != nil {
 		return
 	}
 
 	f.t.Logf("waiting for %s event to be created for pod %s", reason, p.Name)
 
-	// watch for events of deployment's namespace and check if the podverified event is created
-	w, err := f.k8s.CoreV1().Events(p.Namespace).Watch(context.Background(), metav1.ListOptions{
-		FieldSelector: fmt.Sprintf("involvedObject.name=%s", p.Name),
-	})
-	if err != nil {
-		f.err = err
-		return
+	// check the events of the pod's namespace until the event is created
+	f.waitForEvent(p.Namespace, p.Name, reason, f.helperTimeout(timeout))
+	if f.err == nil {
+		f.t.Logf("%s event created for pod %s", reason, p.Name)
 	}
+}
 
-	ctx, done := context.WithTimeout(context.Background(), 30*time.Second)
-	defer done()
-
-	for {
-		select {
-		case <-ctx.Done():
-			f.err = fmt.Errorf("timeout reached while waiting for event to be created")
-		case event := <-w.ResultChan():
-			e, ok := event.Object.(*corev1.Event)
-			if !ok {
-				time.Sleep(500 * time.Millisecond)
-				continue
-			}
+// waitForEvent waits until an event with the reason is created for the object
+func (f *Framework) waitForEvent(namespace, name, reason string, timeout time.Duration) {
+	f.pollUntil(timeout, 500*time.Millisecond, fmt.Sprintf("%s event of %s", reason, name), func(ctx context.Context) (bool, error) {
+		events, err := f.k8s.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+			FieldSelector: fmt.Sprintf("involvedObject.name=%s", name),
+		})
+		if err != nil {
+			return false, err
+		}
+		for _, e := range events.Items {
 			if e.Reason == reason {
-				f.t.Logf("%s event created for pod %s", reason, p.Name)
-				return
+				f.t.Logf("%s event of %s: %s", reason, name, e.Message)
+				return true, nil
 			}
-			time.Sleep(500 * time.Millisecond)
 		}
-	}
+		return false, nil
+	})
 }
@@ -0,0 +1,53 @@
+package framework
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Cluster returns the framework directed at the cluster of the kubeconfig context, e.g. k3d-cosign-agent, to test
+// the webhook across clusters. The frameworks of all clusters share their Cleanup, an empty context returns the
+// framework of the current context. A context which can't be loaded fails the returned framework.
+func (f *Framework) Cluster(context string) *Framework {
+	if f.clusters == nil {
+		f.clusters = map[string]*Framework{f.context: f}
+	}
+	if c, ok := f.clusters[context]; ok {
+		return c
+	}
+	c := &Framework{
+		t:            f.t,
+		context:      context,
+		clusters:     f.clusters,
+		timeout:      f.timeout,
+		timeoutScale: f.timeoutScale,
+	}
+	k8s, err := createClientSet(context)
+	if err != nil {
+		c.err = fmt.Errorf("failed loading kubeconfig context %s: %w", context, err)
+	}
+	c.k8s = k8s
+	f.clusters[context] = c
+	f.t.Logf("using cluster of kubeconfig context %s", context)
+	return c
+}
+
+// Context returns the kubeconfig context the framework is directed at, empty for the current context
+func (f *Framework) Context() string {
+	return f.context
+}
+
+// sortedClusters returns the frameworks of all clusters ordered by context, the current context first
+func (f *Framework) sortedClusters() []*Framework {
+	if len(f.clusters) == 0 {
+		return []*Framework{f}
+	}
+	clusters := make([]*Framework, 0, len(f.clusters))
+	for _, c := range f.clusters {
+		clusters = append(clusters, c)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].context < clusters[j].context
+	})
+	return clusters
+}
@@ -0,0 +1,69 @@
+package framework
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+current-context: k3d-cosign-tests
+clusters:
+- name: tests
+  cluster:
+    server: https://tests.example:6443
+- name: agent
+  cluster:
+    server: https://agent.example:6443
+users:
+- name: admin
+  user:
+    token: secret
+contexts:
+- name: k3d-cosign-tests
+  context:
+    cluster: tests
+    user: admin
+- name: k3d-cosign-agent
+  context:
+    cluster: agent
+    user: admin
+`
+
+func TestFramework_Cluster(t *testing.T) {
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(kubeconfig, []byte(testKubeconfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KUBECONFIG", kubeconfig)
+
+	f, err := New(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.k8s.CoreV1().RESTClient().Get().URL().Host; got != "tests.example:6443" {
+		t.Errorf("current context host = %s", got)
+	}
+	if f.Cluster("") != f {
+		t.Error("empty context doesn't return the framework of the current context")
+	}
+
+	agent := f.Cluster("k3d-cosign-agent")
+	if agent.err != nil {
+		t.Fatal(agent.err)
+	}
+	if got := agent.k8s.CoreV1().RESTClient().Get().URL().Host; got != "agent.example:6443" {
+		t.Errorf("agent context host = %s", got)
+	}
+	if agent.Context() != "k3d-cosign-agent" || f.Cluster("k3d-cosign-agent") != agent || agent.Cluster("") != f {
+		t.Error("clusters aren't shared between the frameworks")
+	}
+
+	if missing := f.Cluster("missing"); missing.err == nil {
+		t.Error("missing context doesn't fail the framework")
+	}
+	if got := len(f.sortedClusters()); got != 3 || f.sortedClusters()[0] != f {
+		t.Errorf("sorted clusters = %d, want 3 with the current context first", got)
+	}
+}
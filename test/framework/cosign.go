@@ -9,7 +9,6 @@ import (
 	"fmt"
 	"os"
 	"regexp"
-	"time"
 
 	"github.com/sigstore/cosign/v2/cmd/cosign/cli/importkeypair"
 	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
@@ -197,7 +196,7 @@ func (f *Framework) SignContainer(opts SignOptions) {
 	}
 	err := sign.SignCmd(
 		&options.RootOptions{
-			Timeout: 30 * time.Second,
+			Timeout: f.scaled(f.helperTimeout(nil)),
 		},
 		options.KeyOpts{
 			KeyRef: opts.KeyPath,
@@ -0,0 +1,86 @@
+package framework
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// ServerSetFields are the fields the API server sets on every object, to be ignored when comparing an object read
+// from the cluster with the one the test created, e.g. AssertObjectMatches(t, got, want, ServerSetFields...)
+var ServerSetFields = []string{
+	"metadata.uid",
+	"metadata.resourceVersion",
+	"metadata.generation",
+	"metadata.creationTimestamp",
+	"metadata.managedFields",
+	"status",
+}
+
+// AssertObjectMatches fails the test with a diff if got doesn't match want. Fields are ignored by their path of json
+// names, e.g. metadata.annotations or spec.template.spec.containers.image, where slice indexes are left out so the
+// field of all elements is ignored. Fields without json name are named as in Go. Nil and empty slices and maps are
+// equal, quantities are compared by value. Returns whether the objects match.
+func (f *Framework) AssertObjectMatches(t *testing.T, got, want any, ignoreFields ...string) bool {
+	t.Helper()
+	if f.err != nil {
+		return false
+	}
+	if diff := objectDiff(got, want, ignoreFields...); diff != "" {
+		t.Errorf("object mismatch (-want +got):\n%s", diff)
+		return false
+	}
+	return true
+}
+
+// objectDiff returns the diff from want to got without the ignored fields, empty if they match
+func objectDiff(got, want any, ignoreFields ...string) string {
+	return cmp.Diff(want, got,
+		cmpopts.EquateEmpty(),
+		cmp.Comparer(func(a, b resource.Quantity) bool { return a.Cmp(b) == 0 }),
+		cmp.FilterPath(func(p cmp.Path) bool { return slices.Contains(ignoreFields, fieldPath(p)) }, cmp.Ignore()),
+	)
+}
+
+// fieldPath returns the path of json names of the cmp path, without slice indexes and inlined structs
+func fieldPath(p cmp.Path) string {
+	var names []string
+	for i, s := range p {
+		switch s := s.(type) {
+		case cmp.StructField:
+			if name := jsonName(p[i-1].Type(), s.Name()); name != "" {
+				names = append(names, name)
+			}
+		case cmp.MapIndex:
+			names = append(names, fmt.Sprint(s.Key()))
+		}
+	}
+	return strings.Join(names, ".")
+}
+
+// jsonName returns the json name of the field of the struct, the Go name without json tag and empty if inlined
+func jsonName(t reflect.Type, field string) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	sf, ok := t.FieldByName(field)
+	if !ok {
+		return field
+	}
+	tag, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	switch {
+	case tag == "-":
+		return field
+	case tag != "":
+		return tag
+	case strings.Contains(sf.Tag.Get("json"), "inline") || sf.Anonymous:
+		return ""
+	}
+	return field
+}
@@ -0,0 +1,108 @@
+package framework
+
+import (
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_objectDiff(t *testing.T) {
+	deployment := func(image string, mutate ...func(*appsv1.Deployment)) *appsv1.Deployment {
+		d := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test-cases"},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{
+						Name:  "app",
+						Image: image,
+						Resources: corev1.ResourceRequirements{
+							Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+						},
+					}}},
+				},
+			},
+		}
+		for _, m := range mutate {
+			m(d)
+		}
+		return d
+	}
+	serverSet := func(d *appsv1.Deployment) {
+		d.ResourceVersion = "42"
+		d.UID = "0815"
+		d.Status.ReadyReplicas = 1
+	}
+
+	tests := []struct {
+		name     string
+		got      any
+		want     any
+		ignore   []string
+		wantDiff string
+	}{
+		{
+			name: "equal",
+			got:  deployment("busybox"),
+			want: deployment("busybox"),
+		},
+		{
+			name:     "different image",
+			got:      deployment("busybox:second"),
+			want:     deployment("busybox"),
+			wantDiff: `Image: "busybox:second"`,
+		},
+		{
+			name:   "ignored image of all containers",
+			got:    deployment("busybox:second"),
+			want:   deployment("busybox"),
+			ignore: []string{"spec.template.spec.containers.image"},
+		},
+		{
+			name:     "server set fields",
+			got:      deployment("busybox", serverSet),
+			want:     deployment("busybox"),
+			wantDiff: "ResourceVersion",
+		},
+		{
+			name:   "ignored server set fields",
+			got:    deployment("busybox", serverSet),
+			want:   deployment("busybox"),
+			ignore: ServerSetFields,
+		},
+		{
+			name: "equal quantities and empty maps",
+			got: deployment("busybox", func(d *appsv1.Deployment) {
+				d.Labels = map[string]string{}
+				d.Spec.Template.Spec.Containers[0].Resources.Limits[corev1.ResourceMemory] = resource.MustParse("1024Mi")
+			}),
+			want: deployment("busybox"),
+		},
+		{
+			name:   "ignored map key",
+			got:    deployment("busybox", func(d *appsv1.Deployment) { d.Annotations = map[string]string{"revision": "2"} }),
+			want:   deployment("busybox", func(d *appsv1.Deployment) { d.Annotations = map[string]string{"revision": "1"} }),
+			ignore: []string{"metadata.annotations.revision"},
+		},
+		{
+			name:   "go names without json tags",
+			got:    []Verdict{{Workload: "app", Allowed: false, Message: "denied by rule"}},
+			want:   []Verdict{{Workload: "app", Allowed: false, Message: "denied"}},
+			ignore: []string{"Message"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := objectDiff(tt.got, tt.want, tt.ignore...)
+			if tt.wantDiff == "" && diff != "" {
+				t.Errorf("objectDiff() = %s, want none", diff)
+			}
+			if tt.wantDiff != "" && !strings.Contains(diff, tt.wantDiff) {
+				t.Errorf("objectDiff() = %s, want %q", diff, tt.wantDiff)
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package framework
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/rest"
+)
+
+// DefaultFakeServerImage is the image of test/fakeserver pushed by make e2e-images, COSIGN_E2E_FAKESERVER_IMAGE
+// overrides it
+const DefaultFakeServerImage = "k3d-registry.localhost:5000/fakeserver:dev"
+
+// FakeResponse is a response programmed on a fake service
+type FakeResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers"`
+	Body    []byte            `json:"body"`
+	// Delay delays the response, e.g. to test timeouts
+	Delay time.Duration `json:"delay"`
+}
+
+// FakeRequest is a request received by a fake service
+type FakeRequest struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+}
+
+// FakeService is a fake external service deployed in the cluster, answering the programmed responses
+type FakeService struct {
+	Name      string
+	Namespace string
+	// ClusterIP is the cluster IP of the service
+	ClusterIP string
+	f         *Framework
+}
+
+// URL returns the in-cluster URL of the service
+func (s *FakeService) URL() string {
+	return fmt.Sprintf("http://%s.%s.svc", s.Name, s.Namespace)
+}
+
+// DeployFakeService deploys the fake server in the namespace and waits until it's ready. It's removed by Cleanup.
+func (f *Framework) DeployFakeService(name, namespace string) *FakeService {
+	s := &FakeService{Name: name, Namespace: namespace, f: f}
+	if f.err != nil {
+		return s
+	}
+
+	image := os.Getenv("COSIGN_E2E_FAKESERVER_IMAGE")
+	if image == "" {
+		image = DefaultFakeServerImage
+	}
+	labels := map[string]string{"app": name}
+	replicas := int32(1)
+	yes, no := true, false
+	d := appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:  "fakeserver",
+						Image: image,
+						Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}},
+						SecurityContext: &corev1.SecurityContext{
+							RunAsNonRoot:             &yes,
+							ReadOnlyRootFilesystem:   &yes,
+							AllowPrivilegeEscalation: &no,
+						},
+						ReadinessProbe: &corev1.Probe{
+							ProbeHandler: corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: "/_fake/healthz", Port: intstr.FromString("http")}},
+						},
+					}},
+				},
+			},
+		},
+	}
+	f.CreateDeployment(d)
+	if f.err != nil {
+		return s
+	}
+	f.fakes = append(f.fakes, s)
+
+	f.t.Logf("creating service %s", name)
+	svc, err := f.k8s.CoreV1().Services(namespace).Create(context.Background(), &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: corev1.ServiceSpec{
+			Selector: labels,
+			Ports:    []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromString("http")}},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		f.err = err
+		return s
+	}
+	s.ClusterIP = svc.Spec.ClusterIP
+	f.WaitForDeployment(d)
+	return s
+}
+
+// proxy returns a request to the fake server through the service proxy of the API server
+func (s *FakeService) proxy(method, path string) *rest.Request {
+	return s.f.k8s.CoreV1().RESTClient().Verb(method).
+		AbsPath("/api/v1/namespaces", s.Namespace, "services", s.Name+":http", "proxy", path)
+}
+
+// SetResponses replaces the programmed responses. Keys are request paths like /v2/ for all methods, or a method
+// and a path like "HEAD /v2/busybox/manifests/first".
+func (s *FakeService) SetResponses(responses map[string]FakeResponse) {
+	s.program("PUT", responses)
+}
+
+// AddResponses adds programmed responses, replacing the ones of the same keys
+func (s *FakeService) AddResponses(responses map[string]FakeResponse) {
+	s.program("POST", responses)
+}
+
+func (s *FakeService) program(method string, responses map[string]FakeResponse) {
+	if s.f.err != nil {
+		return
+	}
+	body, err := json.Marshal(responses)
+	if err != nil {
+		s.f.err = err
+		return
+	}
+	err = s.proxy(method, "/_fake/responses").SetHeader("Content-Type", "application/json").Body(body).Do(context.Background()).Error()
+	if err != nil {
+		s.f.err = fmt.Errorf("failed programming fake service %s: %w", s.Name, err)
+	}
+}
+
+// Requests returns the requests the fake service received since it was deployed or the requests were reset
+func (s *FakeService) Requests() []FakeRequest {
+	if s.f.err != nil {
+		return nil
+	}
+	data, err := s.proxy("GET", "/_fake/requests").Do(context.Background()).Raw()
+	if err != nil {
+		s.f.err = fmt.Errorf("failed getting requests of fake service %s: %w", s.Name, err)
+		return nil
+	}
+	var requests []FakeRequest
+	if err := json.Unmarshal(data, &requests); err != nil {
+		s.f.err = err
+	}
+	return requests
+}
+
+// ResetRequests clears the received requests
+func (s *FakeService) ResetRequests() {
+	if s.f.err != nil {
+		return
+	}
+	if err := s.proxy("DELETE", "/_fake/requests").Do(context.Background()).Error(); err != nil {
+		s.f.err = fmt.Errorf("failed resetting requests of fake service %s: %w", s.Name, err)
+	}
+}
+
+// cleanupFakeServices removes the services of the fake services, their deployments are removed with the others
+func (f *Framework) cleanupFakeServices() {
+	if f.k8s == nil {
+		return
+	}
+	for _, s := range f.fakes {
+		f.t.Logf("cleaning up fake service %s", s.Name)
+		err := f.k8s.CoreV1().Services(s.Namespace).Delete(context.Background(), s.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			f.err = err
+			return
+		}
+		err = f.k8s.AppsV1().Deployments(s.Namespace).Delete(context.Background(), s.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			f.err = err
+			return
+		}
+	}
+	f.fakes = nil
+}
+
+// FakeRegistry is a fake OCI registry serving programmed manifests, e.g. to test rules resolving digests
+type FakeRegistry struct {
+	*FakeService
+}
+
+// DeployFakeRegistry deploys a fake registry answering the version check of the distribution API
+func (f *Framework) DeployFakeRegistry(name, namespace string) *FakeRegistry {
+	r := &FakeRegistry{FakeService: f.DeployFakeService(name, namespace)}
+	r.SetResponses(map[string]FakeResponse{
+		"/v2/": {Status: 200, Headers: map[string]string{"Docker-Distribution-API-Version": "registry/2.0"}, Body: []byte("{}")},
+	})
+	return r
+}
+
+// Host returns the host of the registry for image references, e.g. <host>/busybox:first. It's the cluster IP, as
+// registries on private IPs are called with plain http like the fake server serves.
+func (r *FakeRegistry) Host() string {
+	return r.ClusterIP
+}
+
+// PushManifest serves the OCI manifest for the tag and its digest, and returns the digest
+func (r *FakeRegistry) PushManifest(repo, tag string, manifest []byte) string {
+	responses, digest := manifestResponses(repo, tag, manifest)
+	r.AddResponses(responses)
+	return digest
+}
+
+// FailManifest answers the requests of the manifest of the tag with the status, e.g. 500 or 401
+func (r *FakeRegistry) FailManifest(repo, tag string, status int) {
+	r.AddResponses(map[string]FakeResponse{
+		fmt.Sprintf("/v2/%s/manifests/%s", repo, tag): {Status: status, Body: []byte(`{"errors":[{"code":"UNKNOWN"}]}`)},
+	})
+}
+
+// manifestResponses returns the responses serving the manifest by tag and digest, and the digest
+func manifestResponses(repo, tag string, manifest []byte) (map[string]FakeResponse, string) {
+	sum := sha256.Sum256(manifest)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	res := FakeResponse{
+		Status: 200,
+		Headers: map[string]string{
+			"Content-Type":          "application/vnd.oci.image.manifest.v1+json",
+			"Docker-Content-Digest": digest,
+			"Content-Length":        fmt.Sprint(len(manifest)),
+		},
+		Body: manifest,
+	}
+	return map[string]FakeResponse{
+		fmt.Sprintf("/v2/%s/manifests/%s", repo, tag):    res,
+		fmt.Sprintf("/v2/%s/manifests/%s", repo, digest): res,
+	}, digest
+}
+
+// FakeScanner is a fake vulnerability scanner publishing a feed of blocked images in the format of the blocklist
+// rule, one digest or image pattern per line followed by the reason
+type FakeScanner struct {
+	*FakeService
+	findings []string
+}
+
+// FeedPath is the path of the feed of a fake scanner
+const FeedPath = "/feed"
+
+// DeployFakeScanner deploys a fake scanner with an empty feed
+func (f *Framework) DeployFakeScanner(name, namespace string) *FakeScanner {
+	s := &FakeScanner{FakeService: f.DeployFakeService(name, namespace)}
+	s.publish()
+	return s
+}
+
+// FeedURL returns the in-cluster URL of the feed, e.g. as source of the blocklist rule
+func (s *FakeScanner) FeedURL() string {
+	return s.URL() + FeedPath
+}
+
+// Block adds the digest or image pattern with the reason to the feed
+func (s *FakeScanner) Block(image, reason string) {
+	s.findings = append(s.findings, strings.TrimSpace(image+" "+reason))
+	s.publish()
+}
+
+// Clear empties the feed
+func (s *FakeScanner) Clear() {
+	s.findings = nil
+	s.publish()
+}
+
+// FailFeed answers the requests of the feed with the status, e.g. to test refresh retries
+func (s *FakeScanner) FailFeed(status int) {
+	s.AddResponses(map[string]FakeResponse{FeedPath: {Status: status}})
+}
+
+func (s *FakeScanner) publish() {
+	s.AddResponses(map[string]FakeResponse{FeedPath: {
+		Status:  200,
+		Headers: map[string]string{"Content-Type": "text/plain"},
+		Body:    []byte(feed(s.findings)),
+	}})
+}
+
+// feed returns the feed of the findings
+func feed(findings []string) string {
+	var b strings.Builder
+	b.WriteString("# fake scanner feed\n")
+	for _, l := range findings {
+		b.WriteString(l + "\n")
+	}
+	return b.String()
+}
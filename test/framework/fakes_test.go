@@ -0,0 +1,31 @@
+package framework
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+)
+
+func Test_manifestResponses(t *testing.T) {
+	manifest := []byte(`{"schemaVersion":2}`)
+	responses, digest := manifestResponses("busybox", "first", manifest)
+	if want := fmt.Sprintf("sha256:%x", sha256.Sum256(manifest)); digest != want {
+		t.Fatalf("digest = %q, want %q", digest, want)
+	}
+	for _, path := range []string{"/v2/busybox/manifests/first", "/v2/busybox/manifests/" + digest} {
+		res, ok := responses[path]
+		if !ok {
+			t.Fatalf("no response for %s", path)
+		}
+		if res.Headers["Docker-Content-Digest"] != digest || string(res.Body) != string(manifest) {
+			t.Errorf("response for %s = %+v", path, res)
+		}
+	}
+}
+
+func Test_feed(t *testing.T) {
+	want := "# fake scanner feed\nsha256:abc CVE-2024-1234\n.*/debug:.* debug images\n"
+	if got := feed([]string{"sha256:abc CVE-2024-1234", ".*/debug:.* debug images"}); got != want {
+		t.Errorf("feed() = %q, want %q", got, want)
+	}
+}
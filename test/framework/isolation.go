@@ -0,0 +1,163 @@
+package framework
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// WebhookNamespace is the namespace of the webhook deployed by make e2e-deploy
+	WebhookNamespace = "cosignwebhook"
+	// WebhookName is the name of the webhook release deployed by make e2e-deploy
+	WebhookName = "cosignwebhook"
+
+	// isolationTimeout is how long the CNI may take to enforce a new or deleted NetworkPolicy
+	isolationTimeout = time.Minute
+)
+
+// IsolationTarget is a dependency of the webhook it can be cut off from, e.g. a fake registry or the API server
+type IsolationTarget struct {
+	// Name of the dependency, part of the name of the NetworkPolicy
+	Name string
+	// IPs of the dependency, pod IPs and cluster IPs
+	IPs []string
+}
+
+// Target returns the pods and the cluster IP of the fake service as isolation target
+func (s *FakeService) Target() IsolationTarget {
+	t := IsolationTarget{Name: s.Name}
+	if s.f.err != nil {
+		return t
+	}
+	pods, err := s.f.k8s.CoreV1().Pods(s.Namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("app=%s", s.Name),
+	})
+	if err != nil {
+		s.f.err = err
+		return t
+	}
+	for _, p := range pods.Items {
+		for _, ip := range p.Status.PodIPs {
+			t.IPs = append(t.IPs, ip.IP)
+		}
+	}
+	if s.ClusterIP != "" {
+		t.IPs = append(t.IPs, s.ClusterIP)
+	}
+	return t
+}
+
+// APIServer returns the endpoints and the cluster IP of the kubernetes service as isolation target
+func (f *Framework) APIServer() IsolationTarget {
+	t := IsolationTarget{Name: "apiserver"}
+	if f.err != nil {
+		return t
+	}
+	ctx := context.Background()
+	svc, err := f.k8s.CoreV1().Services(metav1.NamespaceDefault).Get(ctx, "kubernetes", metav1.GetOptions{})
+	if err != nil {
+		f.err = err
+		return t
+	}
+	t.IPs = append(t.IPs, svc.Spec.ClusterIPs...)
+	endpoints, err := f.k8s.CoreV1().Endpoints(metav1.NamespaceDefault).Get(ctx, "kubernetes", metav1.GetOptions{})
+	if err != nil {
+		f.err = err
+		return t
+	}
+	for _, s := range endpoints.Subsets {
+		for _, a := range s.Addresses {
+			t.IPs = append(t.IPs, a.IP)
+		}
+	}
+	return t
+}
+
+// IsolateWebhookFrom applies a NetworkPolicy cutting the webhook pods off from the target while all other egress
+// stays allowed, e.g. to test fail-open and fail-closed behavior when a registry is unreachable. The isolation is
+// lifted when the test ends or when the returned func is called.
+//
+// The probe pod is admitted with dry run to observe when the CNI enforces the policy: its verdict has to depend on
+// the target, e.g. a pod with an image of a fake registry. Isolating waits until the verdict changes, lifting waits
+// until it's the one before the isolation again.
+func (f *Framework) IsolateWebhookFrom(t *testing.T, target IsolationTarget, probe corev1.Pod) func() {
+	if f.err != nil {
+		return func() {}
+	}
+	if len(target.IPs) == 0 {
+		f.err = fmt.Errorf("isolation target %s has no IPs", target.Name)
+		return func() {}
+	}
+	reachable := f.admissionVerdict(context.Background(), &probe)
+	t.Logf("verdict of probe %s while the webhook reaches %s: %s", probe.Name, target.Name, reachable)
+
+	np := isolationPolicy(target)
+	t.Logf("isolating the webhook from %s %v", target.Name, target.IPs)
+	policies := f.k8s.NetworkingV1().NetworkPolicies(WebhookNamespace)
+	if _, err := policies.Create(context.Background(), np, metav1.CreateOptions{}); err != nil {
+		f.err = fmt.Errorf("failed isolating the webhook from %s: %w", target.Name, err)
+		return func() {}
+	}
+	f.pollUntil(isolationTimeout, time.Second, fmt.Sprintf("the isolation from %s", target.Name), func(ctx context.Context) (bool, error) {
+		return f.admissionVerdict(ctx, &probe) != reachable, nil
+	})
+
+	lifted := false
+	lift := func() {
+		if lifted {
+			return
+		}
+		lifted = true
+		t.Logf("lifting the isolation of the webhook from %s", target.Name)
+		err := policies.Delete(context.Background(), np.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			f.err = fmt.Errorf("failed lifting the isolation from %s: %w", target.Name, err)
+			return
+		}
+		f.pollUntil(isolationTimeout, time.Second, fmt.Sprintf("the lift of the isolation from %s", target.Name), func(ctx context.Context) (bool, error) {
+			return f.admissionVerdict(ctx, &probe) == reachable, nil
+		})
+	}
+	t.Cleanup(lift)
+	return lift
+}
+
+// isolationPolicy returns the NetworkPolicy allowing the webhook pods egress to everything except the target
+func isolationPolicy(target IsolationTarget) *networkingv1.NetworkPolicy {
+	var v4, v6 []string
+	for _, ip := range target.IPs {
+		parsed := net.ParseIP(ip)
+		switch {
+		case parsed == nil:
+			continue
+		case parsed.To4() != nil:
+			v4 = append(v4, ip+"/32")
+		default:
+			v6 = append(v6, ip+"/128")
+		}
+	}
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "isolate-webhook-from-" + target.Name,
+			Namespace: WebhookNamespace,
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app.kubernetes.io/name": WebhookName}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			Egress: []networkingv1.NetworkPolicyEgressRule{{
+				To: []networkingv1.NetworkPolicyPeer{
+					{IPBlock: &networkingv1.IPBlock{CIDR: "0.0.0.0/0", Except: v4}},
+					{IPBlock: &networkingv1.IPBlock{CIDR: "::/0", Except: v6}},
+				},
+			}},
+		},
+	}
+}
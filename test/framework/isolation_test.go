@@ -0,0 +1,23 @@
+package framework
+
+import (
+	"slices"
+	"testing"
+)
+
+func Test_isolationPolicy(t *testing.T) {
+	np := isolationPolicy(IsolationTarget{Name: "registry", IPs: []string{"10.42.0.7", "10.43.12.1", "fd00::7", "invalid"}})
+	if np.Name != "isolate-webhook-from-registry" || np.Namespace != WebhookNamespace {
+		t.Errorf("policy %s/%s", np.Namespace, np.Name)
+	}
+	if len(np.Spec.Egress) != 1 || len(np.Spec.Egress[0].To) != 2 {
+		t.Fatalf("egress = %+v, want one rule with an IPv4 and an IPv6 block", np.Spec.Egress)
+	}
+	v4, v6 := np.Spec.Egress[0].To[0].IPBlock, np.Spec.Egress[0].To[1].IPBlock
+	if v4.CIDR != "0.0.0.0/0" || !slices.Equal(v4.Except, []string{"10.42.0.7/32", "10.43.12.1/32"}) {
+		t.Errorf("IPv4 block = %+v", v4)
+	}
+	if v6.CIDR != "::/0" || !slices.Equal(v6.Except, []string{"fd00::7/128"}) {
+		t.Errorf("IPv6 block = %+v", v6)
+	}
+}
@@ -0,0 +1,57 @@
+package framework
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultTimeout is the timeout of the helpers waiting for the cluster, e.g. WaitForDeployment
+	DefaultTimeout = 30 * time.Second
+	// TimeoutScaleEnv multiplies all timeouts of the framework, e.g. 2.5 on slow CI runners
+	TimeoutScaleEnv = "GRUMPY_E2E_TIMEOUT_SCALE"
+)
+
+// Option configures the Framework
+type Option func(*Framework)
+
+// WithTimeout replaces DefaultTimeout as timeout of the helpers, it's scaled by GRUMPY_E2E_TIMEOUT_SCALE as well
+func WithTimeout(timeout time.Duration) Option {
+	return func(f *Framework) {
+		f.timeout = timeout
+	}
+}
+
+// timeoutScale returns the multiplier of the timeouts from GRUMPY_E2E_TIMEOUT_SCALE, 1 if not set
+func timeoutScale() (float64, error) {
+	v := os.Getenv(TimeoutScaleEnv)
+	if v == "" {
+		return 1, nil
+	}
+	scale, err := strconv.ParseFloat(v, 64)
+	if err != nil || scale <= 0 {
+		return 0, fmt.Errorf("%s must be a positive number, got %q", TimeoutScaleEnv, v)
+	}
+	return scale, nil
+}
+
+// helperTimeout returns the override of a helper if passed, else the timeout of the framework
+func (f *Framework) helperTimeout(override []time.Duration) time.Duration {
+	if len(override) > 0 && override[0] > 0 {
+		return override[0]
+	}
+	if f.timeout > 0 {
+		return f.timeout
+	}
+	return DefaultTimeout
+}
+
+// scaled returns the duration multiplied by the timeout scale
+func (f *Framework) scaled(d time.Duration) time.Duration {
+	if f.timeoutScale <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) * f.timeoutScale)
+}
@@ -0,0 +1,47 @@
+package framework
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_timeoutScale(t *testing.T) {
+	tests := []struct {
+		env     string
+		want    float64
+		wantErr bool
+	}{
+		{env: "", want: 1},
+		{env: "2.5", want: 2.5},
+		{env: "0", wantErr: true},
+		{env: "-1", wantErr: true},
+		{env: "slow", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			t.Setenv(TimeoutScaleEnv, tt.env)
+			got, err := timeoutScale()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("timeoutScale() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("timeoutScale() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFramework_helperTimeout(t *testing.T) {
+	f := &Framework{t: t}
+	if got := f.scaled(f.helperTimeout(nil)); got != DefaultTimeout {
+		t.Errorf("default timeout = %s", got)
+	}
+	WithTimeout(time.Minute)(f)
+	f.timeoutScale = 1.5
+	if got := f.scaled(f.helperTimeout(nil)); got != 90*time.Second {
+		t.Errorf("scaled timeout = %s, want 1m30s", got)
+	}
+	if got := f.scaled(f.helperTimeout([]time.Duration{10 * time.Second})); got != 15*time.Second {
+		t.Errorf("scaled override = %s, want 15s", got)
+	}
+}
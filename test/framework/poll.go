@@ -0,0 +1,43 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PollUntil calls cond immediately and then every interval until it returns true or an error, or the context is
+// done. Returns the error of cond or the error of the context, e.g. context.DeadlineExceeded.
+func PollUntil(ctx context.Context, interval time.Duration, cond func(ctx context.Context) (bool, error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		done, err := cond(ctx)
+		if err != nil || done {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollUntil polls cond with PollUntil until the scaled timeout and sets the error of the framework if it fails, the
+// timeout as waiting for what
+func (f *Framework) pollUntil(timeout, interval time.Duration, what string, cond func(ctx context.Context) (bool, error)) {
+	if f.err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), f.scaled(timeout))
+	defer cancel()
+	err := PollUntil(ctx, interval, cond)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		f.err = fmt.Errorf("timeout reached while waiting for %s", what)
+	case err != nil:
+		f.err = err
+	}
+}
@@ -0,0 +1,70 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPollUntil(t *testing.T) {
+	errFailed := errors.New("failed")
+	tests := []struct {
+		name      string
+		timeout   time.Duration
+		cond      func(calls int) (bool, error)
+		wantErr   error
+		wantCalls int
+	}{
+		{
+			name:      "done immediately",
+			timeout:   time.Second,
+			cond:      func(int) (bool, error) { return true, nil },
+			wantCalls: 1,
+		},
+		{
+			name:      "done after polls",
+			timeout:   time.Second,
+			cond:      func(calls int) (bool, error) { return calls == 3, nil },
+			wantCalls: 3,
+		},
+		{
+			name:      "error",
+			timeout:   time.Second,
+			cond:      func(calls int) (bool, error) { return false, errFailed },
+			wantErr:   errFailed,
+			wantCalls: 1,
+		},
+		{
+			name:    "timeout",
+			timeout: 50 * time.Millisecond,
+			cond:    func(int) (bool, error) { return false, nil },
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			calls := 0
+			err := PollUntil(ctx, 10*time.Millisecond, func(context.Context) (bool, error) {
+				calls++
+				return tt.cond(calls)
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("PollUntil() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantCalls > 0 && calls != tt.wantCalls {
+				t.Errorf("PollUntil() called cond %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestFramework_pollUntil(t *testing.T) {
+	f := &Framework{t: t}
+	f.pollUntil(50*time.Millisecond, 10*time.Millisecond, "nothing", func(context.Context) (bool, error) { return false, nil })
+	if f.err == nil || f.err.Error() != "timeout reached while waiting for nothing" {
+		t.Errorf("err = %v", f.err)
+	}
+}
@@ -0,0 +1,217 @@
+package framework
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// UpgradePath configures an upgrade test of the webhook deployed by make e2e-deploy
+type UpgradePath struct {
+	// From and To are the images of the versions A and B, e.g. k3d-registry.localhost:5000/cosignwebhook:4.3.0
+	From string
+	To   string
+	// Workloads are applied with version A and must keep their verdicts and statuses with version B
+	Workloads []appsv1.Deployment
+	// IgnoreMessages only compares whether the workloads are allowed, not the denial messages, e.g. if B rewords
+	// them on purpose
+	IgnoreMessages bool
+}
+
+// Verdict is the admission verdict of the pods of a workload
+type Verdict struct {
+	Workload string
+	Allowed  bool
+	Message  string
+}
+
+// UpgradeResult are the verdicts and ready replicas of the workloads before and after the upgrade
+type UpgradeResult struct {
+	Before []Verdict
+	After  []Verdict
+	// ReadyBefore and ReadyAfter are the ready replicas of the applied workloads by name
+	ReadyBefore map[string]int32
+	ReadyAfter  map[string]int32
+}
+
+// AssertUpgradePath installs version A of the webhook, applies the workloads, upgrades the webhook to version B
+// and asserts that the verdicts of the workloads and the statuses of the applied ones are the same, which catches
+// breaking changes of policies and state handling. The webhook has no custom resources, so the statuses compared are
+// the ready replicas of the workloads. The image of the webhook is restored afterwards.
+func (f *Framework) AssertUpgradePath(u UpgradePath) UpgradeResult {
+	res := UpgradeResult{ReadyBefore: map[string]int32{}, ReadyAfter: map[string]int32{}}
+	if f.err != nil {
+		return res
+	}
+	original := f.webhookImage()
+	if f.err != nil {
+		return res
+	}
+	defer func() {
+		// restore even if the upgrade path failed, so later tests run against the deployed version
+		err := f.err
+		f.err = nil
+		f.SetWebhookImage(original)
+		if err != nil {
+			f.err = err
+		}
+	}()
+
+	f.SetWebhookImage(u.From)
+	res.Before = f.verdicts(u.Workloads)
+	for i, d := range u.Workloads {
+		if !res.Before[i].Allowed {
+			continue
+		}
+		f.CreateDeployment(d)
+		f.WaitForDeployment(d)
+	}
+	res.ReadyBefore = f.readyReplicas(u.Workloads, res.Before)
+
+	f.SetWebhookImage(u.To)
+	res.After = f.verdicts(u.Workloads)
+	res.ReadyAfter = f.readyReplicas(u.Workloads, res.Before)
+	if f.err != nil {
+		return res
+	}
+
+	var ignore []string
+	if u.IgnoreMessages {
+		ignore = append(ignore, "Message")
+	}
+	if diff := objectDiff(res.After, res.Before, ignore...); diff != "" {
+		f.err = fmt.Errorf("verdicts changed from %s to %s (-before +after):\n%s", u.From, u.To, diff)
+		return res
+	}
+	if diff := objectDiff(res.ReadyAfter, res.ReadyBefore); diff != "" {
+		f.err = fmt.Errorf("ready replicas changed after the upgrade to %s (-before +after):\n%s", u.To, diff)
+		return res
+	}
+	f.t.Logf("upgrade from %s to %s kept the verdicts of %d workloads", u.From, u.To, len(u.Workloads))
+	return res
+}
+
+// webhookImage returns the image of the webhook container
+func (f *Framework) webhookImage() string {
+	d, err := f.k8s.AppsV1().Deployments(WebhookNamespace).Get(context.Background(), WebhookName, metav1.GetOptions{})
+	if err != nil {
+		f.err = err
+		return ""
+	}
+	for _, c := range d.Spec.Template.Spec.Containers {
+		if c.Name == WebhookName {
+			return c.Image
+		}
+	}
+	f.err = fmt.Errorf("deployment %s/%s has no container %s", WebhookNamespace, WebhookName, WebhookName)
+	return ""
+}
+
+// SetWebhookImage patches the image of the webhook container and the image verified by the scwebhook init
+// container, and waits until the rollout is complete. The image has to be signed with the key of the chart.
+func (f *Framework) SetWebhookImage(image string) {
+	if f.err != nil {
+		return
+	}
+	ctx := context.Background()
+	deployments := f.k8s.AppsV1().Deployments(WebhookNamespace)
+	d, err := deployments.Get(ctx, WebhookName, metav1.GetOptions{})
+	if err != nil {
+		f.err = err
+		return
+	}
+	spec := &d.Spec.Template.Spec
+	changed := false
+	for i, c := range spec.Containers {
+		if c.Name != WebhookName || c.Image == image {
+			continue
+		}
+		for j, ic := range spec.InitContainers {
+			if ic.Name == "scwebhook" && len(ic.Args) > 0 && ic.Args[len(ic.Args)-1] == c.Image {
+				spec.InitContainers[j].Args[len(ic.Args)-1] = image
+			}
+		}
+		spec.Containers[i].Image = image
+		changed = true
+	}
+	if !changed {
+		return
+	}
+	f.t.Logf("rolling out webhook image %s", image)
+	if d, err = deployments.Update(ctx, d, metav1.UpdateOptions{}); err != nil {
+		f.err = err
+		return
+	}
+	f.waitForRollout(d.Generation)
+}
+
+// waitForRollout waits until the webhook deployment of the generation is rolled out and the old pods are gone
+func (f *Framework) waitForRollout(generation int64) {
+	var updated, replicas int32
+	f.pollUntil(3*time.Minute, 2*time.Second, "the webhook rollout", func(ctx context.Context) (bool, error) {
+		d, err := f.k8s.AppsV1().Deployments(WebhookNamespace).Get(ctx, WebhookName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		s := d.Status
+		replicas = 1
+		if d.Spec.Replicas != nil {
+			replicas = *d.Spec.Replicas
+		}
+		updated = s.UpdatedReplicas
+		return s.ObservedGeneration >= generation && s.UpdatedReplicas == replicas && s.AvailableReplicas == replicas && s.Replicas == replicas, nil
+	})
+	if f.err != nil {
+		f.err = fmt.Errorf("%w, %d of %d replicas updated", f.err, updated, replicas)
+		return
+	}
+	f.t.Logf("webhook rolled out with %d replicas", replicas)
+}
+
+// verdicts admits a pod of the template of each workload with dry run, as signatures are only verified on pods
+func (f *Framework) verdicts(workloads []appsv1.Deployment) []Verdict {
+	verdicts := make([]Verdict, 0, len(workloads))
+	for _, d := range workloads {
+		if f.err != nil {
+			return verdicts
+		}
+		p := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        d.Name + "-upgrade-verdict",
+				Namespace:   d.Namespace,
+				Labels:      d.Spec.Template.Labels,
+				Annotations: d.Spec.Template.Annotations,
+			},
+			Spec: d.Spec.Template.Spec,
+		}
+		v := Verdict{Workload: d.Name, Allowed: true}
+		_, err := f.k8s.CoreV1().Pods(d.Namespace).Create(context.Background(), p, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}})
+		if err != nil {
+			v.Allowed, v.Message = false, err.Error()
+		}
+		f.t.Logf("verdict of workload %s: allowed %t %s", d.Name, v.Allowed, v.Message)
+		verdicts = append(verdicts, v)
+	}
+	return verdicts
+}
+
+// readyReplicas returns the ready replicas of the workloads applied because they were allowed
+func (f *Framework) readyReplicas(workloads []appsv1.Deployment, verdicts []Verdict) map[string]int32 {
+	ready := map[string]int32{}
+	for i, d := range workloads {
+		if f.err != nil || i >= len(verdicts) || !verdicts[i].Allowed {
+			continue
+		}
+		got, err := f.k8s.AppsV1().Deployments(d.Namespace).Get(context.Background(), d.Name, metav1.GetOptions{})
+		if err != nil {
+			f.err = err
+			return ready
+		}
+		ready[d.Name] = got.Status.ReadyReplicas
+	}
+	return ready
+}
@@ -0,0 +1,54 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/eumel8/cosignwebhook/test/framework"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestCertRotation rotates the serving certificate of the webhook while pods are admitted and asserts that
+// no admission fails, neither during the rotation nor with the new CA only
+func TestCertRotation(t *testing.T) {
+	fw, err := framework.New(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cert-rotation",
+			Namespace: "test-cases",
+		},
+		Spec: corev1.PodSpec{
+			TerminationGracePeriodSeconds: &terminationGracePeriodSeconds,
+			Containers: []corev1.Container{
+				{
+					Name:  "cert-rotation",
+					Image: busyboxOne,
+				},
+			},
+		},
+	}
+
+	stop := fw.StartAdmissionLoad(pod)
+	fw.RotateWebhookCert("cosignwebhook", "cosignwebhook")
+	// keep admitting until the API server verified the new certificate with the old CA removed from the caBundle
+	fw.WaitForAdmissions(pod, 10)
+	load := stop()
+
+	if load.Total == 0 {
+		t.Error("no admission requests sent")
+	}
+	if load.Failed > 0 {
+		t.Errorf("%d of %d admissions failed during the rotation: %v", load.Failed, load.Total, load.Errors)
+	}
+	for _, e := range load.Errors {
+		if strings.Contains(e, "x509") {
+			t.Errorf("API server can't verify the webhook certificate: %s", e)
+		}
+	}
+	fw.Cleanup()
+}
@@ -0,0 +1,79 @@
+package test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/eumel8/cosignwebhook/test/framework"
+	"github.com/eumel8/cosignwebhook/webhook"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestUpgradePath upgrades the webhook from the release in COSIGN_E2E_UPGRADE_FROM, e.g.
+// k3d-registry.localhost:5000/cosignwebhook:4.3.0, to the dev image and asserts that a signed and a wrongly signed
+// workload keep their verdicts
+func TestUpgradePath(t *testing.T) {
+	from := os.Getenv("COSIGN_E2E_UPGRADE_FROM")
+	if from == "" {
+		t.Skip("COSIGN_E2E_UPGRADE_FROM not set")
+	}
+	fw, err := framework.New(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	priv, pub := framework.CreateECDSAKeyPair(fw, "upgrade")
+	_, other := framework.CreateECDSAKeyPair(fw, "upgrade-other")
+	fw.SignContainer(framework.SignOptions{
+		KeyPath: priv.Path,
+		Image:   busyboxOne,
+	})
+
+	fw.AssertUpgradePath(framework.UpgradePath{
+		From: from,
+		To:   "k3d-registry.localhost:5000/cosignwebhook:dev",
+		Workloads: []appsv1.Deployment{
+			upgradeDeployment("upgrade-signed", pub.Key),
+			upgradeDeployment("upgrade-wrong-key", other.Key),
+		},
+	})
+	fw.Cleanup()
+}
+
+// upgradeDeployment returns a deployment of busyboxOne verified with the public key
+func upgradeDeployment(name, pubKey string) appsv1.Deployment {
+	return appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "test-cases",
+		},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": name},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"app": name},
+				},
+				Spec: corev1.PodSpec{
+					TerminationGracePeriodSeconds: &terminationGracePeriodSeconds,
+					Containers: []corev1.Container{
+						{
+							Name:    name,
+							Image:   busyboxOne,
+							Command: []string{"sh", "-c", "sleep 3600"},
+							Env: []corev1.EnvVar{
+								{
+									Name:  webhook.CosignEnvVar,
+									Value: pubKey,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
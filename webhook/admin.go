@@ -0,0 +1,122 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	log "github.com/gookit/slog"
+)
+
+// FlushCachePath is the path of the admin API flushing the caches
+const FlushCachePath = "/admin/flush-cache"
+
+// AdminConfig configures the admin API of the webhook
+type AdminConfig struct {
+	// Enabled serves the admin API on /admin
+	Enabled bool `json:"enabled"`
+	// Groups are the groups of which a user needs one to call the admin API, required if enabled
+	Groups []string `json:"groups"`
+}
+
+// validate checks that the admin API is restricted to groups
+func (c AdminConfig) validate() error {
+	if c.Enabled && len(c.Groups) == 0 {
+		return fmt.Errorf("admin: groups are required")
+	}
+	return nil
+}
+
+// FlushResult is the response of the flush-cache API
+type FlushResult struct {
+	// Flushed are the flushed caches, e.g. memo /validate, rule licenses or informer secrets
+	Flushed []string `json:"flushed"`
+	// Errors are the caches which couldn't be flushed with the reason
+	Errors []string `json:"errors,omitempty"`
+}
+
+// cacheFlusher is implemented by rules caching external lookups, e.g. signature attestations or blocklists
+type cacheFlusher interface {
+	// flush drops the cached lookups, or refreshes them if the rule can't work without
+	flush(ctx context.Context) error
+}
+
+// FlushCache clears the memoized verdicts and the caches of external lookups of the rules, and re-lists the
+// objects cached by the informers, e.g. after signatures or scan results changed out-of-band.
+// Clients authenticate with a bearer token of the cluster, which is checked with a TokenReview.
+func (csh *CosignServerHandler) FlushCache(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, status, err := csh.authenticate(r, csh.cfg.Admin.Groups)
+	if err != nil {
+		log.Warnf("Flush cache request rejected: %v", err)
+		http.Error(w, err.Error(), status)
+		return
+	}
+	res := csh.flushCaches(r.Context())
+	log.Infof("User %q flushed the caches %s", user.Username, strings.Join(res.Flushed, ", "))
+	status = http.StatusOK
+	if len(res.Errors) > 0 {
+		log.Errorf("Caches couldn't be flushed: %s", strings.Join(res.Errors, "; "))
+		status = http.StatusInternalServerError
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		log.Errorf("Can't write flush cache response: %v", err)
+	}
+}
+
+// flushCaches flushes the memos of the endpoints, the caches of the rules and fact providers, the shared cache and
+// the informers
+func (csh *CosignServerHandler) flushCaches(ctx context.Context) *FlushResult {
+	res := &FlushResult{}
+	// each rule instance is flushed once
+	flushed := map[cacheFlusher]bool{}
+	for _, e := range csh.endpoints {
+		if e.memo != nil {
+			e.memo.flush()
+			res.Flushed = append(res.Flushed, "memo "+e.Path)
+		}
+		rules := e.rules
+		if e.shadow != nil {
+			rules = append(rules[:len(rules):len(rules)], e.shadow.rules...)
+		}
+		for _, rule := range rules {
+			rule = unwrapRule(rule)
+			f, ok := rule.(cacheFlusher)
+			if !ok || flushed[f] {
+				continue
+			}
+			flushed[f] = true
+			if err := f.flush(ctx); err != nil {
+				res.Errors = append(res.Errors, fmt.Sprintf("rule %s: %v", rule.Name(), err))
+				continue
+			}
+			res.Flushed = append(res.Flushed, "rule "+rule.Name())
+		}
+	}
+	csh.flushFactProviders(ctx, res)
+	if csh.shared != nil {
+		if err := csh.shared.flush(ctx); err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("shared cache: %v", err))
+		} else {
+			res.Flushed = append(res.Flushed, "shared cache")
+		}
+	}
+	relisted := csh.relistInformers(ctx)
+	for _, resource := range slices.Sorted(maps.Keys(relisted)) {
+		if err := relisted[resource]; err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("informer %s: %v", resource, err))
+			continue
+		}
+		res.Flushed = append(res.Flushed, "informer "+resource)
+	}
+	return res
+}
@@ -0,0 +1,88 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+)
+
+func TestCosignServerHandler_FlushCache(t *testing.T) {
+	cs := authenticatingClientset()
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "pull", Namespace: "default"}}
+	cfg := DefaultConfig()
+	cfg.Admin = AdminConfig{Enabled: true, Groups: []string{"platform"}}
+	csh := &CosignServerHandler{cs: cs, meta: fakeMetadataClient(secret), cfg: cfg, informers: informers.NewSharedInformerFactory(cs, 0)}
+	lister := csh.metadataLister("secrets")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	csh.Start(ctx)
+
+	rule, err := newLicensesRule(csh, &Config{Licenses: LicensesConfig{Denied: []string{"AGPL-.*"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	licenses := rule.(*licensesRule)
+	licenses.put("sha256:abc", []string{"MIT"}, time.Now())
+	m, err := newMemo(MemoizeConfig{TTL: metav1.Duration{Duration: time.Minute}}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.put("key", &Decision{Allowed: true})
+	csh.endpoints = []*Endpoint{{Path: DefaultPath, rules: []Rule{rule}, memo: m, csh: csh}}
+
+	// a missed watch event
+	if err := csh.metaInformers["secrets"].Informer().GetStore().Replace(nil, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(csh.FlushCache))
+	defer srv.Close()
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, http.NoBody)
+	req.Header.Set("Authorization", "Bearer valid")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	res := FlushResult{}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"memo " + DefaultPath, "rule " + LicensesRuleName, "facts registry", "informer secrets"}; !slices.Equal(res.Flushed, want) {
+		t.Errorf("flushed %v, want %v", res.Flushed, want)
+	}
+
+	if len(m.entries) != 0 || len(licenses.cache) != 0 {
+		t.Errorf("caches not flushed: %d memoized verdicts, %d cached licenses", len(m.entries), len(licenses.cache))
+	}
+	if _, err := lister.ByNamespace("default").Get("pull"); err != nil {
+		t.Errorf("secret not re-listed: %v", err)
+	}
+
+	cfg.Admin.Groups = []string{"admins"}
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("user outside the groups got status %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+}
+
+func TestAdminConfig_validate(t *testing.T) {
+	if err := (AdminConfig{Enabled: true}).validate(); err == nil {
+		t.Error("validate() without groups, want error")
+	}
+}
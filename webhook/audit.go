@@ -0,0 +1,173 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// syslogFacilityLogAudit is the syslog facility "log audit" of RFC 5424
+	syslogFacilityLogAudit = 13
+	maxSyslogFacility      = 23
+	syslogSeverityWarning  = 4
+	syslogSeverityInfo     = 6
+	syslogAppName          = "cosignwebhook"
+	syslogMsgID            = "decision"
+	defaultJournaldSocket  = "unix:///run/systemd/journal/socket"
+)
+
+// decisionSeverity returns the syslog severity of the decision, denials are warnings
+func decisionSeverity(d *Decision) int {
+	if d.Allowed {
+		return syslogSeverityInfo
+	}
+	return syslogSeverityWarning
+}
+
+// syslogBroker sends decisions as RFC 5424 messages to a syslog server over UDP, TCP or TLS.
+// Over TCP and TLS, messages are framed by octet counting as in RFC 5425.
+type syslogBroker struct {
+	url      string
+	facility int
+
+	mu       sync.Mutex
+	conn     net.Conn
+	hostname string
+}
+
+// send writes the message, connecting first if there is no connection
+func (b *syslogBroker) send(ctx context.Context, d *Decision, msg []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.conn == nil {
+		if err := b.connect(ctx); err != nil {
+			return err
+		}
+	}
+	frame := formatSyslog(b.facility*8+decisionSeverity(d), d.Time, b.hostname, msg)
+	if _, ok := b.conn.(*net.UDPConn); !ok {
+		frame = append(fmt.Appendf(nil, "%d ", len(frame)), frame...)
+	}
+	_ = b.conn.SetWriteDeadline(time.Now().Add(k8sTimeout))
+	if _, err := b.conn.Write(frame); err != nil {
+		b.conn.Close()
+		b.conn = nil
+		return err
+	}
+	return nil
+}
+
+// connect dials the syslog server with the protocol of the URL scheme
+func (b *syslogBroker) connect(ctx context.Context) error {
+	u, err := url.Parse(b.url)
+	if err != nil {
+		return fmt.Errorf("invalid syslog url %q: %w", b.url, err)
+	}
+	if b.hostname == "" {
+		b.hostname, _ = os.Hostname()
+	}
+	d := &net.Dialer{Timeout: k8sTimeout}
+	switch u.Scheme {
+	case "tls":
+		b.conn, err = (&tls.Dialer{NetDialer: d, Config: &tls.Config{MinVersion: tls.VersionTLS12}}).DialContext(ctx, "tcp", u.Host)
+	default:
+		b.conn, err = d.DialContext(ctx, u.Scheme, u.Host)
+	}
+	return err
+}
+
+func (b *syslogBroker) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.conn != nil {
+		b.conn.Close()
+		b.conn = nil
+	}
+}
+
+// formatSyslog formats an RFC 5424 message without structured data
+func formatSyslog(priority int, t time.Time, hostname string, msg []byte) []byte {
+	if hostname == "" {
+		hostname = "-"
+	}
+	header := fmt.Sprintf("<%d>1 %s %s %s %d %s - ", priority, t.UTC().Format(time.RFC3339Nano), hostname, syslogAppName, os.Getpid(), syslogMsgID)
+	return append([]byte(header), msg...)
+}
+
+// journaldBroker sends decisions to the local journal using the native journald protocol.
+// Besides the message, the decision is stored in fields prefixed with COSIGNWEBHOOK_ for filtering with journalctl.
+type journaldBroker struct {
+	socket string
+
+	mu   sync.Mutex
+	conn *net.UnixConn
+}
+
+// send writes the decision as one datagram to the journal socket
+func (b *journaldBroker) send(_ context.Context, d *Decision, msg []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.conn == nil {
+		conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: b.socket, Net: "unixgram"})
+		if err != nil {
+			return err
+		}
+		b.conn = conn
+	}
+	if _, err := b.conn.Write(journaldEntry(d, msg)); err != nil {
+		b.conn.Close()
+		b.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (b *journaldBroker) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.conn != nil {
+		b.conn.Close()
+		b.conn = nil
+	}
+}
+
+// journaldEntry serializes the decision in the native journald format.
+// Values containing newlines use the binary format with explicit length.
+func journaldEntry(d *Decision, msg []byte) []byte {
+	fields := []struct{ key, value string }{
+		{"MESSAGE", string(msg)},
+		{"PRIORITY", fmt.Sprint(decisionSeverity(d))},
+		{"SYSLOG_IDENTIFIER", syslogAppName},
+		{"COSIGNWEBHOOK_ENDPOINT", d.Endpoint},
+		{"COSIGNWEBHOOK_UID", string(d.UID)},
+		{"COSIGNWEBHOOK_KIND", d.Kind},
+		{"COSIGNWEBHOOK_NAMESPACE", d.Namespace},
+		{"COSIGNWEBHOOK_NAME", d.Name},
+		{"COSIGNWEBHOOK_ALLOWED", fmt.Sprint(d.Allowed)},
+		{"COSIGNWEBHOOK_RULE", d.Rule},
+		{"COSIGNWEBHOOK_SEMANTICS", fmt.Sprint(d.Semantics)},
+	}
+	var b bytes.Buffer
+	for _, f := range fields {
+		if f.value == "" {
+			continue
+		}
+		if !strings.Contains(f.value, "\n") {
+			fmt.Fprintf(&b, "%s=%s\n", f.key, f.value)
+			continue
+		}
+		b.WriteString(f.key + "\n")
+		b.Write(binary.LittleEndian.AppendUint64(nil, uint64(len(f.value))))
+		b.WriteString(f.value + "\n")
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_syslogBroker_send(t *testing.T) {
+	d := &Decision{UID: "123", Time: time.Date(2024, 10, 16, 9, 0, 0, 0, time.UTC), Rule: CosignRuleName}
+	wantPrefix := "<108>1 2024-10-16T09:00:00Z host cosignwebhook "
+
+	t.Run("udp", func(t *testing.T) {
+		pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pc.Close()
+		b := &syslogBroker{url: "udp://" + pc.LocalAddr().String(), facility: syslogFacilityLogAudit, hostname: "host"}
+		defer b.close()
+		if err := b.send(context.Background(), d, []byte("msg")); err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, 1024)
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := string(buf[:n])
+		if !strings.HasPrefix(got, wantPrefix) || !strings.HasSuffix(got, " decision - msg") {
+			t.Errorf("got message %q", got)
+		}
+	})
+
+	t.Run("tcp", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+		received := make(chan string, 1)
+		go func() {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			var n int
+			r := bufio.NewReader(conn)
+			if _, err := fmt.Fscanf(r, "%d ", &n); err != nil {
+				return
+			}
+			msg := make([]byte, n)
+			_, _ = r.Read(msg)
+			received <- string(msg)
+		}()
+		b := &syslogBroker{url: "tcp://" + l.Addr().String(), facility: syslogFacilityLogAudit, hostname: "host"}
+		defer b.close()
+		if err := b.send(context.Background(), d, []byte("msg")); err != nil {
+			t.Fatal(err)
+		}
+		if got := <-received; !strings.HasPrefix(got, wantPrefix) || !strings.HasSuffix(got, "msg") {
+			t.Errorf("got message %q", got)
+		}
+	})
+}
+
+func Test_journaldBroker_send(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "journal.socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	b := &journaldBroker{socket: socket}
+	defer b.close()
+	d := &Decision{UID: "123", Namespace: "default", Rule: CosignRuleName}
+	if err := b.send(context.Background(), d, []byte("line1\nline2")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := buf[:n]
+	for _, want := range []string{"PRIORITY=4\n", "COSIGNWEBHOOK_NAMESPACE=default\n", "COSIGNWEBHOOK_RULE=cosign\n", "MESSAGE\n\x0b\x00\x00\x00\x00\x00\x00\x00line1\nline2\n"} {
+		if !bytes.Contains(got, []byte(want)) {
+			t.Errorf("entry %q doesn't contain %q", got, want)
+		}
+	}
+}
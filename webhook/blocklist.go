@@ -0,0 +1,249 @@
+package webhook
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	log "github.com/gookit/slog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	v1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// BlocklistRuleName is the name of the rule denying blocked images
+	BlocklistRuleName        = "blocklist"
+	defaultBlocklistInterval = 5 * time.Minute
+	// maxBlocklistSize bounds the blocklist read from a URL
+	maxBlocklistSize = 10 << 20
+)
+
+var (
+	blocklistAge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cosign_blocklist_age_seconds",
+		Help: "The time since the blocklist was last refreshed successfully, updated on each refresh attempt",
+	}, []string{"source"})
+	blocklistEntries = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cosign_blocklist_entries",
+		Help: "The number of digests and patterns in the blocklist",
+	}, []string{"source"})
+	blocklistRefreshErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cosign_blocklist_refresh_errors_total",
+		Help: "The number of failed refreshes of the blocklist",
+	}, []string{"source"})
+)
+
+// BlocklistConfig configures the source of the blocklist of images which must never run
+type BlocklistConfig struct {
+	// Source is a file, e.g. a mounted ConfigMap, or an http(s) URL, e.g. of a CVE feed. Each line holds an image
+	// digest like sha256:<hex> or a regular expression matching the whole image, optionally followed by a reason.
+	// Empty lines and lines starting with # are skipped.
+	Source string `json:"source"`
+	// Interval of the refreshes, defaults to 5m
+	Interval metav1.Duration `json:"interval"`
+	// Retry configures the attempts of each refresh from a URL, defaults to 3 attempts within 30s
+	Retry RetryPolicy `json:"retry"`
+}
+
+// blocklist is a parsed blocklist
+type blocklist struct {
+	// digests map blocked digests to their reason
+	digests  map[string]string
+	patterns []blockedPattern
+}
+
+// blockedPattern is a blocked image pattern with its reason
+type blockedPattern struct {
+	re     *regexp.Regexp
+	reason string
+}
+
+// blocklistRule denies images on a blocklist which is refreshed from its source in the background.
+// Each refresh takes effect for the next admission request. The rules of all endpoints with the same source share
+// one instance, so the source is fetched once per interval.
+type blocklistRule struct {
+	cfg    BlocklistConfig
+	client *http.Client
+	list   atomic.Pointer[blocklist]
+	// refreshed is the time of the last successful refresh in unix nanoseconds, 0 before
+	refreshed atomic.Int64
+}
+
+func newBlocklistRule(csh *CosignServerHandler, cfg *Config) (Rule, error) {
+	c := cfg.Blocklist
+	if c.Source == "" {
+		return nil, fmt.Errorf("blocklist: source is required")
+	}
+	if c.Interval.Duration <= 0 {
+		c.Interval.Duration = defaultBlocklistInterval
+	}
+	if err := c.Retry.validate(defaultRetryPolicy); err != nil {
+		return nil, fmt.Errorf("blocklist: %w", err)
+	}
+	if r, ok := csh.blocklists[c.Source]; ok {
+		return r, nil
+	}
+	r := &blocklistRule{cfg: c, client: csh.egress.client(c.Retry.Timeout.Duration)}
+	// the webhook starts with an empty blocklist if the source is unavailable, the age metric shows it
+	_ = r.refresh(context.Background())
+	csh.tasks = append(csh.tasks, r.run)
+	if csh.blocklists == nil {
+		csh.blocklists = map[string]*blocklistRule{}
+	}
+	csh.blocklists[c.Source] = r
+	return r, nil
+}
+
+// Name returns the name of the rule
+func (*blocklistRule) Name() string {
+	return BlocklistRuleName
+}
+
+// stateful marks the verdicts as not memoizable, the blocklist changes with each refresh
+func (*blocklistRule) stateful() {}
+
+// Validate checks the images of all containers of pods and workloads against the blocklist.
+// Images are matched by digest if they are referenced by one, and by the patterns.
+func (r *blocklistRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	spec := podSpec(o)
+	list := r.list.Load()
+	if spec == nil || list == nil || o.Request.Operation == v1.Delete {
+		return nil, nil
+	}
+	if refreshed := r.refreshed.Load(); refreshed != 0 {
+		tracef(ctx, "blocklist of %s refreshed at %s", r.cfg.Source, time.Unix(0, refreshed).UTC().Format(time.RFC3339))
+	}
+	var violations []string
+	for _, c := range podContainers(spec) {
+		if reason, ok := list.blocked(c.Image); ok {
+			v := fmt.Sprintf("container %q uses blocked image %q", c.Name, c.Image)
+			if reason != "" {
+				v += ": " + reason
+			}
+			violations = append(violations, v)
+		}
+	}
+	return nil, violationsError(violations)
+}
+
+// blocked returns whether the image is blocked and the reason
+func (l *blocklist) blocked(image string) (string, bool) {
+	if _, digest, ok := strings.Cut(image, "@"); ok {
+		if reason, ok := l.digests[digest]; ok {
+			return reason, true
+		}
+	}
+	for _, p := range l.patterns {
+		if p.re.MatchString(image) {
+			return p.reason, true
+		}
+	}
+	return "", false
+}
+
+// run refreshes the blocklist in each interval until ctx is done
+func (r *blocklistRule) run(ctx context.Context) {
+	log.Infof("Refreshing blocklist from %s every %s", r.cfg.Source, r.cfg.Interval.Duration)
+	ticker := time.NewTicker(r.cfg.Interval.Duration)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = r.refresh(ctx)
+		}
+	}
+}
+
+// refresh reads and parses the blocklist from its source and replaces the current one.
+// On errors, the current blocklist is kept.
+func (r *blocklistRule) refresh(ctx context.Context) error {
+	data, err := r.read(ctx)
+	var list *blocklist
+	if err == nil {
+		list, err = parseBlocklist(data)
+	}
+	if err != nil {
+		log.Errorf("Can't refresh blocklist from %s, keeping the current one: %v", r.cfg.Source, err)
+		blocklistRefreshErrors.WithLabelValues(r.cfg.Source).Inc()
+	} else {
+		r.list.Store(list)
+		r.refreshed.Store(time.Now().UnixNano())
+		blocklistEntries.WithLabelValues(r.cfg.Source).Set(float64(len(list.digests) + len(list.patterns)))
+	}
+	if refreshed := r.refreshed.Load(); refreshed != 0 {
+		blocklistAge.WithLabelValues(r.cfg.Source).Set(time.Since(time.Unix(0, refreshed)).Seconds())
+	}
+	return err
+}
+
+// flush refreshes the blocklist from its source
+func (r *blocklistRule) flush(ctx context.Context) error {
+	return r.refresh(ctx)
+}
+
+// read returns the content of the blocklist source, URLs are read with the retry policy
+func (r *blocklistRule) read(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(r.cfg.Source, "http://") && !strings.HasPrefix(r.cfg.Source, "https://") {
+		return os.ReadFile(r.cfg.Source)
+	}
+	var data []byte
+	err := retry(ctx, &r.cfg.Retry, integrationBlocklist, destinationOf(r.cfg.Source), func(ctx context.Context) error {
+		var gerr error
+		data, gerr = r.get(ctx)
+		return gerr
+	})
+	return data, err
+}
+
+// get reads the blocklist from its URL once
+func (r *blocklistRule) get(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.cfg.Source, http.NoBody)
+	if err != nil {
+		return nil, permanent(err)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, unexpectedStatus(resp, "unexpected status %s")
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxBlocklistSize))
+}
+
+// parseBlocklist parses the lines of a blocklist into digests and patterns
+func parseBlocklist(data []byte) (*blocklist, error) {
+	list := &blocklist{digests: map[string]string{}}
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entry, reason, _ := strings.Cut(line, " ")
+		reason = strings.TrimSpace(reason)
+		if strings.HasPrefix(entry, "sha256:") {
+			list.digests[entry] = reason
+			continue
+		}
+		res, err := compilePatterns([]string{entry})
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		list.patterns = append(list.patterns, blockedPattern{re: res[0], reason: reason})
+	}
+	return list, scanner.Err()
+}
@@ -0,0 +1,136 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const testBlocklist = `# CVE-2024-3094
+sha256:8f1e2a2c4b7d6e5f9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e2f backdoored xz
+
+registry.example.com/legacy/.*
+`
+
+func Test_blocklistRule_Validate(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(source, []byte(testBlocklist), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r, err := newBlocklistRule(&CosignServerHandler{}, &Config{Blocklist: BlocklistConfig{Source: source}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		image   string
+		wantErr string
+	}{
+		{
+			name:  "not blocked",
+			image: "ghcr.io/eumel8/app:1.0",
+		},
+		{
+			name:    "blocked digest",
+			image:   "ghcr.io/eumel8/xz@sha256:8f1e2a2c4b7d6e5f9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e2f",
+			wantErr: "uses blocked image \"ghcr.io/eumel8/xz@sha256:8f1e2a2c4b7d6e5f9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e2f\": backdoored xz",
+		},
+		{
+			name:    "blocked pattern",
+			image:   "registry.example.com/legacy/app:1.0",
+			wantErr: "container \"app\" uses blocked image \"registry.example.com/legacy/app:1.0\"",
+		},
+		{
+			name:  "pattern matches the whole image",
+			image: "mirror.example.com/registry.example.com/legacy/app:1.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Validate(context.Background(), podObject("default", corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Image: tt.image}},
+			}))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_blocklistRule_refresh(t *testing.T) {
+	var mu sync.Mutex
+	content, status := "", http.StatusOK
+	serve := func(c string, s int) {
+		mu.Lock()
+		defer mu.Unlock()
+		content, status = c, s
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(content))
+	}))
+	defer srv.Close()
+
+	csh := &CosignServerHandler{}
+	rule, err := newBlocklistRule(csh, &Config{Blocklist: BlocklistConfig{Source: srv.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the rules of the same source share the blocklist and its refresh
+	shared, err := newBlocklistRule(csh, &Config{Blocklist: BlocklistConfig{Source: srv.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(csh.tasks) != 1 || shared != rule {
+		t.Fatalf("got %d tasks and a rule of its own, want one refresh of the shared rule", len(csh.tasks))
+	}
+	r := rule.(*blocklistRule)
+	pod := podObject("default", corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "ghcr.io/eumel8/app:1.0"}}})
+	if _, err := r.Validate(context.Background(), pod); err != nil {
+		t.Fatalf("Validate() error = %v before the image is blocked", err)
+	}
+
+	serve("ghcr.io/eumel8/app:.*\n", http.StatusOK)
+	if err := r.refresh(context.Background()); err != nil {
+		t.Fatalf("refresh() error = %v", err)
+	}
+	if _, err := r.Validate(context.Background(), pod); err == nil {
+		t.Fatal("Validate() allowed image after it was blocked")
+	}
+
+	// failed refreshes keep the blocklist
+	serve("", http.StatusNotFound)
+	if err := r.refresh(context.Background()); err == nil {
+		t.Error("refresh() with status not found, want error")
+	}
+	serve("ghcr.io/(unclosed\n", http.StatusOK)
+	if err := r.refresh(context.Background()); err == nil {
+		t.Error("refresh() with invalid pattern, want error")
+	}
+	if _, err := r.Validate(context.Background(), pod); err == nil {
+		t.Fatal("Validate() allowed image after failed refreshes")
+	}
+}
+
+func Test_newBlocklistRule(t *testing.T) {
+	if _, err := newBlocklistRule(&CosignServerHandler{}, &Config{}); err == nil {
+		t.Error("newBlocklistRule() without source, want error")
+	}
+}
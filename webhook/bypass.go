@@ -0,0 +1,53 @@
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	log "github.com/gookit/slog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// BypassAnnotation opts an object out of validation if set to "true" and the bypass is enabled
+const BypassAnnotation = "cosignwebhook.eumel8.io/ignore"
+
+var bypassedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "cosign_bypassed_requests_total",
+	Help: "The number of admission requests admitted without validation by the opt-out annotation, by namespace",
+}, []string{"namespace"})
+
+// BypassConfig configures the opt-out of single objects from validation
+type BypassConfig struct {
+	// Enabled lets objects annotated with cosignwebhook.eumel8.io/ignore: "true" skip all rules
+	Enabled bool `json:"enabled"`
+	// Groups of which the requesting user needs one to use the annotation, e.g. an on-call group.
+	// Empty allows all users.
+	Groups []string `json:"groups"`
+}
+
+// bypass admits the request without evaluating any rule if the object opts out with the annotation, and returns
+// whether it did. Each bypass is logged and recorded as decision with the requesting user for the audit trail.
+func (e *Endpoint) bypass(w http.ResponseWriter, o *Object) bool {
+	c := e.csh.cfg.Bypass
+	if !c.Enabled || o.Meta.Annotations[BypassAnnotation] != "true" {
+		return false
+	}
+	user := o.Request.UserInfo
+	if len(c.Groups) > 0 && !slices.ContainsFunc(user.Groups, func(g string) bool { return slices.Contains(c.Groups, g) }) {
+		log.Warnf("User %s isn't allowed to bypass validation of %s %s/%s with annotation %s, validating it",
+			user.Username, o.Request.Kind.Kind, o.Request.Namespace, o.Request.Name, BypassAnnotation)
+		return false
+	}
+	log.Warnf("Validation of %s %s/%s on %s bypassed with annotation %s by %s",
+		o.Request.Kind.Kind, o.Request.Namespace, o.Request.Name, e.Path, BypassAnnotation, user.Username)
+	bypassedRequests.WithLabelValues(o.Request.Namespace).Inc()
+	d := newDecision(e.Path, o.Request, e.csh.semantics)
+	d.Allowed, d.Bypassed = true, true
+	d.Message = fmt.Sprintf("Validation bypassed with annotation %s by %s", BypassAnnotation, user.Username)
+	d.Warnings = []string{d.Message}
+	e.csh.recordDecision(d)
+	accept(w, d.Message, o.Request.UID, d.Warnings...)
+	return true
+}
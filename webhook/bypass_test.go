@@ -0,0 +1,104 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestEndpoint_bypass(t *testing.T) {
+	tests := []struct {
+		name         string
+		cfg          BypassConfig
+		annotation   string
+		groups       []string
+		wantBypassed bool
+	}{
+		{
+			name:       "disabled",
+			annotation: "true",
+		},
+		{
+			name:         "enabled",
+			cfg:          BypassConfig{Enabled: true},
+			annotation:   "true",
+			wantBypassed: true,
+		},
+		{
+			name:       "not annotated",
+			cfg:        BypassConfig{Enabled: true},
+			annotation: "false",
+		},
+		{
+			name:         "user in allowed group",
+			cfg:          BypassConfig{Enabled: true, Groups: []string{"oncall"}},
+			annotation:   "true",
+			groups:       []string{"system:authenticated", "oncall"},
+			wantBypassed: true,
+		},
+		{
+			name:       "user not in allowed group",
+			cfg:        BypassConfig{Enabled: true, Groups: []string{"oncall"}},
+			annotation: "true",
+			groups:     []string{"system:authenticated"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &countingRule{name: SanityRuleName, err: errors.New("broken pod spec")}
+			csh := &CosignServerHandler{cfg: &Config{Bypass: tt.cfg}}
+			e := &Endpoint{Path: DefaultPath, rules: []Rule{rule}, csh: csh}
+			var decisions []*Decision
+			csh.OnDecision(func(d *Decision) { decisions = append(decisions, d) })
+
+			pod, err := json.Marshal(corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name:        "app",
+				Namespace:   "default",
+				Annotations: map[string]string{BypassAnnotation: tt.annotation},
+			}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := json.Marshal(v1.AdmissionReview{Request: &v1.AdmissionRequest{
+				UID:       "1",
+				Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+				Namespace: "default",
+				Name:      "app",
+				Operation: v1.Create,
+				UserInfo:  authenticationv1.UserInfo{Username: "jane", Groups: tt.groups},
+				Object:    runtime.RawExtension{Raw: pod},
+			}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, DefaultPath, bytes.NewReader(body)))
+			ar := v1.AdmissionReview{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &ar); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(decisions) != 1 {
+				t.Fatalf("got %d decisions, want 1", len(decisions))
+			}
+			d := decisions[0]
+			if d.Bypassed != tt.wantBypassed || ar.Response.Allowed != tt.wantBypassed {
+				t.Errorf("decision %+v, allowed %v, want bypassed %v", d, ar.Response.Allowed, tt.wantBypassed)
+			}
+			if tt.wantBypassed && (rule.calls != 0 || !strings.Contains(d.Message, "by jane") || len(ar.Response.Warnings) != 1) {
+				t.Errorf("bypassed decision %+v with %d rule calls, want no calls and the user in message and warning", d, rule.calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	v1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	nodev1listers "k8s.io/client-go/listers/node/v1"
+)
+
+// CapabilitiesRuleName is the name of the rule enforcing dropped Linux capabilities
+const CapabilitiesRuleName = "capabilities"
+
+var (
+	// defaultAllowedCapabilities may be added by default, like in the restricted pod security standard
+	defaultAllowedCapabilities = []string{"NET_BIND_SERVICE"}
+	// defaultForbiddenCapabilities are denied by default, even if allowed
+	defaultForbiddenCapabilities = []string{"SYS_ADMIN", "NET_ADMIN"}
+)
+
+// CapabilitiesConfig configures the Linux capabilities containers may add after dropping ALL
+type CapabilitiesConfig struct {
+	// Allowed are the capabilities containers may add, defaults to NET_BIND_SERVICE
+	Allowed []string `json:"allowed"`
+	// Forbidden are the capabilities containers may never add, even if allowed. Defaults to SYS_ADMIN and NET_ADMIN,
+	// an empty list forbids none.
+	Forbidden []string `json:"forbidden"`
+	// ExemptNamespaces may run containers with any capabilities
+	ExemptNamespaces []string `json:"exemptNamespaces"`
+	// Action is either deny (default) or warn
+	Action string `json:"action"`
+}
+
+// capabilitiesRule requires containers to drop ALL capabilities and only add allowed ones
+type capabilitiesRule struct {
+	cfg CapabilitiesConfig
+	// runtimeClasses detect Windows pods by the node selector of their runtime class
+	runtimeClasses nodev1listers.RuntimeClassLister
+}
+
+func newCapabilitiesRule(csh *CosignServerHandler, cfg *Config) (Rule, error) {
+	c := cfg.Capabilities
+	if err := validateAction(&c.Action, ActionDeny); err != nil {
+		return nil, err
+	}
+	if c.Allowed == nil {
+		c.Allowed = defaultAllowedCapabilities
+	}
+	if c.Forbidden == nil {
+		c.Forbidden = defaultForbiddenCapabilities
+	}
+	c.Allowed = normalizeCapabilities(c.Allowed)
+	c.Forbidden = normalizeCapabilities(c.Forbidden)
+	return &capabilitiesRule{cfg: c, runtimeClasses: csh.informers.Node().V1().RuntimeClasses().Lister()}, nil
+}
+
+// Name returns the name of the rule
+func (*capabilitiesRule) Name() string {
+	return CapabilitiesRuleName
+}
+
+// Validate checks the capabilities of the init, regular and ephemeral containers of pods and workloads. Windows has
+// no Linux capabilities, so Windows pods are skipped.
+func (r *capabilitiesRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	spec := podSpec(o)
+	if spec == nil || o.Request.Operation == v1.Delete {
+		return nil, nil
+	}
+	if slices.Contains(r.cfg.ExemptNamespaces, o.Request.Namespace) {
+		tracef(ctx, "namespace %q is exempt", o.Request.Namespace)
+		return nil, nil
+	}
+	if podOS(spec, r.runtimeClasses) == corev1.Windows {
+		tracef(ctx, "Windows pod")
+		return nil, nil
+	}
+
+	var violations []string
+	for _, c := range podContainers(spec) {
+		var caps corev1.Capabilities
+		if c.SecurityContext != nil && c.SecurityContext.Capabilities != nil {
+			caps = *c.SecurityContext.Capabilities
+		}
+		if !slices.Contains(normalizeCapabilities(capabilityNames(caps.Drop)), "ALL") {
+			violations = append(violations, fmt.Sprintf("container %q doesn't drop ALL capabilities", c.Name))
+		}
+		for _, add := range normalizeCapabilities(capabilityNames(caps.Add)) {
+			switch {
+			case slices.Contains(r.cfg.Forbidden, add):
+				violations = append(violations, fmt.Sprintf("container %q adds forbidden capability %s", c.Name, add))
+			case !slices.Contains(r.cfg.Allowed, add):
+				violations = append(violations, fmt.Sprintf("container %q adds capability %s which isn't allowed, allowed: %s",
+					c.Name, add, strings.Join(r.cfg.Allowed, ", ")))
+			}
+		}
+	}
+	return enforce(r.cfg.Action, violations)
+}
+
+// capabilityNames returns the names of the capabilities
+func capabilityNames(caps []corev1.Capability) []string {
+	names := make([]string, 0, len(caps))
+	for _, c := range caps {
+		names = append(names, string(c))
+	}
+	return names
+}
+
+// normalizeCapabilities returns the capabilities upper case without CAP_ prefix, as the container runtimes accept
+// both
+func normalizeCapabilities(caps []string) []string {
+	normalized := make([]string, 0, len(caps))
+	for _, c := range caps {
+		normalized = append(normalized, strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(c)), "CAP_"))
+	}
+	return normalized
+}
@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_capabilitiesRule_Validate(t *testing.T) {
+	caps := func(drop []corev1.Capability, add ...corev1.Capability) corev1.PodSpec {
+		return corev1.PodSpec{Containers: []corev1.Container{{
+			Name:            "app",
+			SecurityContext: &corev1.SecurityContext{Capabilities: &corev1.Capabilities{Drop: drop, Add: add}},
+		}}}
+	}
+	all := []corev1.Capability{"ALL"}
+	windows := "windows"
+	tests := []struct {
+		name         string
+		namespace    string
+		spec         corev1.PodSpec
+		cfg          CapabilitiesConfig
+		wantWarnings int
+		wantErr      string
+	}{
+		{
+			name: "drops all",
+			spec: caps(all),
+		},
+		{
+			name:    "no security context",
+			spec:    corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+			wantErr: `container "app" doesn't drop ALL capabilities`,
+		},
+		{
+			name:    "drops some",
+			spec:    caps([]corev1.Capability{"NET_RAW"}),
+			wantErr: `container "app" doesn't drop ALL capabilities`,
+		},
+		{
+			name: "adds default allowed capability with prefix",
+			spec: caps([]corev1.Capability{"all"}, "CAP_NET_BIND_SERVICE"),
+		},
+		{
+			name:    "adds capability which isn't allowed",
+			spec:    caps(all, "CHOWN"),
+			wantErr: `container "app" adds capability CHOWN which isn't allowed, allowed: NET_BIND_SERVICE`,
+		},
+		{
+			name:    "adds forbidden capability by default",
+			spec:    caps(all, "SYS_ADMIN", "NET_ADMIN"),
+			wantErr: `container "app" adds forbidden capability SYS_ADMIN; container "app" adds forbidden capability NET_ADMIN`,
+		},
+		{
+			name:    "forbidden wins over allowed",
+			spec:    caps(all, "SYS_ADMIN"),
+			cfg:     CapabilitiesConfig{Allowed: []string{"SYS_ADMIN"}},
+			wantErr: `container "app" adds forbidden capability SYS_ADMIN`,
+		},
+		{
+			name: "nothing forbidden",
+			spec: caps(all, "NET_ADMIN"),
+			cfg:  CapabilitiesConfig{Allowed: []string{"net_admin"}, Forbidden: []string{}},
+		},
+		{
+			name: "windows",
+			spec: corev1.PodSpec{OS: &corev1.PodOS{Name: corev1.Windows}, Containers: []corev1.Container{{Name: "app"}}},
+		},
+		{
+			name: "windows runtime class",
+			spec: corev1.PodSpec{RuntimeClassName: &windows, Containers: []corev1.Container{{Name: "app"}}},
+		},
+		{
+			name:      "exempt namespace",
+			namespace: "kube-system",
+			spec:      caps(nil, "SYS_ADMIN"),
+			cfg:       CapabilitiesConfig{ExemptNamespaces: []string{"kube-system"}},
+		},
+		{
+			name:         "warn",
+			spec:         caps(nil),
+			cfg:          CapabilitiesConfig{Action: ActionWarn},
+			wantWarnings: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := testRule(t, newCapabilitiesRule, &Config{Capabilities: tt.cfg}, windowsRuntimeClass())
+			ns := tt.namespace
+			if ns == "" {
+				ns = "default"
+			}
+			warnings, err := r.Validate(context.Background(), podObject(ns, tt.spec))
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("Validate() warnings = %v, want %d", warnings, tt.wantWarnings)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/gookit/slog"
+)
+
+// CertReloader serves the certificate of the TLS files and reloads it when the files change, e.g. when the kubelet
+// updates the mounted Secret after a rotation. New connections get the new certificate, established ones keep theirs.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	certMod time.Time
+	keyMod  time.Time
+}
+
+// NewCertReloader loads the certificate of the files. The reloader is returned on errors too, it retries loading
+// on each handshake.
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	c := &CertReloader{certFile: certFile, keyFile: keyFile}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c, c.reload()
+}
+
+// GetCertificate returns the current certificate for tls.Config, reloading it if the files changed.
+// If the changed files can't be loaded, e.g. while only one of them is written, the previous certificate is kept.
+func (c *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.reload(); err != nil {
+		if c.cert == nil {
+			return nil, err
+		}
+		log.Errorf("Can't reload serving certificate, keeping the previous one: %v", err)
+	}
+	return c.cert, nil
+}
+
+// Leaf returns the parsed current certificate, nil if none is loaded
+func (c *CertReloader) Leaf() *x509.Certificate {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cert == nil {
+		return nil
+	}
+	return c.cert.Leaf
+}
+
+// reload loads the certificate if the modification time of a file changed since the last load
+func (c *CertReloader) reload() error {
+	certInfo, err := os.Stat(c.certFile)
+	if err != nil {
+		return err
+	}
+	keyInfo, err := os.Stat(c.keyFile)
+	if err != nil {
+		return err
+	}
+	if c.cert != nil && certInfo.ModTime().Equal(c.certMod) && keyInfo.ModTime().Equal(c.keyMod) {
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return err
+		}
+	}
+	if c.cert != nil {
+		log.Infof("Reloaded serving certificate, valid until %s", cert.Leaf.NotAfter.Format(time.RFC3339))
+	}
+	c.cert, c.certMod, c.keyMod = &cert, certInfo.ModTime(), keyInfo.ModTime()
+	return nil
+}
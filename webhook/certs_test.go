@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	mod := time.Now()
+	write := func(name string) {
+		t.Helper()
+		_, cert, key, err := starterCerts([]string{name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		mod = mod.Add(time.Second)
+		for file, data := range map[string][]byte{certFile: cert, keyFile: key} {
+			if err := os.WriteFile(file, data, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(file, mod, mod); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	served := func(c *CertReloader) string {
+		t.Helper()
+		cert, err := c.GetCertificate(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert.Leaf.Subject.CommonName
+	}
+
+	if _, err := NewCertReloader(certFile, keyFile); err == nil {
+		t.Error("NewCertReloader() without files = nil, want error")
+	}
+	write("first")
+	c, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := served(c); got != "first" {
+		t.Errorf("GetCertificate() = %s, want first", got)
+	}
+
+	write("second")
+	if got := served(c); got != "second" {
+		t.Errorf("GetCertificate() after rotation = %s, want second", got)
+	}
+	if got := c.Leaf().Subject.CommonName; got != "second" {
+		t.Errorf("Leaf() = %s, want second", got)
+	}
+
+	// a half written rotation keeps the previous certificate
+	if err := os.WriteFile(keyFile, []byte("invalid"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(keyFile, mod.Add(time.Second), mod.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if got := served(c); got != "second" {
+		t.Errorf("GetCertificate() with invalid key = %s, want second", got)
+	}
+}
@@ -0,0 +1,79 @@
+package webhook
+
+import (
+	"fmt"
+	"time"
+)
+
+// CloudEvents types of the notifications emitted by the webhook
+const (
+	// EventTypeDecisionAllowed is the type of decisions allowing a request without warnings
+	EventTypeDecisionAllowed = "io.eumel8.grumpy.decision.allowed"
+	// EventTypeDecisionWarned is the type of decisions allowing a request with warnings
+	EventTypeDecisionWarned = "io.eumel8.grumpy.decision.warned"
+	// EventTypeDecisionDenied is the type of decisions denying a request
+	EventTypeDecisionDenied = "io.eumel8.grumpy.decision.denied"
+	// EventTypeTelemetryReport is the type of the usage stats sent by the telemetry reporter
+	EventTypeTelemetryReport = "io.eumel8.grumpy.telemetry.report"
+)
+
+const (
+	cloudEventsSpecVersion = "1.0"
+	// CloudEventsContentType is the content type of CloudEvents in structured mode
+	CloudEventsContentType = "application/cloudevents+json"
+	cloudEventsSource      = "/cosignwebhook"
+)
+
+// CloudEvent is a CloudEvents 1.0 event in structured JSON mode
+type CloudEvent struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject,omitempty"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	Data            any       `json:"data"`
+}
+
+// decisionEvent returns the decision as CloudEvent. The source is the endpoint, the subject the object
+// in the form kind/namespace/name.
+func decisionEvent(d *Decision) *CloudEvent {
+	t := EventTypeDecisionAllowed
+	switch {
+	case !d.Allowed:
+		t = EventTypeDecisionDenied
+	case len(d.Warnings) > 0:
+		t = EventTypeDecisionWarned
+	}
+	subject := d.Kind
+	if d.Namespace != "" {
+		subject += "/" + d.Namespace
+	}
+	if d.Name != "" {
+		subject += "/" + d.Name
+	}
+	return &CloudEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              string(d.UID),
+		Source:          cloudEventsSource + d.Endpoint,
+		Type:            t,
+		Subject:         subject,
+		Time:            d.Time,
+		DataContentType: "application/json",
+		Data:            d,
+	}
+}
+
+// telemetryEvent returns the telemetry report as CloudEvent
+func telemetryEvent(r *TelemetryReport, now time.Time) *CloudEvent {
+	return &CloudEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              fmt.Sprintf("%s-%d", r.ClusterID, now.UnixNano()),
+		Source:          cloudEventsSource + "/telemetry",
+		Type:            EventTypeTelemetryReport,
+		Time:            now,
+		DataContentType: "application/json",
+		Data:            r,
+	}
+}
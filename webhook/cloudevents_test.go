@@ -0,0 +1,39 @@
+package webhook
+
+import "testing"
+
+func Test_decisionEvent(t *testing.T) {
+	tests := []struct {
+		name        string
+		d           *Decision
+		wantType    string
+		wantSubject string
+	}{
+		{
+			name:        "allowed",
+			d:           &Decision{Endpoint: "/validate", Kind: "Pod", Namespace: "default", Name: "app", Allowed: true},
+			wantType:    EventTypeDecisionAllowed,
+			wantSubject: "Pod/default/app",
+		},
+		{
+			name:        "warned",
+			d:           &Decision{Endpoint: "/validate", Kind: "Pod", Namespace: "default", Name: "app", Allowed: true, Warnings: []string{"warning"}},
+			wantType:    EventTypeDecisionWarned,
+			wantSubject: "Pod/default/app",
+		},
+		{
+			name:        "denied cluster scoped",
+			d:           &Decision{Endpoint: "/validate", Kind: "Namespace", Name: "dev"},
+			wantType:    EventTypeDecisionDenied,
+			wantSubject: "Namespace/dev",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := decisionEvent(tt.d)
+			if e.Type != tt.wantType || e.Subject != tt.wantSubject || e.Source != "/cosignwebhook/validate" {
+				t.Errorf("decisionEvent() = %s %s %s, want %s %s /cosignwebhook/validate", e.Type, e.Subject, e.Source, tt.wantType, tt.wantSubject)
+			}
+		})
+	}
+}
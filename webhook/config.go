@@ -0,0 +1,262 @@
+package webhook
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultPath is the path of the admission endpoint if no config is given
+const DefaultPath = "/validate"
+
+var (
+	//go:embed defaults/config.yaml
+	defaultConfig []byte
+	//go:embed defaults/config.schema.json
+	configSchema []byte
+)
+
+// Config is the configuration of the webhook, usually read from a YAML file
+type Config struct {
+	// Server are the settings of the webhook server, env vars and flags take precedence
+	Server ServerConfig `json:"server"`
+	// Endpoints are the admission endpoints served by the webhook, each with its own rule set
+	Endpoints []EndpointConfig `json:"endpoints"`
+
+	PriorityClass    PriorityClassConfig    `json:"priorityClass"`
+	RuntimeClass     RuntimeClassConfig     `json:"runtimeClass"`
+	SecurityProfiles SecurityProfilesConfig `json:"securityProfiles"`
+	Env              EnvConfig              `json:"env"`
+	Probes           ProbesConfig           `json:"probes"`
+	Deprecation      DeprecationConfig      `json:"deprecation"`
+	Topology         TopologyConfig         `json:"topology"`
+	Cost             CostConfig             `json:"cost"`
+	Naming           NamingConfig           `json:"naming"`
+	TTL              TTLConfig              `json:"ttl"`
+	Duplicates       DuplicatesConfig       `json:"duplicates"`
+	ServicePorts     ServicePortsConfig     `json:"servicePorts"`
+	HPA              HPAConfig              `json:"hpa"`
+	RBAC             RBACConfig             `json:"rbac"`
+	Namespace        NamespaceConfig        `json:"namespace"`
+	Registries       RegistriesConfig       `json:"registries"`
+
+	ServiceAccountTokens   ServiceAccountTokensConfig   `json:"serviceAccountTokens"`
+	Blocklist              BlocklistConfig              `json:"blocklist"`
+	Licenses               LicensesConfig               `json:"licenses"`
+	ImageTags              ImageTagsConfig              `json:"imageTags"`
+	Resources              ResourcesConfig              `json:"resources"`
+	RequiredLabels         RequiredLabelsConfig         `json:"requiredLabels"`
+	RequiredAnnotations    RequiredAnnotationsConfig    `json:"requiredAnnotations"`
+	Privileged             PrivilegedConfig             `json:"privileged"`
+	HostPath               HostPathConfig               `json:"hostPath"`
+	HostNamespaces         HostNamespacesConfig         `json:"hostNamespaces"`
+	RunAsNonRoot           RunAsNonRootConfig           `json:"runAsNonRoot"`
+	ReadOnlyRootFilesystem ReadOnlyRootFilesystemConfig `json:"readOnlyRootFilesystem"`
+	Capabilities           CapabilitiesConfig           `json:"capabilities"`
+	ServiceAccounts        ServiceAccountsConfig        `json:"serviceAccounts"`
+	Scheduling             SchedulingConfig             `json:"scheduling"`
+	Replicas               ReplicasConfig               `json:"replicas"`
+	ContainerPorts         ContainerPortsConfig         `json:"containerPorts"`
+	IngressHosts           IngressHostsConfig           `json:"ingressHosts"`
+
+	// Decisions configures the buffer of recent decisions and the decision stream
+	Decisions DecisionsConfig `json:"decisions"`
+	// Evaluate configures the API evaluating objects without admission
+	Evaluate EvaluateConfig `json:"evaluate"`
+	// Watchdog limits the wall-clock time of rule evaluations
+	Watchdog WatchdogConfig `json:"watchdog"`
+	// Queue configures the fair queueing of admission requests by namespace
+	Queue QueueConfig `json:"queue"`
+	// Memoize configures the reuse of verdicts for identical pods of a ReplicaSet
+	Memoize MemoizeConfig `json:"memoize"`
+	// Bypass configures the opt-out annotation of single objects
+	Bypass BypassConfig `json:"bypass"`
+	// Order configures the evaluation order of the rules
+	Order OrderConfig `json:"order"`
+	// Informers configures the caches of the informers used by the rules
+	Informers InformersConfig `json:"informers"`
+	// Publishers push decisions to message brokers
+	Publishers []PublisherConfig `json:"publishers"`
+	// Telemetry configures the opt-in export of anonymous usage stats
+	Telemetry TelemetryConfig `json:"telemetry"`
+	// SLO configures the latency and availability objectives of the admission requests
+	SLO SLOConfig `json:"slo"`
+	// Admin configures the admin API, e.g. flushing the caches
+	Admin AdminConfig `json:"admin"`
+	// SharedCache configures the cache of verdicts shared by the replicas
+	SharedCache SharedCacheConfig `json:"sharedCache"`
+	// Facts configures the providers of the facts rules depend on
+	Facts FactsConfig `json:"facts"`
+
+	// shadow is the config evaluated in shadow mode with semantics version shadowSemantics, see ServerConfig
+	shadow          *Config
+	shadowSemantics int
+}
+
+// EndpointConfig configures an admission endpoint and the rules evaluated on it
+type EndpointConfig struct {
+	Path  string   `json:"path"`
+	Rules []string `json:"rules"`
+	// Forward chains a downstream validating webhook after the rules
+	Forward *ForwardConfig `json:"forward,omitempty"`
+}
+
+// DefaultConfig returns the configuration used if no config file is given, embedded from defaults/config.yaml.
+// It serves the cosign signature verification, the sanity checks and the deprecation warnings on /validate.
+func DefaultConfig() *Config {
+	cfg := &Config{}
+	if err := yaml.UnmarshalStrict(defaultConfig, cfg); err != nil {
+		panic(fmt.Sprintf("invalid embedded default config: %v", err))
+	}
+	return cfg
+}
+
+// ConfigSchema returns the JSON schema of the config file
+func ConfigSchema() []byte {
+	return configSchema
+}
+
+// LoadConfig reads the config from the YAML file with passed path and validates it
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read config file %q: %w", path, err)
+	}
+	return ParseConfig(data)
+}
+
+// ParseConfig parses the config from passed YAML data and validates it
+func ParseConfig(data []byte) (*Config, error) {
+	cfg := &Config{}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("can't parse config: %w", err)
+	}
+	if len(cfg.Endpoints) == 0 {
+		cfg.Endpoints = DefaultConfig().Endpoints
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// validate checks that endpoint paths are unique, all referenced rules exist and the publishers are valid
+func (cfg *Config) validate() error {
+	paths := map[string]bool{}
+	for i := range cfg.Endpoints {
+		e := &cfg.Endpoints[i]
+		if !strings.HasPrefix(e.Path, "/") {
+			return fmt.Errorf("endpoint path %q must start with '/'", e.Path)
+		}
+		if paths[e.Path] {
+			return fmt.Errorf("endpoint path %q configured more than once", e.Path)
+		}
+		paths[e.Path] = true
+		if e.Forward != nil {
+			if err := e.Forward.validate(); err != nil {
+				return fmt.Errorf("endpoint %q: %w", e.Path, err)
+			}
+		}
+		for _, r := range e.Rules {
+			if _, ok := ruleFactories[r]; !ok {
+				return fmt.Errorf("unknown rule %q for endpoint %q", r, e.Path)
+			}
+		}
+	}
+	for i := range cfg.Publishers {
+		if err := cfg.Publishers[i].validate(); err != nil {
+			return err
+		}
+	}
+	if err := cfg.Telemetry.Retry.validate(defaultRetryPolicy); err != nil {
+		return fmt.Errorf("telemetry: %w", err)
+	}
+	if err := cfg.SLO.validate(); err != nil {
+		return err
+	}
+	if err := cfg.Admin.validate(); err != nil {
+		return err
+	}
+	if err := cfg.Decisions.validate(); err != nil {
+		return err
+	}
+	if err := cfg.Evaluate.validate(); err != nil {
+		return err
+	}
+	return cfg.SharedCache.validate()
+}
+
+// usesRule returns true if the rule is enabled on an endpoint
+func (cfg *Config) usesRule(rule string) bool {
+	for _, e := range cfg.Endpoints {
+		if slices.Contains(e.Rules, rule) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanonicalConfig returns the config as generic value without empty settings, so configs with the same
+// effect serialize the same way, e.g. for diffing the config in the cluster against the one in git.
+// Map keys are sorted by the JSON and YAML encoders.
+func CanonicalConfig(cfg *Config) (map[string]any, error) {
+	v, err := CanonicalValue(cfg)
+	if err != nil {
+		return nil, err
+	}
+	canonical, _ := v.(map[string]any)
+	return canonical, nil
+}
+
+// CanonicalValue returns the value as generic value without empty settings like CanonicalConfig, e.g. for the
+// naming conventions of a ConfigMap exported along with the config
+func CanonicalValue(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return pruneEmpty(v), nil
+}
+
+// pruneEmpty removes nulls, empty strings, false, zeros and empty collections from the JSON value
+func pruneEmpty(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, child := range t {
+			if child = pruneEmpty(child); child == nil {
+				delete(t, k)
+			} else {
+				t[k] = child
+			}
+		}
+		if len(t) == 0 {
+			return nil
+		}
+	case []any:
+		if len(t) == 0 {
+			return nil
+		}
+	case string:
+		if strings.TrimSpace(t) == "" {
+			return nil
+		}
+	case bool:
+		if !t {
+			return nil
+		}
+	case float64:
+		if t == 0 {
+			return nil
+		}
+	}
+	return v
+}
@@ -0,0 +1,207 @@
+package webhook
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          string
+		wantEndpoints int
+		wantErr       bool
+	}{
+		{
+			name:          "empty config uses default endpoint",
+			data:          "",
+			wantEndpoints: 1,
+		},
+		{
+			name: "multiple endpoints",
+			data: `
+endpoints:
+  - path: /validate/workloads
+    rules: [cosign]
+  - path: /validate/networking
+    rules: []
+`,
+			wantEndpoints: 2,
+		},
+		{
+			name: "unknown rule",
+			data: `
+endpoints:
+  - path: /validate
+    rules: [nope]
+`,
+			wantErr: true,
+		},
+		{
+			name: "forward over http",
+			data: `
+endpoints:
+  - path: /validate
+    rules: [cosign]
+    forward:
+      url: http://other-webhook.default.svc/validate
+`,
+			wantErr: true,
+		},
+		{
+			name: "duplicate path",
+			data: `
+endpoints:
+  - path: /validate
+  - path: /validate
+`,
+			wantErr: true,
+		},
+		{
+			name: "relative path",
+			data: `
+endpoints:
+  - path: validate
+`,
+			wantErr: true,
+		},
+		{
+			name: "decision stream without groups",
+			data: `
+decisions:
+  stream: true
+`,
+			wantErr: true,
+		},
+		{
+			name: "evaluate API without groups",
+			data: `
+evaluate:
+  enabled: true
+`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown field",
+			data:    "endpoint: []",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := ParseConfig([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(cfg.Endpoints) != tt.wantEndpoints {
+				t.Errorf("ParseConfig() got %d endpoints, want %d", len(cfg.Endpoints), tt.wantEndpoints)
+			}
+		})
+	}
+}
+
+func TestDefaultConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	if err := cfg.validate(); err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Endpoints) != 1 || cfg.Endpoints[0].Path != DefaultPath || !slices.Contains(cfg.Endpoints[0].Rules, CosignRuleName) {
+		t.Errorf("DefaultConfig() endpoints = %+v, want cosign on %s", cfg.Endpoints, DefaultPath)
+	}
+}
+
+// TestConfigSchema checks that the embedded schema covers all config sections and rules
+func TestConfigSchema(t *testing.T) {
+	var schema struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	if err := json.Unmarshal(ConfigSchema(), &schema); err != nil {
+		t.Fatal(err)
+	}
+	ct := reflect.TypeOf(Config{})
+	sections := 0
+	for i := 0; i < ct.NumField(); i++ {
+		if !ct.Field(i).IsExported() {
+			continue
+		}
+		sections++
+		name, _, _ := strings.Cut(ct.Field(i).Tag.Get("json"), ",")
+		if _, ok := schema.Properties[name]; !ok {
+			t.Errorf("schema misses config section %q", name)
+		}
+	}
+	if len(schema.Properties) != sections {
+		t.Errorf("schema has %d sections, config %d", len(schema.Properties), sections)
+	}
+
+	var endpoints struct {
+		Items struct {
+			Properties struct {
+				Rules struct {
+					Items struct {
+						Enum []string `json:"enum"`
+					} `json:"items"`
+				} `json:"rules"`
+			} `json:"properties"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(schema.Properties["endpoints"], &endpoints); err != nil {
+		t.Fatal(err)
+	}
+	rules := endpoints.Items.Properties.Rules.Items.Enum
+	for name := range ruleFactories {
+		if !slices.Contains(rules, name) {
+			t.Errorf("schema misses rule %q", name)
+		}
+	}
+	if len(rules) != len(ruleFactories) {
+		t.Errorf("schema has %d rules, %d are registered", len(rules), len(ruleFactories))
+	}
+}
+
+func TestCanonicalConfig(t *testing.T) {
+	a, err := ParseConfig([]byte(`
+endpoints:
+  - path: /validate
+    rules: [cosign, probes]
+probes:
+  action: warn
+  namespaces: []
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ParseConfig([]byte(`
+probes:
+  action: warn
+endpoints:
+  - rules: [cosign, probes]
+    path: /validate
+server:
+  logLevel: ""
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := CanonicalConfig(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb, err := CanonicalConfig(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ca, cb) {
+		t.Errorf("canonical configs differ: %v != %v", ca, cb)
+	}
+	if _, ok := ca["server"]; ok {
+		t.Errorf("canonical config contains empty section server: %v", ca)
+	}
+}
@@ -0,0 +1,80 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	v1 "k8s.io/api/admission/v1"
+)
+
+// ContainerPortsRuleName is the name of the rule restricting the ports of containers
+const ContainerPortsRuleName = "containerPorts"
+
+// ContainerPortsConfig configures the allowed container ports and host ports
+type ContainerPortsConfig struct {
+	// ContainerPortRanges are the allowed ranges of containerPort, empty allows all ports
+	ContainerPortRanges []PortRange `json:"containerPortRanges"`
+	// HostPortNamespaces are the namespaces whose pods may use hostPort, by default none
+	HostPortNamespaces []string `json:"hostPortNamespaces"`
+	// HostPortRanges are the allowed ranges of hostPort in the host port namespaces, empty allows all ports
+	HostPortRanges []PortRange `json:"hostPortRanges"`
+	// Action is either deny (default) or warn
+	Action string `json:"action"`
+}
+
+// containerPortsRule keeps containers to the agreed ports and off the ports of the node
+type containerPortsRule struct {
+	cfg ContainerPortsConfig
+}
+
+func newContainerPortsRule(_ *CosignServerHandler, cfg *Config) (Rule, error) {
+	c := cfg.ContainerPorts
+	if err := validateAction(&c.Action, ActionDeny); err != nil {
+		return nil, err
+	}
+	for _, r := range slices.Concat(c.ContainerPortRanges, c.HostPortRanges) {
+		if r.Min < 1 || r.Max > 65535 || r.Min > r.Max {
+			return nil, fmt.Errorf("invalid port range %s, must be within 1-65535", r)
+		}
+	}
+	return &containerPortsRule{cfg: c}, nil
+}
+
+// Name returns the name of the rule
+func (*containerPortsRule) Name() string {
+	return ContainerPortsRuleName
+}
+
+// Validate checks the container ports and host ports of all containers of pods and workloads
+func (r *containerPortsRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	spec := podSpec(o)
+	if spec == nil || o.Request.Operation == v1.Delete {
+		return nil, nil
+	}
+	ns := o.Request.Namespace
+	hostPorts := slices.Contains(r.cfg.HostPortNamespaces, ns)
+	if hostPorts {
+		tracef(ctx, "namespace %q allows host ports", ns)
+	}
+
+	var violations []string
+	for _, c := range podContainers(spec) {
+		for _, p := range c.Ports {
+			if len(r.cfg.ContainerPortRanges) > 0 && !inPortRanges(r.cfg.ContainerPortRanges, p.ContainerPort) {
+				violations = append(violations, fmt.Sprintf("container %q uses port %d outside of the allowed ranges %v",
+					c.Name, p.ContainerPort, r.cfg.ContainerPortRanges))
+			}
+			switch {
+			case p.HostPort == 0:
+			case !hostPorts:
+				violations = append(violations, fmt.Sprintf("container %q uses host port %d, host ports are not allowed in namespace %q",
+					c.Name, p.HostPort, ns))
+			case len(r.cfg.HostPortRanges) > 0 && !inPortRanges(r.cfg.HostPortRanges, p.HostPort):
+				violations = append(violations, fmt.Sprintf("container %q uses host port %d outside of the allowed ranges %v",
+					c.Name, p.HostPort, r.cfg.HostPortRanges))
+			}
+		}
+	}
+	return enforce(r.cfg.Action, violations)
+}
@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_containerPortsRule_Validate(t *testing.T) {
+	ports := func(ports ...corev1.ContainerPort) corev1.PodSpec {
+		return corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Ports: ports}}}
+	}
+	high := []PortRange{{Min: 1024, Max: 65535}}
+	tests := []struct {
+		name         string
+		namespace    string
+		spec         corev1.PodSpec
+		cfg          ContainerPortsConfig
+		wantWarnings int
+		wantErr      string
+	}{
+		{
+			name: "no ranges",
+			spec: ports(corev1.ContainerPort{ContainerPort: 80}),
+		},
+		{
+			name: "container port in range",
+			spec: ports(corev1.ContainerPort{ContainerPort: 8080}),
+			cfg:  ContainerPortsConfig{ContainerPortRanges: high},
+		},
+		{
+			name:    "container port outside of ranges",
+			spec:    ports(corev1.ContainerPort{ContainerPort: 80}),
+			cfg:     ContainerPortsConfig{ContainerPortRanges: high},
+			wantErr: `container "app" uses port 80 outside of the allowed ranges [1024-65535]`,
+		},
+		{
+			name:    "host port denied by default",
+			spec:    ports(corev1.ContainerPort{ContainerPort: 8080, HostPort: 8080}),
+			wantErr: `container "app" uses host port 8080, host ports are not allowed in namespace "default"`,
+		},
+		{
+			name: "host port in init container",
+			spec: corev1.PodSpec{InitContainers: []corev1.Container{{
+				Name:  "init",
+				Ports: []corev1.ContainerPort{{ContainerPort: 53, HostPort: 53}},
+			}}},
+			wantErr: `container "init" uses host port 53`,
+		},
+		{
+			name:      "host port in allowed namespace",
+			namespace: "ingress-nginx",
+			spec:      ports(corev1.ContainerPort{ContainerPort: 80, HostPort: 80}),
+			cfg:       ContainerPortsConfig{HostPortNamespaces: []string{"ingress-nginx"}},
+		},
+		{
+			name:      "host port outside of ranges",
+			namespace: "ingress-nginx",
+			spec:      ports(corev1.ContainerPort{ContainerPort: 8080, HostPort: 8080}),
+			cfg: ContainerPortsConfig{
+				HostPortNamespaces: []string{"ingress-nginx"},
+				HostPortRanges:     []PortRange{{Min: 80, Max: 80}, {Min: 443, Max: 443}},
+			},
+			wantErr: `container "app" uses host port 8080 outside of the allowed ranges [80-80 443-443]`,
+		},
+		{
+			name:         "warn",
+			spec:         ports(corev1.ContainerPort{ContainerPort: 80, HostPort: 80}),
+			cfg:          ContainerPortsConfig{ContainerPortRanges: high, Action: ActionWarn},
+			wantWarnings: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newContainerPortsRule(&CosignServerHandler{}, &Config{ContainerPorts: tt.cfg})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ns := tt.namespace
+			if ns == "" {
+				ns = "default"
+			}
+			warnings, err := r.Validate(context.Background(), podObject(ns, tt.spec))
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("Validate() warnings = %v, want %d", warnings, tt.wantWarnings)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_newContainerPortsRule_invalid(t *testing.T) {
+	for _, c := range []ContainerPortsConfig{
+		{ContainerPortRanges: []PortRange{{Min: 0, Max: 80}}},
+		{HostPortRanges: []PortRange{{Min: 443, Max: 80}}},
+		{HostPortRanges: []PortRange{{Min: 80, Max: 70000}}},
+		{Action: "block"},
+	} {
+		if _, err := newContainerPortsRule(&CosignServerHandler{}, &Config{ContainerPorts: c}); err == nil {
+			t.Errorf("newContainerPortsRule(%+v), want error", c)
+		}
+	}
+}
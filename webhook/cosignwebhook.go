@@ -6,10 +6,13 @@ import (
 	"crypto/ecdsa"
 	"crypto/rsa"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	log "github.com/gookit/slog"
@@ -20,15 +23,25 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 
 	v1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/record"
 
+	"golang.org/x/sync/singleflight"
+
 	"github.com/google/go-containerregistry/pkg/authn/k8schain"
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
@@ -62,37 +75,240 @@ var (
 // build certs here: https://raw.githubusercontent.com/openshift/external-dns-operator/fb77a3c547a09cd638d4e05a7b8cb81094ff2476/hack/generate-certs.sh
 // generate-certs.sh --service cosignwebhook --webhook cosignwebhook --namespace cosignwebhook --secret cosignwebhook
 type CosignServerHandler struct {
-	cs kubernetes.Interface
-	kc authn.Keychain
-	eb record.EventBroadcaster
+	cs        kubernetes.Interface
+	kc        authn.Keychain
+	eb        record.EventBroadcaster
+	cfg       *Config
+	informers informers.SharedInformerFactory
+	// meta is the client of the metadata informers, which cache objects without their data
+	meta          metadata.Interface
+	metaInformers map[string]informers.GenericInformer
+	// tasks are run in the background by Start, e.g. controllers of rules
+	tasks []func(ctx context.Context)
+	// decisionSinks are called with each decision, see OnDecision
+	decisionSinks []func(d *Decision)
+	decisions     *decisionBuffer
+	// endpoints are the endpoints created by Endpoints, used by the evaluate API
+	endpoints  []*Endpoint
+	configHash string
+	// semantics is the decision semantics version recorded in the decisions
+	semantics int
+	// queue is the fair queue of the admission requests, nil if disabled
+	queue *fairQueue
+	// inflight are the admission requests in flight, the replica is not ready at targetInflight
+	inflight       atomic.Int64
+	targetInflight int
+	// limit limits the admission requests in flight, nil without maximum
+	limit *overflowLimit
+	// unparseable is the behavior for objects which can't be decoded
+	unparseable string
+	// onError decides the requests whose rules fail with an internal error
+	onError onErrorPolicy
+	// trustErr is the mismatch of the serving certificate and the webhook configuration found by VerifyTrust
+	trustErr error
+	// exemptNamespaces are admitted without evaluating any rule
+	exemptNamespaces []string
+	// egress routes the external calls through the configured proxies
+	egress *egress
+	// egressErr fails the endpoints if the egress settings are invalid, external calls never bypass the proxies
+	egressErr error
+	// slo tracks the latency and availability of the admission requests, nil without SLOs
+	slo *sloTracker
+	// shared is the cache of verdicts shared by the replicas, nil if disabled
+	shared *sharedCache
+	// lookups collapses concurrent identical external lookups, see dedupe
+	lookups singleflight.Group
+	// blocklists are the blocklist rules by source, shared by the endpoints
+	blocklists map[string]*blocklistRule
+	// namingWatches are the watched ConfigMaps of naming conventions by namespace/name, shared by the endpoints
+	namingWatches map[string]*namingWatch
+	// ttlSweeper is the ttl rule sweeping expired workloads, nil without sweep
+	ttlSweeper *ttlRule
+}
+
+// Endpoint serves admission requests on its path and evaluates its own rule set
+type Endpoint struct {
+	Path    string
+	rules   []Rule
+	forward *forwarder
+	// shadow evaluates the requests with another semantics version for comparison, if enabled
+	shadow *shadowEngine
+	// memo reuses the verdicts of identical pods of a ReplicaSet, if enabled
+	memo *memo
+	// order evaluates the rules in the learned order, if adaptive ordering is enabled
+	order *adaptiveOrder
+	// facts computes the facts the rules and shadow rules depend on, nil if they depend on none
+	facts *factPlan
+	// matcher matches the field policies of the rules and shadow rules in one pass, nil if they have none
+	matcher *policyMatcher
+	csh     *CosignServerHandler
 }
 
-func NewCosignServerHandler() *CosignServerHandler {
-	cs, err := restClient()
+func NewCosignServerHandler(cfg *Config) *CosignServerHandler {
+	cs, meta, err := restClient()
 	if err != nil {
 		log.Errorf("Can't init rest client: %v", err)
 	}
 	eb := record.NewBroadcaster()
 	eb.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: cs.CoreV1().Events("")})
-	return &CosignServerHandler{
-		cs: cs,
-		eb: eb,
+	csh := &CosignServerHandler{
+		cs:        cs,
+		eb:        eb,
+		cfg:       cfg,
+		informers: informers.NewSharedInformerFactoryWithOptions(cs, 0, informers.WithTransform(trimObject)),
+		meta:      meta,
+	}
+	csh.semantics, err = cfg.Server.SemanticsVersion()
+	if err != nil {
+		log.Errorf("Invalid semantics version, using %d: %v", SemanticsVersion, err)
+		csh.semantics = SemanticsVersion
+	}
+	size := cfg.Decisions.BufferSize
+	if size <= 0 {
+		size = defaultDecisionBufferSize
 	}
+	csh.decisions = newDecisionBuffer(size)
+	csh.queue = newFairQueue(cfg.Queue)
+	csh.targetInflight, err = cfg.Server.TargetInflightRequests()
+	if err != nil {
+		log.Errorf("Invalid target of requests in flight, keeping the replica ready under load: %v", err)
+	}
+	csh.limit, err = newOverflowLimit(cfg.Server)
+	if err != nil {
+		log.Errorf("Invalid maximum of requests in flight, not limiting them: %v", err)
+	}
+	csh.unparseable, err = cfg.Server.UnparseablePolicy()
+	if err != nil {
+		log.Errorf("Invalid unparseable behavior, denying objects which can't be decoded: %v", err)
+		csh.unparseable = UnparseableDeny
+	}
+	csh.onError, err = newOnErrorPolicy(cfg.Server)
+	if err != nil {
+		log.Errorf("Invalid on error behavior, denying requests whose rules fail: %v", err)
+	}
+	csh.exemptNamespaces = cfg.Server.ExemptNamespaceList()
+	csh.egress, csh.egressErr = newEgress(cfg.Server)
+	csh.shared, err = newSharedCache(cfg.SharedCache)
+	if err != nil {
+		log.Errorf("Invalid shared cache settings, caching verdicts per replica: %v", err)
+	}
+	csh.OnDecision(csh.decisions.add)
+	for _, pc := range cfg.Publishers {
+		p := newDecisionPublisher(pc, csh.egress)
+		csh.OnDecision(p.enqueue)
+		csh.tasks = append(csh.tasks, p.run)
+	}
+	if cfg.Telemetry.Endpoint != "" {
+		t := newTelemetryReporter(cfg, csh.egress)
+		csh.OnDecision(t.count)
+		csh.tasks = append(csh.tasks, t.run)
+	}
+	if cfg.SLO.enabled() {
+		csh.slo = newSLOTracker(cfg.SLO)
+		csh.tasks = append(csh.tasks, csh.slo.run)
+	}
+	return csh
+}
+
+// Start starts the informers and background tasks registered by the rules and waits until the informer caches
+// are synced. It has to be called after the endpoints are created, the tasks stop when ctx is done.
+func (csh *CosignServerHandler) Start(ctx context.Context) {
+	csh.informers.Start(ctx.Done())
+	for t, synced := range csh.informers.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			log.Errorf("Informer cache for %v not synced", t)
+		}
+	}
+	csh.startMetadataInformers(ctx)
+	for _, task := range csh.tasks {
+		go func() {
+			defer csh.recoverTask()
+			task(ctx)
+		}()
+	}
+}
+
+// Endpoints creates the admission endpoints with their rules as configured
+func (csh *CosignServerHandler) Endpoints() ([]*Endpoint, error) {
+	if csh.egressErr != nil {
+		return nil, fmt.Errorf("invalid egress settings: %w", csh.egressErr)
+	}
+	if err := csh.cfg.Informers.validate(); err != nil {
+		return nil, err
+	}
+	defs, err := newFactDefs(csh, csh.cfg)
+	if err != nil {
+		return nil, err
+	}
+	endpoints := make([]*Endpoint, 0, len(csh.cfg.Endpoints))
+	for _, ec := range csh.cfg.Endpoints {
+		rules, err := newRules(csh, csh.cfg, ec.Rules)
+		if err != nil {
+			return nil, fmt.Errorf("endpoint %q: %w", ec.Path, err)
+		}
+		e := &Endpoint{
+			Path:  ec.Path,
+			rules: rules,
+			csh:   csh,
+		}
+		if e.memo, err = newMemo(csh.cfg.Memoize, rules, defs); err != nil {
+			return nil, fmt.Errorf("endpoint %q: %w", ec.Path, err)
+		}
+		if ec.Forward != nil {
+			if e.forward, err = newForwarder(*ec.Forward, csh.egress); err != nil {
+				return nil, fmt.Errorf("endpoint %q: %w", ec.Path, err)
+			}
+		}
+		if e.shadow, err = csh.newShadowEngine(ec.Path); err != nil {
+			return nil, fmt.Errorf("endpoint %q: %w", ec.Path, err)
+		}
+		planned := rules
+		if e.shadow != nil {
+			planned = append(rules[:len(rules):len(rules)], e.shadow.rules...)
+		}
+		if e.facts, err = newFactPlan(planned, defs); err != nil {
+			return nil, fmt.Errorf("endpoint %q: %w", ec.Path, err)
+		}
+		if e.facts != nil {
+			e.facts.prepare()
+		}
+		if e.matcher, err = newPolicyMatcher(planned); err != nil {
+			return nil, fmt.Errorf("endpoint %q: %w", ec.Path, err)
+		}
+		if csh.cfg.Order.Adaptive {
+			e.order = newAdaptiveOrder(rules, csh.onError)
+		}
+		endpoints = append(endpoints, e)
+	}
+	if o := csh.cfg.Order; o.Adaptive {
+		if ns, name, ok := strings.Cut(o.ConfigMap, "/"); o.ConfigMap != "" && (!ok || ns == "" || name == "") {
+			return nil, fmt.Errorf("order: configMap %q must be namespace/name", o.ConfigMap)
+		}
+		csh.tasks = append(csh.tasks, csh.learnOrder)
+	}
+	csh.endpoints = endpoints
+	csh.configHash = hashConfig(csh.cfg)
+	return endpoints, nil
 }
 
 // create restClient for get secrets and create events
-func restClient() (*kubernetes.Clientset, error) {
+func restClient() (*kubernetes.Clientset, metadata.Interface, error) {
 	restConfig, err := rest.InClusterConfig()
 	if err != nil {
 		log.Errorf("error init in-cluster config: %v", err)
-		return nil, err
+		return nil, nil, err
 	}
 	cs, err := kubernetes.NewForConfig(restConfig)
 	if err != nil {
 		log.Errorf("error creating k8sclientset: %v", err)
-		return nil, err
+		return nil, nil, err
 	}
-	return cs, err
+	meta, err := metadata.NewForConfig(restConfig)
+	if err != nil {
+		log.Errorf("error creating metadata client: %v", err)
+		return nil, nil, err
+	}
+	return cs, meta, err
 }
 
 // recordPodVerified emits a PodVerified event for the container
@@ -107,8 +323,31 @@ func (csh *CosignServerHandler) recordNoVerification(p *corev1.Pod) {
 	er.Event(p, corev1.EventTypeNormal, "NoVerification", "No signature verification performed")
 }
 
-// getPod returns the pod object from admission review request
-func getPod(b []byte) (*corev1.Pod, *v1.AdmissionReview, error) {
+// recordTTLExpired emits a TTLExpired event for the workload deleted after its time to live
+func (csh *CosignServerHandler) recordTTLExpired(o runtime.Object, ttl time.Duration) {
+	er := csh.eb.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "Cosignwebhook", Host: os.Getenv("HOSTNAME")})
+	er.Eventf(o, corev1.EventTypeNormal, "TTLExpired", "Deleted after time to live of %s expired", ttl)
+}
+
+// decodedVersions are the group versions of the kinds decoded for the rules, objects of other versions of these
+// groups are unparseable
+var decodedVersions = map[string]schema.GroupVersion{
+	"Pod":                     corev1.SchemeGroupVersion,
+	"Service":                 corev1.SchemeGroupVersion,
+	"Deployment":              appsv1.SchemeGroupVersion,
+	"StatefulSet":             appsv1.SchemeGroupVersion,
+	"DaemonSet":               appsv1.SchemeGroupVersion,
+	"HorizontalPodAutoscaler": autoscalingv2.SchemeGroupVersion,
+	"Ingress":                 networkingv1.SchemeGroupVersion,
+	"Role":                    rbacv1.SchemeGroupVersion,
+	"ClusterRole":             rbacv1.SchemeGroupVersion,
+	"RoleBinding":             rbacv1.SchemeGroupVersion,
+	"ClusterRoleBinding":      rbacv1.SchemeGroupVersion,
+}
+
+// getObject returns the object from admission review request. If the request is valid, but its object can't be
+// decoded, the object with the request and metadata is returned with an unparseableError.
+func getObject(b []byte) (*Object, *v1.AdmissionReview, error) {
 	arRequest := v1.AdmissionReview{}
 	if err := json.Unmarshal(b, &arRequest); err != nil {
 		log.Error("Incorrect body")
@@ -118,13 +357,79 @@ func getPod(b []byte) (*corev1.Pod, *v1.AdmissionReview, error) {
 		log.Error("AdmissionReview request not found")
 		return nil, nil, fmt.Errorf("admissionreview request not found")
 	}
+	o := &Object{Request: arRequest.Request}
 	raw := arRequest.Request.Object.Raw
-	pod := corev1.Pod{}
-	if err := json.Unmarshal(raw, &pod); err != nil {
-		log.Error("Error deserializing container")
-		return nil, nil, err
+	if len(raw) == 0 {
+		// no object is sent for deletions
+		return o, &arRequest, nil
+	}
+	meta := metav1.PartialObjectMetadata{}
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		log.Errorf("Error deserializing object metadata: %v", err)
+		return o, &arRequest, &unparseableError{err: fmt.Errorf("invalid object metadata: %w", err)}
+	}
+	o.Meta = meta.ObjectMeta
+	kind := arRequest.Request.Kind
+	gv, ok := decodedVersions[kind.Kind]
+	if !ok || kind.Group != gv.Group {
+		log.Debugf("No decoding for kind %q in group %q, only rules for generic objects apply", kind.Kind, kind.Group)
+		return o, &arRequest, nil
+	}
+	if kind.Version != gv.Version {
+		log.Errorf("Unsupported version %s of %s", kind.Version, kind.Kind)
+		return o, &arRequest, &unparseableError{err: fmt.Errorf("unsupported version %s/%s of %s, only %s is decoded",
+			kind.Group, kind.Version, kind.Kind, gv)}
+	}
+	decode := func(v any) error {
+		if err := json.Unmarshal(raw, v); err != nil {
+			log.Errorf("Error deserializing %s: %v", kind.Kind, err)
+			return &unparseableError{err: fmt.Errorf("invalid %s: %w", kind.Kind, err)}
+		}
+		return nil
+	}
+	var err error
+	switch kind.Kind {
+	case "Pod":
+		o.Pod = &corev1.Pod{}
+		err = decode(o.Pod)
+	case "Deployment":
+		o.Deployment = &appsv1.Deployment{}
+		err = decode(o.Deployment)
+	case "StatefulSet":
+		o.StatefulSet = &appsv1.StatefulSet{}
+		err = decode(o.StatefulSet)
+	case "DaemonSet":
+		o.DaemonSet = &appsv1.DaemonSet{}
+		err = decode(o.DaemonSet)
+	case "Service":
+		o.Service = &corev1.Service{}
+		err = decode(o.Service)
+	case "HorizontalPodAutoscaler":
+		o.HPA = &autoscalingv2.HorizontalPodAutoscaler{}
+		err = decode(o.HPA)
+	case "Ingress":
+		o.Ingress = &networkingv1.Ingress{}
+		err = decode(o.Ingress)
+	case "Role":
+		o.Role = &rbacv1.Role{}
+		err = decode(o.Role)
+	case "ClusterRole":
+		o.ClusterRole = &rbacv1.ClusterRole{}
+		err = decode(o.ClusterRole)
+	case "RoleBinding":
+		o.RoleBinding = &rbacv1.RoleBinding{}
+		err = decode(o.RoleBinding)
+	case "ClusterRoleBinding":
+		o.ClusterRoleBinding = &rbacv1.ClusterRoleBinding{}
+		err = decode(o.ClusterRoleBinding)
 	}
-	return &pod, &arRequest, nil
+	if err != nil {
+		// the rules must not see the partially decoded object
+		o.Pod, o.Deployment, o.StatefulSet, o.DaemonSet, o.Service, o.HPA, o.Ingress = nil, nil, nil, nil, nil, nil, nil
+		o.Role, o.ClusterRole, o.RoleBinding, o.ClusterRoleBinding = nil, nil, nil, nil
+		return o, &arRequest, err
+	}
+	return o, &arRequest, nil
 }
 
 // getPubKeyFromEnv procures the public key from the container's environment section, if present.
@@ -177,8 +482,14 @@ func (csh *CosignServerHandler) Healthz(w http.ResponseWriter, _ *http.Request)
 	}
 }
 
-// Serve the main function for /validate to validate the webhook request or /metrics to get Prometheus data
-func (csh *CosignServerHandler) Serve(w http.ResponseWriter, r *http.Request) {
+// ServeHTTP validates the admission request with the rules of the endpoint
+func (e *Endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer e.csh.recoverTask()
+	defer e.csh.startRequest()()
+	sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	defer e.csh.slo.observe(time.Now(), sw)
+	w = sw
+
 	var body []byte
 	if r.Body != nil {
 		if data, err := io.ReadAll(r.Body); err == nil {
@@ -186,19 +497,6 @@ func (csh *CosignServerHandler) Serve(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Url path of metrics
-	if r.URL.Path == "/metrics" {
-		w.WriteHeader(http.StatusOK)
-		return
-	}
-
-	// Url path of admission
-	if r.URL.Path != "/validate" {
-		log.Error("No validate URI")
-		http.Error(w, "no validate", http.StatusBadRequest)
-		return
-	}
-
 	if len(body) == 0 {
 		log.Error("Empty body")
 		http.Error(w, "empty body", http.StatusBadRequest)
@@ -208,57 +506,205 @@ func (csh *CosignServerHandler) Serve(w http.ResponseWriter, r *http.Request) {
 	// count each request for prometheus metric
 	opsProcessed.Inc()
 
-	pod, arRequest, err := getPod(body)
-	if err != nil {
-		log.Errorf("Error getPod in %s/%s: %v", pod.Namespace, pod.Name, err)
+	o, arRequest, err := getObject(body)
+	var unparseable *unparseableError
+	if err != nil && !errors.As(err, &unparseable) {
+		log.Errorf("Error getObject on %s: %v", e.Path, err)
 		http.Error(w, "incorrect body", http.StatusBadRequest)
 		return
 	}
 
-	ctx := r.Context()
+	if e.exempt(w, o) || e.bypass(w, o) {
+		return
+	}
+	if unparseable != nil {
+		e.unparseable(r.Context(), w, o, unparseable)
+		return
+	}
+
+	if l := e.csh.limit; l != nil {
+		release, ok := l.acquire(r.Context())
+		if !ok {
+			e.overflow(w, o)
+			return
+		}
+		defer release()
+	}
+
+	if q := e.csh.queue; q != nil {
+		release, err := q.acquire(r.Context(), o.Request.Namespace)
+		if err != nil {
+			log.Errorf("Rejected %s %s/%s on %s: %v", o.Request.Kind.Kind, o.Request.Namespace, o.Request.Name, e.Path, err)
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		defer release()
+	}
+
+	var d *Decision
+	var key string
+	if e.memo != nil {
+		key = e.memo.key(o)
+		if d = e.memo.get(key, e.Path, o.Request, e.csh.semantics); d != nil {
+			d = e.memo.recheck(r.Context(), o, d, e.csh)
+		}
+	}
+	if d == nil {
+		results := map[string]ruleResult{}
+		d = e.evaluate(r.Context(), o, results)
+		if e.shadow != nil {
+			e.shadow.submit(o, d, results)
+		}
+		if e.memo != nil {
+			e.memo.put(key, d)
+		}
+	}
+	if e.forward != nil {
+		e.forward.combine(r.Context(), o.Request, d)
+	}
+	e.csh.recordDecision(d)
+	if !d.Allowed {
+		deny(w, d.Message, arRequest.Request.UID)
+		return
+	}
+	accept(w, d.Message, arRequest.Request.UID, d.Warnings...)
+}
+
+// evaluate computes the facts of the rules and validates the object with the rules of the endpoint in order.
+// The first rule returning an error denies the request, the warnings of the rules evaluated until then are kept.
+// The results of the rules are added to results if not nil.
+func (e *Endpoint) evaluate(ctx context.Context, o *Object, results map[string]ruleResult) *Decision {
+	if e.facts != nil {
+		o.Facts = e.facts.compute(ctx, o)
+	}
+	if e.matcher != nil {
+		o.matches = e.matcher.match(o)
+	}
+	rules := e.rules
+	if e.order != nil {
+		rules = e.order.rules()
+	}
+	d := evaluateRules(ctx, o, e.Path, rules, e.csh.semantics, e.csh.onError, results)
+	if e.order != nil && results != nil {
+		e.order.record(results)
+	}
+	if !d.Allowed {
+		log.Errorf("Rule %s denied %s %s/%s: %s", d.Rule, o.Request.Kind.Kind, o.Request.Namespace, o.Request.Name, d.Message)
+	}
+	return d
+}
+
+// ruleResult is the result of a rule for an object
+type ruleResult struct {
+	warnings []string
+	err      error
+	// notes and duration of the evaluation for the trace
+	notes    []string
+	duration time.Duration
+}
+
+// evaluateRules validates the object with passed rules in order and returns the decision of passed semantics
+// version. Rules with a result in results are not evaluated again, new results are added if results is not nil.
+// Internal errors of rules are decided by the on error policy. Denied decisions keep the trace of the rules.
+func evaluateRules(ctx context.Context, o *Object, path string, rules []Rule, semantics int, onError onErrorPolicy, results map[string]ruleResult) *Decision {
+	d := newDecision(path, o.Request, semantics)
+	// the object is shared with the shadow engine, which evaluates it with another semantics version
+	evaluated := *o
+	evaluated.semantics = semantics
+	o = &evaluated
+	var steps []traceStep
+	for _, rule := range rules {
+		res, ok := results[rule.Name()]
+		if !ok {
+			notes := &ruleNotes{}
+			start := time.Now()
+			res.warnings, res.err = validateRule(withNotes(ctx, notes), rule, o)
+			res.duration, res.notes = time.Since(start), notes.get()
+			if results != nil {
+				results[rule.Name()] = res
+			}
+		}
+		var internal *internalRuleError
+		if errors.As(res.err, &internal) {
+			d.FailedRules = append(d.FailedRules, rule.Name())
+			switch onError.forRule(rule.Name()) {
+			case OnErrorIgnoreRule:
+				steps = append(steps, traceStep{rule, res, TraceSkipped})
+				d.Warnings = append(d.Warnings, fmt.Sprintf("Rule %s was skipped, it failed: %v", rule.Name(), res.err))
+				continue
+			case OnErrorAllow:
+				d.Allowed, d.Message = true, fmt.Sprintf("Admitted without validation, rule %s failed: %v", rule.Name(), res.err)
+				d.Warnings = append(d.Warnings, d.Message)
+				return d
+			}
+			d.Trace = newTrace(append(steps, traceStep{rule, res, TraceFailed}), o)
+			d.Rule, d.Message = rule.Name(), res.err.Error()
+			return d
+		}
+		if res.err != nil {
+			d.Trace = newTrace(append(steps, traceStep{rule, res, TraceDenied}), o)
+			d.Rule, d.Message = rule.Name(), res.err.Error()
+			return d
+		}
+		result := TracePassed
+		if len(res.warnings) > 0 {
+			result = TraceWarned
+		}
+		steps = append(steps, traceStep{rule, res, result})
+		d.Warnings = append(d.Warnings, res.warnings...)
+	}
+	d.Allowed, d.Message = true, "Validation passed"
+	return d
+}
+
+// verifyPod verifies the signatures of all containers of the pod which have a public key
+// and records an event about the result.
+func (csh *CosignServerHandler) verifyPod(ctx context.Context, pod *corev1.Pod) error {
 	kc, err := newKeychainForPod(ctx, pod)
 	if err != nil {
-		http.Error(w, "Failed initializing k8schain", http.StatusInternalServerError)
-		return
+		return internalError(fmt.Errorf("failed initializing k8schain"))
 	}
 	csh.kc = kc
+	credentials := pullCredentials(pod.Namespace, &pod.Spec)
 
 	signatureChecked := false
 	for i := range pod.Spec.InitContainers {
 		pubKey := csh.getPubKeyFor(pod.Spec.InitContainers[i], pod.Namespace)
 		if pubKey == "" {
+			tracef(ctx, "init container %q has no public key", pod.Spec.InitContainers[i].Name)
 			continue
 		}
 
-		err = csh.verifyContainer(pod.Spec.InitContainers[i], pubKey)
+		err = csh.verifyContainer(ctx, pod.Spec.InitContainers[i], pubKey, credentials)
 		if err != nil {
-			log.Errorf("Error verifying init container %s/%s/%s: %v", pod.Namespace, pod.Name, pod.Spec.InitContainers[0].Name, err)
-			deny(w, err.Error(), arRequest.Request.UID)
-			return
+			log.Errorf("Error verifying init container %s/%s/%s: %v", pod.Namespace, pod.Name, pod.Spec.InitContainers[i].Name, err)
+			return err
 		}
+		tracef(ctx, "init container %q verified", pod.Spec.InitContainers[i].Name)
 		signatureChecked = true
 	}
 
 	for i := range pod.Spec.Containers {
 		pubKey := csh.getPubKeyFor(pod.Spec.Containers[i], pod.Namespace)
 		if pubKey == "" {
+			tracef(ctx, "container %q has no public key", pod.Spec.Containers[i].Name)
 			continue
 		}
-		err = csh.verifyContainer(pod.Spec.Containers[i], pubKey)
+		err = csh.verifyContainer(ctx, pod.Spec.Containers[i], pubKey, credentials)
 		if err != nil {
 			log.Errorf("Error verifying container %s/%s/%s: %v", pod.Namespace, pod.Name, pod.Spec.Containers[i].Name, err)
-			deny(w, err.Error(), arRequest.Request.UID)
-			return
+			return err
 		}
+		tracef(ctx, "container %q verified", pod.Spec.Containers[i].Name)
 		signatureChecked = true
 	}
 
-	accept(w, "Cosign verification passed", arRequest.Request.UID)
 	if signatureChecked {
 		csh.recordPodVerified(pod)
-		return
+		return nil
 	}
 	csh.recordNoVerification(pod)
+	return nil
 }
 
 // newKeychainForPod builds a new Keychain for the pod
@@ -318,8 +764,9 @@ func (csh *CosignServerHandler) getPubKeyFor(c corev1.Container, ns string) stri
 	return pubKey
 }
 
-// verifyContainer verifies the signature of the container image
-func (csh *CosignServerHandler) verifyContainer(c corev1.Container, pubKey string) error { //nolint:gocritic // better for garbage collection
+// verifyContainer verifies the signature of the container image. Concurrent verifications of the same image are
+// only shared by pods with the same pull credentials.
+func (csh *CosignServerHandler) verifyContainer(ctx context.Context, c corev1.Container, pubKey, credentials string) error { //nolint:gocritic // better for garbage collection
 	log.Debugf("Verifying container %s", c.Name)
 
 	// Lookup image name of current container
@@ -330,50 +777,70 @@ func (csh *CosignServerHandler) verifyContainer(c corev1.Container, pubKey strin
 		return fmt.Errorf("could parse image reference for image %q", image)
 	}
 
+	co, err := csh.checkOpts(c, pubKey, csh.kc)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("Verifying image %q with public key %q", image, pubKey)
+	verify := func(ctx context.Context) ([]byte, error) {
+		if _, _, err := cosign.VerifyImageSignatures(ctx, refImage, co); err != nil {
+			log.Errorf("Error verifying signature: %v", err)
+			return nil, verificationError(err, fmt.Errorf("signature for %q couldn't be verified", image))
+		}
+		return []byte("verified"), nil
+	}
+	key := sharedKey(image, pubKey, getCosignRepository(c.Env))
+	_, err = csh.dedupe(ctx, sharedCacheSignature, key+"/"+credentials, func(ctx context.Context) (any, error) {
+		// only digests are immutable, so the verdicts of tags aren't shared
+		if _, ok := refImage.(name.Digest); ok {
+			return csh.shared.fetch(ctx, sharedCacheSignature, key, func() ([]byte, error) { return verify(ctx) })
+		}
+		return verify(ctx)
+	})
+	if err != nil {
+		return err
+	}
+
+	verifiedProcessed.Inc()
+	log.Infof("Image %q verified successfully", image)
+	return nil
+}
+
+// checkOpts returns the options to verify signatures and attestations of the container image with the public key,
+// pulling from the registry with passed keychain
+func (csh *CosignServerHandler) checkOpts(c corev1.Container, pubKey string, kc authn.Keychain) (*cosign.CheckOpts, error) { //nolint:gocritic // better for garbage collection
 	// Encrypt public key
 	publicKey, err := cryptoutils.UnmarshalPEMToPublicKey([]byte(pubKey))
 	if err != nil {
 		log.Errorf("Error unmarshalling public key: %v", err)
-		return fmt.Errorf("public key for image %q malformed", image)
+		return nil, fmt.Errorf("public key for image %q malformed", c.Image)
 	}
 
 	verifier, err := csh.newVerifierForKey(publicKey)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	remoteOpts := []ociremote.Option{
-		ociremote.WithRemoteOptions(remote.WithAuthFromKeychain(csh.kc)),
+		ociremote.WithRemoteOptions(remote.WithAuthFromKeychain(kc), remote.WithTransport(csh.egress.roundTripper())),
 	}
 	if r := getCosignRepository(c.Env); r != "" {
 		repository, repErr := name.NewRepository(r)
 		if repErr != nil {
 			log.Errorf("Error parsing remote signature repository: %v", repErr)
-			return fmt.Errorf("could not parse signature repository %q", r)
+			return nil, fmt.Errorf("could not parse signature repository %q", r)
 		}
 		log.Debugf("Remote signature repository overridden with: %v", repository)
 		remoteOpts = append(remoteOpts, ociremote.WithTargetRepository(repository))
 	}
 
-	log.Debugf("Verifying image %q with public key %q", image, pubKey)
-	_, _, err = cosign.VerifyImageSignatures(
-		context.Background(),
-		refImage,
-		&cosign.CheckOpts{
-			RegistryClientOpts: remoteOpts,
-			SigVerifier:        verifier,
-			IgnoreSCT:          true,
-			IgnoreTlog:         true,
-		},
-	)
-	if err != nil {
-		log.Errorf("Error verifying signature: %v", err)
-		return fmt.Errorf("signature for %q couldn't be verified", image)
-	}
-
-	verifiedProcessed.Inc()
-	log.Infof("Image %q verified successfully", image)
-	return nil
+	return &cosign.CheckOpts{
+		RegistryClientOpts: remoteOpts,
+		SigVerifier:        verifier,
+		IgnoreSCT:          true,
+		IgnoreTlog:         true,
+	}, nil
 }
 
 // newVerifierForKey creates a new signature verifier for the given public key.
@@ -414,9 +881,11 @@ func deny(w http.ResponseWriter, msg string, uid types.UID) {
 	}
 }
 
-// accept allows the container to start
-func accept(w http.ResponseWriter, msg string, uid types.UID) {
-	resp, err := json.Marshal(admissionReview(http.StatusOK, true, "Success", msg, uid))
+// accept allows the container to start, passed warnings are shown to the client
+func accept(w http.ResponseWriter, msg string, uid types.UID, warnings ...string) {
+	ar := admissionReview(http.StatusOK, true, "Success", msg, uid)
+	ar.Response.Warnings = warnings
+	resp, err := json.Marshal(ar)
 	if err != nil {
 		log.Errorf("Can't encode response: %v", err)
 		http.Error(w, fmt.Sprintf("could not encode response: %v", err), http.StatusInternalServerError)
@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// CostRuleName is the name of the rule limiting expensive resource requests
+const CostRuleName = "cost"
+
+// CostConfig configures the maximum resources a pod may request
+type CostConfig struct {
+	// Limits are the maximum requests per pod by resource name, e.g. cpu: "16" or nvidia.com/gpu: "0"
+	Limits corev1.ResourceList `json:"limits"`
+	// Namespaces override single limits per namespace
+	Namespaces map[string]corev1.ResourceList `json:"namespaces"`
+	// ApprovalAnnotation allows pods to exceed the limits if set, e.g. cost.example.com/approved-by.
+	// The request is admitted with a warning then.
+	ApprovalAnnotation string `json:"approvalAnnotation"`
+}
+
+// costRule prevents accidental scheduling of expensive pods like GPU or very large workloads
+type costRule struct {
+	cfg CostConfig
+}
+
+func newCostRule(_ *CosignServerHandler, cfg *Config) (Rule, error) {
+	return &costRule{cfg: cfg.Cost}, nil
+}
+
+// Name returns the name of the rule
+func (*costRule) Name() string {
+	return CostRuleName
+}
+
+// Validate checks the pod's total requests against the limits of its namespace
+func (r *costRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	if o.Pod == nil {
+		return nil, nil
+	}
+	limits := corev1.ResourceList{}
+	for n, q := range r.cfg.Limits {
+		limits[n] = q
+	}
+	if nsLimits, ok := r.cfg.Namespaces[o.Request.Namespace]; ok {
+		tracef(ctx, "limits of namespace %q", o.Request.Namespace)
+		for n, q := range nsLimits {
+			limits[n] = q
+		}
+	}
+
+	requests := podRequests(&o.Pod.Spec)
+	var violations []string
+	for n, limit := range limits {
+		if req, ok := requests[n]; ok && req.Cmp(limit) > 0 {
+			violations = append(violations, fmt.Sprintf("pod requests %s %s, maximum in namespace %q is %s", req.String(), n, o.Request.Namespace, limit.String()))
+		}
+	}
+	if len(violations) == 0 {
+		return nil, nil
+	}
+	sort.Strings(violations)
+
+	if a := r.cfg.ApprovalAnnotation; a != "" {
+		if approval := o.Pod.Annotations[a]; approval != "" {
+			for i := range violations {
+				violations[i] = fmt.Sprintf("%s, approved by %s", violations[i], approval)
+			}
+			return violations, nil
+		}
+		violations = append(violations, fmt.Sprintf("set annotation %q to request an exception", a))
+	}
+	return nil, violationsError(violations)
+}
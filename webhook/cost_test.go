@@ -0,0 +1,82 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func Test_costRule_Validate(t *testing.T) {
+	gpu := corev1.ResourceName("nvidia.com/gpu")
+	cfg := CostConfig{
+		Limits: corev1.ResourceList{
+			corev1.ResourceCPU: resource.MustParse("4"),
+			gpu:                resource.MustParse("0"),
+		},
+		Namespaces: map[string]corev1.ResourceList{
+			"ml": {gpu: resource.MustParse("2")},
+		},
+		ApprovalAnnotation: "cost.example.com/approved-by",
+	}
+	container := func(cpu, gpus string) corev1.Container {
+		return corev1.Container{Name: "app", Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)},
+			Limits:   corev1.ResourceList{gpu: resource.MustParse(gpus)},
+		}}
+	}
+	tests := []struct {
+		name         string
+		ns           string
+		containers   []corev1.Container
+		annotations  map[string]string
+		wantWarnings int
+		wantErr      bool
+	}{
+		{
+			name:       "within limits",
+			ns:         "default",
+			containers: []corev1.Container{container("2", "0")},
+		},
+		{
+			name:       "cpu summed over containers",
+			ns:         "default",
+			containers: []corev1.Container{container("3", "0"), container("2", "0")},
+			wantErr:    true,
+		},
+		{
+			name:       "gpu from limits",
+			ns:         "default",
+			containers: []corev1.Container{container("1", "1")},
+			wantErr:    true,
+		},
+		{
+			name:       "gpu allowed in namespace",
+			ns:         "ml",
+			containers: []corev1.Container{container("1", "2")},
+		},
+		{
+			name:         "approved",
+			ns:           "default",
+			containers:   []corev1.Container{container("8", "0")},
+			annotations:  map[string]string{"cost.example.com/approved-by": "jane"},
+			wantWarnings: 1,
+		},
+	}
+
+	r := &costRule{cfg: cfg}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := podObject(tt.ns, corev1.PodSpec{Containers: tt.containers})
+			o.Pod.Annotations = tt.annotations
+			warnings, err := r.Validate(context.Background(), o)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("Validate() got %d warnings, want %d", len(warnings), tt.wantWarnings)
+			}
+		})
+	}
+}
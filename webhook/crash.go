@@ -0,0 +1,92 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	log "github.com/gookit/slog"
+
+	v1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// crashReportDecisions is the number of latest decisions in crash reports
+	crashReportDecisions = 50
+	maxStackSize         = 1 << 20
+)
+
+// CrashReport is written to the crash report directory on fatal errors and panics
+type CrashReport struct {
+	Time   time.Time `json:"time"`
+	Reason string    `json:"reason"`
+	// ConfigHash identifies the config the webhook ran with, empty if it failed before loading it
+	ConfigHash string `json:"configHash,omitempty"`
+	Semantics  int    `json:"semantics,omitempty"`
+	// Goroutines are the stack traces of all goroutines
+	Goroutines string `json:"goroutines"`
+	// Decisions are the latest decisions, without messages and users
+	Decisions []CrashDecision `json:"decisions"`
+}
+
+// CrashDecision is the metadata of a decision in a crash report
+type CrashDecision struct {
+	Time      time.Time    `json:"time"`
+	Endpoint  string       `json:"endpoint"`
+	UID       types.UID    `json:"uid"`
+	Kind      string       `json:"kind"`
+	Namespace string       `json:"namespace,omitempty"`
+	Name      string       `json:"name,omitempty"`
+	Operation v1.Operation `json:"operation"`
+	Allowed   bool         `json:"allowed"`
+	Rule      string       `json:"rule,omitempty"`
+}
+
+// WriteCrashReport writes a crash report with passed reason to dir and returns its path.
+// csh may be nil if the webhook failed before creating the handler. Nothing is written if dir is empty.
+func WriteCrashReport(dir, reason string, csh *CosignServerHandler) (string, error) {
+	if dir == "" {
+		return "", nil
+	}
+	stack := make([]byte, maxStackSize)
+	stack = stack[:runtime.Stack(stack, true)]
+	r := CrashReport{Time: time.Now().UTC(), Reason: reason, Goroutines: string(stack), Decisions: []CrashDecision{}}
+	if csh != nil {
+		r.ConfigHash, r.Semantics = csh.configHash, csh.semantics
+		if csh.decisions != nil {
+			recent := csh.decisions.recent()
+			for _, d := range recent[max(0, len(recent)-crashReportDecisions):] {
+				r.Decisions = append(r.Decisions, CrashDecision{
+					Time: d.Time, Endpoint: d.Endpoint, UID: d.UID, Kind: d.Kind, Namespace: d.Namespace,
+					Name: d.Name, Operation: d.Operation, Allowed: d.Allowed, Rule: d.Rule,
+				})
+			}
+		}
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("crash-%s-%d.json", r.Time.Format("20060102T150405Z"), os.Getpid()))
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return "", fmt.Errorf("can't write crash report: %w", err)
+	}
+	return path, nil
+}
+
+// recoverTask writes a crash report if the background task or admission request panics and panics again, so the
+// webhook still crashes or net/http drops the request
+func (csh *CosignServerHandler) recoverTask() {
+	if r := recover(); r != nil {
+		if path, err := WriteCrashReport(csh.cfg.Server.CrashReportDir, fmt.Sprintf("panic: %v", r), csh); err != nil {
+			log.Errorf("Can't write crash report: %v", err)
+		} else if path != "" {
+			log.Errorf("Wrote crash report %s", path)
+		}
+		panic(r)
+	}
+}
@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWriteCrashReport(t *testing.T) {
+	if path, err := WriteCrashReport("", "disabled", nil); err != nil || path != "" {
+		t.Fatalf("WriteCrashReport() without dir = %q, %v, want nothing written", path, err)
+	}
+
+	decisions := newDecisionBuffer(crashReportDecisions + 10)
+	for i := range crashReportDecisions + 10 {
+		decisions.add(&Decision{Name: fmt.Sprintf("pod-%d", i), User: "alice", Message: "denied"})
+	}
+	csh := &CosignServerHandler{configHash: "abc", semantics: 2, decisions: decisions}
+
+	dir := t.TempDir()
+	path, err := WriteCrashReport(dir, "Failed to create endpoints", csh)
+	if err != nil {
+		t.Fatalf("WriteCrashReport() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r CrashReport
+	if err := json.Unmarshal(data, &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Reason != "Failed to create endpoints" || r.ConfigHash != "abc" || r.Semantics != 2 {
+		t.Errorf("report = %+v, want reason, config hash and semantics", r)
+	}
+	if !strings.Contains(r.Goroutines, "TestWriteCrashReport") {
+		t.Errorf("goroutines don't contain the test stack:\n%s", r.Goroutines)
+	}
+	if len(r.Decisions) != crashReportDecisions || r.Decisions[0].Name != "pod-10" {
+		t.Errorf("decisions = %d starting at %v, want the last %d", len(r.Decisions), r.Decisions[0].Name, crashReportDecisions)
+	}
+	if strings.Contains(string(data), "alice") || strings.Contains(string(data), "denied") {
+		t.Errorf("report contains users or messages of decisions")
+	}
+}
+
+func TestRecoverTask(t *testing.T) {
+	cfg := &Config{}
+	cfg.Server.CrashReportDir = t.TempDir()
+	csh := &CosignServerHandler{cfg: cfg}
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("recover() = %v, want the panic passed on", r)
+			}
+		}()
+		defer csh.recoverTask()
+		panic("boom")
+	}()
+
+	entries, err := os.ReadDir(cfg.Server.CrashReportDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("crash reports = %d, want 1", len(entries))
+	}
+}
@@ -0,0 +1,160 @@
+package webhook
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	defaultDecisionBufferSize = 1000
+	decisionSubscriberBuffer  = 100
+)
+
+// DecisionsConfig configures the in-memory buffer of recent decisions and the decision stream
+type DecisionsConfig struct {
+	// BufferSize is the number of recent decisions kept in memory, defaults to 1000
+	BufferSize int `json:"bufferSize"`
+	// Stream serves the decisions as Server-Sent Events on /decisions and single decisions on /decisions/{uid}
+	Stream bool `json:"stream"`
+	// StreamGroups are the groups of which a user needs one to read the stream, required if the stream is enabled
+	StreamGroups []string `json:"streamGroups"`
+}
+
+// validate checks that the decision stream is restricted to groups
+func (c DecisionsConfig) validate() error {
+	if c.Stream && len(c.StreamGroups) == 0 {
+		return fmt.Errorf("decisions: streamGroups are required")
+	}
+	return nil
+}
+
+// Decision is the outcome of an admission request on an endpoint
+type Decision struct {
+	Time      time.Time    `json:"time"`
+	Endpoint  string       `json:"endpoint"`
+	UID       types.UID    `json:"uid"`
+	Kind      string       `json:"kind"`
+	Namespace string       `json:"namespace,omitempty"`
+	Name      string       `json:"name,omitempty"`
+	Operation v1.Operation `json:"operation"`
+	User      string       `json:"user"`
+	Allowed   bool         `json:"allowed"`
+	// Rule is the name of the rule which denied the request
+	Rule     string   `json:"rule,omitempty"`
+	Message  string   `json:"message"`
+	Warnings []string `json:"warnings,omitempty"`
+	// Semantics is the decision semantics version the request was decided with
+	Semantics int `json:"semantics"`
+	// Memoized is set if the verdict of an identical pod of the same ReplicaSet was reused
+	Memoized bool `json:"memoized,omitempty"`
+	// Bypassed is set if the object opted out of validation with the bypass annotation
+	Bypassed bool `json:"bypassed,omitempty"`
+	// FailedRules are the rules which failed with an internal error, decided by the on error policy
+	FailedRules []string `json:"failedRules,omitempty"`
+	// Trace are the rules evaluated until the request was denied, only kept for denied requests
+	Trace []RuleTrace `json:"trace,omitempty"`
+}
+
+// newDecision returns the decision for the admission request, without outcome
+func newDecision(endpoint string, req *v1.AdmissionRequest, semantics int) *Decision {
+	return &Decision{
+		Time:      time.Now(),
+		Endpoint:  endpoint,
+		UID:       req.UID,
+		Kind:      req.Kind.Kind,
+		Namespace: req.Namespace,
+		Name:      req.Name,
+		Operation: req.Operation,
+		User:      req.UserInfo.Username,
+		Semantics: semantics,
+	}
+}
+
+// OnDecision registers a function called with each decision of the endpoints.
+// It's called synchronously while serving the request and must not block.
+func (csh *CosignServerHandler) OnDecision(f func(d *Decision)) {
+	csh.decisionSinks = append(csh.decisionSinks, f)
+}
+
+// recordDecision passes the decision to all registered functions
+func (csh *CosignServerHandler) recordDecision(d *Decision) {
+	for _, f := range csh.decisionSinks {
+		f(d)
+	}
+}
+
+// decisionBuffer keeps the latest decisions in memory and passes new decisions to subscribers
+type decisionBuffer struct {
+	mu          sync.Mutex
+	ring        []*Decision
+	next        int
+	full        bool
+	subscribers map[chan *Decision]struct{}
+}
+
+func newDecisionBuffer(size int) *decisionBuffer {
+	return &decisionBuffer{
+		ring:        make([]*Decision, size),
+		subscribers: map[chan *Decision]struct{}{},
+	}
+}
+
+// add stores the decision and passes it to all subscribers. Subscribers which don't keep up miss decisions.
+func (b *decisionBuffer) add(d *Decision) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.ring[b.next] = d
+	b.next = (b.next + 1) % len(b.ring)
+	b.full = b.full || b.next == 0
+	for ch := range b.subscribers {
+		select {
+		case ch <- d:
+		default:
+		}
+	}
+}
+
+// recent returns the buffered decisions, oldest first
+func (b *decisionBuffer) recent() []*Decision {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.recentLocked()
+}
+
+func (b *decisionBuffer) recentLocked() []*Decision {
+	if !b.full {
+		return append([]*Decision{}, b.ring[:b.next]...)
+	}
+	return append(append([]*Decision{}, b.ring[b.next:]...), b.ring[:b.next]...)
+}
+
+// find returns the latest buffered decision of the admission request, or nil if it isn't buffered (anymore)
+func (b *decisionBuffer) find(uid types.UID) *Decision {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	recent := b.recentLocked()
+	for i := len(recent) - 1; i >= 0; i-- {
+		if recent[i].UID == uid {
+			return recent[i]
+		}
+	}
+	return nil
+}
+
+// subscribe returns the buffered decisions and a channel receiving all later decisions.
+// The returned function cancels the subscription.
+func (b *decisionBuffer) subscribe() ([]*Decision, <-chan *Decision, func()) {
+	ch := make(chan *Decision, decisionSubscriberBuffer)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[ch] = struct{}{}
+	return b.recentLocked(), ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, ch)
+	}
+}
@@ -0,0 +1,162 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/admission/v1"
+)
+
+// DeprecationRuleName is the name of the rule detecting deprecated apiVersions
+const DeprecationRuleName = "deprecation"
+
+// DeprecationConfig configures the detection of deprecated apiVersions
+type DeprecationConfig struct {
+	// TargetVersion is the Kubernetes version the cluster will be upgraded to, e.g. "1.32".
+	// Objects with an apiVersion removed up to this version are reported. Empty reports all known removals.
+	TargetVersion string `json:"targetVersion"`
+	// APIs extend the built-in table of deprecated APIs
+	APIs []DeprecatedAPI `json:"apis"`
+	// Action is either warn (default) or deny
+	Action string `json:"action"`
+}
+
+// DeprecatedAPI is an apiVersion of a kind which is removed in a Kubernetes release
+type DeprecatedAPI struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	// Kind of the object, empty for all kinds of the group version
+	Kind        string `json:"kind"`
+	RemovedIn   string `json:"removedIn"`
+	Replacement string `json:"replacement"`
+}
+
+// deprecatedAPIs is the built-in table of removed apiVersions, taken from the Kubernetes deprecation guide
+var deprecatedAPIs = []DeprecatedAPI{
+	{Group: "extensions", Version: "v1beta1", Kind: "Ingress", RemovedIn: "1.22", Replacement: "networking.k8s.io/v1"},
+	{Group: "networking.k8s.io", Version: "v1beta1", RemovedIn: "1.22", Replacement: "networking.k8s.io/v1"},
+	{Group: "admissionregistration.k8s.io", Version: "v1beta1", RemovedIn: "1.22", Replacement: "admissionregistration.k8s.io/v1"},
+	{Group: "apiextensions.k8s.io", Version: "v1beta1", RemovedIn: "1.22", Replacement: "apiextensions.k8s.io/v1"},
+	{Group: "apiregistration.k8s.io", Version: "v1beta1", RemovedIn: "1.22", Replacement: "apiregistration.k8s.io/v1"},
+	{Group: "certificates.k8s.io", Version: "v1beta1", RemovedIn: "1.22", Replacement: "certificates.k8s.io/v1"},
+	{Group: "coordination.k8s.io", Version: "v1beta1", RemovedIn: "1.22", Replacement: "coordination.k8s.io/v1"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1beta1", RemovedIn: "1.22", Replacement: "rbac.authorization.k8s.io/v1"},
+	{Group: "scheduling.k8s.io", Version: "v1beta1", RemovedIn: "1.22", Replacement: "scheduling.k8s.io/v1"},
+	{Group: "storage.k8s.io", Version: "v1beta1", Kind: "CSIDriver", RemovedIn: "1.22", Replacement: "storage.k8s.io/v1"},
+	{Group: "storage.k8s.io", Version: "v1beta1", Kind: "CSINode", RemovedIn: "1.22", Replacement: "storage.k8s.io/v1"},
+	{Group: "storage.k8s.io", Version: "v1beta1", Kind: "StorageClass", RemovedIn: "1.22", Replacement: "storage.k8s.io/v1"},
+	{Group: "storage.k8s.io", Version: "v1beta1", Kind: "VolumeAttachment", RemovedIn: "1.22", Replacement: "storage.k8s.io/v1"},
+	{Group: "batch", Version: "v1beta1", Kind: "CronJob", RemovedIn: "1.25", Replacement: "batch/v1"},
+	{Group: "discovery.k8s.io", Version: "v1beta1", Kind: "EndpointSlice", RemovedIn: "1.25", Replacement: "discovery.k8s.io/v1"},
+	{Group: "events.k8s.io", Version: "v1beta1", Kind: "Event", RemovedIn: "1.25", Replacement: "events.k8s.io/v1"},
+	{Group: "autoscaling", Version: "v2beta1", Kind: "HorizontalPodAutoscaler", RemovedIn: "1.25", Replacement: "autoscaling/v2"},
+	{Group: "policy", Version: "v1beta1", Kind: "PodDisruptionBudget", RemovedIn: "1.25", Replacement: "policy/v1"},
+	{Group: "policy", Version: "v1beta1", Kind: "PodSecurityPolicy", RemovedIn: "1.25", Replacement: "Pod Security Admission"},
+	{Group: "node.k8s.io", Version: "v1beta1", Kind: "RuntimeClass", RemovedIn: "1.25", Replacement: "node.k8s.io/v1"},
+	{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta1", RemovedIn: "1.26", Replacement: "flowcontrol.apiserver.k8s.io/v1"},
+	{Group: "autoscaling", Version: "v2beta2", Kind: "HorizontalPodAutoscaler", RemovedIn: "1.26", Replacement: "autoscaling/v2"},
+	{Group: "storage.k8s.io", Version: "v1beta1", Kind: "CSIStorageCapacity", RemovedIn: "1.27", Replacement: "storage.k8s.io/v1"},
+	{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta2", RemovedIn: "1.29", Replacement: "flowcontrol.apiserver.k8s.io/v1"},
+	{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta3", RemovedIn: "1.32", Replacement: "flowcontrol.apiserver.k8s.io/v1"},
+}
+
+// deprecationRule reports objects submitted with apiVersions removed in upcoming releases
+type deprecationRule struct {
+	apis   []DeprecatedAPI
+	target [2]int
+	action string
+}
+
+func newDeprecationRule(_ *CosignServerHandler, cfg *Config) (Rule, error) {
+	c := cfg.Deprecation
+	if err := validateAction(&c.Action, ActionWarn); err != nil {
+		return nil, err
+	}
+	r := &deprecationRule{
+		apis:   append(append([]DeprecatedAPI{}, deprecatedAPIs...), c.APIs...),
+		target: [2]int{-1, -1},
+		action: c.Action,
+	}
+	if c.TargetVersion != "" {
+		t, err := parseMinorVersion(c.TargetVersion)
+		if err != nil {
+			return nil, err
+		}
+		r.target = t
+	}
+	for _, a := range c.APIs {
+		if _, err := parseMinorVersion(a.RemovedIn); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Name returns the name of the rule
+func (*deprecationRule) Name() string {
+	return DeprecationRuleName
+}
+
+// Validate checks the apiVersion the object was submitted with against the deprecation table
+func (r *deprecationRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	if o.Request.Operation == v1.Delete {
+		return nil, nil
+	}
+	// with matchPolicy Equivalent, the object may be converted; the request kind is what the client sent
+	gvk := o.Request.Kind
+	if o.Request.RequestKind != nil {
+		gvk = *o.Request.RequestKind
+		if gvk != o.Request.Kind {
+			tracef(ctx, "requested as %s", gvk.String())
+		}
+	}
+
+	var violations []string
+	for _, a := range r.apis {
+		if a.Group != gvk.Group || a.Version != gvk.Version || (a.Kind != "" && a.Kind != gvk.Kind) {
+			continue
+		}
+		removed, _ := parseMinorVersion(a.RemovedIn)
+		if r.target[0] >= 0 && compareMinorVersions(removed, r.target) > 0 {
+			continue
+		}
+		apiVersion := a.Version
+		if a.Group != "" {
+			apiVersion = a.Group + "/" + a.Version
+		}
+		violations = append(violations, fmt.Sprintf("%s %s is removed in Kubernetes %s, use %s instead", apiVersion, gvk.Kind, a.RemovedIn, a.Replacement))
+	}
+	return enforce(r.action, violations)
+}
+
+// parseMinorVersion parses Kubernetes versions like 1.32 or v1.32.1 into major and minor version
+func parseMinorVersion(v string) ([2]int, error) {
+	parts := strings.SplitN(strings.TrimPrefix(v, "v"), ".", 3)
+	if len(parts) < 2 {
+		return [2]int{}, fmt.Errorf("invalid Kubernetes version %q, expected e.g. 1.32", v)
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return [2]int{}, fmt.Errorf("invalid Kubernetes version %q: %w", v, err)
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return [2]int{}, fmt.Errorf("invalid Kubernetes version %q: %w", v, err)
+	}
+	return [2]int{major, minor}, nil
+}
+
+// compareMinorVersions returns -1, 0 or 1 if a is lower, equal or greater than b
+func compareMinorVersions(a, b [2]int) int {
+	for i := range a {
+		if a[i] < b[i] {
+			return -1
+		}
+		if a[i] > b[i] {
+			return 1
+		}
+	}
+	return 0
+}
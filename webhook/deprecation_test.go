@@ -0,0 +1,79 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_deprecationRule_Validate(t *testing.T) {
+	tests := []struct {
+		name         string
+		cfg          DeprecationConfig
+		requestKind  metav1.GroupVersionKind
+		wantWarnings int
+		wantErr      bool
+	}{
+		{
+			name:        "current api",
+			requestKind: metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+		},
+		{
+			name:         "removed api warns",
+			requestKind:  metav1.GroupVersionKind{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta3", Kind: "FlowSchema"},
+			wantWarnings: 1,
+		},
+		{
+			name:        "removed after target version",
+			cfg:         DeprecationConfig{TargetVersion: "1.31"},
+			requestKind: metav1.GroupVersionKind{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta3", Kind: "FlowSchema"},
+		},
+		{
+			name:        "removed up to target version denied",
+			cfg:         DeprecationConfig{TargetVersion: "v1.32.0", Action: ActionDeny},
+			requestKind: metav1.GroupVersionKind{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta3", Kind: "FlowSchema"},
+			wantErr:     true,
+		},
+		{
+			name: "custom api",
+			cfg: DeprecationConfig{APIs: []DeprecatedAPI{
+				{Group: "example.com", Version: "v1alpha1", Kind: "Widget", RemovedIn: "1.33", Replacement: "example.com/v1"},
+			}},
+			requestKind:  metav1.GroupVersionKind{Group: "example.com", Version: "v1alpha1", Kind: "Widget"},
+			wantWarnings: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newDeprecationRule(nil, &Config{Deprecation: tt.cfg})
+			if err != nil {
+				t.Fatal(err)
+			}
+			o := &Object{Request: &v1.AdmissionRequest{
+				Operation:   v1.Create,
+				Kind:        metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+				RequestKind: &tt.requestKind,
+			}}
+			warnings, err := r.Validate(context.Background(), o)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("Validate() got %d warnings, want %d", len(warnings), tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func Test_parseMinorVersion(t *testing.T) {
+	got, err := parseMinorVersion("v1.30.2")
+	if err != nil || got != [2]int{1, 30} {
+		t.Errorf("parseMinorVersion() = %v, %v", got, err)
+	}
+	if _, err := parseMinorVersion("latest"); err == nil {
+		t.Error("expected error for invalid version")
+	}
+}
@@ -0,0 +1,278 @@
+package webhook
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"text/template"
+
+	"sigs.k8s.io/yaml"
+)
+
+// formats of the policy documentation
+const (
+	DocsFormatMarkdown = "markdown"
+	DocsFormatHTML     = "html"
+)
+
+var (
+	//go:embed defaults/docs.md.tmpl
+	docsMarkdownTemplate string
+	//go:embed defaults/docs.html.tmpl
+	docsHTMLTemplate string
+)
+
+// ruleDoc describes a rule for the policy documentation
+type ruleDoc struct {
+	description string
+	// appliesTo are the kinds the rule validates
+	appliesTo string
+	// example of a request the rule rejects or warns about
+	example string
+	// severity if the rule has no or an empty action
+	severity string
+}
+
+// ruleDocs describe all rules which can be referenced in the endpoint config
+var ruleDocs = map[string]ruleDoc{
+	CosignRuleName: {
+		"Verifies the cosign signatures of the container images with the public key of the pod or namespace.",
+		"Pod", "a container image without signature of the configured key", ActionDeny,
+	},
+	PriorityClassRuleName: {
+		"Restricts the priority classes tenant pods may use, so they can't starve system pods.",
+		"Pod, Deployment, StatefulSet, DaemonSet", "a pod in a tenant namespace using system-cluster-critical", ActionDeny,
+	},
+	RuntimeClassRuleName: {
+		"Requires sandboxed runtime classes for pods in untrusted namespaces.",
+		"Pod, Deployment, StatefulSet, DaemonSet", "a pod in an untrusted namespace without the required runtime class", ActionDeny,
+	},
+	SecurityProfilesRuleName: {
+		"Enforces the seccomp, AppArmor and SELinux profiles of containers.",
+		"Pod, Deployment, StatefulSet, DaemonSet", "a container without accepted seccomp profile", ActionDeny,
+	},
+	EnvRuleName: {
+		"Denies forbidden env var names, keeps credentials out of literal env vars, by name and optionally by value, and restricts the secrets env vars may reference.",
+		"Pod, Deployment, StatefulSet, DaemonSet", "a container setting AWS_SECRET_ACCESS_KEY as literal value", ActionDeny,
+	},
+	ProbesRuleName: {
+		"Requires liveness and readiness probes on all containers.",
+		"Pod, Deployment, StatefulSet, DaemonSet", "a container without readiness probe", ActionWarn,
+	},
+	DeprecationRuleName: {
+		"Reports apiVersions removed up to the target Kubernetes version.",
+		"all kinds", "a PodDisruptionBudget with apiVersion policy/v1beta1", ActionWarn,
+	},
+	TopologyRuleName: {
+		"Requires critical deployments to be spread over zones.",
+		"Deployment", "a deployment labeled tier=critical without topology spread constraint", ActionDeny,
+	},
+	CostRuleName: {
+		"Limits the resource requests of pods, e.g. GPUs, unless the pod is approved by annotation.",
+		"Pod", "a pod requesting a GPU without approval annotation", ActionDeny,
+	},
+	QuotaRuleName: {
+		"Denies pods which would exceed the remaining resource quota of their namespace.",
+		"Pod", "a pod requesting more CPU than left in the quota", ActionDeny,
+	},
+	NamingRuleName: {
+		"Enforces naming conventions for object names and label values.",
+		"all kinds", "a deployment whose name misses the team prefix", ActionDeny,
+	},
+	TTLRuleName: {
+		"Requires a TTL annotation on workloads in sandbox namespaces, expired workloads may be deleted.",
+		"Pod, Deployment", "a deployment in a sandbox namespace without TTL", ActionDeny,
+	},
+	ReferencesRuleName: {
+		"Denies workloads referencing nonexistent ConfigMaps, Secrets or ServiceAccounts.",
+		"Pod, Deployment, StatefulSet, DaemonSet", "a pod mounting a ConfigMap with a typo in its name", ActionDeny,
+	},
+	DuplicatesRuleName: {
+		"Detects deployments whose selector overlaps another deployment in the same namespace.",
+		"Deployment", "a second deployment selecting app=web", ActionDeny,
+	},
+	ServicePortsRuleName: {
+		"Validates the type and ports of services, denying NodePort and optionally LoadBalancer services.",
+		"Service", "a service of type NodePort", ActionDeny,
+	},
+	HPARuleName: {
+		"Validates the replica bounds and metrics of horizontal pod autoscalers.",
+		"HorizontalPodAutoscaler", "an HPA scaling on CPU of a deployment without CPU requests", ActionDeny,
+	},
+	RBACRuleName: {
+		"Forbids wildcards in tenant roles and bindings to privileged cluster roles.",
+		"Role, ClusterRole, RoleBinding, ClusterRoleBinding", "a role granting verbs [\"*\"]", ActionDeny,
+	},
+	NamespaceRuleName: {
+		"Validates the metadata and creator of new namespaces.",
+		"Namespace", "a namespace without owner annotation", ActionDeny,
+	},
+	SanityRuleName: {
+		"Catches obviously broken pod specs.",
+		"Pod, Deployment, StatefulSet, DaemonSet", "a container with command [\"sleep 10\"]", ActionDeny,
+	},
+	RegistriesRuleName: {
+		"Restricts the registries images may be pulled from.",
+		"Pod, Deployment, StatefulSet, DaemonSet", "an image from Docker Hub if only ghcr.io is allowed", ActionDeny,
+	},
+	ServiceAccountTokensRuleName: {
+		"Restricts the audiences and expirations of projected service account tokens.",
+		"Pod, Deployment, StatefulSet, DaemonSet", "a pod projecting a token for an audience not in the allowlist", ActionDeny,
+	},
+	BlocklistRuleName: {
+		"Denies images on a blocklist of digests and patterns refreshed from a file or URL.",
+		"Pod, Deployment, StatefulSet, DaemonSet", "an image digest listed in a CVE feed", ActionDeny,
+	},
+	LicensesRuleName: {
+		"Checks the licenses declared in the SBOM attestations of images against an allowlist and denylist.",
+		"Pod", "an image with an AGPL-3.0-only package in a namespace denying AGPL", ActionDeny,
+	},
+	ImageTagsRuleName: {
+		"Denies images without tag or with a mutable tag like latest, optionally all tags in strict or opted-in namespaces.",
+		"Pod, Deployment, StatefulSet, DaemonSet", "a container with image nginx:latest", ActionDeny,
+	},
+	ResourcesRuleName: {
+		"Requires requests and limits on every container within per-namespace bounds.",
+		"Pod, Deployment, StatefulSet, DaemonSet", "a container without memory limit", ActionDeny,
+	},
+	RequiredLabelsRuleName: {
+		"Requires configured labels with values matching their patterns on workloads and their pod templates.",
+		"Pod, Deployment, StatefulSet, DaemonSet", "a deployment without owner label", ActionDeny,
+	},
+	RequiredAnnotationsRuleName: {
+		"Requires configured annotations with values matching their patterns on workloads.",
+		"Pod, Deployment, StatefulSet, DaemonSet", "a deployment without ticket-id annotation", ActionDeny,
+	},
+	PrivilegedRuleName: {
+		"Denies privileged init, regular and ephemeral containers outside of exempt namespaces.",
+		"Pod, Deployment, StatefulSet, DaemonSet", "a container with securityContext.privileged", ActionDeny,
+	},
+	HostPathRuleName: {
+		"Denies hostPath volumes unless the path is one of the allowed paths or below one of them.",
+		"Pod, Deployment, StatefulSet, DaemonSet", "a volume mounting host path /etc", ActionDeny,
+	},
+	HostNamespacesRuleName: {
+		"Denies pods sharing the network, PID or IPC namespace of the node, each can be allowed on its own.",
+		"Pod, Deployment, StatefulSet, DaemonSet", "a pod with hostNetwork", ActionDeny,
+	},
+	RunAsNonRootRuleName: {
+		"Denies containers which may run as root, by the effective runAsUser and runAsNonRoot of the container and the pod.",
+		"Pod, Deployment, StatefulSet, DaemonSet", "a container without runAsNonRoot and runAsUser", ActionDeny,
+	},
+	ReadOnlyRootFilesystemRuleName: {
+		"Requires readOnlyRootFilesystem: true on all containers, except the ones exempted by the cosignwebhook.eumel8.io/writable-root-filesystem annotation.",
+		"Pod, Deployment, StatefulSet, DaemonSet", "a container without readOnlyRootFilesystem", ActionDeny,
+	},
+	CapabilitiesRuleName: {
+		"Requires containers to drop ALL Linux capabilities and only add allowed ones, SYS_ADMIN and NET_ADMIN are forbidden by default.",
+		"Pod, Deployment, StatefulSet, DaemonSet", "a container adding SYS_ADMIN", ActionDeny,
+	},
+	ServiceAccountsRuleName: {
+		"Denies pods using the default service account or service accounts outside the allowlist of their namespace.",
+		"Pod, Deployment, StatefulSet, DaemonSet", "a pod without serviceAccountName", ActionDeny,
+	},
+	SchedulingRuleName: {
+		"Restricts the taints pods may tolerate and the node labels they may select, per namespace.",
+		"Pod, Deployment, StatefulSet, DaemonSet", "a tenant pod tolerating the dedicated infra taint", ActionDeny,
+	},
+	ReplicasRuleName: {
+		"Limits spec.replicas of Deployments and StatefulSets per namespace, so a single team can't exhaust the cluster.",
+		"Deployment, StatefulSet", "a deployment with more replicas than the maximum of its namespace", ActionDeny,
+	},
+	ContainerPortsRuleName: {
+		"Restricts containerPort to allowed ranges and denies hostPort outside of the namespaces allowed to use it.",
+		"Pod, Deployment, StatefulSet, DaemonSet", "a container with hostPort 80", ActionDeny,
+	},
+	IngressHostsRuleName: {
+		"Allows only hosts of ingresses in the domains configured by the operators.",
+		"Ingress", "an ingress claiming the host www.example.com", ActionDeny,
+	},
+}
+
+// PolicyDoc is the documentation of a policy, the endpoints with their rules
+type PolicyDoc struct {
+	Endpoints []EndpointDoc
+}
+
+// EndpointDoc documents an admission endpoint
+type EndpointDoc struct {
+	Path string
+	// Forward is the URL of the downstream validator, if any
+	Forward string
+	Rules   []RuleDoc
+}
+
+// RuleDoc documents a rule of an endpoint
+type RuleDoc struct {
+	Name        string
+	Description string
+	AppliesTo   string
+	Severity    string
+	Example     string
+	// Settings is the YAML config section of the rule, without empty values
+	Settings string
+}
+
+// NewPolicyDoc documents the endpoints and rules of the config
+func NewPolicyDoc(cfg *Config) (*PolicyDoc, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	sections := map[string]any{}
+	if err := json.Unmarshal(data, &sections); err != nil {
+		return nil, err
+	}
+	doc := &PolicyDoc{}
+	for _, e := range cfg.Endpoints {
+		ed := EndpointDoc{Path: e.Path}
+		if e.Forward != nil {
+			ed.Forward = e.Forward.URL
+		}
+		for _, name := range e.Rules {
+			rd := ruleDocs[name]
+			r := RuleDoc{Name: name, Description: rd.description, AppliesTo: rd.appliesTo, Severity: rd.severity, Example: rd.example}
+			if section, ok := pruneEmpty(sections[name]).(map[string]any); ok {
+				if action, ok := section["action"].(string); ok {
+					r.Severity = action
+				}
+				settings, err := yaml.Marshal(section)
+				if err != nil {
+					return nil, err
+				}
+				r.Settings = string(settings)
+			}
+			ed.Rules = append(ed.Rules, r)
+		}
+		doc.Endpoints = append(doc.Endpoints, ed)
+	}
+	return doc, nil
+}
+
+// Render renders the documentation as Markdown or HTML
+func (d *PolicyDoc) Render(format string) ([]byte, error) {
+	var buf bytes.Buffer
+	switch format {
+	case DocsFormatMarkdown:
+		tmpl, err := template.New("docs").Parse(docsMarkdownTemplate)
+		if err != nil {
+			return nil, err
+		}
+		if err := tmpl.Execute(&buf, d); err != nil {
+			return nil, err
+		}
+	case DocsFormatHTML:
+		tmpl, err := htmltemplate.New("docs").Parse(docsHTMLTemplate)
+		if err != nil {
+			return nil, err
+		}
+		if err := tmpl.Execute(&buf, d); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown docs format %q, must be %s or %s", format, DocsFormatMarkdown, DocsFormatHTML)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRuleDocs(t *testing.T) {
+	for _, name := range RuleNames() {
+		d, ok := ruleDocs[name]
+		if !ok {
+			t.Errorf("rule %q is not documented", name)
+			continue
+		}
+		if d.description == "" || d.appliesTo == "" || d.example == "" || d.severity == "" {
+			t.Errorf("documentation of rule %q is incomplete: %+v", name, d)
+		}
+	}
+}
+
+func TestPolicyDoc_Render(t *testing.T) {
+	cfg, err := ParseConfig([]byte(`
+endpoints:
+  - path: /validate
+    rules: [cosign, registries, probes]
+registries:
+  allowed: [ghcr.io]
+probes:
+  action: deny
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := NewPolicyDoc(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := doc.Endpoints[0].Rules
+	if len(rules) != 3 {
+		t.Fatalf("got %d rules, want 3", len(rules))
+	}
+	if rules[0].Settings != "" {
+		t.Errorf("cosign has settings %q, want none", rules[0].Settings)
+	}
+	if rules[1].Settings != "allowed:\n- ghcr.io\n" || rules[1].Severity != ActionDeny {
+		t.Errorf("registries documented as %+v", rules[1])
+	}
+	if rules[2].Severity != ActionDeny {
+		t.Errorf("probes severity = %s, want the configured %s", rules[2].Severity, ActionDeny)
+	}
+
+	md, err := doc.Render(DocsFormatMarkdown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"## Endpoint `/validate`", "| registries | Pod, Deployment, StatefulSet, DaemonSet | deny |", "### probes", "```yaml\nallowed:\n- ghcr.io\n```"} {
+		if !strings.Contains(string(md), want) {
+			t.Errorf("markdown misses %q:\n%s", want, md)
+		}
+	}
+	html, err := doc.Render(DocsFormatHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(html), "<h3>registries</h3>") {
+		t.Errorf("html misses the registries rule:\n%s", html)
+	}
+	if _, err := doc.Render("pdf"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
@@ -0,0 +1,218 @@
+package webhook
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// names of the checks of Diagnose, in order
+const (
+	CheckWebhookConfiguration = "webhook-configuration"
+	CheckCABundle             = "ca-bundle"
+	CheckEndpoints            = "endpoints"
+	CheckCanary               = "canary"
+)
+
+// canaryPodName is the name of the pod created with dry run by the canary check
+const canaryPodName = "cosignwebhook-doctor-canary"
+
+// DoctorOptions name the installation checked by Diagnose
+type DoctorOptions struct {
+	// WebhookConfiguration is the name of the ValidatingWebhookConfiguration of the webhook
+	WebhookConfiguration string
+	// Secret is the name of the TLS Secret in the namespace of the Service, empty for the name of the Service
+	Secret string
+	// CanaryNamespace is a namespace validated by the webhook, the canary pod is created in with dry run
+	CanaryNamespace string
+	// CanaryImage is the image of the canary pod
+	CanaryImage string
+}
+
+// DoctorCheck is the result of a check of Diagnose
+type DoctorCheck struct {
+	Name string `json:"name"`
+	OK   bool   `json:"ok"`
+	// Message describes what was found
+	Message string `json:"message"`
+	// Fix is the suggested fix of a failed check
+	Fix string `json:"fix,omitempty"`
+}
+
+// Diagnose checks an installation of the webhook from outside the cluster: the webhook configuration exists, its
+// caBundle verifies the certificate of the Secret, the Service has ready endpoints and a canary pod created with dry
+// run round-trips through the webhook. It stops at the first failed check the later ones depend on.
+func Diagnose(ctx context.Context, cs kubernetes.Interface, opts DoctorOptions) []DoctorCheck {
+	vwc, err := cs.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(ctx, opts.WebhookConfiguration, metav1.GetOptions{})
+	if err != nil {
+		return []DoctorCheck{{
+			Name:    CheckWebhookConfiguration,
+			Message: fmt.Sprintf("can't get ValidatingWebhookConfiguration %s: %v", opts.WebhookConfiguration, err),
+			Fix:     "install the webhook with the chart or cosignwebhook gen-manifests, or pass its name with --webhook-configuration",
+		}}
+	}
+	var svc *admissionregistrationv1.ServiceReference
+	for _, w := range vwc.Webhooks {
+		if w.ClientConfig.Service != nil {
+			svc = w.ClientConfig.Service
+			break
+		}
+	}
+	if svc == nil {
+		return []DoctorCheck{{
+			Name:    CheckWebhookConfiguration,
+			Message: fmt.Sprintf("ValidatingWebhookConfiguration %s has no webhook calling a Service", vwc.Name),
+			Fix:     "set clientConfig.service of the webhooks to the Service of the webhook",
+		}}
+	}
+	checks := []DoctorCheck{{
+		Name:    CheckWebhookConfiguration,
+		OK:      true,
+		Message: fmt.Sprintf("ValidatingWebhookConfiguration %s calls Service %s/%s", vwc.Name, svc.Namespace, svc.Name),
+	}}
+	checks = append(checks, diagnoseCABundle(ctx, cs, vwc, svc, opts.Secret))
+	endpoints := diagnoseEndpoints(ctx, cs, svc)
+	checks = append(checks, endpoints)
+	if !endpoints.OK {
+		return checks
+	}
+	return append(checks, diagnoseCanary(ctx, cs, vwc, opts))
+}
+
+// diagnoseCABundle checks the certificate of the Secret against the webhooks of the configuration
+func diagnoseCABundle(ctx context.Context, cs kubernetes.Interface, vwc *admissionregistrationv1.ValidatingWebhookConfiguration,
+	svc *admissionregistrationv1.ServiceReference, name string) DoctorCheck {
+	check := DoctorCheck{Name: CheckCABundle}
+	if name == "" {
+		name = svc.Name
+	}
+	secret, err := cs.CoreV1().Secrets(svc.Namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		check.Message = fmt.Sprintf("can't get Secret %s/%s: %v", svc.Namespace, name, err)
+		check.Fix = "pass the name of the TLS Secret of the webhook with --secret"
+		return check
+	}
+	block, _ := pem.Decode(secret.Data[corev1.TLSCertKey])
+	if block == nil {
+		check.Message = fmt.Sprintf("Secret %s/%s has no PEM certificate in %s", svc.Namespace, name, corev1.TLSCertKey)
+		check.Fix = "recreate the Secret with kubectl create secret tls"
+		return check
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		check.Message = fmt.Sprintf("invalid certificate in Secret %s/%s: %v", svc.Namespace, name, err)
+		check.Fix = "recreate the Secret with kubectl create secret tls"
+		return check
+	}
+	if err := verifyTrust(cert, vwc); err != nil {
+		check.Message = err.Error()
+		check.Fix = fmt.Sprintf("issue the certificate for %s.%s.svc and set the caBundle to its CA, e.g. with helm upgrade, "+
+			"which regenerates both, and restart the webhook", svc.Name, svc.Namespace)
+		return check
+	}
+	check.OK = true
+	check.Message = fmt.Sprintf("caBundle verifies the certificate of Secret %s/%s for %s.%s.svc", svc.Namespace, name, svc.Name, svc.Namespace)
+	return check
+}
+
+// diagnoseEndpoints checks that the Service of the webhook has ready endpoints
+func diagnoseEndpoints(ctx context.Context, cs kubernetes.Interface, svc *admissionregistrationv1.ServiceReference) DoctorCheck {
+	check := DoctorCheck{Name: CheckEndpoints}
+	service, err := cs.CoreV1().Services(svc.Namespace).Get(ctx, svc.Name, metav1.GetOptions{})
+	if err != nil {
+		check.Message = fmt.Sprintf("can't get Service %s/%s: %v", svc.Namespace, svc.Name, err)
+		check.Fix = "create the Service of the webhook, or point clientConfig.service of the webhook configuration to it"
+		return check
+	}
+	eps, err := cs.DiscoveryV1().EndpointSlices(svc.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.Set{discoveryv1.LabelServiceName: svc.Name}.String(),
+	})
+	if err != nil {
+		check.Message = fmt.Sprintf("can't list EndpointSlices of Service %s/%s: %v", svc.Namespace, svc.Name, err)
+		return check
+	}
+	ready, notReady := 0, 0
+	for _, s := range eps.Items {
+		for _, e := range s.Endpoints {
+			if e.Conditions.Ready == nil || *e.Conditions.Ready {
+				ready++
+			} else {
+				notReady++
+			}
+		}
+	}
+	if ready == 0 {
+		selector := labels.Set(service.Spec.Selector).String()
+		check.Message = fmt.Sprintf("Service %s/%s has no ready endpoints, %d not ready", svc.Namespace, svc.Name, notReady)
+		check.Fix = fmt.Sprintf("check the pods with kubectl -n %s get pods -l %s, not ready pods report the reason on /readyz and in their logs",
+			svc.Namespace, selector)
+		return check
+	}
+	check.OK = true
+	check.Message = fmt.Sprintf("Service %s/%s has %d ready endpoints, %d not ready", svc.Namespace, svc.Name, ready, notReady)
+	return check
+}
+
+// diagnoseCanary creates a pod with dry run in the canary namespace, the API server calls the webhook for it
+func diagnoseCanary(ctx context.Context, cs kubernetes.Interface, vwc *admissionregistrationv1.ValidatingWebhookConfiguration, opts DoctorOptions) DoctorCheck {
+	check := DoctorCheck{Name: CheckCanary}
+	ns, err := cs.CoreV1().Namespaces().Get(ctx, opts.CanaryNamespace, metav1.GetOptions{})
+	if err != nil {
+		check.Message = fmt.Sprintf("can't get canary namespace %s: %v", opts.CanaryNamespace, err)
+		check.Fix = "pass a namespace validated by the webhook with --canary-namespace"
+		return check
+	}
+	if !validatesNamespace(vwc, ns) {
+		check.Message = fmt.Sprintf("namespace %s isn't validated by ValidatingWebhookConfiguration %s", ns.Name, vwc.Name)
+		check.Fix = "pass a namespace matching the namespaceSelector of the webhooks with --canary-namespace"
+		return check
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: canaryPodName, Namespace: ns.Name},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "canary", Image: opts.CanaryImage}}},
+	}
+	_, err = cs.CoreV1().Pods(ns.Name).Create(ctx, pod, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}})
+	switch {
+	case err == nil:
+		check.OK = true
+		check.Message = fmt.Sprintf("canary pod in namespace %s admitted", ns.Name)
+	case strings.Contains(err.Error(), "denied the request"):
+		// the webhook answered, a denial of the canary is a policy decision
+		check.OK = true
+		check.Message = fmt.Sprintf("canary pod in namespace %s denied by the webhook: %v", ns.Name, err)
+	case strings.Contains(err.Error(), "failed calling webhook"):
+		check.Message = err.Error()
+		check.Fix = "the API server can't reach the webhook: check NetworkPolicies and firewalls between the control plane and the " +
+			"webhook port, the certificate (see ca-bundle) and timeoutSeconds of the webhook configuration"
+	default:
+		check.Message = fmt.Sprintf("can't create canary pod in namespace %s: %v", ns.Name, err)
+		if apierrors.IsForbidden(err) {
+			check.Fix = "grant create on pods in the canary namespace, or pass another one with --canary-namespace"
+		}
+	}
+	return check
+}
+
+// validatesNamespace returns true if a webhook of the configuration matches the namespace
+func validatesNamespace(vwc *admissionregistrationv1.ValidatingWebhookConfiguration, ns *corev1.Namespace) bool {
+	for _, w := range vwc.Webhooks {
+		if w.NamespaceSelector == nil {
+			return true
+		}
+		selector, err := metav1.LabelSelectorAsSelector(w.NamespaceSelector)
+		if err == nil && selector.Matches(labels.Set(ns.Labels)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDiagnose(t *testing.T) {
+	ca, cert, key, err := starterCerts([]string{"cosignwebhook.cosignwebhook.svc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherCA, _, _, err := starterCerts([]string{"cosignwebhook.cosignwebhook.svc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vwc := func(caBundle []byte) *admissionregistrationv1.ValidatingWebhookConfiguration {
+		return &admissionregistrationv1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "cosignwebhook"},
+			Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+				Name: "cosignwebhook.eumel8.io",
+				NamespaceSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{
+					Key: "kubernetes.io/metadata.name", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"cosignwebhook", "kube-system"},
+				}}},
+				ClientConfig: admissionregistrationv1.WebhookClientConfig{
+					Service:  &admissionregistrationv1.ServiceReference{Name: "cosignwebhook", Namespace: "cosignwebhook"},
+					CABundle: caBundle,
+				},
+			}},
+		}
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "cosignwebhook", Namespace: "cosignwebhook"},
+		Data:       map[string][]byte{corev1.TLSCertKey: cert, corev1.TLSPrivateKeyKey: key},
+	}
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "cosignwebhook", Namespace: "cosignwebhook"},
+		Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": "cosignwebhook"}},
+	}
+	endpoints := func(ready bool) *discoveryv1.EndpointSlice {
+		return &discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "cosignwebhook-abcde", Namespace: "cosignwebhook",
+				Labels: map[string]string{discoveryv1.LabelServiceName: "cosignwebhook"},
+			},
+			Endpoints: []discoveryv1.Endpoint{{Addresses: []string{"10.0.0.1"}, Conditions: discoveryv1.EndpointConditions{Ready: &ready}}},
+		}
+	}
+	namespace := func(name string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"kubernetes.io/metadata.name": name}}}
+	}
+
+	tests := []struct {
+		name       string
+		objects    []runtime.Object
+		canary     string
+		wantChecks map[string]bool
+		wantFix    string
+	}{
+		{
+			name:       "healthy",
+			objects:    []runtime.Object{vwc(ca), secret, service, endpoints(true), namespace("default")},
+			canary:     "default",
+			wantChecks: map[string]bool{CheckWebhookConfiguration: true, CheckCABundle: true, CheckEndpoints: true, CheckCanary: true},
+		},
+		{
+			name:       "missing webhook configuration",
+			wantChecks: map[string]bool{CheckWebhookConfiguration: false},
+			wantFix:    "--webhook-configuration",
+		},
+		{
+			name:       "caBundle of other CA",
+			objects:    []runtime.Object{vwc(otherCA), secret, service, endpoints(true), namespace("default")},
+			canary:     "default",
+			wantChecks: map[string]bool{CheckWebhookConfiguration: true, CheckCABundle: false, CheckEndpoints: true, CheckCanary: true},
+			wantFix:    "issue the certificate for cosignwebhook.cosignwebhook.svc",
+		},
+		{
+			name:       "no ready endpoints",
+			objects:    []runtime.Object{vwc(ca), secret, service, endpoints(false)},
+			wantChecks: map[string]bool{CheckWebhookConfiguration: true, CheckCABundle: true, CheckEndpoints: false},
+			wantFix:    "kubectl -n cosignwebhook get pods -l app=cosignwebhook",
+		},
+		{
+			name:       "canary namespace not validated",
+			objects:    []runtime.Object{vwc(ca), secret, service, endpoints(true), namespace("kube-system")},
+			canary:     "kube-system",
+			wantChecks: map[string]bool{CheckWebhookConfiguration: true, CheckCABundle: true, CheckEndpoints: true, CheckCanary: false},
+			wantFix:    "--canary-namespace",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checks := Diagnose(context.Background(), fake.NewSimpleClientset(tt.objects...), DoctorOptions{
+				WebhookConfiguration: "cosignwebhook",
+				CanaryNamespace:      tt.canary,
+				CanaryImage:          "busybox",
+			})
+			if len(checks) != len(tt.wantChecks) {
+				t.Fatalf("Diagnose() = %+v, want %d checks", checks, len(tt.wantChecks))
+			}
+			for _, c := range checks {
+				if want, ok := tt.wantChecks[c.Name]; !ok || c.OK != want {
+					t.Errorf("check %s ok = %v, want %v: %s", c.Name, c.OK, want, c.Message)
+				}
+				if !c.OK && !strings.Contains(c.Fix, tt.wantFix) {
+					t.Errorf("fix of %s = %q, want %q", c.Name, c.Fix, tt.wantFix)
+				}
+			}
+		})
+	}
+}
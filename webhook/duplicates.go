@@ -0,0 +1,84 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+)
+
+// DuplicatesRuleName is the name of the rule detecting overlapping deployment selectors
+const DuplicatesRuleName = "duplicates"
+
+// DuplicatesConfig configures the detection of overlapping deployments
+type DuplicatesConfig struct {
+	// Action is either deny (default) or warn
+	Action string `json:"action"`
+}
+
+// duplicatesRule detects deployments whose selector overlaps another deployment in the same namespace,
+// which makes both controllers fight over the same pods and breaks rollouts
+type duplicatesRule struct {
+	deployments appslisters.DeploymentLister
+	action      string
+}
+
+func newDuplicatesRule(csh *CosignServerHandler, cfg *Config) (Rule, error) {
+	c := cfg.Duplicates
+	if err := validateAction(&c.Action, ActionDeny); err != nil {
+		return nil, err
+	}
+	csh.selectInformers("deployments")
+	return &duplicatesRule{
+		deployments: csh.informers.Apps().V1().Deployments().Lister(),
+		action:      c.Action,
+	}, nil
+}
+
+// Name returns the name of the rule
+func (*duplicatesRule) Name() string {
+	return DuplicatesRuleName
+}
+
+// Validate compares the deployment with all other deployments in its namespace. Two deployments overlap
+// if the selector of one matches the pod template labels of the other.
+func (r *duplicatesRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	d := o.Deployment
+	if d == nil || o.Request.Operation == v1.Delete {
+		return nil, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(d.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector of deployment %q: %w", d.Name, err)
+	}
+	others, err := r.deployments.Deployments(o.Request.Namespace).List(labels.Everything())
+	if err != nil {
+		return nil, internalError(fmt.Errorf("can't list deployments: %w", err))
+	}
+
+	var violations []string
+	for _, other := range others {
+		if other.Name == d.Name {
+			continue
+		}
+		otherSelector, err := metav1.LabelSelectorAsSelector(other.Spec.Selector)
+		if err != nil {
+			continue
+		}
+		matchesOther := selector.Matches(labels.Set(other.Spec.Template.Labels))
+		if matchesOther {
+			tracef(ctx, "selector %q matches the template labels %v of deployment %q", selector, other.Spec.Template.Labels, other.Name)
+		}
+		matchedByOther := otherSelector.Matches(labels.Set(d.Spec.Template.Labels))
+		if matchedByOther {
+			tracef(ctx, "selector %q of deployment %q matches the template labels %v", otherSelector, other.Name, d.Spec.Template.Labels)
+		}
+		if matchesOther || matchedByOther {
+			violations = append(violations, fmt.Sprintf("selector %q of deployment %q overlaps with deployment %q", selector, d.Name, other.Name))
+		}
+	}
+	return enforce(r.action, violations)
+}
@@ -0,0 +1,63 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// selectorDeployment returns a deployment selecting and labeling its pods with passed labels
+func selectorDeployment(name string, selector, podLabels map[string]string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: selector},
+			Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: podLabels}},
+		},
+	}
+}
+
+func Test_duplicatesRule_Validate(t *testing.T) {
+	existing := selectorDeployment("frontend", map[string]string{"app": "web"}, map[string]string{"app": "web", "tier": "frontend"})
+	tests := []struct {
+		name       string
+		deployment *appsv1.Deployment
+		wantErr    bool
+	}{
+		{
+			name:       "distinct selector",
+			deployment: selectorDeployment("backend", map[string]string{"app": "api"}, map[string]string{"app": "api"}),
+		},
+		{
+			name:       "same selector",
+			deployment: selectorDeployment("frontend-copy", map[string]string{"app": "web"}, map[string]string{"app": "web"}),
+			wantErr:    true,
+		},
+		{
+			name:       "existing selector matches new pods",
+			deployment: selectorDeployment("web-canary", map[string]string{"track": "canary"}, map[string]string{"app": "web", "track": "canary"}),
+			wantErr:    true,
+		},
+		{
+			name:       "update of existing deployment",
+			deployment: existing,
+		},
+	}
+
+	r := testRule(t, newDuplicatesRule, &Config{}, existing)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := deploymentObject("default", nil, corev1.PodSpec{})
+			o.Request.Operation = v1.Create
+			o.Deployment = tt.deployment
+			_, err := r.Validate(context.Background(), o)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
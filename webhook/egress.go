@@ -0,0 +1,87 @@
+package webhook
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// egress routes the external HTTP calls of the webhook, to registries, downstream validators, blocklist sources,
+// telemetry endpoints and publishers, through the configured proxies and trusts the configured CAs.
+// A nil egress uses the proxies from the environment and the system CAs.
+type egress struct {
+	// proxy returns the proxy for a URL, nil uses the environment
+	proxy func(*url.URL) (*url.URL, error)
+	// overrides are the proxies by destination host, nil for direct connections
+	overrides map[string]*url.URL
+	// rootCAs are the system CAs with the CA bundle, nil for the system CAs only
+	rootCAs *x509.CertPool
+	// shared is the transport of clients without TLS settings of their own, reusing connections
+	shared *http.Transport
+}
+
+func newEgress(s ServerConfig) (*egress, error) {
+	e := &egress{}
+	proxy, err := s.ProxyURL()
+	if err != nil {
+		return nil, err
+	}
+	if proxy != nil {
+		c := httpproxy.Config{HTTPProxy: proxy.String(), HTTPSProxy: proxy.String(), NoProxy: s.NoProxy}
+		e.proxy = c.ProxyFunc()
+	}
+	if e.overrides, err = s.ProxyOverrideMap(); err != nil {
+		return nil, err
+	}
+	if e.rootCAs, err = s.CAPool(); err != nil {
+		return nil, err
+	}
+	e.shared = e.transport(nil)
+	return e, nil
+}
+
+// proxyFor returns the proxy for the request, nil for a direct connection
+func (e *egress) proxyFor(req *http.Request) (*url.URL, error) {
+	if p, ok := e.overrides[req.URL.Hostname()]; ok {
+		return p, nil
+	}
+	if e.proxy == nil {
+		return http.ProxyFromEnvironment(req)
+	}
+	return e.proxy(req.URL)
+}
+
+// transport returns a new transport for external calls. tlsConfig may be nil, without root CAs it trusts
+// the CAs of the egress.
+func (e *egress) transport(tlsConfig *tls.Config) *http.Transport {
+	if e == nil {
+		e = &egress{}
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if tlsConfig.RootCAs == nil {
+		tlsConfig.RootCAs = e.rootCAs
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = e.proxyFor
+	t.TLSClientConfig = tlsConfig
+	return t
+}
+
+// roundTripper returns the shared transport for external calls
+func (e *egress) roundTripper() http.RoundTripper {
+	if e == nil || e.shared == nil {
+		return http.DefaultTransport
+	}
+	return e.shared
+}
+
+// client returns an HTTP client for external calls with passed timeout
+func (e *egress) client(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: e.roundTripper()}
+}
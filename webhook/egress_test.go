@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_egress_proxyFor(t *testing.T) {
+	e, err := newEgress(ServerConfig{
+		Proxy:          "http://proxy.example.com:3128",
+		NoProxy:        ".internal.example.com",
+		ProxyOverrides: "registry.example.com=direct,ghcr.io=http://proxy-b.example.com:3128",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for dest, want := range map[string]string{
+		"https://quay.io/v2/":                      "proxy.example.com:3128",
+		"https://ghcr.io/v2/":                      "proxy-b.example.com:3128",
+		"https://registry.example.com/v2/":         "",
+		"https://git.internal.example.com/blocked": "",
+	} {
+		req, err := http.NewRequest(http.MethodGet, dest, http.NoBody)
+		if err != nil {
+			t.Fatal(err)
+		}
+		proxy, err := e.proxyFor(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := ""
+		if proxy != nil {
+			got = proxy.Host
+		}
+		if got != want {
+			t.Errorf("proxy for %s = %q, want %q", dest, got, want)
+		}
+	}
+}
+
+func Test_egress_caBundle(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	if resp, err := (*egress)(nil).client(time.Second).Get(srv.URL); err == nil {
+		resp.Body.Close()
+		t.Fatal("request to server with unknown CA succeeded")
+	}
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(bundle, ca, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	e, err := newEgress(ServerConfig{CABundle: bundle})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := e.client(time.Second).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request with CA bundle failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if _, err := newEgress(ServerConfig{CABundle: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Error("newEgress() with missing CA bundle, want error")
+	}
+}
@@ -0,0 +1,116 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// EnvRuleName is the name of the rule restricting environment variables
+const EnvRuleName = "env"
+
+// EnvConfig configures forbidden environment variables and secret references
+type EnvConfig struct {
+	// ForbiddenNames are regular expressions matching the whole name of env vars which must not be set at all,
+	// neither literal nor from a reference, e.g. KUBERNETES_SERVICE_HOST
+	ForbiddenNames []string `json:"forbiddenNames"`
+	// ForbiddenLiterals are regular expressions matching the whole name of env vars
+	// which must not be set with a literal value, e.g. AWS_SECRET_ACCESS_KEY. They may still be set from a secret.
+	ForbiddenLiterals []string `json:"forbiddenLiterals"`
+	// AllowedSecrets maps namespaces to the secrets env vars may reference.
+	// In namespaces not listed, all secrets may be referenced.
+	AllowedSecrets map[string][]string `json:"allowedSecrets"`
+	// SecretValues detects literal values which look like secrets, e.g. API keys, passwords or random tokens
+	SecretValues SecretValuesConfig `json:"secretValues"`
+}
+
+// envRule prevents credentials in plain env vars and references to unexpected secrets
+type envRule struct {
+	forbiddenNames    *patternSet
+	forbiddenLiterals *patternSet
+	allowedSecrets    map[string][]string
+	// secrets is nil if the detection of secret values is disabled
+	secrets *secretScanner
+}
+
+func newEnvRule(_ *CosignServerHandler, cfg *Config) (Rule, error) {
+	names, err := compilePatternSet(cfg.Env.ForbiddenNames)
+	if err != nil {
+		return nil, err
+	}
+	res, err := compilePatternSet(cfg.Env.ForbiddenLiterals)
+	if err != nil {
+		return nil, err
+	}
+	secrets, err := newSecretScanner(cfg.Env.SecretValues)
+	if err != nil {
+		return nil, err
+	}
+	return &envRule{
+		forbiddenNames:    names,
+		forbiddenLiterals: res,
+		allowedSecrets:    cfg.Env.AllowedSecrets,
+		secrets:           secrets,
+	}, nil
+}
+
+// Name returns the name of the rule
+func (*envRule) Name() string {
+	return EnvRuleName
+}
+
+// fieldPolicies returns the forbidden names and literals matched by the matcher of the endpoint
+func (r *envRule) fieldPolicies() []fieldPolicy {
+	return []fieldPolicy{{field: fieldEnvName, patterns: r.forbiddenNames}, {field: fieldEnvName, patterns: r.forbiddenLiterals}}
+}
+
+// Validate checks the env vars and envFrom sources of all containers of pods and workloads. Secret values found
+// are reported with the action of the secret values, the other violations deny.
+func (r *envRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	spec := templatePodSpec(o)
+	if spec == nil {
+		return nil, nil
+	}
+	allowed, restricted := r.allowedSecrets[o.Request.Namespace]
+	if restricted {
+		tracef(ctx, "allowed secrets of namespace %q: %v", o.Request.Namespace, allowed)
+	}
+	forbiddenNames := o.matched(fieldPolicy{field: fieldEnvName, patterns: r.forbiddenNames})
+	forbiddenLiterals := o.matched(fieldPolicy{field: fieldEnvName, patterns: r.forbiddenLiterals})
+
+	var violations, leaks []string
+	for _, c := range podContainers(spec) {
+		for _, e := range c.Env {
+			v := fieldMatch{container: c.Name, value: e.Name}
+			if forbiddenNames[v] {
+				violations = append(violations, fmt.Sprintf("container %q must not set env var %q", c.Name, e.Name))
+				continue
+			}
+			if e.Value != "" && forbiddenLiterals[v] {
+				violations = append(violations, fmt.Sprintf("container %q must not set env var %q as literal value, use a secret reference", c.Name, e.Name))
+			} else if r.secrets != nil {
+				if what := r.secrets.scan(e); what != "" {
+					leaks = append(leaks, fmt.Sprintf("container %q sets env var %q to a literal value which looks like %s, use a secret reference",
+						c.Name, e.Name, what))
+				}
+			}
+			if restricted && e.ValueFrom != nil && e.ValueFrom.SecretKeyRef != nil && !slices.Contains(allowed, e.ValueFrom.SecretKeyRef.Name) {
+				violations = append(violations, fmt.Sprintf("container %q references secret %q in env var %q, which is not allowed in namespace %q",
+					c.Name, e.ValueFrom.SecretKeyRef.Name, e.Name, o.Request.Namespace))
+			}
+		}
+		if !restricted {
+			continue
+		}
+		for _, ef := range c.EnvFrom {
+			if ef.SecretRef != nil && !slices.Contains(allowed, ef.SecretRef.Name) {
+				violations = append(violations, fmt.Sprintf("container %q references secret %q in envFrom, which is not allowed in namespace %q",
+					c.Name, ef.SecretRef.Name, o.Request.Namespace))
+			}
+		}
+	}
+	if len(leaks) > 0 && r.secrets.action == ActionWarn {
+		return leaks, violationsError(violations)
+	}
+	return nil, violationsError(append(violations, leaks...))
+}
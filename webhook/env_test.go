@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_envRule_Validate(t *testing.T) {
+	cfg := &Config{Env: EnvConfig{
+		ForbiddenNames:    []string{"KUBERNETES_SERVICE_(HOST|PORT)", "LD_PRELOAD"},
+		ForbiddenLiterals: []string{"AWS_SECRET_ACCESS_KEY", ".*_PASSWORD"},
+		AllowedSecrets:    map[string][]string{"team-a": {"team-a-creds"}},
+	}}
+	secretRef := func(name string) *corev1.EnvVarSource {
+		return &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: name},
+			Key:                  "key",
+		}}
+	}
+	tests := []struct {
+		name    string
+		ns      string
+		env     []corev1.EnvVar
+		envFrom []corev1.EnvFromSource
+		wantErr bool
+	}{
+		{
+			name: "harmless env var",
+			ns:   "default",
+			env:  []corev1.EnvVar{{Name: "LOG_LEVEL", Value: "debug"}},
+		},
+		{
+			name:    "forbidden literal",
+			ns:      "default",
+			env:     []corev1.EnvVar{{Name: "AWS_SECRET_ACCESS_KEY", Value: "abc"}},
+			wantErr: true,
+		},
+		{
+			name:    "forbidden literal by pattern",
+			ns:      "default",
+			env:     []corev1.EnvVar{{Name: "DB_PASSWORD", Value: "abc"}},
+			wantErr: true,
+		},
+		{
+			name: "forbidden name from secret",
+			ns:   "default",
+			env:  []corev1.EnvVar{{Name: "AWS_SECRET_ACCESS_KEY", ValueFrom: secretRef("aws")}},
+		},
+		{
+			name:    "forbidden name",
+			ns:      "default",
+			env:     []corev1.EnvVar{{Name: "KUBERNETES_SERVICE_HOST", Value: "10.0.0.1"}},
+			wantErr: true,
+		},
+		{
+			name:    "forbidden name from secret",
+			ns:      "default",
+			env:     []corev1.EnvVar{{Name: "LD_PRELOAD", ValueFrom: secretRef("preload")}},
+			wantErr: true,
+		},
+		{
+			name: "name only matching part of a forbidden name",
+			ns:   "default",
+			env:  []corev1.EnvVar{{Name: "MY_KUBERNETES_SERVICE_HOST", Value: "api"}},
+		},
+		{
+			name: "allowed secret",
+			ns:   "team-a",
+			env:  []corev1.EnvVar{{Name: "TOKEN", ValueFrom: secretRef("team-a-creds")}},
+		},
+		{
+			name:    "secret not allowed",
+			ns:      "team-a",
+			env:     []corev1.EnvVar{{Name: "TOKEN", ValueFrom: secretRef("team-b-creds")}},
+			wantErr: true,
+		},
+		{
+			name: "secret not allowed in envFrom",
+			ns:   "team-a",
+			envFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "team-b-creds"},
+			}}},
+			wantErr: true,
+		},
+	}
+
+	r, err := newEnvRule(nil, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Env: tt.env, EnvFrom: tt.envFrom}}}
+			_, err := r.Validate(context.Background(), podObject(tt.ns, spec))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_newEnvRule_invalidPattern(t *testing.T) {
+	_, err := newEnvRule(nil, &Config{Env: EnvConfig{ForbiddenLiterals: []string{"("}}})
+	if err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+	_, err = newEnvRule(nil, &Config{Env: EnvConfig{ForbiddenNames: []string{"("}}})
+	if err == nil {
+		t.Error("expected error for invalid name pattern")
+	}
+}
@@ -0,0 +1,143 @@
+package webhook
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// defaults of the entropy heuristic of secret values
+const (
+	DefaultSecretMinLength  = 20
+	DefaultSecretMinEntropy = 4.0
+)
+
+// SecretValuesConfig configures the detection of secrets in literal env values
+type SecretValuesConfig struct {
+	// Enabled scans the literal values of env vars for secrets
+	Enabled bool `json:"enabled"`
+	// Patterns are additional regular expressions matching a part of secret values, e.g. the format of internal
+	// tokens
+	Patterns []string `json:"patterns"`
+	// IgnoredNames are regular expressions matching the whole name of env vars which are never scanned
+	IgnoredNames []string `json:"ignoredNames"`
+	// MinLength is the minimum length of values the entropy is checked of, defaults to 20
+	MinLength int `json:"minLength"`
+	// MinEntropy is the Shannon entropy in bits per character from which values are considered random, defaults to 4
+	MinEntropy float64 `json:"minEntropy"`
+	// Action is either warn (default) or deny, as the heuristics may match harmless values
+	Action string `json:"action"`
+}
+
+// secretFormat is a well-known format of credentials
+type secretFormat struct {
+	what string
+	re   *regexp.Regexp
+}
+
+var secretFormats = []secretFormat{
+	{"an AWS access key ID", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"a GitHub token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{"a Slack token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{"a Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"a private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+	{"a JSON web token", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+	{"a URL with credentials", regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@]+:[^/\s@]+@`)},
+}
+
+var (
+	// credentialName matches names of env vars which usually hold credentials
+	credentialName = regexp.MustCompile(`(?i)(?:^|_)(?:PASSWORD|PASSWD|SECRET|TOKEN|API_?KEY|ACCESS_?KEY|PRIVATE_?KEY|CREDENTIALS?)(?:_|$)`)
+	// referenceName matches names of env vars which usually hold a reference to a credential, not the credential
+	referenceName = regexp.MustCompile(`(?i)_(?:FILE|PATH|DIR|NAME|REF|ID|URL|ENDPOINT|TTL|EXPIRY)$`)
+	// randomValue matches values of the characters of base64 and hex encodings
+	randomValue = regexp.MustCompile(`^[A-Za-z0-9+/=_-]+$`)
+)
+
+// minCredentialLength is the minimum length of values of env vars named like credentials to be reported
+const minCredentialLength = 8
+
+// secretScanner detects literal env values which look like secrets
+type secretScanner struct {
+	patterns     []*regexp.Regexp
+	ignoredNames *patternSet
+	minLength    int
+	minEntropy   float64
+	action       string
+}
+
+func newSecretScanner(c SecretValuesConfig) (*secretScanner, error) {
+	if !c.Enabled {
+		return nil, nil
+	}
+	if err := validateAction(&c.Action, ActionWarn); err != nil {
+		return nil, err
+	}
+	s := &secretScanner{minLength: c.MinLength, minEntropy: c.MinEntropy, action: c.Action}
+	if s.minLength <= 0 {
+		s.minLength = DefaultSecretMinLength
+	}
+	if s.minEntropy <= 0 {
+		s.minEntropy = DefaultSecretMinEntropy
+	}
+	for _, p := range c.Patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret pattern %q: %w", p, err)
+		}
+		s.patterns = append(s.patterns, re)
+	}
+	var err error
+	if s.ignoredNames, err = compilePatternSet(c.IgnoredNames); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// scan returns what the value of the env var looks like if it looks like a secret, or an empty string. Well-known
+// formats and the configured patterns are checked first, then the name and at last the entropy of the value.
+func (s *secretScanner) scan(e corev1.EnvVar) string {
+	if e.Value == "" || s.ignoredNames.matches(e.Name) {
+		return ""
+	}
+	for _, f := range secretFormats {
+		if f.re.MatchString(e.Value) {
+			return f.what
+		}
+	}
+	for _, re := range s.patterns {
+		if re.MatchString(e.Value) {
+			return "a secret of pattern " + re.String()
+		}
+	}
+	value := strings.TrimSpace(e.Value)
+	if credentialName.MatchString(e.Name) && !referenceName.MatchString(e.Name) && len(value) >= minCredentialLength &&
+		!strings.HasPrefix(value, "/") && !strings.HasPrefix(value, "$(") {
+		return "a credential by its name"
+	}
+	if len(value) >= s.minLength && randomValue.MatchString(value) && strings.ContainsAny(value, "0123456789") {
+		if entropy := shannonEntropy(value); entropy >= s.minEntropy {
+			return fmt.Sprintf("a random secret with %.1f bits of entropy per character", entropy)
+		}
+	}
+	return ""
+}
+
+// shannonEntropy returns the Shannon entropy of the characters of s in bits per character
+func shannonEntropy(s string) float64 {
+	counts := map[rune]int{}
+	n := 0
+	for _, c := range s {
+		counts[c]++
+		n++
+	}
+	var entropy float64
+	for _, count := range counts {
+		p := float64(count) / float64(n)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
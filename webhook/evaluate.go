@@ -0,0 +1,154 @@
+package webhook
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	log "github.com/gookit/slog"
+
+	v1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+// EvaluatePath is the path of the evaluate API
+const EvaluatePath = "/evaluate"
+
+// EvaluateConfig configures the API evaluating objects with the rules of an endpoint without admission,
+// e.g. for CI pipelines checking manifests before they are applied
+type EvaluateConfig struct {
+	// Enabled serves the evaluate API on /evaluate
+	Enabled bool `json:"enabled"`
+	// Groups are the groups of which a user needs one to call the API, required if enabled
+	Groups []string `json:"groups"`
+}
+
+// validate checks that the evaluate API is restricted to groups
+func (c EvaluateConfig) validate() error {
+	if c.Enabled && len(c.Groups) == 0 {
+		return fmt.Errorf("evaluate: groups are required")
+	}
+	return nil
+}
+
+// Evaluate evaluates the object in the request body, as JSON or YAML, with the rules of the endpoint passed
+// in the query parameter endpoint, defaulting to /validate, and returns the decision as JSON.
+// Decisions aren't recorded. The response has an ETag of the config and the object, a request with a matching
+// If-None-Match header is answered with 304 Not Modified without evaluating the object again.
+func (csh *CosignServerHandler) Evaluate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, status, err := csh.authenticate(r, csh.cfg.Evaluate.Groups)
+	if err != nil {
+		log.Warnf("Evaluate request rejected: %v", err)
+		http.Error(w, err.Error(), status)
+		return
+	}
+	path := r.URL.Query().Get("endpoint")
+	if path == "" {
+		path = DefaultPath
+	}
+	var endpoint *Endpoint
+	for _, e := range csh.endpoints {
+		if e.Path == path {
+			endpoint = e
+		}
+	}
+	if endpoint == nil {
+		http.Error(w, fmt.Sprintf("unknown endpoint %q", path), http.StatusNotFound)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil || len(body) == 0 {
+		http.Error(w, "empty body", http.StatusBadRequest)
+		return
+	}
+	raw, err := yaml.YAMLToJSON(body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid object: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	etag := evaluateETag(csh.configHash, path, raw)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	o, err := evaluationObject(raw, user)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid object: %v", err), http.StatusBadRequest)
+		return
+	}
+	d := endpoint.evaluate(r.Context(), o, nil)
+	if endpoint.forward != nil {
+		endpoint.forward.combine(r.Context(), o.Request, d)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(d); err != nil {
+		log.Errorf("Can't write evaluate response: %v", err)
+	}
+}
+
+// evaluationObject builds the admission object for a create request of the raw JSON object by passed user
+func evaluationObject(raw []byte, user *authenticationv1.UserInfo) (*Object, error) {
+	meta := metav1.PartialObjectMetadata{}
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return nil, err
+	}
+	if meta.Kind == "" || meta.APIVersion == "" {
+		return nil, fmt.Errorf("apiVersion and kind are required")
+	}
+	gv, err := schema.ParseGroupVersion(meta.APIVersion)
+	if err != nil {
+		return nil, err
+	}
+	gvk := metav1.GroupVersionKind{Group: gv.Group, Version: gv.Version, Kind: meta.Kind}
+	review, err := json.Marshal(v1.AdmissionReview{Request: &v1.AdmissionRequest{
+		UID:       types.UID("evaluate"),
+		Kind:      gvk,
+		Namespace: meta.Namespace,
+		Name:      meta.Name,
+		Operation: v1.Create,
+		Object:    runtime.RawExtension{Raw: raw},
+		UserInfo:  *user,
+	}})
+	if err != nil {
+		return nil, err
+	}
+	o, _, err := getObject(review)
+	return o, err
+}
+
+// evaluateETag returns the ETag of the evaluation of the object on the endpoint with the config of passed hash
+func evaluateETag(configHash, endpoint string, object []byte) string {
+	h := sha256.New()
+	h.Write([]byte(configHash))
+	h.Write([]byte{0})
+	h.Write([]byte(endpoint))
+	h.Write([]byte{0})
+	h.Write(object)
+	return `"` + hex.EncodeToString(h.Sum(nil)) + `"`
+}
+
+// hashConfig returns a hash of the effective config
+func hashConfig(cfg *Config) string {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,73 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCosignServerHandler_Evaluate(t *testing.T) {
+	cfg := &Config{
+		Endpoints: []EndpointConfig{{Path: DefaultPath, Rules: []string{SanityRuleName}}},
+		Evaluate:  EvaluateConfig{Enabled: true, Groups: []string{"platform"}},
+	}
+	csh := &CosignServerHandler{cs: authenticatingClientset(), cfg: cfg}
+	if _, err := csh.Endpoints(); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(csh.Evaluate))
+	defer srv.Close()
+
+	pod := `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: app
+  namespace: default
+spec:
+  containers:
+    - name: app
+      image: busybox
+    - name: app
+      image: busybox
+`
+	evaluate := func(etag string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"?endpoint="+DefaultPath, strings.NewReader(pod))
+		req.Header.Set("Authorization", "Bearer valid")
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	resp := evaluate("")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	d := Decision{}
+	if err := json.NewDecoder(resp.Body).Decode(&d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Allowed || d.Rule != SanityRuleName || d.Kind != "Pod" || d.User != "jane" {
+		t.Errorf("unexpected decision %+v", d)
+	}
+
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("ETag missing")
+	}
+	if resp := evaluate(etag); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("got status %d with matching If-None-Match, want %d", resp.StatusCode, http.StatusNotModified)
+	}
+	if resp := evaluate(`"other"`); resp.StatusCode != http.StatusOK {
+		t.Errorf("got status %d with other If-None-Match, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
@@ -0,0 +1,32 @@
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	log "github.com/gookit/slog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var exemptRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "cosign_exempt_requests_total",
+	Help: "The number of admission requests admitted without validation as their namespace is exempt, by namespace",
+}, []string{"namespace"})
+
+// exempt admits the request without evaluating any rule if its namespace is exempt, and returns whether it did.
+// System namespaces like kube-system are kept working even if the namespaceSelector of the webhook misses them.
+func (e *Endpoint) exempt(w http.ResponseWriter, o *Object) bool {
+	ns := o.Request.Namespace
+	if ns == "" || !slices.Contains(e.csh.exemptNamespaces, ns) {
+		return false
+	}
+	log.Debugf("Admitted %s %s/%s on %s, namespace is exempt", o.Request.Kind.Kind, ns, o.Request.Name, e.Path)
+	exemptRequests.WithLabelValues(ns).Inc()
+	d := newDecision(e.Path, o.Request, e.csh.semantics)
+	d.Allowed, d.Message = true, fmt.Sprintf("Namespace %s is exempt from validation", ns)
+	e.csh.recordDecision(d)
+	accept(w, d.Message, o.Request.UID)
+	return true
+}
@@ -0,0 +1,59 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestEndpoint_exempt(t *testing.T) {
+	rule := &countingRule{name: SanityRuleName, err: errors.New("broken pod spec")}
+	csh := &CosignServerHandler{cfg: &Config{}, exemptNamespaces: []string{"kube-system", "cert-manager"}}
+	e := &Endpoint{Path: DefaultPath, rules: []Rule{rule}, csh: csh}
+	var decisions []*Decision
+	csh.OnDecision(func(d *Decision) { decisions = append(decisions, d) })
+
+	admit := func(ns string) *v1.AdmissionResponse {
+		t.Helper()
+		body, err := json.Marshal(v1.AdmissionReview{Request: &v1.AdmissionRequest{
+			UID:       "1",
+			Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+			Namespace: ns,
+			Name:      "app",
+			Operation: v1.Create,
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, DefaultPath, bytes.NewReader(body)))
+		ar := v1.AdmissionReview{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &ar); err != nil {
+			t.Fatal(err)
+		}
+		return ar.Response
+	}
+
+	if resp := admit("kube-system"); !resp.Allowed || resp.Result.Message != "Namespace kube-system is exempt from validation" {
+		t.Errorf("response in exempt namespace = %+v, want allowed as exempt", resp)
+	}
+	if rule.calls != 0 {
+		t.Errorf("rule evaluated %d times in exempt namespace, want 0", rule.calls)
+	}
+	if len(decisions) != 1 || !decisions[0].Allowed || decisions[0].Namespace != "kube-system" {
+		t.Errorf("decisions = %+v, want one allowed decision in kube-system", decisions)
+	}
+
+	if resp := admit("default"); resp.Allowed {
+		t.Errorf("response in default namespace = %+v, want denied by the rule", resp)
+	}
+	if rule.calls != 1 {
+		t.Errorf("rule evaluated %d times in default namespace, want 1", rule.calls)
+	}
+}
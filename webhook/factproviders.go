@@ -0,0 +1,424 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	log "github.com/gookit/slog"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	ociremote "github.com/sigstore/cosign/v2/pkg/oci/remote"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+var factProviderHealthy = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "cosign_fact_provider_healthy",
+	Help: "Whether the data source of a fact provider is healthy (1) or failing (0), by provider",
+}, []string{"provider"})
+
+// FactsConfig configures the fact providers
+type FactsConfig struct {
+	// RegistryCacheTTL is how long resolved image digests are cached, e.g. 30s. Zero resolves them per request.
+	RegistryCacheTTL metav1.Duration `json:"registryCacheTTL"`
+}
+
+// FactProvider computes facts from a data source, e.g. the namespaces or the registries. A provider caches what it
+// fetches and reports the health of its source, so rules only declare the facts they read, see FactDependent.
+type FactProvider interface {
+	// Name returns the name of the provider in metrics and flush results
+	Name() string
+	// Facts returns the facts of the provider with the facts each of them depends on
+	Facts() map[Fact][]Fact
+	// Prepare registers the informers the provider reads, it's called when the endpoints are created
+	Prepare()
+	// Compute computes the fact for the object, the facts it depends on are computed before
+	Compute(ctx context.Context, fact Fact, o *Object, facts *Facts) (any, error)
+	// Health returns the error of the data source, nil while it's healthy
+	Health() error
+}
+
+// FactProviderFactory creates a fact provider from the config
+type FactProviderFactory func(csh *CosignServerHandler, cfg *Config) (FactProvider, error)
+
+// factProviderFactories create the providers of the facts rules can depend on, by provider name
+var factProviderFactories = map[string]FactProviderFactory{
+	"namespaces": newNamespaceFactProvider,
+	"registry":   newRegistryFactProvider,
+	"quota":      newQuotaFactProvider,
+}
+
+// RegisterFactProvider registers the factory of a fact provider by name, so rules can depend on its facts. It has to
+// be called before the endpoints are created, e.g. in an init function, and panics if the name is already taken.
+func RegisterFactProvider(name string, f FactProviderFactory) {
+	if _, ok := factProviderFactories[name]; ok {
+		panic(fmt.Sprintf("fact provider %s is already registered", name))
+	}
+	factProviderFactories[name] = f
+}
+
+// newFactDefs creates the fact providers and returns the definitions of their facts. Two providers may not provide
+// the same fact.
+func newFactDefs(csh *CosignServerHandler, cfg *Config) (map[Fact]factDef, error) {
+	defs := map[Fact]factDef{}
+	providedBy := map[Fact]string{}
+	for _, n := range slices.Sorted(maps.Keys(factProviderFactories)) {
+		fp, err := factProviderFactories[n](csh, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("fact provider %s: %w", n, err)
+		}
+		for f, deps := range fp.Facts() {
+			if other, ok := providedBy[f]; ok {
+				return nil, fmt.Errorf("fact %s provided by %s and %s", f, other, n)
+			}
+			providedBy[f] = n
+			defs[f] = factDef{deps: deps, provider: fp}
+		}
+	}
+	return defs, nil
+}
+
+// observeFactProvider records the health of the provider after computing one of its facts
+func observeFactProvider(fp FactProvider) {
+	healthy := 1.0
+	if err := fp.Health(); err != nil {
+		healthy = 0
+	}
+	factProviderHealthy.WithLabelValues(fp.Name()).Set(healthy)
+}
+
+// flushFactProviders flushes the caches of the providers of the fact plans of the endpoints
+func (csh *CosignServerHandler) flushFactProviders(ctx context.Context, res *FlushResult) {
+	var providers []FactProvider
+	for _, e := range csh.endpoints {
+		if e.facts == nil {
+			continue
+		}
+		for _, fp := range e.facts.providers {
+			if !slices.Contains(providers, fp) {
+				providers = append(providers, fp)
+			}
+		}
+	}
+	for _, fp := range providers {
+		f, ok := fp.(cacheFlusher)
+		if !ok {
+			continue
+		}
+		if err := f.flush(ctx); err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("facts %s: %v", fp.Name(), err))
+			continue
+		}
+		res.Flushed = append(res.Flushed, "facts "+fp.Name())
+	}
+}
+
+// factCache caches the values fetched by a provider for a TTL
+type factCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]factCacheEntry
+}
+
+type factCacheEntry struct {
+	value   any
+	expires time.Time
+}
+
+// newFactCache returns a cache with the TTL, or nil if the TTL is zero
+func newFactCache(ttl time.Duration) *factCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &factCache{ttl: ttl, entries: map[string]factCacheEntry{}}
+}
+
+// get returns the cached value of the key, a nil cache has none
+func (c *factCache) get(key string) (any, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || time.Now().After(e.expires) {
+		return nil, false
+	}
+	return e.value, true
+}
+
+// put caches the value of the key and drops the expired entries
+func (c *factCache) put(key string, value any) {
+	if c == nil {
+		return
+	}
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	maps.DeleteFunc(c.entries, func(_ string, e factCacheEntry) bool { return now.After(e.expires) })
+	c.entries[key] = factCacheEntry{value: value, expires: now.Add(c.ttl)}
+}
+
+// flush drops all cached values
+func (c *factCache) flush(context.Context) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+	return nil
+}
+
+// informerHealth returns an error until the informer synced its cache
+func informerHealth(informer cache.SharedIndexInformer, resource string) error {
+	if informer == nil || !informer.HasSynced() {
+		return fmt.Errorf("informer of %s not synced", resource)
+	}
+	return nil
+}
+
+// namespaceFactProvider provides the metadata of the namespace of the object from the informer cache
+type namespaceFactProvider struct {
+	csh      *CosignServerHandler
+	informer cache.SharedIndexInformer
+}
+
+func newNamespaceFactProvider(csh *CosignServerHandler, _ *Config) (FactProvider, error) {
+	return &namespaceFactProvider{csh: csh}, nil
+}
+
+// Name returns the name of the provider
+func (*namespaceFactProvider) Name() string {
+	return "namespaces"
+}
+
+// Facts returns the labels, annotations and tier of the namespace
+func (*namespaceFactProvider) Facts() map[Fact][]Fact {
+	return map[Fact][]Fact{
+		FactNamespaceLabels:      nil,
+		FactNamespaceAnnotations: nil,
+		FactNamespaceTier:        {FactNamespaceLabels},
+	}
+}
+
+// Prepare registers the namespace informer
+func (p *namespaceFactProvider) Prepare() {
+	p.informer = p.csh.informers.Core().V1().Namespaces().Informer()
+}
+
+// Compute returns the metadata of the namespace. Namespaces created since the last watch event have none yet.
+func (p *namespaceFactProvider) Compute(_ context.Context, fact Fact, o *Object, facts *Facts) (any, error) {
+	if fact == FactNamespaceTier {
+		l, err := facts.Get(FactNamespaceLabels)
+		if err != nil {
+			return nil, err
+		}
+		return l.(map[string]string)[namespaceTierLabel], nil
+	}
+	if err := p.Health(); err != nil {
+		return nil, err
+	}
+	ns := &corev1.Namespace{}
+	if o.Request.Namespace != "" {
+		found, err := p.csh.informers.Core().V1().Namespaces().Lister().Get(o.Request.Namespace)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		if found != nil {
+			ns = found
+		}
+	}
+	if fact == FactNamespaceAnnotations {
+		return mapOrEmpty(ns.Annotations), nil
+	}
+	return mapOrEmpty(ns.Labels), nil
+}
+
+// Health returns an error until the namespace informer synced
+func (p *namespaceFactProvider) Health() error {
+	return informerHealth(p.informer, "namespaces")
+}
+
+// mapOrEmpty returns the map, or an empty map if it's nil
+func mapOrEmpty(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
+
+// registryFactProvider resolves the digests of the images from the registries, with the pull secrets of the pod
+type registryFactProvider struct {
+	csh   *CosignServerHandler
+	cache *factCache
+
+	mu      sync.Mutex
+	lastErr error
+}
+
+func newRegistryFactProvider(csh *CosignServerHandler, cfg *Config) (FactProvider, error) {
+	return &registryFactProvider{csh: csh, cache: newFactCache(cfg.Facts.RegistryCacheTTL.Duration)}, nil
+}
+
+// Name returns the name of the provider
+func (*registryFactProvider) Name() string {
+	return "registry"
+}
+
+// Facts returns the image digests
+func (*registryFactProvider) Facts() map[Fact][]Fact {
+	return map[Fact][]Fact{FactImageDigests: nil}
+}
+
+// Prepare does nothing, the registries are called per request
+func (*registryFactProvider) Prepare() {}
+
+// Compute resolves the digests of the images of all containers of pods and workloads. Images referenced by digest
+// aren't resolved, images which couldn't be resolved are missing.
+func (p *registryFactProvider) Compute(ctx context.Context, _ Fact, o *Object, _ *Facts) (any, error) {
+	digests := map[string]name.Digest{}
+	spec := podSpec(o)
+	if spec == nil {
+		return digests, nil
+	}
+	var kc authn.Keychain
+	for _, c := range podContainers(spec) {
+		if _, ok := digests[c.Image]; ok {
+			continue
+		}
+		ref, err := name.ParseReference(c.Image)
+		if err != nil {
+			// rules checking the image report invalid references
+			continue
+		}
+		if d, ok := ref.(name.Digest); ok {
+			digests[c.Image] = d
+			continue
+		}
+		// tags are cached by pull credentials, as they may not be allowed to pull the same images
+		key := pullCredentials(o.Request.Namespace, spec) + "/" + c.Image
+		if d, ok := p.cache.get(key); ok {
+			digests[c.Image] = d.(name.Digest)
+			continue
+		}
+		if kc == nil {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: o.Request.Namespace, Name: o.Request.Name}, Spec: *spec}
+			if kc, err = newKeychainForPod(ctx, pod); err != nil {
+				return nil, fmt.Errorf("failed initializing k8schain")
+			}
+		}
+		resolved, err := p.csh.dedupe(ctx, lookupDigest, key, func(ctx context.Context) (any, error) {
+			return ociremote.ResolveDigest(ref, ociremote.WithRemoteOptions(remote.WithAuthFromKeychain(kc), remote.WithTransport(p.csh.egress.roundTripper()),
+				remote.WithContext(ctx)))
+		})
+		p.setErr(err)
+		if err != nil {
+			log.Errorf("Error resolving digest of image %q: %v", c.Image, err)
+			continue
+		}
+		digests[c.Image] = resolved.(name.Digest)
+		p.cache.put(key, resolved)
+	}
+	return digests, nil
+}
+
+func (p *registryFactProvider) setErr(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastErr = err
+}
+
+// Health returns the error of the last digest lookup, nil if it succeeded
+func (p *registryFactProvider) Health() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lastErr
+}
+
+// flush drops the cached digests
+func (p *registryFactProvider) flush(ctx context.Context) error {
+	return p.cache.flush(ctx)
+}
+
+// imageDigests returns the resolved digests of the images of the object, nil if they couldn't be resolved
+func imageDigests(o *Object) map[string]name.Digest {
+	v, err := o.Facts.Get(FactImageDigests)
+	if err != nil {
+		log.Debugf("No image digests: %v", err)
+		return nil
+	}
+	return v.(map[string]name.Digest)
+}
+
+// QuotaUsage is the value of FactQuotaUsage for a resource quota of the namespace
+type QuotaUsage struct {
+	// Scoped quotas only apply to the objects matching their scopes
+	Scoped bool
+	Hard   corev1.ResourceList
+	Used   corev1.ResourceList
+}
+
+// quotaFactProvider provides the usage of the resource quotas of the namespace from the informer cache
+type quotaFactProvider struct {
+	csh      *CosignServerHandler
+	informer cache.SharedIndexInformer
+}
+
+func newQuotaFactProvider(csh *CosignServerHandler, _ *Config) (FactProvider, error) {
+	return &quotaFactProvider{csh: csh}, nil
+}
+
+// Name returns the name of the provider
+func (*quotaFactProvider) Name() string {
+	return "quota"
+}
+
+// Facts returns the quota usage
+func (*quotaFactProvider) Facts() map[Fact][]Fact {
+	return map[Fact][]Fact{FactQuotaUsage: nil}
+}
+
+// Prepare registers the resource quota informer with its configured selectors
+func (p *quotaFactProvider) Prepare() {
+	p.csh.selectInformers("resourcequotas")
+	p.informer = p.csh.informers.Core().V1().ResourceQuotas().Informer()
+}
+
+// Compute returns the usage of the resource quotas of the namespace by quota name
+func (p *quotaFactProvider) Compute(_ context.Context, _ Fact, o *Object, _ *Facts) (any, error) {
+	if err := p.Health(); err != nil {
+		return nil, err
+	}
+	quotas, err := p.csh.informers.Core().V1().ResourceQuotas().Lister().ResourceQuotas(o.Request.Namespace).List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("can't list resource quotas: %w", err)
+	}
+	usage := make(map[string]QuotaUsage, len(quotas))
+	for _, q := range quotas {
+		usage[q.Name] = QuotaUsage{
+			Scoped: len(q.Spec.Scopes) > 0 || q.Spec.ScopeSelector != nil,
+			Hard:   q.Status.Hard,
+			Used:   q.Status.Used,
+		}
+	}
+	return usage, nil
+}
+
+// Health returns an error until the resource quota informer synced
+func (p *quotaFactProvider) Health() error {
+	return informerHealth(p.informer, "resourcequotas")
+}
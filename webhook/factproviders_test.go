@@ -0,0 +1,121 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_newFactDefs(t *testing.T) {
+	defs, err := newFactDefs(&CosignServerHandler{}, &Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for f, provider := range map[Fact]string{
+		FactImageDigests:         "registry",
+		FactNamespaceLabels:      "namespaces",
+		FactNamespaceAnnotations: "namespaces",
+		FactNamespaceTier:        "namespaces",
+		FactQuotaUsage:           "quota",
+	} {
+		if def, ok := defs[f]; !ok || def.provider.Name() != provider {
+			t.Errorf("fact %s not provided by %s", f, provider)
+		}
+	}
+
+	RegisterFactProvider("duplicate", func(*CosignServerHandler, *Config) (FactProvider, error) {
+		return &funcProvider{name: "duplicate", facts: map[Fact][]Fact{FactQuotaUsage: nil}}, nil
+	})
+	defer delete(factProviderFactories, "duplicate")
+	if _, err := newFactDefs(&CosignServerHandler{}, &Config{}); err == nil || err.Error() != "fact quotaUsage provided by duplicate and quota" {
+		t.Errorf("newFactDefs() error = %v, want duplicate fact", err)
+	}
+}
+
+func TestRegisterFactProvider(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || r != "fact provider quota is already registered" {
+			t.Errorf("RegisterFactProvider() of a taken name recovered %v, want panic", r)
+		}
+	}()
+	RegisterFactProvider("quota", newQuotaFactProvider)
+}
+
+func Test_factCache(t *testing.T) {
+	if c := newFactCache(0); c != nil {
+		t.Fatal("newFactCache(0) != nil")
+	}
+	var disabled *factCache
+	disabled.put("key", 1)
+	if _, ok := disabled.get("key"); ok {
+		t.Error("disabled cache returned a value")
+	}
+
+	c := newFactCache(time.Minute)
+	c.put("key", 1)
+	if v, ok := c.get("key"); !ok || v != 1 {
+		t.Errorf("get() = %v, %v, want 1", v, ok)
+	}
+	c.entries["expired"] = factCacheEntry{value: 2, expires: time.Now().Add(-time.Second)}
+	if _, ok := c.get("expired"); ok {
+		t.Error("get() returned an expired value")
+	}
+	if err := c.flush(context.Background()); err != nil || len(c.entries) != 0 {
+		t.Errorf("flush() = %v, %d entries left", err, len(c.entries))
+	}
+}
+
+func Test_informerFactProviders(t *testing.T) {
+	cs := fake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Annotations: map[string]string{"owner": "payments"}}},
+		&corev1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: "compute", Namespace: "team-a"},
+			Spec:       corev1.ResourceQuotaSpec{Scopes: []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeBestEffort}},
+			Status: corev1.ResourceQuotaStatus{
+				Hard: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("10")},
+				Used: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("2")},
+			},
+		},
+	)
+	csh := &CosignServerHandler{cs: cs, informers: informers.NewSharedInformerFactory(cs, 0)}
+	defs, err := newFactDefs(csh, &Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := newFactPlan([]Rule{&factRule{facts: []Fact{FactNamespaceAnnotations, FactQuotaUsage}}}, defs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.prepare()
+	o := podObject("team-a", corev1.PodSpec{})
+	if _, err := p.compute(context.Background(), o).Get(FactQuotaUsage); err == nil {
+		t.Error("quota usage computed before the informer synced")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	csh.Start(ctx)
+	for _, fp := range p.providers {
+		if err := fp.Health(); err != nil {
+			t.Errorf("provider %s unhealthy: %v", fp.Name(), err)
+		}
+	}
+	facts := p.compute(ctx, o)
+	if a, err := facts.Get(FactNamespaceAnnotations); err != nil || a.(map[string]string)["owner"] != "payments" {
+		t.Errorf("namespace annotations = %v, %v", a, err)
+	}
+	v, err := facts.Get(FactQuotaUsage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage := v.(map[string]QuotaUsage)["compute"]
+	if used := usage.Used[corev1.ResourcePods]; !usage.Scoped || used.Value() != 2 {
+		t.Errorf("quota usage = %+v", usage)
+	}
+}
@@ -0,0 +1,160 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Fact is a value derived from the object under admission which rules depend on, e.g. the resolved digests of its
+// images. The facts of the rules of an endpoint are computed once per request before the rules are evaluated and
+// passed to the rules in Object.Facts.
+type Fact string
+
+const (
+	// FactImageDigests maps the image references of the containers to their digests, resolved from the registries.
+	// Images which couldn't be resolved are missing.
+	FactImageDigests Fact = "imageDigests"
+	// FactNamespaceLabels are the labels of the namespace of the object
+	FactNamespaceLabels Fact = "namespaceLabels"
+	// FactNamespaceAnnotations are the annotations of the namespace of the object
+	FactNamespaceAnnotations Fact = "namespaceAnnotations"
+	// FactNamespaceTier is the value of the tier label of the namespace of the object, empty without label
+	FactNamespaceTier Fact = "namespaceTier"
+	// FactQuotaUsage are the hard limits and the usage of the resource quotas of the namespace of the object
+	FactQuotaUsage Fact = "quotaUsage"
+
+	// namespaceTierLabel is the label of namespaces holding their tier, e.g. critical
+	namespaceTierLabel = "tier"
+)
+
+// FactDependent is implemented by rules which depend on facts
+type FactDependent interface {
+	// Facts returns the facts the rule reads from Object.Facts
+	Facts() []Fact
+}
+
+// factDef declares the provider computing a fact and the facts it depends on
+type factDef struct {
+	deps     []Fact
+	provider FactProvider
+}
+
+// Facts are the facts computed for a request
+type Facts struct {
+	values map[Fact]any
+	errs   map[Fact]error
+}
+
+// Get returns the value of the fact, or the error computing it as internal error of the rule. Only the facts
+// declared by the rules of the endpoint are computed.
+func (f *Facts) Get(fact Fact) (any, error) {
+	if f == nil {
+		return nil, internalError(fmt.Errorf("fact %s not computed", fact))
+	}
+	if err, ok := f.errs[fact]; ok {
+		return nil, internalError(err)
+	}
+	v, ok := f.values[fact]
+	if !ok {
+		return nil, internalError(fmt.Errorf("fact %s not computed, the rule has to declare it", fact))
+	}
+	return v, nil
+}
+
+// factPlan computes the facts the rules of an endpoint depend on in topological order, dependencies first
+type factPlan struct {
+	order []Fact
+	defs  map[Fact]factDef
+	// providers are the providers of the facts, in the order of their first fact
+	providers []FactProvider
+}
+
+// newFactPlan returns the plan of the facts of the rules with their dependencies, or nil if the rules depend on no
+// fact. It fails for unknown facts and dependency cycles.
+func newFactPlan(rules []Rule, defs map[Fact]factDef) (*factPlan, error) {
+	const (
+		visiting = iota + 1
+		visited
+	)
+	p := &factPlan{defs: defs}
+	state := map[Fact]int{}
+	var visit func(f Fact, path []Fact) error
+	visit = func(f Fact, path []Fact) error {
+		path = append(path, f)
+		switch state[f] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("fact dependency cycle %s", joinFacts(path))
+		}
+		def, ok := defs[f]
+		if !ok {
+			return fmt.Errorf("unknown fact %q", f)
+		}
+		state[f] = visiting
+		for _, d := range def.deps {
+			if err := visit(d, path); err != nil {
+				return err
+			}
+		}
+		state[f] = visited
+		p.order = append(p.order, f)
+		if !slices.Contains(p.providers, def.provider) {
+			p.providers = append(p.providers, def.provider)
+		}
+		return nil
+	}
+	for _, r := range rules {
+		d, ok := unwrapRule(r).(FactDependent)
+		if !ok {
+			continue
+		}
+		for _, f := range d.Facts() {
+			if err := visit(f, nil); err != nil {
+				return nil, fmt.Errorf("rule %s: %w", r.Name(), err)
+			}
+		}
+	}
+	if len(p.order) == 0 {
+		return nil, nil
+	}
+	return p, nil
+}
+
+// joinFacts joins the facts of a dependency path
+func joinFacts(facts []Fact) string {
+	s := make([]string, 0, len(facts))
+	for _, f := range facts {
+		s = append(s, string(f))
+	}
+	return strings.Join(s, " -> ")
+}
+
+// prepare registers the informers of the providers
+func (p *factPlan) prepare() {
+	for _, fp := range p.providers {
+		fp.Prepare()
+	}
+}
+
+// compute computes the facts in order. A fact depending on a failed fact fails too, rules reading it get the error.
+func (p *factPlan) compute(ctx context.Context, o *Object) *Facts {
+	facts := &Facts{values: map[Fact]any{}, errs: map[Fact]error{}}
+	for _, f := range p.order {
+		def := p.defs[f]
+		if i := slices.IndexFunc(def.deps, func(d Fact) bool { return facts.errs[d] != nil }); i >= 0 {
+			facts.errs[f] = fmt.Errorf("fact %s depends on %w", f, facts.errs[def.deps[i]])
+			continue
+		}
+		v, err := def.provider.Compute(ctx, f, o, facts)
+		observeFactProvider(def.provider)
+		if err != nil {
+			facts.errs[f] = fmt.Errorf("fact %s: %w", f, err)
+			continue
+		}
+		facts.values[f] = v
+	}
+	return facts
+}
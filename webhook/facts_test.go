@@ -0,0 +1,181 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// factRule is a rule depending on facts
+type factRule struct {
+	facts []Fact
+}
+
+func (*factRule) Name() string { return "facts" }
+
+func (*factRule) Validate(context.Context, *Object) ([]string, error) { return nil, nil }
+
+func (r *factRule) Facts() []Fact { return r.facts }
+
+// funcProvider is a fact provider computing its facts with a func
+type funcProvider struct {
+	name    string
+	facts   map[Fact][]Fact
+	compute func(fact Fact, facts *Facts) (any, error)
+}
+
+func (p *funcProvider) Name() string { return p.name }
+
+func (p *funcProvider) Facts() map[Fact][]Fact { return p.facts }
+
+func (*funcProvider) Prepare() {}
+
+func (p *funcProvider) Compute(_ context.Context, fact Fact, _ *Object, facts *Facts) (any, error) {
+	return p.compute(fact, facts)
+}
+
+func (*funcProvider) Health() error { return nil }
+
+// defsOf returns the definitions of the facts of the provider
+func defsOf(p FactProvider) map[Fact]factDef {
+	defs := map[Fact]factDef{}
+	for f, deps := range p.Facts() {
+		defs[f] = factDef{deps: deps, provider: p}
+	}
+	return defs
+}
+
+func Test_newFactPlan(t *testing.T) {
+	defs := defsOf(&funcProvider{
+		name: "test",
+		facts: map[Fact][]Fact{
+			"a": {"b", "c"},
+			"b": {"c"},
+			"c": nil,
+			"d": nil,
+			"x": {"y"},
+			"y": {"x"},
+		},
+		compute: func(fact Fact, _ *Facts) (any, error) { return string(fact), nil },
+	})
+	tests := []struct {
+		name    string
+		rules   []Rule
+		want    []Fact
+		wantErr string
+	}{
+		{
+			name:  "no facts",
+			rules: []Rule{&cosignRule{}},
+		},
+		{
+			name:  "dependencies first",
+			rules: []Rule{&factRule{facts: []Fact{"a"}}, &factRule{facts: []Fact{"d", "b"}}},
+			want:  []Fact{"c", "b", "a", "d"},
+		},
+		{
+			name:  "rule guarded by the watchdog",
+			rules: withWatchdog(WatchdogConfig{Limit: metav1.Duration{Duration: 1}}, []Rule{&factRule{facts: []Fact{"b"}}}),
+			want:  []Fact{"c", "b"},
+		},
+		{
+			name:    "unknown fact",
+			rules:   []Rule{&factRule{facts: []Fact{"z"}}},
+			wantErr: `rule facts: unknown fact "z"`,
+		},
+		{
+			name:    "cycle",
+			rules:   []Rule{&factRule{facts: []Fact{"x"}}},
+			wantErr: "rule facts: fact dependency cycle x -> y -> x",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := newFactPlan(tt.rules, defs)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("newFactPlan() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == nil {
+				if p != nil {
+					t.Errorf("newFactPlan() = %v, want nil", p.order)
+				}
+				return
+			}
+			if !slices.Equal(p.order, tt.want) {
+				t.Errorf("newFactPlan() = %v, want %v", p.order, tt.want)
+			}
+		})
+	}
+}
+
+func Test_factPlan_compute(t *testing.T) {
+	failed := errors.New("registry unavailable")
+	calls := 0
+	defs := defsOf(&funcProvider{
+		name:  "test",
+		facts: map[Fact][]Fact{"digests": nil, "pinned": {"digests"}},
+		compute: func(fact Fact, _ *Facts) (any, error) {
+			if fact == "digests" {
+				return nil, failed
+			}
+			calls++
+			return true, nil
+		},
+	})
+	p, err := newFactPlan([]Rule{&factRule{facts: []Fact{"pinned"}}}, defs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	facts := p.compute(context.Background(), &Object{Request: &v1.AdmissionRequest{}})
+	if _, err := facts.Get("pinned"); !errors.Is(err, failed) || !strings.Contains(err.Error(), "fact pinned depends on fact digests") {
+		t.Errorf("Get(pinned) error = %v, want failed dependency", err)
+	}
+	if calls != 0 {
+		t.Errorf("fact depending on failed fact computed %d times", calls)
+	}
+	if _, err := facts.Get("other"); err == nil {
+		t.Error("Get() of undeclared fact, want error")
+	}
+	if _, err := (*Facts)(nil).Get("pinned"); err == nil {
+		t.Error("Get() without facts, want error")
+	}
+}
+
+func Test_namespaceTierFact(t *testing.T) {
+	cs := fake.NewSimpleClientset(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "payments", Labels: map[string]string{"tier": "critical"}}})
+	csh := &CosignServerHandler{cs: cs, informers: informers.NewSharedInformerFactory(cs, 0)}
+	defs, err := newFactDefs(csh, &Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := newFactPlan([]Rule{&factRule{facts: []Fact{FactNamespaceTier}}}, defs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.prepare()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	csh.Start(ctx)
+
+	for ns, want := range map[string]string{"payments": "critical", "unknown": ""} {
+		facts := p.compute(ctx, podObject(ns, corev1.PodSpec{}))
+		if tier, err := facts.Get(FactNamespaceTier); err != nil || tier != want {
+			t.Errorf("tier of namespace %s = %v, %v, want %q", ns, tier, err, want)
+		}
+	}
+}
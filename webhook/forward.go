@@ -0,0 +1,245 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	log "github.com/gookit/slog"
+
+	v1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// policies combining the local verdict with the verdict of the downstream validator
+const (
+	ForwardPolicyAnd = "and"
+	ForwardPolicyOr  = "or"
+)
+
+const (
+	// ForwardRuleName is reported as rule of decisions denied by the downstream validator
+	ForwardRuleName       = "forward"
+	defaultForwardTimeout = 5 * time.Second
+	redacted              = "REDACTED"
+)
+
+// ForwardConfig configures the forwarding of admission requests to a downstream validating webhook,
+// e.g. while migrating from another webhook
+type ForwardConfig struct {
+	// URL of the downstream validator, must be https
+	URL string `json:"url"`
+	// CAFile contains the CA certificates to verify the downstream validator, defaults to the system CAs
+	CAFile string `json:"caFile"`
+	// Timeout of the downstream request, defaults to 5s
+	Timeout metav1.Duration `json:"timeout"`
+	// Retry configures the attempts of the downstream request, defaults to 2 attempts, with the second one only
+	// started within the timeout, e.g. after a refused connection
+	Retry RetryPolicy `json:"retry"`
+	// Policy is and (default), allowing requests only if both allow, or or, allowing requests if one allows
+	Policy string `json:"policy"`
+	// FailOpen treats errors of the downstream validator as allowed instead of denied
+	FailOpen bool `json:"failOpen"`
+	// RedactAnnotations are annotations whose values are replaced before forwarding.
+	// Secret data and literal env values are always redacted.
+	RedactAnnotations []string `json:"redactAnnotations"`
+}
+
+// validate checks URL and policy of the forward config and sets the defaults
+func (c *ForwardConfig) validate() error {
+	u, err := url.Parse(c.URL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("forward url must be https, got %q", c.URL)
+	}
+	if c.Policy == "" {
+		c.Policy = ForwardPolicyAnd
+	}
+	if c.Policy != ForwardPolicyAnd && c.Policy != ForwardPolicyOr {
+		return fmt.Errorf("unknown forward policy %q, must be %s or %s", c.Policy, ForwardPolicyAnd, ForwardPolicyOr)
+	}
+	if c.Timeout.Duration <= 0 {
+		c.Timeout.Duration = defaultForwardTimeout
+	}
+	return c.Retry.validate(RetryPolicy{Attempts: 2, Timeout: c.Timeout, MaxElapsed: c.Timeout})
+}
+
+// forwarder sends redacted admission requests to the downstream validator and combines the verdicts
+type forwarder struct {
+	cfg    ForwardConfig
+	client *http.Client
+}
+
+func newForwarder(cfg ForwardConfig, e *egress) (*forwarder, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't read forward CA file: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in forward CA file %q", cfg.CAFile)
+		}
+	}
+	return &forwarder{
+		cfg: cfg,
+		client: &http.Client{
+			Timeout:   cfg.Retry.Timeout.Duration,
+			Transport: e.transport(tlsConfig),
+		},
+	}, nil
+}
+
+// combine asks the downstream validator if its verdict can change the local decision and updates the decision.
+// With policy and, only locally allowed requests are forwarded, with policy or only locally denied ones.
+func (f *forwarder) combine(ctx context.Context, req *v1.AdmissionRequest, d *Decision) {
+	if d.Allowed == (f.cfg.Policy == ForwardPolicyOr) {
+		return
+	}
+	resp, err := f.review(ctx, req)
+	if err != nil {
+		log.Errorf("Downstream validator %s failed for %s %s/%s: %v", f.cfg.URL, req.Kind.Kind, req.Namespace, req.Name, err)
+		if f.cfg.FailOpen {
+			return
+		}
+		resp = &v1.AdmissionResponse{Result: &metav1.Status{Message: fmt.Sprintf("downstream validator failed: %v", err)}}
+	}
+	d.Warnings = append(d.Warnings, resp.Warnings...)
+
+	msg := "denied by downstream validator"
+	if resp.Result != nil && resp.Result.Message != "" {
+		msg = resp.Result.Message
+	}
+	switch {
+	case resp.Allowed && !d.Allowed:
+		d.Allowed, d.Rule, d.Message = true, "", "Validation passed by downstream validator"
+	case !resp.Allowed && d.Allowed:
+		d.Allowed, d.Rule, d.Message = false, ForwardRuleName, msg
+	case !resp.Allowed:
+		d.Message += "; downstream: " + msg
+	}
+}
+
+// review sends the redacted request to the downstream validator with the retry policy and returns its response
+func (f *forwarder) review(ctx context.Context, req *v1.AdmissionRequest) (*v1.AdmissionResponse, error) {
+	r, err := redactRequest(req, f.cfg.RedactAnnotations)
+	if err != nil {
+		return nil, fmt.Errorf("can't redact request: %w", err)
+	}
+	body, err := json.Marshal(v1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{Kind: admissionKind, APIVersion: admissionApi},
+		Request:  r,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var resp *v1.AdmissionResponse
+	err = retry(ctx, &f.cfg.Retry, integrationForward, destinationOf(f.cfg.URL), func(ctx context.Context) error {
+		var perr error
+		resp, perr = f.post(ctx, body)
+		return perr
+	})
+	return resp, err
+}
+
+// post sends the admission review to the downstream validator once
+func (f *forwarder) post(ctx context.Context, body []byte) (*v1.AdmissionResponse, error) {
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, f.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, permanent(err)
+	}
+	hreq.Header.Set("Content-Type", "application/json")
+	hresp, err := f.client.Do(hreq)
+	if err != nil {
+		return nil, err
+	}
+	defer hresp.Body.Close()
+	if hresp.StatusCode != http.StatusOK {
+		return nil, unexpectedStatus(hresp, "unexpected status %s")
+	}
+	data, err := io.ReadAll(hresp.Body)
+	if err != nil {
+		return nil, err
+	}
+	ar := v1.AdmissionReview{}
+	if err := json.Unmarshal(data, &ar); err != nil {
+		return nil, permanent(fmt.Errorf("invalid response: %w", err))
+	}
+	if ar.Response == nil {
+		return nil, permanent(fmt.Errorf("response missing"))
+	}
+	return ar.Response, nil
+}
+
+// redactRequest returns a copy of the request with secret data, literal env values and passed annotations
+// of object and old object replaced
+func redactRequest(req *v1.AdmissionRequest, annotations []string) (*v1.AdmissionRequest, error) {
+	r := req.DeepCopy()
+	for _, raw := range []*[]byte{&r.Object.Raw, &r.OldObject.Raw} {
+		if len(*raw) == 0 {
+			continue
+		}
+		var obj map[string]any
+		if err := json.Unmarshal(*raw, &obj); err != nil {
+			return nil, err
+		}
+		if r.Kind.Kind == "Secret" {
+			for _, k := range []string{"data", "stringData"} {
+				if m, ok := obj[k].(map[string]any); ok {
+					for key := range m {
+						m[key] = redacted
+					}
+				}
+			}
+		}
+		if meta, ok := obj["metadata"].(map[string]any); ok {
+			if a, ok := meta["annotations"].(map[string]any); ok {
+				for _, key := range annotations {
+					if _, ok := a[key]; ok {
+						a[key] = redacted
+					}
+				}
+			}
+		}
+		redactEnv(obj)
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		*raw = data
+		r.Object.Object, r.OldObject.Object = nil, nil
+	}
+	return r, nil
+}
+
+// redactEnv replaces the literal values of all env vars in the JSON value, wherever containers are nested
+func redactEnv(v any) {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, child := range t {
+			if env, ok := child.([]any); ok && k == "env" {
+				for _, e := range env {
+					if m, ok := e.(map[string]any); ok {
+						if _, ok := m["value"]; ok {
+							m["value"] = redacted
+						}
+					}
+				}
+				continue
+			}
+			redactEnv(child)
+		}
+	case []any:
+		for _, child := range t {
+			redactEnv(child)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// downstreamValidator starts a validator allowing requests for objects named allowed and returns its forward config
+func downstreamValidator(t *testing.T, policy string) ForwardConfig {
+	t.Helper()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ar := v1.AdmissionReview{}
+		if err := json.NewDecoder(r.Body).Decode(&ar); err != nil {
+			t.Error(err)
+		}
+		if strings.Contains(string(ar.Request.Object.Raw), "s3cr3t") {
+			t.Error("forwarded request is not redacted")
+		}
+		allowed := ar.Request.Name == "allowed"
+		_ = json.NewEncoder(w).Encode(admissionReview(http.StatusOK, allowed, "", "downstream says no", ar.Request.UID))
+	}))
+	t.Cleanup(srv.Close)
+
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, ca, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := ForwardConfig{URL: srv.URL, CAFile: caFile, Policy: policy}
+	if err := cfg.validate(); err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func Test_forwarder_combine(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       string
+		object       string
+		localAllowed bool
+		wantAllowed  bool
+		wantRule     string
+	}{
+		{name: "and both allow", policy: ForwardPolicyAnd, object: "allowed", localAllowed: true, wantAllowed: true},
+		{name: "and downstream denies", policy: ForwardPolicyAnd, object: "denied", localAllowed: true, wantRule: ForwardRuleName},
+		{name: "and local denies", policy: ForwardPolicyAnd, object: "allowed", wantRule: CosignRuleName},
+		{name: "or downstream allows", policy: ForwardPolicyOr, object: "allowed", wantAllowed: true},
+		{name: "or both deny", policy: ForwardPolicyOr, object: "denied", wantRule: CosignRuleName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newForwarder(downstreamValidator(t, tt.policy), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := &v1.AdmissionRequest{
+				UID:    "123",
+				Kind:   metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+				Name:   tt.object,
+				Object: runtime.RawExtension{Raw: []byte(`{"spec":{"containers":[{"name":"app","env":[{"name":"PASSWORD","value":"s3cr3t"}]}]}}`)},
+			}
+			d := &Decision{Allowed: tt.localAllowed}
+			if !tt.localAllowed {
+				d.Rule, d.Message = CosignRuleName, "no signature"
+			}
+			f.combine(context.Background(), req, d)
+			if d.Allowed != tt.wantAllowed || d.Rule != tt.wantRule {
+				t.Errorf("combine() = allowed %v, rule %q, want %v, %q", d.Allowed, d.Rule, tt.wantAllowed, tt.wantRule)
+			}
+		})
+	}
+}
+
+func Test_redactRequest(t *testing.T) {
+	req := &v1.AdmissionRequest{
+		Kind: metav1.GroupVersionKind{Version: "v1", Kind: "Secret"},
+		Object: runtime.RawExtension{Raw: []byte(`{"metadata":{"annotations":{"token":"abc","team":"a"}},` +
+			`"data":{"password":"czNjcjN0"},"stringData":{"user":"admin"}}`)},
+	}
+	r, err := redactRequest(req, []string{"token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(r.Object.Raw)
+	for _, secret := range []string{"abc", "czNjcjN0", "admin"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("redacted object %s contains %q", got, secret)
+		}
+	}
+	if !strings.Contains(got, `"team":"a"`) {
+		t.Errorf("redacted object %s lost annotation team", got)
+	}
+	if !strings.Contains(string(req.Object.Raw), "admin") {
+		t.Error("original request was modified")
+	}
+}
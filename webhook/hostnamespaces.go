@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"context"
+	"slices"
+
+	v1 "k8s.io/api/admission/v1"
+)
+
+// HostNamespacesRuleName is the name of the rule denying pods sharing the namespaces of the node
+const HostNamespacesRuleName = "hostNamespaces"
+
+// HostNamespacesConfig configures which namespaces of the node pods may share, by default none
+type HostNamespacesConfig struct {
+	// AllowHostNetwork allows hostNetwork, sharing the network of the node
+	AllowHostNetwork bool `json:"allowHostNetwork"`
+	// AllowHostPID allows hostPID, seeing and signalling the processes of the node
+	AllowHostPID bool `json:"allowHostPID"`
+	// AllowHostIPC allows hostIPC, sharing the shared memory of the node
+	AllowHostIPC bool `json:"allowHostIPC"`
+	// ExemptNamespaces may share all namespaces of the node, e.g. kube-system
+	ExemptNamespaces []string `json:"exemptNamespaces"`
+	// Action is either deny (default) or warn
+	Action string `json:"action"`
+}
+
+// hostNamespacesRule denies pods requesting the network, PID or IPC namespace of the node
+type hostNamespacesRule struct {
+	cfg HostNamespacesConfig
+}
+
+func newHostNamespacesRule(_ *CosignServerHandler, cfg *Config) (Rule, error) {
+	c := cfg.HostNamespaces
+	if err := validateAction(&c.Action, ActionDeny); err != nil {
+		return nil, err
+	}
+	return &hostNamespacesRule{cfg: c}, nil
+}
+
+// Name returns the name of the rule
+func (*hostNamespacesRule) Name() string {
+	return HostNamespacesRuleName
+}
+
+// Validate checks the host namespaces of pods and workloads
+func (r *hostNamespacesRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	spec := podSpec(o)
+	if spec == nil || o.Request.Operation == v1.Delete {
+		return nil, nil
+	}
+	if slices.Contains(r.cfg.ExemptNamespaces, o.Request.Namespace) {
+		tracef(ctx, "namespace %q is exempt", o.Request.Namespace)
+		return nil, nil
+	}
+
+	var violations []string
+	if spec.HostNetwork && !r.cfg.AllowHostNetwork {
+		violations = append(violations, "hostNetwork isn't allowed")
+	}
+	if spec.HostPID && !r.cfg.AllowHostPID {
+		violations = append(violations, "hostPID isn't allowed")
+	}
+	if spec.HostIPC && !r.cfg.AllowHostIPC {
+		violations = append(violations, "hostIPC isn't allowed")
+	}
+	return enforce(r.cfg.Action, violations)
+}
@@ -0,0 +1,84 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_hostNamespacesRule_Validate(t *testing.T) {
+	tests := []struct {
+		name         string
+		namespace    string
+		spec         corev1.PodSpec
+		cfg          HostNamespacesConfig
+		wantWarnings int
+		wantErr      string
+	}{
+		{
+			name: "no host namespaces",
+		},
+		{
+			name:    "hostNetwork",
+			spec:    corev1.PodSpec{HostNetwork: true},
+			wantErr: "hostNetwork isn't allowed",
+		},
+		{
+			name:    "all host namespaces",
+			spec:    corev1.PodSpec{HostNetwork: true, HostPID: true, HostIPC: true},
+			wantErr: "hostNetwork isn't allowed; hostPID isn't allowed; hostIPC isn't allowed",
+		},
+		{
+			name:    "hostNetwork allowed",
+			spec:    corev1.PodSpec{HostNetwork: true, HostPID: true},
+			cfg:     HostNamespacesConfig{AllowHostNetwork: true},
+			wantErr: "hostPID isn't allowed",
+		},
+		{
+			name: "all allowed",
+			spec: corev1.PodSpec{HostNetwork: true, HostPID: true, HostIPC: true},
+			cfg:  HostNamespacesConfig{AllowHostNetwork: true, AllowHostPID: true, AllowHostIPC: true},
+		},
+		{
+			name:      "exempt namespace",
+			namespace: "kube-system",
+			spec:      corev1.PodSpec{HostNetwork: true, HostPID: true},
+			cfg:       HostNamespacesConfig{ExemptNamespaces: []string{"kube-system"}},
+		},
+		{
+			name:         "warn",
+			spec:         corev1.PodSpec{HostIPC: true},
+			cfg:          HostNamespacesConfig{Action: ActionWarn},
+			wantWarnings: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newHostNamespacesRule(&CosignServerHandler{}, &Config{HostNamespaces: tt.cfg})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ns := tt.namespace
+			if ns == "" {
+				ns = "default"
+			}
+			tt.spec.Containers = []corev1.Container{{Name: "app"}}
+			warnings, err := r.Validate(context.Background(), podObject(ns, tt.spec))
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("Validate() warnings = %v, want %d", warnings, tt.wantWarnings)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	v1 "k8s.io/api/admission/v1"
+)
+
+// HostPathRuleName is the name of the rule denying hostPath volumes outside of the allowed paths
+const HostPathRuleName = "hostPath"
+
+// HostPathConfig configures the host paths pods may mount
+type HostPathConfig struct {
+	// AllowedPaths are the host paths which may be mounted with everything below them, e.g. /var/log allows
+	// /var/log/pods but not /var/logs. Empty denies all hostPath volumes.
+	AllowedPaths []string `json:"allowedPaths"`
+	// Action is either deny (default) or warn
+	Action string `json:"action"`
+}
+
+// hostPathRule denies hostPath volumes outside of the allowed paths, as they give access to the files of the node
+type hostPathRule struct {
+	allowed []string
+	action  string
+}
+
+func newHostPathRule(_ *CosignServerHandler, cfg *Config) (Rule, error) {
+	c := cfg.HostPath
+	if err := validateAction(&c.Action, ActionDeny); err != nil {
+		return nil, err
+	}
+	allowed := make([]string, 0, len(c.AllowedPaths))
+	for _, p := range c.AllowedPaths {
+		if !path.IsAbs(p) {
+			return nil, fmt.Errorf("hostPath: allowed path %q must be absolute", p)
+		}
+		allowed = append(allowed, path.Clean(p))
+	}
+	return &hostPathRule{allowed: allowed, action: c.Action}, nil
+}
+
+// Name returns the name of the rule
+func (*hostPathRule) Name() string {
+	return HostPathRuleName
+}
+
+// Validate checks the hostPath volumes of pods and workloads
+func (r *hostPathRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	spec := podSpec(o)
+	if spec == nil || o.Request.Operation == v1.Delete {
+		return nil, nil
+	}
+
+	var violations []string
+	for _, v := range spec.Volumes {
+		if v.HostPath == nil {
+			continue
+		}
+		if r.allows(v.HostPath.Path) {
+			tracef(ctx, "host path %q of volume %q is allowed", v.HostPath.Path, v.Name)
+			continue
+		}
+		violations = append(violations, fmt.Sprintf("volume %q mounts host path %q which isn't allowed", v.Name, v.HostPath.Path))
+	}
+	return enforce(r.action, violations)
+}
+
+// allows returns whether the host path is one of the allowed paths or below one of them
+func (r *hostPathRule) allows(p string) bool {
+	p = path.Clean(p)
+	return slices.ContainsFunc(r.allowed, func(a string) bool {
+		return p == a || strings.HasPrefix(p, strings.TrimSuffix(a, "/")+"/")
+	})
+}
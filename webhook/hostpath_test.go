@@ -0,0 +1,88 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_hostPathRule_Validate(t *testing.T) {
+	hostPath := func(p string) corev1.Volume {
+		return corev1.Volume{Name: "host", VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: p}}}
+	}
+	tests := []struct {
+		name         string
+		volumes      []corev1.Volume
+		action       string
+		wantWarnings int
+		wantErr      string
+	}{
+		{
+			name:    "no hostPath",
+			volumes: []corev1.Volume{{Name: "data", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}},
+		},
+		{
+			name:    "allowed path",
+			volumes: []corev1.Volume{hostPath("/var/log")},
+		},
+		{
+			name:    "below allowed path",
+			volumes: []corev1.Volume{hostPath("/var/log/pods/")},
+		},
+		{
+			name:    "sibling of allowed path",
+			volumes: []corev1.Volume{hostPath("/var/logs")},
+			wantErr: `volume "host" mounts host path "/var/logs" which isn't allowed`,
+		},
+		{
+			name:    "escaping allowed path",
+			volumes: []corev1.Volume{hostPath("/var/log/../../etc")},
+			wantErr: `host path "/var/log/../../etc" which isn't allowed`,
+		},
+		{
+			name:    "root",
+			volumes: []corev1.Volume{hostPath("/")},
+			wantErr: `host path "/" which isn't allowed`,
+		},
+		{
+			name:         "warn",
+			volumes:      []corev1.Volume{hostPath("/etc")},
+			action:       ActionWarn,
+			wantWarnings: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newHostPathRule(&CosignServerHandler{}, &Config{HostPath: HostPathConfig{
+				AllowedPaths: []string{"/var/log/", "/run/containerd/containerd.sock"},
+				Action:       tt.action,
+			}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			o := podObject("default", corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}, Volumes: tt.volumes})
+			warnings, err := r.Validate(context.Background(), o)
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("Validate() warnings = %v, want %d", warnings, tt.wantWarnings)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_newHostPathRule(t *testing.T) {
+	if _, err := newHostPathRule(&CosignServerHandler{}, &Config{HostPath: HostPathConfig{AllowedPaths: []string{"var/log"}}}); err == nil {
+		t.Error("newHostPathRule() with relative path, want error")
+	}
+}
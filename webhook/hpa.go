@@ -0,0 +1,155 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	v1 "k8s.io/api/admission/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+)
+
+// HPARuleName is the name of the rule validating horizontal pod autoscalers
+const HPARuleName = "hpa"
+
+// HPAConfig configures the validation of horizontal pod autoscalers, with replica bounds per namespace
+type HPAConfig struct {
+	HPABounds `json:",inline"`
+	// Namespaces replace the default bounds per namespace
+	Namespaces map[string]HPABounds `json:"namespaces"`
+	// RequiredMetricTypes are the metric types of which each HPA needs at least one, e.g. Resource. Empty allows all.
+	RequiredMetricTypes []autoscalingv2.MetricSourceType `json:"requiredMetricTypes"`
+}
+
+// HPABounds restrict the replica range of horizontal pod autoscalers
+type HPABounds struct {
+	// MinReplicas is the lowest allowed minReplicas, 0 for no limit
+	MinReplicas int32 `json:"minReplicas"`
+	// MaxReplicas is the highest allowed maxReplicas, 0 for no limit
+	MaxReplicas int32 `json:"maxReplicas"`
+}
+
+// hpaRule validates the replica bounds and metrics of horizontal pod autoscalers and
+// checks that the target workload requests the resources the HPA scales on
+type hpaRule struct {
+	cfg          HPAConfig
+	deployments  appslisters.DeploymentLister
+	statefulSets appslisters.StatefulSetLister
+}
+
+func newHPARule(csh *CosignServerHandler, cfg *Config) (Rule, error) {
+	for _, t := range cfg.HPA.RequiredMetricTypes {
+		switch t {
+		case autoscalingv2.ObjectMetricSourceType, autoscalingv2.PodsMetricSourceType, autoscalingv2.ResourceMetricSourceType,
+			autoscalingv2.ContainerResourceMetricSourceType, autoscalingv2.ExternalMetricSourceType:
+		default:
+			return nil, fmt.Errorf("unknown metric type %q", t)
+		}
+	}
+	csh.selectInformers("deployments", "statefulsets")
+	return &hpaRule{
+		cfg:          cfg.HPA,
+		deployments:  csh.informers.Apps().V1().Deployments().Lister(),
+		statefulSets: csh.informers.Apps().V1().StatefulSets().Lister(),
+	}, nil
+}
+
+// Name returns the name of the rule
+func (*hpaRule) Name() string {
+	return HPARuleName
+}
+
+// Validate checks the replica bounds, the metric types and the resource requests of the target workload
+func (r *hpaRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	hpa := o.HPA
+	if hpa == nil || o.Request.Operation == v1.Delete {
+		return nil, nil
+	}
+	bounds := r.cfg.HPABounds
+	if b, ok := r.cfg.Namespaces[o.Request.Namespace]; ok {
+		tracef(ctx, "bounds of namespace %q", o.Request.Namespace)
+		bounds = b
+	}
+
+	var violations []string
+	minReplicas := int32(1)
+	if hpa.Spec.MinReplicas != nil {
+		minReplicas = *hpa.Spec.MinReplicas
+	}
+	if bounds.MinReplicas > 0 && minReplicas < bounds.MinReplicas {
+		violations = append(violations, fmt.Sprintf("minReplicas %d is lower than %d", minReplicas, bounds.MinReplicas))
+	}
+	if bounds.MaxReplicas > 0 && hpa.Spec.MaxReplicas > bounds.MaxReplicas {
+		violations = append(violations, fmt.Sprintf("maxReplicas %d is higher than %d", hpa.Spec.MaxReplicas, bounds.MaxReplicas))
+	}
+
+	if len(r.cfg.RequiredMetricTypes) > 0 && !slices.ContainsFunc(hpa.Spec.Metrics, func(m autoscalingv2.MetricSpec) bool {
+		return slices.Contains(r.cfg.RequiredMetricTypes, m.Type)
+	}) {
+		violations = append(violations, fmt.Sprintf("metrics must contain one of the types: %v", r.cfg.RequiredMetricTypes))
+	}
+
+	missing, err := r.missingRequests(o.Request.Namespace, hpa)
+	if err != nil {
+		return nil, err
+	}
+	return nil, violationsError(append(violations, missing...))
+}
+
+// missingRequests returns the containers of the target workload which don't request a resource the HPA
+// scales on by utilization. Without requests, the utilization can't be computed and the HPA doesn't scale.
+// Unknown targets and kinds other than Deployment and StatefulSet are skipped.
+func (r *hpaRule) missingRequests(ns string, hpa *autoscalingv2.HorizontalPodAutoscaler) ([]string, error) {
+	ref := hpa.Spec.ScaleTargetRef
+	var spec *corev1.PodSpec
+	var err error
+	switch ref.Kind {
+	case "Deployment":
+		d, lerr := r.deployments.Deployments(ns).Get(ref.Name)
+		if lerr == nil {
+			spec = &d.Spec.Template.Spec
+		}
+		err = lerr
+	case "StatefulSet":
+		s, lerr := r.statefulSets.StatefulSets(ns).Get(ref.Name)
+		if lerr == nil {
+			spec = &s.Spec.Template.Spec
+		}
+		err = lerr
+	default:
+		return nil, nil
+	}
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't get %s %q: %w", ref.Kind, ref.Name, err)
+	}
+
+	var violations []string
+	for _, m := range hpa.Spec.Metrics {
+		switch {
+		case m.Resource != nil && m.Resource.Target.Type == autoscalingv2.UtilizationMetricType:
+			for i := range spec.Containers {
+				if _, ok := containerRequests(&spec.Containers[i])[m.Resource.Name]; !ok {
+					violations = append(violations, fmt.Sprintf("container %q of %s %q doesn't request %s, which the HPA scales on",
+						spec.Containers[i].Name, ref.Kind, ref.Name, m.Resource.Name))
+				}
+			}
+		case m.ContainerResource != nil && m.ContainerResource.Target.Type == autoscalingv2.UtilizationMetricType:
+			for i := range spec.Containers {
+				if spec.Containers[i].Name != m.ContainerResource.Container {
+					continue
+				}
+				if _, ok := containerRequests(&spec.Containers[i])[m.ContainerResource.Name]; !ok {
+					violations = append(violations, fmt.Sprintf("container %q of %s %q doesn't request %s, which the HPA scales on",
+						spec.Containers[i].Name, ref.Kind, ref.Name, m.ContainerResource.Name))
+				}
+			}
+		}
+	}
+	return violations, nil
+}
@@ -0,0 +1,109 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// hpaObject returns an admission object for an HPA scaling deployment app on CPU utilization
+func hpaObject(ns string, minReplicas, maxReplicas int32) *Object {
+	utilization := int32(80)
+	return &Object{
+		Request: &v1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Group: "autoscaling", Version: "v2", Kind: "HorizontalPodAutoscaler"},
+			Namespace: ns,
+			Name:      "app",
+			Operation: v1.Create,
+		},
+		Meta: metav1.ObjectMeta{Name: "app", Namespace: ns},
+		HPA: &autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: ns},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "app"},
+				MinReplicas:    &minReplicas,
+				MaxReplicas:    maxReplicas,
+				Metrics: []autoscalingv2.MetricSpec{{
+					Type: autoscalingv2.ResourceMetricSourceType,
+					Resource: &autoscalingv2.ResourceMetricSource{
+						Name:   corev1.ResourceCPU,
+						Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: &utilization},
+					},
+				}},
+			},
+		},
+	}
+}
+
+func Test_hpaRule_Validate(t *testing.T) {
+	deployment := func(ns string, requests corev1.ResourceList) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: ns},
+			Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Resources: corev1.ResourceRequirements{Requests: requests}}},
+			}}},
+		}
+	}
+	cfg := &Config{HPA: HPAConfig{
+		HPABounds:           HPABounds{MinReplicas: 2, MaxReplicas: 10},
+		Namespaces:          map[string]HPABounds{"batch": {MaxReplicas: 100}},
+		RequiredMetricTypes: []autoscalingv2.MetricSourceType{autoscalingv2.ResourceMetricSourceType},
+	}}
+	r := testRule(t, newHPARule, cfg,
+		deployment("default", corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")}),
+		deployment("batch", nil),
+	)
+
+	tests := []struct {
+		name    string
+		o       *Object
+		wantErr bool
+	}{
+		{
+			name: "within bounds",
+			o:    hpaObject("default", 2, 10),
+		},
+		{
+			name:    "min replicas too low",
+			o:       hpaObject("default", 1, 10),
+			wantErr: true,
+		},
+		{
+			name:    "max replicas too high",
+			o:       hpaObject("default", 2, 20),
+			wantErr: true,
+		},
+		{
+			name: "missing metric type",
+			o: func() *Object {
+				o := hpaObject("default", 2, 10)
+				o.HPA.Spec.Metrics = []autoscalingv2.MetricSpec{{Type: autoscalingv2.ExternalMetricSourceType}}
+				return o
+			}(),
+			wantErr: true,
+		},
+		{
+			name:    "target without cpu requests",
+			o:       hpaObject("batch", 1, 50),
+			wantErr: true,
+		},
+		{
+			name: "unknown target",
+			o:    hpaObject("other", 2, 10),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Validate(context.Background(), tt.o)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+const (
+	// ImageTagsRuleName is the name of the rule denying mutable image tags
+	ImageTagsRuleName = "imageTags"
+	// RequireDigestLabel set to true on a namespace requires images referenced by digest, see
+	// ImageTagsConfig.RequireDigestNamespaces
+	RequireDigestLabel = "cosignwebhook.eumel8.io/require-digest"
+)
+
+// ImageTagsConfig configures which image tags are considered mutable
+type ImageTagsConfig struct {
+	// ForbiddenTags are regular expressions of mutable tags like latest or main, defaults to latest
+	ForbiddenTags []string `json:"forbiddenTags"`
+	// RequireDigest denies images referenced by tag in all namespaces, only digests like @sha256:... are allowed
+	RequireDigest bool `json:"requireDigest"`
+	// RequireDigestNamespaces requires digests in the namespaces labeled with RequireDigestLabel set to true
+	RequireDigestNamespaces bool `json:"requireDigestNamespaces"`
+	// Action is either deny (default) or warn
+	Action string `json:"action"`
+}
+
+// imageTagsRule denies images without tag or with a mutable tag, as the image run may change without any change
+// of the pod spec. Images referenced by digest are always allowed.
+type imageTagsRule struct {
+	forbidden     *patternSet
+	requireDigest bool
+	optIn         bool
+	action        string
+}
+
+func newImageTagsRule(_ *CosignServerHandler, cfg *Config) (Rule, error) {
+	c := cfg.ImageTags
+	if err := validateAction(&c.Action, ActionDeny); err != nil {
+		return nil, err
+	}
+	if len(c.ForbiddenTags) == 0 {
+		c.ForbiddenTags = []string{"latest"}
+	}
+	forbidden, err := compilePatternSet(c.ForbiddenTags)
+	if err != nil {
+		return nil, err
+	}
+	return &imageTagsRule{
+		forbidden:     forbidden,
+		requireDigest: c.RequireDigest,
+		optIn:         c.RequireDigestNamespaces,
+		action:        c.Action,
+	}, nil
+}
+
+// Name returns the name of the rule
+func (*imageTagsRule) Name() string {
+	return ImageTagsRuleName
+}
+
+// Facts returns the labels of the namespace if namespaces may opt in to digests
+func (r *imageTagsRule) Facts() []Fact {
+	if !r.optIn {
+		return nil
+	}
+	return []Fact{FactNamespaceLabels}
+}
+
+// fieldPolicies returns the forbidden tags matched by the matcher of the endpoint
+func (r *imageTagsRule) fieldPolicies() []fieldPolicy {
+	return []fieldPolicy{{field: fieldImageTag, patterns: r.forbidden}}
+}
+
+// Validate checks the tags of the images of all containers of pods and workloads
+func (r *imageTagsRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	spec := podSpec(o)
+	if spec == nil {
+		return nil, nil
+	}
+	requireDigest, err := r.digestRequired(o)
+	if err != nil {
+		return nil, err
+	}
+	if requireDigest {
+		tracef(ctx, "namespace %q requires digests", o.Request.Namespace)
+	}
+	forbidden := o.matched(fieldPolicy{field: fieldImageTag, patterns: r.forbidden})
+	var violations []string
+	for _, c := range podContainers(spec) {
+		ref, err := name.ParseReference(c.Image)
+		if err != nil {
+			violations = append(violations, fmt.Sprintf("container %q has invalid image %q", c.Name, c.Image))
+			continue
+		}
+		tag, ok := ref.(name.Tag)
+		if !ok {
+			continue
+		}
+		if requireDigest {
+			violations = append(violations, fmt.Sprintf("container %q uses image %q by tag, namespace %q requires a digest like @sha256:...",
+				c.Name, c.Image, o.Request.Namespace))
+			continue
+		}
+		// the default tag latest is filled in by the parser, so check the image for a tag
+		if !strings.Contains(c.Image[strings.LastIndex(c.Image, "/")+1:], ":") {
+			violations = append(violations, fmt.Sprintf("container %q uses image %q without tag, pin a version or digest", c.Name, c.Image))
+			continue
+		}
+		if forbidden[fieldMatch{container: c.Name, value: tag.TagStr()}] {
+			violations = append(violations, fmt.Sprintf("container %q uses image %q with mutable tag %q, pin a version or digest",
+				c.Name, c.Image, tag.TagStr()))
+		}
+	}
+	return enforce(r.action, violations)
+}
+
+// digestRequired returns whether images have to be referenced by digest in the namespace of the object. Fails if
+// the namespace may have opted in but its labels are unknown, e.g. before the informer synced.
+func (r *imageTagsRule) digestRequired(o *Object) (bool, error) {
+	if r.requireDigest || !r.optIn {
+		return r.requireDigest, nil
+	}
+	v, err := o.Facts.Get(FactNamespaceLabels)
+	if err != nil {
+		return false, internalError(fmt.Errorf("can't check whether namespace %q requires digests: %w", o.Request.Namespace, err))
+	}
+	return v.(map[string]string)[RequireDigestLabel] == "true", nil
+}
@@ -0,0 +1,132 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_imageTagsRule_Validate(t *testing.T) {
+	const digest = "ghcr.io/eumel8/app@sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+	optedIn := &Facts{values: map[Fact]any{FactNamespaceLabels: map[string]string{RequireDigestLabel: "true"}}}
+	notOptedIn := &Facts{values: map[Fact]any{FactNamespaceLabels: map[string]string{}}}
+	tests := []struct {
+		name         string
+		cfg          ImageTagsConfig
+		facts        *Facts
+		image        string
+		wantWarnings int
+		wantErr      string
+	}{
+		{
+			name:  "version tag",
+			image: "ghcr.io/eumel8/app:1.0",
+		},
+		{
+			name:  "digest",
+			image: "ghcr.io/eumel8/app@sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae",
+		},
+		{
+			name:  "latest with digest",
+			image: "ghcr.io/eumel8/app:latest@sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae",
+		},
+		{
+			name:    "latest",
+			image:   "nginx:latest",
+			wantErr: `container "app" uses image "nginx:latest" with mutable tag "latest"`,
+		},
+		{
+			name:    "no tag",
+			image:   "nginx",
+			wantErr: `container "app" uses image "nginx" without tag`,
+		},
+		{
+			name:    "no tag with registry port",
+			image:   "registry.example.com:5000/app",
+			wantErr: "without tag",
+		},
+		{
+			name:    "configured tag",
+			cfg:     ImageTagsConfig{ForbiddenTags: []string{"latest", "main", "dev-.*"}},
+			image:   "ghcr.io/eumel8/app:dev-1234",
+			wantErr: `mutable tag "dev-1234"`,
+		},
+		{
+			name:  "configured tags replace latest",
+			cfg:   ImageTagsConfig{ForbiddenTags: []string{"main"}},
+			image: "nginx:latest",
+		},
+		{
+			name:    "digest required",
+			cfg:     ImageTagsConfig{RequireDigest: true},
+			image:   "ghcr.io/eumel8/app:1.0",
+			wantErr: `container "app" uses image "ghcr.io/eumel8/app:1.0" by tag, namespace "default" requires a digest like @sha256:...`,
+		},
+		{
+			name:  "digest required with digest",
+			cfg:   ImageTagsConfig{RequireDigest: true},
+			image: digest,
+		},
+		{
+			name:    "namespace opted in",
+			cfg:     ImageTagsConfig{RequireDigestNamespaces: true},
+			facts:   optedIn,
+			image:   "ghcr.io/eumel8/app:1.0",
+			wantErr: "requires a digest",
+		},
+		{
+			name:  "namespace opted in with digest",
+			cfg:   ImageTagsConfig{RequireDigestNamespaces: true},
+			facts: optedIn,
+			image: digest,
+		},
+		{
+			name:  "namespace not opted in",
+			cfg:   ImageTagsConfig{RequireDigestNamespaces: true},
+			facts: notOptedIn,
+			image: "ghcr.io/eumel8/app:1.0",
+		},
+		{
+			name:  "opt in disabled",
+			facts: optedIn,
+			image: "ghcr.io/eumel8/app:1.0",
+		},
+		{
+			name:    "namespace labels unknown",
+			cfg:     ImageTagsConfig{RequireDigestNamespaces: true},
+			image:   "ghcr.io/eumel8/app:1.0",
+			wantErr: `can't check whether namespace "default" requires digests`,
+		},
+		{
+			name:         "mutable tag warns",
+			cfg:          ImageTagsConfig{Action: ActionWarn},
+			image:        "nginx:latest",
+			wantWarnings: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newImageTagsRule(nil, &Config{ImageTags: tt.cfg})
+			if err != nil {
+				t.Fatal(err)
+			}
+			o := podObject("default", corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Image: tt.image}},
+			})
+			o.Facts = tt.facts
+			warnings, err := r.Validate(context.Background(), o)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Validate() error = %v, want none", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("Validate() warnings = %v, want %d", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
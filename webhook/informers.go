@@ -0,0 +1,260 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"time"
+
+	log "github.com/gookit/slog"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	nodev1 "k8s.io/api/node/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/informers"
+	appsinformers "k8s.io/client-go/informers/apps/v1"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/informers/internalinterfaces"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata/metadatainformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// lastAppliedAnnotation holds the last applied config of kubectl, a copy of the whole object
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// InformersConfig configures the caches of the informers used by the rules
+type InformersConfig struct {
+	// Selectors restrict the cached objects by resource, e.g. secrets, configmaps or deployments
+	Selectors map[string]SelectorConfig `json:"selectors"`
+}
+
+// SelectorConfig selects the objects of a resource by label and field selector
+type SelectorConfig struct {
+	Label string `json:"label"`
+	Field string `json:"field"`
+}
+
+// filteredInformer creates the informer of a resource with a selector
+type filteredInformer struct {
+	obj runtime.Object
+	new func(kubernetes.Interface, string, time.Duration, cache.Indexers, internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer
+}
+
+// filteredInformers are the resources cached by the rules which can be restricted by selectors
+var filteredInformers = map[string]filteredInformer{
+	"serviceaccounts": {&corev1.ServiceAccount{}, coreinformers.NewFilteredServiceAccountInformer},
+	"resourcequotas":  {&corev1.ResourceQuota{}, coreinformers.NewFilteredResourceQuotaInformer},
+	"deployments":     {&appsv1.Deployment{}, appsinformers.NewFilteredDeploymentInformer},
+	"statefulsets":    {&appsv1.StatefulSet{}, appsinformers.NewFilteredStatefulSetInformer},
+}
+
+// metadataInformers are the resources whose metadata only is cached by the rules, e.g. to check that referenced
+// objects exist without their data crossing the wire. They can be restricted by selectors, too.
+var metadataInformers = map[string]schema.GroupVersionResource{
+	"configmaps": corev1.SchemeGroupVersion.WithResource("configmaps"),
+	"secrets":    corev1.SchemeGroupVersion.WithResource("secrets"),
+}
+
+// listFunc lists the objects of a resource from the API server
+type listFunc func(ctx context.Context, cs kubernetes.Interface, opts metav1.ListOptions) (runtime.Object, error)
+
+// informerLists are the resources cached by the shared informers of the rules with their list functions,
+// used to re-list the caches on demand
+var informerLists = map[string]struct {
+	obj  runtime.Object
+	list listFunc
+}{
+	"serviceaccounts": {&corev1.ServiceAccount{}, func(ctx context.Context, cs kubernetes.Interface, opts metav1.ListOptions) (runtime.Object, error) {
+		return cs.CoreV1().ServiceAccounts(metav1.NamespaceAll).List(ctx, opts)
+	}},
+	"resourcequotas": {&corev1.ResourceQuota{}, func(ctx context.Context, cs kubernetes.Interface, opts metav1.ListOptions) (runtime.Object, error) {
+		return cs.CoreV1().ResourceQuotas(metav1.NamespaceAll).List(ctx, opts)
+	}},
+	"deployments": {&appsv1.Deployment{}, func(ctx context.Context, cs kubernetes.Interface, opts metav1.ListOptions) (runtime.Object, error) {
+		return cs.AppsV1().Deployments(metav1.NamespaceAll).List(ctx, opts)
+	}},
+	"statefulsets": {&appsv1.StatefulSet{}, func(ctx context.Context, cs kubernetes.Interface, opts metav1.ListOptions) (runtime.Object, error) {
+		return cs.AppsV1().StatefulSets(metav1.NamespaceAll).List(ctx, opts)
+	}},
+	"namespaces": {&corev1.Namespace{}, func(ctx context.Context, cs kubernetes.Interface, opts metav1.ListOptions) (runtime.Object, error) {
+		return cs.CoreV1().Namespaces().List(ctx, opts)
+	}},
+	"runtimeclasses": {&nodev1.RuntimeClass{}, func(ctx context.Context, cs kubernetes.Interface, opts metav1.ListOptions) (runtime.Object, error) {
+		return cs.NodeV1().RuntimeClasses().List(ctx, opts)
+	}},
+}
+
+// validate checks that selectors are only configured for resources which can be restricted
+func (c InformersConfig) validate() error {
+	for resource := range c.Selectors {
+		_, filtered := filteredInformers[resource]
+		if _, ok := metadataInformers[resource]; !ok && !filtered {
+			supported := append(slices.Collect(maps.Keys(filteredInformers)), slices.Collect(maps.Keys(metadataInformers))...)
+			slices.Sort(supported)
+			return fmt.Errorf("informers: no selector for resource %q, supported are %v", resource, supported)
+		}
+	}
+	return nil
+}
+
+// selectInformers registers the informers of passed resources with their configured selectors in the informer
+// factory. Rules call it before creating their listers, so the informers only list and watch the selected objects.
+func (csh *CosignServerHandler) selectInformers(resources ...string) {
+	if csh.cfg == nil {
+		return
+	}
+	for _, resource := range resources {
+		s, ok := csh.cfg.Informers.Selectors[resource]
+		f, known := filteredInformers[resource]
+		if !ok || !known {
+			continue
+		}
+		tweak := func(o *metav1.ListOptions) {
+			o.LabelSelector, o.FieldSelector = s.Label, s.Field
+		}
+		csh.informers.InformerFor(f.obj, func(cs kubernetes.Interface, resync time.Duration) cache.SharedIndexInformer {
+			return f.new(cs, metav1.NamespaceAll, resync, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, tweak)
+		})
+	}
+}
+
+// metadataLister returns the lister of the informer caching the metadata of the selected objects of the resource.
+// Rules call it while they are created, the informer is started by Start.
+func (csh *CosignServerHandler) metadataLister(resource string) cache.GenericLister {
+	if inf, ok := csh.metaInformers[resource]; ok {
+		return inf.Lister()
+	}
+	var tweak metadatainformer.TweakListOptionsFunc
+	if csh.cfg != nil {
+		if s, ok := csh.cfg.Informers.Selectors[resource]; ok {
+			tweak = func(o *metav1.ListOptions) {
+				o.LabelSelector, o.FieldSelector = s.Label, s.Field
+			}
+		}
+	}
+	inf := metadatainformer.NewFilteredMetadataInformer(csh.meta, metadataInformers[resource], metav1.NamespaceAll, 0,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, tweak)
+	if err := inf.Informer().SetTransform(trimObject); err != nil {
+		log.Errorf("Can't trim the cached %s: %v", resource, err)
+	}
+	if csh.metaInformers == nil {
+		csh.metaInformers = map[string]informers.GenericInformer{}
+	}
+	csh.metaInformers[resource] = inf
+	return inf.Lister()
+}
+
+// startMetadataInformers starts the metadata informers and waits until their caches are synced
+func (csh *CosignServerHandler) startMetadataInformers(ctx context.Context) {
+	for _, inf := range csh.metaInformers {
+		go inf.Informer().Run(ctx.Done())
+	}
+	for resource, inf := range csh.metaInformers {
+		if !cache.WaitForCacheSync(ctx.Done(), inf.Informer().HasSynced) {
+			log.Errorf("Informer cache for %s metadata not synced", resource)
+		}
+	}
+}
+
+// trimObject strips the fields of cached objects which no rule reads, the managed fields, the last applied config,
+// the data of secrets and configmaps and the status of workloads, to keep the caches small on big clusters.
+// Secrets and configmaps are cached by the rules with their metadata only, their data never reaches the webhook.
+func trimObject(obj any) (any, error) {
+	if m, err := meta.Accessor(obj); err == nil {
+		m.SetManagedFields(nil)
+		if a := m.GetAnnotations(); a[lastAppliedAnnotation] != "" {
+			delete(a, lastAppliedAnnotation)
+			m.SetAnnotations(a)
+		}
+	}
+	switch o := obj.(type) {
+	case *corev1.Secret:
+		o.Data, o.StringData = nil, nil
+	case *corev1.ConfigMap:
+		o.Data, o.BinaryData = nil, nil
+	case *appsv1.Deployment:
+		o.Status = appsv1.DeploymentStatus{}
+	case *appsv1.StatefulSet:
+		o.Status = appsv1.StatefulSetStatus{}
+	}
+	return obj, nil
+}
+
+// relistInformers lists the objects of all started informers from the API server again and replaces their caches,
+// e.g. if watch events were missed. It returns the re-listed resources with their errors.
+func (csh *CosignServerHandler) relistInformers(ctx context.Context) map[string]error {
+	// with a closed channel, WaitForCacheSync only returns the started informers
+	stopped := make(chan struct{})
+	close(stopped)
+	started := csh.informers.WaitForCacheSync(stopped)
+
+	results := map[string]error{}
+	for resource, l := range informerLists {
+		if _, ok := started[reflect.TypeOf(l.obj)]; !ok {
+			continue
+		}
+		results[resource] = csh.relist(ctx, resource, l.obj, l.list)
+	}
+	for resource, inf := range csh.metaInformers {
+		results[resource] = csh.relistMetadata(ctx, resource, inf.Informer().GetStore())
+	}
+	return results
+}
+
+// relist lists the selected objects of the resource and replaces the cache of its informer
+func (csh *CosignServerHandler) relist(ctx context.Context, resource string, obj runtime.Object, list listFunc) error {
+	opts := metav1.ListOptions{}
+	if s, ok := csh.cfg.Informers.Selectors[resource]; ok {
+		opts.LabelSelector, opts.FieldSelector = s.Label, s.Field
+	}
+	l, err := list(ctx, csh.cs, opts)
+	if err != nil {
+		return err
+	}
+	items, err := meta.ExtractList(l)
+	if err != nil {
+		return err
+	}
+	lm, err := meta.ListAccessor(l)
+	if err != nil {
+		return err
+	}
+	objs := make([]any, 0, len(items))
+	for _, item := range items {
+		o, err := trimObject(item)
+		if err != nil {
+			return err
+		}
+		objs = append(objs, o)
+	}
+	// the informer exists, so no new one is created
+	return csh.informers.InformerFor(obj, nil).GetStore().Replace(objs, lm.GetResourceVersion())
+}
+
+// relistMetadata lists the metadata of the selected objects of the resource and replaces the cache of its informer
+func (csh *CosignServerHandler) relistMetadata(ctx context.Context, resource string, store cache.Store) error {
+	opts := metav1.ListOptions{}
+	if s, ok := csh.cfg.Informers.Selectors[resource]; ok {
+		opts.LabelSelector, opts.FieldSelector = s.Label, s.Field
+	}
+	l, err := csh.meta.Resource(metadataInformers[resource]).List(ctx, opts)
+	if err != nil {
+		return err
+	}
+	objs := make([]any, 0, len(l.Items))
+	for i := range l.Items {
+		o, err := trimObject(&l.Items[i])
+		if err != nil {
+			return err
+		}
+		objs = append(objs, o)
+	}
+	return store.Replace(objs, l.ResourceVersion)
+}
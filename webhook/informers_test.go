@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func Test_trimObject(t *testing.T) {
+	meta := metav1.ObjectMeta{
+		Name:          "app",
+		Annotations:   map[string]string{lastAppliedAnnotation: "{}", "team": "a"},
+		ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl"}},
+	}
+	secret := &corev1.Secret{ObjectMeta: *meta.DeepCopy(), Data: map[string][]byte{"password": []byte("secret")}}
+	if _, err := trimObject(secret); err != nil {
+		t.Fatal(err)
+	}
+	if secret.Data != nil || secret.ManagedFields != nil || len(secret.Annotations) != 1 || secret.Annotations["team"] != "a" {
+		t.Errorf("trimObject() secret = %+v, want data, managed fields and last applied config stripped", secret)
+	}
+	d := &appsv1.Deployment{ObjectMeta: *meta.DeepCopy(), Status: appsv1.DeploymentStatus{Replicas: 3}}
+	d.Spec.Template.Labels = map[string]string{"app": "web"}
+	if _, err := trimObject(d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Status.Replicas != 0 || d.Spec.Template.Labels["app"] != "web" {
+		t.Errorf("trimObject() deployment = %+v, want status stripped and spec kept", d)
+	}
+}
+
+func Test_selectInformers(t *testing.T) {
+	cfg := &Config{Informers: InformersConfig{Selectors: map[string]SelectorConfig{
+		"secrets": {Field: "type!=helm.sh/release.v1"},
+	}}}
+	if err := cfg.Informers.validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := (InformersConfig{Selectors: map[string]SelectorConfig{"pods": {}}}).validate(); err == nil {
+		t.Error("validate() of unsupported resource = nil, want error")
+	}
+
+	cs, meta := fake.NewSimpleClientset(), fakeMetadataClient()
+	csh := &CosignServerHandler{cs: cs, meta: meta, cfg: cfg, informers: informers.NewSharedInformerFactoryWithOptions(cs, 0, informers.WithTransform(trimObject))}
+	if _, err := newReferencesRule(csh, cfg); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	csh.Start(ctx)
+
+	selected := map[string]string{}
+	for _, a := range append(cs.Actions(), meta.Actions()...) {
+		if l, ok := a.(k8stesting.ListAction); ok {
+			selected[l.GetResource().Resource] = l.GetListRestrictions().Fields.String()
+		}
+	}
+	if got := selected["secrets"]; got != "type!=helm.sh/release.v1" {
+		t.Errorf("secrets listed with field selector %q, want type!=helm.sh/release.v1", got)
+	}
+	if got, ok := selected["configmaps"]; !ok || got != "" {
+		t.Errorf("configmaps listed with field selector %q, want none", got)
+	}
+	for _, a := range cs.Actions() {
+		if r := a.GetResource().Resource; r == "secrets" || r == "configmaps" {
+			t.Errorf("%s %s with the typed client, want their metadata only", a.GetVerb(), r)
+		}
+	}
+}
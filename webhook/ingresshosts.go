@@ -0,0 +1,126 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// IngressHostsRuleName is the name of the rule restricting the hosts of ingresses
+const IngressHostsRuleName = "ingressHosts"
+
+// IngressHostsConfig configures the domains ingresses may claim hosts of, with overrides per namespace
+type IngressHostsConfig struct {
+	// Domains are the allowed domains. A wildcard domain like *.apps.example.com allows all hosts below
+	// apps.example.com, other domains only the host itself. Empty allows all hosts.
+	Domains []string `json:"domains"`
+	// Namespaces replace the allowed domains per namespace
+	Namespaces map[string][]string `json:"namespaces"`
+	// Action is either deny (default) or warn
+	Action string `json:"action"`
+}
+
+// ingressHostsRule keeps teams from claiming hostnames outside of the domains of the operators
+type ingressHostsRule struct {
+	cfg IngressHostsConfig
+}
+
+func newIngressHostsRule(_ *CosignServerHandler, cfg *Config) (Rule, error) {
+	c := cfg.IngressHosts
+	if err := validateAction(&c.Action, ActionDeny); err != nil {
+		return nil, err
+	}
+	var err error
+	if c.Domains, err = normalizeDomains(c.Domains); err != nil {
+		return nil, err
+	}
+	namespaces := make(map[string][]string, len(c.Namespaces))
+	for ns, domains := range c.Namespaces {
+		if namespaces[ns], err = normalizeDomains(domains); err != nil {
+			return nil, fmt.Errorf("namespace %q: %w", ns, err)
+		}
+	}
+	c.Namespaces = namespaces
+	return &ingressHostsRule{cfg: c}, nil
+}
+
+// normalizeDomains returns the domains in lower case, as hosts are matched case-insensitively. It checks that the
+// domains are DNS names, optionally with a leading wildcard label.
+func normalizeDomains(domains []string) ([]string, error) {
+	if domains == nil {
+		return nil, nil
+	}
+	normalized := make([]string, 0, len(domains))
+	for _, d := range domains {
+		d = strings.ToLower(d)
+		if errs := validation.IsDNS1123Subdomain(strings.TrimPrefix(d, "*.")); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid domain %q: %s", d, strings.Join(errs, ", "))
+		}
+		normalized = append(normalized, d)
+	}
+	return normalized, nil
+}
+
+// Name returns the name of the rule
+func (*ingressHostsRule) Name() string {
+	return IngressHostsRuleName
+}
+
+// Validate checks that the hosts of the rules and TLS entries of ingresses are in the allowed domains of the namespace
+func (r *ingressHostsRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	ing := o.Ingress
+	if ing == nil || o.Request.Operation == v1.Delete {
+		return nil, nil
+	}
+	ns := o.Request.Namespace
+	domains, ok := r.cfg.Namespaces[ns]
+	if ok {
+		tracef(ctx, "domains of namespace %q", ns)
+	} else {
+		domains = r.cfg.Domains
+	}
+	if len(domains) == 0 {
+		return nil, nil
+	}
+
+	var violations []string
+	if len(ing.Spec.Rules) == 0 && ing.Spec.DefaultBackend != nil {
+		violations = append(violations, fmt.Sprintf("ingress %q has only a default backend, which matches all hosts", o.Meta.Name))
+	}
+	for _, rule := range ing.Spec.Rules {
+		if rule.Host == "" {
+			violations = append(violations, fmt.Sprintf("ingress %q has a rule without host, which matches all hosts", o.Meta.Name))
+			continue
+		}
+		if !inDomains(domains, rule.Host) {
+			violations = append(violations, fmt.Sprintf("host %q of ingress %q is outside of the allowed domains %v", rule.Host, o.Meta.Name, domains))
+		}
+	}
+	for _, tls := range ing.Spec.TLS {
+		for _, host := range tls.Hosts {
+			if !inDomains(domains, host) {
+				violations = append(violations, fmt.Sprintf("TLS host %q of ingress %q is outside of the allowed domains %v", host, o.Meta.Name, domains))
+			}
+		}
+	}
+	return enforce(r.cfg.Action, violations)
+}
+
+// inDomains returns true if the host is in one of the domains. A wildcard host like *.foo.apps.example.com is in the
+// domain *.apps.example.com, but *.apps.example.com itself isn't, as it would claim all hosts of the domain.
+func inDomains(domains []string, host string) bool {
+	host = strings.ToLower(host)
+	for _, d := range domains {
+		if parent, ok := strings.CutPrefix(d, "*."); ok {
+			if strings.HasSuffix(strings.TrimPrefix(host, "*."), "."+parent) {
+				return true
+			}
+		} else if host == d {
+			return true
+		}
+	}
+	return false
+}
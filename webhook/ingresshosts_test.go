@@ -0,0 +1,192 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/admission/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ingressObject returns an admission object for an ingress with passed spec in passed namespace
+func ingressObject(ns string, spec networkingv1.IngressSpec) *Object {
+	return &Object{
+		Request: &v1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"},
+			Namespace: ns,
+			Name:      "test",
+		},
+		Meta: metav1.ObjectMeta{Name: "test", Namespace: ns},
+		Ingress: &networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: ns},
+			Spec:       spec,
+		},
+	}
+}
+
+func Test_ingressHostsRule_Validate(t *testing.T) {
+	hosts := func(hosts ...string) networkingv1.IngressSpec {
+		spec := networkingv1.IngressSpec{}
+		for _, h := range hosts {
+			spec.Rules = append(spec.Rules, networkingv1.IngressRule{Host: h})
+		}
+		return spec
+	}
+	apps := []string{"*.apps.example.com"}
+	tests := []struct {
+		name         string
+		namespace    string
+		spec         networkingv1.IngressSpec
+		cfg          IngressHostsConfig
+		wantWarnings int
+		wantErr      string
+	}{
+		{
+			name: "no domains",
+			spec: hosts("www.example.com"),
+		},
+		{
+			name: "host in wildcard domain",
+			spec: hosts("shop.apps.example.com", "API.Shop.apps.example.com", "*.shop.apps.example.com"),
+			cfg:  IngressHostsConfig{Domains: apps},
+		},
+		{
+			name:    "host outside of domains",
+			spec:    hosts("shop.apps.example.com", "www.example.com"),
+			cfg:     IngressHostsConfig{Domains: apps},
+			wantErr: `host "www.example.com" of ingress "test" is outside of the allowed domains [*.apps.example.com]`,
+		},
+		{
+			name:    "wildcard host claiming the domain",
+			spec:    hosts("*.apps.example.com"),
+			cfg:     IngressHostsConfig{Domains: apps},
+			wantErr: `host "*.apps.example.com" of ingress "test" is outside`,
+		},
+		{
+			name:    "parent of wildcard domain",
+			spec:    hosts("apps.example.com"),
+			cfg:     IngressHostsConfig{Domains: apps},
+			wantErr: `host "apps.example.com"`,
+		},
+		{
+			name:    "suffix without dot",
+			spec:    hosts("evilapps.example.com"),
+			cfg:     IngressHostsConfig{Domains: apps},
+			wantErr: `host "evilapps.example.com"`,
+		},
+		{
+			name:    "rule without host",
+			spec:    hosts(""),
+			cfg:     IngressHostsConfig{Domains: apps},
+			wantErr: `ingress "test" has a rule without host`,
+		},
+		{
+			name: "default backend without rules",
+			spec: networkingv1.IngressSpec{
+				DefaultBackend: &networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{Name: "shop"}},
+			},
+			cfg:     IngressHostsConfig{Domains: apps},
+			wantErr: `ingress "test" has only a default backend, which matches all hosts`,
+		},
+		{
+			name:    "domains in upper case",
+			spec:    hosts("shop.apps.example.com", "Shop.Example.com"),
+			cfg:     IngressHostsConfig{Domains: []string{"*.Apps.Example.com"}, Namespaces: map[string][]string{"other": {"Shop.Example.com"}}},
+			wantErr: `host "Shop.Example.com" of ingress "test" is outside of the allowed domains [*.apps.example.com]`,
+		},
+		{
+			name:      "domains of namespace in upper case",
+			namespace: "shop",
+			spec:      hosts("shop.example.com"),
+			cfg:       IngressHostsConfig{Domains: apps, Namespaces: map[string][]string{"shop": {"SHOP.example.com"}}},
+		},
+		{
+			name: "TLS host outside of domains",
+			spec: networkingv1.IngressSpec{
+				Rules: []networkingv1.IngressRule{{Host: "shop.apps.example.com"}},
+				TLS:   []networkingv1.IngressTLS{{Hosts: []string{"shop.apps.example.com", "shop.example.com"}}},
+			},
+			cfg:     IngressHostsConfig{Domains: apps},
+			wantErr: `TLS host "shop.example.com" of ingress "test" is outside`,
+		},
+		{
+			name:      "exact domain of namespace",
+			namespace: "shop",
+			spec:      hosts("shop.example.com"),
+			cfg:       IngressHostsConfig{Domains: apps, Namespaces: map[string][]string{"shop": {"shop.example.com"}}},
+		},
+		{
+			name:      "namespace replaces the domains",
+			namespace: "shop",
+			spec:      hosts("shop.apps.example.com"),
+			cfg:       IngressHostsConfig{Domains: apps, Namespaces: map[string][]string{"shop": {"shop.example.com"}}},
+			wantErr:   `host "shop.apps.example.com" of ingress "test" is outside of the allowed domains [shop.example.com]`,
+		},
+		{
+			name:         "warn",
+			spec:         hosts("www.example.com", ""),
+			cfg:          IngressHostsConfig{Domains: apps, Action: ActionWarn},
+			wantWarnings: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newIngressHostsRule(&CosignServerHandler{}, &Config{IngressHosts: tt.cfg})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ns := tt.namespace
+			if ns == "" {
+				ns = "default"
+			}
+			warnings, err := r.Validate(context.Background(), ingressObject(ns, tt.spec))
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("Validate() warnings = %v, want %d", warnings, tt.wantWarnings)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_newIngressHostsRule_invalid(t *testing.T) {
+	for _, c := range []IngressHostsConfig{
+		{Domains: []string{"apps.*.example.com"}},
+		{Domains: []string{"Apps.example.com"}},
+		{Namespaces: map[string][]string{"shop": {"https://shop.example.com"}}},
+		{Action: "block"},
+	} {
+		if _, err := newIngressHostsRule(&CosignServerHandler{}, &Config{IngressHosts: c}); err == nil {
+			t.Errorf("newIngressHostsRule(%+v), want error", c)
+		}
+	}
+}
+
+func Test_getObject_ingress(t *testing.T) {
+	body, err := json.Marshal(v1.AdmissionReview{Request: &v1.AdmissionRequest{
+		Kind:   metav1.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"},
+		Object: runtime.RawExtension{Raw: []byte(`{"metadata":{"name":"web"},"spec":{"rules":[{"host":"web.apps.example.com"}]}}`)},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, _, err := getObject(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Ingress == nil || len(o.Ingress.Spec.Rules) != 1 || o.Ingress.Spec.Rules[0].Host != "web.apps.example.com" {
+		t.Errorf("getObject() ingress = %+v, want the decoded ingress", o.Ingress)
+	}
+}
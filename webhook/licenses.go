@@ -0,0 +1,373 @@
+package webhook
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/gookit/slog"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// LicensesRuleName is the name of the rule checking the licenses declared in SBOM attestations
+	LicensesRuleName = "licenses"
+	// maxLicenseEntries bounds the licenses cached by digest
+	maxLicenseEntries      = 10000
+	defaultLicenseCacheTTL = time.Hour
+
+	spdxPredicateType      = "https://spdx.dev/Document"
+	cycloneDXPredicateType = "https://cyclonedx.org/bom"
+)
+
+// LicensePolicy restricts licenses by their SPDX identifiers, given as regular expressions like AGPL-.*
+type LicensePolicy struct {
+	// Allowed licenses, empty allows all licenses which aren't denied
+	Allowed []string `json:"allowed"`
+	// Denied licenses
+	Denied []string `json:"denied"`
+}
+
+// LicensesConfig configures the licenses images may declare in their SBOM attestations
+type LicensesConfig struct {
+	// Allowed licenses, empty allows all licenses which aren't denied
+	Allowed []string `json:"allowed"`
+	// Denied licenses, e.g. AGPL-.*
+	Denied []string `json:"denied"`
+	// Namespaces maps namespaces to their license policy, replacing the global one
+	Namespaces map[string]LicensePolicy `json:"namespaces"`
+	// CacheTTL is how long the licenses of a digest are cached, defaults to 1h
+	CacheTTL metav1.Duration `json:"cacheTTL"`
+	// Action is either deny (default) or warn
+	Action string `json:"action"`
+}
+
+// licensePolicy is a compiled license policy
+type licensePolicy struct {
+	allowed *patternSet
+	denied  *patternSet
+}
+
+// licenseEntry are the cached licenses of a digest
+type licenseEntry struct {
+	licenses []string
+	expires  time.Time
+}
+
+// licensesRule checks the licenses declared in the SBOM attestations of images. Attestations are verified with the
+// public key of the container, like signatures. Containers without public key and images without SBOM attestation
+// are skipped.
+type licensesRule struct {
+	csh        *CosignServerHandler
+	policy     licensePolicy
+	namespaces map[string]licensePolicy
+	ttl        time.Duration
+	action     string
+
+	mu    sync.Mutex
+	cache map[string]licenseEntry
+}
+
+func newLicensesRule(csh *CosignServerHandler, cfg *Config) (Rule, error) {
+	c := cfg.Licenses
+	if err := validateAction(&c.Action, ActionDeny); err != nil {
+		return nil, err
+	}
+	if c.CacheTTL.Duration <= 0 {
+		c.CacheTTL.Duration = defaultLicenseCacheTTL
+	}
+	policy, err := compileLicensePolicy(LicensePolicy{Allowed: c.Allowed, Denied: c.Denied})
+	if err != nil {
+		return nil, err
+	}
+	namespaces := make(map[string]licensePolicy, len(c.Namespaces))
+	for ns, p := range c.Namespaces {
+		if namespaces[ns], err = compileLicensePolicy(p); err != nil {
+			return nil, fmt.Errorf("namespace %s: %w", ns, err)
+		}
+	}
+	return &licensesRule{
+		csh:        csh,
+		policy:     policy,
+		namespaces: namespaces,
+		ttl:        c.CacheTTL.Duration,
+		action:     c.Action,
+		cache:      map[string]licenseEntry{},
+	}, nil
+}
+
+// compileLicensePolicy compiles the patterns of the license policy
+func compileLicensePolicy(p LicensePolicy) (licensePolicy, error) {
+	allowed, err := compilePatternSet(p.Allowed)
+	if err != nil {
+		return licensePolicy{}, fmt.Errorf("allowed licenses: %w", err)
+	}
+	denied, err := compilePatternSet(p.Denied)
+	if err != nil {
+		return licensePolicy{}, fmt.Errorf("denied licenses: %w", err)
+	}
+	return licensePolicy{allowed: allowed, denied: denied}, nil
+}
+
+// Name returns the name of the rule
+func (*licensesRule) Name() string {
+	return LicensesRuleName
+}
+
+// Facts returns the facts the rule depends on
+func (*licensesRule) Facts() []Fact {
+	return []Fact{FactImageDigests}
+}
+
+// Validate checks the licenses of the images of all containers of pods against the policy of the namespace.
+// A container violates the policy if any license of its image is denied or, with an allowlist, not allowed.
+func (r *licensesRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	ns := o.Request.Namespace
+	policy, ok := r.namespaces[ns]
+	if ok {
+		tracef(ctx, "policy of namespace %q", ns)
+	} else {
+		policy = r.policy
+	}
+	if o.Pod == nil || (policy.allowed.empty() && policy.denied.empty()) {
+		return nil, nil
+	}
+
+	// the keychain is only built if the licenses aren't cached
+	var kc authn.Keychain
+	keychain := func() (authn.Keychain, error) {
+		if kc != nil {
+			return kc, nil
+		}
+		var err error
+		kc, err = newKeychainForPod(ctx, o.Pod)
+		return kc, err
+	}
+
+	digests := imageDigests(o)
+	credentials := pullCredentials(ns, &o.Pod.Spec)
+	var violations []string
+	for _, c := range podContainers(&o.Pod.Spec) {
+		pubKey := r.csh.getPubKeyFor(c, ns)
+		if pubKey == "" {
+			continue
+		}
+		licenses, err := r.licenses(ctx, c, pubKey, credentials, digests, keychain)
+		if err != nil {
+			return nil, err
+		}
+		tracef(ctx, "image %q has licenses %v", c.Image, licenses)
+		for _, l := range licenses {
+			switch {
+			case policy.denied.matches(l):
+				violations = append(violations, fmt.Sprintf("container %q uses image %q with denied license %s", c.Name, c.Image, l))
+			case !policy.allowed.empty() && !policy.allowed.matches(l):
+				violations = append(violations, fmt.Sprintf("container %q uses image %q with license %s, which is not allowed in namespace %q",
+					c.Name, c.Image, l, ns))
+			}
+		}
+	}
+	return enforce(r.action, violations)
+}
+
+// licenses returns the licenses declared in the verified SBOM attestations of the container image,
+// cached by digest. Images referenced by tag are looked up in the resolved digests. Concurrent lookups are only
+// shared by pods with the same pull credentials.
+func (r *licensesRule) licenses(ctx context.Context, c corev1.Container, pubKey, credentials string, digests map[string]name.Digest, keychain func() (authn.Keychain, error)) ([]string, error) { //nolint:gocritic // better for garbage collection
+	ref, err := name.ParseReference(c.Image)
+	if err != nil {
+		return nil, fmt.Errorf("could parse image reference for image %q", c.Image)
+	}
+	digest, ok := ref.(name.Digest)
+	if !ok {
+		if digest, ok = digests[c.Image]; !ok {
+			return nil, internalError(fmt.Errorf("digest of image %q couldn't be resolved", c.Image))
+		}
+	}
+
+	now := time.Now()
+	r.mu.Lock()
+	e, ok := r.cache[digest.DigestStr()]
+	r.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.licenses, nil
+	}
+
+	kc, err := keychain()
+	if err != nil {
+		return nil, internalError(fmt.Errorf("failed initializing k8schain"))
+	}
+	co, err := r.csh.checkOpts(c, pubKey, kc)
+	if err != nil {
+		return nil, err
+	}
+	key := sharedKey(digest.String(), pubKey, getCosignRepository(c.Env))
+	data, err := r.csh.dedupe(ctx, sharedCacheLicenses, key+"/"+credentials, func(ctx context.Context) (any, error) {
+		return r.csh.shared.fetch(ctx, sharedCacheLicenses, key, func() ([]byte, error) {
+			l, lerr := attestedLicenses(ctx, digest, co, c.Image)
+			if lerr != nil {
+				return nil, lerr
+			}
+			return json.Marshal(l)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	var licenses []string
+	if err := json.Unmarshal(data.([]byte), &licenses); err != nil {
+		return nil, internalError(fmt.Errorf("can't parse shared licenses of %q: %w", c.Image, err))
+	}
+
+	r.put(digest.DigestStr(), licenses, now)
+	return licenses, nil
+}
+
+// attestedLicenses returns the sorted licenses declared in the verified SBOM attestations of the digest
+func attestedLicenses(ctx context.Context, digest name.Digest, co *cosign.CheckOpts, image string) ([]string, error) {
+	co.ClaimVerifier = cosign.IntotoSubjectClaimVerifier
+	var licenses []string
+	attestations, _, err := cosign.VerifyImageAttestations(ctx, digest, co)
+	var noAttestations *cosign.ErrNoMatchingAttestations
+	switch {
+	case errors.As(err, &noAttestations):
+		log.Debugf("Image %q has no attestations", image)
+	case err != nil:
+		log.Errorf("Error verifying attestations of image %q: %v", image, err)
+		return nil, verificationError(err, fmt.Errorf("attestations of %q couldn't be verified", image))
+	}
+	for _, a := range attestations {
+		payload, perr := a.Payload()
+		if perr != nil {
+			return nil, fmt.Errorf("can't read attestation of %q: %w", image, perr)
+		}
+		l, perr := sbomLicenses(payload)
+		if perr != nil {
+			return nil, fmt.Errorf("can't parse attestation of %q: %w", image, perr)
+		}
+		licenses = append(licenses, l...)
+	}
+	slices.Sort(licenses)
+	return slices.Compact(licenses), nil
+}
+
+// put caches the licenses of the digest
+func (r *licensesRule) put(digest string, licenses []string, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.cache) >= maxLicenseEntries {
+		for k, e := range r.cache {
+			if now.After(e.expires) {
+				delete(r.cache, k)
+			}
+		}
+		if len(r.cache) >= maxLicenseEntries {
+			return
+		}
+	}
+	r.cache[digest] = licenseEntry{licenses: licenses, expires: now.Add(r.ttl)}
+}
+
+// flush drops the cached licenses
+func (r *licensesRule) flush(context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	clear(r.cache)
+	return nil
+}
+
+// sbomLicenses returns the license identifiers declared in an attestation, a DSSE envelope of an in-toto statement.
+// Attestations of other predicate types than SPDX and CycloneDX have no licenses.
+func sbomLicenses(envelope []byte) ([]string, error) {
+	env := struct {
+		Payload string `json:"payload"`
+	}{}
+	if err := json.Unmarshal(envelope, &env); err != nil {
+		return nil, err
+	}
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return nil, err
+	}
+	statement := struct {
+		PredicateType string          `json:"predicateType"`
+		Predicate     json.RawMessage `json:"predicate"`
+	}{}
+	if err := json.Unmarshal(payload, &statement); err != nil {
+		return nil, err
+	}
+
+	var expressions []string
+	switch statement.PredicateType {
+	case spdxPredicateType:
+		doc := struct {
+			Packages []struct {
+				LicenseConcluded string `json:"licenseConcluded"`
+				LicenseDeclared  string `json:"licenseDeclared"`
+			} `json:"packages"`
+		}{}
+		if err := json.Unmarshal(statement.Predicate, &doc); err != nil {
+			return nil, err
+		}
+		for _, p := range doc.Packages {
+			expressions = append(expressions, p.LicenseConcluded, p.LicenseDeclared)
+		}
+	case cycloneDXPredicateType:
+		bom := struct {
+			Components []struct {
+				Licenses []struct {
+					License struct {
+						ID   string `json:"id"`
+						Name string `json:"name"`
+					} `json:"license"`
+					Expression string `json:"expression"`
+				} `json:"licenses"`
+			} `json:"components"`
+		}{}
+		if err := json.Unmarshal(statement.Predicate, &bom); err != nil {
+			return nil, err
+		}
+		for _, c := range bom.Components {
+			for _, l := range c.Licenses {
+				expressions = append(expressions, l.License.ID, l.License.Name, l.Expression)
+			}
+		}
+	default:
+		return nil, nil
+	}
+
+	var licenses []string
+	for _, e := range expressions {
+		licenses = append(licenses, licenseIDs(e)...)
+	}
+	return licenses, nil
+}
+
+// licenseIDs returns the license identifiers of an SPDX license expression like (MIT OR GPL-2.0-only WITH
+// Classpath-exception-2.0). Operators, exceptions and the special values NONE and NOASSERTION are skipped.
+func licenseIDs(expression string) []string {
+	var ids []string
+	fields := strings.FieldsFunc(expression, func(r rune) bool { return r == ' ' || r == '(' || r == ')' })
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "AND", "OR", "NONE", "NOASSERTION":
+		case "WITH":
+			i++
+		default:
+			ids = append(ids, fields[i])
+		}
+	}
+	return ids
+}
@@ -0,0 +1,153 @@
+package webhook
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// attestation returns a DSSE envelope of an in-toto statement with passed predicate
+func attestation(t *testing.T, predicateType string, predicate any) []byte {
+	t.Helper()
+	statement, err := json.Marshal(map[string]any{
+		"_type":         "https://in-toto.io/Statement/v0.1",
+		"predicateType": predicateType,
+		"predicate":     predicate,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope, err := json.Marshal(map[string]any{
+		"payloadType": "application/vnd.in-toto+json",
+		"payload":     base64.StdEncoding.EncodeToString(statement),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return envelope
+}
+
+func Test_sbomLicenses(t *testing.T) {
+	tests := []struct {
+		name     string
+		envelope []byte
+		want     []string
+	}{
+		{
+			name: "spdx",
+			envelope: attestation(t, spdxPredicateType, map[string]any{
+				"packages": []map[string]string{
+					{"licenseConcluded": "MIT", "licenseDeclared": "NOASSERTION"},
+					{"licenseDeclared": "(GPL-2.0-only WITH Classpath-exception-2.0 OR Apache-2.0)"},
+				},
+			}),
+			want: []string{"MIT", "GPL-2.0-only", "Apache-2.0"},
+		},
+		{
+			name: "cyclonedx",
+			envelope: attestation(t, cycloneDXPredicateType, map[string]any{
+				"components": []map[string]any{
+					{"licenses": []map[string]any{{"license": map[string]string{"id": "AGPL-3.0-only"}}}},
+					{"licenses": []map[string]any{{"expression": "MIT AND BSD-3-Clause"}}},
+				},
+			}),
+			want: []string{"AGPL-3.0-only", "MIT", "BSD-3-Clause"},
+		},
+		{
+			name:     "other predicate",
+			envelope: attestation(t, "https://slsa.dev/provenance/v0.2", map[string]any{"builder": map[string]string{"id": "ci"}}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sbomLicenses(tt.envelope)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sbomLicenses() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_licensesRule_Validate(t *testing.T) {
+	const image = "ghcr.io/eumel8/app@sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+	cfg := LicensesConfig{
+		Denied:     []string{"AGPL-.*"},
+		Namespaces: map[string]LicensePolicy{"restricted": {Allowed: []string{"MIT", "Apache-2.0"}}},
+	}
+	tests := []struct {
+		name      string
+		namespace string
+		licenses  []string
+		pubKey    string
+		wantErr   string
+	}{
+		{
+			name:     "allowed license",
+			licenses: []string{"MIT"},
+			pubKey:   "key",
+		},
+		{
+			name:     "denied license",
+			licenses: []string{"AGPL-3.0-only", "MIT"},
+			pubKey:   "key",
+			wantErr:  "container \"app\" uses image \"" + image + "\" with denied license AGPL-3.0-only",
+		},
+		{
+			name:     "without public key",
+			licenses: []string{"AGPL-3.0-only"},
+		},
+		{
+			name:      "namespace policy replaces global one",
+			namespace: "restricted",
+			licenses:  []string{"AGPL-3.0-only", "MIT"},
+			pubKey:    "key",
+			wantErr:   "with license AGPL-3.0-only, which is not allowed in namespace \"restricted\"",
+		},
+		{
+			name:      "namespace policy allows license",
+			namespace: "restricted",
+			licenses:  []string{"Apache-2.0"},
+			pubKey:    "key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := newLicensesRule(&CosignServerHandler{}, &Config{Licenses: cfg})
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := rule.(*licensesRule)
+			r.put("sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae", tt.licenses, time.Now())
+
+			ns := tt.namespace
+			if ns == "" {
+				ns = "default"
+			}
+			c := corev1.Container{Name: "app", Image: image}
+			if tt.pubKey != "" {
+				c.Env = []corev1.EnvVar{{Name: CosignEnvVar, Value: tt.pubKey}}
+			}
+			_, err = r.Validate(context.Background(), podObject(ns, corev1.PodSpec{Containers: []corev1.Container{c}}))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
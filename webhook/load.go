@@ -0,0 +1,130 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	log "github.com/gookit/slog"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// OverflowRuleName is reported as rule of decisions denied by overflow deny
+const OverflowRuleName = "overflow"
+
+var (
+	inflightRequests = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "cosign_inflight_requests",
+		Help: "The number of admission requests in flight on this replica, e.g. as HPA custom metric",
+	})
+	overflowed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cosign_overflow_total",
+		Help: "The number of admission requests over the maximum in flight, by overflow behavior",
+	}, []string{"overflow"})
+	saturation = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "cosign_saturation_ratio",
+		Help: "The admission requests in flight on this replica relative to the target, above 1 the replica is overloaded",
+	})
+)
+
+// startRequest counts an admission request in flight until the returned func is called
+func (csh *CosignServerHandler) startRequest() func() {
+	csh.updateLoad(csh.inflight.Add(1))
+	return func() {
+		csh.updateLoad(csh.inflight.Add(-1))
+	}
+}
+
+func (csh *CosignServerHandler) updateLoad(inflight int64) {
+	inflightRequests.Set(float64(inflight))
+	if csh.targetInflight > 0 {
+		saturation.Set(float64(inflight) / float64(csh.targetInflight))
+	}
+}
+
+// overloaded returns true if the requests in flight reached the target
+func (csh *CosignServerHandler) overloaded() bool {
+	return csh.targetInflight > 0 && csh.inflight.Load() >= int64(csh.targetInflight)
+}
+
+// Readyz is called by /readyz for readiness checks. It returns 503 while the replica is overloaded, so the Service
+// sheds load to other replicas, or if the serving certificate doesn't match the webhook configuration, and 'ok'
+// otherwise.
+func (csh *CosignServerHandler) Readyz(w http.ResponseWriter, _ *http.Request) {
+	if csh.trustErr != nil {
+		http.Error(w, csh.trustErr.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if csh.overloaded() {
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+		return
+	}
+	if _, err := w.Write([]byte("ok")); err != nil {
+		log.Errorf("Can't write response: %v", err)
+	}
+}
+
+// overflowLimit limits the admission requests evaluated at the same time
+type overflowLimit struct {
+	slots    chan struct{}
+	behavior string
+	deadline time.Duration
+}
+
+// newOverflowLimit returns the limit of the server settings, or nil if there is no maximum
+func newOverflowLimit(s ServerConfig) (*overflowLimit, error) {
+	limit, err := s.MaxInflightRequests()
+	if err != nil || limit == 0 {
+		return nil, err
+	}
+	behavior, deadline, err := s.OverflowPolicy()
+	if err != nil {
+		return nil, err
+	}
+	return &overflowLimit{slots: make(chan struct{}, limit), behavior: behavior, deadline: deadline}, nil
+}
+
+// acquire takes a slot and returns the func to free it. Without free slot, it waits up to the deadline with
+// overflow queue and returns false if there is still none.
+func (l *overflowLimit) acquire(ctx context.Context) (func(), bool) {
+	release := func() { <-l.slots }
+	select {
+	case l.slots <- struct{}{}:
+		return release, true
+	default:
+	}
+	if l.behavior == OverflowQueue {
+		timer := time.NewTimer(l.deadline)
+		defer timer.Stop()
+		select {
+		case l.slots <- struct{}{}:
+			return release, true
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+	}
+	overflowed.WithLabelValues(l.behavior).Inc()
+	return nil, false
+}
+
+// overflow answers an admission request over the maximum in flight with the overflow behavior
+func (e *Endpoint) overflow(w http.ResponseWriter, o *Object) {
+	l := e.csh.limit
+	log.Warnf("Overflow %s for %s %s/%s on %s", l.behavior, o.Request.Kind.Kind, o.Request.Namespace, o.Request.Name, e.Path)
+	d := newDecision(e.Path, o.Request, e.csh.semantics)
+	switch l.behavior {
+	case OverflowAllow:
+		d.Allowed, d.Message = true, "Admitted without validation, the webhook is overloaded"
+		d.Warnings = []string{d.Message}
+		e.csh.recordDecision(d)
+		accept(w, d.Message, o.Request.UID, d.Warnings...)
+	case OverflowDeny:
+		d.Rule, d.Message = OverflowRuleName, "the webhook is overloaded, retry later"
+		e.csh.recordDecision(d)
+		deny(w, d.Message, o.Request.UID)
+	default:
+		http.Error(w, "no free slot within the overflow deadline", http.StatusTooManyRequests)
+	}
+}
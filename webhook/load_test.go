@@ -0,0 +1,76 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCosignServerHandler_Readyz(t *testing.T) {
+	csh := &CosignServerHandler{targetInflight: 2}
+	ready := func() int {
+		rec := httptest.NewRecorder()
+		csh.Readyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rec.Code
+	}
+
+	done := csh.startRequest()
+	if got := ready(); got != http.StatusOK {
+		t.Errorf("Readyz() below target = %d, want %d", got, http.StatusOK)
+	}
+	doneOverload := csh.startRequest()
+	if got := ready(); got != http.StatusServiceUnavailable {
+		t.Errorf("Readyz() at target = %d, want %d", got, http.StatusServiceUnavailable)
+	}
+	if got := testutil.ToFloat64(saturation); got != 1 {
+		t.Errorf("cosign_saturation_ratio = %v, want 1", got)
+	}
+	doneOverload()
+	done()
+	if got := ready(); got != http.StatusOK {
+		t.Errorf("Readyz() after requests = %d, want %d", got, http.StatusOK)
+	}
+	if got := testutil.ToFloat64(inflightRequests); got != 0 {
+		t.Errorf("cosign_inflight_requests = %v, want 0", got)
+	}
+
+	if _, err := (ServerConfig{TargetInflight: "many"}).TargetInflightRequests(); err == nil {
+		t.Error("TargetInflightRequests() of invalid value = nil, want error")
+	}
+}
+
+func Test_overflowLimit(t *testing.T) {
+	if l, err := newOverflowLimit(DefaultServerConfig()); l != nil || err != nil {
+		t.Fatalf("newOverflowLimit() without maximum = %v, %v, want nil", l, err)
+	}
+	s := DefaultServerConfig()
+	s.MaxInflight, s.OverflowDeadline = "1", "10ms"
+	l, err := newOverflowLimit(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release, ok := l.acquire(context.Background())
+	if !ok {
+		t.Fatal("acquire() of free slot failed")
+	}
+	if _, ok := l.acquire(context.Background()); ok {
+		t.Error("acquire() over the maximum succeeded after the deadline")
+	}
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		release()
+	}()
+	l.deadline = time.Second
+	if _, ok := l.acquire(context.Background()); !ok {
+		t.Error("acquire() with overflow queue didn't get the freed slot")
+	}
+
+	s.Overflow = "drop"
+	if _, err := newOverflowLimit(s); err == nil {
+		t.Error("newOverflowLimit() with invalid overflow = nil, want error")
+	}
+}
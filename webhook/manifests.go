@@ -0,0 +1,175 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// formats of the generated installation manifests
+const (
+	// ManifestFormatRaw is plain YAML with ${CA_BUNDLE}, ${TLS_CRT} and ${TLS_KEY} placeholders, e.g. for envsubst
+	ManifestFormatRaw = "raw"
+	// ManifestFormatYtt is a ytt template with a data values schema for the certificates
+	ManifestFormatYtt = "ytt"
+	// ManifestFormatJsonnet is a jsonnet function taking the certificates as top-level arguments
+	ManifestFormatJsonnet = "jsonnet"
+)
+
+// ManifestFormats are the supported formats of the installation manifests
+var ManifestFormats = []string{ManifestFormatRaw, ManifestFormatYtt, ManifestFormatJsonnet}
+
+// placeholders of the certificates in the installation manifests and the names of their values
+var manifestPlaceholders = []struct {
+	placeholder string
+	value       string
+	description string
+}{
+	{"${CA_BUNDLE}", "caBundle", "base64 encoded PEM CA bundle the API server verifies the webhook with"},
+	{"${TLS_CRT}", "tlsCrt", "base64 encoded PEM serving certificate of the webhook"},
+	{"${TLS_KEY}", "tlsKey", "base64 encoded PEM private key of the serving certificate"},
+}
+
+// ManifestOptions are the options of the installation manifests
+type ManifestOptions struct {
+	// Format is raw, ytt or jsonnet
+	Format string
+	// Namespace the webhook is deployed to
+	Namespace string
+	// Image of the webhook
+	Image string
+	// Namespaces the webhook validates, empty validates all namespaces but the own one and kube-system
+	Namespaces []string
+	// Config is the policy of the webhook, empty uses the default configuration
+	Config []byte
+	// FailurePolicy of the webhook configuration, Fail or Ignore
+	FailurePolicy string
+}
+
+// ManifestFile is a generated file of the installation manifests
+type ManifestFile struct {
+	Name string
+	Data []byte
+}
+
+// GenerateManifests generates the manifests installing the webhook without Helm.
+// The webhook certificate and its CA bundle are left as placeholders to be filled by the tool of the format.
+func GenerateManifests(opts ManifestOptions) ([]ManifestFile, error) {
+	if opts.Namespace == "" {
+		opts.Namespace = DefaultStarterNamespace
+	}
+	if opts.Image == "" {
+		opts.Image = DefaultStarterImage
+	}
+	if len(opts.Config) == 0 {
+		opts.Config = defaultConfig
+	}
+	switch opts.FailurePolicy {
+	case "":
+		opts.FailurePolicy = "Fail"
+	case "Fail", "Ignore":
+	default:
+		return nil, fmt.Errorf("unknown failure policy %q, must be Fail or Ignore", opts.FailurePolicy)
+	}
+	if _, err := ParseConfig(opts.Config); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	header := `# Installation manifests of cosignwebhook generated by "cosignwebhook gen-manifests".`
+	switch opts.Format {
+	case ManifestFormatRaw, "":
+		var lines []string
+		for _, p := range manifestPlaceholders {
+			// not the placeholder itself, the comment would be filled with the key
+			lines = append(lines, fmt.Sprintf("# %s: %s", strings.Trim(p.placeholder, "${}"), p.description))
+		}
+		header += "\n# Replace the placeholders before applying, e.g. with envsubst '$CA_BUNDLE $TLS_CRT $TLS_KEY':\n" + strings.Join(lines, "\n")
+	case ManifestFormatYtt:
+		header += "\n# Render with: ytt -f . --data-value caBundle=... --data-value tlsCrt=... --data-value tlsKey=..."
+	case ManifestFormatJsonnet:
+		header += "\n# Render with: jsonnet -y --tla-str caBundle=... --tla-str tlsCrt=... --tla-str tlsKey=... cosignwebhook.jsonnet"
+	default:
+		return nil, fmt.Errorf("unknown format %q, must be one of %s", opts.Format, strings.Join(ManifestFormats, ", "))
+	}
+	manifests, err := renderManifests(map[string]any{
+		"Header":        header,
+		"Namespace":     opts.Namespace,
+		"Namespaces":    opts.Namespaces,
+		"Image":         opts.Image,
+		"Config":        string(opts.Config),
+		"FailurePolicy": opts.FailurePolicy,
+		"CABundle":      manifestPlaceholders[0].placeholder,
+		"Cert":          manifestPlaceholders[1].placeholder,
+		"Key":           manifestPlaceholders[2].placeholder,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch opts.Format {
+	case ManifestFormatYtt:
+		return yttManifests(manifests), nil
+	case ManifestFormatJsonnet:
+		return jsonnetManifests(manifests)
+	default:
+		return []ManifestFile{{Name: "cosignwebhook.yaml", Data: manifests}}, nil
+	}
+}
+
+// yttManifests turns the placeholders into data values and adds the schema of the values.
+// ytt rejects plain comments, so the header is turned into ytt comments.
+func yttManifests(manifests []byte) []ManifestFile {
+	var tmpl bytes.Buffer
+	tmpl.WriteString("#@ load(\"@ytt:data\", \"data\")\n")
+	for _, line := range strings.SplitAfter(string(manifests), "\n") {
+		if strings.HasPrefix(line, "# ") {
+			line = "#!" + line[1:]
+		}
+		for _, p := range manifestPlaceholders {
+			line = strings.Replace(line, p.placeholder, "#@ data.values."+p.value, 1)
+		}
+		tmpl.WriteString(line)
+	}
+	schema := []string{"#@data/values-schema", "---"}
+	for _, p := range manifestPlaceholders {
+		schema = append(schema, "#@schema/desc \""+p.description+"\"", p.value+": \"\"")
+	}
+	return []ManifestFile{
+		{Name: "cosignwebhook.yaml", Data: tmpl.Bytes()},
+		{Name: "schema.yaml", Data: []byte(strings.Join(schema, "\n") + "\n")},
+	}
+}
+
+// jsonnetManifests converts the manifests to a jsonnet function with the placeholders as top-level arguments
+func jsonnetManifests(manifests []byte) ([]ManifestFile, error) {
+	docs := strings.Split(string(manifests), "\n---\n")
+	var out bytes.Buffer
+	for _, line := range strings.Split(docs[0], "\n") {
+		out.WriteString("//" + strings.TrimPrefix(line, "#") + "\n")
+	}
+	args := make([]string, 0, len(manifestPlaceholders))
+	for _, p := range manifestPlaceholders {
+		args = append(args, p.value)
+	}
+	fmt.Fprintf(&out, "function(%s) [\n", strings.Join(args, ", "))
+	for _, d := range docs[1:] {
+		j, err := yaml.YAMLToJSON([]byte(d))
+		if err != nil {
+			return nil, err
+		}
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, j, "  ", "  "); err != nil {
+			return nil, err
+		}
+		doc := indented.String()
+		for _, p := range manifestPlaceholders {
+			doc = strings.ReplaceAll(doc, `"`+p.placeholder+`"`, p.value)
+		}
+		out.WriteString("  " + doc + ",\n")
+	}
+	out.WriteString("]\n")
+	return []ManifestFile{{Name: "cosignwebhook.jsonnet", Data: out.Bytes()}}, nil
+}
@@ -0,0 +1,140 @@
+package webhook
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+func TestGenerateManifests(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      ManifestOptions
+		wantFiles []string
+		wantErr   bool
+	}{
+		{
+			name:      "raw",
+			opts:      ManifestOptions{Namespace: "security", Namespaces: []string{"team-a"}},
+			wantFiles: []string{"cosignwebhook.yaml"},
+		},
+		{
+			name:      "ytt",
+			opts:      ManifestOptions{Format: ManifestFormatYtt, FailurePolicy: "Ignore"},
+			wantFiles: []string{"cosignwebhook.yaml", "schema.yaml"},
+		},
+		{
+			name:      "jsonnet",
+			opts:      ManifestOptions{Format: ManifestFormatJsonnet, Config: []byte("endpoints:\n- path: /validate\n  rules: [sanity]\n")},
+			wantFiles: []string{"cosignwebhook.jsonnet"},
+		},
+		{
+			name:    "unknown format",
+			opts:    ManifestOptions{Format: "kustomize"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown failure policy",
+			opts:    ManifestOptions{FailurePolicy: "Retry"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid config",
+			opts:    ManifestOptions{Config: []byte("endpoints:\n- path: /validate\n  rules: [magic]\n")},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := GenerateManifests(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateManifests() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			var names []string
+			for _, f := range files {
+				names = append(names, f.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.wantFiles, ",") {
+				t.Fatalf("files = %v, want %v", names, tt.wantFiles)
+			}
+			data := string(files[0].Data)
+			for _, p := range manifestPlaceholders {
+				switch tt.opts.Format {
+				case ManifestFormatYtt:
+					if !strings.Contains(data, "#@ data.values."+p.value) || !strings.Contains(string(files[1].Data), p.value+":") {
+						t.Errorf("data value %s missing", p.value)
+					}
+				case ManifestFormatJsonnet:
+					if !strings.Contains(data, ": "+p.value) {
+						t.Errorf("argument %s isn't used", p.value)
+					}
+				default:
+					if !strings.Contains(data, ": "+p.placeholder+"\n") {
+						t.Errorf("placeholder %s missing", p.placeholder)
+					}
+				}
+			}
+			if tt.opts.Format != "" {
+				return
+			}
+
+			// the raw manifests are valid once the placeholders are replaced
+			for _, p := range manifestPlaceholders {
+				data = strings.ReplaceAll(data, p.placeholder, "Zm9v")
+			}
+			kinds := map[string]bool{}
+			for _, d := range strings.Split(data, "\n---\n")[1:] {
+				o := map[string]any{}
+				if err := yaml.Unmarshal([]byte(d), &o); err != nil {
+					t.Fatalf("invalid manifest: %v\n%s", err, d)
+				}
+				kinds[o["kind"].(string)] = true
+				if o["kind"] == "ValidatingWebhookConfiguration" {
+					j, _ := json.Marshal(o)
+					if !strings.Contains(string(j), `"namespace":"security"`) || !strings.Contains(string(j), `"values":["team-a"]`) {
+						t.Errorf("unexpected webhook configuration %s", j)
+					}
+				}
+			}
+			for _, k := range []string{"Namespace", "ClusterRole", "ClusterRoleBinding", "ServiceAccount", "Secret", "ConfigMap", "Service", "Deployment", "ValidatingWebhookConfiguration"} {
+				if !kinds[k] {
+					t.Errorf("manifest of %s missing", k)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateManifests_references(t *testing.T) {
+	for _, config := range []string{"", "endpoints:\n- path: /validate\n  rules: [cosign, references]\n"} {
+		files, err := GenerateManifests(ManifestOptions{Config: []byte(config)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		granted := strings.Contains(string(files[0].Data), "    - configmaps\n    - secrets\n    verbs:\n    - list\n    - watch\n")
+		if want := config != ""; granted != want {
+			t.Errorf("list and watch of configmaps and secrets granted = %v for config %q, want %v", granted, config, want)
+		}
+	}
+}
+
+func TestGenerateManifests_namingConfigMap(t *testing.T) {
+	for _, config := range []string{"", "naming:\n  configMap: platform/naming-conventions\n"} {
+		files, err := GenerateManifests(ManifestOptions{Config: []byte(config)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := string(files[0].Data)
+		granted := strings.Contains(data, "kind: Role\nmetadata:\n  name: cosignwebhook-naming\n  namespace: platform\n") &&
+			strings.Contains(data, "    resourceNames:\n    - naming-conventions\n    verbs:\n    - get\n    - list\n    - watch\n")
+		if want := config != ""; granted != want {
+			t.Errorf("get, list and watch of the naming ConfigMap granted = %v for config %q, want %v", granted, config, want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package webhook
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// containerField is a string field of the containers of pods and pod templates matched by field policies
+type containerField int
+
+const (
+	// fieldEnvName are the names of the env vars of the containers
+	fieldEnvName containerField = iota
+	// fieldImageTag are the explicit tags of the images of the containers referenced by tag
+	fieldImageTag
+)
+
+// fieldPolicy matches a field of the containers against the patterns of a rule
+type fieldPolicy struct {
+	field    containerField
+	patterns *patternSet
+}
+
+// fieldPolicyRule is implemented by rules matching fields of the containers against patterns. The policies of all
+// rules of an endpoint are compiled into one matcher, which matches them in a single traversal of the object.
+type fieldPolicyRule interface {
+	Rule
+	fieldPolicies() []fieldPolicy
+}
+
+// fieldMatch is a value of a field of a container matching a policy
+type fieldMatch struct {
+	container string
+	value     string
+}
+
+// fieldMatches are the values of the fields of an object matching the policies, by the patterns of the policy
+type fieldMatches map[*patternSet]map[fieldMatch]bool
+
+// policyMatcher is the compiled field policies of the rules of an endpoint. The patterns of all policies of a field
+// are merged into one expression, so a value matching no policy, the common case, is rejected in a single pass.
+type policyMatcher struct {
+	fields map[containerField]*fieldMatcher
+}
+
+// fieldMatcher matches the values of a field against the policies of the field
+type fieldMatcher struct {
+	any      *regexp.Regexp
+	patterns []*patternSet
+}
+
+// newPolicyMatcher compiles the field policies of the rules, or returns nil if the rules have none
+func newPolicyMatcher(rules []Rule) (*policyMatcher, error) {
+	patterns := map[containerField][]*patternSet{}
+	for _, r := range rules {
+		pr, ok := unwrapRule(r).(fieldPolicyRule)
+		if !ok {
+			continue
+		}
+		for _, p := range pr.fieldPolicies() {
+			if !p.patterns.empty() {
+				patterns[p.field] = append(patterns[p.field], p.patterns)
+			}
+		}
+	}
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	m := &policyMatcher{fields: map[containerField]*fieldMatcher{}}
+	for field, sets := range patterns {
+		alternatives := make([]string, 0, len(sets))
+		for _, s := range sets {
+			alternatives = append(alternatives, s.re.String())
+		}
+		re, err := regexp.Compile(strings.Join(alternatives, "|"))
+		if err != nil {
+			return nil, err
+		}
+		m.fields[field] = &fieldMatcher{any: re, patterns: sets}
+	}
+	return m, nil
+}
+
+// match returns the values of the fields of the containers of the object matching the policies
+func (m *policyMatcher) match(o *Object) fieldMatches {
+	matches := fieldMatches{}
+	spec := podSpec(o)
+	if spec == nil {
+		return matches
+	}
+	for _, fm := range m.fields {
+		for _, s := range fm.patterns {
+			matches[s] = map[fieldMatch]bool{}
+		}
+	}
+	eachContainerField(spec, func(field containerField, v fieldMatch) {
+		fm, ok := m.fields[field]
+		if !ok || !fm.any.MatchString(v.value) {
+			return
+		}
+		for _, s := range fm.patterns {
+			if s.matches(v.value) {
+				matches[s][v] = true
+			}
+		}
+	})
+	return matches
+}
+
+// eachContainerField calls fn for the values of all fields of all containers of the pod spec
+func eachContainerField(spec *corev1.PodSpec, fn func(containerField, fieldMatch)) {
+	for _, c := range podContainers(spec) {
+		for _, e := range c.Env {
+			fn(fieldEnvName, fieldMatch{container: c.Name, value: e.Name})
+		}
+		// the default tag latest is filled in by the parser, so only explicit tags are matched
+		ref, err := name.ParseReference(c.Image)
+		if tag, ok := ref.(name.Tag); err == nil && ok && strings.Contains(c.Image[strings.LastIndex(c.Image, "/")+1:], ":") {
+			fn(fieldImageTag, fieldMatch{container: c.Name, value: tag.TagStr()})
+		}
+	}
+}
+
+// matched returns the values of the object matching the policy. They are computed by the matcher of the endpoint,
+// or by traversing the object if the policy isn't compiled into it, e.g. for rules evaluated on their own.
+func (o *Object) matched(p fieldPolicy) map[fieldMatch]bool {
+	if v, ok := o.matches[p.patterns]; ok {
+		return v
+	}
+	matched := map[fieldMatch]bool{}
+	spec := podSpec(o)
+	if spec == nil || p.patterns.empty() {
+		return matched
+	}
+	eachContainerField(spec, func(field containerField, v fieldMatch) {
+		if field == p.field && p.patterns.matches(v.value) {
+			matched[v] = true
+		}
+	})
+	return matched
+}
@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"context"
+	"maps"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_policyMatcher(t *testing.T) {
+	cfg := &Config{
+		Env:       EnvConfig{ForbiddenNames: []string{"KUBERNETES_.*"}, ForbiddenLiterals: []string{".*_SECRET.*"}},
+		ImageTags: ImageTagsConfig{ForbiddenTags: []string{"latest", "main"}},
+	}
+	env, err := newEnvRule(nil, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags, err := newImageTagsRule(nil, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := newPolicyMatcher([]Rule{env, tags, &countingRule{name: SanityRuleName}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m2, _ := newPolicyMatcher([]Rule{&countingRule{name: SanityRuleName}}); m2 != nil {
+		t.Errorf("newPolicyMatcher() without field policies = %+v, want nil", m2)
+	}
+
+	o := podObject("default", corev1.PodSpec{
+		InitContainers: []corev1.Container{{Name: "init", Image: "busybox:main"}},
+		Containers: []corev1.Container{{
+			Name:  "app",
+			Image: "registry.example.com:5000/app:latest",
+			Env: []corev1.EnvVar{
+				{Name: "KUBERNETES_SERVICE_HOST", Value: "10.0.0.1"},
+				{Name: "AWS_SECRET_ACCESS_KEY", Value: "abc"},
+				{Name: "LOG_LEVEL", Value: "debug"},
+			},
+		}, {Name: "sidecar", Image: "envoy:1.31"}},
+	})
+	matches := m.match(o)
+	want := map[*patternSet]map[fieldMatch]bool{
+		env.(*envRule).forbiddenNames:    {{container: "app", value: "KUBERNETES_SERVICE_HOST"}: true},
+		env.(*envRule).forbiddenLiterals: {{container: "app", value: "AWS_SECRET_ACCESS_KEY"}: true},
+		tags.(*imageTagsRule).forbidden:  {{container: "init", value: "main"}: true, {container: "app", value: "latest"}: true},
+	}
+	if len(matches) != len(want) {
+		t.Fatalf("match() = %v, want %v", matches, want)
+	}
+	for s, w := range want {
+		if !maps.Equal(matches[s], w) {
+			t.Errorf("match() of %s = %v, want %v", s.re, matches[s], w)
+		}
+	}
+
+	// the rules decide alike with the matches of the matcher and on their own
+	for _, r := range []Rule{env, tags} {
+		_, alone := r.Validate(context.Background(), o)
+		matched := *o
+		matched.matches = matches
+		_, compiled := r.Validate(context.Background(), &matched)
+		if alone == nil || compiled == nil || alone.Error() != compiled.Error() {
+			t.Errorf("%s: Validate() with matcher = %v, on its own = %v, want the same denial", r.Name(), compiled, alone)
+		}
+	}
+}
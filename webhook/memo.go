@@ -0,0 +1,172 @@
+package webhook
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	v1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// maxMemoEntries bounds the verdicts memoized by an endpoint
+const maxMemoEntries = 10000
+
+var memoHits = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "cosign_memo_hits_total",
+	Help: "The number of pod admissions decided by the memoized verdict of an identical pod of the same ReplicaSet",
+}, []string{"endpoint"})
+
+// MemoizeConfig configures the memoization of the verdicts of pods created by ReplicaSets
+type MemoizeConfig struct {
+	// TTL of a memoized verdict, e.g. 1m. Zero disables the memoization.
+	TTL metav1.Duration `json:"ttl"`
+}
+
+// statefulRule is implemented by rules whose verdict depends on state changing between identical pods, e.g. the
+// quota usage of the namespace. Their verdicts aren't memoized, they are evaluated again for each memoized verdict.
+type statefulRule interface {
+	Rule
+	stateful()
+}
+
+// memoEntry is a memoized verdict
+type memoEntry struct {
+	allowed  bool
+	rule     string
+	message  string
+	warnings []string
+	trace    []RuleTrace
+	expires  time.Time
+}
+
+// memo reuses the verdict of the first pod of a ReplicaSet for the identical pods created after it within the TTL,
+// e.g. during a scale-up. Pods are identical if they have the same owner, pod-template-hash, labels, annotations
+// and spec.
+type memo struct {
+	ttl time.Duration
+	// stateful rules of the endpoint and the plan of their facts
+	stateful []Rule
+	facts    *factPlan
+
+	mu      sync.Mutex
+	entries map[string]memoEntry
+}
+
+// newMemo returns the memo of the rules of an endpoint, or nil if memoization is disabled
+func newMemo(cfg MemoizeConfig, rules []Rule, defs map[Fact]factDef) (*memo, error) {
+	if cfg.TTL.Duration <= 0 {
+		return nil, nil
+	}
+	m := &memo{ttl: cfg.TTL.Duration, entries: map[string]memoEntry{}}
+	for _, r := range rules {
+		if _, ok := unwrapRule(r).(statefulRule); ok {
+			m.stateful = append(m.stateful, r)
+		}
+	}
+	var err error
+	if m.facts, err = newFactPlan(m.stateful, defs); err != nil {
+		return nil, err
+	}
+	if m.facts != nil {
+		m.facts.prepare()
+	}
+	return m, nil
+}
+
+// key returns the key of the pod, or an empty string if its verdict isn't memoized
+func (m *memo) key(o *Object) string {
+	if o.Pod == nil || o.Request.Operation != v1.Create {
+		return ""
+	}
+	hash := o.Pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]
+	owner := metav1.GetControllerOf(o.Pod)
+	if hash == "" || owner == nil || owner.Kind != "ReplicaSet" {
+		return ""
+	}
+	data, err := json.Marshal([]any{o.Pod.Labels, o.Pod.Annotations, o.Pod.Spec})
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return o.Request.Namespace + "/" + string(owner.UID) + "/" + hash + "/" + hex.EncodeToString(sum[:])
+}
+
+// get returns the memoized verdict of the key as decision of the request, or nil if there is none
+func (m *memo) get(key string, path string, req *v1.AdmissionRequest, semantics int) *Decision {
+	if key == "" {
+		return nil
+	}
+	m.mu.Lock()
+	e, ok := m.entries[key]
+	m.mu.Unlock()
+	if !ok || time.Now().After(e.expires) {
+		return nil
+	}
+	memoHits.WithLabelValues(path).Inc()
+	d := newDecision(path, req, semantics)
+	d.Allowed, d.Rule, d.Message, d.Warnings = e.allowed, e.rule, e.message, slices.Clone(e.warnings)
+	d.Trace = slices.Clone(e.trace)
+	d.Memoized = true
+	return d
+}
+
+// recheck evaluates the stateful rules for the memoized decision of the object. It returns their decision if they
+// deny, else the memoized decision with the rules failed in the evaluation.
+func (m *memo) recheck(ctx context.Context, o *Object, d *Decision, csh *CosignServerHandler) *Decision {
+	if !d.Allowed || len(m.stateful) == 0 {
+		return d
+	}
+	if m.facts != nil {
+		o.Facts = m.facts.compute(ctx, o)
+	}
+	s := evaluateRules(ctx, o, d.Endpoint, m.stateful, csh.semantics, csh.onError, nil)
+	if !s.Allowed {
+		return s
+	}
+	d.FailedRules = append(d.FailedRules, s.FailedRules...)
+	return d
+}
+
+// flush drops all memoized verdicts
+func (m *memo) flush() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clear(m.entries)
+}
+
+// put memoizes the verdict of the decision for the key. Verdicts of failed and stateful rules aren't memoized, so
+// the next pod is evaluated again.
+func (m *memo) put(key string, d *Decision) {
+	if key == "" || len(d.FailedRules) > 0 || slices.ContainsFunc(m.stateful, func(r Rule) bool { return r.Name() == d.Rule }) {
+		return
+	}
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.entries) >= maxMemoEntries {
+		for k, e := range m.entries {
+			if now.After(e.expires) {
+				delete(m.entries, k)
+			}
+		}
+		if len(m.entries) >= maxMemoEntries {
+			return
+		}
+	}
+	m.entries[key] = memoEntry{
+		allowed:  d.Allowed,
+		rule:     d.Rule,
+		message:  d.Message,
+		warnings: slices.Clone(d.Warnings),
+		trace:    slices.Clone(d.Trace),
+		expires:  now.Add(m.ttl),
+	}
+}
@@ -0,0 +1,106 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// replicaSetPod returns the object of a pod created by the ReplicaSet
+func replicaSetPod(name, image string) *Object {
+	o := podObject("default", corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}})
+	o.Request.Operation, o.Request.Name, o.Request.UID = v1.Create, name, types.UID("uid-"+name)
+	o.Pod.Name = name
+	o.Pod.Labels = map[string]string{"app": "web", "pod-template-hash": "5d8f7"}
+	controller := true
+	o.Pod.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-5d8f7", UID: "rs-uid", Controller: &controller}}
+	return o
+}
+
+func Test_memo(t *testing.T) {
+	if m, _ := newMemo(MemoizeConfig{}, nil, nil); m != nil {
+		t.Fatal("newMemo() disabled != nil")
+	}
+	m, err := newMemo(MemoizeConfig{TTL: metav1.Duration{Duration: time.Minute}}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := replicaSetPod("web-5d8f7-abcde", "nginx:1.27")
+	key := m.key(first)
+	if key == "" {
+		t.Fatal("key() of ReplicaSet pod is empty")
+	}
+	if d := m.get(key, DefaultPath, first.Request, SemanticsVersion); d != nil {
+		t.Fatalf("get() before put = %+v, want nil", d)
+	}
+	m.put(key, &Decision{Rule: CosignRuleName, Message: "no signature", Warnings: []string{"deprecated"}})
+
+	second := replicaSetPod("web-5d8f7-fghij", "nginx:1.27")
+	d := m.get(m.key(second), DefaultPath, second.Request, SemanticsVersion)
+	if d == nil || !d.Memoized || d.Allowed || d.Rule != CosignRuleName || d.Name != "web-5d8f7-fghij" || len(d.Warnings) != 1 {
+		t.Errorf("get() of identical pod = %+v, want memoized verdict for the pod", d)
+	}
+
+	if k := m.key(replicaSetPod("web-5d8f7-klmno", "nginx:1.28")); k == key {
+		t.Error("key() of pod with other spec equals the key of the first pod")
+	}
+	bare := podObject("default", corev1.PodSpec{})
+	bare.Request.Operation = v1.Create
+	if k := m.key(bare); k != "" {
+		t.Errorf("key() of bare pod = %q, want empty", k)
+	}
+
+	m.entries[key] = memoEntry{expires: time.Now().Add(-time.Second)}
+	if d := m.get(key, DefaultPath, second.Request, SemanticsVersion); d != nil {
+		t.Errorf("get() of expired verdict = %+v, want nil", d)
+	}
+}
+
+// statefulCountingRule is a counting rule whose verdicts aren't memoized
+type statefulCountingRule struct {
+	countingRule
+}
+
+func (*statefulCountingRule) stateful() {}
+
+func Test_memo_stateful(t *testing.T) {
+	quota := &statefulCountingRule{countingRule{name: QuotaRuleName}}
+	cosign := &countingRule{name: CosignRuleName}
+	csh := &CosignServerHandler{semantics: SemanticsVersion}
+	m, err := newMemo(MemoizeConfig{TTL: metav1.Duration{Duration: time.Minute}}, []Rule{&watchdogRule{Rule: quota, limit: time.Minute}, cosign}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.stateful) != 1 || m.stateful[0].Name() != QuotaRuleName {
+		t.Fatalf("stateful = %v, want %s", m.stateful, QuotaRuleName)
+	}
+
+	first := replicaSetPod("web-5d8f7-abcde", "nginx:1.27")
+	key := m.key(first)
+	m.put(key, &Decision{Allowed: true})
+
+	second := replicaSetPod("web-5d8f7-fghij", "nginx:1.27")
+	d := m.recheck(context.Background(), second, m.get(key, DefaultPath, second.Request, SemanticsVersion), csh)
+	if !d.Allowed || !d.Memoized || quota.calls != 1 || cosign.calls != 0 {
+		t.Errorf("recheck() = %+v with %d quota and %d cosign calls, want memoized admission rechecked by quota", d, quota.calls, cosign.calls)
+	}
+
+	quota.err = errors.New("exceeded quota")
+	d = m.recheck(context.Background(), second, m.get(key, DefaultPath, second.Request, SemanticsVersion), csh)
+	if d.Allowed || d.Memoized || d.Rule != QuotaRuleName {
+		t.Errorf("recheck() with exceeded quota = %+v, want denied by %s", d, QuotaRuleName)
+	}
+
+	clear(m.entries)
+	m.put(key, d)
+	if len(m.entries) != 0 {
+		t.Errorf("put() of denial by %s memoized %d verdicts, want none", QuotaRuleName, len(m.entries))
+	}
+}
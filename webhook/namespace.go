@@ -0,0 +1,74 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	v1 "k8s.io/api/admission/v1"
+)
+
+// NamespaceRuleName is the name of the rule validating namespaces at creation
+const NamespaceRuleName = "namespace"
+
+// NamespaceConfig configures the requirements for new namespaces
+type NamespaceConfig struct {
+	// RequiredAnnotations must be set with a non-empty value, e.g. the owner, team or cost center
+	RequiredAnnotations []string `json:"requiredAnnotations"`
+	// Prefixes of which the name has to start with one, empty allows all names
+	Prefixes []string `json:"prefixes"`
+	// RestrictCreation denies the creation of namespaces by users not listed in PlatformUsers or PlatformGroups
+	RestrictCreation bool `json:"restrictCreation"`
+	// PlatformUsers may create namespaces if creation is restricted, e.g. a service account of the GitOps controller
+	PlatformUsers []string `json:"platformUsers"`
+	// PlatformGroups may create namespaces if creation is restricted
+	PlatformGroups []string `json:"platformGroups"`
+}
+
+// namespaceRule validates the metadata and creator of new namespaces
+type namespaceRule struct {
+	cfg NamespaceConfig
+}
+
+func newNamespaceRule(_ *CosignServerHandler, cfg *Config) (Rule, error) {
+	return &namespaceRule{cfg: cfg.Namespace}, nil
+}
+
+// Name returns the name of the rule
+func (*namespaceRule) Name() string {
+	return NamespaceRuleName
+}
+
+// Validate checks creator, name and annotations of namespaces on creation
+func (r *namespaceRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	if o.Request.Kind.Kind != "Namespace" || o.Request.Operation != v1.Create {
+		return nil, nil
+	}
+	name := o.Meta.Name
+	if r.cfg.RestrictCreation {
+		if !r.isPlatformUser(o.Request) {
+			return nil, fmt.Errorf("namespace %q can only be created by the platform team, user %q is not allowed", name, o.Request.UserInfo.Username)
+		}
+		tracef(ctx, "user %q is a platform user", o.Request.UserInfo.Username)
+	}
+
+	var violations []string
+	if len(r.cfg.Prefixes) > 0 && !slices.ContainsFunc(r.cfg.Prefixes, func(p string) bool { return strings.HasPrefix(name, p) }) {
+		violations = append(violations, fmt.Sprintf("namespace %q must start with one of: %s", name, strings.Join(r.cfg.Prefixes, ", ")))
+	}
+	for _, a := range r.cfg.RequiredAnnotations {
+		if o.Meta.Annotations[a] == "" {
+			violations = append(violations, fmt.Sprintf("namespace %q needs annotation %q", name, a))
+		}
+	}
+	return nil, violationsError(violations)
+}
+
+// isPlatformUser returns true if the requesting user or one of its groups may create namespaces
+func (r *namespaceRule) isPlatformUser(req *v1.AdmissionRequest) bool {
+	if slices.Contains(r.cfg.PlatformUsers, req.UserInfo.Username) {
+		return true
+	}
+	return slices.ContainsFunc(req.UserInfo.Groups, func(g string) bool { return slices.Contains(r.cfg.PlatformGroups, g) })
+}
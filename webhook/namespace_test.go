@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_namespaceRule_Validate(t *testing.T) {
+	cfg := &Config{Namespace: NamespaceConfig{
+		RequiredAnnotations: []string{"example.com/owner"},
+		Prefixes:            []string{"team-"},
+		RestrictCreation:    true,
+		PlatformGroups:      []string{"platform"},
+	}}
+	r, err := newNamespaceRule(nil, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	namespace := func(name string, annotations map[string]string, groups ...string) *Object {
+		return &Object{
+			Request: &v1.AdmissionRequest{
+				Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Namespace"},
+				Name:      name,
+				Operation: v1.Create,
+				UserInfo:  authenticationv1.UserInfo{Username: "jane", Groups: groups},
+			},
+			Meta: metav1.ObjectMeta{Name: name, Annotations: annotations},
+		}
+	}
+	owner := map[string]string{"example.com/owner": "team-a"}
+
+	tests := []struct {
+		name    string
+		o       *Object
+		wantErr bool
+	}{
+		{
+			name: "valid namespace",
+			o:    namespace("team-a-dev", owner, "platform"),
+		},
+		{
+			name:    "not a platform user",
+			o:       namespace("team-a-dev", owner, "developers"),
+			wantErr: true,
+		},
+		{
+			name:    "missing prefix",
+			o:       namespace("dev", owner, "platform"),
+			wantErr: true,
+		},
+		{
+			name:    "missing owner",
+			o:       namespace("team-a-dev", nil, "platform"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Validate(context.Background(), tt.o)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,319 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	log "github.com/gookit/slog"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// NamingRuleName is the name of the rule enforcing naming conventions
+	NamingRuleName = "naming"
+	// namingKey is the key of the conventions in the ConfigMap of the naming rule
+	namingKey = "conventions.yaml"
+	// namingSyncTimeout is the time after which an unsynced ConfigMap of naming conventions is logged as error
+	namingSyncTimeout = time.Minute
+)
+
+// NamingConfig configures the naming conventions for object names and label values
+type NamingConfig struct {
+	Conventions []NamingConvention `json:"conventions"`
+	// ConfigMap as namespace/name holds further conventions as YAML list in the key conventions.yaml. They are
+	// re-read on each change of the ConfigMap, so operators can change them without restarting the webhook.
+	ConfigMap string `json:"configMap"`
+}
+
+// NamingConvention is a naming convention for objects of some kinds in some namespaces
+type NamingConvention struct {
+	// Kinds the convention applies to, e.g. Deployment. Empty applies to all kinds.
+	Kinds []string `json:"kinds"`
+	// Namespaces the convention applies to, e.g. the namespaces of a team. Empty applies to all namespaces.
+	Namespaces []string `json:"namespaces"`
+	// Pattern is a regular expression the whole name has to match
+	Pattern string `json:"pattern"`
+	// Patterns are regular expressions of which the whole name has to match one, e.g. ^team-[a-z0-9-]+$.
+	// Pattern is added to them if set.
+	Patterns []string `json:"patterns"`
+	// MaxLength of the name, 0 for no limit
+	MaxLength int `json:"maxLength"`
+	// Prefixes of which the name has to start with one, e.g. the team name
+	Prefixes []string `json:"prefixes"`
+	// Labels maps label keys to regular expressions their whole value has to match, if the label is set
+	Labels map[string]string `json:"labels"`
+	// Message is a Go template appended to denials as guidance. Available fields are
+	// .Kind, .Name, .Namespace, .Pattern, .Patterns, .MaxLength and .Prefixes.
+	Message string `json:"message"`
+}
+
+// namingConvention is the compiled form of a NamingConvention
+type namingConvention struct {
+	NamingConvention
+	// patterns are the compiled Pattern and Patterns
+	patterns *patternSet
+	labels   map[string]*regexp.Regexp
+	message  *template.Template
+}
+
+// namingRule enforces naming conventions for object names and label values
+type namingRule struct {
+	conventions []namingConvention
+	// watch holds the conventions of the ConfigMap, nil without ConfigMap
+	watch *namingWatch
+}
+
+// namingWatch holds the conventions of a watched ConfigMap. The rules of all endpoints with the same ConfigMap
+// share one watch, so it's watched once.
+type namingWatch struct {
+	// configMap is the namespace/name of the watched ConfigMap
+	configMap string
+	// synced is set once the informer of the ConfigMap synced. Until then, the rules fail with an internal error.
+	synced atomic.Bool
+	// conventions are the compiled conventions of the ConfigMap, replaced on each change
+	conventions atomic.Pointer[[]namingConvention]
+}
+
+func newNamingRule(csh *CosignServerHandler, cfg *Config) (Rule, error) {
+	conventions, err := compileNamingConventions(cfg.Naming.Conventions)
+	if err != nil {
+		return nil, err
+	}
+	r := &namingRule{conventions: conventions}
+	if cm := cfg.Naming.ConfigMap; cm != "" {
+		ns, name, ok := strings.Cut(cm, "/")
+		if !ok || ns == "" || name == "" {
+			return nil, fmt.Errorf("configMap %q must be namespace/name", cm)
+		}
+		if r.watch, ok = csh.namingWatches[cm]; !ok {
+			r.watch = &namingWatch{configMap: cm}
+			csh.tasks = append(csh.tasks, func(ctx context.Context) {
+				r.watch.run(ctx, csh, ns, name)
+			})
+			if csh.namingWatches == nil {
+				csh.namingWatches = map[string]*namingWatch{}
+			}
+			csh.namingWatches[cm] = r.watch
+		}
+	}
+	return r, nil
+}
+
+// run loads the conventions of the ConfigMap on each change until ctx is done. If the informer doesn't sync, e.g.
+// because the webhook may not list ConfigMaps, it's logged as error and the rules keep failing.
+func (w *namingWatch) run(ctx context.Context, csh *CosignServerHandler, ns, name string) {
+	informer := coreinformers.NewFilteredConfigMapInformer(csh.cs, ns, 0, cache.Indexers{}, func(o *metav1.ListOptions) {
+		o.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+	})
+	registration, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj any) { w.load(obj) },
+		UpdateFunc: func(_, obj any) { w.load(obj) },
+		DeleteFunc: func(any) {
+			log.Warnf("ConfigMap %s/%s of naming conventions deleted, enforcing the conventions of the config only", ns, name)
+			w.conventions.Store(nil)
+		},
+	})
+	if err != nil {
+		log.Errorf("Can't watch ConfigMap %s/%s of naming conventions: %v", ns, name, err)
+		return
+	}
+	go informer.Run(ctx.Done())
+
+	syncCtx, cancel := context.WithTimeout(ctx, namingSyncTimeout)
+	defer cancel()
+	if !cache.WaitForCacheSync(syncCtx.Done(), registration.HasSynced) {
+		if ctx.Err() != nil {
+			return
+		}
+		log.Errorf("ConfigMap %s/%s of naming conventions not synced after %s, the naming rule fails until it is. "+
+			"Check that the webhook may get, list and watch configmaps.", ns, name, namingSyncTimeout)
+		if !cache.WaitForCacheSync(ctx.Done(), registration.HasSynced) {
+			return
+		}
+	}
+	w.synced.Store(true)
+	log.Infof("Watching ConfigMap %s/%s of naming conventions", ns, name)
+}
+
+// load compiles the conventions of the ConfigMap. Invalid conventions are logged and the previous ones kept.
+func (w *namingWatch) load(obj any) {
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		return
+	}
+	conventions, err := parseNamingConventions(cm.Data[namingKey])
+	if err != nil {
+		log.Errorf("Invalid naming conventions in ConfigMap %s/%s, keeping the previous ones: %v", cm.Namespace, cm.Name, err)
+		return
+	}
+	log.Infof("Loaded %d naming conventions from ConfigMap %s/%s", len(conventions), cm.Namespace, cm.Name)
+	w.conventions.Store(&conventions)
+}
+
+// ParseNamingConfigMap returns the conventions of the ConfigMap of the naming rule. It fails for invalid
+// conventions, which the rule would not load.
+func ParseNamingConfigMap(cm *corev1.ConfigMap) ([]NamingConvention, error) {
+	conventions, err := parseNamingConventions(cm.Data[namingKey])
+	if err != nil {
+		return nil, err
+	}
+	ncs := make([]NamingConvention, 0, len(conventions))
+	for _, nc := range conventions {
+		ncs = append(ncs, nc.NamingConvention)
+	}
+	return ncs, nil
+}
+
+// parseNamingConventions parses and compiles a YAML list of conventions
+func parseNamingConventions(data string) ([]namingConvention, error) {
+	var ncs []NamingConvention
+	if err := yaml.UnmarshalStrict([]byte(data), &ncs); err != nil {
+		return nil, fmt.Errorf("key %s: %w", namingKey, err)
+	}
+	return compileNamingConventions(ncs)
+}
+
+// compileNamingConventions compiles the patterns and message templates of the conventions
+func compileNamingConventions(cs []NamingConvention) ([]namingConvention, error) {
+	conventions := make([]namingConvention, 0, len(cs))
+	for i := range cs {
+		nc := namingConvention{NamingConvention: cs[i], labels: map[string]*regexp.Regexp{}}
+		patterns, err := compilePatternSet(nc.patternList())
+		if err != nil {
+			return nil, err
+		}
+		nc.patterns = patterns
+		for k, p := range nc.Labels {
+			res, err := compilePatterns([]string{p})
+			if err != nil {
+				return nil, fmt.Errorf("label %q: %w", k, err)
+			}
+			nc.labels[k] = res[0]
+		}
+		if nc.Message != "" {
+			t, err := template.New("message").Parse(nc.Message)
+			if err != nil {
+				return nil, fmt.Errorf("invalid message template %q: %w", nc.Message, err)
+			}
+			nc.message = t
+		}
+		conventions = append(conventions, nc)
+	}
+	return conventions, nil
+}
+
+// Name returns the name of the rule
+func (*namingRule) Name() string {
+	return NamingRuleName
+}
+
+// stateful marks the verdicts as not memoizable, the conventions of the ConfigMap change with each update
+func (*namingRule) stateful() {}
+
+// Validate checks the name and label values of the object against all applicable conventions
+func (r *namingRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	name := o.Meta.Name
+	if name == "" {
+		// objects with generateName get their name after admission
+		return nil, nil
+	}
+	conventions := r.conventions
+	if r.watch != nil {
+		if !r.watch.synced.Load() {
+			return nil, internalError(fmt.Errorf("ConfigMap %s of naming conventions not synced", r.watch.configMap))
+		}
+		if watched := r.watch.conventions.Load(); watched != nil {
+			tracef(ctx, "%d conventions of ConfigMap %s", len(*watched), r.watch.configMap)
+			conventions = slices.Concat(conventions, *watched)
+		}
+	}
+	kind := o.Request.Kind.Kind
+	ns := o.Request.Namespace
+
+	var violations []string
+	for i := range conventions {
+		nc := &conventions[i]
+		if (len(nc.Kinds) > 0 && !slices.Contains(nc.Kinds, kind)) || (len(nc.Namespaces) > 0 && !slices.Contains(nc.Namespaces, ns)) {
+			continue
+		}
+		tracef(ctx, "convention %d applies", i+1)
+
+		var found []string
+		if v := nc.validatePatterns(name); v != "" {
+			found = append(found, v)
+		}
+		if nc.MaxLength > 0 && len(name) > nc.MaxLength {
+			found = append(found, fmt.Sprintf("name %q is longer than %d characters", name, nc.MaxLength))
+		}
+		if len(nc.Prefixes) > 0 && !slices.ContainsFunc(nc.Prefixes, func(p string) bool { return strings.HasPrefix(name, p) }) {
+			found = append(found, fmt.Sprintf("name %q must start with one of: %s", name, strings.Join(nc.Prefixes, ", ")))
+		}
+		keys := make([]string, 0, len(nc.labels))
+		for k := range nc.labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if v, ok := o.Meta.Labels[k]; ok && !nc.labels[k].MatchString(v) {
+				found = append(found, fmt.Sprintf("value %q of label %q doesn't match pattern %q", v, k, nc.Labels[k]))
+			}
+		}
+
+		if len(found) > 0 && nc.message != nil {
+			found = append(found, nc.guidance(kind, name, ns))
+		}
+		violations = append(violations, found...)
+	}
+	return nil, violationsError(violations)
+}
+
+// validatePatterns returns a violation if the name matches none of the patterns of the convention
+func (nc *namingConvention) validatePatterns(name string) string {
+	if nc.patterns.empty() || nc.patterns.matches(name) {
+		return ""
+	}
+	if len(nc.patternList()) == 1 {
+		return fmt.Sprintf("name %q doesn't match pattern %q", name, nc.patternList()[0])
+	}
+	return fmt.Sprintf("name %q doesn't match any of the patterns: %s", name, strings.Join(nc.patternList(), ", "))
+}
+
+// patternList returns Pattern and Patterns of the convention in the order they are compiled
+func (nc *namingConvention) patternList() []string {
+	if nc.Pattern == "" {
+		return nc.Patterns
+	}
+	return append([]string{nc.Pattern}, nc.Patterns...)
+}
+
+// guidance renders the message template of the convention for the object
+func (nc *namingConvention) guidance(kind, name, ns string) string {
+	var b bytes.Buffer
+	err := nc.message.Execute(&b, map[string]any{
+		"Kind":      kind,
+		"Name":      name,
+		"Namespace": ns,
+		"Pattern":   nc.Pattern,
+		"Patterns":  nc.patternList(),
+		"MaxLength": nc.MaxLength,
+		"Prefixes":  nc.Prefixes,
+	})
+	if err != nil {
+		return nc.Message
+	}
+	return b.String()
+}
@@ -0,0 +1,201 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_namingRule_Validate(t *testing.T) {
+	cfg := &Config{Naming: NamingConfig{Conventions: []NamingConvention{
+		{
+			Kinds:     []string{"Pod"},
+			Pattern:   "[a-z0-9-]+",
+			MaxLength: 20,
+		},
+		{
+			Namespaces: []string{"team-a"},
+			Prefixes:   []string{"team-a-"},
+			Labels:     map[string]string{"env": "dev|prod"},
+			Message:    "see the naming guide for {{ .Namespace }}",
+		},
+		{
+			Kinds:      []string{"Pod"},
+			Namespaces: []string{"team-b"},
+			Patterns:   []string{"^team-[a-z0-9-]+$", "platform-[a-z]+"},
+		},
+	}}}
+	tests := []struct {
+		name        string
+		ns          string
+		objName     string
+		labels      map[string]string
+		wantErr     bool
+		wantMessage string
+	}{
+		{
+			name:    "valid name",
+			ns:      "default",
+			objName: "my-app",
+		},
+		{
+			name:    "pattern mismatch",
+			ns:      "default",
+			objName: "My_App",
+			wantErr: true,
+		},
+		{
+			name:    "too long",
+			ns:      "default",
+			objName: "a-very-long-name-for-a-pod",
+			wantErr: true,
+		},
+		{
+			name:    "team prefix",
+			ns:      "team-a",
+			objName: "team-a-app",
+			labels:  map[string]string{"env": "prod"},
+		},
+		{
+			name:        "missing team prefix with guidance",
+			ns:          "team-a",
+			objName:     "app",
+			wantErr:     true,
+			wantMessage: "see the naming guide for team-a",
+		},
+		{
+			name:    "invalid label value",
+			ns:      "team-a",
+			objName: "team-a-app",
+			labels:  map[string]string{"env": "production"},
+			wantErr: true,
+		},
+		{
+			name:    "first of patterns",
+			ns:      "team-b",
+			objName: "team-web",
+		},
+		{
+			name:    "second of patterns",
+			ns:      "team-b",
+			objName: "platform-dns",
+		},
+		{
+			name:        "none of patterns",
+			ns:          "team-b",
+			objName:     "web",
+			wantErr:     true,
+			wantMessage: `doesn't match any of the patterns: ^team-[a-z0-9-]+$, platform-[a-z]+`,
+		},
+	}
+
+	r, err := newNamingRule(nil, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := podObject(tt.ns, corev1.PodSpec{})
+			o.Meta.Name = tt.objName
+			o.Meta.Labels = tt.labels
+			_, err := r.Validate(context.Background(), o)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantMessage != "" && !strings.Contains(err.Error(), tt.wantMessage) {
+				t.Errorf("Validate() error = %v, want message %q", err, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func Test_namingRule_ConfigMap(t *testing.T) {
+	csh := &CosignServerHandler{}
+	if _, err := newNamingRule(csh, &Config{Naming: NamingConfig{ConfigMap: "conventions"}}); err == nil {
+		t.Error("newNamingRule() with configMap without namespace = nil, want error")
+	}
+	rule, err := newNamingRule(csh, &Config{Naming: NamingConfig{
+		Conventions: []NamingConvention{{MaxLength: 20}},
+		ConfigMap:   "cosignwebhook/naming",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the rules of the same ConfigMap share its watch
+	shared, err := newNamingRule(csh, &Config{Naming: NamingConfig{ConfigMap: "cosignwebhook/naming"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(csh.tasks) != 1 || shared.(*namingRule).watch != rule.(*namingRule).watch {
+		t.Errorf("tasks = %d, want one watch of the ConfigMap shared by the rules", len(csh.tasks))
+	}
+	r := rule.(*namingRule)
+	validate := func(name string) error {
+		o := podObject("default", corev1.PodSpec{})
+		o.Meta.Name = name
+		_, err := r.Validate(context.Background(), o)
+		return err
+	}
+	cm := func(data string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{Data: map[string]string{namingKey: data}}
+	}
+
+	var internal *internalRuleError
+	if err := validate("App"); !errors.As(err, &internal) {
+		t.Errorf("Validate() before sync = %v, want internal error", err)
+	}
+	r.watch.synced.Store(true)
+	if err := validate("App"); err != nil {
+		t.Errorf("Validate() before load = %v, want nil", err)
+	}
+	r.watch.load(cm("- pattern: \"[a-z]+\"\n"))
+	if err := validate("App"); err == nil {
+		t.Error("Validate() with convention of the ConfigMap = nil, want error")
+	}
+	// invalid conventions keep the previous ones
+	r.watch.load(cm("- pattern: \"[a-z\"\n"))
+	if err := validate("App"); err == nil {
+		t.Error("Validate() after invalid change = nil, want error of the previous conventions")
+	}
+	r.watch.load(cm("- pattern: \"[A-Za-z]+\"\n"))
+	if err := validate("App"); err != nil {
+		t.Errorf("Validate() after change = %v, want nil", err)
+	}
+	if err := validate("a-name-longer-than-twenty"); err == nil {
+		t.Error("Validate() = nil, want error of the configured conventions")
+	}
+}
+
+func Test_namingRule_watch(t *testing.T) {
+	csh := &CosignServerHandler{cs: fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "cosignwebhook", Name: "naming"},
+		Data:       map[string]string{namingKey: "- pattern: \"[a-z]+\"\n"},
+	})}
+	rule, err := newNamingRule(csh, &Config{Naming: NamingConfig{ConfigMap: "cosignwebhook/naming"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := rule.(*namingRule)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go csh.tasks[0](ctx)
+
+	deadline := time.Now().Add(time.Second)
+	for !r.watch.synced.Load() {
+		if time.Now().After(deadline) {
+			t.Fatal("ConfigMap of naming conventions not synced")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	o := podObject("default", corev1.PodSpec{})
+	o.Meta.Name = "App"
+	if _, err := r.Validate(context.Background(), o); err == nil {
+		t.Error("Validate() after sync = nil, want error of the convention of the ConfigMap")
+	}
+}
@@ -0,0 +1,155 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// presets of Onboard
+const (
+	// PresetBaseline enforces the baseline Pod Security Standard and warns about the restricted one
+	PresetBaseline = "baseline"
+	// PresetRestricted enforces the restricted Pod Security Standard and requires images referenced by digest
+	PresetRestricted = "restricted"
+)
+
+// podSecurityLabelPrefix is the prefix of the namespace labels of the Pod Security admission
+const podSecurityLabelPrefix = "pod-security.kubernetes.io/"
+
+// onboardPresets are the namespace labels set by the presets
+var onboardPresets = map[string]map[string]string{
+	PresetBaseline: {
+		podSecurityLabelPrefix + "enforce": "baseline",
+		podSecurityLabelPrefix + "warn":    "restricted",
+	},
+	PresetRestricted: {
+		podSecurityLabelPrefix + "enforce": "restricted",
+		podSecurityLabelPrefix + "warn":    "restricted",
+		RequireDigestLabel:                 "true",
+	},
+}
+
+// OnboardPresets returns the names of the presets of Onboard, sorted
+func OnboardPresets() []string {
+	return slices.Sorted(maps.Keys(onboardPresets))
+}
+
+// OnboardOptions select the namespaces onboarded by Onboard and their preset
+type OnboardOptions struct {
+	// NamespaceSelector is a label selector of the namespaces, e.g. team=foo
+	NamespaceSelector string
+	// Preset is baseline or restricted
+	Preset string
+	// WebhookConfiguration is the name of the ValidatingWebhookConfiguration of the webhook. Empty skips the check
+	// whether the namespaces are validated.
+	WebhookConfiguration string
+	// DryRun reports the changes without applying them
+	DryRun bool
+}
+
+// LabelChange is a label set on a namespace, From is empty if the label was missing
+type LabelChange struct {
+	Label string `json:"label"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to"`
+}
+
+// OnboardedNamespace is the result of Onboard for a namespace
+type OnboardedNamespace struct {
+	Namespace string `json:"namespace"`
+	// Changes are the labels set, empty if the namespace was onboarded already
+	Changes []LabelChange `json:"changes,omitempty"`
+	// Validated is set if the webhook configuration selects the labeled namespace, or if it isn't checked
+	Validated bool `json:"validated"`
+	// Failed is set if the labels couldn't be set
+	Failed bool `json:"failed,omitempty"`
+	// Message describes the outcome, e.g. why the namespace isn't validated
+	Message string `json:"message"`
+}
+
+// Onboard sets the labels of the preset on the namespaces matching the selector in one step and checks that the
+// webhook validates them. Namespaces which can't be labeled are reported and don't stop the others.
+func Onboard(ctx context.Context, cs kubernetes.Interface, opts OnboardOptions) ([]OnboardedNamespace, error) {
+	preset, ok := onboardPresets[opts.Preset]
+	if !ok {
+		return nil, fmt.Errorf("unknown preset %q, must be one of %s", opts.Preset, strings.Join(OnboardPresets(), ", "))
+	}
+	if opts.NamespaceSelector == "" {
+		return nil, fmt.Errorf("a namespace selector is required")
+	}
+	var vwc *admissionregistrationv1.ValidatingWebhookConfiguration
+	if opts.WebhookConfiguration != "" {
+		var err error
+		vwc, err = cs.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(ctx, opts.WebhookConfiguration, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("can't get ValidatingWebhookConfiguration %s: %w", opts.WebhookConfiguration, err)
+		}
+	}
+	nsl, err := cs.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: opts.NamespaceSelector})
+	if err != nil {
+		return nil, fmt.Errorf("can't list namespaces %s: %w", opts.NamespaceSelector, err)
+	}
+
+	results := make([]OnboardedNamespace, 0, len(nsl.Items))
+	for _, ns := range nsl.Items {
+		results = append(results, onboardNamespace(ctx, cs, &ns, preset, vwc, opts.DryRun))
+	}
+	return results, nil
+}
+
+// onboardNamespace sets the labels of the preset on the namespace and checks that the webhook configuration, if any,
+// selects the labeled namespace
+func onboardNamespace(ctx context.Context, cs kubernetes.Interface, ns *corev1.Namespace, preset map[string]string,
+	vwc *admissionregistrationv1.ValidatingWebhookConfiguration, dryRun bool,
+) OnboardedNamespace {
+	r := OnboardedNamespace{Namespace: ns.Name, Validated: true}
+	for _, label := range slices.Sorted(maps.Keys(preset)) {
+		if from := ns.Labels[label]; from != preset[label] {
+			r.Changes = append(r.Changes, LabelChange{Label: label, From: from, To: preset[label]})
+		}
+	}
+	var unvalidated string
+	if vwc != nil {
+		labeled := ns.DeepCopy()
+		if labeled.Labels == nil {
+			labeled.Labels = map[string]string{}
+		}
+		maps.Copy(labeled.Labels, preset)
+		if r.Validated = validatesNamespace(vwc, labeled); !r.Validated {
+			unvalidated = fmt.Sprintf(", but ValidatingWebhookConfiguration %s doesn't select it, check its namespaceSelector", vwc.Name)
+		}
+	}
+
+	switch {
+	case len(r.Changes) == 0:
+		r.Message = "onboarded already"
+	case dryRun:
+		r.Message = fmt.Sprintf("would set %d labels", len(r.Changes))
+	default:
+		patch, err := json.Marshal(map[string]any{"metadata": map[string]any{"labels": preset}})
+		if err == nil {
+			_, err = cs.CoreV1().Namespaces().Patch(ctx, ns.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+		}
+		if err != nil {
+			r.Failed, r.Message = true, fmt.Sprintf("can't label namespace: %v", err)
+			if apierrors.IsForbidden(err) {
+				r.Message += ", patch on namespaces is required"
+			}
+			return r
+		}
+		r.Message = fmt.Sprintf("set %d labels", len(r.Changes))
+	}
+	r.Message += unvalidated
+	return r
+}
@@ -0,0 +1,105 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestOnboard(t *testing.T) {
+	namespace := func(name string, labels map[string]string) *corev1.Namespace {
+		l := map[string]string{"kubernetes.io/metadata.name": name}
+		for k, v := range labels {
+			l[k] = v
+		}
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: l}}
+	}
+	vwc := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "cosignwebhook"},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+			Name: "cosignwebhook.eumel8.io",
+			NamespaceSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{
+				Key: "kubernetes.io/metadata.name", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"foo-system"},
+			}}},
+		}},
+	}
+	cs := fake.NewSimpleClientset(vwc,
+		namespace("foo-dev", map[string]string{"team": "foo"}),
+		namespace("foo-prod", map[string]string{"team": "foo", podSecurityLabelPrefix + "enforce": "restricted",
+			podSecurityLabelPrefix + "warn": "restricted", RequireDigestLabel: "true"}),
+		namespace("foo-system", map[string]string{"team": "foo"}),
+		namespace("bar", map[string]string{"team": "bar"}),
+	)
+
+	results, err := Onboard(context.Background(), cs, OnboardOptions{
+		NamespaceSelector: "team=foo", Preset: PresetRestricted, WebhookConfiguration: "cosignwebhook", DryRun: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("Onboard() = %+v, want the 3 namespaces of team foo", results)
+	}
+	got := map[string]OnboardedNamespace{}
+	for _, r := range results {
+		got[r.Namespace] = r
+	}
+	if r := got["foo-dev"]; len(r.Changes) != 3 || !r.Validated || r.Failed {
+		t.Errorf("foo-dev = %+v, want 3 changes and validated", r)
+	}
+	if r := got["foo-prod"]; len(r.Changes) != 0 || r.Message != "onboarded already" {
+		t.Errorf("foo-prod = %+v, want onboarded already", r)
+	}
+	if r := got["foo-system"]; r.Validated || !strings.Contains(r.Message, "doesn't select it") {
+		t.Errorf("foo-system = %+v, want not validated", r)
+	}
+	ns, _ := cs.CoreV1().Namespaces().Get(context.Background(), "foo-dev", metav1.GetOptions{})
+	if _, ok := ns.Labels[RequireDigestLabel]; ok {
+		t.Error("dry run labeled the namespace")
+	}
+
+	if _, err := Onboard(context.Background(), cs, OnboardOptions{NamespaceSelector: "team=foo", Preset: PresetBaseline}); err != nil {
+		t.Fatal(err)
+	}
+	ns, _ = cs.CoreV1().Namespaces().Get(context.Background(), "foo-prod", metav1.GetOptions{})
+	if ns.Labels[podSecurityLabelPrefix+"enforce"] != "baseline" || ns.Labels[RequireDigestLabel] != "true" || ns.Labels["team"] != "foo" {
+		t.Errorf("labels = %v, want enforce baseline and the other labels kept", ns.Labels)
+	}
+}
+
+func TestOnboard_patchFails(t *testing.T) {
+	cs := fake.NewSimpleClientset(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "foo", Labels: map[string]string{"team": "foo"}}})
+	cs.PrependReactor("patch", "namespaces", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "namespaces"}, "foo", errors.New("rbac"))
+	})
+	results, err := Onboard(context.Background(), cs, OnboardOptions{NamespaceSelector: "team=foo", Preset: PresetRestricted})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || !results[0].Failed || !strings.Contains(results[0].Message, "patch on namespaces is required") {
+		t.Errorf("Onboard() = %+v, want failed with hint", results)
+	}
+}
+
+func TestOnboard_invalid(t *testing.T) {
+	cs := fake.NewSimpleClientset()
+	for _, opts := range []OnboardOptions{
+		{NamespaceSelector: "team=foo", Preset: "privileged"},
+		{Preset: PresetRestricted},
+		{NamespaceSelector: "team=foo", Preset: PresetRestricted, WebhookConfiguration: "missing"},
+	} {
+		if _, err := Onboard(context.Background(), cs, opts); err == nil {
+			t.Errorf("Onboard(%+v), want error", opts)
+		}
+	}
+}
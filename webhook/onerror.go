@@ -0,0 +1,104 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	log "github.com/gookit/slog"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+)
+
+var ruleErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "cosign_rule_errors_total",
+	Help: "The number of rule evaluations which failed with an internal error instead of a verdict, by rule",
+}, []string{"rule"})
+
+// internalRuleError is the error of a rule which couldn't decide, e.g. because an external call failed or the rule
+// panicked. It's decided by the on error policy of the rule, while other errors of rules are violations.
+type internalRuleError struct {
+	err error
+}
+
+func (e *internalRuleError) Error() string {
+	return e.err.Error()
+}
+
+func (e *internalRuleError) Unwrap() error {
+	return e.err
+}
+
+// internalError marks the error as internal error of a rule, nil stays nil
+func internalError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &internalRuleError{err: err}
+}
+
+// verificationError returns failure if err is the failure of a signature or attestation verification, which is a
+// violation. Other errors of cosign, e.g. of the registry or the network, are returned as internal errors.
+func verificationError(err, failure error) error {
+	var (
+		verification   *cosign.VerificationFailure
+		noMatching     *cosign.ErrNoMatchingSignatures
+		noSignatures   *cosign.ErrNoSignaturesFound
+		noAttestations *cosign.ErrNoMatchingAttestations
+		noCertificate  *cosign.ErrNoCertificateFoundOnSignature
+		tagNotFound    *cosign.ErrImageTagNotFound
+		legacy         *cosign.VerificationError
+	)
+	switch {
+	case errors.As(err, &verification), errors.As(err, &noMatching), errors.As(err, &noSignatures),
+		errors.As(err, &noAttestations), errors.As(err, &noCertificate), errors.As(err, &tagNotFound),
+		errors.As(err, &legacy):
+		return failure
+	default:
+		return internalError(fmt.Errorf("%w: %w", failure, err))
+	}
+}
+
+// onErrorPolicy decides the admission requests whose rules fail with an internal error. The zero value denies.
+type onErrorPolicy struct {
+	def       string
+	overrides map[string]string
+}
+
+// newOnErrorPolicy returns the on error policy of the server settings
+func newOnErrorPolicy(s ServerConfig) (onErrorPolicy, error) {
+	def, overrides, err := s.OnErrorPolicies()
+	if err != nil {
+		return onErrorPolicy{}, err
+	}
+	return onErrorPolicy{def: def, overrides: overrides}, nil
+}
+
+// forRule returns the on error behavior of the rule
+func (p onErrorPolicy) forRule(rule string) string {
+	if b, ok := p.overrides[rule]; ok {
+		return b
+	}
+	if p.def == "" {
+		return OnErrorDeny
+	}
+	return p.def
+}
+
+// validateRule validates the object with the rule. A panic of the rule is recovered and returned as internal error.
+func validateRule(ctx context.Context, rule Rule, o *Object) (warnings []string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("Rule %s panicked on %s %s/%s: %v\n%s", rule.Name(), o.Request.Kind.Kind, o.Request.Namespace, o.Request.Name, r, debug.Stack())
+			warnings, err = nil, internalError(fmt.Errorf("rule %s panicked: %v", rule.Name(), r))
+		}
+		var internal *internalRuleError
+		if errors.As(err, &internal) {
+			ruleErrors.WithLabelValues(rule.Name()).Inc()
+		}
+	}()
+	return rule.Validate(ctx, o)
+}
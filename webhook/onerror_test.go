@@ -0,0 +1,153 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// panicRule panics on validation, e.g. like a nil dereference
+type panicRule struct {
+	name string
+}
+
+func (r *panicRule) Name() string {
+	return r.name
+}
+
+func (*panicRule) Validate(_ context.Context, o *Object) ([]string, error) {
+	return nil, errors.New(o.Pod.Spec.NodeName)
+}
+
+func Test_evaluateRules_onError(t *testing.T) {
+	failing := func() Rule {
+		return &countingRule{name: QuotaRuleName, err: internalError(errors.New("can't list resource quotas"))}
+	}
+	tests := []struct {
+		name         string
+		rules        func() []Rule
+		onError      onErrorPolicy
+		wantAllowed  bool
+		wantRule     string
+		wantMessage  string
+		wantWarnings int
+		wantFailed   []string
+	}{
+		{
+			name:        "violation isn't an internal error",
+			rules:       func() []Rule { return []Rule{&countingRule{name: SanityRuleName, err: errors.New("broken pod spec")}} },
+			onError:     onErrorPolicy{def: OnErrorAllow},
+			wantRule:    SanityRuleName,
+			wantMessage: "broken pod spec",
+		},
+		{
+			name:        "deny by default",
+			rules:       func() []Rule { return []Rule{failing()} },
+			wantRule:    QuotaRuleName,
+			wantMessage: "can't list resource quotas",
+			wantFailed:  []string{QuotaRuleName},
+		},
+		{
+			name: "allow skips the remaining rules",
+			rules: func() []Rule {
+				return []Rule{failing(), &countingRule{name: SanityRuleName, err: errors.New("broken pod spec")}}
+			},
+			onError:      onErrorPolicy{def: OnErrorAllow},
+			wantAllowed:  true,
+			wantMessage:  "Admitted without validation, rule quota failed: can't list resource quotas",
+			wantWarnings: 1,
+			wantFailed:   []string{QuotaRuleName},
+		},
+		{
+			name: "ignore-rule evaluates the remaining rules",
+			rules: func() []Rule {
+				return []Rule{failing(), &countingRule{name: SanityRuleName, err: errors.New("broken pod spec")}}
+			},
+			onError:      onErrorPolicy{def: OnErrorIgnoreRule},
+			wantRule:     SanityRuleName,
+			wantMessage:  "broken pod spec",
+			wantWarnings: 1,
+			wantFailed:   []string{QuotaRuleName},
+		},
+		{
+			name:         "override by rule",
+			rules:        func() []Rule { return []Rule{failing()} },
+			onError:      onErrorPolicy{def: OnErrorDeny, overrides: map[string]string{QuotaRuleName: OnErrorIgnoreRule}},
+			wantAllowed:  true,
+			wantMessage:  "Validation passed",
+			wantWarnings: 1,
+			wantFailed:   []string{QuotaRuleName},
+		},
+		{
+			name:        "panic is recovered",
+			rules:       func() []Rule { return []Rule{&panicRule{name: "panicking"}} },
+			wantRule:    "panicking",
+			wantMessage: "rule panicking panicked",
+			wantFailed:  []string{"panicking"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := podObject("default", corev1.PodSpec{})
+			o.Pod = nil
+			d := evaluateRules(context.Background(), o, DefaultPath, tt.rules(), SemanticsVersion, tt.onError, nil)
+			if d.Allowed != tt.wantAllowed || d.Rule != tt.wantRule || !strings.Contains(d.Message, tt.wantMessage) {
+				t.Errorf("decision = %+v, want allowed=%t rule=%q message %q", d, tt.wantAllowed, tt.wantRule, tt.wantMessage)
+			}
+			if len(d.Warnings) != tt.wantWarnings {
+				t.Errorf("warnings = %v, want %d", d.Warnings, tt.wantWarnings)
+			}
+			if !slices.Equal(d.FailedRules, tt.wantFailed) {
+				t.Errorf("failed rules = %v, want %v", d.FailedRules, tt.wantFailed)
+			}
+		})
+	}
+}
+
+func Test_validateRule_countsInternalErrors(t *testing.T) {
+	o := podObject("default", corev1.PodSpec{})
+	before := testutil.ToFloat64(ruleErrors.WithLabelValues(DuplicatesRuleName))
+	r := &countingRule{name: DuplicatesRuleName, err: internalError(errors.New("can't list deployments"))}
+	if _, err := validateRule(context.Background(), r, o); err == nil {
+		t.Fatal("validateRule() error = nil, want the internal error")
+	}
+	r.err = errors.New("selector overlaps")
+	if _, err := validateRule(context.Background(), r, o); err == nil {
+		t.Fatal("validateRule() error = nil, want the violation")
+	}
+	if got := testutil.ToFloat64(ruleErrors.WithLabelValues(DuplicatesRuleName)) - before; got != 1 {
+		t.Errorf("cosign_rule_errors_total = %v, want 1", got)
+	}
+}
+
+func Test_internalError(t *testing.T) {
+	if internalError(nil) != nil {
+		t.Error("internalError(nil) != nil")
+	}
+	cause := errors.New("connection refused")
+	err := internalError(cause)
+	if !errors.Is(err, cause) || err.Error() != "connection refused" {
+		t.Errorf("internalError() = %v, want it wrapping the cause", err)
+	}
+}
+
+func Test_verificationError(t *testing.T) {
+	failure := errors.New(`signature for "nginx" couldn't be verified`)
+	for _, err := range []error{&cosign.VerificationFailure{}, &cosign.ErrNoMatchingSignatures{}, &cosign.ErrNoMatchingAttestations{}} {
+		if got := verificationError(err, failure); got != failure {
+			t.Errorf("verificationError(%T) = %v, want the violation", err, got)
+		}
+	}
+	cause := errors.New("dial tcp: connection refused")
+	err := verificationError(cause, failure)
+	var internal *internalRuleError
+	if !errors.As(err, &internal) || !errors.Is(err, cause) || !errors.Is(err, failure) {
+		t.Errorf("verificationError() = %v, want an internal error wrapping the cause", err)
+	}
+}
@@ -0,0 +1,210 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	log "github.com/gookit/slog"
+)
+
+// OpenAPIPath serves the OpenAPI document on the monitor port
+const OpenAPIPath = "/openapi/v3"
+
+// OpenAPISpec returns the OpenAPI v3 document of the HTTP APIs of the webhook. The schemas are generated from the
+// Go types returned by the APIs, so the document can't drift from the code.
+func OpenAPISpec() map[string]any {
+	bearer := []map[string][]string{{"bearer": {}}}
+	text := func(description string) map[string]any {
+		return map[string]any{"description": description, "content": map[string]any{"text/plain": map[string]any{"schema": map[string]any{"type": "string"}}}}
+	}
+	query := func(name, description string) map[string]any {
+		return map[string]any{"name": name, "in": "query", "description": description, "schema": map[string]any{"type": "string"}}
+	}
+	decision := map[string]any{"$ref": "#/components/schemas/Decision"}
+	flushResult := map[string]any{"$ref": "#/components/schemas/FlushResult"}
+	webhookServers := []map[string]string{{"url": "https://cosignwebhook.cosignwebhook.svc:443", "description": "webhook port"}}
+	monitorServers := []map[string]string{{"url": "http://cosignwebhook.cosignwebhook.svc:80", "description": "monitor port"}}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "cosignwebhook",
+			"description": "HTTP APIs of the cosignwebhook besides the admission endpoints",
+			"version":     "v1",
+		},
+		"paths": map[string]any{
+			EvaluatePath: map[string]any{
+				"servers": webhookServers,
+				"post": map[string]any{
+					"operationId": "evaluate",
+					"summary":     "Evaluate an object with the rules of an endpoint without admission",
+					"description": "Decisions aren't recorded. The ETag of the response identifies the config and the object, " +
+						"a request with a matching If-None-Match header is answered with 304.",
+					"security": bearer,
+					"parameters": []map[string]any{
+						query("endpoint", "Path of the admission endpoint whose rules evaluate the object, defaults to "+DefaultPath),
+						{"name": "If-None-Match", "in": "header", "schema": map[string]any{"type": "string"}},
+					},
+					"requestBody": map[string]any{
+						"required": true,
+						"content": map[string]any{
+							"application/json": map[string]any{"schema": map[string]any{"type": "object"}},
+							"application/yaml": map[string]any{"schema": map[string]any{"type": "object"}},
+						},
+					},
+					"responses": map[string]any{
+						"200": map[string]any{"description": "Decision of the endpoint", "content": map[string]any{"application/json": map[string]any{"schema": decision}}},
+						"304": map[string]any{"description": "Not modified since the evaluation with the ETag"},
+						"400": text("Invalid object"),
+						"401": text("Bearer token missing or invalid"),
+						"403": text("User not in the allowed groups"),
+						"404": text("Unknown endpoint"),
+					},
+				},
+			},
+			DecisionStreamPath: map[string]any{
+				"servers": webhookServers,
+				"get": map[string]any{
+					"operationId": "streamDecisions",
+					"summary":     "Stream the recent and all later decisions",
+					"description": "Server-Sent Events of type decision, the data is a Decision as JSON.",
+					"security":    bearer,
+					"parameters": []map[string]any{
+						query("namespace", "Only decisions of the namespace"),
+						query("rule", "Only decisions denied by the rule"),
+					},
+					"responses": map[string]any{
+						"200": map[string]any{"description": "Stream of decisions", "content": map[string]any{"text/event-stream": map[string]any{"schema": decision}}},
+						"401": text("Bearer token missing or invalid"),
+						"403": text("User not in the allowed groups"),
+					},
+				},
+			},
+			DecisionPath: map[string]any{
+				"servers": webhookServers,
+				"get": map[string]any{
+					"operationId": "getDecision",
+					"summary":     "Get the latest buffered decision of an admission request",
+					"description": "Denied decisions include the trace of the evaluated rules.",
+					"security":    bearer,
+					"parameters": []map[string]any{
+						{"name": "uid", "in": "path", "required": true, "description": "UID of the admission request", "schema": map[string]any{"type": "string"}},
+					},
+					"responses": map[string]any{
+						"200": map[string]any{"description": "Decision", "content": map[string]any{"application/json": map[string]any{"schema": decision}}},
+						"401": text("Bearer token missing or invalid"),
+						"403": text("User not in the allowed groups"),
+						"404": text("Decision not buffered"),
+					},
+				},
+			},
+			FlushCachePath: map[string]any{
+				"servers": webhookServers,
+				"post": map[string]any{
+					"operationId": "flushCache",
+					"summary":     "Flush the caches of the replica",
+					"description": "Clears the memoized verdicts and the caches of external lookups, and re-lists the objects cached by the informers.",
+					"security":    bearer,
+					"responses": map[string]any{
+						"200": map[string]any{"description": "Flushed caches", "content": map[string]any{"application/json": map[string]any{"schema": flushResult}}},
+						"401": text("Bearer token missing or invalid"),
+						"403": text("User not in the allowed groups"),
+						"500": map[string]any{"description": "Caches which couldn't be flushed", "content": map[string]any{"application/json": map[string]any{"schema": flushResult}}},
+					},
+				},
+			},
+			"/healthz": map[string]any{
+				"servers": monitorServers,
+				"get": map[string]any{
+					"operationId": "healthz",
+					"summary":     "Liveness of the webhook",
+					"responses":   map[string]any{"200": text("ok")},
+				},
+			},
+			"/readyz": map[string]any{
+				"servers": monitorServers,
+				"get": map[string]any{
+					"operationId": "readyz",
+					"summary":     "Readiness of the replica, not ready while overloaded",
+					"responses":   map[string]any{"200": text("ok"), "503": text("overloaded")},
+				},
+			},
+			OpenAPIPath: map[string]any{
+				"servers": monitorServers,
+				"get": map[string]any{
+					"operationId": "openapi",
+					"summary":     "This document",
+					"responses":   map[string]any{"200": map[string]any{"description": "OpenAPI document", "content": map[string]any{"application/json": map[string]any{}}}},
+				},
+			},
+		},
+		"components": map[string]any{
+			"securitySchemes": map[string]any{
+				"bearer": map[string]any{"type": "http", "scheme": "bearer", "description": "Token of the cluster, checked with a TokenReview"},
+			},
+			"schemas": map[string]any{
+				"Decision":    schemaOf(reflect.TypeOf(Decision{})),
+				"FlushResult": schemaOf(reflect.TypeOf(FlushResult{})),
+			},
+		},
+	}
+}
+
+// ServeOpenAPI serves the OpenAPI document as JSON
+func ServeOpenAPI(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(OpenAPISpec()); err != nil {
+		log.Errorf("Can't write OpenAPI document: %v", err)
+	}
+}
+
+// schemaOf returns the schema of the JSON encoding of the type
+func schemaOf(t reflect.Type) map[string]any {
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaOf(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaOf(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]any{}
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if !f.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			properties[name] = schemaOf(f.Type)
+			if !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+		s := map[string]any{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			s["required"] = required
+		}
+		return s
+	default:
+		return map[string]any{}
+	}
+}
@@ -0,0 +1,45 @@
+package webhook
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPISpec(t *testing.T) {
+	data, err := json.Marshal(OpenAPISpec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spec struct {
+		Paths      map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]any `json:"properties"`
+				Required   []string       `json:"required"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		t.Fatal(err)
+	}
+	for path, method := range map[string]string{EvaluatePath: "post", DecisionStreamPath: "get", DecisionPath: "get", FlushCachePath: "post", "/healthz": "get", "/readyz": "get", OpenAPIPath: "get"} {
+		if _, ok := spec.Paths[path][method]; !ok {
+			t.Errorf("spec misses %s %s", method, path)
+		}
+	}
+
+	decision := spec.Components.Schemas["Decision"]
+	dt := reflect.TypeOf(Decision{})
+	for i := 0; i < dt.NumField(); i++ {
+		name, opts, _ := strings.Cut(dt.Field(i).Tag.Get("json"), ",")
+		if _, ok := decision.Properties[name]; !ok {
+			t.Errorf("Decision schema misses %q", name)
+		}
+		if required := slices.Contains(decision.Required, name); required == strings.Contains(opts, "omitempty") {
+			t.Errorf("Decision schema has %q required %v, want the opposite", name, required)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package webhook
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/gookit/slog"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultOrderInterval = time.Minute
+	// orderKey is the key of the learned order in the ConfigMap
+	orderKey = "order.json"
+)
+
+// OrderConfig configures the evaluation order of the rules
+type OrderConfig struct {
+	// Adaptive evaluates the rules of each endpoint ordered by their deny rate, so the rules most likely to deny
+	// short-circuit the evaluation first. Warnings of the rules after the denying one are not returned.
+	Adaptive bool `json:"adaptive"`
+	// ConfigMap persisting the learned order across restarts as namespace/name, empty keeps it in memory
+	ConfigMap string `json:"configMap"`
+	// Interval of the reordering and persisting, defaults to 1m
+	Interval metav1.Duration `json:"interval"`
+}
+
+// ruleStats counts the evaluations and denials of a rule
+type ruleStats struct {
+	Evaluated uint64 `json:"evaluated"`
+	Denied    uint64 `json:"denied"`
+}
+
+// rate returns the share of evaluations the rule denied
+func (s ruleStats) rate() float64 {
+	if s.Evaluated == 0 {
+		return 0
+	}
+	return float64(s.Denied) / float64(s.Evaluated)
+}
+
+// adaptiveOrder learns the deny rates of the rules of an endpoint and orders them by it, rules with the same
+// rate keep the configured order. Rules admitting the request on an internal error skip the remaining rules, so
+// they keep their position and the other rules are only reordered between them. Otherwise a rule moved after one
+// admitting on error could no longer deny the requests it denied before.
+type adaptiveOrder struct {
+	configured []Rule
+	// pinned are the rules admitting on internal errors, which keep their position
+	pinned  map[string]bool
+	ordered atomic.Pointer[[]Rule]
+
+	mu    sync.Mutex
+	stats map[string]ruleStats
+}
+
+func newAdaptiveOrder(rules []Rule, onError onErrorPolicy) *adaptiveOrder {
+	a := &adaptiveOrder{configured: rules, pinned: map[string]bool{}, stats: map[string]ruleStats{}}
+	for _, r := range rules {
+		a.pinned[r.Name()] = onError.forRule(r.Name()) == OnErrorAllow
+	}
+	a.ordered.Store(&rules)
+	return a
+}
+
+// rules returns the rules in the learned order
+func (a *adaptiveOrder) rules() []Rule {
+	return *a.ordered.Load()
+}
+
+// record counts the results of the rules evaluated for a request
+func (a *adaptiveOrder) record(results map[string]ruleResult) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for name, res := range results {
+		s := a.stats[name]
+		s.Evaluated++
+		if res.err != nil {
+			s.Denied++
+		}
+		a.stats[name] = s
+	}
+}
+
+// reorder orders the rules by their current deny rates and returns the stats
+func (a *adaptiveOrder) reorder() map[string]ruleStats {
+	a.mu.Lock()
+	stats := make(map[string]ruleStats, len(a.stats))
+	for name, s := range a.stats {
+		stats[name] = s
+	}
+	a.mu.Unlock()
+
+	ordered := slices.Clone(a.configured)
+	start := 0
+	for i := 0; i <= len(ordered); i++ {
+		if i < len(ordered) && !a.pinned[ordered[i].Name()] {
+			continue
+		}
+		slices.SortStableFunc(ordered[start:i], func(x, y Rule) int {
+			return cmp.Compare(stats[y.Name()].rate(), stats[x.Name()].rate())
+		})
+		start = i + 1
+	}
+	a.ordered.Store(&ordered)
+	return stats
+}
+
+// restore adds persisted stats, e.g. of the last run, and reorders the rules
+func (a *adaptiveOrder) restore(stats map[string]ruleStats) {
+	a.mu.Lock()
+	for name, s := range stats {
+		cur := a.stats[name]
+		cur.Evaluated += s.Evaluated
+		cur.Denied += s.Denied
+		a.stats[name] = cur
+	}
+	a.mu.Unlock()
+	a.reorder()
+}
+
+// learnOrder reorders the rules of the endpoints in each interval and persists the stats of the rules in the
+// ConfigMap, if configured, until ctx is done. The persisted stats are restored on start.
+func (csh *CosignServerHandler) learnOrder(ctx context.Context) {
+	cfg := csh.cfg.Order
+	interval := cfg.Interval.Duration
+	if interval <= 0 {
+		interval = defaultOrderInterval
+	}
+	ns, name, _ := strings.Cut(cfg.ConfigMap, "/")
+	if cfg.ConfigMap != "" {
+		if err := csh.restoreOrder(ctx, ns, name); err != nil {
+			log.Warnf("Can't restore rule order from ConfigMap %s: %v", cfg.ConfigMap, err)
+		}
+	}
+	log.Infof("Learning rule order every %s", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			stats := map[string]map[string]ruleStats{}
+			for _, e := range csh.endpoints {
+				if e.order != nil {
+					stats[e.Path] = e.order.reorder()
+				}
+			}
+			if cfg.ConfigMap == "" {
+				continue
+			}
+			if err := csh.persistOrder(ctx, ns, name, stats); err != nil {
+				log.Warnf("Can't persist rule order in ConfigMap %s: %v", cfg.ConfigMap, err)
+			}
+		}
+	}
+}
+
+// restoreOrder restores the stats of the rules by endpoint path from the ConfigMap
+func (csh *CosignServerHandler) restoreOrder(ctx context.Context, ns, name string) error {
+	cm, err := csh.cs.CoreV1().ConfigMaps(ns).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	stats := map[string]map[string]ruleStats{}
+	if err := json.Unmarshal([]byte(cm.Data[orderKey]), &stats); err != nil {
+		return fmt.Errorf("invalid key %s: %w", orderKey, err)
+	}
+	for _, e := range csh.endpoints {
+		if s, ok := stats[e.Path]; ok && e.order != nil {
+			e.order.restore(s)
+		}
+	}
+	return nil
+}
+
+// persistOrder writes the stats of the rules by endpoint path to the ConfigMap, creating it if it doesn't exist
+func (csh *CosignServerHandler) persistOrder(ctx context.Context, ns, name string, stats map[string]map[string]ruleStats) error {
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+	cms := csh.cs.CoreV1().ConfigMaps(ns)
+	cm, err := cms.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns}, Data: map[string]string{orderKey: string(data)}}
+		_, err = cms.Create(ctx, cm, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[orderKey] = string(data)
+	_, err = cms.Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
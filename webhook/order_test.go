@@ -0,0 +1,81 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_adaptiveOrder(t *testing.T) {
+	cosign := &countingRule{name: CosignRuleName}
+	sanity := &countingRule{name: SanityRuleName, err: errors.New("broken pod spec")}
+	e := &Endpoint{Path: DefaultPath, rules: []Rule{cosign, sanity}, csh: &CosignServerHandler{semantics: SemanticsVersion}}
+	e.order = newAdaptiveOrder(e.rules, onErrorPolicy{})
+	o := podObject("default", corev1.PodSpec{})
+
+	for range 3 {
+		e.evaluate(context.Background(), o, map[string]ruleResult{})
+	}
+	if cosign.calls != 3 || sanity.calls != 3 {
+		t.Fatalf("calls before reorder = %d, %d, want 3, 3", cosign.calls, sanity.calls)
+	}
+	stats := e.order.reorder()
+	if stats[SanityRuleName] != (ruleStats{Evaluated: 3, Denied: 3}) {
+		t.Errorf("stats of sanity = %+v, want 3 evaluated and denied", stats[SanityRuleName])
+	}
+	d := e.evaluate(context.Background(), o, map[string]ruleResult{})
+	if d.Rule != SanityRuleName || cosign.calls != 3 {
+		t.Errorf("after reorder denied by %q with %d cosign calls, want sanity first and cosign skipped", d.Rule, cosign.calls)
+	}
+
+	// the learned stats survive a restart
+	csh := &CosignServerHandler{cs: fake.NewSimpleClientset(), endpoints: []*Endpoint{e}}
+	if err := csh.persistOrder(context.Background(), "cosignwebhook", "order", map[string]map[string]ruleStats{DefaultPath: stats}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := csh.cs.CoreV1().ConfigMaps("cosignwebhook").Get(context.Background(), "order", metav1.GetOptions{}); err != nil {
+		t.Fatalf("ConfigMap not created: %v", err)
+	}
+	e.order = newAdaptiveOrder([]Rule{cosign, sanity}, onErrorPolicy{})
+	if err := csh.restoreOrder(context.Background(), "cosignwebhook", "order"); err != nil {
+		t.Fatal(err)
+	}
+	if rules := e.order.rules(); rules[0].Name() != SanityRuleName {
+		t.Errorf("restored order starts with %s, want %s", rules[0].Name(), SanityRuleName)
+	}
+}
+
+func Test_adaptiveOrder_onErrorAllow(t *testing.T) {
+	sanity := &countingRule{name: SanityRuleName}
+	licenses := &countingRule{name: LicensesRuleName, err: internalError(errors.New("registry unavailable"))}
+	cosign := &countingRule{name: CosignRuleName}
+	onError := onErrorPolicy{def: OnErrorDeny, overrides: map[string]string{LicensesRuleName: OnErrorAllow}}
+	csh := &CosignServerHandler{semantics: SemanticsVersion, onError: onError}
+	e := &Endpoint{Path: DefaultPath, rules: []Rule{sanity, licenses, cosign}, csh: csh}
+	e.order = newAdaptiveOrder(e.rules, onError)
+	o := podObject("default", corev1.PodSpec{})
+
+	for range 3 {
+		if d := e.evaluate(context.Background(), o, map[string]ruleResult{}); !d.Allowed {
+			t.Fatalf("decision = %+v, want admitted on the error of %s", d, LicensesRuleName)
+		}
+	}
+	e.order.reorder()
+	// licenses fails most often, but moving it first would admit the requests sanity denies
+	names := make([]string, 0, 3)
+	for _, r := range e.order.rules() {
+		names = append(names, r.Name())
+	}
+	if want := []string{SanityRuleName, LicensesRuleName, CosignRuleName}; !slices.Equal(names, want) {
+		t.Errorf("order = %v, want %v", names, want)
+	}
+	sanity.err = errors.New("broken pod spec")
+	if d := e.evaluate(context.Background(), o, map[string]ruleResult{}); d.Allowed || d.Rule != SanityRuleName {
+		t.Errorf("decision after reorder = %+v, want denied by %s", d, SanityRuleName)
+	}
+}
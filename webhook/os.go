@@ -0,0 +1,26 @@
+package webhook
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	nodev1listers "k8s.io/client-go/listers/node/v1"
+)
+
+// podOS returns the operating system the pod runs on, taken from the os field of the spec, the kubernetes.io/os
+// node selector or the node selector of its runtime class, in this order. Empty if the pod doesn't select one,
+// the runtime classes are only looked up with a lister.
+func podOS(spec *corev1.PodSpec, runtimeClasses nodev1listers.RuntimeClassLister) corev1.OSName {
+	if spec.OS != nil && spec.OS.Name != "" {
+		return spec.OS.Name
+	}
+	if name := spec.NodeSelector[corev1.LabelOSStable]; name != "" {
+		return corev1.OSName(name)
+	}
+	if spec.RuntimeClassName == nil || runtimeClasses == nil {
+		return ""
+	}
+	rc, err := runtimeClasses.Get(*spec.RuntimeClassName)
+	if err != nil || rc.Scheduling == nil {
+		return ""
+	}
+	return corev1.OSName(rc.Scheduling.NodeSelector[corev1.LabelOSStable])
+}
@@ -0,0 +1,87 @@
+package webhook
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	nodev1 "k8s.io/api/node/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	nodev1listers "k8s.io/client-go/listers/node/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func Test_podOS(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for name, osName := range map[string]string{"windows": "windows", "gvisor": ""} {
+		rc := &nodev1.RuntimeClass{ObjectMeta: metav1.ObjectMeta{Name: name}, Handler: name}
+		if osName != "" {
+			rc.Scheduling = &nodev1.Scheduling{NodeSelector: map[string]string{corev1.LabelOSStable: osName}}
+		}
+		if err := indexer.Add(rc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runtimeClasses := nodev1listers.NewRuntimeClassLister(indexer)
+	windows, gvisor, unknown := "windows", "gvisor", "unknown"
+
+	tests := []struct {
+		name string
+		spec corev1.PodSpec
+		want corev1.OSName
+	}{
+		{
+			name: "no OS selected",
+		},
+		{
+			name: "os field",
+			spec: corev1.PodSpec{OS: &corev1.PodOS{Name: corev1.Windows}},
+			want: corev1.Windows,
+		},
+		{
+			name: "os field wins over node selector",
+			spec: corev1.PodSpec{
+				OS:           &corev1.PodOS{Name: corev1.Linux},
+				NodeSelector: map[string]string{corev1.LabelOSStable: "windows"},
+			},
+			want: corev1.Linux,
+		},
+		{
+			name: "node selector",
+			spec: corev1.PodSpec{NodeSelector: map[string]string{corev1.LabelOSStable: "windows"}},
+			want: corev1.Windows,
+		},
+		{
+			name: "runtime class scheduled on windows",
+			spec: corev1.PodSpec{RuntimeClassName: &windows},
+			want: corev1.Windows,
+		},
+		{
+			name: "runtime class without scheduling",
+			spec: corev1.PodSpec{RuntimeClassName: &gvisor},
+		},
+		{
+			name: "unknown runtime class",
+			spec: corev1.PodSpec{RuntimeClassName: &unknown},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := podOS(&tt.spec, runtimeClasses); got != tt.want {
+				t.Errorf("podOS() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := podOS(&corev1.PodSpec{RuntimeClassName: &windows}, nil); got != "" {
+		t.Errorf("podOS() without lister = %q, want empty", got)
+	}
+}
+
+// windowsRuntimeClass returns the runtime class windows, which schedules pods to Windows nodes
+func windowsRuntimeClass() *nodev1.RuntimeClass {
+	return &nodev1.RuntimeClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "windows"},
+		Handler:    "windows",
+		Scheduling: &nodev1.Scheduling{NodeSelector: map[string]string{corev1.LabelOSStable: string(corev1.Windows)}},
+	}
+}
@@ -0,0 +1,79 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// PriorityClassRuleName is the name of the rule restricting priority classes
+	PriorityClassRuleName = "priorityClass"
+	systemPriorityPrefix  = "system-"
+)
+
+// PriorityClassConfig configures which priority classes pods may use
+type PriorityClassConfig struct {
+	// Allowed lists the priority classes allowed in all namespaces. If empty, all non-system classes are allowed.
+	Allowed []string `json:"allowed"`
+	// Namespaces overrides the allowed priority classes per namespace
+	Namespaces map[string][]string `json:"namespaces"`
+	// SystemNamespaces may use the system-* priority classes, e.g. kube-system
+	SystemNamespaces []string `json:"systemNamespaces"`
+	// DenyPreemption denies pods outside of system namespaces which may preempt other pods
+	DenyPreemption bool `json:"denyPreemption"`
+}
+
+// priorityClassRule prevents tenant workloads from abusing priorities and starving system pods
+type priorityClassRule struct {
+	cfg PriorityClassConfig
+}
+
+func newPriorityClassRule(_ *CosignServerHandler, cfg *Config) (Rule, error) {
+	return &priorityClassRule{cfg: cfg.PriorityClass}, nil
+}
+
+// Name returns the name of the rule
+func (*priorityClassRule) Name() string {
+	return PriorityClassRuleName
+}
+
+// Validate checks the priority class and preemption policy of pods and workloads
+func (r *priorityClassRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	spec := templatePodSpec(o)
+	if spec == nil {
+		return nil, nil
+	}
+	ns := o.Request.Namespace
+	pc := spec.PriorityClassName
+	system := slices.Contains(r.cfg.SystemNamespaces, ns)
+	if system {
+		tracef(ctx, "namespace %q is a system namespace", ns)
+	}
+
+	if pc != "" && !system {
+		if strings.HasPrefix(pc, systemPriorityPrefix) {
+			return nil, fmt.Errorf("priority class %q is reserved for system namespaces", pc)
+		}
+		allowed, ok := r.cfg.Namespaces[ns]
+		if ok {
+			tracef(ctx, "priority classes of namespace %q", ns)
+		} else {
+			allowed = r.cfg.Allowed
+		}
+		if len(allowed) > 0 && !slices.Contains(allowed, pc) {
+			return nil, fmt.Errorf("priority class %q is not allowed in namespace %q, allowed: %s", pc, ns, strings.Join(allowed, ", "))
+		}
+	}
+
+	if r.cfg.DenyPreemption && !system {
+		p := spec.PreemptionPolicy
+		if p == nil || *p != corev1.PreemptNever {
+			return nil, fmt.Errorf("pods in namespace %q must not preempt other pods, preemptionPolicy must be %q", ns, corev1.PreemptNever)
+		}
+	}
+	return nil, nil
+}
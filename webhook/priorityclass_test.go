@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_priorityClassRule_Validate(t *testing.T) {
+	never := corev1.PreemptNever
+	lower := corev1.PreemptLowerPriority
+	cfg := PriorityClassConfig{
+		Allowed:          []string{"low"},
+		Namespaces:       map[string][]string{"team-a": {"low", "high"}},
+		SystemNamespaces: []string{"kube-system"},
+		DenyPreemption:   true,
+	}
+	tests := []struct {
+		name    string
+		ns      string
+		spec    corev1.PodSpec
+		wantErr bool
+	}{
+		{
+			name: "allowed class",
+			ns:   "default",
+			spec: corev1.PodSpec{PriorityClassName: "low", PreemptionPolicy: &never},
+		},
+		{
+			name:    "class not allowed",
+			ns:      "default",
+			spec:    corev1.PodSpec{PriorityClassName: "high", PreemptionPolicy: &never},
+			wantErr: true,
+		},
+		{
+			name: "namespace override",
+			ns:   "team-a",
+			spec: corev1.PodSpec{PriorityClassName: "high", PreemptionPolicy: &never},
+		},
+		{
+			name:    "system class in tenant namespace",
+			ns:      "team-a",
+			spec:    corev1.PodSpec{PriorityClassName: "system-cluster-critical", PreemptionPolicy: &never},
+			wantErr: true,
+		},
+		{
+			name: "system class in system namespace",
+			ns:   "kube-system",
+			spec: corev1.PodSpec{PriorityClassName: "system-cluster-critical", PreemptionPolicy: &lower},
+		},
+		{
+			name:    "preemption denied",
+			ns:      "default",
+			spec:    corev1.PodSpec{PreemptionPolicy: &lower},
+			wantErr: true,
+		},
+	}
+
+	r := &priorityClassRule{cfg: cfg}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Validate(context.Background(), podObject(tt.ns, tt.spec))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	v1 "k8s.io/api/admission/v1"
+)
+
+// PrivilegedRuleName is the name of the rule denying privileged containers
+const PrivilegedRuleName = "privileged"
+
+// PrivilegedConfig configures the namespaces which may run privileged containers
+type PrivilegedConfig struct {
+	// ExemptNamespaces may run privileged containers, e.g. CNI or storage drivers in kube-system
+	ExemptNamespaces []string `json:"exemptNamespaces"`
+	// Action is either deny (default) or warn
+	Action string `json:"action"`
+}
+
+// privilegedRule denies containers with securityContext.privileged, as they have full access to the node
+type privilegedRule struct {
+	cfg PrivilegedConfig
+}
+
+func newPrivilegedRule(_ *CosignServerHandler, cfg *Config) (Rule, error) {
+	c := cfg.Privileged
+	if err := validateAction(&c.Action, ActionDeny); err != nil {
+		return nil, err
+	}
+	return &privilegedRule{cfg: c}, nil
+}
+
+// Name returns the name of the rule
+func (*privilegedRule) Name() string {
+	return PrivilegedRuleName
+}
+
+// Validate checks the init, regular and ephemeral containers of pods and workloads
+func (r *privilegedRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	spec := podSpec(o)
+	if spec == nil || o.Request.Operation == v1.Delete {
+		return nil, nil
+	}
+	if slices.Contains(r.cfg.ExemptNamespaces, o.Request.Namespace) {
+		tracef(ctx, "namespace %q is exempt", o.Request.Namespace)
+		return nil, nil
+	}
+
+	var violations []string
+	for _, c := range podContainers(spec) {
+		if c.SecurityContext != nil && c.SecurityContext.Privileged != nil && *c.SecurityContext.Privileged {
+			violations = append(violations, fmt.Sprintf("container %q is privileged", c.Name))
+		}
+	}
+	return enforce(r.cfg.Action, violations)
+}
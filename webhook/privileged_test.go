@@ -0,0 +1,93 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_privilegedRule_Validate(t *testing.T) {
+	yes, no := true, false
+	privileged := &corev1.SecurityContext{Privileged: &yes}
+	tests := []struct {
+		name         string
+		namespace    string
+		spec         corev1.PodSpec
+		action       string
+		wantWarnings int
+		wantErr      string
+	}{
+		{
+			name: "unprivileged",
+			spec: corev1.PodSpec{Containers: []corev1.Container{
+				{Name: "app"},
+				{Name: "sidecar", SecurityContext: &corev1.SecurityContext{Privileged: &no}},
+			}},
+		},
+		{
+			name:    "privileged container",
+			spec:    corev1.PodSpec{Containers: []corev1.Container{{Name: "app", SecurityContext: privileged}}},
+			wantErr: `container "app" is privileged`,
+		},
+		{
+			name: "privileged init container",
+			spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{Name: "init", SecurityContext: privileged}},
+				Containers:     []corev1.Container{{Name: "app"}},
+			},
+			wantErr: `container "init" is privileged`,
+		},
+		{
+			name: "privileged ephemeral container",
+			spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app"}},
+				EphemeralContainers: []corev1.EphemeralContainer{{EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+					Name: "debug", SecurityContext: privileged,
+				}}},
+			},
+			wantErr: `container "debug" is privileged`,
+		},
+		{
+			name:      "exempt namespace",
+			namespace: "kube-system",
+			spec:      corev1.PodSpec{Containers: []corev1.Container{{Name: "cni", SecurityContext: privileged}}},
+		},
+		{
+			name:         "warn",
+			spec:         corev1.PodSpec{Containers: []corev1.Container{{Name: "app", SecurityContext: privileged}}},
+			action:       ActionWarn,
+			wantWarnings: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newPrivilegedRule(&CosignServerHandler{}, &Config{Privileged: PrivilegedConfig{
+				ExemptNamespaces: []string{"kube-system"},
+				Action:           tt.action,
+			}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ns := tt.namespace
+			if ns == "" {
+				ns = "default"
+			}
+			warnings, err := r.Validate(context.Background(), podObject(ns, tt.spec))
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("Validate() warnings = %v, want %d", warnings, tt.wantWarnings)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
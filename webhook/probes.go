@@ -0,0 +1,63 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// ProbesRuleName is the name of the rule requiring liveness and readiness probes
+const ProbesRuleName = "probes"
+
+// ProbesConfig configures in which namespaces containers need probes
+type ProbesConfig struct {
+	// Namespaces in which probes are required, empty means all namespaces
+	Namespaces []string `json:"namespaces"`
+	// ExcludedNamespaces opt out of the probe requirement
+	ExcludedNamespaces []string `json:"excludedNamespaces"`
+	// Action is either warn (default) or deny
+	Action string `json:"action"`
+}
+
+// probesRule requires liveness and readiness probes for operational hygiene
+type probesRule struct {
+	cfg ProbesConfig
+}
+
+func newProbesRule(_ *CosignServerHandler, cfg *Config) (Rule, error) {
+	c := cfg.Probes
+	if err := validateAction(&c.Action, ActionWarn); err != nil {
+		return nil, err
+	}
+	return &probesRule{cfg: c}, nil
+}
+
+// Name returns the name of the rule
+func (*probesRule) Name() string {
+	return ProbesRuleName
+}
+
+// Validate checks that all regular containers of pods and workloads define liveness and readiness probes
+func (r *probesRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	spec := templatePodSpec(o)
+	if spec == nil {
+		return nil, nil
+	}
+	ns := o.Request.Namespace
+	if slices.Contains(r.cfg.ExcludedNamespaces, ns) || (len(r.cfg.Namespaces) > 0 && !slices.Contains(r.cfg.Namespaces, ns)) {
+		tracef(ctx, "probes aren't required in namespace %q", ns)
+		return nil, nil
+	}
+
+	var violations []string
+	for i := range spec.Containers {
+		c := &spec.Containers[i]
+		if c.LivenessProbe == nil {
+			violations = append(violations, fmt.Sprintf("container %q has no liveness probe", c.Name))
+		}
+		if c.ReadinessProbe == nil {
+			violations = append(violations, fmt.Sprintf("container %q has no readiness probe", c.Name))
+		}
+	}
+	return enforce(r.cfg.Action, violations)
+}
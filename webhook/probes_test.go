@@ -0,0 +1,73 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_probesRule_Validate(t *testing.T) {
+	probe := &corev1.Probe{}
+	tests := []struct {
+		name         string
+		cfg          ProbesConfig
+		ns           string
+		container    corev1.Container
+		wantWarnings int
+		wantErr      bool
+	}{
+		{
+			name:      "probes present",
+			ns:        "default",
+			container: corev1.Container{Name: "app", LivenessProbe: probe, ReadinessProbe: probe},
+		},
+		{
+			name:         "missing probes warn by default",
+			ns:           "default",
+			container:    corev1.Container{Name: "app"},
+			wantWarnings: 2,
+		},
+		{
+			name:      "missing readiness probe denied",
+			cfg:       ProbesConfig{Action: ActionDeny},
+			ns:        "default",
+			container: corev1.Container{Name: "app", LivenessProbe: probe},
+			wantErr:   true,
+		},
+		{
+			name:      "namespace opted out",
+			cfg:       ProbesConfig{Action: ActionDeny, ExcludedNamespaces: []string{"sandbox"}},
+			ns:        "sandbox",
+			container: corev1.Container{Name: "app"},
+		},
+		{
+			name:      "namespace not selected",
+			cfg:       ProbesConfig{Action: ActionDeny, Namespaces: []string{"prod"}},
+			ns:        "dev",
+			container: corev1.Container{Name: "app"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newProbesRule(nil, &Config{Probes: tt.cfg})
+			if err != nil {
+				t.Fatal(err)
+			}
+			warnings, err := r.Validate(context.Background(), podObject(tt.ns, corev1.PodSpec{Containers: []corev1.Container{tt.container}}))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("Validate() got %d warnings, want %d", len(warnings), tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func Test_newProbesRule_invalidAction(t *testing.T) {
+	if _, err := newProbesRule(nil, &Config{Probes: ProbesConfig{Action: "block"}}); err == nil {
+		t.Error("expected error for invalid action")
+	}
+}
@@ -0,0 +1,296 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	log "github.com/gookit/slog"
+	"github.com/nats-io/nats.go"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// types of decision publishers
+const (
+	PublisherNATS      = "nats"
+	PublisherKafkaREST = "kafka-rest"
+	PublisherSyslog    = "syslog"
+	PublisherJournald  = "journald"
+)
+
+// serialization formats of published decisions
+const (
+	FormatJSON        = "json"
+	FormatCloudEvents = "cloudevents"
+)
+
+const publisherQueueSize = 1000
+
+var publishDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "cosign_publish_dropped_total",
+	Help: "The number of decisions not published because the queue was full or the broker failed",
+}, []string{"publisher"})
+
+// PublisherConfig configures a publisher pushing decisions to a message broker or an audit log
+type PublisherConfig struct {
+	// Type is nats, kafka-rest, syslog or journald
+	Type string `json:"type"`
+	// URL is the address of the NATS server, e.g. nats://nats:4222 or tls://nats:4222,
+	// the address of the Confluent Kafka REST proxy, e.g. http://kafka-rest-proxy:8082,
+	// the address of the syslog server, e.g. udp://syslog:514, tcp://syslog:601 or tls://syslog:6514,
+	// or the journald socket, defaults to unix:///run/systemd/journal/socket
+	URL string `json:"url"`
+	// Subject is the NATS subject or the Kafka topic
+	Subject string `json:"subject"`
+	// Facility is the syslog facility, defaults to 13 (log audit)
+	Facility *int `json:"facility"`
+	// Token authenticates at the NATS server or is sent as bearer token to the Kafka REST proxy
+	Token string `json:"token"`
+	// User and Password authenticate at the NATS server
+	User     string `json:"user"`
+	Password string `json:"password"`
+	// CAFile is a PEM file of the CAs verifying the NATS server with tls://, defaults to the system roots
+	CAFile string `json:"caFile"`
+	// Format is cloudevents (default) or json for the plain decision
+	Format string `json:"format"`
+	// OnlyDenied publishes only denied requests
+	OnlyDenied bool `json:"onlyDenied"`
+	// Retry configures the attempts of publishing a decision, defaults to 3 attempts within 30s
+	Retry RetryPolicy `json:"retry"`
+}
+
+// validate checks type and format of the publisher config and sets the default format
+func (c *PublisherConfig) validate() error {
+	switch c.Type {
+	case PublisherNATS, PublisherKafkaREST:
+		if c.URL == "" || c.Subject == "" {
+			return fmt.Errorf("%s publisher needs url and subject", c.Type)
+		}
+	case PublisherSyslog:
+		u, err := url.Parse(c.URL)
+		if err != nil || (u.Scheme != "udp" && u.Scheme != "tcp" && u.Scheme != "tls") {
+			return fmt.Errorf("syslog publisher needs url udp://, tcp:// or tls://, got %q", c.URL)
+		}
+		if c.Facility != nil && (*c.Facility < 0 || *c.Facility > maxSyslogFacility) {
+			return fmt.Errorf("invalid syslog facility %d", *c.Facility)
+		}
+	case PublisherJournald:
+		if c.URL == "" {
+			c.URL = defaultJournaldSocket
+		}
+	default:
+		return fmt.Errorf("unknown publisher type %q, must be %s, %s, %s or %s", c.Type, PublisherNATS, PublisherKafkaREST, PublisherSyslog, PublisherJournald)
+	}
+	if c.Type != PublisherNATS && (c.User != "" || c.Password != "" || c.CAFile != "") {
+		return fmt.Errorf("user, password and caFile are only supported by the %s publisher", PublisherNATS)
+	}
+	if c.Token != "" && c.User != "" {
+		return fmt.Errorf("%s publisher needs either token or user", c.Type)
+	}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return fmt.Errorf("can't read CA file of %s publisher: %w", c.Type, err)
+		}
+		if !x509.NewCertPool().AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in CA file %q of %s publisher", c.CAFile, c.Type)
+		}
+	}
+	if c.Format == "" {
+		c.Format = FormatCloudEvents
+	}
+	if c.Format != FormatJSON && c.Format != FormatCloudEvents {
+		return fmt.Errorf("unknown publisher format %q, must be %s or %s", c.Format, FormatJSON, FormatCloudEvents)
+	}
+	return c.Retry.validate(defaultRetryPolicy)
+}
+
+// broker sends the encoded decisions to a message broker or audit log
+type broker interface {
+	send(ctx context.Context, d *Decision, msg []byte) error
+	close()
+}
+
+// decisionPublisher queues decisions and publishes them to a broker in the background,
+// so admission requests never wait for the broker
+type decisionPublisher struct {
+	cfg    PublisherConfig
+	broker broker
+	queue  chan *Decision
+}
+
+func newDecisionPublisher(cfg PublisherConfig, e *egress) *decisionPublisher {
+	p := &decisionPublisher{cfg: cfg, queue: make(chan *Decision, publisherQueueSize)}
+	switch cfg.Type {
+	case PublisherNATS:
+		p.broker = &natsBroker{cfg: cfg}
+	case PublisherKafkaREST:
+		p.broker = &kafkaRESTBroker{url: cfg.URL, topic: cfg.Subject, token: cfg.Token, client: e.client(cfg.Retry.Timeout.Duration)}
+	case PublisherSyslog:
+		facility := syslogFacilityLogAudit
+		if cfg.Facility != nil {
+			facility = *cfg.Facility
+		}
+		p.broker = &syslogBroker{url: cfg.URL, facility: facility}
+	case PublisherJournald:
+		p.broker = &journaldBroker{socket: strings.TrimPrefix(cfg.URL, "unix://")}
+	}
+	return p
+}
+
+// enqueue queues the decision for publishing, it's dropped if the queue is full
+func (p *decisionPublisher) enqueue(d *Decision) {
+	if p.cfg.OnlyDenied && d.Allowed {
+		return
+	}
+	select {
+	case p.queue <- d:
+	default:
+		publishDropped.WithLabelValues(p.cfg.Type).Inc()
+	}
+}
+
+// run publishes the queued decisions until ctx is done
+func (p *decisionPublisher) run(ctx context.Context) {
+	log.Infof("Publishing decisions to %s %s", p.cfg.Type, p.cfg.Subject)
+	defer p.broker.close()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-p.queue:
+			msg, err := encodeDecision(d, p.cfg.Format)
+			if err == nil {
+				err = retry(ctx, &p.cfg.Retry, integrationPublisher, destinationOf(p.cfg.URL), func(ctx context.Context) error {
+					return p.broker.send(ctx, d, msg)
+				})
+			}
+			if err != nil {
+				publishDropped.WithLabelValues(p.cfg.Type).Inc()
+				log.Warnf("Can't publish decision to %s: %v", p.cfg.Type, err)
+			}
+		}
+	}
+}
+
+// encodeDecision serializes the decision in passed format
+func encodeDecision(d *Decision, format string) ([]byte, error) {
+	if format == FormatCloudEvents {
+		return json.Marshal(decisionEvent(d))
+	}
+	return json.Marshal(d)
+}
+
+// natsBroker publishes messages to a NATS subject with the NATS client, which reconnects with the backoff of the
+// retry policy and buffers the messages published while reconnecting
+type natsBroker struct {
+	cfg PublisherConfig
+
+	mu   sync.Mutex
+	conn *nats.Conn
+}
+
+// send publishes the message, connecting first if there is no connection
+func (b *natsBroker) send(_ context.Context, _ *Decision, msg []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.conn == nil || b.conn.IsClosed() {
+		conn, err := nats.Connect(b.cfg.URL, b.options()...)
+		if err != nil {
+			return err
+		}
+		b.conn = conn
+	}
+	return b.conn.Publish(b.cfg.Subject, msg)
+}
+
+// options returns the options of the NATS client: authentication, the CAs verifying the server and reconnects
+func (b *natsBroker) options() []nats.Option {
+	opts := []nats.Option{
+		nats.Name("cosignwebhook"),
+		nats.Timeout(k8sTimeout),
+		nats.MaxReconnects(-1),
+		nats.CustomReconnectDelay(b.cfg.Retry.backoff),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				log.Warnf("Disconnected from NATS: %v", err)
+			}
+		}),
+		nats.ReconnectHandler(func(*nats.Conn) {
+			log.Infof("Reconnected to NATS")
+		}),
+		nats.ErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, err error) {
+			log.Warnf("NATS error: %v", err)
+		}),
+	}
+	if strings.HasPrefix(b.cfg.URL, "tls://") {
+		opts = append(opts, nats.Secure(&tls.Config{MinVersion: tls.VersionTLS12}))
+	}
+	if b.cfg.CAFile != "" {
+		opts = append(opts, nats.RootCAs(b.cfg.CAFile))
+	}
+	if b.cfg.Token != "" {
+		opts = append(opts, nats.Token(b.cfg.Token))
+	}
+	if b.cfg.User != "" {
+		opts = append(opts, nats.UserInfo(b.cfg.User, b.cfg.Password))
+	}
+	return opts
+}
+
+// close flushes the buffered messages and closes the connection
+func (b *natsBroker) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.conn != nil {
+		b.conn.Close()
+		b.conn = nil
+	}
+}
+
+// kafkaRESTBroker produces messages to a Kafka topic through the Confluent Kafka REST proxy (API v2)
+type kafkaRESTBroker struct {
+	url    string
+	topic  string
+	token  string
+	client *http.Client
+}
+
+// send produces the message with the request UID as key
+func (b *kafkaRESTBroker) send(ctx context.Context, d *Decision, msg []byte) error {
+	body, err := json.Marshal(map[string]any{
+		"records": []map[string]any{{"key": d.UID, "value": json.RawMessage(msg)}},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(b.url, "/")+"/topics/"+url.PathEscape(b.topic), bytes.NewReader(body))
+	if err != nil {
+		return permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	if b.token != "" {
+		req.Header.Set("Authorization", "Bearer "+b.token)
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return unexpectedStatus(resp, "unexpected status %s from Kafka REST proxy")
+	}
+	return nil
+}
+
+func (*kafkaRESTBroker) close() {}
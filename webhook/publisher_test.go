@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPublisherConfig_validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     PublisherConfig
+		wantErr bool
+	}{
+		{name: "nats", cfg: PublisherConfig{Type: PublisherNATS, URL: "nats://nats:4222", Subject: "decisions"}},
+		{name: "nats user", cfg: PublisherConfig{Type: PublisherNATS, URL: "nats://nats:4222", Subject: "decisions", User: "webhook", Password: "secret"}},
+		{name: "nats token and user", cfg: PublisherConfig{Type: PublisherNATS, URL: "nats://nats:4222", Subject: "decisions", Token: "t", User: "webhook"}, wantErr: true},
+		{name: "nats missing CA file", cfg: PublisherConfig{Type: PublisherNATS, URL: "tls://nats:4222", Subject: "decisions", CAFile: "/nonexistent/ca.crt"}, wantErr: true},
+		{name: "kafka-rest cloudevents", cfg: PublisherConfig{Type: PublisherKafkaREST, URL: "http://kafka:8082", Subject: "decisions", Format: FormatCloudEvents}},
+		{name: "kafka-rest user", cfg: PublisherConfig{Type: PublisherKafkaREST, URL: "http://kafka:8082", Subject: "decisions", User: "webhook"}, wantErr: true},
+		{name: "unknown type", cfg: PublisherConfig{Type: "amqp", URL: "amqp://mq", Subject: "decisions"}, wantErr: true},
+		{name: "unknown format", cfg: PublisherConfig{Type: PublisherNATS, URL: "nats://nats:4222", Subject: "decisions", Format: "xml"}, wantErr: true},
+		{name: "missing subject", cfg: PublisherConfig{Type: PublisherNATS, URL: "nats://nats:4222"}, wantErr: true},
+		{name: "syslog tls", cfg: PublisherConfig{Type: PublisherSyslog, URL: "tls://syslog:6514"}},
+		{name: "syslog without scheme", cfg: PublisherConfig{Type: PublisherSyslog, URL: "syslog:514"}, wantErr: true},
+		{name: "journald", cfg: PublisherConfig{Type: PublisherJournald}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_kafkaRESTBroker_send(t *testing.T) {
+	var got struct {
+		Records []struct {
+			Key   string   `json:"key"`
+			Value Decision `json:"value"`
+		} `json:"records"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/topics/decisions" || r.Header.Get("Content-Type") != "application/vnd.kafka.json.v2+json" {
+			t.Errorf("unexpected request %s %s", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	b := &kafkaRESTBroker{url: srv.URL, topic: "decisions", client: srv.Client()}
+	msg, _ := encodeDecision(&Decision{UID: "123", Name: "test"}, FormatJSON)
+	if err := b.send(context.Background(), &Decision{UID: "123"}, msg); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Records) != 1 || got.Records[0].Key != "123" || got.Records[0].Value.Name != "test" {
+		t.Errorf("unexpected records: %+v", got)
+	}
+}
+
+func Test_natsBroker_send(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	published := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.WriteString(conn, `INFO {"max_payload":1048576}`+"\r\n")
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch {
+			case strings.HasPrefix(line, "PING"):
+				_, _ = io.WriteString(conn, "PONG\r\n")
+			case strings.HasPrefix(line, "PUB decisions "):
+				payload, _ := r.ReadString('\n')
+				published <- strings.TrimSpace(payload)
+			}
+		}
+	}()
+
+	cfg := PublisherConfig{Type: PublisherNATS, URL: "nats://" + l.Addr().String(), Subject: "decisions"}
+	if err := cfg.validate(); err != nil {
+		t.Fatal(err)
+	}
+	b := &natsBroker{cfg: cfg}
+	defer b.close()
+	msg, _ := encodeDecision(&Decision{UID: "123"}, FormatCloudEvents)
+	if err := b.send(context.Background(), &Decision{UID: "123"}, msg); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-published; got != string(msg) {
+		t.Errorf("published %q, want %q", got, msg)
+	}
+}
@@ -0,0 +1,194 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	defaultQueueMaxInFlight          = 64
+	defaultQueueNamespaceMaxInFlight = 8
+	defaultQueueNamespaceMaxQueued   = 100
+)
+
+var errQueueFull = errors.New("admission queue of namespace is full")
+
+var (
+	queueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cosign_queue_depth",
+		Help: "The number of admission requests waiting in the fair queue, by namespace",
+	}, []string{"namespace"})
+	queueInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "cosign_queue_inflight",
+		Help: "The number of admission requests evaluated by the fair queue",
+	})
+	queuePriority = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "cosign_queue_priority_total",
+		Help: "The number of admission requests of priority namespaces which bypassed the fair queue",
+	})
+	queueRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cosign_queue_rejected_total",
+		Help: "The number of admission requests rejected because the queue of their namespace was full or they timed out waiting",
+	}, []string{"namespace"})
+)
+
+// QueueConfig configures the fair queueing of admission requests by namespace, so a namespace with a burst
+// of admissions can't starve the requests of other namespaces
+type QueueConfig struct {
+	// Enabled queues the admission requests of all endpoints
+	Enabled bool `json:"enabled"`
+	// MaxInFlight is the number of requests evaluated at the same time, defaults to 64
+	MaxInFlight int `json:"maxInFlight"`
+	// NamespaceMaxInFlight is the number of requests of a namespace evaluated at the same time, defaults to 8
+	NamespaceMaxInFlight int `json:"namespaceMaxInFlight"`
+	// NamespaceMaxQueued is the number of requests of a namespace waiting, more are rejected. Defaults to 100.
+	NamespaceMaxQueued int `json:"namespaceMaxQueued"`
+	// PriorityNamespaces bypass the queue and its limits, e.g. kube-system, so cluster-critical components
+	// are never delayed by the load of tenants
+	PriorityNamespaces []string `json:"priorityNamespaces"`
+}
+
+// fairQueue limits the requests in flight overall and by namespace. Waiting requests are dispatched round robin
+// over the namespaces, first in first out within a namespace.
+type fairQueue struct {
+	maxInFlight, nsMaxInFlight, nsMaxQueued int
+	priority                                []string
+
+	mu       sync.Mutex
+	inFlight int
+	active   map[string]int
+	waiting  map[string][]chan struct{}
+	// order are the namespaces with waiting requests in round robin order
+	order []string
+}
+
+// newFairQueue returns the fair queue, or nil if it is disabled
+func newFairQueue(cfg QueueConfig) *fairQueue {
+	if !cfg.Enabled {
+		return nil
+	}
+	q := &fairQueue{
+		maxInFlight:   cfg.MaxInFlight,
+		nsMaxInFlight: cfg.NamespaceMaxInFlight,
+		nsMaxQueued:   cfg.NamespaceMaxQueued,
+		priority:      cfg.PriorityNamespaces,
+		active:        map[string]int{},
+		waiting:       map[string][]chan struct{}{},
+	}
+	if q.maxInFlight <= 0 {
+		q.maxInFlight = defaultQueueMaxInFlight
+	}
+	if q.nsMaxInFlight <= 0 {
+		q.nsMaxInFlight = defaultQueueNamespaceMaxInFlight
+	}
+	if q.nsMaxQueued <= 0 {
+		q.nsMaxQueued = defaultQueueNamespaceMaxQueued
+	}
+	return q
+}
+
+// acquire waits until a request of the namespace may be evaluated and returns the func to call when it's done.
+// It fails if the queue of the namespace is full or ctx is done while waiting. Requests of priority namespaces
+// don't wait and don't count against the limits.
+func (q *fairQueue) acquire(ctx context.Context, ns string) (func(), error) {
+	if slices.Contains(q.priority, ns) {
+		queuePriority.Inc()
+		return func() {}, nil
+	}
+	release := func() { q.release(ns) }
+
+	q.mu.Lock()
+	if len(q.waiting[ns]) == 0 && q.inFlight < q.maxInFlight && q.active[ns] < q.nsMaxInFlight {
+		q.start(ns)
+		q.mu.Unlock()
+		return release, nil
+	}
+	if len(q.waiting[ns]) >= q.nsMaxQueued {
+		q.mu.Unlock()
+		queueRejected.WithLabelValues(ns).Inc()
+		return nil, errQueueFull
+	}
+	ready := make(chan struct{})
+	if len(q.waiting[ns]) == 0 {
+		q.order = append(q.order, ns)
+	}
+	q.waiting[ns] = append(q.waiting[ns], ready)
+	queueDepth.WithLabelValues(ns).Inc()
+	q.mu.Unlock()
+
+	select {
+	case <-ready:
+		return release, nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		i := slices.Index(q.waiting[ns], ready)
+		if i < 0 {
+			// dispatched meanwhile, give the slot to the next request
+			q.releaseLocked(ns)
+		} else {
+			q.remove(ns, i)
+		}
+		queueRejected.WithLabelValues(ns).Inc()
+		return nil, ctx.Err()
+	}
+}
+
+func (q *fairQueue) release(ns string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.releaseLocked(ns)
+}
+
+func (q *fairQueue) releaseLocked(ns string) {
+	q.inFlight--
+	queueInFlight.Dec()
+	if q.active[ns]--; q.active[ns] <= 0 {
+		delete(q.active, ns)
+	}
+	q.dispatch()
+}
+
+func (q *fairQueue) start(ns string) {
+	q.inFlight++
+	queueInFlight.Inc()
+	q.active[ns]++
+}
+
+// dispatch starts waiting requests round robin over the namespaces until the limits are reached
+func (q *fairQueue) dispatch() {
+	for i := 0; i < len(q.order) && q.inFlight < q.maxInFlight; {
+		ns := q.order[i]
+		if q.active[ns] >= q.nsMaxInFlight {
+			i++
+			continue
+		}
+		ready := q.waiting[ns][0]
+		q.remove(ns, 0)
+		q.start(ns)
+		close(ready)
+		// the namespace goes to the end of the round, the next namespace moved to i
+		if j := slices.Index(q.order, ns); j >= 0 {
+			q.order = append(slices.Delete(q.order, j, j+1), ns)
+		}
+	}
+}
+
+// remove removes the i-th waiting request of the namespace
+func (q *fairQueue) remove(ns string, i int) {
+	q.waiting[ns] = slices.Delete(q.waiting[ns], i, i+1)
+	queueDepth.WithLabelValues(ns).Dec()
+	if len(q.waiting[ns]) > 0 {
+		return
+	}
+	delete(q.waiting, ns)
+	queueDepth.DeleteLabelValues(ns)
+	if j := slices.Index(q.order, ns); j >= 0 {
+		q.order = slices.Delete(q.order, j, j+1)
+	}
+}
@@ -0,0 +1,73 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_fairQueue(t *testing.T) {
+	if q := newFairQueue(QueueConfig{}); q != nil {
+		t.Fatal("newFairQueue() disabled != nil")
+	}
+	q := newFairQueue(QueueConfig{Enabled: true, MaxInFlight: 2, NamespaceMaxInFlight: 1, NamespaceMaxQueued: 2, PriorityNamespaces: []string{"kube-system"}})
+	ctx := context.Background()
+
+	releaseBurst, err := q.acquire(ctx, "burst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the second request of burst waits for the namespace limit
+	started := make(chan string, 4)
+	wait := func(ns string) {
+		release, err := q.acquire(ctx, ns)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		started <- ns
+		release()
+	}
+	go wait("burst")
+	go wait("burst")
+	time.Sleep(20 * time.Millisecond)
+	if _, err := q.acquire(ctx, "burst"); !errors.Is(err, errQueueFull) {
+		t.Errorf("acquire() on full queue error = %v, want %v", err, errQueueFull)
+	}
+
+	// other namespaces are not starved by the waiting requests of burst
+	releaseOther, err := q.acquire(ctx, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := q.acquire(timeout, "third"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("acquire() over the overall limit error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	// priority namespaces bypass the limits
+	releasePriority, err := q.acquire(ctx, "kube-system")
+	if err != nil {
+		t.Fatalf("acquire() of priority namespace error = %v", err)
+	}
+	releasePriority()
+	releaseOther()
+
+	releaseBurst()
+	for range 2 {
+		select {
+		case ns := <-started:
+			if ns != "burst" {
+				t.Errorf("started %s, want burst", ns)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("waiting requests not dispatched")
+		}
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.inFlight != 0 || len(q.active) != 0 || len(q.waiting) != 0 || len(q.order) != 0 {
+		t.Errorf("queue not empty: inFlight %d, active %v, waiting %v, order %v", q.inFlight, q.active, q.waiting, q.order)
+	}
+}
@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	v1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// QuotaRuleName is the name of the rule checking resource quotas at admission
+const QuotaRuleName = "quota"
+
+// quotaRule denies pods which would exceed the remaining resource quota of their namespace.
+// The quota admission plugin would reject them anyway, but with a less helpful message.
+type quotaRule struct{}
+
+func newQuotaRule(_ *CosignServerHandler, _ *Config) (Rule, error) {
+	return &quotaRule{}, nil
+}
+
+// Name returns the name of the rule
+func (*quotaRule) Name() string {
+	return QuotaRuleName
+}
+
+// stateful marks the verdicts as not memoizable, the quota usage changes with each pod
+func (*quotaRule) stateful() {}
+
+// Facts returns the quota usage of the namespace
+func (*quotaRule) Facts() []Fact {
+	return []Fact{FactQuotaUsage}
+}
+
+// Validate compares the pod's usage with the remaining quota of all quotas in its namespace.
+// Quotas with scopes are skipped, as their applicability depends on more than the pod spec.
+func (r *quotaRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	if o.Pod == nil || o.Request.Operation != v1.Create {
+		return nil, nil
+	}
+	v, err := o.Facts.Get(FactQuotaUsage)
+	if err != nil {
+		return nil, internalError(fmt.Errorf("can't check resource quotas: %w", err))
+	}
+	quotas := v.(map[string]QuotaUsage)
+
+	usage := podQuotaUsage(&o.Pod.Spec)
+	var violations []string
+	for _, name := range slices.Sorted(maps.Keys(quotas)) {
+		q := quotas[name]
+		if q.Scoped {
+			tracef(ctx, "quota %q has scopes, skipped", name)
+			continue
+		}
+		for _, n := range slices.Sorted(maps.Keys(q.Hard)) {
+			hard := q.Hard[n]
+			req, ok := usage[n]
+			if !ok {
+				continue
+			}
+			used := q.Used[n]
+			remaining := hard.DeepCopy()
+			remaining.Sub(used)
+			if req.Cmp(remaining) > 0 {
+				violations = append(violations, fmt.Sprintf("exceeded quota %q: requested %s=%s, used %s of %s",
+					name, n, req.String(), used.String(), hard.String()))
+			}
+		}
+	}
+	return nil, violationsError(violations)
+}
+
+// podQuotaUsage returns the quota usage of the pod by quota resource name
+func podQuotaUsage(spec *corev1.PodSpec) corev1.ResourceList {
+	usage := corev1.ResourceList{
+		corev1.ResourcePods: resource.MustParse("1"),
+	}
+	for n, q := range podRequests(spec) {
+		usage[corev1.ResourceName(corev1.DefaultResourceRequestsPrefix+string(n))] = q
+		if n == corev1.ResourceCPU || n == corev1.ResourceMemory || n == corev1.ResourceEphemeralStorage {
+			usage[n] = q
+		}
+	}
+	for i := range spec.Containers {
+		for n, q := range spec.Containers[i].Resources.Limits {
+			if strings.Contains(string(n), "/") {
+				// extended resources can't be overcommitted, only their requests are counted
+				continue
+			}
+			name := corev1.ResourceName("limits." + string(n))
+			sum := usage[name]
+			sum.Add(q)
+			usage[name] = sum
+		}
+	}
+	return usage
+}
@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func Test_quotaRule_Validate(t *testing.T) {
+	compute := QuotaUsage{
+		Hard: corev1.ResourceList{
+			corev1.ResourceRequestsCPU: resource.MustParse("4"),
+			corev1.ResourcePods:        resource.MustParse("10"),
+		},
+		Used: corev1.ResourceList{
+			corev1.ResourceRequestsCPU: resource.MustParse("3"),
+			corev1.ResourcePods:        resource.MustParse("2"),
+		},
+	}
+	scoped := QuotaUsage{
+		Scoped: true,
+		Hard:   compute.Hard,
+		Used:   corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("4")},
+	}
+	quotas := &Facts{values: map[Fact]any{FactQuotaUsage: map[string]QuotaUsage{"compute": compute, "scoped": scoped}}}
+	noQuotas := &Facts{values: map[Fact]any{FactQuotaUsage: map[string]QuotaUsage{}}}
+
+	tests := []struct {
+		name    string
+		facts   *Facts
+		cpu     string
+		wantErr string
+	}{
+		{
+			name:  "fits into quota",
+			facts: quotas,
+			cpu:   "500m",
+		},
+		{
+			name:    "exceeds quota",
+			facts:   quotas,
+			cpu:     "2",
+			wantErr: `exceeded quota "compute": requested requests.cpu=2, used 3 of 4`,
+		},
+		{
+			name:  "no quota in namespace",
+			facts: noQuotas,
+			cpu:   "100",
+		},
+		{
+			name:    "quota usage unknown",
+			facts:   &Facts{errs: map[Fact]error{FactQuotaUsage: errors.New("informer resourcequotas not synced")}},
+			cpu:     "500m",
+			wantErr: "can't check resource quotas: informer resourcequotas not synced",
+		},
+	}
+
+	r, err := newQuotaRule(nil, &Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := podObject("team-a", corev1.PodSpec{Containers: []corev1.Container{{
+				Name: "app",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(tt.cpu)},
+				},
+			}}})
+			o.Request.Operation = v1.Create
+			o.Facts = tt.facts
+			_, err := r.Validate(context.Background(), o)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	v1 "k8s.io/api/admission/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// RBACRuleName is the name of the rule validating roles and bindings
+const RBACRuleName = "rbac"
+
+// RBACConfig configures the validation of roles and bindings
+type RBACConfig struct {
+	// TenantNamespaces are regular expressions matching the whole name of namespaces whose roles
+	// must not use wildcard verbs, resources or API groups. Empty applies to all namespaces.
+	TenantNamespaces []string `json:"tenantNamespaces"`
+	// PrivilegedClusterRoles are the cluster roles only allowed subjects may be bound to, defaults to cluster-admin
+	PrivilegedClusterRoles []string `json:"privilegedClusterRoles"`
+	// AllowedSubjects may be bound to the privileged cluster roles. An empty namespace matches all namespaces.
+	AllowedSubjects []rbacv1.Subject `json:"allowedSubjects"`
+}
+
+// rbacRule forbids wildcards in tenant roles and bindings to privileged cluster roles
+type rbacRule struct {
+	tenantNamespaces       *patternSet
+	privilegedClusterRoles []string
+	allowedSubjects        []rbacv1.Subject
+}
+
+func newRBACRule(_ *CosignServerHandler, cfg *Config) (Rule, error) {
+	c := cfg.RBAC
+	res, err := compilePatternSet(c.TenantNamespaces)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.PrivilegedClusterRoles) == 0 {
+		c.PrivilegedClusterRoles = []string{"cluster-admin"}
+	}
+	return &rbacRule{
+		tenantNamespaces:       res,
+		privilegedClusterRoles: c.PrivilegedClusterRoles,
+		allowedSubjects:        c.AllowedSubjects,
+	}, nil
+}
+
+// Name returns the name of the rule
+func (*rbacRule) Name() string {
+	return RBACRuleName
+}
+
+// Validate checks the rules of roles in tenant namespaces and the subjects of bindings to privileged cluster roles
+func (r *rbacRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	if o.Request.Operation == v1.Delete {
+		return nil, nil
+	}
+	var violations []string
+	switch {
+	case o.Role != nil:
+		if r.tenantNamespaces.empty() || r.tenantNamespaces.matches(o.Request.Namespace) {
+			violations = wildcardRules(o.Role.Rules)
+		} else {
+			tracef(ctx, "namespace %q is no tenant namespace", o.Request.Namespace)
+		}
+	case o.RoleBinding != nil:
+		violations = r.privilegedSubjects(o.RoleBinding.RoleRef, o.RoleBinding.Subjects, o.Request.Namespace)
+	case o.ClusterRoleBinding != nil:
+		violations = r.privilegedSubjects(o.ClusterRoleBinding.RoleRef, o.ClusterRoleBinding.Subjects, "")
+	}
+	return nil, violationsError(violations)
+}
+
+// wildcardRules returns the policy rules using wildcard verbs, resources or API groups
+func wildcardRules(rules []rbacv1.PolicyRule) []string {
+	var violations []string
+	for i, pr := range rules {
+		for field, values := range map[string][]string{"verbs": pr.Verbs, "resources": pr.Resources, "apiGroups": pr.APIGroups} {
+			if slices.Contains(values, rbacv1.VerbAll) {
+				violations = append(violations, fmt.Sprintf("rule %d must not use wildcard %s", i, field))
+			}
+		}
+	}
+	slices.Sort(violations)
+	return violations
+}
+
+// privilegedSubjects returns the subjects bound to a privileged cluster role which are not allowed.
+// ns is the namespace of role bindings, used for service accounts without namespace.
+func (r *rbacRule) privilegedSubjects(ref rbacv1.RoleRef, subjects []rbacv1.Subject, ns string) []string {
+	if ref.Kind != "ClusterRole" || !slices.Contains(r.privilegedClusterRoles, ref.Name) {
+		return nil
+	}
+	var violations []string
+	for _, s := range subjects {
+		if s.Kind == rbacv1.ServiceAccountKind && s.Namespace == "" {
+			s.Namespace = ns
+		}
+		if !slices.ContainsFunc(r.allowedSubjects, func(a rbacv1.Subject) bool {
+			return a.Kind == s.Kind && a.Name == s.Name && (a.Namespace == "" || a.Namespace == s.Namespace)
+		}) {
+			violations = append(violations, fmt.Sprintf("%s %q must not be bound to cluster role %q", s.Kind, s.Name, ref.Name))
+		}
+	}
+	return violations
+}
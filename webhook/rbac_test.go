@@ -0,0 +1,84 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/admission/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_rbacRule_Validate(t *testing.T) {
+	cfg := &Config{RBAC: RBACConfig{
+		TenantNamespaces: []string{"team-.*"},
+		AllowedSubjects:  []rbacv1.Subject{{Kind: rbacv1.GroupKind, Name: "platform-admins"}},
+	}}
+	r, err := newRBACRule(nil, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := func(ns, kind string) *v1.AdmissionRequest {
+		return &v1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: kind},
+			Namespace: ns,
+			Operation: v1.Create,
+		}
+	}
+	role := func(ns string, verbs ...string) *Object {
+		return &Object{Request: request(ns, "Role"), Role: &rbacv1.Role{
+			Rules: []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: verbs}},
+		}}
+	}
+	clusterAdmin := rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "cluster-admin"}
+
+	tests := []struct {
+		name    string
+		o       *Object
+		wantErr bool
+	}{
+		{
+			name: "tenant role without wildcards",
+			o:    role("team-a", "get", "list"),
+		},
+		{
+			name:    "tenant role with wildcard verbs",
+			o:       role("team-a", "*"),
+			wantErr: true,
+		},
+		{
+			name: "wildcard outside tenant namespaces",
+			o:    role("kube-system", "*"),
+		},
+		{
+			name: "allowed cluster admin binding",
+			o: &Object{Request: request("", "ClusterRoleBinding"), ClusterRoleBinding: &rbacv1.ClusterRoleBinding{
+				RoleRef:  clusterAdmin,
+				Subjects: []rbacv1.Subject{{Kind: rbacv1.GroupKind, Name: "platform-admins"}},
+			}},
+		},
+		{
+			name: "cluster admin bound to service account",
+			o: &Object{Request: request("team-a", "RoleBinding"), RoleBinding: &rbacv1.RoleBinding{
+				RoleRef:  clusterAdmin,
+				Subjects: []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "default"}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "binding to other cluster role",
+			o: &Object{Request: request("team-a", "RoleBinding"), RoleBinding: &rbacv1.RoleBinding{
+				RoleRef:  rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "view"},
+				Subjects: []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "default"}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Validate(context.Background(), tt.o)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	v1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	nodev1listers "k8s.io/client-go/listers/node/v1"
+)
+
+const (
+	// ReadOnlyRootFilesystemRuleName is the name of the rule requiring read-only root filesystems
+	ReadOnlyRootFilesystemRuleName = "readOnlyRootFilesystem"
+	// WritableRootFilesystemAnnotation exempts the containers of a pod or pod template from the rule, "true" for all
+	// containers or a comma-separated list of container names
+	WritableRootFilesystemAnnotation = "cosignwebhook.eumel8.io/writable-root-filesystem"
+)
+
+// ReadOnlyRootFilesystemConfig configures the namespaces and annotation exempting containers from read-only root
+// filesystems
+type ReadOnlyRootFilesystemConfig struct {
+	// ExemptNamespaces may run containers with writable root filesystems
+	ExemptNamespaces []string `json:"exemptNamespaces"`
+	// Annotation exempting containers, defaults to cosignwebhook.eumel8.io/writable-root-filesystem
+	Annotation string `json:"annotation"`
+	// Action is either deny (default) or warn
+	Action string `json:"action"`
+}
+
+// readOnlyRootFilesystemRule requires readOnlyRootFilesystem: true in the securityContext of all containers, except
+// the ones exempted by the annotation of the pod or pod template
+type readOnlyRootFilesystemRule struct {
+	cfg ReadOnlyRootFilesystemConfig
+	// runtimeClasses detect Windows pods by the node selector of their runtime class
+	runtimeClasses nodev1listers.RuntimeClassLister
+}
+
+func newReadOnlyRootFilesystemRule(csh *CosignServerHandler, cfg *Config) (Rule, error) {
+	c := cfg.ReadOnlyRootFilesystem
+	if err := validateAction(&c.Action, ActionDeny); err != nil {
+		return nil, err
+	}
+	if c.Annotation == "" {
+		c.Annotation = WritableRootFilesystemAnnotation
+	}
+	return &readOnlyRootFilesystemRule{cfg: c, runtimeClasses: csh.informers.Node().V1().RuntimeClasses().Lister()}, nil
+}
+
+// Name returns the name of the rule
+func (*readOnlyRootFilesystemRule) Name() string {
+	return ReadOnlyRootFilesystemRuleName
+}
+
+// Validate checks the root filesystem of the init, regular and ephemeral containers of pods and workloads. The
+// annotation is read from the pod template, so the pods created by a workload are exempted like the workload.
+// Windows doesn't support read-only root filesystems, so Windows pods are skipped.
+func (r *readOnlyRootFilesystemRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	meta, spec := podTemplate(o)
+	if spec == nil || o.Request.Operation == v1.Delete {
+		return nil, nil
+	}
+	if slices.Contains(r.cfg.ExemptNamespaces, o.Request.Namespace) {
+		tracef(ctx, "namespace %q is exempt", o.Request.Namespace)
+		return nil, nil
+	}
+	if podOS(spec, r.runtimeClasses) == corev1.Windows {
+		tracef(ctx, "Windows pod")
+		return nil, nil
+	}
+	exempt := strings.TrimSpace(meta.Annotations[r.cfg.Annotation])
+	if exempt == "true" {
+		tracef(ctx, "exempt by annotation %s", r.cfg.Annotation)
+		return nil, nil
+	}
+	var exemptContainers []string
+	for _, n := range strings.Split(exempt, ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			exemptContainers = append(exemptContainers, n)
+		}
+	}
+
+	var violations []string
+	for _, c := range podContainers(spec) {
+		if slices.Contains(exemptContainers, c.Name) {
+			tracef(ctx, "container %q is exempt by annotation %s", c.Name, r.cfg.Annotation)
+			continue
+		}
+		if sc := c.SecurityContext; sc == nil || sc.ReadOnlyRootFilesystem == nil || !*sc.ReadOnlyRootFilesystem {
+			violations = append(violations, fmt.Sprintf("container %q has a writable root filesystem, set readOnlyRootFilesystem: true or annotate the pod with %s",
+				c.Name, r.cfg.Annotation))
+		}
+	}
+	return enforce(r.cfg.Action, violations)
+}
@@ -0,0 +1,103 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_readOnlyRootFilesystemRule_Validate(t *testing.T) {
+	yes, no := true, false
+	windows := "windows"
+	tests := []struct {
+		name         string
+		namespace    string
+		annotations  map[string]string
+		spec         corev1.PodSpec
+		cfg          ReadOnlyRootFilesystemConfig
+		wantWarnings int
+		wantErr      string
+	}{
+		{
+			name: "read-only",
+			spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", SecurityContext: &corev1.SecurityContext{ReadOnlyRootFilesystem: &yes}}}},
+		},
+		{
+			name:    "no security context",
+			spec:    corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+			wantErr: `container "app" has a writable root filesystem`,
+		},
+		{
+			name: "writable init container",
+			spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{Name: "init", SecurityContext: &corev1.SecurityContext{ReadOnlyRootFilesystem: &no}}},
+				Containers:     []corev1.Container{{Name: "app", SecurityContext: &corev1.SecurityContext{ReadOnlyRootFilesystem: &yes}}},
+			},
+			wantErr: `container "init" has a writable root filesystem`,
+		},
+		{
+			name:        "all containers exempted",
+			annotations: map[string]string{WritableRootFilesystemAnnotation: "true"},
+			spec:        corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}, {Name: "sidecar"}}},
+		},
+		{
+			name:        "listed containers exempted",
+			annotations: map[string]string{WritableRootFilesystemAnnotation: "app, cache"},
+			spec:        corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}, {Name: "cache"}, {Name: "sidecar"}}},
+			wantErr:     `container "sidecar" has a writable root filesystem`,
+		},
+		{
+			name:        "custom annotation",
+			annotations: map[string]string{"example.com/writable": "true"},
+			spec:        corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+			cfg:         ReadOnlyRootFilesystemConfig{Annotation: "example.com/writable"},
+		},
+		{
+			name: "windows",
+			spec: corev1.PodSpec{OS: &corev1.PodOS{Name: corev1.Windows}, Containers: []corev1.Container{{Name: "app"}}},
+		},
+		{
+			name: "windows runtime class",
+			spec: corev1.PodSpec{RuntimeClassName: &windows, Containers: []corev1.Container{{Name: "app"}}},
+		},
+		{
+			name:      "exempt namespace",
+			namespace: "kube-system",
+			spec:      corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+			cfg:       ReadOnlyRootFilesystemConfig{ExemptNamespaces: []string{"kube-system"}},
+		},
+		{
+			name:         "warn",
+			spec:         corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+			cfg:          ReadOnlyRootFilesystemConfig{Action: ActionWarn},
+			wantWarnings: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := testRule(t, newReadOnlyRootFilesystemRule, &Config{ReadOnlyRootFilesystem: tt.cfg}, windowsRuntimeClass())
+			ns := tt.namespace
+			if ns == "" {
+				ns = "default"
+			}
+			o := podObject(ns, tt.spec)
+			o.Pod.Annotations = tt.annotations
+			warnings, err := r.Validate(context.Background(), o)
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("Validate() warnings = %v, want %d", warnings, tt.wantWarnings)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	v1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ReferencesRuleName is the name of the rule checking that referenced objects exist
+const ReferencesRuleName = "references"
+
+// referencesRule denies workloads referencing nonexistent ConfigMaps, Secrets or ServiceAccounts,
+// catching typos at admission instead of at pod start. Optional references are ignored.
+type referencesRule struct {
+	// configMaps and secrets cache the metadata only, existence checks need no data
+	configMaps      cache.GenericLister
+	secrets         cache.GenericLister
+	serviceAccounts corelisters.ServiceAccountLister
+}
+
+func newReferencesRule(csh *CosignServerHandler, _ *Config) (Rule, error) {
+	csh.selectInformers("serviceaccounts")
+	return &referencesRule{
+		configMaps:      csh.metadataLister("configmaps"),
+		secrets:         csh.metadataLister("secrets"),
+		serviceAccounts: csh.informers.Core().V1().ServiceAccounts().Lister(),
+	}, nil
+}
+
+// Name returns the name of the rule
+func (*referencesRule) Name() string {
+	return ReferencesRuleName
+}
+
+// podReferences are the names of objects a pod spec references
+type podReferences struct {
+	configMaps      map[string]bool
+	secrets         map[string]bool
+	serviceAccounts map[string]bool
+}
+
+// Validate looks up all objects referenced by pods and workloads in the informer caches
+func (r *referencesRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	spec := podSpec(o)
+	if spec == nil || o.Request.Operation == v1.Delete {
+		return nil, nil
+	}
+	ns := o.Request.Namespace
+	refs := requiredReferences(spec)
+
+	var violations []string
+	for _, name := range sortedKeys(refs.configMaps) {
+		if _, err := r.configMaps.ByNamespace(ns).Get(name); apierrors.IsNotFound(err) {
+			violations = append(violations, fmt.Sprintf("configmap %q not found in namespace %q", name, ns))
+		} else if err != nil {
+			tracef(ctx, "can't look up configmap %q, assuming it exists: %v", name, err)
+		}
+	}
+	for _, name := range sortedKeys(refs.secrets) {
+		if _, err := r.secrets.ByNamespace(ns).Get(name); apierrors.IsNotFound(err) {
+			violations = append(violations, fmt.Sprintf("secret %q not found in namespace %q", name, ns))
+		} else if err != nil {
+			tracef(ctx, "can't look up secret %q, assuming it exists: %v", name, err)
+		}
+	}
+	for _, name := range sortedKeys(refs.serviceAccounts) {
+		if _, err := r.serviceAccounts.ServiceAccounts(ns).Get(name); apierrors.IsNotFound(err) {
+			violations = append(violations, fmt.Sprintf("serviceaccount %q not found in namespace %q", name, ns))
+		} else if err != nil {
+			tracef(ctx, "can't look up serviceaccount %q, assuming it exists: %v", name, err)
+		}
+	}
+	return nil, violationsError(violations)
+}
+
+// requiredReferences collects the non-optional references of the pod spec
+func requiredReferences(spec *corev1.PodSpec) podReferences {
+	refs := podReferences{
+		configMaps:      map[string]bool{},
+		secrets:         map[string]bool{},
+		serviceAccounts: map[string]bool{},
+	}
+	if spec.ServiceAccountName != "" && spec.ServiceAccountName != "default" {
+		refs.serviceAccounts[spec.ServiceAccountName] = true
+	}
+	for _, s := range spec.ImagePullSecrets {
+		refs.secrets[s.Name] = true
+	}
+	for i := range spec.Volumes {
+		v := &spec.Volumes[i]
+		if v.ConfigMap != nil && !isTrue(v.ConfigMap.Optional) {
+			refs.configMaps[v.ConfigMap.Name] = true
+		}
+		if v.Secret != nil && !isTrue(v.Secret.Optional) {
+			refs.secrets[v.Secret.SecretName] = true
+		}
+		if v.Projected == nil {
+			continue
+		}
+		for _, p := range v.Projected.Sources {
+			if p.ConfigMap != nil && !isTrue(p.ConfigMap.Optional) {
+				refs.configMaps[p.ConfigMap.Name] = true
+			}
+			if p.Secret != nil && !isTrue(p.Secret.Optional) {
+				refs.secrets[p.Secret.Name] = true
+			}
+		}
+	}
+	for _, c := range podContainers(spec) {
+		for _, e := range c.Env {
+			if e.ValueFrom == nil {
+				continue
+			}
+			if ref := e.ValueFrom.ConfigMapKeyRef; ref != nil && !isTrue(ref.Optional) {
+				refs.configMaps[ref.Name] = true
+			}
+			if ref := e.ValueFrom.SecretKeyRef; ref != nil && !isTrue(ref.Optional) {
+				refs.secrets[ref.Name] = true
+			}
+		}
+		for _, ef := range c.EnvFrom {
+			if ef.ConfigMapRef != nil && !isTrue(ef.ConfigMapRef.Optional) {
+				refs.configMaps[ef.ConfigMapRef.Name] = true
+			}
+			if ef.SecretRef != nil && !isTrue(ef.SecretRef.Optional) {
+				refs.secrets[ef.SecretRef.Name] = true
+			}
+		}
+	}
+	return refs
+}
+
+// isTrue returns the value of a bool pointer, false if nil
+func isTrue(b *bool) bool {
+	return b != nil && *b
+}
+
+// sortedKeys returns the keys of the set in sorted order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,79 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_referencesRule_Validate(t *testing.T) {
+	optional := true
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "default"}}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "app-secret", Namespace: "default"}}
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}}
+
+	tests := []struct {
+		name    string
+		spec    corev1.PodSpec
+		wantErr bool
+	}{
+		{
+			name: "existing references",
+			spec: corev1.PodSpec{
+				ServiceAccountName: "app",
+				Volumes: []corev1.Volume{
+					{Name: "config", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "app-config"},
+					}}},
+				},
+				Containers: []corev1.Container{{Name: "app", EnvFrom: []corev1.EnvFromSource{
+					{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "app-secret"}}},
+				}}},
+			},
+		},
+		{
+			name:    "missing service account",
+			spec:    corev1.PodSpec{ServiceAccountName: "typo"},
+			wantErr: true,
+		},
+		{
+			name: "missing secret in env",
+			spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Env: []corev1.EnvVar{
+				{Name: "TOKEN", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "typo"},
+					Key:                  "token",
+				}}},
+			}}}},
+			wantErr: true,
+		},
+		{
+			name: "missing optional configmap",
+			spec: corev1.PodSpec{Volumes: []corev1.Volume{
+				{Name: "config", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "typo"},
+					Optional:             &optional,
+				}}},
+			}},
+		},
+		{
+			name:    "missing image pull secret",
+			spec:    corev1.PodSpec{ImagePullSecrets: []corev1.LocalObjectReference{{Name: "typo"}}},
+			wantErr: true,
+		},
+	}
+
+	r := testRule(t, newReferencesRule, &Config{}, cm, secret, sa)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := podObject("default", tt.spec)
+			o.Request.Operation = v1.Create
+			_, err := r.Validate(context.Background(), o)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
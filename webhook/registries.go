@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// RegistriesRuleName is the name of the rule restricting the registries images are pulled from
+const RegistriesRuleName = "registries"
+
+// RegistriesConfig configures the registries images may be pulled from
+type RegistriesConfig struct {
+	// Allowed registries, e.g. ghcr.io or registry.example.com:5000. docker.io is Docker Hub.
+	// A repository prefix like registry.example.com/team-a/* only allows the repositories below it.
+	Allowed []string `json:"allowed"`
+	// Namespaces maps namespaces to their allowed registries, replacing Allowed
+	Namespaces map[string][]string `json:"namespaces"`
+	// Action is either deny (default) or warn
+	Action string `json:"action"`
+}
+
+// allowedRegistry is a registry, optionally restricted to the repositories below a prefix
+type allowedRegistry struct {
+	registry string
+	prefix   string
+}
+
+// registriesRule keeps images from unknown registries out of the cluster
+type registriesRule struct {
+	allowed    []allowedRegistry
+	namespaces map[string][]allowedRegistry
+	action     string
+}
+
+func newRegistriesRule(_ *CosignServerHandler, cfg *Config) (Rule, error) {
+	c := cfg.Registries
+	if err := validateAction(&c.Action, ActionDeny); err != nil {
+		return nil, err
+	}
+	allowed, err := parseAllowedRegistries(c.Allowed)
+	if err != nil {
+		return nil, err
+	}
+	namespaces := make(map[string][]allowedRegistry, len(c.Namespaces))
+	for ns, registries := range c.Namespaces {
+		if namespaces[ns], err = parseAllowedRegistries(registries); err != nil {
+			return nil, fmt.Errorf("namespace %s: %w", ns, err)
+		}
+	}
+	return &registriesRule{allowed: allowed, namespaces: namespaces, action: c.Action}, nil
+}
+
+// parseAllowedRegistries parses registries with optional repository prefixes
+func parseAllowedRegistries(registries []string) ([]allowedRegistry, error) {
+	allowed := make([]allowedRegistry, 0, len(registries))
+	for _, a := range registries {
+		host, prefix, _ := strings.Cut(strings.TrimSuffix(a, "/*"), "/")
+		reg, err := name.NewRegistry(host)
+		if err != nil {
+			return nil, fmt.Errorf("invalid registry %q: %w", a, err)
+		}
+		allowed = append(allowed, allowedRegistry{registry: reg.RegistryStr(), prefix: prefix})
+	}
+	return allowed, nil
+}
+
+// Name returns the name of the rule
+func (*registriesRule) Name() string {
+	return RegistriesRuleName
+}
+
+// Validate checks the registry of the images of all containers of pods and workloads
+func (r *registriesRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	allowed, ok := r.namespaces[o.Request.Namespace]
+	if ok {
+		tracef(ctx, "registries of namespace %q", o.Request.Namespace)
+	} else {
+		allowed = r.allowed
+	}
+	spec := podSpec(o)
+	if spec == nil || len(allowed) == 0 {
+		return nil, nil
+	}
+	var violations []string
+	for _, c := range podContainers(spec) {
+		ref, err := name.ParseReference(c.Image)
+		if err != nil {
+			violations = append(violations, fmt.Sprintf("container %q has invalid image %q", c.Name, c.Image))
+			continue
+		}
+		if !registryAllowed(allowed, ref.Context()) {
+			violations = append(violations, fmt.Sprintf("container %q uses image %q from registry %q, which is not allowed",
+				c.Name, c.Image, ref.Context().RegistryStr()))
+		}
+	}
+	return enforce(r.action, violations)
+}
+
+// registryAllowed returns whether the repository is in one of the allowed registries and below its prefix
+func registryAllowed(allowed []allowedRegistry, repo name.Repository) bool {
+	for _, a := range allowed {
+		if a.registry != repo.RegistryStr() {
+			continue
+		}
+		if path := repo.RepositoryStr(); a.prefix == "" || path == a.prefix || strings.HasPrefix(path, a.prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_registriesRule_Validate(t *testing.T) {
+	tests := []struct {
+		name         string
+		cfg          RegistriesConfig
+		image        string
+		wantWarnings int
+		wantErr      bool
+	}{
+		{
+			name:  "no registries configured",
+			image: "evil.example.com/app:1.0",
+		},
+		{
+			name:  "allowed registry",
+			cfg:   RegistriesConfig{Allowed: []string{"ghcr.io"}},
+			image: "ghcr.io/eumel8/app:1.0",
+		},
+		{
+			name:  "docker hub short name",
+			cfg:   RegistriesConfig{Allowed: []string{"docker.io"}},
+			image: "nginx:1.27",
+		},
+		{
+			name:    "registry not allowed",
+			cfg:     RegistriesConfig{Allowed: []string{"ghcr.io"}},
+			image:   "nginx:1.27",
+			wantErr: true,
+		},
+		{
+			name:         "registry not allowed warns",
+			cfg:          RegistriesConfig{Allowed: []string{"ghcr.io"}, Action: ActionWarn},
+			image:        "quay.io/app:1.0",
+			wantWarnings: 1,
+		},
+		{
+			name:  "allowed registry with wildcard",
+			cfg:   RegistriesConfig{Allowed: []string{"registry.internal.example.com/*"}},
+			image: "registry.internal.example.com/team-a/app:1.0",
+		},
+		{
+			name:  "allowed repository prefix",
+			cfg:   RegistriesConfig{Allowed: []string{"registry.example.com/team-a/*"}},
+			image: "registry.example.com/team-a/app:1.0",
+		},
+		{
+			name:    "repository outside prefix",
+			cfg:     RegistriesConfig{Allowed: []string{"registry.example.com/team-a/*"}},
+			image:   "registry.example.com/team-ab/app:1.0",
+			wantErr: true,
+		},
+		{
+			name: "namespace override allows registry",
+			cfg: RegistriesConfig{
+				Allowed:    []string{"ghcr.io"},
+				Namespaces: map[string][]string{"default": {"quay.io"}},
+			},
+			image: "quay.io/app:1.0",
+		},
+		{
+			name: "namespace override replaces allowed registries",
+			cfg: RegistriesConfig{
+				Allowed:    []string{"ghcr.io"},
+				Namespaces: map[string][]string{"default": {"quay.io"}},
+			},
+			image:   "ghcr.io/eumel8/app:1.0",
+			wantErr: true,
+		},
+		{
+			name: "override of other namespace",
+			cfg: RegistriesConfig{
+				Allowed:    []string{"ghcr.io"},
+				Namespaces: map[string][]string{"team-a": {"quay.io"}},
+			},
+			image:   "quay.io/app:1.0",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newRegistriesRule(nil, &Config{Registries: tt.cfg})
+			if err != nil {
+				t.Fatal(err)
+			}
+			warnings, err := r.Validate(context.Background(), podObject("default", corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Image: tt.image}},
+			}))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("Validate() warnings = %v, want %d", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/admission/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// regressionDir holds the corpus of sanitized admission reviews, the config they are validated with
+// and the expected verdicts
+const regressionDir = "testdata/regression"
+
+var updateVerdicts = flag.Bool("update", false, "rewrite the verdicts of the regression corpus with the current ones")
+
+// regressionVerdict is the outcome of an admission review of the regression corpus
+type regressionVerdict struct {
+	Allowed bool `json:"allowed"`
+	// Rule is the name of the rule which denied the review
+	Rule     string   `json:"rule,omitempty"`
+	Message  string   `json:"message"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// TestRegressionCorpus sends each admission review of the corpus through the webhook and compares the verdict
+// with the recorded one, so a release can't change the behavior in edge cases by accident.
+// An intended change is recorded with: go test ./webhook/ -run TestRegressionCorpus -update
+func TestRegressionCorpus(t *testing.T) {
+	cfg, err := LoadConfig(filepath.Join(regressionDir, "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := fake.NewSimpleClientset()
+	csh := &CosignServerHandler{cs: cs, cfg: cfg, informers: informers.NewSharedInformerFactory(cs, 0)}
+	endpoints, err := csh.Endpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rule string
+	csh.OnDecision(func(d *Decision) { rule = d.Rule })
+
+	verdictsFile := filepath.Join(regressionDir, "verdicts.json")
+	files, err := filepath.Glob(filepath.Join(regressionDir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]regressionVerdict{}
+	for _, file := range files {
+		if file == verdictsFile {
+			continue
+		}
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+		body, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rule = ""
+		rec := httptest.NewRecorder()
+		endpoints[0].ServeHTTP(rec, httptest.NewRequest(http.MethodPost, DefaultPath, bytes.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: got status %d, want %d", name, rec.Code, http.StatusOK)
+			continue
+		}
+		ar := v1.AdmissionReview{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &ar); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got[name] = regressionVerdict{
+			Allowed:  ar.Response.Allowed,
+			Rule:     rule,
+			Message:  ar.Response.Result.Message,
+			Warnings: ar.Response.Warnings,
+		}
+	}
+
+	if *updateVerdicts {
+		data, err := json.MarshalIndent(got, "", "\t")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(verdictsFile, append(data, '\n'), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	data, err := os.ReadFile(verdictsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]regressionVerdict{}
+	if err := json.Unmarshal(data, &want); err != nil {
+		t.Fatal(err)
+	}
+	for name, v := range got {
+		w, ok := want[name]
+		if !ok {
+			t.Errorf("%s: no verdict recorded, run the test with -update", name)
+			continue
+		}
+		if !reflect.DeepEqual(v, w) {
+			t.Errorf("%s: verdict changed\n got %+v\nwant %+v", name, v, w)
+		}
+	}
+	for name := range want {
+		if _, ok := got[name]; !ok {
+			t.Errorf("%s: verdict recorded without admission review", name)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/admission/v1"
+)
+
+// ReplicasRuleName is the name of the rule limiting the replicas of workloads
+const ReplicasRuleName = "replicas"
+
+// ReplicasConfig configures the maximum replicas of Deployments and StatefulSets
+type ReplicasConfig struct {
+	// MaxReplicas is the highest allowed spec.replicas, 0 for no limit
+	MaxReplicas int32 `json:"maxReplicas"`
+	// Namespaces replace the maximum per namespace, 0 for no limit
+	Namespaces map[string]int32 `json:"namespaces"`
+	// Action is either deny (default) or warn
+	Action string `json:"action"`
+}
+
+// replicasRule keeps a single team from exhausting the capacity of the cluster
+type replicasRule struct {
+	cfg ReplicasConfig
+}
+
+func newReplicasRule(_ *CosignServerHandler, cfg *Config) (Rule, error) {
+	c := cfg.Replicas
+	if err := validateAction(&c.Action, ActionDeny); err != nil {
+		return nil, err
+	}
+	if c.MaxReplicas < 0 {
+		return nil, fmt.Errorf("maxReplicas must not be negative")
+	}
+	for ns, m := range c.Namespaces {
+		if m < 0 {
+			return nil, fmt.Errorf("namespace %s: maxReplicas must not be negative", ns)
+		}
+	}
+	return &replicasRule{cfg: c}, nil
+}
+
+// Name returns the name of the rule
+func (*replicasRule) Name() string {
+	return ReplicasRuleName
+}
+
+// Validate checks spec.replicas of Deployments and StatefulSets, which default to 1
+func (r *replicasRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	if o.Request.Operation == v1.Delete {
+		return nil, nil
+	}
+	var (
+		kind     string
+		replicas *int32
+	)
+	switch {
+	case o.Deployment != nil:
+		kind, replicas = "deployment", o.Deployment.Spec.Replicas
+	case o.StatefulSet != nil:
+		kind, replicas = "statefulset", o.StatefulSet.Spec.Replicas
+	default:
+		return nil, nil
+	}
+	ns := o.Request.Namespace
+	maxReplicas := r.cfg.MaxReplicas
+	if m, ok := r.cfg.Namespaces[ns]; ok {
+		maxReplicas = m
+		tracef(ctx, "maximum %d of namespace %q", m, ns)
+	} else {
+		tracef(ctx, "default maximum %d", maxReplicas)
+	}
+	n := int32(1)
+	if replicas != nil {
+		n = *replicas
+	}
+
+	var violations []string
+	if maxReplicas > 0 && n > maxReplicas {
+		violations = append(violations, fmt.Sprintf("%s %q has %d replicas, the maximum in namespace %q is %d",
+			kind, o.Meta.Name, n, ns, maxReplicas))
+	}
+	return enforce(r.cfg.Action, violations)
+}
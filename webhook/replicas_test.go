@@ -0,0 +1,106 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_replicasRule_Validate(t *testing.T) {
+	replicas := func(ns string, n int32) *Object {
+		o := deploymentObject(ns, nil, corev1.PodSpec{})
+		o.Deployment.Spec.Replicas = &n
+		return o
+	}
+	statefulSet := func(ns string, n int32) *Object {
+		o := deploymentObject(ns, nil, corev1.PodSpec{})
+		o.Deployment = nil
+		o.StatefulSet = &appsv1.StatefulSet{Spec: appsv1.StatefulSetSpec{Replicas: &n}}
+		return o
+	}
+	tests := []struct {
+		name         string
+		object       *Object
+		cfg          ReplicasConfig
+		wantWarnings int
+		wantErr      string
+	}{
+		{
+			name:   "no limit",
+			object: replicas("default", 1000),
+		},
+		{
+			name:   "below maximum",
+			object: replicas("default", 10),
+			cfg:    ReplicasConfig{MaxReplicas: 10},
+		},
+		{
+			name:    "above maximum",
+			object:  replicas("default", 11),
+			cfg:     ReplicasConfig{MaxReplicas: 10},
+			wantErr: `deployment "test" has 11 replicas, the maximum in namespace "default" is 10`,
+		},
+		{
+			name:   "defaults to one replica",
+			object: deploymentObject("default", nil, corev1.PodSpec{}),
+			cfg:    ReplicasConfig{MaxReplicas: 1},
+		},
+		{
+			name:    "statefulset",
+			object:  statefulSet("default", 3),
+			cfg:     ReplicasConfig{MaxReplicas: 2},
+			wantErr: `statefulset "test" has 3 replicas, the maximum in namespace "default" is 2`,
+		},
+		{
+			name:    "namespace maximum",
+			object:  replicas("team-a", 5),
+			cfg:     ReplicasConfig{MaxReplicas: 10, Namespaces: map[string]int32{"team-a": 4}},
+			wantErr: `deployment "test" has 5 replicas, the maximum in namespace "team-a" is 4`,
+		},
+		{
+			name:   "namespace without limit",
+			object: replicas("batch", 500),
+			cfg:    ReplicasConfig{MaxReplicas: 10, Namespaces: map[string]int32{"batch": 0}},
+		},
+		{
+			name:   "pods are skipped",
+			object: podObject("default", corev1.PodSpec{}),
+			cfg:    ReplicasConfig{MaxReplicas: 1},
+		},
+		{
+			name:         "warn",
+			object:       replicas("default", 11),
+			cfg:          ReplicasConfig{MaxReplicas: 10, Action: ActionWarn},
+			wantWarnings: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newReplicasRule(&CosignServerHandler{}, &Config{Replicas: tt.cfg})
+			if err != nil {
+				t.Fatal(err)
+			}
+			warnings, err := r.Validate(context.Background(), tt.object)
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("Validate() warnings = %v, want %d", warnings, tt.wantWarnings)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := newReplicasRule(&CosignServerHandler{}, &Config{Replicas: ReplicasConfig{MaxReplicas: -1}}); err == nil {
+		t.Error("negative maxReplicas accepted")
+	}
+}
@@ -0,0 +1,76 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	v1 "k8s.io/api/admission/v1"
+)
+
+// RequiredAnnotationsRuleName is the name of the rule denying workloads without the required annotations
+const RequiredAnnotationsRuleName = "requiredAnnotations"
+
+// RequiredAnnotationsConfig configures the annotations workloads must have, e.g. contact and ticket-id
+type RequiredAnnotationsConfig struct {
+	// Annotations maps the required annotation keys to regular expressions their whole value has to match,
+	// an empty pattern allows any value
+	Annotations map[string]string `json:"annotations"`
+	// Namespaces maps namespaces to annotations required in addition to the global ones, replacing the pattern
+	// of a global annotation with the same key
+	Namespaces map[string]map[string]string `json:"namespaces"`
+	// Action is either deny (default) or warn
+	Action string `json:"action"`
+}
+
+// requiredAnnotationsRule denies pods and workloads without the required annotations or with values not matching
+// their pattern. Unlike labels, annotations are only required on the object itself, as they describe the
+// workload and aren't used to select its pods.
+type requiredAnnotationsRule struct {
+	annotations map[string]requiredValue
+	namespaces  map[string]map[string]requiredValue
+	action      string
+}
+
+func newRequiredAnnotationsRule(_ *CosignServerHandler, cfg *Config) (Rule, error) {
+	c := cfg.RequiredAnnotations
+	if err := validateAction(&c.Action, ActionDeny); err != nil {
+		return nil, err
+	}
+	if len(c.Annotations) == 0 && len(c.Namespaces) == 0 {
+		return nil, fmt.Errorf("requiredAnnotations: no annotations configured")
+	}
+	annotations, err := compileRequiredValues("annotation", c.Annotations)
+	if err != nil {
+		return nil, err
+	}
+	namespaces := make(map[string]map[string]requiredValue, len(c.Namespaces))
+	for ns, a := range c.Namespaces {
+		nsAnnotations, err := compileRequiredValues("annotation", a)
+		if err != nil {
+			return nil, fmt.Errorf("namespace %s: %w", ns, err)
+		}
+		namespaces[ns] = maps.Clone(annotations)
+		maps.Copy(namespaces[ns], nsAnnotations)
+	}
+	return &requiredAnnotationsRule{annotations: annotations, namespaces: namespaces, action: c.Action}, nil
+}
+
+// Name returns the name of the rule
+func (*requiredAnnotationsRule) Name() string {
+	return RequiredAnnotationsRuleName
+}
+
+// Validate checks the annotations of pods and workloads
+func (r *requiredAnnotationsRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	if template, _ := podTemplate(o); template == nil || o.Request.Operation == v1.Delete {
+		return nil, nil
+	}
+	required, ok := r.namespaces[o.Request.Namespace]
+	if ok {
+		tracef(ctx, "annotations of namespace %q", o.Request.Namespace)
+	} else {
+		required = r.annotations
+	}
+	return enforce(r.action, checkRequiredValues("", "annotation", o.Meta.Annotations, required))
+}
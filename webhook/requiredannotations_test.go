@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_requiredAnnotationsRule_Validate(t *testing.T) {
+	cfg := RequiredAnnotationsConfig{
+		Annotations: map[string]string{"contact": "", "ticket-id": "[A-Z]+-[0-9]+"},
+		Namespaces:  map[string]map[string]string{"payments": {"change-approval": ""}},
+	}
+	complete := map[string]string{"contact": "team@example.com", "ticket-id": "OPS-42"}
+	tests := []struct {
+		name         string
+		namespace    string
+		annotations  map[string]string
+		action       string
+		wantWarnings int
+		wantErr      string
+	}{
+		{
+			name:        "all annotations",
+			annotations: complete,
+		},
+		{
+			name:        "missing annotation",
+			annotations: map[string]string{"ticket-id": "OPS-42"},
+			wantErr:     `missing required annotation "contact"`,
+		},
+		{
+			name:        "value not matching",
+			annotations: map[string]string{"contact": "team@example.com", "ticket-id": "42"},
+			wantErr:     `value "42" of annotation "ticket-id" doesn't match pattern "[A-Z]+-[0-9]+"`,
+		},
+		{
+			name:        "namespace annotation",
+			namespace:   "payments",
+			annotations: complete,
+			wantErr:     `missing required annotation "change-approval"`,
+		},
+		{
+			name:         "warn",
+			action:       ActionWarn,
+			wantWarnings: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := cfg
+			c.Action = tt.action
+			r, err := newRequiredAnnotationsRule(&CosignServerHandler{}, &Config{RequiredAnnotations: c})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ns := tt.namespace
+			if ns == "" {
+				ns = "default"
+			}
+			o := podObject(ns, corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "nginx:1.27"}}})
+			o.Meta.Annotations = tt.annotations
+			warnings, err := r.Validate(context.Background(), o)
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("Validate() warnings = %v, want %d", warnings, tt.wantWarnings)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_newRequiredAnnotationsRule(t *testing.T) {
+	for _, c := range []RequiredAnnotationsConfig{{}, {Annotations: map[string]string{"contact": "(unclosed"}}} {
+		if _, err := newRequiredAnnotationsRule(&CosignServerHandler{}, &Config{RequiredAnnotations: c}); err == nil {
+			t.Errorf("newRequiredAnnotationsRule(%+v) want error", c)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+
+	v1 "k8s.io/api/admission/v1"
+)
+
+// RequiredLabelsRuleName is the name of the rule denying workloads without the required labels
+const RequiredLabelsRuleName = "requiredLabels"
+
+// RequiredLabelsConfig configures the labels workloads must have, e.g. app, owner and environment
+type RequiredLabelsConfig struct {
+	// Labels maps the required label keys to regular expressions their whole value has to match,
+	// an empty pattern allows any value
+	Labels map[string]string `json:"labels"`
+	// Namespaces maps namespaces to labels required in addition to the global ones, replacing the pattern
+	// of a global label with the same key
+	Namespaces map[string]map[string]string `json:"namespaces"`
+	// Action is either deny (default) or warn
+	Action string `json:"action"`
+}
+
+// requiredValue is a required label or annotation with its compiled pattern, nil allows any value
+type requiredValue struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+// requiredLabelsRule denies pods and workloads without the required labels or with values not matching their
+// pattern. Workloads need the labels on themselves and on their pod template, so their pods pass the rule too.
+type requiredLabelsRule struct {
+	labels     map[string]requiredValue
+	namespaces map[string]map[string]requiredValue
+	action     string
+}
+
+func newRequiredLabelsRule(_ *CosignServerHandler, cfg *Config) (Rule, error) {
+	c := cfg.RequiredLabels
+	if err := validateAction(&c.Action, ActionDeny); err != nil {
+		return nil, err
+	}
+	if len(c.Labels) == 0 && len(c.Namespaces) == 0 {
+		return nil, fmt.Errorf("requiredLabels: no labels configured")
+	}
+	labels, err := compileRequiredValues("label", c.Labels)
+	if err != nil {
+		return nil, err
+	}
+	namespaces := make(map[string]map[string]requiredValue, len(c.Namespaces))
+	for ns, l := range c.Namespaces {
+		nsLabels, err := compileRequiredValues("label", l)
+		if err != nil {
+			return nil, fmt.Errorf("namespace %s: %w", ns, err)
+		}
+		namespaces[ns] = maps.Clone(labels)
+		maps.Copy(namespaces[ns], nsLabels)
+	}
+	return &requiredLabelsRule{labels: labels, namespaces: namespaces, action: c.Action}, nil
+}
+
+// compileRequiredValues compiles the patterns of the required labels or annotations
+func compileRequiredValues(kind string, values map[string]string) (map[string]requiredValue, error) {
+	compiled := make(map[string]requiredValue, len(values))
+	for k, p := range values {
+		l := requiredValue{pattern: p}
+		if p != "" {
+			res, err := compilePatterns([]string{p})
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", kind, k, err)
+			}
+			l.re = res[0]
+		}
+		compiled[k] = l
+	}
+	return compiled, nil
+}
+
+// Name returns the name of the rule
+func (*requiredLabelsRule) Name() string {
+	return RequiredLabelsRuleName
+}
+
+// Validate checks the labels of pods, workloads and the pod templates of workloads
+func (r *requiredLabelsRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	template, _ := podTemplate(o)
+	if template == nil || o.Request.Operation == v1.Delete {
+		return nil, nil
+	}
+	required, ok := r.namespaces[o.Request.Namespace]
+	if ok {
+		tracef(ctx, "labels of namespace %q", o.Request.Namespace)
+	} else {
+		required = r.labels
+	}
+	violations := checkRequiredValues("", "label", o.Meta.Labels, required)
+	if o.Pod == nil {
+		violations = append(violations, checkRequiredValues("pod template ", "label", template.Labels, required)...)
+	}
+	return enforce(r.action, violations)
+}
+
+// checkRequiredValues returns the violations of the labels or annotations, prefixed with where they are
+func checkRequiredValues(prefix, kind string, values map[string]string, required map[string]requiredValue) []string {
+	var violations []string
+	for _, k := range slices.Sorted(maps.Keys(required)) {
+		l := required[k]
+		v, ok := values[k]
+		switch {
+		case !ok:
+			violations = append(violations, fmt.Sprintf("%smissing required %s %q", prefix, kind, k))
+		case l.re != nil && !l.re.MatchString(v):
+			violations = append(violations, fmt.Sprintf("%svalue %q of %s %q doesn't match pattern %q", prefix, v, kind, k, l.pattern))
+		}
+	}
+	return violations
+}
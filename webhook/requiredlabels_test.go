@@ -0,0 +1,119 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_requiredLabelsRule_Validate(t *testing.T) {
+	cfg := RequiredLabelsConfig{
+		Labels:     map[string]string{"app": "", "environment": "dev|prod"},
+		Namespaces: map[string]map[string]string{"payments": {"owner": "team-.*"}},
+	}
+	complete := map[string]string{"app": "web", "environment": "prod"}
+	tests := []struct {
+		name         string
+		namespace    string
+		labels       map[string]string
+		deployment   bool
+		template     map[string]string
+		action       string
+		wantWarnings int
+		wantErr      string
+	}{
+		{
+			name:   "all labels",
+			labels: complete,
+		},
+		{
+			name:    "missing label",
+			labels:  map[string]string{"app": "web"},
+			wantErr: `missing required label "environment"`,
+		},
+		{
+			name:    "value not matching",
+			labels:  map[string]string{"app": "web", "environment": "production"},
+			wantErr: `value "production" of label "environment" doesn't match pattern "dev|prod"`,
+		},
+		{
+			name:      "namespace label",
+			namespace: "payments",
+			labels:    complete,
+			wantErr:   `missing required label "owner"`,
+		},
+		{
+			name:      "namespace label set",
+			namespace: "payments",
+			labels:    map[string]string{"app": "web", "environment": "dev", "owner": "team-pay"},
+		},
+		{
+			name:       "deployment with labelled template",
+			labels:     complete,
+			deployment: true,
+			template:   complete,
+		},
+		{
+			name:       "deployment template without labels",
+			labels:     complete,
+			deployment: true,
+			wantErr:    `pod template missing required label "app"`,
+		},
+		{
+			name:         "warn",
+			labels:       map[string]string{},
+			action:       ActionWarn,
+			wantWarnings: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := cfg
+			c.Action = tt.action
+			r, err := newRequiredLabelsRule(&CosignServerHandler{}, &Config{RequiredLabels: c})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ns := tt.namespace
+			if ns == "" {
+				ns = "default"
+			}
+			o := podObject(ns, corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "nginx:1.27"}}})
+			o.Meta.Labels = tt.labels
+			if tt.deployment {
+				o.Request.Kind.Kind = "Deployment"
+				o.Deployment = &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: tt.template},
+					Spec:       o.Pod.Spec,
+				}}}
+				o.Pod = nil
+			}
+			warnings, err := r.Validate(context.Background(), o)
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("Validate() warnings = %v, want %d", warnings, tt.wantWarnings)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_newRequiredLabelsRule(t *testing.T) {
+	for _, c := range []RequiredLabelsConfig{{}, {Labels: map[string]string{"app": "(unclosed"}}} {
+		if _, err := newRequiredLabelsRule(&CosignServerHandler{}, &Config{RequiredLabels: c}); err == nil {
+			t.Errorf("newRequiredLabelsRule(%+v) want error", c)
+		}
+	}
+}
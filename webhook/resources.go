@@ -0,0 +1,133 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ResourcesRuleName is the name of the rule requiring resource requests and limits
+const ResourcesRuleName = "resources"
+
+// ResourceBounds are the bounds of the resources of each container
+type ResourceBounds struct {
+	// Min are the minimum requests by resource name, e.g. cpu: 10m
+	Min corev1.ResourceList `json:"min"`
+	// Max are the maximum limits by resource name, e.g. memory: 8Gi
+	Max corev1.ResourceList `json:"max"`
+}
+
+// ResourcesConfig configures the requests and limits required on each container
+type ResourcesConfig struct {
+	// Resources which need a request and a limit, defaults to cpu and memory
+	Resources []corev1.ResourceName `json:"resources"`
+	// Min are the minimum requests by resource name
+	Min corev1.ResourceList `json:"min"`
+	// Max are the maximum limits by resource name
+	Max corev1.ResourceList `json:"max"`
+	// Namespaces override single bounds per namespace
+	Namespaces map[string]ResourceBounds `json:"namespaces"`
+	// Action is either deny (default) or warn
+	Action string `json:"action"`
+}
+
+// resourcesRule requires requests and limits on every container, so the scheduler can place pods
+// and no container starves its neighbors
+type resourcesRule struct {
+	cfg ResourcesConfig
+}
+
+func newResourcesRule(_ *CosignServerHandler, cfg *Config) (Rule, error) {
+	c := cfg.Resources
+	if err := validateAction(&c.Action, ActionDeny); err != nil {
+		return nil, err
+	}
+	if len(c.Resources) == 0 {
+		c.Resources = []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory}
+	}
+	return &resourcesRule{cfg: c}, nil
+}
+
+// Name returns the name of the rule
+func (*resourcesRule) Name() string {
+	return ResourcesRuleName
+}
+
+// Validate checks the requests and limits of the init and regular containers of pods and workloads.
+// Ephemeral containers can't have resources.
+func (r *resourcesRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	spec := podSpec(o)
+	if spec == nil {
+		return nil, nil
+	}
+	bounds := r.bounds(o.Request.Namespace)
+	if _, ok := r.cfg.Namespaces[o.Request.Namespace]; ok {
+		tracef(ctx, "bounds of namespace %q", o.Request.Namespace)
+	}
+
+	var violations []string
+	containers := append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+	for i := range containers {
+		if v := r.check(&containers[i], bounds); len(v) > 0 {
+			violations = append(violations, fmt.Sprintf("container %q: %s", containers[i].Name, strings.Join(v, ", ")))
+		}
+	}
+	return enforce(r.cfg.Action, violations)
+}
+
+// bounds returns the bounds of the namespace, the global ones overridden by the ones of the namespace
+func (r *resourcesRule) bounds(ns string) ResourceBounds {
+	b := ResourceBounds{Min: corev1.ResourceList{}, Max: corev1.ResourceList{}}
+	for n, q := range r.cfg.Min {
+		b.Min[n] = q
+	}
+	for n, q := range r.cfg.Max {
+		b.Max[n] = q
+	}
+	for n, q := range r.cfg.Namespaces[ns].Min {
+		b.Min[n] = q
+	}
+	for n, q := range r.cfg.Namespaces[ns].Max {
+		b.Max[n] = q
+	}
+	return b
+}
+
+// check returns the missing and out of range requests and limits of the container
+func (r *resourcesRule) check(c *corev1.Container, b ResourceBounds) []string {
+	var violations []string
+	for _, n := range r.cfg.Resources {
+		if _, ok := c.Resources.Requests[n]; !ok {
+			violations = append(violations, fmt.Sprintf("missing %s request", n))
+		}
+		if _, ok := c.Resources.Limits[n]; !ok {
+			violations = append(violations, fmt.Sprintf("missing %s limit", n))
+		}
+	}
+	for _, n := range sortedResourceNames(b.Min) {
+		minimum := b.Min[n]
+		if req, ok := c.Resources.Requests[n]; ok && req.Cmp(minimum) < 0 {
+			violations = append(violations, fmt.Sprintf("%s request %s is below the minimum %s", n, req.String(), minimum.String()))
+		}
+	}
+	for _, n := range sortedResourceNames(b.Max) {
+		maximum := b.Max[n]
+		if limit, ok := c.Resources.Limits[n]; ok && limit.Cmp(maximum) > 0 {
+			violations = append(violations, fmt.Sprintf("%s limit %s exceeds the maximum %s", n, limit.String(), maximum.String()))
+		}
+	}
+	return violations
+}
+
+// sortedResourceNames returns the resource names of the list in order
+func sortedResourceNames(l corev1.ResourceList) []corev1.ResourceName {
+	names := make([]corev1.ResourceName, 0, len(l))
+	for n := range l {
+		names = append(names, n)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
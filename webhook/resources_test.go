@@ -0,0 +1,92 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func Test_resourcesRule_Validate(t *testing.T) {
+	cfg := ResourcesConfig{
+		Min: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("32Mi")},
+		Max: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2"), corev1.ResourceMemory: resource.MustParse("4Gi")},
+		Namespaces: map[string]ResourceBounds{
+			"batch": {Max: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("16Gi")}},
+		},
+	}
+	resources := func(requests, limits corev1.ResourceList) corev1.ResourceRequirements {
+		return corev1.ResourceRequirements{Requests: requests, Limits: limits}
+	}
+	complete := corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m"), corev1.ResourceMemory: resource.MustParse("8Gi")}
+	tests := []struct {
+		name         string
+		cfg          ResourcesConfig
+		namespace    string
+		resources    corev1.ResourceRequirements
+		wantWarnings int
+		wantErr      string
+	}{
+		{
+			name: "within bounds",
+			resources: resources(
+				corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m"), corev1.ResourceMemory: resource.MustParse("64Mi")},
+				corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1"), corev1.ResourceMemory: resource.MustParse("1Gi")},
+			),
+		},
+		{
+			name:      "missing",
+			resources: resources(corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")}, nil),
+			wantErr:   `container "app": missing memory request, missing cpu limit, missing memory limit`,
+		},
+		{
+			name: "out of range",
+			resources: resources(
+				corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m"), corev1.ResourceMemory: resource.MustParse("16Mi")},
+				corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4"), corev1.ResourceMemory: resource.MustParse("8Gi")},
+			),
+			wantErr: `container "app": memory request 16Mi is below the minimum 32Mi, cpu limit 4 exceeds the maximum 2, memory limit 8Gi exceeds the maximum 4Gi`,
+		},
+		{
+			name:      "namespace overrides single bound",
+			namespace: "batch",
+			resources: resources(complete, complete),
+		},
+		{
+			name:         "missing warns",
+			cfg:          ResourcesConfig{Action: ActionWarn},
+			resources:    resources(nil, nil),
+			wantWarnings: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := cfg
+			if tt.cfg.Action != "" {
+				c = tt.cfg
+			}
+			r, err := newResourcesRule(nil, &Config{Resources: c})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ns := tt.namespace
+			if ns == "" {
+				ns = "default"
+			}
+			warnings, err := r.Validate(context.Background(), podObject(ns, corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Image: "app", Resources: tt.resources}},
+			}))
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Validate() error = %v, want none", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("Validate() warnings = %v, want %d", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
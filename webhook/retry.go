@@ -0,0 +1,217 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// integrations calling external destinations, used as metric label
+const (
+	integrationForward   = "forward"
+	integrationPublisher = "publisher"
+	integrationTelemetry = "telemetry"
+	integrationBlocklist = "blocklist"
+)
+
+var (
+	externalCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cosign_external_calls_total",
+		Help: "The number of calls to external destinations by result, success or error, after all attempts",
+	}, []string{"integration", "destination", "result"})
+	externalRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cosign_external_retries_total",
+		Help: "The number of retried attempts of calls to external destinations",
+	}, []string{"integration", "destination"})
+	externalDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "cosign_external_call_duration_seconds",
+		Help: "The duration of calls to external destinations including all attempts and backoffs",
+	}, []string{"integration", "destination"})
+)
+
+// RetryPolicy configures the attempts of calls to an external integration. Network errors, 5xx and 429 responses
+// are retried with exponential backoff and jitter, other errors fail immediately.
+type RetryPolicy struct {
+	// Attempts is the maximum number of attempts, defaults depend on the integration
+	Attempts int `json:"attempts"`
+	// InitialBackoff is the backoff after the first failed attempt, doubled after each further one, defaults to 200ms
+	InitialBackoff metav1.Duration `json:"initialBackoff"`
+	// MaxBackoff bounds the backoff, defaults to 5s
+	MaxBackoff metav1.Duration `json:"maxBackoff"`
+	// Jitter is the fraction of the backoff which is randomized, between 0 and 1, defaults to 0.2
+	Jitter *float64 `json:"jitter"`
+	// Timeout of each attempt, defaults depend on the integration
+	Timeout metav1.Duration `json:"timeout"`
+	// MaxElapsed bounds the time of all attempts and backoffs, no further attempt is started after it,
+	// defaults depend on the integration
+	MaxElapsed metav1.Duration `json:"maxElapsed"`
+}
+
+const (
+	defaultInitialBackoff = 200 * time.Millisecond
+	defaultMaxBackoff     = 5 * time.Second
+	defaultJitter         = 0.2
+)
+
+// defaultRetryPolicy are the defaults of the background integrations, publishers, telemetry and blocklist
+var defaultRetryPolicy = RetryPolicy{
+	Attempts:   3,
+	Timeout:    metav1.Duration{Duration: k8sTimeout},
+	MaxElapsed: metav1.Duration{Duration: 30 * time.Second},
+}
+
+// validate checks the retry policy and sets unset fields to the defaults of the integration
+func (p *RetryPolicy) validate(defaults RetryPolicy) error {
+	if p.Attempts < 0 {
+		return fmt.Errorf("retry attempts must not be negative, got %d", p.Attempts)
+	}
+	if p.Jitter != nil && (*p.Jitter < 0 || *p.Jitter > 1) {
+		return fmt.Errorf("retry jitter must be between 0 and 1, got %v", *p.Jitter)
+	}
+	if p.Attempts == 0 {
+		p.Attempts = defaults.Attempts
+	}
+	if p.InitialBackoff.Duration <= 0 {
+		p.InitialBackoff.Duration = defaultInitialBackoff
+	}
+	if p.MaxBackoff.Duration <= 0 {
+		p.MaxBackoff.Duration = defaultMaxBackoff
+	}
+	if p.Jitter == nil {
+		jitter := defaultJitter
+		p.Jitter = &jitter
+	}
+	if p.Timeout.Duration <= 0 {
+		p.Timeout = defaults.Timeout
+	}
+	if p.MaxElapsed.Duration <= 0 {
+		p.MaxElapsed = defaults.MaxElapsed
+	}
+	return nil
+}
+
+// backoff returns the randomized backoff after passed number of failed attempts
+func (p *RetryPolicy) backoff(failed int) time.Duration {
+	d := p.InitialBackoff.Duration
+	for i := 1; i < failed && d < p.MaxBackoff.Duration; i++ {
+		d *= 2
+	}
+	d = min(d, p.MaxBackoff.Duration)
+	jitter := defaultJitter
+	if p.Jitter != nil {
+		jitter = *p.Jitter
+	}
+	// the backoff is spread evenly over [d*(1-jitter), d*(1+jitter)]
+	return time.Duration(float64(d) * (1 + jitter*(2*rand.Float64()-1))) //nolint:gosec // no security context
+}
+
+// permanentError is an error which isn't retried
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// permanent marks the error as not retryable
+func permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// statusError is an unexpected HTTP status of an external destination
+type statusError struct {
+	code int
+	msg  string
+}
+
+func (e *statusError) Error() string {
+	return e.msg
+}
+
+// unexpectedStatus returns the error of an unexpected HTTP status with passed message format and status
+func unexpectedStatus(resp *http.Response, format string) error {
+	return &statusError{code: resp.StatusCode, msg: fmt.Sprintf(format, resp.Status)}
+}
+
+// retryable returns whether a call failing with err may succeed with another attempt
+func retryable(err error) bool {
+	var perm *permanentError
+	var status *statusError
+	switch {
+	case errors.As(err, &perm), errors.Is(err, context.Canceled):
+		return false
+	case errors.As(err, &status):
+		return status.code >= http.StatusInternalServerError || status.code == http.StatusTooManyRequests
+	default:
+		return true
+	}
+}
+
+// destinationOf returns the host of the URL as metric label, or the URL if it has none, like a socket path
+func destinationOf(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return rawURL
+}
+
+// retry calls fn until it succeeds, fails with an error which isn't retryable, the attempts are used up or
+// the next attempt would start after the maximum elapsed time. Each attempt gets a context with the timeout of
+// the policy. The error of the last attempt is returned.
+func retry(ctx context.Context, p *RetryPolicy, integration, destination string, fn func(context.Context) error) error {
+	start := time.Now()
+	defer func() {
+		externalDuration.WithLabelValues(integration, destination).Observe(time.Since(start).Seconds())
+	}()
+	attempts := max(p.Attempts, 1)
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = attemptWithTimeout(ctx, p.Timeout.Duration, fn)
+		if err == nil || attempt >= attempts || !retryable(err) {
+			break
+		}
+		backoff := p.backoff(attempt)
+		if p.MaxElapsed.Duration > 0 && time.Since(start)+backoff > p.MaxElapsed.Duration {
+			break
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			externalCalls.WithLabelValues(integration, destination, "error").Inc()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+		externalRetries.WithLabelValues(integration, destination).Inc()
+	}
+	if err != nil {
+		externalCalls.WithLabelValues(integration, destination, "error").Inc()
+		return err
+	}
+	externalCalls.WithLabelValues(integration, destination, "success").Inc()
+	return nil
+}
+
+// attemptWithTimeout calls fn with a context bounded by the timeout, zero means no timeout
+func attemptWithTimeout(ctx context.Context, timeout time.Duration, fn func(context.Context) error) error {
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return fn(ctx)
+}
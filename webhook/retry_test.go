@@ -0,0 +1,107 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_retry(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantAttempts int32
+		wantErr      bool
+	}{
+		{name: "success", statuses: []int{http.StatusOK}, wantAttempts: 1},
+		{name: "retried 5xx", statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}, wantAttempts: 3},
+		{name: "4xx not retried", statuses: []int{http.StatusBadRequest, http.StatusOK}, wantAttempts: 1, wantErr: true},
+		{name: "attempts used up", statuses: []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusOK}, wantAttempts: 3, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				n := attempts.Add(1)
+				w.WriteHeader(tt.statuses[n-1])
+			}))
+			defer srv.Close()
+
+			p := RetryPolicy{InitialBackoff: metav1.Duration{Duration: time.Millisecond}}
+			if err := p.validate(defaultRetryPolicy); err != nil {
+				t.Fatal(err)
+			}
+			err := retry(context.Background(), &p, "test", destinationOf(srv.URL), func(ctx context.Context) error {
+				req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, http.NoBody)
+				if err != nil {
+					return permanent(err)
+				}
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					return err
+				}
+				defer resp.Body.Close()
+				if resp.StatusCode != http.StatusOK {
+					return unexpectedStatus(resp, "unexpected status %s")
+				}
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("retry() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("retry() made %d attempts, want %d", got, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func Test_retry_maxElapsed(t *testing.T) {
+	p := RetryPolicy{
+		Attempts:       10,
+		InitialBackoff: metav1.Duration{Duration: 50 * time.Millisecond},
+		MaxElapsed:     metav1.Duration{Duration: 100 * time.Millisecond},
+	}
+	if err := p.validate(defaultRetryPolicy); err != nil {
+		t.Fatal(err)
+	}
+	attempts := 0
+	err := retry(context.Background(), &p, "test", "example.com", func(context.Context) error {
+		attempts++
+		return errors.New("connection refused")
+	})
+	if err == nil || attempts < 2 || attempts > 3 {
+		t.Errorf("retry() = %v after %d attempts, want error after 2 or 3 attempts", err, attempts)
+	}
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	jitter := 0.5
+	p := RetryPolicy{
+		InitialBackoff: metav1.Duration{Duration: 100 * time.Millisecond},
+		MaxBackoff:     metav1.Duration{Duration: time.Second},
+		Jitter:         &jitter,
+	}
+	for failed, want := range map[int]time.Duration{1: 100 * time.Millisecond, 3: 400 * time.Millisecond, 10: time.Second} {
+		for range 10 {
+			if got := p.backoff(failed); got < want/2 || got > want*3/2 {
+				t.Errorf("backoff(%d) = %s, want %s ± 50%%", failed, got, want)
+			}
+		}
+	}
+}
+
+func TestRetryPolicy_validate(t *testing.T) {
+	jitter := 1.5
+	for _, p := range []RetryPolicy{{Attempts: -1}, {Jitter: &jitter}} {
+		if err := p.validate(defaultRetryPolicy); err == nil {
+			t.Errorf("validate(%+v) want error", p)
+		}
+	}
+}
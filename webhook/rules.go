@@ -0,0 +1,324 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	v1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CosignRuleName is the name of the rule verifying image signatures
+const CosignRuleName = "cosign"
+
+// actions of rules which either deny requests or only warn the client
+const (
+	ActionDeny = "deny"
+	ActionWarn = "warn"
+)
+
+// Object is the object under admission, decoded from the AdmissionReview request.
+// Meta is set for all kinds, of the typed fields only the one matching the kind of the request is set.
+type Object struct {
+	Request     *v1.AdmissionRequest
+	Meta        metav1.ObjectMeta
+	Pod         *corev1.Pod
+	Deployment  *appsv1.Deployment
+	StatefulSet *appsv1.StatefulSet
+	DaemonSet   *appsv1.DaemonSet
+	Service     *corev1.Service
+	HPA         *autoscalingv2.HorizontalPodAutoscaler
+	Ingress     *networkingv1.Ingress
+
+	Role               *rbacv1.Role
+	ClusterRole        *rbacv1.ClusterRole
+	RoleBinding        *rbacv1.RoleBinding
+	ClusterRoleBinding *rbacv1.ClusterRoleBinding
+
+	// Facts are the facts the rules of the endpoint depend on, see FactDependent
+	Facts *Facts
+	// matches are the values matching the field policies of the rules of the endpoint, see fieldPolicyRule
+	matches fieldMatches
+	// semantics is the semantics version the rules decide with, 0 for the current one
+	semantics int
+}
+
+// templateSemantics is the semantics version since which the pod templates of workloads are validated like pods
+const templateSemantics = 3
+
+// validatesTemplates returns true if the rules validate the pod templates of all workloads. With older semantics,
+// the rules validating pods only keep doing so and the others validate the pod templates of deployments only.
+func (o *Object) validatesTemplates() bool {
+	return o.semantics == 0 || o.semantics >= templateSemantics
+}
+
+// decidedAlike returns true if the rules decide the object alike with the semantics versions a and b. They only
+// differ in the pod templates of workloads, see templateSemantics.
+func (o *Object) decidedAlike(a, b int) bool {
+	if o.Deployment == nil && o.StatefulSet == nil && o.DaemonSet == nil {
+		return true
+	}
+	return (a == 0 || a >= templateSemantics) == (b == 0 || b >= templateSemantics)
+}
+
+// Rule validates objects under admission. A returned error denies the request,
+// returned warnings are passed back to the client.
+type Rule interface {
+	Name() string
+	Validate(ctx context.Context, o *Object) ([]string, error)
+}
+
+// ruleFactory creates a rule from the webhook configuration
+type ruleFactory func(csh *CosignServerHandler, cfg *Config) (Rule, error)
+
+// ruleFactories contains all rules which can be referenced in the endpoint config
+var ruleFactories = map[string]ruleFactory{
+	CosignRuleName:           newCosignRule,
+	PriorityClassRuleName:    newPriorityClassRule,
+	RuntimeClassRuleName:     newRuntimeClassRule,
+	SecurityProfilesRuleName: newSecurityProfilesRule,
+	EnvRuleName:              newEnvRule,
+	ProbesRuleName:           newProbesRule,
+	DeprecationRuleName:      newDeprecationRule,
+	TopologyRuleName:         newTopologyRule,
+	CostRuleName:             newCostRule,
+	QuotaRuleName:            newQuotaRule,
+	NamingRuleName:           newNamingRule,
+	TTLRuleName:              newTTLRule,
+	ReferencesRuleName:       newReferencesRule,
+	DuplicatesRuleName:       newDuplicatesRule,
+	ServicePortsRuleName:     newServicePortsRule,
+	HPARuleName:              newHPARule,
+	RBACRuleName:             newRBACRule,
+	NamespaceRuleName:        newNamespaceRule,
+	SanityRuleName:           newSanityRule,
+	RegistriesRuleName:       newRegistriesRule,
+
+	ServiceAccountTokensRuleName:   newServiceAccountTokensRule,
+	BlocklistRuleName:              newBlocklistRule,
+	LicensesRuleName:               newLicensesRule,
+	ImageTagsRuleName:              newImageTagsRule,
+	ResourcesRuleName:              newResourcesRule,
+	RequiredLabelsRuleName:         newRequiredLabelsRule,
+	RequiredAnnotationsRuleName:    newRequiredAnnotationsRule,
+	PrivilegedRuleName:             newPrivilegedRule,
+	HostPathRuleName:               newHostPathRule,
+	HostNamespacesRuleName:         newHostNamespacesRule,
+	RunAsNonRootRuleName:           newRunAsNonRootRule,
+	ReadOnlyRootFilesystemRuleName: newReadOnlyRootFilesystemRule,
+	CapabilitiesRuleName:           newCapabilitiesRule,
+	ServiceAccountsRuleName:        newServiceAccountsRule,
+	SchedulingRuleName:             newSchedulingRule,
+	ReplicasRuleName:               newReplicasRule,
+	ContainerPortsRuleName:         newContainerPortsRule,
+	IngressHostsRuleName:           newIngressHostsRule,
+}
+
+// RuleNames returns the names of all rules which can be referenced in the endpoint config, sorted
+func RuleNames() []string {
+	return slices.Sorted(maps.Keys(ruleFactories))
+}
+
+// newRules creates the rules with passed names
+func newRules(csh *CosignServerHandler, cfg *Config, names []string) ([]Rule, error) {
+	rules := make([]Rule, 0, len(names))
+	for _, n := range names {
+		f, ok := ruleFactories[n]
+		if !ok {
+			return nil, fmt.Errorf("unknown rule %q", n)
+		}
+		r, err := f(csh, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("can't create rule %q: %w", n, err)
+		}
+		rules = append(rules, r)
+	}
+	return withWatchdog(cfg.Watchdog, rules), nil
+}
+
+// cosignRule verifies the signatures of the pod's container images
+type cosignRule struct {
+	csh *CosignServerHandler
+}
+
+func newCosignRule(csh *CosignServerHandler, _ *Config) (Rule, error) {
+	return &cosignRule{csh: csh}, nil
+}
+
+// Name returns the name of the rule
+func (*cosignRule) Name() string {
+	return CosignRuleName
+}
+
+// Validate verifies the images of pods, other objects are ignored
+func (r *cosignRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	if o.Pod == nil {
+		return nil, nil
+	}
+	return nil, r.csh.verifyPod(ctx, o.Pod)
+}
+
+// podContainers returns the init, regular and ephemeral containers of the pod spec
+func podContainers(spec *corev1.PodSpec) []corev1.Container {
+	containers := make([]corev1.Container, 0, len(spec.InitContainers)+len(spec.Containers)+len(spec.EphemeralContainers))
+	containers = append(containers, spec.InitContainers...)
+	containers = append(containers, spec.Containers...)
+	for i := range spec.EphemeralContainers {
+		containers = append(containers, corev1.Container(spec.EphemeralContainers[i].EphemeralContainerCommon))
+	}
+	return containers
+}
+
+// violationsError joins the violations found by a rule into one denial error, or returns nil if there are none
+func violationsError(violations []string) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(violations, "; "))
+}
+
+// compilePatterns compiles regular expressions which have to match the whole value
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile("^(?:" + p + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// patternSet matches values against a list of patterns which have to match the whole value. The patterns are
+// compiled into a single alternation, so a value is matched in one pass instead of one pass per pattern.
+type patternSet struct {
+	re *regexp.Regexp
+}
+
+// compilePatternSet compiles the patterns into a pattern set, errors name the invalid pattern
+func compilePatternSet(patterns []string) (*patternSet, error) {
+	if _, err := compilePatterns(patterns); err != nil {
+		return nil, err
+	}
+	if len(patterns) == 0 {
+		return &patternSet{}, nil
+	}
+	alternatives := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		alternatives = append(alternatives, "(?:"+p+")")
+	}
+	re, err := regexp.Compile("^(?:" + strings.Join(alternatives, "|") + ")$")
+	if err != nil {
+		return nil, err
+	}
+	return &patternSet{re: re}, nil
+}
+
+// empty returns true if the set has no patterns
+func (s *patternSet) empty() bool {
+	return s.re == nil
+}
+
+// matches returns true if one of the patterns matches the value
+func (s *patternSet) matches(value string) bool {
+	return s.re != nil && s.re.MatchString(value)
+}
+
+// enforce denies the violations found by a rule, or only returns them as warnings if the action is warn
+func enforce(action string, violations []string) ([]string, error) {
+	if action == ActionWarn {
+		return violations, nil
+	}
+	return nil, violationsError(violations)
+}
+
+// validateAction checks that passed action is either deny or warn, an empty action is replaced by passed default
+func validateAction(action *string, def string) error {
+	switch *action {
+	case "":
+		*action = def
+	case ActionDeny, ActionWarn:
+	default:
+		return fmt.Errorf("invalid action %q, must be %q or %q", *action, ActionDeny, ActionWarn)
+	}
+	return nil
+}
+
+// podRequests returns the effective resource requests of the pod spec: the sum of the containers' requests,
+// or the highest init container request if that's larger. Missing requests default to the limits.
+func podRequests(spec *corev1.PodSpec) corev1.ResourceList {
+	total := corev1.ResourceList{}
+	for i := range spec.Containers {
+		for n, q := range containerRequests(&spec.Containers[i]) {
+			sum := total[n]
+			sum.Add(q)
+			total[n] = sum
+		}
+	}
+	for i := range spec.InitContainers {
+		for n, q := range containerRequests(&spec.InitContainers[i]) {
+			if cur, ok := total[n]; !ok || q.Cmp(cur) > 0 {
+				total[n] = q
+			}
+		}
+	}
+	return total
+}
+
+// containerRequests returns the requests of the container, defaulting to its limits
+func containerRequests(c *corev1.Container) corev1.ResourceList {
+	req := corev1.ResourceList{}
+	for n, q := range c.Resources.Limits {
+		req[n] = q.DeepCopy()
+	}
+	for n, q := range c.Resources.Requests {
+		req[n] = q.DeepCopy()
+	}
+	return req
+}
+
+// podSpec returns the pod spec of pods or the pod template spec of workloads, or nil for other objects
+func podSpec(o *Object) *corev1.PodSpec {
+	_, spec := podTemplate(o)
+	return spec
+}
+
+// templatePodSpec returns the pod spec like podSpec for the rules which validated pods only before
+// templateSemantics. With older semantics, it's nil for workloads.
+func templatePodSpec(o *Object) *corev1.PodSpec {
+	if o.Pod == nil && !o.validatesTemplates() {
+		return nil
+	}
+	return podSpec(o)
+}
+
+// podTemplate returns the metadata and spec of pods or of the pod template of workloads, or nil for other objects.
+// Rules validating pod templates catch violations when the workload is applied, not only when its controller fails
+// to create pods. Before templateSemantics, only the pod templates of deployments are returned.
+func podTemplate(o *Object) (*metav1.ObjectMeta, *corev1.PodSpec) {
+	switch {
+	case o.Pod != nil:
+		return &o.Pod.ObjectMeta, &o.Pod.Spec
+	case o.Deployment != nil:
+		return &o.Deployment.Spec.Template.ObjectMeta, &o.Deployment.Spec.Template.Spec
+	case !o.validatesTemplates():
+		return nil, nil
+	case o.StatefulSet != nil:
+		return &o.StatefulSet.Spec.Template.ObjectMeta, &o.StatefulSet.Spec.Template.Spec
+	case o.DaemonSet != nil:
+		return &o.DaemonSet.Spec.Template.ObjectMeta, &o.DaemonSet.Spec.Template.Spec
+	default:
+		return nil, nil
+	}
+}
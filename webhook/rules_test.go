@@ -0,0 +1,158 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+// podObject returns an admission object for a pod with passed spec in passed namespace
+func podObject(ns string, spec corev1.PodSpec) *Object {
+	return &Object{
+		Request: &v1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+			Namespace: ns,
+			Name:      "test",
+		},
+		Meta: metav1.ObjectMeta{Name: "test", Namespace: ns},
+		Pod: &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: ns},
+			Spec:       spec,
+		},
+	}
+}
+
+// testRule creates a rule with passed factory for a handler with a fake clientset containing passed objects,
+// and starts the informers registered by the rule
+func testRule(t *testing.T, f ruleFactory, cfg *Config, objects ...runtime.Object) Rule {
+	t.Helper()
+	cs := fake.NewSimpleClientset(objects...)
+	csh := &CosignServerHandler{
+		cs:        cs,
+		eb:        record.NewBroadcaster(),
+		informers: informers.NewSharedInformerFactory(cs, 0),
+		meta:      fakeMetadataClient(objects...),
+	}
+	r, err := f(csh, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	csh.Start(ctx)
+	return r
+}
+
+// fakeMetadataClient returns a fake metadata client serving the metadata of passed configmaps and secrets,
+// other objects are left out
+func fakeMetadataClient(objects ...runtime.Object) *metadatafake.FakeMetadataClient {
+	scheme := runtime.NewScheme()
+	if err := metav1.AddMetaToScheme(scheme); err != nil {
+		panic(err)
+	}
+	var partial []runtime.Object
+	for _, o := range objects {
+		var kind string
+		var meta metav1.ObjectMeta
+		switch obj := o.(type) {
+		case *corev1.ConfigMap:
+			kind, meta = "ConfigMap", obj.ObjectMeta
+		case *corev1.Secret:
+			kind, meta = "Secret", obj.ObjectMeta
+		default:
+			continue
+		}
+		partial = append(partial, &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: kind},
+			ObjectMeta: meta,
+		})
+	}
+	return metadatafake.NewSimpleMetadataClient(scheme, partial...)
+}
+
+func Test_newRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []string
+		wantErr bool
+	}{
+		{
+			name:  "known rules",
+			rules: []string{CosignRuleName, PriorityClassRuleName},
+		},
+		{
+			name:    "unknown rule",
+			rules:   []string{"unknown"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newRules(&CosignServerHandler{}, DefaultConfig(), tt.rules)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(got) != len(tt.rules) {
+				t.Errorf("newRules() got %d rules, want %d", len(got), len(tt.rules))
+			}
+		})
+	}
+}
+
+func Test_patternSet(t *testing.T) {
+	s, err := compilePatternSet([]string{"tenant-.*", "team-[a-z]+", "a|b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for value, want := range map[string]bool{
+		"tenant-a":    true,
+		"team-x":      true,
+		"team-1":      false,
+		"a":           true,
+		"ab":          false,
+		"kube-system": false,
+		"my-tenant-a": false,
+	} {
+		if got := s.matches(value); got != want {
+			t.Errorf("matches(%q) = %v, want %v", value, got, want)
+		}
+	}
+
+	if empty, _ := compilePatternSet(nil); !empty.empty() || empty.matches("") {
+		t.Error("compilePatternSet(nil) matches")
+	}
+	if _, err := compilePatternSet([]string{"ok", "("}); err == nil || !strings.Contains(err.Error(), `"("`) {
+		t.Errorf("compilePatternSet() error = %v, want error naming the invalid pattern", err)
+	}
+}
+
+func Test_podTemplate(t *testing.T) {
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "web", Image: "web"}}},
+	}
+	for name, o := range map[string]*Object{
+		"deployment":  {Deployment: &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Template: template}}},
+		"statefulset": {StatefulSet: &appsv1.StatefulSet{Spec: appsv1.StatefulSetSpec{Template: template}}},
+		"daemonset":   {DaemonSet: &appsv1.DaemonSet{Spec: appsv1.DaemonSetSpec{Template: template}}},
+	} {
+		meta, spec := podTemplate(o)
+		if meta == nil || meta.Labels["app"] != "web" || spec == nil || spec.Containers[0].Name != "web" {
+			t.Errorf("%s: podTemplate() = %v, %v, want the pod template", name, meta, spec)
+		}
+	}
+	if meta, spec := podTemplate(&Object{Service: &corev1.Service{}}); meta != nil || spec != nil {
+		t.Errorf("podTemplate() of a service = %v, %v, want nil", meta, spec)
+	}
+}
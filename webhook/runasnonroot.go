@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	v1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	nodev1listers "k8s.io/client-go/listers/node/v1"
+)
+
+// RunAsNonRootRuleName is the name of the rule denying containers which may run as root
+const RunAsNonRootRuleName = "runAsNonRoot"
+
+// RunAsNonRootConfig configures the namespaces whose containers may run as root
+type RunAsNonRootConfig struct {
+	// ExemptNamespaces may run containers as root
+	ExemptNamespaces []string `json:"exemptNamespaces"`
+	// Action is either deny (default) or warn
+	Action string `json:"action"`
+}
+
+// runAsNonRootRule denies containers which may run as root. A container runs as non-root if its effective
+// runAsUser is not 0, or if it's unset and its effective runAsNonRoot is true, so the kubelet refuses images running
+// as root. The settings of the container take precedence over the ones of the pod.
+type runAsNonRootRule struct {
+	cfg RunAsNonRootConfig
+	// runtimeClasses detect Windows pods by the node selector of their runtime class
+	runtimeClasses nodev1listers.RuntimeClassLister
+}
+
+func newRunAsNonRootRule(csh *CosignServerHandler, cfg *Config) (Rule, error) {
+	c := cfg.RunAsNonRoot
+	if err := validateAction(&c.Action, ActionDeny); err != nil {
+		return nil, err
+	}
+	return &runAsNonRootRule{cfg: c, runtimeClasses: csh.informers.Node().V1().RuntimeClasses().Lister()}, nil
+}
+
+// Name returns the name of the rule
+func (*runAsNonRootRule) Name() string {
+	return RunAsNonRootRuleName
+}
+
+// Validate checks the effective user of the init, regular and ephemeral containers of pods and workloads. Windows
+// containers run as user names set in windowsOptions, not as UIDs, so Windows pods are skipped.
+func (r *runAsNonRootRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	spec := podSpec(o)
+	if spec == nil || o.Request.Operation == v1.Delete {
+		return nil, nil
+	}
+	if slices.Contains(r.cfg.ExemptNamespaces, o.Request.Namespace) {
+		tracef(ctx, "namespace %q is exempt", o.Request.Namespace)
+		return nil, nil
+	}
+	if podOS(spec, r.runtimeClasses) == corev1.Windows {
+		tracef(ctx, "Windows pod")
+		return nil, nil
+	}
+	psc := spec.SecurityContext
+	if psc == nil {
+		psc = &corev1.PodSecurityContext{}
+	}
+
+	var violations []string
+	for _, c := range podContainers(spec) {
+		user, nonRoot := psc.RunAsUser, psc.RunAsNonRoot
+		if sc := c.SecurityContext; sc != nil {
+			if sc.RunAsUser != nil {
+				user = sc.RunAsUser
+			}
+			if sc.RunAsNonRoot != nil {
+				nonRoot = sc.RunAsNonRoot
+			}
+		}
+		switch {
+		case user != nil && *user == 0:
+			violations = append(violations, fmt.Sprintf("container %q runs as root user 0", c.Name))
+		case user == nil && (nonRoot == nil || !*nonRoot):
+			violations = append(violations, fmt.Sprintf("container %q may run as root, set runAsNonRoot or a non-zero runAsUser", c.Name))
+		}
+	}
+	return enforce(r.cfg.Action, violations)
+}
@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_runAsNonRootRule_Validate(t *testing.T) {
+	yes, no := true, false
+	var root, user int64 = 0, 1000
+	windows := "windows"
+	tests := []struct {
+		name         string
+		namespace    string
+		os           *corev1.PodOS
+		runtimeClass *string
+		pod          *corev1.PodSecurityContext
+		container    *corev1.SecurityContext
+		cfg          RunAsNonRootConfig
+		wantWarnings int
+		wantErr      string
+	}{
+		{
+			name:    "no security context",
+			wantErr: `container "app" may run as root`,
+		},
+		{
+			name: "pod runAsNonRoot",
+			pod:  &corev1.PodSecurityContext{RunAsNonRoot: &yes},
+		},
+		{
+			name:      "container runAsNonRoot",
+			container: &corev1.SecurityContext{RunAsNonRoot: &yes},
+		},
+		{
+			name:      "container overrides pod runAsNonRoot",
+			pod:       &corev1.PodSecurityContext{RunAsNonRoot: &yes},
+			container: &corev1.SecurityContext{RunAsNonRoot: &no},
+			wantErr:   `container "app" may run as root`,
+		},
+		{
+			name: "pod non-root user",
+			pod:  &corev1.PodSecurityContext{RunAsUser: &user},
+		},
+		{
+			name:    "pod root user",
+			pod:     &corev1.PodSecurityContext{RunAsNonRoot: &yes, RunAsUser: &root},
+			wantErr: `container "app" runs as root user 0`,
+		},
+		{
+			name:      "container user overrides pod root user",
+			pod:       &corev1.PodSecurityContext{RunAsUser: &root},
+			container: &corev1.SecurityContext{RunAsUser: &user},
+		},
+		{
+			name:      "container root user overrides pod user",
+			pod:       &corev1.PodSecurityContext{RunAsNonRoot: &yes, RunAsUser: &user},
+			container: &corev1.SecurityContext{RunAsUser: &root},
+			wantErr:   `container "app" runs as root user 0`,
+		},
+		{
+			name: "windows",
+			os:   &corev1.PodOS{Name: corev1.Windows},
+			pod:  &corev1.PodSecurityContext{RunAsUser: &root},
+		},
+		{
+			name:         "windows runtime class",
+			runtimeClass: &windows,
+		},
+		{
+			name:      "exempt namespace",
+			namespace: "kube-system",
+			cfg:       RunAsNonRootConfig{ExemptNamespaces: []string{"kube-system"}},
+		},
+		{
+			name:         "warn",
+			cfg:          RunAsNonRootConfig{Action: ActionWarn},
+			wantWarnings: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := testRule(t, newRunAsNonRootRule, &Config{RunAsNonRoot: tt.cfg}, windowsRuntimeClass())
+			ns := tt.namespace
+			if ns == "" {
+				ns = "default"
+			}
+			spec := corev1.PodSpec{
+				OS:               tt.os,
+				RuntimeClassName: tt.runtimeClass,
+				SecurityContext:  tt.pod,
+				Containers:       []corev1.Container{{Name: "app", SecurityContext: tt.container}},
+			}
+			warnings, err := r.Validate(context.Background(), podObject(ns, spec))
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("Validate() warnings = %v, want %d", warnings, tt.wantWarnings)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
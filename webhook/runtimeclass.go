@@ -0,0 +1,47 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+)
+
+// RuntimeClassRuleName is the name of the rule requiring runtime classes
+const RuntimeClassRuleName = "runtimeClass"
+
+// RuntimeClassConfig configures the runtime classes required per namespace
+type RuntimeClassConfig struct {
+	// Namespaces maps namespaces to the runtime class their pods must use, e.g. gvisor for untrusted namespaces
+	Namespaces map[string]string `json:"namespaces"`
+}
+
+// runtimeClassRule requires sandboxed runtimes for pods in untrusted namespaces
+type runtimeClassRule struct {
+	cfg RuntimeClassConfig
+}
+
+func newRuntimeClassRule(_ *CosignServerHandler, cfg *Config) (Rule, error) {
+	return &runtimeClassRule{cfg: cfg.RuntimeClass}, nil
+}
+
+// Name returns the name of the rule
+func (*runtimeClassRule) Name() string {
+	return RuntimeClassRuleName
+}
+
+// Validate checks the runtime class of pods and workloads in configured namespaces
+func (r *runtimeClassRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	spec := templatePodSpec(o)
+	if spec == nil {
+		return nil, nil
+	}
+	want, ok := r.cfg.Namespaces[o.Request.Namespace]
+	if !ok {
+		return nil, nil
+	}
+	tracef(ctx, "namespace %q requires runtime class %q", o.Request.Namespace, want)
+	got := spec.RuntimeClassName
+	if got == nil || *got != want {
+		return nil, fmt.Errorf("pods in namespace %q must use runtime class %q", o.Request.Namespace, want)
+	}
+	return nil, nil
+}
@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_runtimeClassRule_Validate(t *testing.T) {
+	gvisor, runc := "gvisor", "runc"
+	cfg := &Config{RuntimeClass: RuntimeClassConfig{Namespaces: map[string]string{"untrusted": gvisor}}}
+	tests := []struct {
+		name         string
+		namespace    string
+		runtimeClass *string
+		wantErr      string
+	}{
+		{
+			name:         "allowed class",
+			namespace:    "untrusted",
+			runtimeClass: &gvisor,
+		},
+		{
+			name:         "disallowed class",
+			namespace:    "untrusted",
+			runtimeClass: &runc,
+			wantErr:      `pods in namespace "untrusted" must use runtime class "gvisor"`,
+		},
+		{
+			name:      "missing runtime class",
+			namespace: "untrusted",
+			wantErr:   `pods in namespace "untrusted" must use runtime class "gvisor"`,
+		},
+		{
+			name:      "default class in namespace without requirement",
+			namespace: "default",
+		},
+		{
+			name:         "any class in namespace without requirement",
+			namespace:    "default",
+			runtimeClass: &runc,
+		},
+	}
+
+	r, err := newRuntimeClassRule(nil, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := corev1.PodSpec{RuntimeClassName: tt.runtimeClass, Containers: []corev1.Container{{Name: "app"}}}
+			for _, o := range []*Object{podObject(tt.namespace, spec), deploymentObject(tt.namespace, nil, spec)} {
+				warnings, err := r.Validate(context.Background(), o)
+				if len(warnings) != 0 {
+					t.Errorf("Validate(%s) warnings = %v, want none", o.Request.Kind.Kind, warnings)
+				}
+				if tt.wantErr == "" {
+					if err != nil {
+						t.Errorf("Validate(%s) error = %v, want none", o.Request.Kind.Kind, err)
+					}
+					continue
+				}
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Validate(%s) error = %v, want %q", o.Request.Kind.Kind, err, tt.wantErr)
+				}
+			}
+		})
+	}
+}
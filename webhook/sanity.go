@@ -0,0 +1,79 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	v1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// SanityRuleName is the name of the rule detecting obviously broken pod specs
+const SanityRuleName = "sanity"
+
+// sanityRule catches common mistakes in pod specs early, with precise messages instead of
+// a pod which crashes or never gets ready
+type sanityRule struct{}
+
+func newSanityRule(_ *CosignServerHandler, _ *Config) (Rule, error) {
+	return &sanityRule{}, nil
+}
+
+// Name returns the name of the rule
+func (*sanityRule) Name() string {
+	return SanityRuleName
+}
+
+// Validate checks the pod spec of pods and workloads for commands, resources, container names and ports
+func (*sanityRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	spec := podSpec(o)
+	if spec == nil || o.Request.Operation == v1.Delete {
+		return nil, nil
+	}
+
+	containers := podContainers(spec)
+	tracef(ctx, "%d containers", len(containers))
+	var violations []string
+	names := map[string]bool{}
+	for _, c := range containers {
+		if names[c.Name] {
+			violations = append(violations, fmt.Sprintf("container name %q is used more than once", c.Name))
+		}
+		names[c.Name] = true
+
+		if len(c.Command) == 1 && strings.ContainsAny(c.Command[0], " \t") {
+			violations = append(violations, fmt.Sprintf("command %q of container %q is a single string with spaces, "+
+				"split it into command and args or use [\"sh\", \"-c\", ...]", c.Command[0], c.Name))
+		}
+
+		// the resources are sorted, so the messages don't depend on the map order
+		for _, n := range slices.Sorted(maps.Keys(c.Resources.Requests)) {
+			req := c.Resources.Requests[n]
+			if limit, ok := c.Resources.Limits[n]; ok && req.Cmp(limit) > 0 {
+				violations = append(violations, fmt.Sprintf("%s request %s of container %q is larger than its limit %s",
+					n, req.String(), c.Name, limit.String()))
+			}
+		}
+	}
+
+	// containers share the network namespace, the same port can't be bound twice
+	ports := map[string]string{}
+	for i := range spec.Containers {
+		c := &spec.Containers[i]
+		for _, p := range c.Ports {
+			protocol := p.Protocol
+			if protocol == "" {
+				protocol = corev1.ProtocolTCP
+			}
+			key := fmt.Sprintf("%d/%s", p.ContainerPort, protocol)
+			if other, ok := ports[key]; ok && other != c.Name {
+				violations = append(violations, fmt.Sprintf("port %s of container %q conflicts with container %q", key, c.Name, other))
+			}
+			ports[key] = c.Name
+		}
+	}
+	return nil, violationsError(violations)
+}
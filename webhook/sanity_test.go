@@ -0,0 +1,92 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func Test_sanityRule_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    corev1.PodSpec
+		wantErr bool
+	}{
+		{
+			name: "valid pod",
+			spec: corev1.PodSpec{Containers: []corev1.Container{
+				{Name: "app", Command: []string{"sh", "-c", "sleep 10"}, Ports: []corev1.ContainerPort{{ContainerPort: 8080}}},
+				{Name: "proxy", Ports: []corev1.ContainerPort{{ContainerPort: 8080, Protocol: corev1.ProtocolUDP}}},
+			}},
+		},
+		{
+			name:    "command with spaces",
+			spec:    corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Command: []string{"sleep 10"}}}},
+			wantErr: true,
+		},
+		{
+			name: "request larger than limit",
+			spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+				Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+			}}}},
+			wantErr: true,
+		},
+		{
+			name: "duplicate container names",
+			spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{Name: "app"}},
+				Containers:     []corev1.Container{{Name: "app"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "port conflict",
+			spec: corev1.PodSpec{Containers: []corev1.Container{
+				{Name: "app", Ports: []corev1.ContainerPort{{ContainerPort: 8080}}},
+				{Name: "proxy", Ports: []corev1.ContainerPort{{ContainerPort: 8080, Protocol: corev1.ProtocolTCP}}},
+			}},
+			wantErr: true,
+		},
+	}
+	r, _ := newSanityRule(nil, &Config{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Validate(context.Background(), podObject("default", tt.spec))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_sanityRule_ValidateOrder(t *testing.T) {
+	spec := corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Resources: corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceMemory:           resource.MustParse("1Gi"),
+			corev1.ResourceCPU:              resource.MustParse("2"),
+			corev1.ResourceEphemeralStorage: resource.MustParse("2Gi"),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceMemory:           resource.MustParse("512Mi"),
+			corev1.ResourceCPU:              resource.MustParse("1"),
+			corev1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+		},
+	}}}}
+	r, _ := newSanityRule(nil, &Config{})
+	_, want := r.Validate(context.Background(), podObject("default", spec))
+	if want == nil || !strings.Contains(want.Error(), "cpu request 2") {
+		t.Fatalf("Validate() error = %v, want cpu request larger than its limit", want)
+	}
+	if i, j := strings.Index(want.Error(), "cpu"), strings.Index(want.Error(), "memory"); i > j {
+		t.Errorf("Validate() error = %v, want cpu before memory", want)
+	}
+	for range 20 {
+		if _, err := r.Validate(context.Background(), podObject("default", spec)); err == nil || err.Error() != want.Error() {
+			t.Fatalf("Validate() error = %v, want %v", err, want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	v1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// SchedulingRuleName is the name of the rule restricting tolerations and node selectors
+	SchedulingRuleName = "scheduling"
+	// defaultTolerationPrefix is the prefix of the taints the API server and the DaemonSet controller tolerate
+	// by default, e.g. node.kubernetes.io/not-ready
+	defaultTolerationPrefix = "node.kubernetes.io/"
+)
+
+// wellKnownNodeSelectors are the node labels pods may always select unless denied
+var wellKnownNodeSelectors = []string{corev1.LabelOSStable, corev1.LabelArchStable}
+
+// SchedulingPolicy restricts the taints pods may tolerate and the node labels they may select by key
+type SchedulingPolicy struct {
+	// AllowedTolerations are the taint keys pods may tolerate, empty allows all which aren't denied
+	AllowedTolerations []string `json:"allowedTolerations"`
+	// DeniedTolerations are the taint keys pods must not tolerate
+	DeniedTolerations []string `json:"deniedTolerations"`
+	// AllowedNodeSelectors are the node label keys pods may select, empty allows all which aren't denied
+	AllowedNodeSelectors []string `json:"allowedNodeSelectors"`
+	// DeniedNodeSelectors are the node label keys pods must not select
+	DeniedNodeSelectors []string `json:"deniedNodeSelectors"`
+}
+
+// SchedulingConfig configures the tolerations and node selectors workloads may use
+type SchedulingConfig struct {
+	// AllowedTolerations are the taint keys pods may tolerate, empty allows all which aren't denied
+	AllowedTolerations []string `json:"allowedTolerations"`
+	// DeniedTolerations are the taint keys pods must not tolerate, e.g. dedicated infra taints
+	DeniedTolerations []string `json:"deniedTolerations"`
+	// AllowedNodeSelectors are the node label keys pods may select, empty allows all which aren't denied
+	AllowedNodeSelectors []string `json:"allowedNodeSelectors"`
+	// DeniedNodeSelectors are the node label keys pods must not select, e.g. node-role.kubernetes.io/infra
+	DeniedNodeSelectors []string `json:"deniedNodeSelectors"`
+	// Namespaces maps namespaces to their scheduling policy, replacing the global one
+	Namespaces map[string]SchedulingPolicy `json:"namespaces"`
+	// Action is either deny (default) or warn
+	Action string `json:"action"`
+}
+
+// schedulingRule keeps tenant workloads off dedicated nodes, e.g. infra nodes tainted for the platform
+type schedulingRule struct {
+	policy     SchedulingPolicy
+	namespaces map[string]SchedulingPolicy
+	action     string
+}
+
+func newSchedulingRule(_ *CosignServerHandler, cfg *Config) (Rule, error) {
+	c := cfg.Scheduling
+	if err := validateAction(&c.Action, ActionDeny); err != nil {
+		return nil, err
+	}
+	return &schedulingRule{
+		policy: SchedulingPolicy{
+			AllowedTolerations:   c.AllowedTolerations,
+			DeniedTolerations:    c.DeniedTolerations,
+			AllowedNodeSelectors: c.AllowedNodeSelectors,
+			DeniedNodeSelectors:  c.DeniedNodeSelectors,
+		},
+		namespaces: c.Namespaces,
+		action:     c.Action,
+	}, nil
+}
+
+// Name returns the name of the rule
+func (*schedulingRule) Name() string {
+	return SchedulingRuleName
+}
+
+// Validate checks the tolerations, node selectors and required node affinity of pods and workloads. The
+// node.kubernetes.io taints and the kubernetes.io/os and kubernetes.io/arch labels are always allowed unless denied.
+func (r *schedulingRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	spec := podSpec(o)
+	if spec == nil || o.Request.Operation == v1.Delete {
+		return nil, nil
+	}
+	ns := o.Request.Namespace
+	p, ok := r.namespaces[ns]
+	if !ok {
+		p = r.policy
+		tracef(ctx, "default policy, namespace %q has none", ns)
+	} else {
+		tracef(ctx, "policy of namespace %q", ns)
+	}
+
+	var violations []string
+	for _, t := range spec.Tolerations {
+		switch {
+		case t.Key == "" && t.Operator == corev1.TolerationOpExists:
+			if len(p.AllowedTolerations) > 0 || len(p.DeniedTolerations) > 0 {
+				violations = append(violations, "toleration of all taints is not allowed")
+			}
+		case slices.Contains(p.DeniedTolerations, t.Key):
+			violations = append(violations, fmt.Sprintf("toleration of taint %q is denied", t.Key))
+		case len(p.AllowedTolerations) > 0 && !slices.Contains(p.AllowedTolerations, t.Key) &&
+			!strings.HasPrefix(t.Key, defaultTolerationPrefix):
+			violations = append(violations, fmt.Sprintf("toleration of taint %q is not allowed in namespace %q, allowed: %s",
+				t.Key, ns, strings.Join(p.AllowedTolerations, ", ")))
+		}
+	}
+	for _, key := range nodeSelectorKeys(spec) {
+		switch {
+		case slices.Contains(p.DeniedNodeSelectors, key):
+			violations = append(violations, fmt.Sprintf("node selector %q is denied", key))
+		case len(p.AllowedNodeSelectors) > 0 && !slices.Contains(p.AllowedNodeSelectors, key) &&
+			!slices.Contains(wellKnownNodeSelectors, key):
+			violations = append(violations, fmt.Sprintf("node selector %q is not allowed in namespace %q, allowed: %s",
+				key, ns, strings.Join(p.AllowedNodeSelectors, ", ")))
+		}
+	}
+	return enforce(r.action, violations)
+}
+
+// nodeSelectorKeys returns the sorted node label keys selected by the node selector and the required node affinity
+func nodeSelectorKeys(spec *corev1.PodSpec) []string {
+	keys := map[string]bool{}
+	for k := range spec.NodeSelector {
+		keys[k] = true
+	}
+	if a := spec.Affinity; a != nil && a.NodeAffinity != nil && a.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		for _, term := range a.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+			for _, e := range term.MatchExpressions {
+				keys[e.Key] = true
+			}
+		}
+	}
+	return slices.Sorted(maps.Keys(keys))
+}
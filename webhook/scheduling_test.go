@@ -0,0 +1,148 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_schedulingRule_Validate(t *testing.T) {
+	tolerate := func(keys ...string) corev1.PodSpec {
+		spec := corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}}
+		for _, k := range keys {
+			spec.Tolerations = append(spec.Tolerations, corev1.Toleration{Key: k, Operator: corev1.TolerationOpExists})
+		}
+		return spec
+	}
+	selectNodes := func(keys ...string) corev1.PodSpec {
+		spec := corev1.PodSpec{NodeSelector: map[string]string{}, Containers: []corev1.Container{{Name: "app"}}}
+		for _, k := range keys {
+			spec.NodeSelector[k] = "true"
+		}
+		return spec
+	}
+	infra := SchedulingConfig{
+		DeniedTolerations:   []string{"dedicated"},
+		DeniedNodeSelectors: []string{"node-role.kubernetes.io/infra"},
+	}
+	tests := []struct {
+		name         string
+		namespace    string
+		spec         corev1.PodSpec
+		cfg          SchedulingConfig
+		wantWarnings int
+		wantErr      string
+	}{
+		{
+			name: "no policy",
+			spec: tolerate("dedicated"),
+		},
+		{
+			name:    "denied toleration",
+			spec:    tolerate("dedicated"),
+			cfg:     infra,
+			wantErr: `toleration of taint "dedicated" is denied`,
+		},
+		{
+			name:    "toleration of all taints",
+			spec:    tolerate(""),
+			cfg:     infra,
+			wantErr: "toleration of all taints is not allowed",
+		},
+		{
+			name: "other toleration",
+			spec: tolerate("gpu"),
+			cfg:  infra,
+		},
+		{
+			name:    "toleration not allowed",
+			spec:    tolerate("gpu", "node.kubernetes.io/not-ready"),
+			cfg:     SchedulingConfig{AllowedTolerations: []string{"spot"}},
+			wantErr: `toleration of taint "gpu" is not allowed in namespace "default", allowed: spot`,
+		},
+		{
+			name: "default tolerations allowed",
+			spec: tolerate("spot", "node.kubernetes.io/not-ready", "node.kubernetes.io/unreachable"),
+			cfg:  SchedulingConfig{AllowedTolerations: []string{"spot"}},
+		},
+		{
+			name:    "denied node selector",
+			spec:    selectNodes("node-role.kubernetes.io/infra"),
+			cfg:     infra,
+			wantErr: `node selector "node-role.kubernetes.io/infra" is denied`,
+		},
+		{
+			name: "denied required node affinity",
+			spec: corev1.PodSpec{
+				Affinity: &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+						NodeSelectorTerms: []corev1.NodeSelectorTerm{{MatchExpressions: []corev1.NodeSelectorRequirement{{
+							Key: "node-role.kubernetes.io/infra", Operator: corev1.NodeSelectorOpExists,
+						}}}},
+					},
+				}},
+				Containers: []corev1.Container{{Name: "app"}},
+			},
+			cfg:     infra,
+			wantErr: `node selector "node-role.kubernetes.io/infra" is denied`,
+		},
+		{
+			name:    "node selector not allowed",
+			spec:    selectNodes("pool", "kubernetes.io/os"),
+			cfg:     SchedulingConfig{AllowedNodeSelectors: []string{"topology.kubernetes.io/zone"}},
+			wantErr: `node selector "pool" is not allowed in namespace "default", allowed: topology.kubernetes.io/zone`,
+		},
+		{
+			name:      "namespace replaces global policy",
+			namespace: "platform",
+			spec:      tolerate("dedicated"),
+			cfg: SchedulingConfig{
+				DeniedTolerations: infra.DeniedTolerations,
+				Namespaces:        map[string]SchedulingPolicy{"platform": {AllowedTolerations: []string{"dedicated"}}},
+			},
+		},
+		{
+			name:      "namespace policy",
+			namespace: "team-a",
+			spec:      selectNodes("pool"),
+			cfg: SchedulingConfig{
+				Namespaces: map[string]SchedulingPolicy{"team-a": {DeniedNodeSelectors: []string{"pool"}}},
+			},
+			wantErr: `node selector "pool" is denied`,
+		},
+		{
+			name:         "warn",
+			spec:         tolerate("dedicated"),
+			cfg:          SchedulingConfig{DeniedTolerations: []string{"dedicated"}, Action: ActionWarn},
+			wantWarnings: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newSchedulingRule(&CosignServerHandler{}, &Config{Scheduling: tt.cfg})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ns := tt.namespace
+			if ns == "" {
+				ns = "default"
+			}
+			warnings, err := r.Validate(context.Background(), podObject(ns, tt.spec))
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("Validate() warnings = %v, want %d", warnings, tt.wantWarnings)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	nodev1listers "k8s.io/client-go/listers/node/v1"
+)
+
+// SecurityProfilesRuleName is the name of the rule enforcing seccomp, AppArmor and SELinux profiles
+const SecurityProfilesRuleName = "securityProfiles"
+
+// SecurityProfilesConfig configures the security profiles containers must run with
+type SecurityProfilesConfig struct {
+	// RequireSeccomp requires the seccomp profile type RuntimeDefault for each container
+	RequireSeccomp bool `json:"requireSeccomp"`
+	// SeccompProfiles lists the accepted seccomp profiles in annotation format, e.g. runtime/default or
+	// localhost/profiles/audit.json. If set, each container needs one of them, RequireSeccomp isn't needed.
+	SeccompProfiles []string `json:"seccompProfiles"`
+	// AppArmorProfiles lists the approved AppArmor profiles in annotation format,
+	// e.g. runtime/default or localhost/my-profile. Empty allows all profiles.
+	AppArmorProfiles []string `json:"appArmorProfiles"`
+	// SELinuxTypes lists the approved SELinux types, e.g. container_t. Empty allows all types.
+	SELinuxTypes []string `json:"seLinuxTypes"`
+}
+
+// securityProfilesRule enforces the seccomp, AppArmor and SELinux profiles of containers
+type securityProfilesRule struct {
+	cfg SecurityProfilesConfig
+	// runtimeClasses detect Windows pods by the node selector of their runtime class
+	runtimeClasses nodev1listers.RuntimeClassLister
+}
+
+func newSecurityProfilesRule(csh *CosignServerHandler, cfg *Config) (Rule, error) {
+	return &securityProfilesRule{
+		cfg:            cfg.SecurityProfiles,
+		runtimeClasses: csh.informers.Node().V1().RuntimeClasses().Lister(),
+	}, nil
+}
+
+// Name returns the name of the rule
+func (*securityProfilesRule) Name() string {
+	return SecurityProfilesRuleName
+}
+
+// Validate checks the effective security profiles of each container of pods and workloads. Seccomp, AppArmor
+// and SELinux don't exist on Windows, so Windows pods are skipped.
+func (r *securityProfilesRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	meta, spec := podTemplate(o)
+	if spec == nil || (o.Pod == nil && !o.validatesTemplates()) {
+		return nil, nil
+	}
+	if podOS(spec, r.runtimeClasses) == corev1.Windows {
+		tracef(ctx, "Windows pod")
+		return nil, nil
+	}
+	psc := spec.SecurityContext
+	if psc == nil {
+		psc = &corev1.PodSecurityContext{}
+	}
+
+	var violations []string
+	for _, c := range podContainers(spec) {
+		sc := c.SecurityContext
+		if sc == nil {
+			sc = &corev1.SecurityContext{}
+		}
+
+		if len(r.cfg.SeccompProfiles) > 0 {
+			profile := seccompProfile(psc, sc)
+			if !slices.Contains(r.cfg.SeccompProfiles, profile) {
+				violations = append(violations, fmt.Sprintf("container %q uses seccomp profile %q, accepted: %s",
+					c.Name, profile, strings.Join(r.cfg.SeccompProfiles, ", ")))
+			}
+		} else if r.cfg.RequireSeccomp && seccompProfile(psc, sc) != seccompRuntimeDefault {
+			violations = append(violations, fmt.Sprintf("container %q must use seccomp profile %s", c.Name, corev1.SeccompProfileTypeRuntimeDefault))
+		}
+
+		if len(r.cfg.AppArmorProfiles) > 0 {
+			profile := appArmorProfile(meta.Annotations, psc, sc, c.Name)
+			if !slices.Contains(r.cfg.AppArmorProfiles, profile) {
+				violations = append(violations, fmt.Sprintf("container %q uses AppArmor profile %q, approved: %s",
+					c.Name, profile, strings.Join(r.cfg.AppArmorProfiles, ", ")))
+			}
+		}
+
+		if len(r.cfg.SELinuxTypes) > 0 {
+			selinux := psc.SELinuxOptions
+			if sc.SELinuxOptions != nil {
+				selinux = sc.SELinuxOptions
+			}
+			if selinux != nil && selinux.Type != "" && !slices.Contains(r.cfg.SELinuxTypes, selinux.Type) {
+				violations = append(violations, fmt.Sprintf("container %q uses SELinux type %q, approved: %s",
+					c.Name, selinux.Type, strings.Join(r.cfg.SELinuxTypes, ", ")))
+			}
+		}
+	}
+
+	return nil, violationsError(violations)
+}
+
+const (
+	// seccompRuntimeDefault is the seccomp profile RuntimeDefault in annotation format
+	seccompRuntimeDefault = "runtime/default"
+	// seccompUnconfined is the effective seccomp profile without profile, the kubelet default
+	seccompUnconfined = "unconfined"
+)
+
+// seccompProfile returns the effective seccomp profile of the container in annotation format. The container profile
+// takes precedence over the pod profile, without any the container runs unconfined.
+func seccompProfile(psc *corev1.PodSecurityContext, sc *corev1.SecurityContext) string {
+	p := psc.SeccompProfile
+	if sc.SeccompProfile != nil {
+		p = sc.SeccompProfile
+	}
+	if p == nil {
+		return seccompUnconfined
+	}
+	switch p.Type {
+	case corev1.SeccompProfileTypeRuntimeDefault:
+		return seccompRuntimeDefault
+	case corev1.SeccompProfileTypeLocalhost:
+		if p.LocalhostProfile != nil {
+			return "localhost/" + *p.LocalhostProfile
+		}
+		return "localhost/"
+	default:
+		return seccompUnconfined
+	}
+}
+
+// appArmorProfile returns the effective AppArmor profile of the container in annotation format.
+// The securityContext field takes precedence over the deprecated annotation.
+// Without any setting, the runtime default is used.
+func appArmorProfile(annotations map[string]string, psc *corev1.PodSecurityContext, sc *corev1.SecurityContext, container string) string {
+	p := psc.AppArmorProfile
+	if sc.AppArmorProfile != nil {
+		p = sc.AppArmorProfile
+	}
+	if p == nil {
+		if a, ok := annotations[corev1.DeprecatedAppArmorBetaContainerAnnotationKeyPrefix+container]; ok {
+			return a
+		}
+		return corev1.DeprecatedAppArmorBetaProfileRuntimeDefault
+	}
+	switch p.Type {
+	case corev1.AppArmorProfileTypeLocalhost:
+		if p.LocalhostProfile != nil {
+			return corev1.DeprecatedAppArmorBetaProfileNamePrefix + *p.LocalhostProfile
+		}
+		return corev1.DeprecatedAppArmorBetaProfileNamePrefix
+	case corev1.AppArmorProfileTypeUnconfined:
+		return corev1.DeprecatedAppArmorBetaProfileNameUnconfined
+	default:
+		return corev1.DeprecatedAppArmorBetaProfileRuntimeDefault
+	}
+}
@@ -0,0 +1,175 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_securityProfilesRule_Validate(t *testing.T) {
+	localhost, containerUser := "my-profile", "ContainerUser"
+	cfg := SecurityProfilesConfig{
+		RequireSeccomp:   true,
+		AppArmorProfiles: []string{"runtime/default", "localhost/my-profile"},
+		SELinuxTypes:     []string{"container_t"},
+	}
+	runtimeDefault := &corev1.PodSecurityContext{
+		SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+	}
+	tests := []struct {
+		name    string
+		spec    corev1.PodSpec
+		wantErr bool
+	}{
+		{
+			name: "pod level seccomp",
+			spec: corev1.PodSpec{
+				SecurityContext: runtimeDefault,
+				Containers:      []corev1.Container{{Name: "app"}},
+			},
+		},
+		{
+			name: "missing seccomp",
+			spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "container overrides seccomp",
+			spec: corev1.PodSpec{
+				SecurityContext: runtimeDefault,
+				Containers: []corev1.Container{{
+					Name: "app",
+					SecurityContext: &corev1.SecurityContext{
+						SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeUnconfined},
+					},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "approved localhost AppArmor profile",
+			spec: corev1.PodSpec{
+				SecurityContext: runtimeDefault,
+				Containers: []corev1.Container{{
+					Name: "app",
+					SecurityContext: &corev1.SecurityContext{
+						AppArmorProfile: &corev1.AppArmorProfile{Type: corev1.AppArmorProfileTypeLocalhost, LocalhostProfile: &localhost},
+					},
+				}},
+			},
+		},
+		{
+			name: "unconfined AppArmor profile",
+			spec: corev1.PodSpec{
+				SecurityContext: &corev1.PodSecurityContext{
+					SeccompProfile:  &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+					AppArmorProfile: &corev1.AppArmorProfile{Type: corev1.AppArmorProfileTypeUnconfined},
+				},
+				Containers: []corev1.Container{{Name: "app"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unapproved SELinux type",
+			spec: corev1.PodSpec{
+				SecurityContext: &corev1.PodSecurityContext{
+					SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+					SELinuxOptions: &corev1.SELinuxOptions{Type: "spc_t"},
+				},
+				Containers: []corev1.Container{{Name: "app"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "windows pod without seccomp",
+			spec: corev1.PodSpec{
+				NodeSelector: map[string]string{corev1.LabelOSStable: "windows"},
+				SecurityContext: &corev1.PodSecurityContext{
+					WindowsOptions: &corev1.WindowsSecurityContextOptions{RunAsUserName: &containerUser},
+				},
+				Containers: []corev1.Container{{Name: "app"}},
+			},
+		},
+		{
+			name: "linux pod without seccomp",
+			spec: corev1.PodSpec{
+				OS:         &corev1.PodOS{Name: corev1.Linux},
+				Containers: []corev1.Container{{Name: "app"}},
+			},
+			wantErr: true,
+		},
+	}
+
+	r := &securityProfilesRule{cfg: cfg}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Validate(context.Background(), podObject("default", tt.spec))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_securityProfilesRule_seccompProfiles(t *testing.T) {
+	audit, other := "profiles/audit.json", "profiles/other.json"
+	r := &securityProfilesRule{cfg: SecurityProfilesConfig{SeccompProfiles: []string{"runtime/default", "localhost/profiles/audit.json"}}}
+	tests := []struct {
+		name    string
+		pod     *corev1.SeccompProfile
+		ctr     *corev1.SeccompProfile
+		wantErr string
+	}{
+		{
+			name: "runtime default",
+			pod:  &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+		},
+		{
+			name: "accepted localhost profile of the container",
+			pod:  &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeUnconfined},
+			ctr:  &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeLocalhost, LocalhostProfile: &audit},
+		},
+		{
+			name:    "other localhost profile",
+			ctr:     &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeLocalhost, LocalhostProfile: &other},
+			wantErr: `container "app" uses seccomp profile "localhost/profiles/other.json", accepted: runtime/default, localhost/profiles/audit.json`,
+		},
+		{
+			name:    "no profile",
+			wantErr: `container "app" uses seccomp profile "unconfined"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := corev1.PodSpec{
+				SecurityContext: &corev1.PodSecurityContext{SeccompProfile: tt.pod},
+				Containers:      []corev1.Container{{Name: "app", SecurityContext: &corev1.SecurityContext{SeccompProfile: tt.ctr}}},
+			}
+			_, err := r.Validate(context.Background(), podObject("default", spec))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_appArmorProfile_annotation(t *testing.T) {
+	o := podObject("default", corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}})
+	o.Pod.Annotations = map[string]string{
+		corev1.DeprecatedAppArmorBetaContainerAnnotationKeyPrefix + "app": "localhost/other",
+	}
+	got := appArmorProfile(o.Pod.Annotations, &corev1.PodSecurityContext{}, &corev1.SecurityContext{}, "app")
+	if got != "localhost/other" {
+		t.Errorf("appArmorProfile() = %q, want %q", got, "localhost/other")
+	}
+}
@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	v1 "k8s.io/api/admission/v1"
+)
+
+const (
+	// ServiceAccountsRuleName is the name of the rule restricting the service accounts of pods
+	ServiceAccountsRuleName = "serviceAccounts"
+	// defaultServiceAccount is used by pods without serviceAccountName
+	defaultServiceAccount = "default"
+)
+
+// ServiceAccountsConfig configures which service accounts pods may run as
+type ServiceAccountsConfig struct {
+	// Allowed lists the service accounts allowed in all namespaces. If empty, all except default are allowed.
+	Allowed []string `json:"allowed"`
+	// Namespaces overrides the allowed service accounts per namespace
+	Namespaces map[string][]string `json:"namespaces"`
+	// ExemptNamespaces may use any service account including default, e.g. kube-system
+	ExemptNamespaces []string `json:"exemptNamespaces"`
+	// Action is either deny (default) or warn
+	Action string `json:"action"`
+}
+
+// serviceAccountsRule pushes teams toward dedicated least-privilege identities, as the default service account is
+// shared by all pods of a namespace and tends to collect permissions
+type serviceAccountsRule struct {
+	cfg ServiceAccountsConfig
+}
+
+func newServiceAccountsRule(_ *CosignServerHandler, cfg *Config) (Rule, error) {
+	c := cfg.ServiceAccounts
+	if err := validateAction(&c.Action, ActionDeny); err != nil {
+		return nil, err
+	}
+	return &serviceAccountsRule{cfg: c}, nil
+}
+
+// Name returns the name of the rule
+func (*serviceAccountsRule) Name() string {
+	return ServiceAccountsRuleName
+}
+
+// Validate checks the service account of pods and workloads
+func (r *serviceAccountsRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	spec := podSpec(o)
+	ns := o.Request.Namespace
+	if spec == nil || o.Request.Operation == v1.Delete {
+		return nil, nil
+	}
+	if slices.Contains(r.cfg.ExemptNamespaces, ns) {
+		tracef(ctx, "namespace %q is exempt", ns)
+		return nil, nil
+	}
+
+	sa := spec.ServiceAccountName
+	if sa == "" {
+		sa = spec.DeprecatedServiceAccount
+	}
+	if sa == "" {
+		sa = defaultServiceAccount
+	}
+	allowed, ok := r.cfg.Namespaces[ns]
+	if ok {
+		tracef(ctx, "service accounts of namespace %q", ns)
+	} else {
+		allowed = r.cfg.Allowed
+	}
+
+	var violations []string
+	switch {
+	case slices.Contains(allowed, sa):
+	case sa == defaultServiceAccount:
+		violations = append(violations, fmt.Sprintf("pods in namespace %q must not use the default service account, set serviceAccountName", ns))
+	case len(allowed) > 0:
+		violations = append(violations, fmt.Sprintf("service account %q is not allowed in namespace %q, allowed: %s",
+			sa, ns, strings.Join(allowed, ", ")))
+	}
+	return enforce(r.cfg.Action, violations)
+}
@@ -0,0 +1,110 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_serviceAccountsRule_Validate(t *testing.T) {
+	sa := func(name string) corev1.PodSpec {
+		return corev1.PodSpec{ServiceAccountName: name, Containers: []corev1.Container{{Name: "app"}}}
+	}
+	tests := []struct {
+		name         string
+		namespace    string
+		spec         corev1.PodSpec
+		cfg          ServiceAccountsConfig
+		wantWarnings int
+		wantErr      string
+	}{
+		{
+			name: "dedicated service account",
+			spec: sa("app"),
+		},
+		{
+			name:    "no service account",
+			spec:    sa(""),
+			wantErr: `pods in namespace "default" must not use the default service account, set serviceAccountName`,
+		},
+		{
+			name:    "explicit default service account",
+			spec:    sa("default"),
+			wantErr: `pods in namespace "default" must not use the default service account, set serviceAccountName`,
+		},
+		{
+			name:    "deprecated service account field",
+			spec:    corev1.PodSpec{DeprecatedServiceAccount: "default", Containers: []corev1.Container{{Name: "app"}}},
+			wantErr: `must not use the default service account`,
+		},
+		{
+			name: "allowed globally",
+			spec: sa("app"),
+			cfg:  ServiceAccountsConfig{Allowed: []string{"app", "worker"}},
+		},
+		{
+			name:    "not allowed globally",
+			spec:    sa("admin"),
+			cfg:     ServiceAccountsConfig{Allowed: []string{"app", "worker"}},
+			wantErr: `service account "admin" is not allowed in namespace "default", allowed: app, worker`,
+		},
+		{
+			name:      "namespace overrides global allowlist",
+			namespace: "team-a",
+			spec:      sa("admin"),
+			cfg:       ServiceAccountsConfig{Allowed: []string{"app"}, Namespaces: map[string][]string{"team-a": {"admin"}}},
+		},
+		{
+			name:      "not allowed in namespace",
+			namespace: "team-a",
+			spec:      sa("app"),
+			cfg:       ServiceAccountsConfig{Allowed: []string{"app"}, Namespaces: map[string][]string{"team-a": {"admin"}}},
+			wantErr:   `service account "app" is not allowed in namespace "team-a", allowed: admin`,
+		},
+		{
+			name:      "default allowed explicitly in namespace",
+			namespace: "legacy",
+			spec:      sa(""),
+			cfg:       ServiceAccountsConfig{Namespaces: map[string][]string{"legacy": {"default"}}},
+		},
+		{
+			name:      "exempt namespace",
+			namespace: "kube-system",
+			spec:      sa(""),
+			cfg:       ServiceAccountsConfig{ExemptNamespaces: []string{"kube-system"}},
+		},
+		{
+			name:         "warn",
+			spec:         sa(""),
+			cfg:          ServiceAccountsConfig{Action: ActionWarn},
+			wantWarnings: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newServiceAccountsRule(&CosignServerHandler{}, &Config{ServiceAccounts: tt.cfg})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ns := tt.namespace
+			if ns == "" {
+				ns = "default"
+			}
+			warnings, err := r.Validate(context.Background(), podObject(ns, tt.spec))
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("Validate() warnings = %v, want %d", warnings, tt.wantWarnings)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ServiceAccountTokensRuleName is the name of the rule restricting projected service account tokens
+	ServiceAccountTokensRuleName = "serviceAccountTokens"
+	// defaultTokenExpiration is the expiration of projected tokens without expirationSeconds
+	defaultTokenExpiration = time.Hour
+)
+
+// ServiceAccountTokensConfig configures the audiences and expirations of projected service account tokens
+type ServiceAccountTokensConfig struct {
+	// Audiences tokens may be requested for, e.g. vault or sts.amazonaws.com. Tokens for the API server,
+	// without audience, are always allowed.
+	Audiences []string `json:"audiences"`
+	// MaxExpiration of tokens, e.g. 24h. Tokens without expirationSeconds expire after 1h. 0 for no limit.
+	MaxExpiration metav1.Duration `json:"maxExpiration"`
+	// Action is either deny (default) or warn
+	Action string `json:"action"`
+}
+
+// serviceAccountTokensRule keeps pods from requesting tokens for unexpected audiences, which could be used
+// to impersonate the workload at other services
+type serviceAccountTokensRule struct {
+	cfg ServiceAccountTokensConfig
+}
+
+func newServiceAccountTokensRule(_ *CosignServerHandler, cfg *Config) (Rule, error) {
+	c := cfg.ServiceAccountTokens
+	if err := validateAction(&c.Action, ActionDeny); err != nil {
+		return nil, err
+	}
+	return &serviceAccountTokensRule{cfg: c}, nil
+}
+
+// Name returns the name of the rule
+func (*serviceAccountTokensRule) Name() string {
+	return ServiceAccountTokensRuleName
+}
+
+// Validate checks the audience and expiration of the service account tokens projected into volumes
+// of pods and workloads
+func (r *serviceAccountTokensRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	spec := podSpec(o)
+	if spec == nil || o.Request.Operation == v1.Delete {
+		return nil, nil
+	}
+
+	var violations []string
+	for i := range spec.Volumes {
+		vol := &spec.Volumes[i]
+		if vol.Projected == nil {
+			continue
+		}
+		for _, src := range vol.Projected.Sources {
+			token := src.ServiceAccountToken
+			if token == nil {
+				continue
+			}
+			if token.Audience != "" && !slices.Contains(r.cfg.Audiences, token.Audience) {
+				allowed := "only the API server audience is allowed"
+				if len(r.cfg.Audiences) > 0 {
+					allowed = "allowed: " + strings.Join(r.cfg.Audiences, ", ")
+				}
+				violations = append(violations, fmt.Sprintf("volume %q requests a service account token for audience %q, %s",
+					vol.Name, token.Audience, allowed))
+			}
+			expiration := defaultTokenExpiration
+			if token.ExpirationSeconds != nil {
+				expiration = time.Duration(*token.ExpirationSeconds) * time.Second
+			}
+			tracef(ctx, "volume %q requests a token for audience %q valid for %s", vol.Name, token.Audience, expiration)
+			if maxExpiration := r.cfg.MaxExpiration.Duration; maxExpiration > 0 && expiration > maxExpiration {
+				violations = append(violations, fmt.Sprintf("volume %q requests a service account token valid for %s, the maximum is %s",
+					vol.Name, expiration, maxExpiration))
+			}
+		}
+	}
+	return enforce(r.cfg.Action, violations)
+}
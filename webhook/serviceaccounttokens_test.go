@@ -0,0 +1,88 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_serviceAccountTokensRule_Validate(t *testing.T) {
+	day, week := int64(24*60*60), int64(7*24*60*60)
+	tests := []struct {
+		name         string
+		cfg          ServiceAccountTokensConfig
+		token        corev1.ServiceAccountTokenProjection
+		wantWarnings int
+		wantErr      bool
+	}{
+		{
+			name:  "API server audience",
+			token: corev1.ServiceAccountTokenProjection{Path: "token"},
+		},
+		{
+			name:  "allowed audience",
+			cfg:   ServiceAccountTokensConfig{Audiences: []string{"vault"}},
+			token: corev1.ServiceAccountTokenProjection{Path: "token", Audience: "vault"},
+		},
+		{
+			name:    "audience without allowlist",
+			token:   corev1.ServiceAccountTokenProjection{Path: "token", Audience: "vault"},
+			wantErr: true,
+		},
+		{
+			name:    "unexpected audience",
+			cfg:     ServiceAccountTokensConfig{Audiences: []string{"vault"}},
+			token:   corev1.ServiceAccountTokenProjection{Path: "token", Audience: "https://attacker.example.com"},
+			wantErr: true,
+		},
+		{
+			name:         "unexpected audience warns",
+			cfg:          ServiceAccountTokensConfig{Action: ActionWarn},
+			token:        corev1.ServiceAccountTokenProjection{Path: "token", Audience: "vault"},
+			wantWarnings: 1,
+		},
+		{
+			name:  "expiration within maximum",
+			cfg:   ServiceAccountTokensConfig{MaxExpiration: metav1.Duration{Duration: 24 * time.Hour}},
+			token: corev1.ServiceAccountTokenProjection{Path: "token", ExpirationSeconds: &day},
+		},
+		{
+			name:  "default expiration within maximum",
+			cfg:   ServiceAccountTokensConfig{MaxExpiration: metav1.Duration{Duration: 24 * time.Hour}},
+			token: corev1.ServiceAccountTokenProjection{Path: "token"},
+		},
+		{
+			name:    "expiration over maximum",
+			cfg:     ServiceAccountTokensConfig{MaxExpiration: metav1.Duration{Duration: 24 * time.Hour}},
+			token:   corev1.ServiceAccountTokenProjection{Path: "token", ExpirationSeconds: &week},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newServiceAccountTokensRule(nil, &Config{ServiceAccountTokens: tt.cfg})
+			if err != nil {
+				t.Fatal(err)
+			}
+			warnings, err := r.Validate(context.Background(), podObject("default", corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Image: "app"}},
+				Volumes: []corev1.Volume{{
+					Name: "token",
+					VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{
+						Sources: []corev1.VolumeProjection{{ServiceAccountToken: &tt.token}},
+					}},
+				}},
+			}))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("Validate() warnings = %v, want %d", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ServicePortsRuleName is the name of the rule validating service ports
+const ServicePortsRuleName = "servicePorts"
+
+// ServicePortsConfig configures the port policy of services, with overrides per namespace
+type ServicePortsConfig struct {
+	ServicePortPolicy `json:",inline"`
+	// Namespaces replace the default policy per namespace
+	Namespaces map[string]ServicePortPolicy `json:"namespaces"`
+}
+
+// ServicePortPolicy restricts the type and ports of services
+type ServicePortPolicy struct {
+	// AllowNodePort allows services of type NodePort. Node ports of LoadBalancer services aren't checked, as the
+	// apiserver allocates them unless spec.allocateLoadBalancerNodePorts is false.
+	AllowNodePort bool `json:"allowNodePort"`
+	// DenyLoadBalancer denies services of type LoadBalancer, e.g. in clusters where each one costs a cloud load balancer
+	DenyLoadBalancer bool `json:"denyLoadBalancer"`
+	// PortRanges are the allowed ranges of service ports, empty allows all ports
+	PortRanges []PortRange `json:"portRanges"`
+	// NamePattern is a regular expression each port name has to match completely, e.g. (http|grpc|tcp)(-.+)?.
+	// If set, all ports have to be named.
+	NamePattern string `json:"namePattern"`
+}
+
+// PortRange is a range of ports including its bounds
+type PortRange struct {
+	Min int32 `json:"min"`
+	Max int32 `json:"max"`
+}
+
+// servicePortPolicy is the compiled form of a ServicePortPolicy
+type servicePortPolicy struct {
+	ServicePortPolicy
+	namePattern *regexp.Regexp
+}
+
+// servicePortsRule validates the type and ports of services
+type servicePortsRule struct {
+	def        servicePortPolicy
+	namespaces map[string]servicePortPolicy
+}
+
+func newServicePortsRule(_ *CosignServerHandler, cfg *Config) (Rule, error) {
+	def, err := compileServicePortPolicy(cfg.ServicePorts.ServicePortPolicy)
+	if err != nil {
+		return nil, err
+	}
+	r := &servicePortsRule{def: def, namespaces: map[string]servicePortPolicy{}}
+	for ns, p := range cfg.ServicePorts.Namespaces {
+		if r.namespaces[ns], err = compileServicePortPolicy(p); err != nil {
+			return nil, fmt.Errorf("namespace %q: %w", ns, err)
+		}
+	}
+	return r, nil
+}
+
+// compileServicePortPolicy compiles the name pattern of the policy
+func compileServicePortPolicy(p ServicePortPolicy) (servicePortPolicy, error) {
+	sp := servicePortPolicy{ServicePortPolicy: p}
+	if p.NamePattern != "" {
+		res, err := compilePatterns([]string{p.NamePattern})
+		if err != nil {
+			return sp, err
+		}
+		sp.namePattern = res[0]
+	}
+	return sp, nil
+}
+
+// Name returns the name of the rule
+func (*servicePortsRule) Name() string {
+	return ServicePortsRuleName
+}
+
+// Validate checks the service against the port policy of its namespace
+func (r *servicePortsRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	svc := o.Service
+	if svc == nil {
+		return nil, nil
+	}
+	p, ok := r.namespaces[o.Request.Namespace]
+	if ok {
+		tracef(ctx, "policy of namespace %q", o.Request.Namespace)
+	} else {
+		p = r.def
+	}
+
+	var violations []string
+	switch {
+	case svc.Spec.Type == corev1.ServiceTypeNodePort && !p.AllowNodePort:
+		violations = append(violations, fmt.Sprintf("services of type NodePort are not allowed in namespace %q", o.Request.Namespace))
+	case svc.Spec.Type == corev1.ServiceTypeLoadBalancer && p.DenyLoadBalancer:
+		violations = append(violations, fmt.Sprintf("services of type LoadBalancer are not allowed in namespace %q", o.Request.Namespace))
+	}
+	for _, port := range svc.Spec.Ports {
+		if len(p.PortRanges) > 0 && !inPortRanges(p.PortRanges, port.Port) {
+			violations = append(violations, fmt.Sprintf("port %d is outside of the allowed ranges %v", port.Port, p.PortRanges))
+		}
+		if p.namePattern != nil && !p.namePattern.MatchString(port.Name) {
+			violations = append(violations, fmt.Sprintf("name %q of port %d doesn't match pattern %q", port.Name, port.Port, p.NamePattern))
+		}
+	}
+	return nil, violationsError(violations)
+}
+
+// inPortRanges returns true if the port is in one of the ranges
+func inPortRanges(ranges []PortRange, port int32) bool {
+	for _, r := range ranges {
+		if port >= r.Min && port <= r.Max {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the range in the format min-max
+func (r PortRange) String() string {
+	return fmt.Sprintf("%d-%d", r.Min, r.Max)
+}
@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// serviceObject returns an admission object for a service with passed spec in passed namespace
+func serviceObject(ns string, spec corev1.ServiceSpec) *Object {
+	return &Object{
+		Request: &v1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Service"},
+			Namespace: ns,
+			Name:      "test",
+		},
+		Meta: metav1.ObjectMeta{Name: "test", Namespace: ns},
+		Service: &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: ns},
+			Spec:       spec,
+		},
+	}
+}
+
+func Test_servicePortsRule_Validate(t *testing.T) {
+	cfg := &Config{ServicePorts: ServicePortsConfig{
+		ServicePortPolicy: ServicePortPolicy{
+			PortRanges:  []PortRange{{Min: 80, Max: 80}, {Min: 8000, Max: 8999}},
+			NamePattern: "(http|grpc)(-.+)?",
+		},
+		Namespaces: map[string]ServicePortPolicy{
+			"ingress": {AllowNodePort: true},
+			"edge":    {AllowNodePort: true, DenyLoadBalancer: true},
+		},
+	}}
+	tests := []struct {
+		name    string
+		ns      string
+		spec    corev1.ServiceSpec
+		wantErr bool
+	}{
+		{
+			name: "valid service",
+			ns:   "default",
+			spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80}, {Name: "grpc-api", Port: 8080}}},
+		},
+		{
+			name:    "port out of range",
+			ns:      "default",
+			spec:    corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 443}}},
+			wantErr: true,
+		},
+		{
+			name:    "unnamed port",
+			ns:      "default",
+			spec:    corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80}}},
+			wantErr: true,
+		},
+		{
+			name:    "node port",
+			ns:      "default",
+			spec:    corev1.ServiceSpec{Type: corev1.ServiceTypeNodePort, Ports: []corev1.ServicePort{{Name: "http", Port: 80}}},
+			wantErr: true,
+		},
+		{
+			name: "node port allowed in namespace",
+			ns:   "ingress",
+			spec: corev1.ServiceSpec{Type: corev1.ServiceTypeNodePort, Ports: []corev1.ServicePort{{Port: 443, NodePort: 30443}}},
+		},
+		{
+			name: "load balancer with allocated node port allowed by default",
+			ns:   "default",
+			spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer, Ports: []corev1.ServicePort{{Name: "http", Port: 80, NodePort: 30080}}},
+		},
+		{
+			name:    "load balancer denied in namespace",
+			ns:      "edge",
+			spec:    corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer, Ports: []corev1.ServicePort{{Port: 443}}},
+			wantErr: true,
+		},
+	}
+
+	r, err := newServicePortsRule(nil, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Validate(context.Background(), serviceObject(tt.ns, tt.spec))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,474 @@
+package webhook
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SemanticsVersion is the version of the decision semantics. It's bumped when the verdicts for the same request
+// and config change, and recorded in each decision. The previous version can be kept for one release with the
+// semantics setting.
+//
+//	1: without config file, only the cosign rule runs on /validate
+//	2: without config file, the embedded defaults also run the sanity and deprecation rules
+//	3: pod rules validate the pod templates of StatefulSets and DaemonSets, and the rules env, priorityClass,
+//	   probes, runtimeClass and securityProfiles those of Deployments, too
+const SemanticsVersion = 3
+
+// sources of effective settings, from lowest to highest precedence
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// names of the flags and env vars of the settings
+const (
+	ConfigFlag      = "config"
+	ConfigEnv       = "COSIGNWEBHOOK_CONFIG"
+	TLSCertFileFlag = "tlsCertFile"
+	TLSKeyFileFlag  = "tlsKeyFile"
+	LogLevelFlag    = "logLevel"
+	SemanticsFlag   = "semantics"
+	// ShadowSemanticsFlag names the semantics version evaluated in shadow mode next to the enforcing one
+	ShadowSemanticsFlag = "shadowSemantics"
+	CrashReportDirFlag  = "crashReportDir"
+	CrashReportDirEnv   = "COSIGNWEBHOOK_CRASH_REPORT_DIR"
+	TargetInflightFlag  = "targetInflight"
+	// MaxInflightFlag limits the admission requests in flight, OverflowFlag sets what happens to the ones over it
+	MaxInflightFlag      = "maxInflight"
+	OverflowFlag         = "overflow"
+	OverflowDeadlineFlag = "overflowDeadline"
+	// UnparseableFlag sets what happens to admission requests with objects the webhook can't decode
+	UnparseableFlag = "unparseable"
+	// OnErrorFlag sets what happens to admission requests if a rule fails internally, OnErrorOverridesFlag
+	// overrides it by rule
+	OnErrorFlag          = "onError"
+	OnErrorOverridesFlag = "onErrorOverrides"
+	// WebhookConfigurationFlag names the ValidatingWebhookConfiguration the serving certificate is verified against
+	WebhookConfigurationFlag = "webhookConfiguration"
+	// ExemptNamespacesFlag lists the namespaces admitted without evaluating any rule
+	ExemptNamespacesFlag = "exemptNamespaces"
+	// ProxyFlag, NoProxyFlag and ProxyOverridesFlag route the external calls, CABundleFlag adds trusted CAs
+	ProxyFlag          = "proxy"
+	NoProxyFlag        = "noProxy"
+	ProxyOverridesFlag = "proxyOverrides"
+	CABundleFlag       = "caBundle"
+)
+
+// ProxyDirect is the proxy override for destinations reached without proxy
+const ProxyDirect = "direct"
+
+// behaviors for admission requests over the maximum in flight
+const (
+	// OverflowQueue waits up to the overflow deadline for a free slot and rejects the request with 429 after it
+	OverflowQueue = "queue"
+	// OverflowAllow admits the request without validation and with a warning
+	OverflowAllow = "allow"
+	// OverflowDeny denies the request
+	OverflowDeny = "deny"
+)
+
+// behaviors for admission requests with objects the webhook can't decode, e.g. with malformed quantities
+const (
+	// UnparseableAllow admits the request without validation and with a warning
+	UnparseableAllow = "allow"
+	// UnparseableDeny denies the request
+	UnparseableDeny = "deny"
+)
+
+// behaviors for admission requests if a rule fails with an internal error, e.g. an external call failed
+const (
+	// OnErrorDeny denies the request by the failed rule
+	OnErrorDeny = "deny"
+	// OnErrorAllow admits the request without evaluating the remaining rules and with a warning
+	OnErrorAllow = "allow"
+	// OnErrorIgnoreRule skips the failed rule with a warning and evaluates the remaining rules
+	OnErrorIgnoreRule = "ignore-rule"
+)
+
+// ServerConfig are the settings of the webhook server. They are layered: defaults < config file < env < flags.
+type ServerConfig struct {
+	// TLSCertFile contains the x509 certificate for HTTPS
+	TLSCertFile string `json:"tlsCertFile"`
+	// TLSKeyFile contains the x509 private key to the certificate
+	TLSKeyFile string `json:"tlsKeyFile"`
+	// LogLevel of the webhook, e.g. info, debug, warn, error, fatal
+	LogLevel string `json:"logLevel"`
+	// Semantics is the decision semantics version, the current or the previous one
+	Semantics string `json:"semantics"`
+	// ShadowSemantics is the semantics version evaluated in shadow mode, without effect on the verdicts.
+	// Empty disables the shadow mode.
+	ShadowSemantics string `json:"shadowSemantics"`
+	// CrashReportDir is the directory crash reports are written to on fatal errors, e.g. a mounted volume.
+	// Empty disables crash reports.
+	CrashReportDir string `json:"crashReportDir"`
+	// TargetInflight is the number of admission requests in flight at which the replica reports not ready,
+	// so the Service sheds load to other replicas. Empty or 0 keeps the replica ready under load.
+	TargetInflight string `json:"targetInflight"`
+	// MaxInflight is the number of admission requests evaluated at the same time, empty or 0 for no limit
+	MaxInflight string `json:"maxInflight"`
+	// Overflow is the behavior for admission requests over MaxInflight: queue, allow or deny
+	Overflow string `json:"overflow"`
+	// OverflowDeadline is how long a request waits for a free slot with overflow queue, e.g. 2s
+	OverflowDeadline string `json:"overflowDeadline"`
+	// Unparseable is the behavior for admission requests with objects the webhook can't decode: allow or deny
+	Unparseable string `json:"unparseable"`
+	// OnError is the behavior for admission requests if a rule fails with an internal error: deny, allow or
+	// ignore-rule
+	OnError string `json:"onError"`
+	// OnErrorOverrides is a comma separated list of rule=behavior, overriding OnError by rule
+	OnErrorOverrides string `json:"onErrorOverrides"`
+	// WebhookConfiguration is the name of the ValidatingWebhookConfiguration registering the webhook. On boot the
+	// serving certificate is verified against its webhooks. Empty disables the check.
+	WebhookConfiguration string `json:"webhookConfiguration"`
+	// ExemptNamespaces is a comma separated list of namespaces which are never denied, e.g. kube-system.
+	// Their admission requests are admitted before any rule is evaluated.
+	ExemptNamespaces string `json:"exemptNamespaces"`
+	// Proxy is the URL of the proxy for external calls to registries, downstream validators, blocklist sources,
+	// telemetry and publishers. Empty uses HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
+	Proxy string `json:"proxy"`
+	// NoProxy is a comma separated list of hosts, domains and CIDRs reached without Proxy, like NO_PROXY
+	NoProxy string `json:"noProxy"`
+	// ProxyOverrides is a comma separated list of host=proxy URL, or host=direct, overriding the proxy by destination
+	ProxyOverrides string `json:"proxyOverrides"`
+	// CABundle is a PEM file of CAs trusted for external calls in addition to the system CAs
+	CABundle string `json:"caBundle"`
+}
+
+// DefaultServerConfig returns the server settings used if not set otherwise
+func DefaultServerConfig() ServerConfig {
+	return ServerConfig{
+		TLSCertFile: "/etc/certs/tls.crt",
+		TLSKeyFile:  "/etc/certs/tls.key",
+		LogLevel:    "info",
+		Semantics:   strconv.Itoa(SemanticsVersion),
+
+		Overflow:         OverflowQueue,
+		OverflowDeadline: "2s",
+		Unparseable:      UnparseableDeny,
+		OnError:          OnErrorDeny,
+	}
+}
+
+// SemanticsVersion returns the decision semantics version, the current one if not set
+func (s ServerConfig) SemanticsVersion() (int, error) {
+	if s.Semantics == "" {
+		return SemanticsVersion, nil
+	}
+	return parseSemantics(s.Semantics)
+}
+
+// TargetInflightRequests returns the target of admission requests in flight, 0 if not set
+func (s ServerConfig) TargetInflightRequests() (int, error) {
+	if s.TargetInflight == "" {
+		return 0, nil
+	}
+	v, err := strconv.Atoi(s.TargetInflight)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid target of requests in flight %q, must be a positive number", s.TargetInflight)
+	}
+	return v, nil
+}
+
+// MaxInflightRequests returns the maximum of admission requests in flight, 0 if not set
+func (s ServerConfig) MaxInflightRequests() (int, error) {
+	if s.MaxInflight == "" {
+		return 0, nil
+	}
+	v, err := strconv.Atoi(s.MaxInflight)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid maximum of requests in flight %q, must be a positive number", s.MaxInflight)
+	}
+	return v, nil
+}
+
+// OverflowPolicy returns the behavior and the deadline for admission requests over the maximum in flight
+func (s ServerConfig) OverflowPolicy() (string, time.Duration, error) {
+	if !slices.Contains([]string{OverflowQueue, OverflowAllow, OverflowDeny}, s.Overflow) {
+		return "", 0, fmt.Errorf("invalid overflow %q, must be %s, %s or %s", s.Overflow, OverflowQueue, OverflowAllow, OverflowDeny)
+	}
+	deadline, err := time.ParseDuration(s.OverflowDeadline)
+	if err != nil || deadline <= 0 {
+		return "", 0, fmt.Errorf("invalid overflow deadline %q, must be a positive duration like 2s", s.OverflowDeadline)
+	}
+	return s.Overflow, deadline, nil
+}
+
+// UnparseablePolicy returns the behavior for admission requests with objects the webhook can't decode
+func (s ServerConfig) UnparseablePolicy() (string, error) {
+	if !slices.Contains([]string{UnparseableAllow, UnparseableDeny}, s.Unparseable) {
+		return "", fmt.Errorf("invalid unparseable %q, must be %s or %s", s.Unparseable, UnparseableAllow, UnparseableDeny)
+	}
+	return s.Unparseable, nil
+}
+
+// OnErrorPolicies returns the behavior for admission requests if a rule fails with an internal error, and the
+// behaviors overriding it by rule
+func (s ServerConfig) OnErrorPolicies() (string, map[string]string, error) {
+	behaviors := []string{OnErrorDeny, OnErrorAllow, OnErrorIgnoreRule}
+	if !slices.Contains(behaviors, s.OnError) {
+		return "", nil, fmt.Errorf("invalid onError %q, must be %s, %s or %s", s.OnError, OnErrorDeny, OnErrorAllow, OnErrorIgnoreRule)
+	}
+	overrides := map[string]string{}
+	for _, o := range strings.Split(s.OnErrorOverrides, ",") {
+		if o = strings.TrimSpace(o); o == "" {
+			continue
+		}
+		rule, behavior, ok := strings.Cut(o, "=")
+		if !ok || !slices.Contains(RuleNames(), rule) || !slices.Contains(behaviors, behavior) {
+			return "", nil, fmt.Errorf("invalid onError override %q, must be rule=%s, rule=%s or rule=%s", o, OnErrorDeny, OnErrorAllow, OnErrorIgnoreRule)
+		}
+		overrides[rule] = behavior
+	}
+	return s.OnError, overrides, nil
+}
+
+// ExemptNamespaceList returns the exempt namespaces, nil if not set
+func (s ServerConfig) ExemptNamespaceList() []string {
+	var namespaces []string
+	for _, ns := range strings.Split(s.ExemptNamespaces, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
+// ProxyURL returns the proxy for external calls, nil if not set
+func (s ServerConfig) ProxyURL() (*url.URL, error) {
+	if s.Proxy == "" {
+		return nil, nil
+	}
+	return parseProxy(s.Proxy)
+}
+
+// ProxyOverrideMap returns the proxies by destination host, nil for hosts reached without proxy
+func (s ServerConfig) ProxyOverrideMap() (map[string]*url.URL, error) {
+	overrides := map[string]*url.URL{}
+	for _, o := range strings.Split(s.ProxyOverrides, ",") {
+		if o = strings.TrimSpace(o); o == "" {
+			continue
+		}
+		host, proxy, ok := strings.Cut(o, "=")
+		if !ok || host == "" {
+			return nil, fmt.Errorf("invalid proxy override %q, must be host=proxy URL or host=%s", o, ProxyDirect)
+		}
+		if proxy == ProxyDirect {
+			overrides[host] = nil
+			continue
+		}
+		u, err := parseProxy(proxy)
+		if err != nil {
+			return nil, err
+		}
+		overrides[host] = u
+	}
+	return overrides, nil
+}
+
+// CAPool returns the system CAs with the CA bundle, nil if no CA bundle is set
+func (s ServerConfig) CAPool() (*x509.CertPool, error) {
+	if s.CABundle == "" {
+		return nil, nil
+	}
+	pem, err := os.ReadFile(s.CABundle)
+	if err != nil {
+		return nil, fmt.Errorf("can't read CA bundle: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %q", s.CABundle)
+	}
+	return pool, nil
+}
+
+// parseProxy parses a proxy URL, which needs a scheme and host
+func parseProxy(proxy string) (*url.URL, error) {
+	u, err := url.Parse(proxy)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %q, must be a URL like http://proxy.example.com:3128", proxy)
+	}
+	return u, nil
+}
+
+// parseSemantics parses a semantics version, which must be the current or the previous one
+func parseSemantics(s string) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil || (v != SemanticsVersion && v != SemanticsVersion-1) {
+		return 0, fmt.Errorf("unsupported semantics version %q, must be %d or %d", s, SemanticsVersion, SemanticsVersion-1)
+	}
+	return v, nil
+}
+
+// Setting is an effective setting and the layer its value came from
+type Setting struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	// Env is the env var of the setting
+	Env string `json:"env"`
+}
+
+// serverSetting is a layered server setting with its flag and env var
+type serverSetting struct {
+	flag  string
+	env   string
+	field func(s *ServerConfig) *string
+}
+
+// serverSettings are the layered server settings
+var serverSettings = []serverSetting{
+	{TLSCertFileFlag, "COSIGNWEBHOOK_TLS_CERT_FILE", func(s *ServerConfig) *string { return &s.TLSCertFile }},
+	{TLSKeyFileFlag, "COSIGNWEBHOOK_TLS_KEY_FILE", func(s *ServerConfig) *string { return &s.TLSKeyFile }},
+	{LogLevelFlag, "COSIGNWEBHOOK_LOG_LEVEL", func(s *ServerConfig) *string { return &s.LogLevel }},
+	{SemanticsFlag, "COSIGNWEBHOOK_SEMANTICS", func(s *ServerConfig) *string { return &s.Semantics }},
+	{ShadowSemanticsFlag, "COSIGNWEBHOOK_SHADOW_SEMANTICS", func(s *ServerConfig) *string { return &s.ShadowSemantics }},
+	{CrashReportDirFlag, CrashReportDirEnv, func(s *ServerConfig) *string { return &s.CrashReportDir }},
+	{TargetInflightFlag, "COSIGNWEBHOOK_TARGET_INFLIGHT", func(s *ServerConfig) *string { return &s.TargetInflight }},
+	{MaxInflightFlag, "COSIGNWEBHOOK_MAX_INFLIGHT", func(s *ServerConfig) *string { return &s.MaxInflight }},
+	{OverflowFlag, "COSIGNWEBHOOK_OVERFLOW", func(s *ServerConfig) *string { return &s.Overflow }},
+	{OverflowDeadlineFlag, "COSIGNWEBHOOK_OVERFLOW_DEADLINE", func(s *ServerConfig) *string { return &s.OverflowDeadline }},
+	{UnparseableFlag, "COSIGNWEBHOOK_UNPARSEABLE", func(s *ServerConfig) *string { return &s.Unparseable }},
+	{OnErrorFlag, "COSIGNWEBHOOK_ON_ERROR", func(s *ServerConfig) *string { return &s.OnError }},
+	{OnErrorOverridesFlag, "COSIGNWEBHOOK_ON_ERROR_OVERRIDES", func(s *ServerConfig) *string { return &s.OnErrorOverrides }},
+	{WebhookConfigurationFlag, "COSIGNWEBHOOK_WEBHOOK_CONFIGURATION", func(s *ServerConfig) *string { return &s.WebhookConfiguration }},
+	{ExemptNamespacesFlag, "COSIGNWEBHOOK_EXEMPT_NAMESPACES", func(s *ServerConfig) *string { return &s.ExemptNamespaces }},
+	{ProxyFlag, "COSIGNWEBHOOK_PROXY", func(s *ServerConfig) *string { return &s.Proxy }},
+	{NoProxyFlag, "COSIGNWEBHOOK_NO_PROXY", func(s *ServerConfig) *string { return &s.NoProxy }},
+	{ProxyOverridesFlag, "COSIGNWEBHOOK_PROXY_OVERRIDES", func(s *ServerConfig) *string { return &s.ProxyOverrides }},
+	{CABundleFlag, "COSIGNWEBHOOK_CA_BUNDLE", func(s *ServerConfig) *string { return &s.CABundle }},
+}
+
+// LoadEffectiveConfig loads the config file named by flag or env, or the embedded defaults without file,
+// and layers the server settings of defaults, config file, env and flags. flags contains the flags set explicitly
+// by name, getenv looks up env vars. It returns the effective config and where each setting came from.
+func LoadEffectiveConfig(flags map[string]string, getenv func(string) string) (*Config, []Setting, error) {
+	path := Setting{Name: ConfigFlag, Value: "", Source: SourceDefault, Env: ConfigEnv}
+	if v := getenv(ConfigEnv); v != "" {
+		path.Value, path.Source = v, SourceEnv
+	}
+	if v, ok := flags[ConfigFlag]; ok {
+		path.Value, path.Source = v, SourceFlag
+	}
+
+	cfg := DefaultConfig()
+	if path.Value != "" {
+		c, err := LoadConfig(path.Value)
+		if err != nil {
+			return nil, nil, err
+		}
+		cfg = c
+	}
+	settings := LayerServerSettings(cfg, flags, getenv)
+	if err := cfg.validateServer(); err != nil {
+		return nil, nil, err
+	}
+	return cfg, append([]Setting{path}, settings...), nil
+}
+
+// ParseServerArgs returns the server flags set in the args of the webhook, e.g. of the container of its
+// Deployment, by name. Flags are given as -name value, -name=value or with two dashes; other flags are skipped.
+func ParseServerArgs(args []string) map[string]string {
+	flags := map[string]string{}
+	for i := 0; i < len(args); i++ {
+		name, ok := strings.CutPrefix(args[i], "-")
+		if !ok {
+			continue
+		}
+		name = strings.TrimPrefix(name, "-")
+		name, value, inline := strings.Cut(name, "=")
+		if name != ConfigFlag && !slices.ContainsFunc(serverSettings, func(s serverSetting) bool { return s.flag == name }) {
+			continue
+		}
+		if !inline {
+			if i+1 == len(args) {
+				break
+			}
+			i++
+			value = args[i]
+		}
+		flags[name] = value
+	}
+	return flags
+}
+
+// LayerServerSettings layers the server settings of defaults, the config, env and flags into the config.
+// flags contains the flags set explicitly by name, getenv looks up env vars. It returns where each setting came
+// from. The settings aren't validated, as files they name may only exist where the webhook runs.
+func LayerServerSettings(cfg *Config, flags map[string]string, getenv func(string) string) []Setting {
+	file := cfg.Server
+	cfg.Server = DefaultServerConfig()
+	settings := make([]Setting, 0, len(serverSettings))
+	for _, s := range serverSettings {
+		value, source := s.field(&cfg.Server), SourceDefault
+		if v := *s.field(&file); v != "" {
+			*value, source = v, SourceFile
+		}
+		if v := getenv(s.env); v != "" {
+			*value, source = v, SourceEnv
+		}
+		if v, ok := flags[s.flag]; ok {
+			*value, source = v, SourceFlag
+		}
+		settings = append(settings, Setting{Name: s.flag, Value: *value, Source: source, Env: s.env})
+	}
+	return settings
+}
+
+// validateServer validates the layered server settings and derives the shadow config from them
+func (cfg *Config) validateServer() error {
+	semantics, err := cfg.Server.SemanticsVersion()
+	if err != nil {
+		return err
+	}
+	if _, err := cfg.Server.TargetInflightRequests(); err != nil {
+		return err
+	}
+	if _, err := cfg.Server.MaxInflightRequests(); err != nil {
+		return err
+	}
+	if _, _, err := cfg.Server.OverflowPolicy(); err != nil {
+		return err
+	}
+	if _, err := cfg.Server.UnparseablePolicy(); err != nil {
+		return err
+	}
+	if _, _, err := cfg.Server.OnErrorPolicies(); err != nil {
+		return err
+	}
+	if _, err := cfg.Server.ProxyURL(); err != nil {
+		return err
+	}
+	if _, err := cfg.Server.ProxyOverrideMap(); err != nil {
+		return err
+	}
+	if _, err := cfg.Server.CAPool(); err != nil {
+		return err
+	}
+	if cfg.Server.ShadowSemantics != "" {
+		shadow, err := parseSemantics(cfg.Server.ShadowSemantics)
+		if err != nil {
+			return fmt.Errorf("shadow: %w", err)
+		}
+		if shadow == semantics {
+			return fmt.Errorf("shadow semantics version %d is the enforcing one", shadow)
+		}
+		// the rules decide with the semantics version they are evaluated with, see Object. The shadow has no
+		// effects, only the enforcing rules delete expired workloads.
+		shadowCfg := *cfg
+		shadowCfg.TTL.Sweep = false
+		cfg.shadow, cfg.shadowSemantics = &shadowCfg, shadow
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package webhook
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLoadEffectiveConfig(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	data := "server:\n  tlsCertFile: /file/tls.crt\n  tlsKeyFile: /file/tls.key\n  logLevel: warn\n"
+	if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{
+		ConfigEnv:                    file,
+		"COSIGNWEBHOOK_TLS_KEY_FILE": "/env/tls.key",
+		"COSIGNWEBHOOK_LOG_LEVEL":    "error",
+	}
+	flags := map[string]string{LogLevelFlag: "debug"}
+
+	cfg, settings, err := LoadEffectiveConfig(flags, func(k string) string { return env[k] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]Setting{
+		ConfigFlag:               {Value: file, Source: SourceEnv},
+		TLSCertFileFlag:          {Value: "/file/tls.crt", Source: SourceFile},
+		TLSKeyFileFlag:           {Value: "/env/tls.key", Source: SourceEnv},
+		LogLevelFlag:             {Value: "debug", Source: SourceFlag},
+		SemanticsFlag:            {Value: "3", Source: SourceDefault},
+		ShadowSemanticsFlag:      {Source: SourceDefault},
+		CrashReportDirFlag:       {Source: SourceDefault},
+		TargetInflightFlag:       {Source: SourceDefault},
+		MaxInflightFlag:          {Source: SourceDefault},
+		OverflowFlag:             {Value: OverflowQueue, Source: SourceDefault},
+		OverflowDeadlineFlag:     {Value: "2s", Source: SourceDefault},
+		UnparseableFlag:          {Value: UnparseableDeny, Source: SourceDefault},
+		OnErrorFlag:              {Value: OnErrorDeny, Source: SourceDefault},
+		OnErrorOverridesFlag:     {Source: SourceDefault},
+		WebhookConfigurationFlag: {Source: SourceDefault},
+		ExemptNamespacesFlag:     {Source: SourceDefault},
+		ProxyFlag:                {Source: SourceDefault},
+		NoProxyFlag:              {Source: SourceDefault},
+		ProxyOverridesFlag:       {Source: SourceDefault},
+		CABundleFlag:             {Source: SourceDefault},
+	}
+	for _, s := range settings {
+		if w := want[s.Name]; s.Value != w.Value || s.Source != w.Source {
+			t.Errorf("setting %s = %q from %s, want %q from %s", s.Name, s.Value, s.Source, w.Value, w.Source)
+		}
+	}
+	if cfg.Server.LogLevel != "debug" || cfg.Server.TLSKeyFile != "/env/tls.key" {
+		t.Errorf("unexpected effective server config %+v", cfg.Server)
+	}
+
+	cfg, settings, err = LoadEffectiveConfig(nil, func(string) string { return "" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server != DefaultServerConfig() {
+		t.Errorf("server config without layers = %+v, want defaults", cfg.Server)
+	}
+	for _, s := range settings {
+		if s.Source != SourceDefault {
+			t.Errorf("setting %s from %s, want %s", s.Name, s.Source, SourceDefault)
+		}
+	}
+}
+
+func TestServerConfig_ExemptNamespaceList(t *testing.T) {
+	s := ServerConfig{ExemptNamespaces: "kube-system, cert-manager,,"}
+	if got := s.ExemptNamespaceList(); !slices.Equal(got, []string{"kube-system", "cert-manager"}) {
+		t.Errorf("ExemptNamespaceList() = %v, want [kube-system cert-manager]", got)
+	}
+	if got := (ServerConfig{}).ExemptNamespaceList(); got != nil {
+		t.Errorf("ExemptNamespaceList() without setting = %v, want nil", got)
+	}
+}
+
+func TestServerConfig_UnparseablePolicy(t *testing.T) {
+	if got, err := DefaultServerConfig().UnparseablePolicy(); err != nil || got != UnparseableDeny {
+		t.Errorf("UnparseablePolicy() by default = %q, %v, want deny", got, err)
+	}
+	if _, err := (ServerConfig{Unparseable: "queue"}).UnparseablePolicy(); err == nil {
+		t.Error("UnparseablePolicy() of queue, want error")
+	}
+	env := map[string]string{"COSIGNWEBHOOK_UNPARSEABLE": "queue"}
+	if _, _, err := LoadEffectiveConfig(nil, func(k string) string { return env[k] }); err == nil {
+		t.Error("LoadEffectiveConfig() with unparseable queue, want error")
+	}
+}
+
+func TestServerConfig_OnErrorPolicies(t *testing.T) {
+	s := ServerConfig{OnError: OnErrorAllow, OnErrorOverrides: "licenses=ignore-rule, quota=deny,"}
+	def, overrides, err := s.OnErrorPolicies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def != OnErrorAllow || overrides[LicensesRuleName] != OnErrorIgnoreRule || overrides[QuotaRuleName] != OnErrorDeny {
+		t.Errorf("OnErrorPolicies() = %q, %v, want allow with overrides of licenses and quota", def, overrides)
+	}
+	for _, invalid := range []ServerConfig{
+		{OnError: "warn"},
+		{OnError: OnErrorDeny, OnErrorOverrides: "licenses"},
+		{OnError: OnErrorDeny, OnErrorOverrides: "unknown=allow"},
+		{OnError: OnErrorDeny, OnErrorOverrides: "licenses=warn"},
+	} {
+		if _, _, err := invalid.OnErrorPolicies(); err == nil {
+			t.Errorf("OnErrorPolicies() of %+v, want error", invalid)
+		}
+	}
+}
+
+func TestServerConfig_ProxyOverrideMap(t *testing.T) {
+	s := ServerConfig{ProxyOverrides: "registry.internal=direct, ghcr.io=http://proxy-b.example.com:3128,"}
+	got, err := s.ProxyOverrideMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, ok := got["registry.internal"]; !ok || p != nil {
+		t.Errorf("override of registry.internal = %v, want direct", p)
+	}
+	if p := got["ghcr.io"]; p == nil || p.Host != "proxy-b.example.com:3128" {
+		t.Errorf("override of ghcr.io = %v, want proxy-b.example.com:3128", p)
+	}
+	for _, invalid := range []string{"ghcr.io", "=direct", "ghcr.io=proxy-b"} {
+		if _, err := (ServerConfig{ProxyOverrides: invalid}).ProxyOverrideMap(); err == nil {
+			t.Errorf("ProxyOverrideMap() of %q, want error", invalid)
+		}
+	}
+}
+
+func TestLoadEffectiveConfig_caBundle(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, bundle := range []string{filepath.Join(dir, "missing.pem"), notPEM} {
+		flags := map[string]string{CABundleFlag: bundle, ProxyFlag: "http://proxy.example.com:3128"}
+		if _, _, err := LoadEffectiveConfig(flags, func(string) string { return "" }); err == nil {
+			t.Errorf("LoadEffectiveConfig() with CA bundle %s, want error", bundle)
+		}
+	}
+}
+
+func TestLoadEffectiveConfig_semantics(t *testing.T) {
+	env := map[string]string{"COSIGNWEBHOOK_SEMANTICS": "2"}
+	cfg, _, err := LoadEffectiveConfig(nil, func(k string) string { return env[k] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(cfg.Endpoints[0].Rules, SanityRuleName) {
+		t.Errorf("endpoints with semantics 2 = %+v, want embedded defaults", cfg.Endpoints)
+	}
+	if v, _ := cfg.Server.SemanticsVersion(); v != 2 {
+		t.Errorf("SemanticsVersion() = %d, want 2", v)
+	}
+
+	cfg, _, err = LoadEffectiveConfig(nil, func(string) string { return "" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := cfg.Server.SemanticsVersion(); v != SemanticsVersion {
+		t.Errorf("SemanticsVersion() = %d, want %d", v, SemanticsVersion)
+	}
+
+	for _, v := range []string{"1", "4"} {
+		if _, _, err := LoadEffectiveConfig(map[string]string{SemanticsFlag: v}, func(string) string { return "" }); err == nil {
+			t.Errorf("expected error for unsupported semantics version %s", v)
+		}
+	}
+}
+
+func TestParseServerArgs(t *testing.T) {
+	args := []string{"-logLevel", "debug", "--config=/etc/cosignwebhook/config.yaml", "-inject-latency=2s", "serve", "--onError", "allow", "-exemptNamespaces"}
+	want := map[string]string{
+		LogLevelFlag: "debug",
+		ConfigFlag:   "/etc/cosignwebhook/config.yaml",
+		OnErrorFlag:  OnErrorAllow,
+	}
+	if got := ParseServerArgs(args); !maps.Equal(got, want) {
+		t.Errorf("ParseServerArgs() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,125 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	log "github.com/gookit/slog"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// results of the comparison of the enforcing and the shadow verdict
+const (
+	shadowMatch    = "match"
+	shadowMismatch = "mismatch"
+	// shadowDropped are the requests not evaluated in shadow mode because the queue was full
+	shadowDropped = "dropped"
+)
+
+// shadowQueueSize bounds the requests waiting for their shadow evaluation
+const shadowQueueSize = 100
+
+var shadowDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "cosign_shadow_decisions_total",
+	Help: "The number of requests evaluated in shadow mode, by whether the shadow verdict matched the enforcing one or the request was dropped",
+}, []string{"endpoint", "result"})
+
+// shadowEngine evaluates requests with the rules of another semantics version without effect on the verdicts,
+// so the verdicts of an upgrade can be compared before enforcing them. The requests are evaluated in the
+// background, without delaying the admission responses.
+type shadowEngine struct {
+	path      string
+	rules     []Rule
+	semantics int
+	onError   onErrorPolicy
+	// queue holds the comparisons waiting for the background evaluation
+	queue chan shadowComparison
+}
+
+// shadowComparison is an enforcing decision to compare with the verdict of the shadow rules
+type shadowComparison struct {
+	o         *Object
+	allowed   bool
+	rule      string
+	semantics int
+	// results of the enforcing rules which the shadow rules reuse, nil if they decide the object differently
+	results map[string]ruleResult
+}
+
+// newShadowEngine returns the shadow engine for the endpoint, or nil if shadow mode is disabled or the endpoint
+// doesn't exist in the shadow config. The shadow rules are created from the shadow config, so they decide with
+// the shadow semantics independently of the enforcing rules. They share the informers, ConfigMap watches and
+// blocklists of the enforcing rules, so creating them starts no background work again.
+func (csh *CosignServerHandler) newShadowEngine(path string) (*shadowEngine, error) {
+	cfg := csh.cfg.shadow
+	if cfg == nil {
+		return nil, nil
+	}
+	i := slices.IndexFunc(cfg.Endpoints, func(ec EndpointConfig) bool { return ec.Path == path })
+	if i < 0 {
+		return nil, nil
+	}
+	rules, err := newRules(csh, cfg, cfg.Endpoints[i].Rules)
+	if err != nil {
+		return nil, fmt.Errorf("shadow: %w", err)
+	}
+	s := &shadowEngine{
+		path:      path,
+		rules:     rules,
+		semantics: csh.cfg.shadowSemantics,
+		onError:   csh.onError,
+		queue:     make(chan shadowComparison, shadowQueueSize),
+	}
+	csh.tasks = append(csh.tasks, s.run)
+	return s, nil
+}
+
+// submit queues the comparison of the enforcing decision with the shadow verdict. The shadow rules reuse the
+// results of the enforcing rules if the semantics versions decide the object alike. If the queue is full, the
+// request is dropped and counted.
+func (s *shadowEngine) submit(o *Object, d *Decision, results map[string]ruleResult) {
+	c := shadowComparison{o: o, allowed: d.Allowed, rule: d.Rule, semantics: d.Semantics}
+	if o.decidedAlike(d.Semantics, s.semantics) {
+		c.results = maps.Clone(results)
+	}
+	select {
+	case s.queue <- c:
+	default:
+		shadowDecisions.WithLabelValues(s.path, shadowDropped).Inc()
+	}
+}
+
+// run evaluates the queued comparisons until ctx is done
+func (s *shadowEngine) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case c := <-s.queue:
+			s.compare(ctx, c)
+		}
+	}
+}
+
+// compare evaluates the object with the shadow rules and counts and logs whether the verdict matches the
+// enforcing decision. It returns true if they match.
+func (s *shadowEngine) compare(ctx context.Context, c shadowComparison) bool {
+	results := c.results
+	if results == nil {
+		results = map[string]ruleResult{}
+	}
+	o := c.o
+	sd := evaluateRules(ctx, o, s.path, s.rules, s.semantics, s.onError, results)
+	if sd.Allowed == c.allowed && sd.Rule == c.rule {
+		shadowDecisions.WithLabelValues(s.path, shadowMatch).Inc()
+		return true
+	}
+	shadowDecisions.WithLabelValues(s.path, shadowMismatch).Inc()
+	log.Infof("Shadow semantics %d decided %s %s/%s on %s differently: allowed=%t rule=%q, enforcing semantics %d: allowed=%t rule=%q",
+		s.semantics, o.Request.Kind.Kind, o.Request.Namespace, o.Request.Name, s.path, sd.Allowed, sd.Rule, c.semantics, c.allowed, c.rule)
+	return false
+}
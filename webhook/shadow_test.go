@@ -0,0 +1,154 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	v1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// countingRule returns a fixed result and counts its evaluations
+type countingRule struct {
+	name  string
+	err   error
+	calls int
+}
+
+func (r *countingRule) Name() string {
+	return r.name
+}
+
+func (r *countingRule) Validate(_ context.Context, _ *Object) ([]string, error) {
+	r.calls++
+	return nil, r.err
+}
+
+func Test_shadowEngine_compare(t *testing.T) {
+	cosign := &countingRule{name: CosignRuleName}
+	csh := &CosignServerHandler{
+		semantics: SemanticsVersion,
+		cfg: &Config{
+			shadow:          &Config{Endpoints: []EndpointConfig{{Path: DefaultPath, Rules: []string{CosignRuleName}}}},
+			shadowSemantics: SemanticsVersion - 1,
+		},
+	}
+	e := &Endpoint{Path: DefaultPath, rules: []Rule{cosign}, csh: csh}
+
+	shadow, err := csh.newShadowEngine(DefaultPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shadow == nil || len(shadow.rules) != 1 || shadow.rules[0] == Rule(cosign) {
+		t.Fatalf("shadow engine = %+v, want a cosign rule of its own", shadow)
+	}
+	if len(csh.tasks) != 1 {
+		t.Fatalf("tasks = %d, want the background evaluation", len(csh.tasks))
+	}
+	// the shadow rule of the same name decides differently with the shadow semantics
+	shadowCosign := &countingRule{name: CosignRuleName, err: errors.New("unsigned image")}
+	shadow.rules[0] = shadowCosign
+	compare := func(o *Object) bool {
+		results := map[string]ruleResult{}
+		d := e.evaluate(context.Background(), o, results)
+		if !d.Allowed || d.Semantics != SemanticsVersion {
+			t.Fatalf("enforcing decision = %+v, want allowed with semantics %d", d, SemanticsVersion)
+		}
+		shadow.submit(o, d, results)
+		return shadow.compare(context.Background(), <-shadow.queue)
+	}
+
+	// the pod templates of workloads are evaluated again with the shadow semantics
+	sts := &Object{
+		Request:     &v1.AdmissionRequest{Namespace: "default", Name: "test"},
+		StatefulSet: &appsv1.StatefulSet{},
+	}
+	if compare(sts) {
+		t.Error("compare() = true, want mismatch as the shadow cosign rule denies")
+	}
+	if cosign.calls != 1 || shadowCosign.calls != 1 {
+		t.Errorf("rules evaluated %d and %d times, want once each", cosign.calls, shadowCosign.calls)
+	}
+	shadowCosign.err = nil
+	if !compare(sts) {
+		t.Error("compare() = false, want match")
+	}
+
+	// pods are decided alike, the shadow reuses the enforcing results
+	shadowCosign.err, shadowCosign.calls = errors.New("unsigned image"), 0
+	if !compare(podObject("default", corev1.PodSpec{})) || shadowCosign.calls != 0 {
+		t.Errorf("shadow rule evaluated %d times, want the enforcing result reused", shadowCosign.calls)
+	}
+
+	// a full queue drops the comparison
+	for range shadowQueueSize {
+		shadow.submit(sts, &Decision{Allowed: true}, nil)
+	}
+	before := testutil.ToFloat64(shadowDecisions.WithLabelValues(DefaultPath, shadowDropped))
+	shadow.submit(sts, &Decision{Allowed: true}, nil)
+	if got := testutil.ToFloat64(shadowDecisions.WithLabelValues(DefaultPath, shadowDropped)) - before; got != 1 {
+		t.Errorf("dropped comparisons = %v, want 1", got)
+	}
+
+	if s, _ := csh.newShadowEngine("/other"); s != nil {
+		t.Errorf("shadow engine for endpoint missing in the shadow config = %+v, want nil", s)
+	}
+}
+
+func Test_newShadowEngine_sharesBackgroundWork(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(source, []byte("sha256:0123\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{
+		Endpoints: []EndpointConfig{{Path: DefaultPath, Rules: []string{BlocklistRuleName, NamingRuleName}}},
+		Blocklist: BlocklistConfig{Source: source},
+		Naming:    NamingConfig{ConfigMap: "cosignwebhook/naming"},
+	}
+	shadowCfg := *cfg
+	cfg.shadow, cfg.shadowSemantics = &shadowCfg, SemanticsVersion-1
+	csh := &CosignServerHandler{semantics: SemanticsVersion, cfg: cfg}
+	if _, err := newRules(csh, cfg, cfg.Endpoints[0].Rules); err != nil {
+		t.Fatal(err)
+	}
+	tasks := len(csh.tasks)
+	if _, err := csh.newShadowEngine(DefaultPath); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(csh.tasks) - tasks; got != 1 {
+		t.Errorf("shadow engine added %d tasks, want only its background evaluation", got)
+	}
+}
+
+func Test_evaluateRules_templateSemantics(t *testing.T) {
+	r, err := newProbesRule(nil, &Config{Probes: ProbesConfig{Action: ActionDeny}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := &Object{
+		Request:     &v1.AdmissionRequest{Namespace: "default", Name: "test"},
+		StatefulSet: &appsv1.StatefulSet{Spec: appsv1.StatefulSetSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}}}}},
+	}
+	tests := []struct {
+		semantics int
+		allowed   bool
+	}{
+		{semantics: templateSemantics - 1, allowed: true},
+		{semantics: templateSemantics, allowed: false},
+	}
+	for _, tt := range tests {
+		d := evaluateRules(context.Background(), o, DefaultPath, []Rule{r}, tt.semantics, onErrorPolicy{}, map[string]ruleResult{})
+		if d.Allowed != tt.allowed {
+			t.Errorf("semantics %d: allowed = %t, want %t", tt.semantics, d.Allowed, tt.allowed)
+		}
+	}
+	if o.semantics != 0 {
+		t.Errorf("evaluated object semantics = %d, want 0 unchanged", o.semantics)
+	}
+}
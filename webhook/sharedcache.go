@@ -0,0 +1,407 @@
+package webhook
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/gookit/slog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultSharedCachePrefix      = "cosignwebhook:"
+	defaultSharedCacheTTL         = 10 * time.Minute
+	defaultSharedCacheTimeout     = time.Second
+	defaultSharedCacheLockTimeout = 30 * time.Second
+	// sharedCachePoll is the interval in which a replica checks for the verdict computed by another replica
+	sharedCachePoll = 100 * time.Millisecond
+	// maxSharedCacheConns bounds the idle connections kept to the server
+	maxSharedCacheConns = 8
+
+	sharedCacheSignature = "signature"
+	sharedCacheLicenses  = "licenses"
+)
+
+var sharedCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "cosign_shared_cache_requests_total",
+	Help: "The number of lookups in the shared cache by kind and result, hit, miss, timeout or error",
+}, []string{"kind", "result"})
+
+// SharedCacheConfig configures the optional cache shared by the replicas, a Redis or Valkey server, so signature and
+// license verdicts of a digest are computed once per fleet instead of once per replica
+type SharedCacheConfig struct {
+	// Address of the server, e.g. valkey.cosignwebhook:6379. Empty disables the shared cache.
+	Address string `json:"address"`
+	// Username of the ACL user, defaults to the default user
+	Username string `json:"username"`
+	// PasswordFile contains the password of the server, e.g. mounted from a secret
+	PasswordFile string `json:"passwordFile"`
+	// DB is the number of the database
+	DB int `json:"db"`
+	// TLS connects with TLS
+	TLS bool `json:"tls"`
+	// CAFile contains the CA certificates to verify the server, defaults to the system CAs
+	CAFile string `json:"caFile"`
+	// Prefix of the keys, defaults to cosignwebhook:
+	Prefix string `json:"prefix"`
+	// TTL of the cached verdicts, defaults to 10m
+	TTL metav1.Duration `json:"ttl"`
+	// Timeout of a command, defaults to 1s
+	Timeout metav1.Duration `json:"timeout"`
+	// LockTimeout is how long replicas wait for the verdict computed by another replica before computing it
+	// themselves, defaults to 30s
+	LockTimeout metav1.Duration `json:"lockTimeout"`
+}
+
+// validate checks the shared cache config and sets the defaults
+func (c *SharedCacheConfig) validate() error {
+	if c.Address == "" {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(c.Address); err != nil {
+		return fmt.Errorf("sharedCache: invalid address %q: %w", c.Address, err)
+	}
+	if c.DB < 0 {
+		return fmt.Errorf("sharedCache: db must not be negative, got %d", c.DB)
+	}
+	if c.TTL.Duration < 0 || c.Timeout.Duration < 0 || c.LockTimeout.Duration < 0 {
+		return fmt.Errorf("sharedCache: durations must not be negative")
+	}
+	if c.Prefix == "" {
+		c.Prefix = defaultSharedCachePrefix
+	}
+	if c.TTL.Duration == 0 {
+		c.TTL.Duration = defaultSharedCacheTTL
+	}
+	if c.Timeout.Duration == 0 {
+		c.Timeout.Duration = defaultSharedCacheTimeout
+	}
+	if c.LockTimeout.Duration == 0 {
+		c.LockTimeout.Duration = defaultSharedCacheLockTimeout
+	}
+	return nil
+}
+
+// sharedCache is a client of a Redis or Valkey server speaking RESP2, with a small pool of idle connections.
+// The cache is an optimization, so errors are logged and the verdicts are computed locally.
+type sharedCache struct {
+	cfg      SharedCacheConfig
+	password string
+	tls      *tls.Config
+	conns    chan *respConn
+}
+
+// respConn is a connection to the server
+type respConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// respError is an error reply of the server
+type respError string
+
+func (e respError) Error() string {
+	return string(e)
+}
+
+// newSharedCache returns the shared cache, or nil if it's disabled
+func newSharedCache(cfg SharedCacheConfig) (*sharedCache, error) {
+	if cfg.Address == "" {
+		return nil, nil
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	s := &sharedCache{cfg: cfg, conns: make(chan *respConn, maxSharedCacheConns)}
+	if cfg.PasswordFile != "" {
+		password, err := os.ReadFile(cfg.PasswordFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't read shared cache password: %w", err)
+		}
+		s.password = strings.TrimSpace(string(password))
+	}
+	if cfg.TLS {
+		host, _, _ := net.SplitHostPort(cfg.Address)
+		s.tls = &tls.Config{MinVersion: tls.VersionTLS12, ServerName: host}
+		if cfg.CAFile != "" {
+			pem, err := os.ReadFile(cfg.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("can't read shared cache CA file: %w", err)
+			}
+			s.tls.RootCAs = x509.NewCertPool()
+			if !s.tls.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in shared cache CA file %q", cfg.CAFile)
+			}
+		}
+	}
+	return s, nil
+}
+
+// sharedKey returns the key of a verdict depending on the passed values, e.g. digest and public key
+func sharedKey(values ...string) string {
+	h := sha256.Sum256([]byte(strings.Join(values, "\n")))
+	return hex.EncodeToString(h[:])
+}
+
+// fetch returns the value of the key of the kind, computed by compute once across the replicas. The first replica
+// missing the value takes a lock and computes it, the other ones wait for its value until the lock timeout.
+// Errors of compute aren't cached. A nil cache computes the value.
+func (s *sharedCache) fetch(ctx context.Context, kind, key string, compute func() ([]byte, error)) ([]byte, error) {
+	if s == nil {
+		return compute()
+	}
+	k := s.cfg.Prefix + kind + ":" + key
+	deadline := time.Now().Add(s.cfg.LockTimeout.Duration)
+	for {
+		v, err := s.get(ctx, k)
+		if err != nil {
+			return s.fallback(kind, err, compute)
+		}
+		if v != nil {
+			sharedCacheRequests.WithLabelValues(kind, "hit").Inc()
+			return v, nil
+		}
+		token, locked, err := s.lock(ctx, k)
+		if err != nil {
+			return s.fallback(kind, err, compute)
+		}
+		if locked {
+			sharedCacheRequests.WithLabelValues(kind, "miss").Inc()
+			return s.compute(ctx, k, token, compute)
+		}
+		if time.Now().After(deadline) {
+			sharedCacheRequests.WithLabelValues(kind, "timeout").Inc()
+			log.Warnf("Timed out waiting for another replica computing the %s verdict, computing it", kind)
+			return compute()
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(sharedCachePoll):
+		}
+	}
+}
+
+// fallback computes the value locally after an error of the shared cache
+func (*sharedCache) fallback(kind string, err error, compute func() ([]byte, error)) ([]byte, error) {
+	sharedCacheRequests.WithLabelValues(kind, "error").Inc()
+	log.Warnf("Shared cache failed, computing the %s verdict locally: %v", kind, err)
+	return compute()
+}
+
+// compute computes and stores the value of the locked key and releases the lock
+func (s *sharedCache) compute(ctx context.Context, key, token string, compute func() ([]byte, error)) ([]byte, error) {
+	defer func() {
+		if err := s.unlock(context.WithoutCancel(ctx), key, token); err != nil {
+			log.Warnf("Can't release the lock of the shared cache: %v", err)
+		}
+	}()
+	v, err := compute()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.do(ctx, "SET", key, string(v), "PX", strconv.FormatInt(s.cfg.TTL.Milliseconds(), 10)); err != nil {
+		log.Warnf("Can't store the verdict in the shared cache: %v", err)
+	}
+	return v, nil
+}
+
+// get returns the value of the key, nil if it doesn't exist
+func (s *sharedCache) get(ctx context.Context, key string) ([]byte, error) {
+	reply, err := s.do(ctx, "GET", key)
+	if err != nil {
+		return nil, err
+	}
+	v, _ := reply.([]byte)
+	return v, nil
+}
+
+// lock takes the lock of the key for the lock timeout with a random token and returns the token and whether it
+// got the lock
+func (s *sharedCache) lock(ctx context.Context, key string) (token string, locked bool, err error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", false, err
+	}
+	token = hex.EncodeToString(b)
+	reply, err := s.do(ctx, "SET", key+":lock", token, "NX", "PX", strconv.FormatInt(s.cfg.LockTimeout.Milliseconds(), 10))
+	return token, reply == "OK", err
+}
+
+// unlockScript deletes the lock only if it's still held with the token, so an expired lock taken by another
+// replica isn't released
+const unlockScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) end return 0`
+
+// unlock releases the lock of the key if it's held with the token
+func (s *sharedCache) unlock(ctx context.Context, key, token string) error {
+	_, err := s.do(ctx, "EVAL", unlockScript, "1", key+":lock", token)
+	return err
+}
+
+// flush deletes all keys with the prefix
+func (s *sharedCache) flush(ctx context.Context) error {
+	cursor := "0"
+	for {
+		reply, err := s.do(ctx, "SCAN", cursor, "MATCH", s.cfg.Prefix+"*", "COUNT", "100")
+		if err != nil {
+			return err
+		}
+		page, ok := reply.([]any)
+		if !ok || len(page) != 2 {
+			return fmt.Errorf("unexpected SCAN reply %v", reply)
+		}
+		next, _ := page[0].([]byte)
+		keys, _ := page[1].([]any)
+		if len(keys) > 0 {
+			args := make([]string, 0, len(keys)+1)
+			args = append(args, "DEL")
+			for _, k := range keys {
+				b, _ := k.([]byte)
+				args = append(args, string(b))
+			}
+			if _, err := s.do(ctx, args...); err != nil {
+				return err
+			}
+		}
+		if cursor = string(next); cursor == "0" || cursor == "" {
+			return nil
+		}
+	}
+}
+
+// do sends the command and returns its reply, a string, int64, []byte, []any or nil
+func (s *sharedCache) do(ctx context.Context, args ...string) (any, error) {
+	c, err := s.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := c.command(ctx, s.cfg.Timeout.Duration, args...)
+	var rerr respError
+	if err != nil && !errors.As(err, &rerr) {
+		c.Close()
+		return nil, err
+	}
+	select {
+	case s.conns <- c:
+	default:
+		c.Close()
+	}
+	return reply, err
+}
+
+// conn returns an idle connection or dials a new one, authenticated and with the database selected
+func (s *sharedCache) conn(ctx context.Context) (*respConn, error) {
+	select {
+	case c := <-s.conns:
+		return c, nil
+	default:
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout.Duration)
+	defer cancel()
+	var nc net.Conn
+	var err error
+	if s.tls != nil {
+		nc, err = (&tls.Dialer{Config: s.tls}).DialContext(ctx, "tcp", s.cfg.Address)
+	} else {
+		nc, err = (&net.Dialer{}).DialContext(ctx, "tcp", s.cfg.Address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &respConn{Conn: nc, r: bufio.NewReader(nc)}
+	var setup [][]string
+	switch {
+	case s.password != "" && s.cfg.Username != "":
+		setup = append(setup, []string{"AUTH", s.cfg.Username, s.password})
+	case s.password != "":
+		setup = append(setup, []string{"AUTH", s.password})
+	}
+	if s.cfg.DB > 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(s.cfg.DB)})
+	}
+	for _, args := range setup {
+		if _, err := c.command(ctx, s.cfg.Timeout.Duration, args...); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("%s: %w", args[0], err)
+		}
+	}
+	return c, nil
+}
+
+// command writes the command as array of bulk strings and reads the reply within the timeout
+func (c *respConn) command(ctx context.Context, timeout time.Duration, args ...string) (any, error) {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := c.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := io.WriteString(c, b.String()); err != nil {
+		return nil, err
+	}
+	return readReply(c.r)
+}
+
+// readReply reads a RESP2 reply
+func readReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, respError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unknown reply %q", line)
+}
@@ -0,0 +1,217 @@
+package webhook
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeValkey is an in-memory server speaking the subset of RESP2 used by the shared cache
+type fakeValkey struct {
+	mu       sync.Mutex
+	data     map[string]string
+	password string
+	l        net.Listener
+}
+
+func newFakeValkey(t *testing.T, password string) *fakeValkey {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeValkey{data: map[string]string{}, password: password, l: l}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(c)
+		}
+	}()
+	return f
+}
+
+func (f *fakeValkey) serve(c net.Conn) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+	authenticated := f.password == ""
+	for {
+		reply, err := readReply(r)
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]any)
+		args := make([]string, len(items))
+		for i, it := range items {
+			b, _ := it.([]byte)
+			args[i] = string(b)
+		}
+		if !authenticated && args[0] != "AUTH" {
+			io.WriteString(c, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		if args[0] == "AUTH" {
+			authenticated = args[len(args)-1] == f.password
+		}
+		io.WriteString(c, f.command(args))
+	}
+}
+
+func (f *fakeValkey) command(args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch args[0] {
+	case "AUTH", "SELECT":
+		return "+OK\r\n"
+	case "GET":
+		v, ok := f.data[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+	case "SET":
+		if _, ok := f.data[args[1]]; ok && args[3] == "NX" {
+			return "$-1\r\n"
+		}
+		f.data[args[1]] = args[2]
+		return "+OK\r\n"
+	case "EVAL":
+		if f.data[args[3]] != args[4] {
+			return ":0\r\n"
+		}
+		delete(f.data, args[3])
+		return ":1\r\n"
+	case "DEL":
+		for _, k := range args[1:] {
+			delete(f.data, k)
+		}
+		return fmt.Sprintf(":%d\r\n", len(args)-1)
+	case "SCAN":
+		var b strings.Builder
+		var keys []string
+		for k := range f.data {
+			if strings.HasPrefix(k, strings.TrimSuffix(args[3], "*")) {
+				keys = append(keys, k)
+			}
+		}
+		fmt.Fprintf(&b, "*2\r\n$1\r\n0\r\n*%d\r\n", len(keys))
+		for _, k := range keys {
+			fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(k), k)
+		}
+		return b.String()
+	}
+	return "-ERR unknown command\r\n"
+}
+
+func newTestSharedCache(t *testing.T, address string) *sharedCache {
+	t.Helper()
+	s, err := newSharedCache(SharedCacheConfig{Address: address, LockTimeout: metav1.Duration{Duration: 5 * time.Second}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func Test_sharedCache_fetch(t *testing.T) {
+	f := newFakeValkey(t, "")
+	// two replicas
+	a, b := newTestSharedCache(t, f.l.Addr().String()), newTestSharedCache(t, f.l.Addr().String())
+
+	var computed atomic.Int32
+	compute := func() ([]byte, error) {
+		computed.Add(1)
+		time.Sleep(200 * time.Millisecond)
+		return []byte("verified"), nil
+	}
+	var wg sync.WaitGroup
+	for _, s := range []*sharedCache{a, b, a, b} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := s.fetch(context.Background(), sharedCacheSignature, "digest", compute)
+			if err != nil || string(v) != "verified" {
+				t.Errorf("fetch() = %q, %v, want verified", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := computed.Load(); got != 1 {
+		t.Errorf("computed %d times, want once", got)
+	}
+	if _, ok := f.data[defaultSharedCachePrefix+sharedCacheSignature+":digest:lock"]; ok {
+		t.Error("lock not released")
+	}
+
+	// errors aren't shared
+	failed := errors.New("signature couldn't be verified")
+	for range 2 {
+		if _, err := a.fetch(context.Background(), sharedCacheSignature, "other", func() ([]byte, error) { return nil, failed }); !errors.Is(err, failed) {
+			t.Errorf("fetch() error = %v, want %v", err, failed)
+		}
+	}
+
+	if err := a.flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.data) != 0 {
+		t.Errorf("flush() left %v", f.data)
+	}
+}
+
+func Test_sharedCache_fallback(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+	s := newTestSharedCache(t, l.Addr().String())
+	v, err := s.fetch(context.Background(), sharedCacheLicenses, "digest", func() ([]byte, error) { return []byte(`["MIT"]`), nil })
+	if err != nil || string(v) != `["MIT"]` {
+		t.Errorf("fetch() with unreachable server = %q, %v, want computed value", v, err)
+	}
+
+	// a nil cache is disabled
+	if v, err := (*sharedCache)(nil).fetch(context.Background(), sharedCacheLicenses, "digest", func() ([]byte, error) { return []byte("x"), nil }); err != nil || string(v) != "x" {
+		t.Errorf("fetch() of nil cache = %q, %v", v, err)
+	}
+}
+
+func Test_sharedCache_auth(t *testing.T) {
+	f := newFakeValkey(t, "secret")
+	password := t.TempDir() + "/password"
+	if err := os.WriteFile(password, []byte("secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := newSharedCache(SharedCacheConfig{Address: f.l.Addr().String(), PasswordFile: password, DB: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.fetch(context.Background(), sharedCacheSignature, "digest", func() ([]byte, error) { return []byte("verified"), nil }); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := s.get(context.Background(), defaultSharedCachePrefix+sharedCacheSignature+":digest"); err != nil || string(v) != "verified" {
+		t.Errorf("get() = %q, %v, want verified", v, err)
+	}
+}
+
+func TestSharedCacheConfig_validate(t *testing.T) {
+	for _, c := range []SharedCacheConfig{{Address: "valkey"}, {Address: "valkey:6379", DB: -1}, {Address: "valkey:6379", TTL: metav1.Duration{Duration: -time.Second}}} {
+		if err := c.validate(); err == nil {
+			t.Errorf("validate(%+v) want error", c)
+		}
+	}
+}
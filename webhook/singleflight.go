@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/sync/singleflight"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// lookupDigest is the kind of the lookups resolving the digest of an image
+	lookupDigest = "digest"
+	// lookupTimeout bounds a shared lookup, which doesn't end with the request of the caller which started it
+	lookupTimeout = 30 * time.Second
+)
+
+var lookupsDeduplicated = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "cosign_lookups_deduplicated_total",
+	Help: "The number of external lookups which shared the result of an identical concurrent lookup, by kind",
+}, []string{"kind"})
+
+// dedupe collapses concurrent identical lookups of the kind and key, e.g. verifying the signature of the same
+// digest during a rollout, into one call of fn and returns its result to all callers. fn is called with a context
+// detached from the callers, bounded by lookupTimeout, so a caller whose request ends doesn't fail the others.
+// Each caller waits until its own ctx is done.
+func (csh *CosignServerHandler) dedupe(ctx context.Context, kind, key string, fn func(ctx context.Context) (any, error)) (any, error) {
+	return awaitLookup(ctx, kind, csh.joinLookup(ctx, kind, key, fn))
+}
+
+// joinLookup starts the lookup of the kind and key, or joins the identical one in flight, and returns the channel
+// of its result
+func (csh *CosignServerHandler) joinLookup(ctx context.Context, kind, key string, fn func(ctx context.Context) (any, error)) <-chan singleflight.Result {
+	return csh.lookups.DoChan(kind+":"+key, func() (any, error) {
+		lctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), lookupTimeout)
+		defer cancel()
+		return fn(lctx)
+	})
+}
+
+// awaitLookup returns the result of the lookup of the kind, or the error of ctx if it's done before
+func awaitLookup(ctx context.Context, kind string, ch <-chan singleflight.Result) (any, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Shared {
+			lookupsDeduplicated.WithLabelValues(kind).Inc()
+		}
+		return res.Val, res.Err
+	}
+}
+
+// pullCredentials identifies the pull credentials of pods with the spec in the namespace, i.e. the namespace,
+// service account and image pull secrets their keychain is built from. Lookups are only shared by pods with the
+// same credentials, as they may not be allowed to pull the same images.
+func pullCredentials(ns string, spec *corev1.PodSpec) string {
+	secrets := make([]string, 0, len(spec.ImagePullSecrets))
+	for _, s := range spec.ImagePullSecrets {
+		secrets = append(secrets, s.Name)
+	}
+	slices.Sort(secrets)
+	return ns + "/" + spec.ServiceAccountName + "/" + strings.Join(secrets, ",")
+}
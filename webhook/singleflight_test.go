@@ -0,0 +1,83 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/sync/singleflight"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCosignServerHandler_dedupe(t *testing.T) {
+	csh := &CosignServerHandler{}
+	var calls atomic.Int32
+	started, release := make(chan struct{}), make(chan struct{})
+	lookup := func(context.Context) (any, error) {
+		calls.Add(1)
+		close(started)
+		<-release
+		return "sha256:abc", nil
+	}
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderErr := make(chan error)
+	go func() {
+		_, err := csh.dedupe(leaderCtx, lookupDigest, "nginx:1.27", lookup)
+		leaderErr <- err
+	}()
+	<-started
+	// the lookup is in flight, identical lookups join it
+	followers := make([]<-chan singleflight.Result, 10)
+	for i := range followers {
+		followers[i] = csh.joinLookup(context.Background(), lookupDigest, "nginx:1.27", lookup)
+	}
+	// a lookup of another image isn't collapsed
+	if v, _ := csh.dedupe(context.Background(), lookupDigest, "redis:7", func(context.Context) (any, error) { return "sha256:def", nil }); v != "sha256:def" {
+		t.Errorf("dedupe() of other key = %v, want sha256:def", v)
+	}
+
+	// the request of the leader ends, the lookup continues for the followers
+	cancelLeader()
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("dedupe() of the canceled leader error = %v, want canceled", err)
+	}
+	close(release)
+	for _, ch := range followers {
+		if v, err := awaitLookup(context.Background(), lookupDigest, ch); v != "sha256:abc" || err != nil {
+			t.Errorf("dedupe() = %v, %v, want sha256:abc", v, err)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("lookup called %d times, want once", got)
+	}
+
+	// after the lookup finished, the next one calls again
+	if _, err := csh.dedupe(context.Background(), lookupDigest, "nginx:1.27", func(context.Context) (any, error) { calls.Add(1); return nil, nil }); err != nil || calls.Load() != 2 {
+		t.Errorf("dedupe() after finished lookup called %d times, want 2", calls.Load())
+	}
+}
+
+func Test_pullCredentials(t *testing.T) {
+	spec := func(sa string, secrets ...string) *corev1.PodSpec {
+		s := &corev1.PodSpec{ServiceAccountName: sa}
+		for _, name := range secrets {
+			s.ImagePullSecrets = append(s.ImagePullSecrets, corev1.LocalObjectReference{Name: name})
+		}
+		return s
+	}
+	same := pullCredentials("team-a", spec("builder", "registry", "mirror"))
+	if got := pullCredentials("team-a", spec("builder", "mirror", "registry")); got != same {
+		t.Errorf("pullCredentials() = %q with reordered secrets, want %q", got, same)
+	}
+	for _, other := range []string{
+		pullCredentials("team-b", spec("builder", "registry", "mirror")),
+		pullCredentials("team-a", spec("default", "registry", "mirror")),
+		pullCredentials("team-a", spec("builder", "registry")),
+	} {
+		if other == same {
+			t.Errorf("pullCredentials() = %q for other credentials, want them told apart", other)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/gookit/slog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// service level objectives tracked by the webhook
+const (
+	SLOLatency      = "latency"
+	SLOAvailability = "availability"
+)
+
+// alert severities of burn rates
+const (
+	SLOSeverityPage   = "page"
+	SLOSeverityTicket = "ticket"
+)
+
+const (
+	defaultLatencyObjective   = 0.99
+	defaultSLOSummaryInterval = time.Hour
+	sloUpdateInterval         = time.Minute
+	// sloBuckets are the minutes of the longest burn rate window
+	sloBuckets = 360
+)
+
+// sloWindows are the windows of the burn rates. An alert fires if the burn rates of a long and a short window
+// both exceed the threshold of the severity, the short window lets the alert resolve quickly.
+var sloWindows = []struct {
+	severity    string
+	long, short time.Duration
+	threshold   float64
+}{
+	// 2% of a 30 day error budget burnt in one hour
+	{severity: SLOSeverityPage, long: time.Hour, short: 5 * time.Minute, threshold: 14.4},
+	// 5% of a 30 day error budget burnt in six hours
+	{severity: SLOSeverityTicket, long: 6 * time.Hour, short: 30 * time.Minute, threshold: 6},
+}
+
+var (
+	sloObjective = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cosign_slo_objective",
+		Help: "The objective of the SLO, the share of good requests",
+	}, []string{"slo"})
+	sloRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cosign_slo_requests_total",
+		Help: "The number of admission requests counted for the SLO by result, good or bad",
+	}, []string{"slo", "result"})
+	sloBurnRate = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cosign_slo_burn_rate",
+		Help: "The rate the error budget of the SLO is burnt at in the window, 1 uses it up exactly within the SLO period",
+	}, []string{"slo", "window"})
+	sloAlert = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cosign_slo_alert",
+		Help: "Whether the burn rates of the SLO exceed the threshold of the severity, 1 if firing",
+	}, []string{"slo", "severity"})
+)
+
+// SLOConfig configures the service level objectives of the admission requests
+type SLOConfig struct {
+	// Latency is the target latency of admission requests, e.g. 500ms, empty disables the latency SLO
+	Latency metav1.Duration `json:"latency"`
+	// LatencyObjective is the share of requests answered within the target latency, defaults to 0.99 (p99)
+	LatencyObjective float64 `json:"latencyObjective"`
+	// Availability is the share of requests answered without server error or rejection, e.g. 0.999,
+	// zero disables the availability SLO
+	Availability float64 `json:"availability"`
+	// SummaryInterval is the interval of the SLO summary in the log, defaults to 1h
+	SummaryInterval metav1.Duration `json:"summaryInterval"`
+}
+
+// enabled returns whether any SLO is configured
+func (c *SLOConfig) enabled() bool {
+	return c.Latency.Duration > 0 || c.Availability > 0
+}
+
+// validate checks the objectives and sets the defaults
+func (c *SLOConfig) validate() error {
+	if c.LatencyObjective == 0 {
+		c.LatencyObjective = defaultLatencyObjective
+	}
+	if c.LatencyObjective <= 0 || c.LatencyObjective >= 1 {
+		return fmt.Errorf("slo: latency objective must be between 0 and 1, got %v", c.LatencyObjective)
+	}
+	if c.Availability < 0 || c.Availability >= 1 {
+		return fmt.Errorf("slo: availability must be between 0 and 1, got %v", c.Availability)
+	}
+	if c.SummaryInterval.Duration <= 0 {
+		c.SummaryInterval.Duration = defaultSLOSummaryInterval
+	}
+	return nil
+}
+
+// sloBucket counts the requests of one minute
+type sloBucket struct {
+	minute int64
+	total  int64
+	slow   int64
+	failed int64
+}
+
+// sloTracker counts the requests of the last hours by minute and computes the burn rates of the SLOs
+type sloTracker struct {
+	cfg SLOConfig
+
+	mu      sync.Mutex
+	buckets [sloBuckets]sloBucket
+}
+
+func newSLOTracker(cfg SLOConfig) *sloTracker {
+	t := &sloTracker{cfg: cfg}
+	for _, slo := range t.objectives() {
+		sloObjective.WithLabelValues(slo.name).Set(slo.objective)
+	}
+	return t
+}
+
+// sloTarget is a configured SLO
+type sloTarget struct {
+	name      string
+	objective float64
+	// bad returns the bad requests of the bucket
+	bad func(b *sloBucket) int64
+}
+
+// objectives returns the configured SLOs
+func (t *sloTracker) objectives() []sloTarget {
+	var o []sloTarget
+	if t.cfg.Latency.Duration > 0 {
+		o = append(o, sloTarget{name: SLOLatency, objective: t.cfg.LatencyObjective, bad: func(b *sloBucket) int64 { return b.slow }})
+	}
+	if t.cfg.Availability > 0 {
+		o = append(o, sloTarget{name: SLOAvailability, objective: t.cfg.Availability, bad: func(b *sloBucket) int64 { return b.failed }})
+	}
+	return o
+}
+
+// observe counts a request started at start and answered with the status of w. Client errors don't count,
+// server errors and rejections like 429 are failed requests. It's a no-op on a nil tracker.
+func (t *sloTracker) observe(start time.Time, w *statusWriter) {
+	if t == nil || (w.status >= http.StatusBadRequest && w.status < http.StatusInternalServerError && w.status != http.StatusTooManyRequests) {
+		return
+	}
+	t.record(time.Now(), time.Since(start), w.status >= http.StatusInternalServerError || w.status == http.StatusTooManyRequests)
+}
+
+// record counts a request answered at now after latency
+func (t *sloTracker) record(now time.Time, latency time.Duration, failed bool) {
+	slow := t.cfg.Latency.Duration > 0 && latency > t.cfg.Latency.Duration
+	if t.cfg.Latency.Duration > 0 {
+		sloRequests.WithLabelValues(SLOLatency, goodOrBad(!slow)).Inc()
+	}
+	if t.cfg.Availability > 0 {
+		sloRequests.WithLabelValues(SLOAvailability, goodOrBad(!failed)).Inc()
+	}
+
+	minute := now.Unix() / 60
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b := &t.buckets[minute%sloBuckets]
+	if b.minute != minute {
+		*b = sloBucket{minute: minute}
+	}
+	b.total++
+	if slow {
+		b.slow++
+	}
+	if failed {
+		b.failed++
+	}
+}
+
+func goodOrBad(good bool) string {
+	if good {
+		return "good"
+	}
+	return "bad"
+}
+
+// burnRate returns the burn rate of the SLO over the window ending at now, the share of bad requests relative to
+// the error budget. Windows without requests don't burn.
+func (t *sloTracker) burnRate(slo sloTarget, now time.Time, window time.Duration) float64 {
+	last := now.Unix() / 60
+	first := last - int64(window/time.Minute) + 1
+	var total, bad int64
+	t.mu.Lock()
+	for i := range t.buckets {
+		if b := &t.buckets[i]; b.minute >= first && b.minute <= last {
+			total += b.total
+			bad += slo.bad(b)
+		}
+	}
+	t.mu.Unlock()
+	if total == 0 {
+		return 0
+	}
+	return float64(bad) / float64(total) / (1 - slo.objective)
+}
+
+// sloStatus are the burn rates and firing alerts of an SLO
+type sloStatus struct {
+	name      string
+	objective float64
+	burnRates map[time.Duration]float64
+	alerts    []string
+}
+
+// status returns the burn rates and alerts of all SLOs at now
+func (t *sloTracker) status(now time.Time) []sloStatus {
+	var statuses []sloStatus
+	for _, slo := range t.objectives() {
+		s := sloStatus{name: slo.name, objective: slo.objective, burnRates: map[time.Duration]float64{}}
+		for _, w := range sloWindows {
+			long, short := t.burnRate(slo, now, w.long), t.burnRate(slo, now, w.short)
+			s.burnRates[w.long], s.burnRates[w.short] = long, short
+			if long > w.threshold && short > w.threshold {
+				s.alerts = append(s.alerts, w.severity)
+			}
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses
+}
+
+// update sets the burn rate and alert metrics
+func (t *sloTracker) update(now time.Time) []sloStatus {
+	statuses := t.status(now)
+	for _, s := range statuses {
+		for window, rate := range s.burnRates {
+			sloBurnRate.WithLabelValues(s.name, windowLabel(window)).Set(rate)
+		}
+		for _, w := range sloWindows {
+			firing := 0.0
+			if slices.Contains(s.alerts, w.severity) {
+				firing = 1
+			}
+			sloAlert.WithLabelValues(s.name, w.severity).Set(firing)
+		}
+	}
+	return statuses
+}
+
+// run updates the metrics each minute and logs a summary in each summary interval until ctx is done
+func (t *sloTracker) run(ctx context.Context) {
+	log.Infof("Tracking SLOs, logging a summary every %s", t.cfg.SummaryInterval.Duration)
+	ticker := time.NewTicker(sloUpdateInterval)
+	defer ticker.Stop()
+	summary := time.NewTicker(t.cfg.SummaryInterval.Duration)
+	defer summary.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			t.update(now)
+		case now := <-summary.C:
+			t.logSummary(t.update(now))
+		}
+	}
+}
+
+// logSummary logs the burn rates of the SLOs, as warning if an alert fires
+func (t *sloTracker) logSummary(statuses []sloStatus) {
+	for _, s := range statuses {
+		var rates []string
+		for _, w := range sloWindows {
+			for _, window := range []time.Duration{w.short, w.long} {
+				rates = append(rates, fmt.Sprintf("%s %.2f", windowLabel(window), s.burnRates[window]))
+			}
+		}
+		target := fmt.Sprintf("%g%%", s.objective*100)
+		if s.name == SLOLatency {
+			target += " within " + t.cfg.Latency.Duration.String()
+		}
+		if len(s.alerts) > 0 {
+			log.Warnf("SLO %s (%s) is burning its error budget too fast, alerts %s, burn rates %s",
+				s.name, target, strings.Join(s.alerts, ", "), strings.Join(rates, ", "))
+			continue
+		}
+		log.Infof("SLO %s (%s) burn rates %s", s.name, target, strings.Join(rates, ", "))
+	}
+}
+
+// windowLabel returns the window without zero units, like 5m or 1h
+func windowLabel(window time.Duration) string {
+	return strings.TrimSuffix(strings.TrimSuffix(window.String(), "0s"), "0m")
+}
+
+// statusWriter records the status of the response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_sloTracker_status(t *testing.T) {
+	cfg := SLOConfig{Latency: metav1.Duration{Duration: 500 * time.Millisecond}, Availability: 0.99}
+	if err := cfg.validate(); err != nil {
+		t.Fatal(err)
+	}
+	tr := newSLOTracker(cfg)
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	// 2 hours ago: slow requests, outside the 1h window
+	for range 100 {
+		tr.record(now.Add(-2*time.Hour), time.Second, false)
+	}
+	// last minutes: 20% failed
+	for i := range 100 {
+		tr.record(now.Add(-time.Minute), 100*time.Millisecond, i%5 == 0)
+	}
+
+	statuses := tr.status(now)
+	if len(statuses) != 2 || statuses[0].name != SLOLatency || statuses[1].name != SLOAvailability {
+		t.Fatalf("status() = %+v, want latency and availability", statuses)
+	}
+	latency, availability := statuses[0], statuses[1]
+	if got := latency.burnRates[time.Hour]; got != 0 {
+		t.Errorf("latency burn rate 1h = %v, want 0", got)
+	}
+	// 100 of 200 requests were slow with a budget of 1%
+	if got := latency.burnRates[6*time.Hour]; math.Abs(got-50) > 1e-9 {
+		t.Errorf("latency burn rate 6h = %v, want 50", got)
+	}
+	// the slow requests are older than the short window of the ticket, so it's resolved already
+	if len(latency.alerts) > 0 {
+		t.Errorf("latency alerts = %v, want none", latency.alerts)
+	}
+	// 20 of 100 requests failed with a budget of 1%
+	if got := availability.burnRates[5*time.Minute]; math.Abs(got-20) > 1e-9 {
+		t.Errorf("availability burn rate 5m = %v, want 20", got)
+	}
+	if !slices.Equal(availability.alerts, []string{SLOSeverityPage, SLOSeverityTicket}) {
+		t.Errorf("availability alerts = %v, want page and ticket", availability.alerts)
+	}
+
+	// the buckets of older minutes are reused
+	if rate := tr.burnRate(sloTarget{objective: 0.99, bad: func(b *sloBucket) int64 { return b.failed }}, now.Add(7*time.Hour), 6*time.Hour); rate != 0 {
+		t.Errorf("burn rate after 7h = %v, want 0", rate)
+	}
+}
+
+func Test_sloTracker_observe(t *testing.T) {
+	tr := newSLOTracker(SLOConfig{Availability: 0.999})
+	for _, status := range []int{http.StatusOK, http.StatusBadRequest, http.StatusTooManyRequests, http.StatusInternalServerError} {
+		w := &statusWriter{ResponseWriter: httptest.NewRecorder(), status: http.StatusOK}
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+		}
+		tr.observe(time.Now(), w)
+	}
+	var total, failed int64
+	for _, b := range tr.buckets {
+		total += b.total
+		failed += b.failed
+	}
+	if total != 3 || failed != 2 {
+		t.Errorf("observe() counted %d requests with %d failed, want 3 with 2 failed", total, failed)
+	}
+
+	// a nil tracker is disabled
+	(*sloTracker)(nil).observe(time.Now(), &statusWriter{status: http.StatusOK})
+}
+
+func TestSLOConfig_validate(t *testing.T) {
+	for _, c := range []SLOConfig{{LatencyObjective: 1}, {Availability: 1.5}, {Availability: -0.1}} {
+		if err := c.validate(); err == nil {
+			t.Errorf("validate(%+v) want error", c)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	_ "embed"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"sigs.k8s.io/yaml"
+)
+
+// strictness levels of the generated starter policy
+const (
+	// StrictnessPermissive only warns about policy violations and lets requests pass if the webhook fails.
+	// Broken pod specs are still rejected by the sanity rule.
+	StrictnessPermissive = "permissive"
+	// StrictnessStandard verifies signatures and denies images from unknown registries
+	StrictnessStandard = "standard"
+	// StrictnessStrict additionally requires seccomp profiles and probes and denies deprecated apiVersions
+	StrictnessStrict = "strict"
+)
+
+const (
+	// DefaultStarterNamespace is the namespace the webhook is deployed to by the generated manifests
+	DefaultStarterNamespace = "cosignwebhook"
+	// DefaultStarterImage is the webhook image of the generated manifests
+	DefaultStarterImage = "ghcr.io/eumel8/cosignwebhook/cosignwebhook:4.3.0"
+	starterCertValidity = 10 * 365 * 24 * time.Hour
+)
+
+//go:embed defaults/manifests.yaml.tmpl
+var manifestsTemplate string
+
+// StarterOptions are the answers to the questions of the init wizard
+type StarterOptions struct {
+	// Registries images may be pulled from, empty allows all registries
+	Registries []string
+	// Namespaces the webhook validates, empty validates all namespaces but the own one and kube-system
+	Namespaces []string
+	// Rules are added to the rules of the strictness
+	Rules []string
+	// Strictness is permissive, standard or strict
+	Strictness string
+	// Namespace the webhook is deployed to
+	Namespace string
+	// Image of the webhook
+	Image string
+}
+
+// Starter is a starter policy file and the manifests deploying the webhook with it
+type Starter struct {
+	Config    []byte
+	Manifests []byte
+}
+
+// NewStarter generates a starter policy and deployment manifests with a self-signed webhook certificate
+func NewStarter(opts StarterOptions) (*Starter, error) {
+	if opts.Namespace == "" {
+		opts.Namespace = DefaultStarterNamespace
+	}
+	if opts.Image == "" {
+		opts.Image = DefaultStarterImage
+	}
+	policy, failurePolicy, err := starterPolicy(opts)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := yaml.Marshal(policy)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ParseConfig(cfg); err != nil {
+		return nil, fmt.Errorf("generated invalid policy: %w", err)
+	}
+
+	service := "cosignwebhook." + opts.Namespace
+	ca, cert, key, err := starterCerts([]string{service, service + ".svc"})
+	if err != nil {
+		return nil, fmt.Errorf("can't generate webhook certificate: %w", err)
+	}
+	manifests, err := renderManifests(map[string]any{
+		"Header": `# Deployment manifests of cosignwebhook generated by "cosignwebhook init".
+# The webhook certificate is self-signed and valid for 10 years.`,
+		"Namespace":     opts.Namespace,
+		"Namespaces":    opts.Namespaces,
+		"Image":         opts.Image,
+		"Config":        string(cfg),
+		"FailurePolicy": failurePolicy,
+		"CABundle":      base64.StdEncoding.EncodeToString(ca),
+		"Cert":          base64.StdEncoding.EncodeToString(cert),
+		"Key":           base64.StdEncoding.EncodeToString(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Starter{Config: cfg, Manifests: manifests}, nil
+}
+
+// renderManifests renders the deployment manifests template with the passed values. The permissions of the
+// webhook depend on the rules of the config.
+func renderManifests(values map[string]any) ([]byte, error) {
+	cfg, err := ParseConfig([]byte(values["Config"].(string)))
+	if err != nil {
+		return nil, err
+	}
+	values["References"] = cfg.usesRule(ReferencesRuleName)
+	// the naming ConfigMap is watched as namespace/name
+	if ns, name, ok := strings.Cut(cfg.Naming.ConfigMap, "/"); ok {
+		values["NamingConfigMap"] = []string{ns, name}
+	}
+	tmpl, err := template.New("manifests").Funcs(template.FuncMap{
+		"indent": func(n int, s string) string {
+			pad := strings.Repeat(" ", n)
+			return pad + strings.ReplaceAll(strings.TrimSuffix(s, "\n"), "\n", "\n"+pad)
+		},
+		"join": strings.Join,
+	}).Parse(manifestsTemplate)
+	if err != nil {
+		return nil, err
+	}
+	var manifests bytes.Buffer
+	if err := tmpl.Execute(&manifests, values); err != nil {
+		return nil, err
+	}
+	return manifests.Bytes(), nil
+}
+
+// starterPolicy returns the config of the strictness level and the failure policy of the webhook
+func starterPolicy(opts StarterOptions) (map[string]any, string, error) {
+	rules := []string{CosignRuleName, SanityRuleName}
+	action, failurePolicy := ActionDeny, "Fail"
+	policy := map[string]any{}
+	switch opts.Strictness {
+	case StrictnessPermissive:
+		// the cosign rule can't warn only
+		rules = []string{SanityRuleName}
+		action, failurePolicy = ActionWarn, "Ignore"
+	case StrictnessStandard, "":
+	case StrictnessStrict:
+		rules = append(rules, SecurityProfilesRuleName, ProbesRuleName)
+		policy["securityProfiles"] = map[string]any{"requireSeccomp": true}
+		policy["probes"] = map[string]any{"action": ActionDeny}
+	default:
+		return nil, "", fmt.Errorf("unknown strictness %q, must be %s, %s or %s", opts.Strictness, StrictnessPermissive, StrictnessStandard, StrictnessStrict)
+	}
+	if len(opts.Registries) > 0 {
+		for _, r := range opts.Registries {
+			if _, err := name.NewRegistry(r); err != nil {
+				return nil, "", fmt.Errorf("invalid registry %q: %w", r, err)
+			}
+		}
+		rules = append(rules, RegistriesRuleName)
+		policy["registries"] = map[string]any{"allowed": opts.Registries, "action": action}
+	}
+	rules = append(rules, DeprecationRuleName)
+	for _, r := range opts.Rules {
+		if !slices.Contains(rules, r) {
+			rules = append(rules, r)
+		}
+	}
+	deprecation := ActionWarn
+	if opts.Strictness == StrictnessStrict {
+		deprecation = ActionDeny
+	}
+	policy["deprecation"] = map[string]any{"action": deprecation}
+	policy["endpoints"] = []map[string]any{{"path": DefaultPath, "rules": rules}}
+	return policy, failurePolicy, nil
+}
+
+// starterCerts generates a self-signed CA and a serving certificate for passed DNS names, all PEM encoded
+func starterCerts(dnsNames []string) (ca, cert, key []byte, err error) {
+	notBefore := time.Now()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "cosign-webhook-ca"},
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(starterCertValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	serverKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	serverDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    notBefore,
+		NotAfter:     notBefore.Add(starterCertValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, caTemplate, &serverKey.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(serverKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: serverDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		nil
+}
@@ -0,0 +1,124 @@
+package webhook
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"slices"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+func TestNewStarter(t *testing.T) {
+	tests := []struct {
+		name              string
+		opts              StarterOptions
+		wantRules         []string
+		wantFailurePolicy string
+		wantErr           bool
+	}{
+		{
+			name:              "standard",
+			opts:              StarterOptions{Registries: []string{"ghcr.io", "docker.io"}},
+			wantRules:         []string{CosignRuleName, SanityRuleName, RegistriesRuleName, DeprecationRuleName},
+			wantFailurePolicy: "Fail",
+		},
+		{
+			name:              "permissive",
+			opts:              StarterOptions{Strictness: StrictnessPermissive, Namespaces: []string{"team-a"}},
+			wantRules:         []string{SanityRuleName, DeprecationRuleName},
+			wantFailurePolicy: "Ignore",
+		},
+		{
+			name:              "strict",
+			opts:              StarterOptions{Strictness: StrictnessStrict, Namespace: "security"},
+			wantRules:         []string{CosignRuleName, SanityRuleName, SecurityProfilesRuleName, ProbesRuleName, DeprecationRuleName},
+			wantFailurePolicy: "Fail",
+		},
+		{
+			name:              "additional rules",
+			opts:              StarterOptions{Rules: []string{HPARuleName, CosignRuleName}},
+			wantRules:         []string{CosignRuleName, SanityRuleName, DeprecationRuleName, HPARuleName},
+			wantFailurePolicy: "Fail",
+		},
+		{
+			name:    "unknown rule",
+			opts:    StarterOptions{Rules: []string{"magic"}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown strictness",
+			opts:    StarterOptions{Strictness: "paranoid"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid registry",
+			opts:    StarterOptions{Registries: []string{"ghcr.io/eumel8"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewStarter(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewStarter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			cfg, err := ParseConfig(s.Config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(cfg.Endpoints[0].Rules, tt.wantRules) {
+				t.Errorf("rules = %v, want %v", cfg.Endpoints[0].Rules, tt.wantRules)
+			}
+
+			docs := strings.Split(string(s.Manifests), "\n---\n")
+			objects := map[string]map[string]any{}
+			for _, d := range docs[1:] {
+				o := map[string]any{}
+				if err := yaml.Unmarshal([]byte(d), &o); err != nil {
+					t.Fatalf("invalid manifest: %v\n%s", err, d)
+				}
+				objects[o["kind"].(string)] = o
+			}
+			cm := objects["ConfigMap"]["data"].(map[string]any)["config.yaml"].(string)
+			if cm != string(s.Config) {
+				t.Errorf("config map contains %q, want %q", cm, s.Config)
+			}
+			webhook := objects["ValidatingWebhookConfiguration"]["webhooks"].([]any)[0].(map[string]any)
+			if webhook["failurePolicy"] != tt.wantFailurePolicy {
+				t.Errorf("failurePolicy = %v, want %s", webhook["failurePolicy"], tt.wantFailurePolicy)
+			}
+
+			// the serving certificate must be valid for the service with the CA bundle of the webhook
+			ns := tt.opts.Namespace
+			if ns == "" {
+				ns = DefaultStarterNamespace
+			}
+			roots := x509.NewCertPool()
+			roots.AppendCertsFromPEM(decodeBase64(t, webhook["clientConfig"].(map[string]any)["caBundle"].(string)))
+			block, _ := pem.Decode(decodeBase64(t, objects["Secret"]["data"].(map[string]any)["tls.crt"].(string)))
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := cert.Verify(x509.VerifyOptions{DNSName: "cosignwebhook." + ns + ".svc", Roots: roots}); err != nil {
+				t.Errorf("can't verify serving certificate: %v", err)
+			}
+		})
+	}
+}
+
+func decodeBase64(t *testing.T, s string) []byte {
+	t.Helper()
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
@@ -0,0 +1,134 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	log "github.com/gookit/slog"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// DecisionStreamPath is the path of the decision stream
+	DecisionStreamPath = "/decisions"
+	// DecisionPath is the path of a single buffered decision by the UID of its admission request
+	DecisionPath    = "/decisions/{uid}"
+	streamKeepAlive = 30 * time.Second
+)
+
+// DecisionStream streams the buffered and all later decisions as Server-Sent Events.
+// Clients authenticate with a bearer token of the cluster, which is checked with a TokenReview.
+// The query parameters namespace and rule filter the decisions.
+func (csh *CosignServerHandler) DecisionStream(w http.ResponseWriter, r *http.Request) {
+	if _, status, err := csh.authenticate(r, csh.cfg.Decisions.StreamGroups); err != nil {
+		log.Warnf("Decision stream request rejected: %v", err)
+		http.Error(w, err.Error(), status)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	namespace := r.URL.Query().Get("namespace")
+	rule := r.URL.Query().Get("rule")
+	matches := func(d *Decision) bool {
+		return (namespace == "" || d.Namespace == namespace) && (rule == "" || d.Rule == rule)
+	}
+
+	recent, ch, cancel := csh.decisions.subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	for _, d := range recent {
+		if matches(d) {
+			if err := writeDecisionEvent(w, d); err != nil {
+				return
+			}
+		}
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case d := <-ch:
+			if !matches(d) {
+				continue
+			}
+			if err := writeDecisionEvent(w, d); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// Decision returns the latest buffered decision of the admission request with the UID of the path as JSON.
+// Denied decisions include the trace of the evaluated rules. Clients authenticate like for the decision stream.
+func (csh *CosignServerHandler) Decision(w http.ResponseWriter, r *http.Request) {
+	if _, status, err := csh.authenticate(r, csh.cfg.Decisions.StreamGroups); err != nil {
+		log.Warnf("Decision request rejected: %v", err)
+		http.Error(w, err.Error(), status)
+		return
+	}
+	uid := types.UID(r.PathValue("uid"))
+	d := csh.decisions.find(uid)
+	if d == nil {
+		http.Error(w, fmt.Sprintf("decision %s not found", uid), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(d); err != nil {
+		log.Errorf("Can't write decision %s: %v", uid, err)
+	}
+}
+
+// writeDecisionEvent writes the decision as event to the stream
+func writeDecisionEvent(w http.ResponseWriter, d *Decision) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: decision\ndata: %s\n\n", data)
+	return err
+}
+
+// authenticate checks the bearer token of the request with a TokenReview and that the user is in one of
+// passed groups. Without groups, all users are denied. It returns the user, or the HTTP status to respond with if
+// the request is rejected.
+func (csh *CosignServerHandler) authenticate(r *http.Request, groups []string) (*authenticationv1.UserInfo, int, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil, http.StatusUnauthorized, fmt.Errorf("bearer token required")
+	}
+	tr, err := csh.cs.AuthenticationV1().TokenReviews().Create(r.Context(), &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("can't review token: %w", err)
+	}
+	if !tr.Status.Authenticated {
+		return nil, http.StatusUnauthorized, fmt.Errorf("invalid token: %s", tr.Status.Error)
+	}
+	if !slices.ContainsFunc(tr.Status.User.Groups, func(g string) bool { return slices.Contains(groups, g) }) {
+		return nil, http.StatusForbidden, fmt.Errorf("user %q is not in one of the groups %v", tr.Status.User.Username, groups)
+	}
+	return &tr.Status.User, http.StatusOK, nil
+}
@@ -0,0 +1,124 @@
+package webhook
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func Test_decisionBuffer(t *testing.T) {
+	b := newDecisionBuffer(2)
+	b.add(&Decision{Name: "a"})
+	if got := b.recent(); len(got) != 1 || got[0].Name != "a" {
+		t.Fatalf("recent() = %v, want [a]", got)
+	}
+	b.add(&Decision{Name: "b"})
+	b.add(&Decision{Name: "c"})
+	got := b.recent()
+	if len(got) != 2 || got[0].Name != "b" || got[1].Name != "c" {
+		t.Fatalf("recent() = %v, want [b c]", got)
+	}
+}
+
+// authenticatingClientset returns a fake clientset whose TokenReviews authenticate the token valid
+// as user jane in group platform
+func authenticatingClientset() *fake.Clientset {
+	cs := fake.NewSimpleClientset()
+	cs.PrependReactor("create", "tokenreviews", func(a k8stesting.Action) (bool, runtime.Object, error) {
+		tr := a.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		tr.Status.Authenticated = tr.Spec.Token == "valid"
+		tr.Status.User = authenticationv1.UserInfo{Username: "jane", Groups: []string{"platform"}}
+		return true, tr, nil
+	})
+	return cs
+}
+
+func TestCosignServerHandler_DecisionStream(t *testing.T) {
+	cs := authenticatingClientset()
+	cfg := DefaultConfig()
+	cfg.Decisions.StreamGroups = []string{"platform"}
+	csh := &CosignServerHandler{cs: cs, cfg: cfg, decisions: newDecisionBuffer(10)}
+	csh.OnDecision(csh.decisions.add)
+	csh.recordDecision(&Decision{Namespace: "a", Rule: CosignRuleName})
+	csh.recordDecision(&Decision{Namespace: "b", Rule: CosignRuleName})
+
+	srv := httptest.NewServer(http.HandlerFunc(csh.DecisionStream))
+	defer srv.Close()
+
+	for _, token := range []string{"", "invalid"} {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, http.NoBody)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("token %q: got status %d, want %d", token, resp.StatusCode, http.StatusUnauthorized)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?namespace=b", http.NoBody)
+	req.Header.Set("Authorization", "Bearer valid")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	events := make(chan Decision)
+	go func() {
+		s := bufio.NewScanner(resp.Body)
+		for s.Scan() {
+			if data, ok := strings.CutPrefix(s.Text(), "data: "); ok {
+				d := Decision{}
+				_ = json.Unmarshal([]byte(data), &d)
+				events <- d
+			}
+		}
+	}()
+
+	// the buffered decision of namespace b is replayed, then new decisions are streamed
+	if d := <-events; d.Namespace != "b" {
+		t.Errorf("got buffered decision of namespace %q, want b", d.Namespace)
+	}
+	csh.recordDecision(&Decision{Namespace: "a", Name: "skipped"})
+	csh.recordDecision(&Decision{Namespace: "b", Name: "streamed"})
+	if d := <-events; d.Name != "streamed" {
+		t.Errorf("got decision %q, want streamed", d.Name)
+	}
+}
+
+func TestCosignServerHandler_authenticate(t *testing.T) {
+	csh := &CosignServerHandler{cs: authenticatingClientset()}
+	tests := []struct {
+		name   string
+		groups []string
+		want   int
+	}{
+		{name: "user in group", groups: []string{"ci", "platform"}, want: http.StatusOK},
+		{name: "user in other group", groups: []string{"ci"}, want: http.StatusForbidden},
+		{name: "no groups deny all users", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, DecisionStreamPath, http.NoBody)
+			req.Header.Set("Authorization", "Bearer valid")
+			if _, status, _ := csh.authenticate(req, tt.groups); status != tt.want {
+				t.Errorf("authenticate() status = %d, want %d", status, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/gookit/slog"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const defaultTelemetryInterval = time.Hour
+
+// TelemetryConfig configures the opt-in export of aggregated, anonymous usage stats.
+// Only rule names and decision counters are sent, no object names, namespaces or users.
+// Reports are posted as CloudEvents of type io.eumel8.grumpy.telemetry.report.
+type TelemetryConfig struct {
+	// Endpoint is the URL the stats are posted to as JSON, empty disables the export
+	Endpoint string `json:"endpoint"`
+	// Interval between reports, defaults to 1h
+	Interval metav1.Duration `json:"interval"`
+	// ClusterID identifies the cluster in the reports, e.g. a random ID chosen by the fleet owner
+	ClusterID string `json:"clusterID"`
+	// Retry configures the attempts of a report, defaults to 3 attempts within 30s.
+	// Reports failing all attempts are sent with the next interval.
+	Retry RetryPolicy `json:"retry"`
+}
+
+// TelemetryReport is the aggregate of one interval as sent to the telemetry endpoint
+type TelemetryReport struct {
+	ClusterID string `json:"clusterID,omitempty"`
+	// Rules are the names of the configured rules
+	Rules []string `json:"rules"`
+	// Since is the start of the interval
+	Since   time.Time `json:"since"`
+	Allowed int64     `json:"allowed"`
+	Denied  int64     `json:"denied"`
+	Warned  int64     `json:"warned"`
+	// Denials counts the denials by rule
+	Denials map[string]int64 `json:"denials"`
+}
+
+// telemetryReporter counts decisions and posts the aggregate to the telemetry endpoint in each interval
+type telemetryReporter struct {
+	cfg    TelemetryConfig
+	rules  []string
+	client *http.Client
+
+	mu     sync.Mutex
+	report TelemetryReport
+}
+
+func newTelemetryReporter(cfg *Config, e *egress) *telemetryReporter {
+	c := cfg.Telemetry
+	if c.Interval.Duration <= 0 {
+		c.Interval.Duration = defaultTelemetryInterval
+	}
+	names := map[string]bool{}
+	for _, e := range cfg.Endpoints {
+		for _, r := range e.Rules {
+			names[r] = true
+		}
+	}
+	t := &telemetryReporter{
+		cfg:    c,
+		rules:  sortedKeys(names),
+		client: e.client(c.Retry.Timeout.Duration),
+	}
+	t.reset(time.Now())
+	return t
+}
+
+// reset starts a new interval at passed time
+func (t *telemetryReporter) reset(now time.Time) {
+	t.report = TelemetryReport{
+		ClusterID: t.cfg.ClusterID,
+		Rules:     t.rules,
+		Since:     now,
+		Denials:   map[string]int64{},
+	}
+}
+
+// count adds the decision to the current interval
+func (t *telemetryReporter) count(d *Decision) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch {
+	case !d.Allowed:
+		t.report.Denied++
+		t.report.Denials[d.Rule]++
+	case len(d.Warnings) > 0:
+		t.report.Warned++
+		t.report.Allowed++
+	default:
+		t.report.Allowed++
+	}
+}
+
+// run reports in each interval until ctx is done
+func (t *telemetryReporter) run(ctx context.Context) {
+	log.Infof("Reporting usage stats to %s every %s", t.cfg.Endpoint, t.cfg.Interval.Duration)
+	ticker := time.NewTicker(t.cfg.Interval.Duration)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := t.send(ctx); err != nil {
+				log.Warnf("Can't report usage stats: %v", err)
+			}
+		}
+	}
+}
+
+// send posts the current interval and starts a new one. If the post fails, the counters are added back
+// to be sent with the next interval.
+func (t *telemetryReporter) send(ctx context.Context) error {
+	t.mu.Lock()
+	report := t.report
+	t.reset(time.Now())
+	t.mu.Unlock()
+
+	err := t.post(ctx, &report)
+	if err != nil {
+		t.mu.Lock()
+		t.report.Since = report.Since
+		t.report.Allowed += report.Allowed
+		t.report.Denied += report.Denied
+		t.report.Warned += report.Warned
+		for r, n := range report.Denials {
+			t.report.Denials[r] += n
+		}
+		t.mu.Unlock()
+	}
+	return err
+}
+
+// post sends the report to the telemetry endpoint with the retry policy
+func (t *telemetryReporter) post(ctx context.Context, report *TelemetryReport) error {
+	body, err := json.Marshal(telemetryEvent(report, time.Now()))
+	if err != nil {
+		return err
+	}
+	return retry(ctx, &t.cfg.Retry, integrationTelemetry, destinationOf(t.cfg.Endpoint), func(ctx context.Context) error {
+		return t.postOnce(ctx, body)
+	})
+}
+
+// postOnce sends the encoded report to the telemetry endpoint once
+func (t *telemetryReporter) postOnce(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return permanent(err)
+	}
+	req.Header.Set("Content-Type", CloudEventsContentType)
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return unexpectedStatus(resp, "unexpected status %s")
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_telemetryReporter_send(t *testing.T) {
+	var got TelemetryReport
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := CloudEvent{Data: &got}
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Error(err)
+		}
+		if event.Type != EventTypeTelemetryReport || r.Header.Get("Content-Type") != CloudEventsContentType {
+			t.Errorf("unexpected event type %q, content type %q", event.Type, r.Header.Get("Content-Type"))
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	cfg := &Config{Endpoints: []EndpointConfig{{Path: DefaultPath, Rules: []string{CosignRuleName}}}}
+	cfg.Telemetry = TelemetryConfig{Endpoint: srv.URL, ClusterID: "test"}
+	tr := newTelemetryReporter(cfg, nil)
+	tr.count(&Decision{Allowed: true})
+	tr.count(&Decision{Allowed: true, Warnings: []string{"warning"}})
+	tr.count(&Decision{Rule: CosignRuleName})
+
+	// a failed report is retried with the next interval
+	status = http.StatusInternalServerError
+	if err := tr.send(context.Background()); err == nil {
+		t.Fatal("send() expected error")
+	}
+	tr.count(&Decision{Rule: CosignRuleName})
+	status = http.StatusOK
+	if err := tr.send(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got.ClusterID != "test" || len(got.Rules) != 1 || got.Rules[0] != CosignRuleName {
+		t.Errorf("unexpected report metadata: %+v", got)
+	}
+	if got.Allowed != 2 || got.Warned != 1 || got.Denied != 2 || got.Denials[CosignRuleName] != 2 {
+		t.Errorf("unexpected report counters: %+v", got)
+	}
+	if tr.report.Allowed != 0 || tr.report.Denied != 0 {
+		t.Errorf("counters not reset after report: %+v", tr.report)
+	}
+}
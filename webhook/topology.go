@@ -0,0 +1,91 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// TopologyRuleName is the name of the rule requiring topology constraints for HA workloads
+const TopologyRuleName = "topology"
+
+// TopologyConfig configures which deployments have to be spread over which topology domains
+type TopologyConfig struct {
+	// Selector matches the labels of deployments which have to be spread, defaults to tier=critical
+	Selector map[string]string `json:"selector"`
+	// TopologyKeys have to be used in a topologySpreadConstraint or pod anti-affinity,
+	// defaults to topology.kubernetes.io/zone
+	TopologyKeys []string `json:"topologyKeys"`
+}
+
+// topologyRule requires critical deployments to be spread over zones
+type topologyRule struct {
+	selector labels.Selector
+	keys     []string
+}
+
+func newTopologyRule(_ *CosignServerHandler, cfg *Config) (Rule, error) {
+	c := cfg.Topology
+	if len(c.Selector) == 0 {
+		c.Selector = map[string]string{"tier": "critical"}
+	}
+	if len(c.TopologyKeys) == 0 {
+		c.TopologyKeys = []string{corev1.LabelTopologyZone}
+	}
+	return &topologyRule{
+		selector: labels.SelectorFromSet(c.Selector),
+		keys:     c.TopologyKeys,
+	}, nil
+}
+
+// Name returns the name of the rule
+func (*topologyRule) Name() string {
+	return TopologyRuleName
+}
+
+// Validate checks that selected deployments use all required topology keys
+func (r *topologyRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	if o.Deployment == nil || !r.selector.Matches(labels.Set(o.Deployment.Labels)) {
+		return nil, nil
+	}
+	tracef(ctx, "labels of deployment %q match %q", o.Deployment.Name, r.selector)
+	spec := &o.Deployment.Spec.Template.Spec
+	var missing []string
+	for _, k := range r.keys {
+		if !hasTopologyKey(spec, k) {
+			missing = append(missing, k)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("deployment %q matching %q must define topologySpreadConstraints or pod anti-affinity for topology keys: %s",
+			o.Deployment.Name, r.selector, strings.Join(missing, ", "))
+	}
+	return nil, nil
+}
+
+// hasTopologyKey returns true if the pod spec spreads pods over passed topology key,
+// either with a topologySpreadConstraint or a pod anti-affinity term
+func hasTopologyKey(spec *corev1.PodSpec, key string) bool {
+	for _, c := range spec.TopologySpreadConstraints {
+		if c.TopologyKey == key {
+			return true
+		}
+	}
+	if spec.Affinity == nil || spec.Affinity.PodAntiAffinity == nil {
+		return false
+	}
+	for _, t := range spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+		if t.TopologyKey == key {
+			return true
+		}
+	}
+	for _, t := range spec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+		if t.PodAffinityTerm.TopologyKey == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// deploymentObject returns an admission object for a deployment with passed labels and pod spec
+func deploymentObject(ns string, lbls map[string]string, spec corev1.PodSpec) *Object {
+	return &Object{
+		Request: &v1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+			Namespace: ns,
+			Name:      "test",
+		},
+		Meta: metav1.ObjectMeta{Name: "test", Namespace: ns, Labels: lbls},
+		Deployment: &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: ns, Labels: lbls},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{Spec: spec},
+			},
+		},
+	}
+}
+
+func Test_topologyRule_Validate(t *testing.T) {
+	critical := map[string]string{"tier": "critical"}
+	tests := []struct {
+		name    string
+		labels  map[string]string
+		spec    corev1.PodSpec
+		wantErr bool
+	}{
+		{
+			name:   "not critical",
+			labels: map[string]string{"tier": "batch"},
+		},
+		{
+			name:    "critical without constraints",
+			labels:  critical,
+			wantErr: true,
+		},
+		{
+			name:   "critical with spread constraint",
+			labels: critical,
+			spec: corev1.PodSpec{TopologySpreadConstraints: []corev1.TopologySpreadConstraint{
+				{TopologyKey: corev1.LabelTopologyZone},
+			}},
+		},
+		{
+			name:   "critical with preferred anti-affinity",
+			labels: critical,
+			spec: corev1.PodSpec{Affinity: &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{
+				PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+					{PodAffinityTerm: corev1.PodAffinityTerm{TopologyKey: corev1.LabelTopologyZone}},
+				},
+			}}},
+		},
+		{
+			name:   "critical with wrong topology key",
+			labels: critical,
+			spec: corev1.PodSpec{TopologySpreadConstraints: []corev1.TopologySpreadConstraint{
+				{TopologyKey: corev1.LabelHostname},
+			}},
+			wantErr: true,
+		},
+	}
+
+	r, err := newTopologyRule(nil, &Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Validate(context.Background(), deploymentObject("default", tt.labels, tt.spec))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
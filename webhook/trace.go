@@ -0,0 +1,103 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// results of the rules in the trace of a decision
+const (
+	TracePassed  = "passed"
+	TraceWarned  = "warned"
+	TraceDenied  = "denied"
+	TraceFailed  = "failed"
+	TraceSkipped = "skipped"
+)
+
+// RuleTrace is the evaluation of a rule for a denied request, so the reason of the denial can be looked up
+// without evaluating the request again with debug logging
+type RuleTrace struct {
+	Rule string `json:"rule"`
+	// Result is passed, warned, denied, failed with an internal error or skipped by the on error policy
+	Result   string   `json:"result"`
+	Duration string   `json:"duration"`
+	Warnings []string `json:"warnings,omitempty"`
+	Error    string   `json:"error,omitempty"`
+	// Facts are the values of the facts the rule read, or the errors computing them
+	Facts map[Fact]any `json:"facts,omitempty"`
+	// Notes are recorded by the rule while evaluating, e.g. the policy it selected or the selector which matched
+	Notes []string `json:"notes,omitempty"`
+}
+
+// traceKey is the context key of the notes of the rule evaluated
+type traceKey struct{}
+
+// ruleNotes are the notes a rule records while evaluating. They are locked, as a rule abandoned by the watchdog may
+// still record notes.
+type ruleNotes struct {
+	mu    sync.Mutex
+	notes []string
+}
+
+// withNotes returns the context recording the notes of a rule in n
+func withNotes(ctx context.Context, n *ruleNotes) context.Context {
+	return context.WithValue(ctx, traceKey{}, n)
+}
+
+// tracef records a note of the evaluation of a rule for the trace of the decision. It's a no-op outside of an
+// evaluation.
+func tracef(ctx context.Context, format string, args ...any) {
+	n, ok := ctx.Value(traceKey{}).(*ruleNotes)
+	if !ok {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.notes = append(n.notes, fmt.Sprintf(format, args...))
+}
+
+// get returns a copy of the recorded notes
+func (n *ruleNotes) get() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]string(nil), n.notes...)
+}
+
+// traceStep is a rule evaluated for a request with its result. The trace is only built from the steps if the
+// request is denied, so allowed requests don't pay for it.
+type traceStep struct {
+	rule   Rule
+	res    ruleResult
+	result string
+}
+
+// newTrace returns the trace of the evaluated rules with the facts they read
+func newTrace(steps []traceStep, o *Object) []RuleTrace {
+	trace := make([]RuleTrace, 0, len(steps))
+	for _, s := range steps {
+		t := RuleTrace{
+			Rule:     s.rule.Name(),
+			Result:   s.result,
+			Duration: s.res.duration.Round(time.Microsecond).String(),
+			Warnings: s.res.warnings,
+			Notes:    s.res.notes,
+		}
+		if s.res.err != nil {
+			t.Error = s.res.err.Error()
+		}
+		if d, ok := unwrapRule(s.rule).(FactDependent); ok && len(d.Facts()) > 0 {
+			t.Facts = map[Fact]any{}
+			for _, f := range d.Facts() {
+				v, err := o.Facts.Get(f)
+				if err != nil {
+					v = "error: " + err.Error()
+				}
+				t.Facts[f] = v
+			}
+		}
+		trace = append(trace, t)
+	}
+	return trace
+}
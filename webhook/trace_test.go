@@ -0,0 +1,166 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_evaluateRules_trace(t *testing.T) {
+	replicas, err := newReplicasRule(&CosignServerHandler{}, &Config{Replicas: ReplicasConfig{MaxReplicas: 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sanity := &countingRule{name: SanityRuleName}
+	o := podObject("default", corev1.PodSpec{})
+	o.Pod = nil
+	n := int32(5)
+	o.Deployment = &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Replicas: &n}}
+
+	d := evaluateRules(context.Background(), o, DefaultPath, []Rule{sanity, replicas}, SemanticsVersion, onErrorPolicy{}, nil)
+	if d.Allowed || len(d.Trace) != 2 {
+		t.Fatalf("decision = %+v, want denied with the trace of both rules", d)
+	}
+	if got := d.Trace[0]; got.Rule != SanityRuleName || got.Result != TracePassed {
+		t.Errorf("trace[0] = %+v, want sanity passed", got)
+	}
+	got := d.Trace[1]
+	if got.Rule != ReplicasRuleName || got.Result != TraceDenied || got.Error == "" {
+		t.Errorf("trace[1] = %+v, want replicas denied with error", got)
+	}
+	if len(got.Notes) != 1 || got.Notes[0] != "default maximum 3" {
+		t.Errorf("trace[1] notes = %v, want the selected maximum", got.Notes)
+	}
+
+	n = 2
+	d = evaluateRules(context.Background(), o, DefaultPath, []Rule{sanity, replicas}, SemanticsVersion, onErrorPolicy{}, nil)
+	if !d.Allowed || d.Trace != nil {
+		t.Errorf("decision = %+v, want allowed without trace", d)
+	}
+}
+
+func Test_evaluateRules_traceFailed(t *testing.T) {
+	o := podObject("default", corev1.PodSpec{})
+	rules := []Rule{
+		&countingRule{name: QuotaRuleName, err: internalError(errors.New("can't list resource quotas"))},
+		&countingRule{name: SanityRuleName, err: errors.New("broken pod spec")},
+	}
+	d := evaluateRules(context.Background(), o, DefaultPath, rules, SemanticsVersion, onErrorPolicy{def: OnErrorIgnoreRule}, nil)
+	if len(d.Trace) != 2 || d.Trace[0].Result != TraceSkipped || d.Trace[1].Result != TraceDenied {
+		t.Errorf("trace = %+v, want quota skipped and sanity denied", d.Trace)
+	}
+	d = evaluateRules(context.Background(), o, DefaultPath, rules, SemanticsVersion, onErrorPolicy{}, nil)
+	if len(d.Trace) != 1 || d.Trace[0].Result != TraceFailed {
+		t.Errorf("trace = %+v, want quota failed", d.Trace)
+	}
+}
+
+func Test_tracef(t *testing.T) {
+	// outside of an evaluation it's a no-op
+	tracef(context.Background(), "ignored")
+
+	n := &ruleNotes{}
+	tracef(withNotes(context.Background(), n), "policy of namespace %q", "team-a")
+	if got := n.get(); len(got) != 1 || got[0] != `policy of namespace "team-a"` {
+		t.Errorf("notes = %v, want the recorded note", got)
+	}
+}
+
+func Test_ruleNotes(t *testing.T) {
+	gvisor := "gvisor"
+	tests := []struct {
+		name      string
+		factory   ruleFactory
+		cfg       *Config
+		namespace string
+		want      string
+	}{
+		{
+			name:      "exempt namespace",
+			factory:   newPrivilegedRule,
+			cfg:       &Config{Privileged: PrivilegedConfig{ExemptNamespaces: []string{"kube-system"}}},
+			namespace: "kube-system",
+			want:      `namespace "kube-system" is exempt`,
+		},
+		{
+			name:      "registries of namespace",
+			factory:   newRegistriesRule,
+			cfg:       &Config{Registries: RegistriesConfig{Allowed: []string{"ghcr.io"}, Namespaces: map[string][]string{"team-a": {"docker.io"}}}},
+			namespace: "team-a",
+			want:      `registries of namespace "team-a"`,
+		},
+		{
+			name:      "required runtime class",
+			factory:   newRuntimeClassRule,
+			cfg:       &Config{RuntimeClass: RuntimeClassConfig{Namespaces: map[string]string{"untrusted": gvisor}}},
+			namespace: "untrusted",
+			want:      `namespace "untrusted" requires runtime class "gvisor"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := tt.factory(&CosignServerHandler{}, tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			n := &ruleNotes{}
+			spec := corev1.PodSpec{RuntimeClassName: &gvisor, Containers: []corev1.Container{{Name: "app", Image: "nginx:1.27"}}}
+			if _, err := r.Validate(withNotes(context.Background(), n), podObject(tt.namespace, spec)); err != nil {
+				t.Fatal(err)
+			}
+			if got := n.get(); !slices.Contains(got, tt.want) {
+				t.Errorf("notes = %v, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCosignServerHandler_Decision(t *testing.T) {
+	csh := &CosignServerHandler{cs: authenticatingClientset(), cfg: DefaultConfig(), decisions: newDecisionBuffer(10)}
+	csh.OnDecision(csh.decisions.add)
+	csh.recordDecision(&Decision{UID: "a", Allowed: true})
+	csh.recordDecision(&Decision{UID: "b", Rule: ReplicasRuleName, Trace: []RuleTrace{{Rule: ReplicasRuleName, Result: TraceDenied}}})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(DecisionPath, csh.Decision)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	get := func(uid, token string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/decisions/"+uid, http.NoBody)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := get("b", "invalid")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("invalid token: got status %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+	resp = get("c", "valid")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown uid: got status %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+
+	resp = get("b", "valid")
+	defer resp.Body.Close()
+	var d Decision
+	if err := json.NewDecoder(resp.Body).Decode(&d); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || d.UID != "b" || len(d.Trace) != 1 || d.Trace[0].Rule != ReplicasRuleName {
+		t.Errorf("got status %d and decision %+v, want decision b with trace", resp.StatusCode, d)
+	}
+}
@@ -0,0 +1,81 @@
+package webhook
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	log "github.com/gookit/slog"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VerifyTrust checks on boot that the API server can reach the webhook with the serving certificate: each webhook
+// of the registered ValidatingWebhookConfiguration must call a DNS name in the SANs of the certificate, and its
+// caBundle must verify the certificate. Otherwise the replica reports not ready on /readyz with the reason.
+// It does nothing if the webhook configuration isn't set.
+func (csh *CosignServerHandler) VerifyTrust(ctx context.Context, cert *x509.Certificate) {
+	name := csh.cfg.Server.WebhookConfiguration
+	if name == "" {
+		return
+	}
+	if cert == nil {
+		csh.trustErr = errors.New("no serving certificate loaded")
+	} else if vwc, err := csh.cs.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(ctx, name, metav1.GetOptions{}); err != nil {
+		csh.trustErr = fmt.Errorf("can't get ValidatingWebhookConfiguration %s: %w", name, err)
+	} else {
+		csh.trustErr = verifyTrust(cert, vwc)
+	}
+	if csh.trustErr != nil {
+		log.Errorf("Serving certificate doesn't match the webhook configuration, not ready: %v", csh.trustErr)
+	}
+}
+
+// verifyTrust returns the mismatches of the serving certificate and the webhooks of the configuration
+func verifyTrust(cert *x509.Certificate, vwc *admissionregistrationv1.ValidatingWebhookConfiguration) error {
+	var errs []error
+	for _, w := range vwc.Webhooks {
+		host, err := webhookHost(w.ClientConfig)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s of ValidatingWebhookConfiguration %s: %w", w.Name, vwc.Name, err))
+			continue
+		}
+		if err := cert.VerifyHostname(host); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s of ValidatingWebhookConfiguration %s calls %s, but the serving certificate is only valid for [%s]",
+				w.Name, vwc.Name, host, strings.Join(cert.DNSNames, ", ")))
+			continue
+		}
+		if len(w.ClientConfig.CABundle) == 0 {
+			// the API server verifies with its system trust roots
+			continue
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(w.ClientConfig.CABundle) {
+			errs = append(errs, fmt.Errorf("webhook %s of ValidatingWebhookConfiguration %s has no PEM certificate in its caBundle", w.Name, vwc.Name))
+			continue
+		}
+		if _, err := cert.Verify(x509.VerifyOptions{Roots: roots, DNSName: host}); err != nil {
+			errs = append(errs, fmt.Errorf("caBundle of webhook %s of ValidatingWebhookConfiguration %s doesn't verify the serving certificate: %w", w.Name, vwc.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// webhookHost returns the host name the API server calls the webhook with
+func webhookHost(cc admissionregistrationv1.WebhookClientConfig) (string, error) {
+	if cc.Service != nil {
+		return cc.Service.Name + "." + cc.Service.Namespace + ".svc", nil
+	}
+	if cc.URL == nil {
+		return "", errors.New("neither service nor url in clientConfig")
+	}
+	u, err := url.Parse(*cc.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid url in clientConfig: %w", err)
+	}
+	return u.Hostname(), nil
+}
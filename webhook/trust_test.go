@@ -0,0 +1,91 @@
+package webhook
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestVerifyTrust(t *testing.T) {
+	ca, certPEM, _, err := starterCerts([]string{"cosignwebhook.cosignwebhook", "cosignwebhook.cosignwebhook.svc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherCA, _, _, err := starterCerts([]string{"cosignwebhook.cosignwebhook.svc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vwc := func(namespace string, caBundle []byte) *admissionregistrationv1.ValidatingWebhookConfiguration {
+		return &admissionregistrationv1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "cosignwebhook"},
+			Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+				Name: "cosignwebhook.eumel8.io",
+				ClientConfig: admissionregistrationv1.WebhookClientConfig{
+					Service:  &admissionregistrationv1.ServiceReference{Name: "cosignwebhook", Namespace: namespace},
+					CABundle: caBundle,
+				},
+			}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		vwc     *admissionregistrationv1.ValidatingWebhookConfiguration
+		wantErr string
+	}{
+		{
+			name: "matching",
+			vwc:  vwc("cosignwebhook", ca),
+		},
+		{
+			name:    "other namespace",
+			vwc:     vwc("security", ca),
+			wantErr: "calls cosignwebhook.security.svc, but the serving certificate is only valid for [cosignwebhook.cosignwebhook, cosignwebhook.cosignwebhook.svc]",
+		},
+		{
+			name:    "other CA",
+			vwc:     vwc("cosignwebhook", otherCA),
+			wantErr: "caBundle of webhook cosignwebhook.eumel8.io of ValidatingWebhookConfiguration cosignwebhook doesn't verify",
+		},
+		{
+			name:    "missing",
+			wantErr: "can't get ValidatingWebhookConfiguration cosignwebhook",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := fake.NewSimpleClientset()
+			if tt.vwc != nil {
+				cs = fake.NewSimpleClientset(tt.vwc)
+			}
+			csh := &CosignServerHandler{cs: cs, cfg: &Config{Server: ServerConfig{WebhookConfiguration: "cosignwebhook"}}}
+			csh.VerifyTrust(context.Background(), cert)
+
+			rec := httptest.NewRecorder()
+			csh.Readyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if tt.wantErr == "" {
+				if rec.Code != http.StatusOK {
+					t.Errorf("Readyz() = %d %q, want %d", rec.Code, rec.Body, http.StatusOK)
+				}
+				return
+			}
+			if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), tt.wantErr) {
+				t.Errorf("Readyz() = %d %q, want %d %q", rec.Code, rec.Body, http.StatusServiceUnavailable, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	log "github.com/gookit/slog"
+
+	v1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+const (
+	// TTLRuleName is the name of the rule requiring a time to live for ephemeral workloads
+	TTLRuleName = "ttl"
+	// TTLAnnotation is the default annotation holding the time to live of a workload, e.g. 24h
+	TTLAnnotation        = "cosignwebhook.eumel8.io/ttl"
+	defaultSweepInterval = 5 * time.Minute
+	// TTLSweeperLease is the name of the Lease electing the replica which sweeps, in the namespace of the webhook
+	TTLSweeperLease = "cosignwebhook-ttl-sweeper"
+)
+
+// podNamespaceFile holds the namespace of the webhook pod, mounted with its service account token
+var podNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// podNamespace returns the namespace of the webhook pod, DefaultStarterNamespace outside of a pod
+func podNamespace() string {
+	b, err := os.ReadFile(podNamespaceFile)
+	if ns := strings.TrimSpace(string(b)); err == nil && ns != "" {
+		return ns
+	}
+	return DefaultStarterNamespace
+}
+
+// TTLConfig configures the time to live of workloads in sandbox namespaces
+type TTLConfig struct {
+	// Namespaces are the sandbox namespaces whose workloads need a TTL
+	Namespaces []string `json:"namespaces"`
+	// Annotation holding the TTL as duration, defaults to cosignwebhook.eumel8.io/ttl
+	Annotation string `json:"annotation"`
+	// MaxTTL is the highest TTL allowed, 0 for no limit
+	MaxTTL metav1.Duration `json:"maxTTL"`
+	// Sweep enables the background deletion of deployments and bare pods past their TTL
+	Sweep bool `json:"sweep"`
+	// SweepInterval is the interval of the sweeper, defaults to 5m
+	SweepInterval metav1.Duration `json:"sweepInterval"`
+}
+
+// ttlRule requires a TTL annotation on workloads in sandbox namespaces and optionally deletes expired workloads
+type ttlRule struct {
+	csh *CosignServerHandler
+	cfg TTLConfig
+}
+
+func newTTLRule(csh *CosignServerHandler, cfg *Config) (Rule, error) {
+	c := cfg.TTL
+	if c.Annotation == "" {
+		c.Annotation = TTLAnnotation
+	}
+	if c.SweepInterval.Duration <= 0 {
+		c.SweepInterval.Duration = defaultSweepInterval
+	}
+	r := &ttlRule{csh: csh, cfg: c}
+	// the rules of all endpoints share one sweeper, the first one sweeps
+	if c.Sweep && csh.ttlSweeper == nil {
+		csh.ttlSweeper = r
+		csh.tasks = append(csh.tasks, r.sweep)
+	}
+	return r, nil
+}
+
+// Name returns the name of the rule
+func (*ttlRule) Name() string {
+	return TTLRuleName
+}
+
+// Validate checks the TTL annotation of deployments and pods not owned by a controller
+func (r *ttlRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	if o.Request.Operation == v1.Delete || !slices.Contains(r.cfg.Namespaces, o.Request.Namespace) {
+		return nil, nil
+	}
+	if o.Deployment == nil && (o.Pod == nil || len(o.Pod.OwnerReferences) > 0) {
+		return nil, nil
+	}
+	value, ok := o.Meta.Annotations[r.cfg.Annotation]
+	if !ok {
+		return nil, fmt.Errorf("workloads in namespace %q need a time to live in annotation %q, e.g. 24h", o.Request.Namespace, r.cfg.Annotation)
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 {
+		return nil, fmt.Errorf("annotation %q must be a positive duration like 24h, got %q", r.cfg.Annotation, value)
+	}
+	tracef(ctx, "time to live %s", ttl)
+	if limit := r.cfg.MaxTTL.Duration; limit > 0 && ttl > limit {
+		return nil, fmt.Errorf("time to live %s in annotation %q exceeds the maximum of %s", ttl, r.cfg.Annotation, limit)
+	}
+	return nil, nil
+}
+
+// expired returns the TTL of the object and whether it's expired at passed time.
+// Objects without a valid TTL never expire.
+func (r *ttlRule) expired(m *metav1.ObjectMeta, now time.Time) (time.Duration, bool) {
+	ttl, err := time.ParseDuration(m.Annotations[r.cfg.Annotation])
+	if err != nil || ttl <= 0 {
+		return 0, false
+	}
+	return ttl, m.CreationTimestamp.Add(ttl).Before(now)
+}
+
+// sweep deletes expired workloads while the replica holds the sweeper Lease, until ctx is done. Only the elected
+// replica sweeps, so the replicas don't delete the same workloads and record duplicate events. The identity is the
+// hostname with a random suffix, so a restarted replica doesn't take over the Lease of its predecessor.
+func (r *ttlRule) sweep(ctx context.Context) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Errorf("TTL sweeper can't get its identity: %v", err)
+		return
+	}
+	id := hostname + "_" + rand.String(8)
+	le, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Name: TTLSweeperLease, Namespace: podNamespace()},
+			Client:     r.csh.cs.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: id},
+		},
+		LeaseDuration:   15 * time.Second,
+		RenewDeadline:   10 * time.Second,
+		RetryPeriod:     2 * time.Second,
+		ReleaseOnCancel: true,
+		Name:            TTLSweeperLease,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: r.sweepLeading,
+			OnStoppedLeading: func() {
+				log.Infof("TTL sweeper %s stopped leading", id)
+			},
+		},
+	})
+	if err != nil {
+		log.Errorf("TTL sweeper can't elect a leader: %v", err)
+		return
+	}
+	// a replica which lost the Lease runs for it again
+	for ctx.Err() == nil {
+		le.Run(ctx)
+	}
+}
+
+// sweepLeading deletes expired workloads in each interval until ctx is done, i.e. the Lease is lost
+func (r *ttlRule) sweepLeading(ctx context.Context) {
+	log.Infof("Starting TTL sweeper with interval %s", r.cfg.SweepInterval.Duration)
+	ticker := time.NewTicker(r.cfg.SweepInterval.Duration)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.sweepOnce(ctx, time.Now())
+		}
+	}
+}
+
+// sweepOnce deletes the deployments and bare pods in the sandbox namespaces which are expired at passed time
+// and records an event for each deletion
+func (r *ttlRule) sweepOnce(ctx context.Context, now time.Time) {
+	for _, ns := range r.cfg.Namespaces {
+		deployments, err := r.csh.cs.AppsV1().Deployments(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			log.Errorf("TTL sweeper can't list deployments in %s: %v", ns, err)
+			continue
+		}
+		for i := range deployments.Items {
+			d := &deployments.Items[i]
+			ttl, expired := r.expired(&d.ObjectMeta, now)
+			if !expired {
+				continue
+			}
+			if err := r.csh.cs.AppsV1().Deployments(ns).Delete(ctx, d.Name, metav1.DeleteOptions{}); err != nil {
+				log.Errorf("TTL sweeper can't delete deployment %s/%s: %v", ns, d.Name, err)
+				continue
+			}
+			log.Infof("TTL sweeper deleted deployment %s/%s", ns, d.Name)
+			r.csh.recordTTLExpired(d, ttl)
+		}
+
+		pods, err := r.csh.cs.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			log.Errorf("TTL sweeper can't list pods in %s: %v", ns, err)
+			continue
+		}
+		for i := range pods.Items {
+			p := &pods.Items[i]
+			if len(p.OwnerReferences) > 0 {
+				continue
+			}
+			ttl, expired := r.expired(&p.ObjectMeta, now)
+			if !expired {
+				continue
+			}
+			if err := r.csh.cs.CoreV1().Pods(ns).Delete(ctx, p.Name, metav1.DeleteOptions{}); err != nil {
+				log.Errorf("TTL sweeper can't delete pod %s/%s: %v", ns, p.Name, err)
+				continue
+			}
+			log.Infof("TTL sweeper deleted pod %s/%s", ns, p.Name)
+			r.csh.recordTTLExpired(p, ttl)
+		}
+	}
+}
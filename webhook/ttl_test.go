@@ -0,0 +1,153 @@
+package webhook
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_ttlRule_Validate(t *testing.T) {
+	cfg := &Config{TTL: TTLConfig{
+		Namespaces: []string{"sandbox"},
+		MaxTTL:     metav1.Duration{Duration: 72 * time.Hour},
+	}}
+	tests := []struct {
+		name    string
+		ns      string
+		ttl     string
+		owned   bool
+		wantErr bool
+	}{
+		{
+			name: "valid ttl",
+			ns:   "sandbox",
+			ttl:  "24h",
+		},
+		{
+			name:    "missing ttl",
+			ns:      "sandbox",
+			wantErr: true,
+		},
+		{
+			name:    "invalid ttl",
+			ns:      "sandbox",
+			ttl:     "tomorrow",
+			wantErr: true,
+		},
+		{
+			name:    "ttl above maximum",
+			ns:      "sandbox",
+			ttl:     "168h",
+			wantErr: true,
+		},
+		{
+			name:  "pod owned by controller",
+			ns:    "sandbox",
+			owned: true,
+		},
+		{
+			name: "no sandbox namespace",
+			ns:   "prod",
+		},
+	}
+
+	r, err := newTTLRule(&CosignServerHandler{}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := podObject(tt.ns, corev1.PodSpec{})
+			if tt.ttl != "" {
+				o.Meta.Annotations = map[string]string{TTLAnnotation: tt.ttl}
+			}
+			if tt.owned {
+				o.Pod.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "test"}}
+			}
+			_, err := r.Validate(context.Background(), o)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_ttlRule_sweepOnce(t *testing.T) {
+	created := metav1.NewTime(time.Now().Add(-2 * time.Hour))
+	meta := func(name, ttl string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "sandbox",
+			CreationTimestamp: created,
+			Annotations:       map[string]string{TTLAnnotation: ttl},
+		}
+	}
+	expired := &appsv1.Deployment{ObjectMeta: meta("expired", "1h")}
+	alive := &appsv1.Deployment{ObjectMeta: meta("alive", "3h")}
+	pod := &corev1.Pod{ObjectMeta: meta("bare-pod", "1h")}
+
+	r := testRule(t, newTTLRule, &Config{TTL: TTLConfig{Namespaces: []string{"sandbox"}}}, expired, alive, pod).(*ttlRule)
+	r.sweepOnce(context.Background(), time.Now())
+
+	deployments, err := r.csh.cs.AppsV1().Deployments("sandbox").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deployments.Items) != 1 || deployments.Items[0].Name != "alive" {
+		t.Errorf("sweepOnce() left deployments %v, want only alive", deployments.Items)
+	}
+	pods, err := r.csh.cs.CoreV1().Pods("sandbox").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods.Items) != 0 {
+		t.Errorf("sweepOnce() left %d pods, want 0", len(pods.Items))
+	}
+}
+
+func Test_ttlRule_sweep(t *testing.T) {
+	defaultFile := podNamespaceFile
+	t.Cleanup(func() { podNamespaceFile = defaultFile })
+	podNamespaceFile = filepath.Join(t.TempDir(), "namespace")
+	if err := os.WriteFile(podNamespaceFile, []byte("webhook\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	expired := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+		Name:              "expired",
+		Namespace:         "sandbox",
+		CreationTimestamp: metav1.NewTime(time.Now().Add(-2 * time.Hour)),
+		Annotations:       map[string]string{TTLAnnotation: "1h"},
+	}}
+	cfg := &Config{TTL: TTLConfig{Namespaces: []string{"sandbox"}, Sweep: true, SweepInterval: metav1.Duration{Duration: 10 * time.Millisecond}}}
+	r := testRule(t, newTTLRule, cfg, expired).(*ttlRule)
+	// the rules of further endpoints share the sweeper
+	tasks := len(r.csh.tasks)
+	if _, err := newTTLRule(r.csh, cfg); err != nil || len(r.csh.tasks) != tasks {
+		t.Fatalf("newTTLRule() added %d tasks, want the sweeper shared: %v", len(r.csh.tasks)-tasks, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for {
+		lease, err := r.csh.cs.CoordinationV1().Leases("webhook").Get(ctx, TTLSweeperLease, metav1.GetOptions{})
+		deployments, listErr := r.csh.cs.AppsV1().Deployments("sandbox").List(ctx, metav1.ListOptions{})
+		if err == nil && listErr == nil && lease.Spec.HolderIdentity != nil && len(deployments.Items) == 0 {
+			if hostname, _ := os.Hostname(); !strings.HasPrefix(*lease.Spec.HolderIdentity, hostname+"_") {
+				t.Errorf("Lease holder = %q, want the hostname with a random suffix", *lease.Spec.HolderIdentity)
+			}
+			return
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("sweeper didn't take Lease webhook/%s and delete the expired deployment: %v", TTLSweeperLease, err)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+
+	log "github.com/gookit/slog"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// UnparseableRuleName is reported as rule of decisions denied by unparseable deny
+const UnparseableRuleName = "unparseable"
+
+var unparseableObjects = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "cosign_unparseable_total",
+	Help: "The number of admission requests with objects which can't be decoded, by kind and unparseable behavior",
+}, []string{"kind", "unparseable"})
+
+// unparseableError is returned by getObject if the admission review is valid, but the object in it can't be
+// decoded, e.g. because of a malformed quantity or an unsupported version
+type unparseableError struct {
+	err error
+}
+
+func (e *unparseableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *unparseableError) Unwrap() error {
+	return e.err
+}
+
+// unparseable answers an admission request whose object can't be decoded with the unparseable behavior. The
+// deprecation rule only reads the kind of the request, so it runs first: removed versions like autoscaling/v2beta2
+// aren't decoded, and are denied with their replacement instead of as unparseable.
+func (e *Endpoint) unparseable(ctx context.Context, w http.ResponseWriter, o *Object, err *unparseableError) {
+	var warnings []string
+	for _, r := range e.rules {
+		if _, ok := unwrapRule(r).(*deprecationRule); !ok {
+			continue
+		}
+		d := evaluateRules(ctx, o, e.Path, []Rule{r}, e.csh.semantics, e.csh.onError, nil)
+		if !d.Allowed {
+			e.csh.recordDecision(d)
+			deny(w, d.Message, o.Request.UID)
+			return
+		}
+		warnings = d.Warnings
+	}
+
+	behavior := e.csh.unparseable
+	log.Warnf("Unparseable %s for %s %s/%s on %s: %v", behavior, o.Request.Kind.Kind, o.Request.Namespace, o.Request.Name, e.Path, err)
+	unparseableObjects.WithLabelValues(o.Request.Kind.Kind, behavior).Inc()
+	d := newDecision(e.Path, o.Request, e.csh.semantics)
+	if behavior == UnparseableAllow {
+		d.Allowed, d.Message = true, "Admitted without validation, the object can't be decoded: "+err.Error()
+		d.Warnings = append(warnings, d.Message)
+		e.csh.recordDecision(d)
+		accept(w, d.Message, o.Request.UID, d.Warnings...)
+		return
+	}
+	d.Rule, d.Message = UnparseableRuleName, "the object can't be decoded: "+err.Error()
+	e.csh.recordDecision(d)
+	deny(w, d.Message, o.Request.UID)
+}
@@ -0,0 +1,236 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestEndpoint_unparseable(t *testing.T) {
+	pod := metav1.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	malformed := `{"metadata":{"name":"app"},"spec":{"containers":[{"name":"app","resources":{"requests":{"cpu":"1x"}}}]}}`
+	tests := []struct {
+		name        string
+		unparseable string
+		kind        metav1.GroupVersionKind
+		object      string
+		namespace   string
+		wantAllowed bool
+		wantMessage string
+		wantRule    string
+		wantCalls   int
+	}{
+		{
+			name:        "valid pod",
+			unparseable: UnparseableDeny,
+			kind:        pod,
+			object:      `{"metadata":{"name":"app"},"spec":{"containers":[{"name":"app"}]}}`,
+			wantAllowed: true,
+			wantCalls:   1,
+		},
+		{
+			name:        "malformed quantity denied",
+			unparseable: UnparseableDeny,
+			kind:        pod,
+			object:      malformed,
+			wantMessage: "the object can't be decoded: invalid Pod",
+			wantRule:    UnparseableRuleName,
+		},
+		{
+			name:        "malformed quantity allowed",
+			unparseable: UnparseableAllow,
+			kind:        pod,
+			object:      malformed,
+			wantAllowed: true,
+			wantMessage: "Admitted without validation, the object can't be decoded: invalid Pod",
+		},
+		{
+			name:        "unsupported version",
+			unparseable: UnparseableDeny,
+			kind:        metav1.GroupVersionKind{Group: "autoscaling", Version: "v1", Kind: "HorizontalPodAutoscaler"},
+			object:      `{"metadata":{"name":"app"}}`,
+			wantMessage: "unsupported version autoscaling/v1 of HorizontalPodAutoscaler, only autoscaling/v2 is decoded",
+			wantRule:    UnparseableRuleName,
+		},
+		{
+			name:        "same kind in other group is generic",
+			unparseable: UnparseableDeny,
+			kind:        metav1.GroupVersionKind{Group: "example.com", Version: "v1alpha1", Kind: "Service"},
+			object:      `{"metadata":{"name":"app"},"spec":{"ports":"any"}}`,
+			wantAllowed: true,
+			wantCalls:   1,
+		},
+		{
+			name:        "exempt namespace",
+			unparseable: UnparseableDeny,
+			kind:        pod,
+			object:      malformed,
+			namespace:   "kube-system",
+			wantAllowed: true,
+			wantMessage: "Namespace kube-system is exempt from validation",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &countingRule{name: SanityRuleName}
+			csh := &CosignServerHandler{cfg: &Config{}, exemptNamespaces: []string{"kube-system"}, unparseable: tt.unparseable}
+			e := &Endpoint{Path: DefaultPath, rules: []Rule{rule}, csh: csh}
+			var decisions []*Decision
+			csh.OnDecision(func(d *Decision) { decisions = append(decisions, d) })
+			ns := tt.namespace
+			if ns == "" {
+				ns = "default"
+			}
+
+			body, err := json.Marshal(v1.AdmissionReview{Request: &v1.AdmissionRequest{
+				UID:       "1",
+				Kind:      tt.kind,
+				Namespace: ns,
+				Name:      "app",
+				Operation: v1.Create,
+				Object:    runtime.RawExtension{Raw: []byte(tt.object)},
+			}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, DefaultPath, bytes.NewReader(body)))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", rec.Code)
+			}
+			ar := v1.AdmissionReview{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &ar); err != nil {
+				t.Fatal(err)
+			}
+			if ar.Response.Allowed != tt.wantAllowed {
+				t.Errorf("allowed = %v, want %v", ar.Response.Allowed, tt.wantAllowed)
+			}
+			if tt.wantMessage != "" && (ar.Response.Result == nil || !strings.Contains(ar.Response.Result.Message, tt.wantMessage)) {
+				t.Errorf("response = %+v, want message %q", ar.Response, tt.wantMessage)
+			}
+			if rule.calls != tt.wantCalls {
+				t.Errorf("rule evaluated %d times, want %d", rule.calls, tt.wantCalls)
+			}
+			if len(decisions) != 1 || decisions[0].Rule != tt.wantRule {
+				t.Errorf("decisions = %+v, want one with rule %q", decisions, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestEndpoint_unparseableDeprecated(t *testing.T) {
+	tests := []struct {
+		name         string
+		action       string
+		unparseable  string
+		wantAllowed  bool
+		wantRule     string
+		wantMessage  string
+		wantWarnings int
+	}{
+		{
+			name:        "removed version denied by deprecation",
+			action:      ActionDeny,
+			unparseable: UnparseableDeny,
+			wantRule:    DeprecationRuleName,
+			wantMessage: "autoscaling/v2beta2 HorizontalPodAutoscaler is removed in Kubernetes 1.26, use autoscaling/v2 instead",
+		},
+		{
+			name:        "removed version warned and denied as unparseable",
+			action:      ActionWarn,
+			unparseable: UnparseableDeny,
+			wantRule:    UnparseableRuleName,
+			wantMessage: "the object can't be decoded: unsupported version autoscaling/v2beta2",
+		},
+		{
+			name:         "removed version warned and admitted as unparseable",
+			action:       ActionWarn,
+			unparseable:  UnparseableAllow,
+			wantAllowed:  true,
+			wantMessage:  "Admitted without validation",
+			wantWarnings: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := newDeprecationRule(nil, &Config{Deprecation: DeprecationConfig{Action: tt.action}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			csh := &CosignServerHandler{cfg: &Config{}, unparseable: tt.unparseable}
+			e := &Endpoint{Path: DefaultPath, rules: []Rule{rule}, csh: csh}
+			var decisions []*Decision
+			csh.OnDecision(func(d *Decision) { decisions = append(decisions, d) })
+
+			body, err := json.Marshal(v1.AdmissionReview{Request: &v1.AdmissionRequest{
+				UID:       "1",
+				Kind:      metav1.GroupVersionKind{Group: "autoscaling", Version: "v2beta2", Kind: "HorizontalPodAutoscaler"},
+				Namespace: "default",
+				Name:      "app",
+				Operation: v1.Create,
+				Object:    runtime.RawExtension{Raw: []byte(`{"metadata":{"name":"app"}}`)},
+			}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, DefaultPath, bytes.NewReader(body)))
+			ar := v1.AdmissionReview{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &ar); err != nil {
+				t.Fatal(err)
+			}
+			if ar.Response.Allowed != tt.wantAllowed {
+				t.Errorf("allowed = %v, want %v", ar.Response.Allowed, tt.wantAllowed)
+			}
+			if ar.Response.Result == nil || !strings.Contains(ar.Response.Result.Message, tt.wantMessage) {
+				t.Errorf("response = %+v, want message %q", ar.Response, tt.wantMessage)
+			}
+			if len(ar.Response.Warnings) != tt.wantWarnings {
+				t.Errorf("warnings = %v, want %d", ar.Response.Warnings, tt.wantWarnings)
+			}
+			if len(decisions) != 1 || decisions[0].Rule != tt.wantRule {
+				t.Errorf("decisions = %+v, want one with rule %q", decisions, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestEndpoint_unparseableReview(t *testing.T) {
+	e := &Endpoint{Path: DefaultPath, csh: &CosignServerHandler{cfg: &Config{}, unparseable: UnparseableAllow}}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, DefaultPath, strings.NewReader(`{"request":"app"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400 for a body which isn't an AdmissionReview", rec.Code)
+	}
+}
+
+func Test_getObject_unparseable(t *testing.T) {
+	body, err := json.Marshal(v1.AdmissionReview{Request: &v1.AdmissionRequest{
+		Kind:   metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+		Object: runtime.RawExtension{Raw: []byte(`{"metadata":{"name":"app","labels":{"app":"web"}},"spec":{"replicas":"two"}}`)},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, _, err := getObject(body)
+	var unparseable *unparseableError
+	if !errors.As(err, &unparseable) {
+		t.Fatalf("getObject() error = %v, want unparseable", err)
+	}
+	if o == nil || o.Meta.Labels["app"] != "web" {
+		t.Fatalf("getObject() object = %+v, want the metadata", o)
+	}
+	if o.Deployment != nil {
+		t.Errorf("getObject() deployment = %+v, want nil", o.Deployment)
+	}
+}
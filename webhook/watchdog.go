@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	log "github.com/gookit/slog"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	watchdogStuck = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cosign_watchdog_stuck_total",
+		Help: "The number of rule evaluations abandoned by the watchdog after exceeding the limit, by rule",
+	}, []string{"rule"})
+	watchdogAbandoned = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "cosign_watchdog_abandoned_workers",
+		Help: "The number of abandoned evaluation workers which are still running",
+	})
+)
+
+// WatchdogConfig configures the watchdog of the rule evaluations
+type WatchdogConfig struct {
+	// Limit is the hard wall-clock limit of a rule evaluation, e.g. 8s. Zero disables the watchdog.
+	// It should be below the timeout of the webhook, so the API server gets the verdict of the watchdog.
+	Limit metav1.Duration `json:"limit"`
+}
+
+// watchdogRule evaluates the rule in its own worker goroutine. If the worker exceeds the limit, e.g. stuck in an
+// external call ignoring the context, its context is cancelled and it is abandoned, so the next evaluation gets a
+// new worker. The request is denied by the offending rule.
+type watchdogRule struct {
+	Rule
+	limit time.Duration
+}
+
+// withWatchdog returns the rules guarded by the watchdog, or the rules as they are if it is disabled
+func withWatchdog(cfg WatchdogConfig, rules []Rule) []Rule {
+	if cfg.Limit.Duration <= 0 {
+		return rules
+	}
+	guarded := make([]Rule, 0, len(rules))
+	for _, r := range rules {
+		guarded = append(guarded, &watchdogRule{Rule: r, limit: cfg.Limit.Duration})
+	}
+	return guarded
+}
+
+// unwrapRule returns the rule guarded by the watchdog, or the rule itself if it isn't guarded
+func unwrapRule(r Rule) Rule {
+	if w, ok := r.(*watchdogRule); ok {
+		return w.Rule
+	}
+	return r
+}
+
+// Validate validates the object with the rule in a new worker and waits at most the limit for its result
+func (r *watchdogRule) Validate(ctx context.Context, o *Object) ([]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// finished is set by whoever is first, the worker returning or the watchdog abandoning it
+	var finished atomic.Bool
+	done := make(chan ruleResult, 1)
+	go func() {
+		var res ruleResult
+		res.warnings, res.err = validateRule(ctx, r.Rule, o)
+		if !finished.CompareAndSwap(false, true) {
+			log.Infof("Abandoned worker of rule %s returned", r.Name())
+			watchdogAbandoned.Dec()
+			return
+		}
+		done <- res
+	}()
+
+	timer := time.NewTimer(r.limit)
+	defer timer.Stop()
+	select {
+	case res := <-done:
+		return res.warnings, res.err
+	case <-timer.C:
+		if !finished.CompareAndSwap(false, true) {
+			res := <-done
+			return res.warnings, res.err
+		}
+		watchdogStuck.WithLabelValues(r.Name()).Inc()
+		watchdogAbandoned.Inc()
+		log.Errorf("Watchdog abandoned rule %s on %s %s/%s after %s", r.Name(), o.Request.Kind.Kind, o.Request.Namespace, o.Request.Name, r.limit)
+		return nil, internalError(fmt.Errorf("rule %s exceeded the evaluation limit of %s", r.Name(), r.limit))
+	}
+}
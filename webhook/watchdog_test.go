@@ -0,0 +1,65 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// stuckRule blocks until release is closed, ignoring the context
+type stuckRule struct {
+	release chan struct{}
+}
+
+func (*stuckRule) Name() string {
+	return "stuck"
+}
+
+func (r *stuckRule) Validate(_ context.Context, _ *Object) ([]string, error) {
+	<-r.release
+	return nil, nil
+}
+
+func Test_watchdogRule(t *testing.T) {
+	if rules := withWatchdog(WatchdogConfig{}, []Rule{&stuckRule{}}); rules[0].Name() != "stuck" {
+		t.Fatalf("withWatchdog() disabled = %v, want the rules as they are", rules)
+	} else if _, ok := rules[0].(*watchdogRule); ok {
+		t.Fatal("withWatchdog() disabled guarded the rules")
+	}
+
+	cfg := WatchdogConfig{Limit: metav1.Duration{Duration: 50 * time.Millisecond}}
+	stuck := &stuckRule{release: make(chan struct{})}
+	failing := &countingRule{name: SanityRuleName, err: errors.New("broken pod spec")}
+	rules := withWatchdog(cfg, []Rule{failing, stuck})
+	o := podObject("default", corev1.PodSpec{})
+
+	if _, err := rules[0].Validate(context.Background(), o); err == nil || err.Error() != "broken pod spec" {
+		t.Errorf("Validate() error = %v, want the error of the rule", err)
+	}
+
+	before := testutil.ToFloat64(watchdogStuck.WithLabelValues("stuck"))
+	d := evaluateRules(context.Background(), o, DefaultPath, rules[1:], SemanticsVersion, onErrorPolicy{}, nil)
+	if d.Allowed || d.Rule != "stuck" {
+		t.Errorf("decision = %+v, want denied by the stuck rule", d)
+	}
+	if got := testutil.ToFloat64(watchdogStuck.WithLabelValues("stuck")) - before; got != 1 {
+		t.Errorf("cosign_watchdog_stuck_total = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(watchdogAbandoned); got != 1 {
+		t.Errorf("cosign_watchdog_abandoned_workers = %v, want 1", got)
+	}
+
+	close(stuck.release)
+	deadline := time.Now().Add(time.Second)
+	for testutil.ToFloat64(watchdogAbandoned) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("abandoned worker not counted as returned")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
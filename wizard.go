@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/eumel8/cosignwebhook/webhook"
+
+	"github.com/spf13/cobra"
+)
+
+// initOptions are the flags of the init command
+type initOptions struct {
+	nonInteractive bool
+	registries     string
+	namespaces     string
+	rules          string
+	strictness     string
+	namespace      string
+	dir            string
+	format         string
+}
+
+// initResult is the output of the init command
+type initResult struct {
+	// Files are the paths of the written files
+	Files []string `json:"files"`
+}
+
+// newInitCommand returns the command generating a starter policy and deployment manifests
+func newInitCommand() *cobra.Command {
+	o := &initOptions{}
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Generate a starter policy and deployment manifests",
+		Long: `Generate a starter policy config.yaml and the deployment manifests manifests.yaml with a
+self-signed webhook certificate. Asks for the options not given as flags, unless --non-interactive is set.`,
+		Example: `  cosignwebhook init
+  cosignwebhook init --non-interactive --registries ghcr.io,docker.io --strictness strict --dir deploy -o json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runInit(cmd, o)
+		},
+	}
+	cmd.Flags().BoolVar(&o.nonInteractive, "non-interactive", false, "Don't ask, use the flags and defaults only.")
+	cmd.Flags().StringVar(&o.registries, "registries", "", "Comma-separated registries images may be pulled from, e.g. ghcr.io,docker.io. Empty allows all registries.")
+	cmd.Flags().StringVar(&o.namespaces, "namespaces", "", "Comma-separated namespaces to validate. Empty validates all but the webhook namespace and kube-system.")
+	cmd.Flags().StringVar(&o.rules, "rules", "", "Comma-separated rules to add to the rules of the strictness.")
+	cmd.Flags().StringVar(&o.strictness, "strictness", webhook.StrictnessStandard, "Strictness of the policy: permissive, standard or strict.")
+	cmd.Flags().StringVar(&o.namespace, "namespace", webhook.DefaultStarterNamespace, "Namespace the webhook is deployed to.")
+	cmd.Flags().StringVar(&o.dir, "dir", ".", "Directory to write config.yaml and manifests.yaml to.")
+	addOutputFlag(cmd, &o.format)
+
+	_ = cmd.RegisterFlagCompletionFunc("rules", completeRules)
+	_ = cmd.RegisterFlagCompletionFunc("namespaces", completeNamespaces(true))
+	_ = cmd.RegisterFlagCompletionFunc("namespace", completeNamespaces(false))
+	_ = cmd.RegisterFlagCompletionFunc("strictness", cobra.FixedCompletions(
+		[]string{webhook.StrictnessPermissive, webhook.StrictnessStandard, webhook.StrictnessStrict}, cobra.ShellCompDirectiveNoFileComp))
+	_ = cmd.MarkFlagDirname("dir")
+	return cmd
+}
+
+// runInit generates the starter files, asking for the options not given as flags on stderr unless non-interactive is set
+func runInit(cmd *cobra.Command, o *initOptions) error {
+	if err := checkOutput(o.format); err != nil {
+		return err
+	}
+	if !o.nonInteractive {
+		in := bufio.NewReader(cmd.InOrStdin())
+		questions := []struct {
+			flag     string
+			question string
+			value    *string
+		}{
+			{"registries", "Registries images may be pulled from (comma-separated, empty allows all)", &o.registries},
+			{"namespaces", "Namespaces to validate (comma-separated, empty for all)", &o.namespaces},
+			{"strictness", "Strictness (permissive, standard, strict)", &o.strictness},
+			{"namespace", "Namespace of the webhook", &o.namespace},
+		}
+		for _, q := range questions {
+			if cmd.Flags().Changed(q.flag) {
+				continue
+			}
+			answer, err := ask(in, cmd.ErrOrStderr(), q.question, *q.value)
+			if err != nil {
+				return err
+			}
+			*q.value = answer
+		}
+	}
+
+	s, err := webhook.NewStarter(webhook.StarterOptions{
+		Registries: splitList(o.registries),
+		Namespaces: splitList(o.namespaces),
+		Rules:      splitList(o.rules),
+		Strictness: o.strictness,
+		Namespace:  o.namespace,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to generate starter files: %w", err)
+	}
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"config.yaml", s.Config},
+		{"manifests.yaml", s.Manifests},
+	}
+	result := initResult{}
+	for _, f := range files {
+		path := filepath.Join(o.dir, f.name)
+		// the manifests contain the private key of the webhook
+		if err := os.WriteFile(path, f.data, 0o600); err != nil {
+			return err
+		}
+		result.Files = append(result.Files, path)
+	}
+	return writeOutput(cmd.OutOrStdout(), o.format, result, func(w io.Writer) {
+		for _, f := range result.Files {
+			fmt.Fprintf(w, "Wrote %s\n", f)
+		}
+	})
+}
+
+// ask prints the question with the default and returns the answer, or the default for an empty answer
+func ask(in *bufio.Reader, out io.Writer, question, def string) (string, error) {
+	fmt.Fprintf(out, "%s [%s]: ", question, def)
+	answer, err := in.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	if answer = strings.TrimSpace(answer); answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+// splitList splits a comma-separated list and drops empty items
+func splitList(s string) []string {
+	var items []string
+	for _, i := range strings.Split(s, ",") {
+		if i = strings.TrimSpace(i); i != "" {
+			items = append(items, i)
+		}
+	}
+	return items
+}